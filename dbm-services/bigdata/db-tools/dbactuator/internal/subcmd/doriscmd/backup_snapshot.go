@@ -0,0 +1,103 @@
+package doriscmd
+
+import (
+	"dbm-services/bigdata/db-tools/dbactuator/internal/subcmd"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/components/doris"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util"
+	"dbm-services/common/go-pubpkg/logger"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// BackupSnapshotAct 备份库表
+type BackupSnapshotAct struct {
+	*subcmd.BaseOptions
+	Service doris.BackupRestoreService
+}
+
+// BackupSnapshotCommand 备份库表命令
+func BackupSnapshotCommand() *cobra.Command {
+	act := BackupSnapshotAct{
+		BaseOptions: subcmd.GBaseOptions,
+	}
+	// 生成 Doris备份 命令
+	cmd := &cobra.Command{
+		Use:     "backup_snapshot",
+		Short:   "doris 备份库表到仓库",
+		Example: fmt.Sprintf(`dbactuator doris backup_snapshot %s`, subcmd.CmdBaseExapmleStr),
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(act.Validate())
+			if act.RollBack {
+				util.CheckErr(act.Rollback())
+				return
+			}
+			util.CheckErr(act.Init())
+			util.CheckErr(act.Run())
+		},
+	}
+	return cmd
+}
+
+// Validate 用于验证参数
+func (d *BackupSnapshotAct) Validate() (err error) {
+	return d.BaseOptions.Validate()
+}
+
+// Init 用于初始化
+func (d *BackupSnapshotAct) Init() (err error) {
+	logger.Info("BackupSnapshotAct Init")
+	if err = d.Deserialize(&d.Service.Params); err != nil {
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	d.Service.GeneralParam = subcmd.GeneralRuntimeParam
+	// 初始化Doris安装默认参数
+	d.Service.InstallParams = doris.InitDefaultInstallParam()
+	return nil
+}
+
+// Rollback 用于回滚操作
+// @receiver d
+//
+//	@return err
+func (d *BackupSnapshotAct) Rollback() (err error) {
+	var r rollback.RollBackObjects
+	if err = d.DeserializeAndValidate(&r); err != nil {
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	err = r.RollBack()
+	if err != nil {
+		logger.Error("roll back failed %s", err.Error())
+	}
+	return
+}
+
+// Run 用于执行
+func (d *BackupSnapshotAct) Run() (err error) {
+	// 步骤1. Doris备份
+	steps := subcmd.Steps{
+
+		{
+			FunName: "Doris备份",
+			Func:    d.Service.BackupSnapshot,
+		},
+	}
+
+	// json 解析每个步骤执行返回内容
+	if err := steps.Run(); err != nil {
+		rollbackCtxBytes, jsonErr := json.Marshal(d.Service.RollBackContext)
+		if jsonErr != nil {
+			logger.Error("json Marshal %s", err.Error())
+			fmt.Printf("<ctx>Can't RollBack<ctx>\n")
+		}
+		fmt.Printf("<ctx>%s<ctx>\n", string(rollbackCtxBytes))
+		return err
+	}
+
+	logger.Info("backup snapshot successfully")
+	return nil
+}
@@ -36,6 +36,11 @@ func DorisCommand() *cobra.Command {
 				CheckProcessStartCommand(),
 				CreateResourceCommand(),
 				DropResourceCommand(),
+				CreateRepositoryCommand(),
+				BackupSnapshotCommand(),
+				CheckBackupCommand(),
+				ListSnapshotCommand(),
+				RestoreSnapshotCommand(),
 			},
 		},
 	}
@@ -0,0 +1,103 @@
+package doriscmd
+
+import (
+	"dbm-services/bigdata/db-tools/dbactuator/internal/subcmd"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/components/doris"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util"
+	"dbm-services/common/go-pubpkg/logger"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// CreateRepositoryAct 创建S3备份仓库
+type CreateRepositoryAct struct {
+	*subcmd.BaseOptions
+	Service doris.BackupRestoreService
+}
+
+// CreateRepositoryCommand 创建S3备份仓库命令
+func CreateRepositoryCommand() *cobra.Command {
+	act := CreateRepositoryAct{
+		BaseOptions: subcmd.GBaseOptions,
+	}
+	// 生成 创建Doris备份仓库 命令
+	cmd := &cobra.Command{
+		Use:     "create_repository",
+		Short:   "doris 创建S3备份仓库",
+		Example: fmt.Sprintf(`dbactuator doris create_repository %s`, subcmd.CmdBaseExapmleStr),
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(act.Validate())
+			if act.RollBack {
+				util.CheckErr(act.Rollback())
+				return
+			}
+			util.CheckErr(act.Init())
+			util.CheckErr(act.Run())
+		},
+	}
+	return cmd
+}
+
+// Validate 用于验证参数
+func (d *CreateRepositoryAct) Validate() (err error) {
+	return d.BaseOptions.Validate()
+}
+
+// Init 用于初始化
+func (d *CreateRepositoryAct) Init() (err error) {
+	logger.Info("CreateRepositoryAct Init")
+	if err = d.Deserialize(&d.Service.Params); err != nil {
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	d.Service.GeneralParam = subcmd.GeneralRuntimeParam
+	// 初始化Doris安装默认参数
+	d.Service.InstallParams = doris.InitDefaultInstallParam()
+	return nil
+}
+
+// Rollback 用于回滚操作
+// @receiver d
+//
+//	@return err
+func (d *CreateRepositoryAct) Rollback() (err error) {
+	var r rollback.RollBackObjects
+	if err = d.DeserializeAndValidate(&r); err != nil {
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	err = r.RollBack()
+	if err != nil {
+		logger.Error("roll back failed %s", err.Error())
+	}
+	return
+}
+
+// Run 用于执行
+func (d *CreateRepositoryAct) Run() (err error) {
+	// 步骤1. 创建Doris备份仓库
+	steps := subcmd.Steps{
+
+		{
+			FunName: "创建Doris备份仓库",
+			Func:    d.Service.CreateRepository,
+		},
+	}
+
+	// json 解析每个步骤执行返回内容
+	if err := steps.Run(); err != nil {
+		rollbackCtxBytes, jsonErr := json.Marshal(d.Service.RollBackContext)
+		if jsonErr != nil {
+			logger.Error("json Marshal %s", err.Error())
+			fmt.Printf("<ctx>Can't RollBack<ctx>\n")
+		}
+		fmt.Printf("<ctx>%s<ctx>\n", string(rollbackCtxBytes))
+		return err
+	}
+
+	logger.Info("create repository successfully")
+	return nil
+}
@@ -42,6 +42,14 @@ func NewEsCommand() *cobra.Command {
 				CheckNodesCommand(),
 				GenCerCommand(),
 				PackCerCommand(),
+				ConfigSnapshotRepoCommand(),
+				RegisterSnapshotRepoCommand(),
+				CreateSnapshotCommand(),
+				SnapshotPolicyCommand(),
+				PruneSnapshotCommand(),
+				RestoreSnapshotCommand(),
+				RollingRestartCommand(),
+				RollingUpgradeCommand(),
 			},
 		},
 	}
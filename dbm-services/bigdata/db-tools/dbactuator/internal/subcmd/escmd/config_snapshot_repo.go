@@ -0,0 +1,98 @@
+package escmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbm-services/bigdata/db-tools/dbactuator/internal/subcmd"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/components/elasticsearch"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util"
+	"dbm-services/common/go-pubpkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// ConfigSnapshotRepoAct 配置快照仓库
+type ConfigSnapshotRepoAct struct {
+	*subcmd.BaseOptions
+	Service elasticsearch.EsSnapshotComp
+}
+
+// ConfigSnapshotRepoCommand 配置快照仓库
+func ConfigSnapshotRepoCommand() *cobra.Command {
+	act := ConfigSnapshotRepoAct{
+		BaseOptions: subcmd.GBaseOptions,
+	}
+	cmd := &cobra.Command{
+		Use:     "config_snapshot_repo",
+		Short:   "配置快照仓库(path.repo/s3 keystore), 需在所有节点执行",
+		Example: fmt.Sprintf(`dbactuator es config_snapshot_repo %s`, subcmd.CmdBaseExapmleStr),
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(act.Validate())
+			if act.RollBack {
+				util.CheckErr(act.Rollback())
+				return
+			}
+			util.CheckErr(act.Init())
+			util.CheckErr(act.Run())
+		},
+	}
+	return cmd
+}
+
+// Validate TODO
+func (d *ConfigSnapshotRepoAct) Validate() (err error) {
+	return d.BaseOptions.Validate()
+}
+
+// Init TODO
+func (d *ConfigSnapshotRepoAct) Init() (err error) {
+	logger.Info("ConfigSnapshotRepoAct Init")
+	if err = d.Deserialize(&d.Service.Params); err != nil {
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	d.Service.GeneralParam = subcmd.GeneralRuntimeParam
+	return d.Service.Init()
+}
+
+// Rollback TODO
+//
+//	@receiver d
+//	@return err
+func (d *ConfigSnapshotRepoAct) Rollback() (err error) {
+	var r rollback.RollBackObjects
+	if err = d.DeserializeAndValidate(&r); err != nil {
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	err = r.RollBack()
+	if err != nil {
+		logger.Error("roll back failed %s", err.Error())
+	}
+	return
+}
+
+// Run TODO
+func (d *ConfigSnapshotRepoAct) Run() (err error) {
+	steps := subcmd.Steps{
+		{
+			FunName: "配置快照仓库",
+			Func:    d.Service.ConfigRepo,
+		},
+	}
+
+	if err := steps.Run(); err != nil {
+		rollbackCtxb, rerr := json.Marshal(d.Service.RollBackContext)
+		if rerr != nil {
+			logger.Error("json Marshal %s", err.Error())
+			fmt.Printf("<ctx>Can't RollBack<ctx>\n")
+		}
+		fmt.Printf("<ctx>%s<ctx>\n", string(rollbackCtxb))
+		return err
+	}
+
+	logger.Info("config_snapshot_repo successfully")
+	return nil
+}
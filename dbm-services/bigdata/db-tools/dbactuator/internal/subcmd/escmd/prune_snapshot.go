@@ -0,0 +1,98 @@
+package escmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbm-services/bigdata/db-tools/dbactuator/internal/subcmd"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/components/elasticsearch"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util"
+	"dbm-services/common/go-pubpkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// PruneSnapshotAct 清理快照
+type PruneSnapshotAct struct {
+	*subcmd.BaseOptions
+	Service elasticsearch.EsSnapshotComp
+}
+
+// PruneSnapshotCommand 清理快照
+func PruneSnapshotCommand() *cobra.Command {
+	act := PruneSnapshotAct{
+		BaseOptions: subcmd.GBaseOptions,
+	}
+	cmd := &cobra.Command{
+		Use:     "prune_snapshot",
+		Short:   "按保留策略清理快照",
+		Example: fmt.Sprintf(`dbactuator es prune_snapshot %s`, subcmd.CmdBaseExapmleStr),
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(act.Validate())
+			if act.RollBack {
+				util.CheckErr(act.Rollback())
+				return
+			}
+			util.CheckErr(act.Init())
+			util.CheckErr(act.Run())
+		},
+	}
+	return cmd
+}
+
+// Validate TODO
+func (d *PruneSnapshotAct) Validate() (err error) {
+	return d.BaseOptions.Validate()
+}
+
+// Init TODO
+func (d *PruneSnapshotAct) Init() (err error) {
+	logger.Info("PruneSnapshotAct Init")
+	if err = d.Deserialize(&d.Service.Params); err != nil {
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	d.Service.GeneralParam = subcmd.GeneralRuntimeParam
+	return d.Service.Init()
+}
+
+// Rollback TODO
+//
+//	@receiver d
+//	@return err
+func (d *PruneSnapshotAct) Rollback() (err error) {
+	var r rollback.RollBackObjects
+	if err = d.DeserializeAndValidate(&r); err != nil {
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	err = r.RollBack()
+	if err != nil {
+		logger.Error("roll back failed %s", err.Error())
+	}
+	return
+}
+
+// Run TODO
+func (d *PruneSnapshotAct) Run() (err error) {
+	steps := subcmd.Steps{
+		{
+			FunName: "清理快照",
+			Func:    d.Service.PruneSnapshots,
+		},
+	}
+
+	if err := steps.Run(); err != nil {
+		rollbackCtxb, rerr := json.Marshal(d.Service.RollBackContext)
+		if rerr != nil {
+			logger.Error("json Marshal %s", err.Error())
+			fmt.Printf("<ctx>Can't RollBack<ctx>\n")
+		}
+		fmt.Printf("<ctx>%s<ctx>\n", string(rollbackCtxb))
+		return err
+	}
+
+	logger.Info("prune_snapshot successfully")
+	return nil
+}
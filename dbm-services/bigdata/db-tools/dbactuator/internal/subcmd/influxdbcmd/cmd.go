@@ -30,6 +30,9 @@ func NewInfluxdbCommand() *cobra.Command {
 				StartProcessCommand(),
 				StopProcessCommand(),
 				RestartProcessCommand(),
+				BackupCommand(),
+				RestoreCommand(),
+				CleanBackupCommand(),
 			},
 		},
 	}
@@ -29,6 +29,7 @@ func NewKafkaCommand() *cobra.Command {
 				InstallSupervisorCommand(),
 				InstallZookeeperCommand(),
 				InitKafkaUserCommand(),
+				GenClusterIDCommand(),
 				InstallBrokerCommand(),
 				InstallManagerCommand(),
 				CleanDataCommand(),
@@ -0,0 +1,104 @@
+package kafkacmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbm-services/bigdata/db-tools/dbactuator/internal/subcmd"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/components/kafka"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util"
+	"dbm-services/common/go-pubpkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// GenClusterIDAct TODO
+type GenClusterIDAct struct {
+	*subcmd.BaseOptions
+	Service kafka.InstallKafkaComp
+}
+
+// GenClusterIDCommand TODO
+func GenClusterIDCommand() *cobra.Command {
+	act := GenClusterIDAct{
+		BaseOptions: subcmd.GBaseOptions,
+	}
+	cmd := &cobra.Command{
+		Use:     "gen_cluster_id",
+		Short:   "生成KRaft集群ID",
+		Example: fmt.Sprintf(`dbactuator kafka gen_cluster_id %s`, subcmd.CmdBaseExapmleStr),
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(act.Validate())
+			if act.RollBack {
+				util.CheckErr(act.Rollback())
+				return
+			}
+			util.CheckErr(act.Init())
+			util.CheckErr(act.Run())
+		},
+	}
+	return cmd
+}
+
+// Validate TODO
+func (d *GenClusterIDAct) Validate() (err error) {
+	return d.BaseOptions.Validate()
+}
+
+// Init TODO
+func (d *GenClusterIDAct) Init() (err error) {
+	logger.Info("GenClusterIDAct Init")
+	if err = d.Deserialize(&d.Service.Params); err != nil {
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	d.Service.GeneralParam = subcmd.GeneralRuntimeParam
+	return d.Service.InitDefaultParam()
+}
+
+// Rollback TODO
+//
+//	@receiver d
+//	@return err
+func (d *GenClusterIDAct) Rollback() (err error) {
+	var r rollback.RollBackObjects
+	if err = d.DeserializeAndValidate(&r); err != nil {
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	err = r.RollBack()
+	if err != nil {
+		logger.Error("roll back failed %s", err.Error())
+	}
+	return
+}
+
+// Run TODO
+func (d *GenClusterIDAct) Run() (err error) {
+	steps := subcmd.Steps{
+		/* Todo
+		{
+			FunName: "预检查",
+			Func:    d.Service.PreCheck,
+		},
+		*/
+		{
+			FunName: "生成集群ID",
+			Func:    d.Service.GenClusterID,
+		},
+	}
+
+	if err := steps.Run(); err != nil {
+		rollbackCtxb, rerr := json.Marshal(d.Service.RollBackContext)
+		if rerr != nil {
+			logger.Error("json Marshal %s", err.Error())
+			fmt.Printf("<ctx>Can't RollBack<ctx>\n")
+		}
+		fmt.Printf("<ctx>%s<ctx>\n", string(rollbackCtxb))
+		return err
+	}
+
+	logger.Info("gen cluster id successfully")
+	return nil
+}
@@ -0,0 +1,98 @@
+package vmcmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbm-services/bigdata/db-tools/dbactuator/internal/subcmd"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/components/victoriametrics"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util"
+	"dbm-services/common/go-pubpkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// RestoreAct 是一个结构体，用于恢复vmstorage。
+type RestoreAct struct {
+	*subcmd.BaseOptions                            // BaseOptions 是基础选项，可能包含了一些全局设置或配置。
+	Service             victoriametrics.BackupComp // Service 是用于恢复vmstorage的组件。
+}
+
+// RestoreCommand 是一个函数，返回一个cobra.Command对象，该对象定义了一个命令行命令，用于恢复vmstorage。
+func RestoreCommand() *cobra.Command {
+	act := RestoreAct{
+		BaseOptions: subcmd.GBaseOptions,
+	}
+	cmd := &cobra.Command{
+		Use:     "restore",
+		Short:   "恢复vmstorage",
+		Example: fmt.Sprintf(`dbactuator vm restore %s`, subcmd.CmdBaseExapmleStr),
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(act.Validate()) // 验证参数
+			if act.RollBack {             // 如果需要回滚，则执行回滚操作
+				util.CheckErr(act.Rollback())
+				return
+			}
+			util.CheckErr(act.Init()) // 初始化
+			util.CheckErr(act.Run())  // 运行
+		},
+	}
+	return cmd
+}
+
+// Validate 是 RestoreAct 的验证函数，用于验证参数是否有效。
+func (d *RestoreAct) Validate() (err error) {
+	return d.BaseOptions.Validate()
+}
+
+// Init 是 RestoreAct 的初始化函数，用于初始化操作。
+func (d *RestoreAct) Init() (err error) {
+	logger.Info("RestoreAct Init")
+	if err = d.Deserialize(&d.Service.Params); err != nil { // 反序列化参数
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	d.Service.GeneralParam = subcmd.GeneralRuntimeParam // 设置通用运行时参数
+	return d.Service.Init()                             // 初始化服务
+}
+
+// Rollback TODO
+//
+//	@receiver d
+//	@return err
+func (d *RestoreAct) Rollback() (err error) {
+	var r rollback.RollBackObjects
+	if err = d.DeserializeAndValidate(&r); err != nil {
+		logger.Error("DeserializeAndValidate failed, %v", err)
+		return err
+	}
+	err = r.RollBack()
+	if err != nil {
+		logger.Error("roll back failed %s", err.Error())
+	}
+	return
+}
+
+// Run 是 RestoreAct 的运行函数，用于执行恢复vmstorage的操作。
+func (d *RestoreAct) Run() (err error) {
+	steps := subcmd.Steps{
+		{
+			FunName: "恢复vmstorage",
+			Func:    d.Service.Restore, // 恢复vmstorage的函数
+		},
+	}
+
+	if err := steps.Run(); err != nil { // 执行步骤
+		rollbackCtxb, rerr := json.Marshal(d.Service.RollBackContext) // 序列化回滚上下文
+		if rerr != nil {
+			logger.Error("json Marshal %s", err.Error())
+			fmt.Printf("<ctx>Can't RollBack<ctx>\n")
+		}
+		fmt.Printf("<ctx>%s<ctx>\n", string(rollbackCtxb))
+		return err
+	}
+
+	logger.Info("restore successfully") // 打印成功信息
+	return nil
+}
@@ -47,6 +47,10 @@ func VMCommand() *cobra.Command {
 				CleanDataCommand(),
 				ReloadVMSelectCommand(),
 				ReloadVMInsertCommand(),
+				// 备份恢复命令
+				BackupCommand(),
+				RestoreCommand(),
+				CleanBackupCommand(),
 			},
 		},
 	}
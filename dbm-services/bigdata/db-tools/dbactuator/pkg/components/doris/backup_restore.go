@@ -0,0 +1,447 @@
+package doris
+
+import (
+	"database/sql"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/components"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/dorisutil"
+	"dbm-services/common/go-pubpkg/logger"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql" // mysql
+)
+
+const (
+	// BackupJobFinished 备份/恢复任务完成
+	BackupJobFinished = "FINISHED"
+	// BackupJobCancelled 备份/恢复任务取消或失败
+	BackupJobCancelled = "CANCELLED"
+	// DefaultBackupTimeout 备份/恢复任务默认超时时间, 单位秒
+	DefaultBackupTimeout = 86400
+	// BackupPollInterval 查询任务进度的间隔
+	BackupPollInterval = 10 * time.Second
+)
+
+// snapshotNameRe 快照名(label)只允许字母开头的字母、数字、下划线和中划线
+var snapshotNameRe = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,127}$`)
+
+// BackupRestoreService Doris 仓库备份恢复接口
+type BackupRestoreService struct {
+	GeneralParam    *components.GeneralParam
+	Params          *BackupRestoreParams
+	RollBackContext rollback.RollBackObjects
+	InstallParams
+}
+
+// BackupRestoreParams 备份恢复 参数 结构体, 不同的子命令使用其中一部分
+type BackupRestoreParams struct {
+	Host         string `json:"host" validate:"required,ip" ` // FE IP, 恢复到其他集群时为目标集群的FE
+	QueryPort    int    `json:"query_port" validate:"required"`
+	UserName     string `json:"username" validate:"required"`
+	Password     string `json:"password" validate:"required"`
+	RootPassword string `json:"root_password"`
+
+	Repository   string `json:"repository" validate:"required"` // 仓库名
+	ReadOnly     bool   `json:"readonly"`                       // 只读仓库, 恢复到其他集群时使用
+	AccessKey    string `json:"access_key"`
+	SecretKey    string `json:"secret_key"`
+	Endpoint     string `json:"endpoint"` // 为空时使用COS内网域名, MinIO eg: http://127.0.0.1:9000
+	Region       string `json:"region"`
+	BucketName   string `json:"bucket_name"`
+	RootPath     string `json:"root_path"`
+	UsePathStyle bool   `json:"use_path_style"` // MinIO 需要为true
+
+	Database       string            `json:"database"`        // 备份的库
+	Tables         []string          `json:"tables"`          // 备份/恢复的表, 为空时为整库
+	Snapshot       string            `json:"snapshot"`        // 快照名(label), 备份时为空则自动生成; 恢复时为空则使用最新的快照
+	TargetDatabase string            `json:"target_database"` // 恢复的目标库, 为空时与database相同
+	RenameTables   map[string]string `json:"rename_tables"`   // 恢复时重命名, 原表名 -> 新表名
+	ReplicationNum int               `json:"replication_num"` // 恢复时的副本数, 目标集群BE数较少时需要指定
+	Timeout        int               `json:"timeout"`         // 任务超时时间, 单位秒
+	NoWait         bool              `json:"no_wait"`         // 只提交任务, 不等待完成
+}
+
+// SnapshotInfo SHOW SNAPSHOT 返回的快照信息
+type SnapshotInfo struct {
+	Snapshot  string `json:"snapshot"`
+	Timestamp string `json:"timestamp"`
+	Status    string `json:"status"`
+}
+
+// openDB 连接FE, 与其他接口一致优先使用root密码
+func (i *BackupRestoreService) openDB() (*sql.DB, error) {
+	pwd := dorisutil.DefaultString(i.Params.RootPassword, i.Params.Password)
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
+		i.Params.UserName, pwd, i.Params.Host, i.Params.QueryPort, ""))
+	if err != nil {
+		logger.Error("连接Doris数据库失败，%v", err)
+		return nil, err
+	}
+	return db, nil
+}
+
+// queryRows 执行SHOW语句, 不同版本返回的列不同, 按列名返回
+func queryRows(db *sql.DB, query string) ([]map[string]string, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var result []map[string]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for k := range values {
+			dest[k] = &values[k]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(columns))
+		for k, c := range columns {
+			row[c] = values[k].String
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// CreateRepository 创建S3仓库, 已存在时跳过
+func (i *BackupRestoreService) CreateRepository() (err error) {
+	db, err := i.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return i.createRepository(db)
+}
+
+func (i *BackupRestoreService) createRepository(db *sql.DB) error {
+	repos, err := queryRows(db, "SHOW REPOSITORIES")
+	if err != nil {
+		logger.Error("show repositories failed, %v", err)
+		return err
+	}
+	for _, r := range repos {
+		if r["RepoName"] == i.Params.Repository {
+			logger.Info("仓库 %s 已存在", i.Params.Repository)
+			return nil
+		}
+	}
+
+	if i.Params.BucketName == "" || i.Params.AccessKey == "" || i.Params.SecretKey == "" {
+		return errors.New("创建仓库需要bucket_name, access_key, secret_key")
+	}
+	endpoint := i.Params.Endpoint
+	// 若传参为空，会默认拼接COS内网域名，兼容其他S3 对象存储
+	if endpoint == "" {
+		endpoint = fmt.Sprintf(DefaultCosEndpoint, i.Params.Region)
+	}
+	readOnly := ""
+	if i.Params.ReadOnly {
+		readOnly = "READ ONLY "
+	}
+	location := fmt.Sprintf("s3://%s/%s", i.Params.BucketName, strings.Trim(i.Params.RootPath, "/"))
+	// 拼接 创建仓库SQL
+	createRepoSql := fmt.Sprintf(`CREATE %sREPOSITORY %s
+	WITH S3
+	ON LOCATION %s
+	PROPERTIES
+	(
+		"s3.endpoint" = %s,
+		"s3.region" = %s,
+		"s3.access_key" = %s,
+		"s3.secret_key" = %s,
+		"use_path_style" = "%t"
+	);`, readOnly, quoteName(i.Params.Repository), quoteString(location), quoteString(endpoint),
+		quoteString(i.Params.Region), quoteString(i.Params.AccessKey), quoteString(i.Params.SecretKey),
+		i.Params.UsePathStyle)
+	logger.Info("创建仓库 %s, location: %s, endpoint: %s", i.Params.Repository, location, endpoint)
+	if _, err = db.Exec(createRepoSql); err != nil {
+		logger.Error("create doris repository failed, %v", err)
+		return err
+	}
+	return nil
+}
+
+// BackupSnapshot 按库/表提交备份任务, 默认等待完成
+func (i *BackupRestoreService) BackupSnapshot() (err error) {
+	if i.Params.Database == "" {
+		return errors.New("database不能为空")
+	}
+	label := i.Params.Snapshot
+	if label == "" {
+		label = fmt.Sprintf("snapshot_%s_%s", i.Params.Database, time.Now().Format("20060102150405"))
+	}
+	if err = checkSnapshotName(label); err != nil {
+		return err
+	}
+	db, err := i.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	on := ""
+	if len(i.Params.Tables) > 0 {
+		var tables []string
+		for _, t := range i.Params.Tables {
+			tables = append(tables, quoteName(t))
+		}
+		on = fmt.Sprintf(" ON (%s)", strings.Join(tables, ", "))
+	}
+	backupSql := fmt.Sprintf(`BACKUP SNAPSHOT %s.%s TO %s%s PROPERTIES ("type" = "full", "timeout" = "%d");`,
+		quoteName(i.Params.Database), quoteName(label), quoteName(i.Params.Repository), on, i.timeout())
+	logger.Info("提交备份任务 [%s]", backupSql)
+	if _, err = db.Exec(backupSql); err != nil {
+		logger.Error("backup snapshot failed, %v", err)
+		return err
+	}
+	components.PrintOutputCtx(map[string]string{
+		"repository": i.Params.Repository,
+		"database":   i.Params.Database,
+		"snapshot":   label,
+	})
+	if i.Params.NoWait {
+		return nil
+	}
+	return i.waitJob(db, showBackupSql(i.Params.Database, label), "SnapshotName", label)
+}
+
+// CheckBackup 查询备份任务进度, 直到完成
+func (i *BackupRestoreService) CheckBackup() (err error) {
+	if i.Params.Database == "" || i.Params.Snapshot == "" {
+		return errors.New("database和snapshot不能为空")
+	}
+	if err = checkSnapshotName(i.Params.Snapshot); err != nil {
+		return err
+	}
+	db, err := i.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return i.waitJob(db, showBackupSql(i.Params.Database, i.Params.Snapshot), "SnapshotName", i.Params.Snapshot)
+}
+
+// showBackupSql 查询库中指定快照的备份任务
+func showBackupSql(database, label string) string {
+	return fmt.Sprintf("SHOW BACKUP FROM %s WHERE SnapshotName = %s", quoteName(database), quoteString(label))
+}
+
+// ListSnapshots 列出仓库中的快照, snapshot不为空时只查询该快照
+func (i *BackupRestoreService) ListSnapshots() (err error) {
+	if i.Params.Snapshot != "" {
+		if err = checkSnapshotName(i.Params.Snapshot); err != nil {
+			return err
+		}
+	}
+	db, err := i.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	snapshots, err := i.listSnapshots(db)
+	if err != nil {
+		return err
+	}
+	for _, s := range snapshots {
+		logger.Info("snapshot: %s, timestamp: %s, status: %s", s.Snapshot, s.Timestamp, s.Status)
+	}
+	components.PrintOutputCtx(snapshots)
+	return nil
+}
+
+// listSnapshots 按时间从新到旧返回快照列表
+func (i *BackupRestoreService) listSnapshots(db *sql.DB) ([]SnapshotInfo, error) {
+	showSql := fmt.Sprintf("SHOW SNAPSHOT ON %s", quoteName(i.Params.Repository))
+	if i.Params.Snapshot != "" {
+		showSql += fmt.Sprintf(" WHERE SNAPSHOT = %s", quoteString(i.Params.Snapshot))
+	}
+	rows, err := queryRows(db, showSql)
+	if err != nil {
+		logger.Error("show snapshot failed, %v", err)
+		return nil, err
+	}
+	var snapshots []SnapshotInfo
+	for _, r := range rows {
+		snapshots = append(snapshots, SnapshotInfo{
+			Snapshot:  r["Snapshot"],
+			Timestamp: r["Timestamp"],
+			Status:    r["Status"],
+		})
+	}
+	// Timestamp 格式为 2006-01-02-15-04-05, 可以直接按字符串排序
+	sort.Slice(snapshots, func(a, b int) bool {
+		return snapshots[a].Timestamp > snapshots[b].Timestamp
+	})
+	return snapshots, nil
+}
+
+// RestoreSnapshot 从仓库恢复到当前或其他集群, 仓库不存在时以只读方式创建
+func (i *BackupRestoreService) RestoreSnapshot() (err error) {
+	if i.Params.Snapshot != "" {
+		if err = checkSnapshotName(i.Params.Snapshot); err != nil {
+			return err
+		}
+	}
+	on, err := restoreOnClause(i.Params.Tables, i.Params.RenameTables)
+	if err != nil {
+		return err
+	}
+	db, err := i.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	i.Params.ReadOnly = true
+	if err = i.createRepository(db); err != nil {
+		return err
+	}
+
+	snapshots, err := i.listSnapshots(db)
+	if err != nil {
+		return err
+	}
+	var snapshot *SnapshotInfo
+	for k := range snapshots {
+		// 不指定快照名时, 只恢复属于该库的快照
+		if i.Params.Snapshot == "" && i.Params.Database != "" &&
+			!strings.HasPrefix(snapshots[k].Snapshot, fmt.Sprintf("snapshot_%s_", i.Params.Database)) {
+			continue
+		}
+		if snapshots[k].Status == "OK" {
+			snapshot = &snapshots[k]
+			break
+		}
+	}
+	if snapshot == nil {
+		return fmt.Errorf("仓库 %s 中没有可用的快照 %s", i.Params.Repository, i.Params.Snapshot)
+	}
+
+	targetDb := dorisutil.DefaultString(i.Params.TargetDatabase, i.Params.Database)
+	if targetDb == "" {
+		return errors.New("database和target_database不能同时为空")
+	}
+	if _, err = db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", quoteName(targetDb))); err != nil {
+		logger.Error("create database %s failed, %v", targetDb, err)
+		return err
+	}
+
+	props := []string{
+		fmt.Sprintf(`"backup_timestamp" = %s`, quoteString(snapshot.Timestamp)),
+		fmt.Sprintf(`"timeout" = "%d"`, i.timeout()),
+	}
+	if i.Params.ReplicationNum > 0 {
+		props = append(props, fmt.Sprintf(`"replication_num" = "%d"`, i.Params.ReplicationNum))
+	}
+	restoreSql := fmt.Sprintf("RESTORE SNAPSHOT %s.%s FROM %s%s PROPERTIES (%s);", quoteName(targetDb),
+		quoteName(snapshot.Snapshot), quoteName(i.Params.Repository), on, strings.Join(props, ", "))
+	logger.Info("提交恢复任务 [%s]", restoreSql)
+	if _, err = db.Exec(restoreSql); err != nil {
+		logger.Error("restore snapshot failed, %v", err)
+		return err
+	}
+	components.PrintOutputCtx(map[string]string{
+		"repository": i.Params.Repository,
+		"database":   targetDb,
+		"snapshot":   snapshot.Snapshot,
+	})
+	if i.Params.NoWait {
+		return nil
+	}
+	return i.waitJob(db, fmt.Sprintf("SHOW RESTORE FROM %s", quoteName(targetDb)), "Label", snapshot.Snapshot)
+}
+
+// restoreOnClause 拼接恢复的表, 重命名的表必须在tables中, 整库恢复时不支持重命名
+func restoreOnClause(tables []string, renameTables map[string]string) (string, error) {
+	inTables := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		inTables[t] = true
+	}
+	for t := range renameTables {
+		if !inTables[t] {
+			return "", fmt.Errorf("重命名的表 %s 不在tables中, 整库恢复时不支持重命名", t)
+		}
+	}
+	if len(tables) == 0 {
+		return "", nil
+	}
+	var items []string
+	for _, t := range tables {
+		if newName := renameTables[t]; newName != "" {
+			items = append(items, fmt.Sprintf("%s AS %s", quoteName(t), quoteName(newName)))
+		} else {
+			items = append(items, quoteName(t))
+		}
+	}
+	return fmt.Sprintf(" ON (%s)", strings.Join(items, ", ")), nil
+}
+
+// waitJob 轮询 SHOW BACKUP / SHOW RESTORE 直到任务完成或取消
+func (i *BackupRestoreService) waitJob(db *sql.DB, showSql string, labelColumn string, label string) error {
+	deadline := time.Now().Add(time.Duration(i.timeout()) * time.Second)
+	for time.Now().Before(deadline) {
+		rows, err := queryRows(db, showSql)
+		if err != nil {
+			logger.Error("[%s] failed, %v", showSql, err)
+			return err
+		}
+		// 同一个label可能有多次任务, 取最后一个
+		var job map[string]string
+		for _, r := range rows {
+			if r[labelColumn] == label {
+				job = r
+			}
+		}
+		if job == nil {
+			return fmt.Errorf("没有找到任务 %s", label)
+		}
+		switch job["State"] {
+		case BackupJobFinished:
+			logger.Info("任务 %s 完成", label)
+			return nil
+		case BackupJobCancelled:
+			return fmt.Errorf("任务 %s 已取消, TaskErrMsg: %s, Status: %s", label, job["TaskErrMsg"], job["Status"])
+		}
+		logger.Info("任务 %s 状态: %s, 进度: %s, 未完成: %s", label, job["State"], job["Progress"],
+			job["UnfinishedTasks"])
+		time.Sleep(BackupPollInterval)
+	}
+	return fmt.Errorf("任务 %s 超时", label)
+}
+
+func (i *BackupRestoreService) timeout() int {
+	if i.Params.Timeout <= 0 {
+		return DefaultBackupTimeout
+	}
+	return i.Params.Timeout
+}
+
+// quoteName 库表名加反引号
+func quoteName(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// quoteString 字符串常量加双引号, 转义反斜杠和引号
+func quoteString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `'`, `\'`).Replace(s) + `"`
+}
+
+// checkSnapshotName 快照名会拼接到SQL中, 只允许安全的字符
+func checkSnapshotName(name string) error {
+	if !snapshotNameRe.MatchString(name) {
+		return fmt.Errorf("snapshot [%s] 不合法, 只能包含字母、数字、下划线和中划线, 且以字母开头", name)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package doris
+
+import (
+	"testing"
+)
+
+func TestQuoteString(t *testing.T) {
+	tests := map[string]string{
+		"snapshot_db_20240101":  `"snapshot_db_20240101"`,
+		`a" OR "1"="1`:          `"a\" OR \"1\"=\"1"`,
+		`sk\'x`:                 `"sk\\\'x"`,
+		"2024-01-01-00-00-00-1": `"2024-01-01-00-00-00-1"`,
+	}
+	for in, want := range tests {
+		if got := quoteString(in); got != want {
+			t.Errorf("quoteString(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestCheckSnapshotName(t *testing.T) {
+	for _, name := range []string{"snapshot_db_20240101150405", "daily-backup"} {
+		if err := checkSnapshotName(name); err != nil {
+			t.Errorf("%s should be valid, %v", name, err)
+		}
+	}
+	for _, name := range []string{"", `x" OR SnapshotName != "`, "1snapshot", "snap shot", "snap`shot"} {
+		if err := checkSnapshotName(name); err == nil {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestShowBackupSql(t *testing.T) {
+	want := "SHOW BACKUP FROM `db` WHERE SnapshotName = \"snapshot_db_1\""
+	if got := showBackupSql("db", "snapshot_db_1"); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestRestoreOnClause(t *testing.T) {
+	on, err := restoreOnClause([]string{"t1", "t2"}, map[string]string{"t1": "t1_new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := " ON (`t1` AS `t1_new`, `t2`)"; on != want {
+		t.Fatalf("got %s, want %s", on, want)
+	}
+	if on, err = restoreOnClause(nil, nil); err != nil || on != "" {
+		t.Fatalf("whole database restore got %q, %v", on, err)
+	}
+	if _, err = restoreOnClause(nil, map[string]string{"t1": "t1_new"}); err == nil {
+		t.Fatal("rename without tables should fail")
+	}
+	if _, err = restoreOnClause([]string{"t2"}, map[string]string{"t1": "t1_new"}); err == nil {
+		t.Fatal("rename table not in tables should fail")
+	}
+}
@@ -52,6 +52,7 @@ type InstallEsParams struct {
 	BkBizID        int             `json:"bk_biz_id"`
 	DbType         string          `json:"db_type"`
 	ServiceType    string          `json:"service_type"`
+	PathRepo       string          `json:"path_repo"` // 快照仓库目录, eg: /data/esbackup,/mnt/nfs
 }
 
 // InitDirs TODO
@@ -92,6 +93,7 @@ type ESYaml struct {
 	ClusterRoutingAllocationSameShardHost bool   `yaml:"cluster.routing.allocation.same_shard.host"`
 	DiscoveryZenPingUnicastHosts          string `yaml:"discovery.zen.ping.unicast.hosts"` // 兼容5.4
 	NodeRoles                             string `yaml:"node.roles"`                       // 8.0参数
+	PathRepo                              string `yaml:"path.repo"`                        // 快照仓库目录
 }
 
 // RenderConfig 需要替换的配置值 Todo
@@ -403,6 +405,7 @@ func (i *InstallEsComp) InstallEsBase(role string, instances int) error {
 			ClusterRoutingAllocationSameShardHost: true,
 			DiscoveryZenPingUnicastHosts:          masterIP,
 			NodeRoles:                             roleSet,
+			PathRepo:                              i.Params.PathRepo,
 		}
 
 		var buf bytes.Buffer
@@ -472,6 +475,13 @@ func (i *InstallEsComp) InstallEsBase(role string, instances int) error {
 			}
 		}
 
+		// path.repo格式: /a,/b -> [/a,/b], 模板中没有path.repo时追加
+		if i.Params.PathRepo != "" {
+			if err = i.configPathRepo(esYamlFile); err != nil {
+				return err
+			}
+		}
+
 		logger.Info("生成jvm参数")
 		heapSize, err := esutil.GetInstHeapByIP(uint64(instances))
 		if err != nil {
@@ -779,3 +789,27 @@ stdout_logfile_backups=10 ; # of stdout logfile backups (default 10)`)
 	_ = esutil.SupervisorctlUpdate()
 	return nil
 }
+
+// configPathRepo 创建快照仓库目录并写入path.repo
+func (i *InstallEsComp) configPathRepo(esYamlFile string) error {
+	var dirs []string
+	for _, d := range strings.Split(i.Params.PathRepo, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			dirs = append(dirs, d)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	extraCmd := fmt.Sprintf(`mkdir -p %s; chown -R mysql %s`, strings.Join(dirs, " "), strings.Join(dirs, " "))
+	logger.Info("Doing create dir [%s]", extraCmd)
+	if _, err := osutil.ExecShellCommand(false, extraCmd); err != nil {
+		logger.Error("Command [%s] failed, message: [%s]", extraCmd, err)
+		return err
+	}
+	if _, err := esutil.SetYamlSetting(esYamlFile, "path.repo", fmt.Sprintf("[%s]", strings.Join(dirs, ","))); err != nil {
+		logger.Error("write path.repo to %s failed, %v", esYamlFile, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,372 @@
+package elasticsearch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/components"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/core/cst"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/esutil"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/osutil"
+	"dbm-services/common/go-pubpkg/logger"
+
+	"github.com/hashicorp/go-version"
+)
+
+const (
+	// RollingRetryTimes 滚动重启时等待节点加入和集群恢复的检查次数
+	RollingRetryTimes = 360
+	// RollingWaitInterval 滚动重启时的检查间隔
+	RollingWaitInterval = 10 * time.Second
+)
+
+// RollingEsComp 滚动重启与滚动升级, 逐个处理本机上的实例, 集群层面由调用方逐台机器执行
+type RollingEsComp struct {
+	GeneralParam    *components.GeneralParam
+	Params          *RollingEsParams
+	RollBackContext rollback.RollBackObjects
+	instances       []*rollingInstance
+}
+
+// RollingEsParams 滚动重启参数
+type RollingEsParams struct {
+	Host       string   `json:"host" validate:"required,ip"`
+	Username   string   `json:"username"`
+	Password   string   `json:"password"`
+	EsVersion  string   `json:"es_version"`  // 升级的目标版本, 仅rolling_upgrade需要
+	Nodes      []string `json:"nodes"`       // 需要处理的节点名, eg: hot-127.0.0.1_1, 为空时处理本机所有实例
+	WaitStatus string   `json:"wait_status"` // 每个节点处理完后等待的集群状态, 默认green. 升级过程中新版本节点上的主分片无法在旧版本节点分配副本, 可以指定yellow
+}
+
+// rollingInstance 本机的一个es实例, /data/esenv/es_{seq}
+type rollingInstance struct {
+	Seq      int
+	NodeName string
+	HTTPPort int
+	Link     string // /data/esenv/es_1
+	BaseDir  string // es_1 指向的目录
+}
+
+func (r *rollingInstance) process() string {
+	return fmt.Sprintf("elasticsearch%d", r.Seq)
+}
+
+// baseDir es_{seq} 指向目录的绝对路径
+func (r *rollingInstance) baseDir() string {
+	if filepath.IsAbs(r.BaseDir) {
+		return r.BaseDir
+	}
+	return filepath.Join(cst.DefaulEsEnv, r.BaseDir)
+}
+
+// Init 读取本机实例信息
+func (d *RollingEsComp) Init() (err error) {
+	if d.Params.WaitStatus == "" {
+		d.Params.WaitStatus = "green"
+	}
+	links, err := filepath.Glob(fmt.Sprintf("%s/es_*", cst.DefaulEsEnv))
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		seq, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(link), "es_"))
+		if err != nil {
+			continue
+		}
+		yamlFile := fmt.Sprintf("%s/config/elasticsearch.yml", link)
+		nodeName, err := esutil.GetYamlSetting(yamlFile, "node.name")
+		if err != nil {
+			logger.Error("读取 %s 失败, %v", yamlFile, err)
+			return err
+		}
+		if len(d.Params.Nodes) != 0 && !containsStr(d.Params.Nodes, nodeName) {
+			continue
+		}
+		port, err := esutil.GetYamlSetting(yamlFile, "http.port")
+		if err != nil {
+			return err
+		}
+		httpPort, err := strconv.Atoi(port)
+		if err != nil {
+			return fmt.Errorf("%s http.port [%s] 不合法", yamlFile, port)
+		}
+		baseDir, err := os.Readlink(link)
+		if err != nil {
+			logger.Error("readlink %s failed, %v", link, err)
+			return err
+		}
+		d.instances = append(d.instances, &rollingInstance{
+			Seq:      seq,
+			NodeName: nodeName,
+			HTTPPort: httpPort,
+			Link:     link,
+			BaseDir:  baseDir,
+		})
+	}
+	if len(d.instances) == 0 {
+		return fmt.Errorf("本机没有需要处理的实例, nodes: %v", d.Params.Nodes)
+	}
+	sort.Slice(d.instances, func(i, j int) bool {
+		return d.instances[i].Seq < d.instances[j].Seq
+	})
+	return nil
+}
+
+func containsStr(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *RollingEsComp) esIns(ins *rollingInstance) esutil.EsInsObject {
+	username, password := esutil.GetCredentials(d.Params.Username, d.Params.Password)
+	return esutil.EsInsObject{
+		Host:     d.Params.Host,
+		HTTPPort: ins.HTTPPort,
+		UserName: username,
+		Password: password,
+	}
+}
+
+// RollingRestart 逐个实例重启
+func (d *RollingEsComp) RollingRestart() (err error) {
+	for _, ins := range d.instances {
+		if err = d.rollingOne(ins, nil); err != nil {
+			return err
+		}
+	}
+	logger.Info("滚动重启完成")
+	return nil
+}
+
+// DecompressPkg 解压新版本安装包, 解压会删除es_1软链, 需要恢复
+func (d *RollingEsComp) DecompressPkg() (err error) {
+	if d.Params.EsVersion == "" {
+		return errors.New("es_version不能为空")
+	}
+	oldLinks := make(map[string]string)
+	links, _ := filepath.Glob(fmt.Sprintf("%s/es_*", cst.DefaulEsEnv))
+	for _, link := range links {
+		if target, err := os.Readlink(link); err == nil {
+			oldLinks[link] = target
+		}
+	}
+
+	i := &InstallEsComp{Params: &InstallEsParams{EsVersion: d.Params.EsVersion}}
+	if err = i.InitDefaultParam(); err != nil {
+		return err
+	}
+	if err = i.DecompressEsPkg(); err != nil {
+		logger.Error("解压安装包失败, %v", err)
+		return err
+	}
+
+	for link, target := range oldLinks {
+		if _, err := os.Lstat(link); err == nil {
+			continue
+		}
+		extraCmd := fmt.Sprintf("ln -sfn %s %s", target, link)
+		logger.Info("恢复软链 [%s]", extraCmd)
+		if _, err = osutil.ExecShellCommand(false, extraCmd); err != nil {
+			logger.Error("[%s] execute failed, %v", extraCmd, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// RollingUpgrade 逐个实例升级版本, 已经是目标版本的实例跳过, 可以重入
+func (d *RollingEsComp) RollingUpgrade() (err error) {
+	target, err := version.NewVersion(d.Params.EsVersion)
+	if err != nil {
+		return fmt.Errorf("es_version [%s] 不合法, %w", d.Params.EsVersion, err)
+	}
+	for _, ins := range d.instances {
+		cur, err := d.esIns(ins).GetVersion()
+		if err != nil {
+			logger.Error("获取 %s 版本失败, %v", ins.NodeName, err)
+			return err
+		}
+		if cur.Equal(target) {
+			logger.Info("%s 已经是 %s, 跳过", ins.NodeName, target)
+			continue
+		}
+		if cur.GreaterThan(target) {
+			return fmt.Errorf("%s 当前版本 %s 高于目标版本 %s, 不支持降级", ins.NodeName, cur, target)
+		}
+		// 停止实例前检查插件, 避免升级后无法启动
+		if _, err = d.extraPlugins(ins); err != nil {
+			return err
+		}
+		if err = d.rollingOne(ins, d.swapPkg); err != nil {
+			return err
+		}
+	}
+	logger.Info("滚动升级到 %s 完成", target)
+	return nil
+}
+
+func (d *RollingEsComp) newBase() string {
+	return fmt.Sprintf("%s/elasticsearch-%s", cst.DefaulEsEnv, d.Params.EsVersion)
+}
+
+// extraPlugins 返回原实例安装了、新版本安装包中没有的插件, 这些插件需要复制到新目录
+// 新版本安装包自带的插件使用新版本的; 需要复制的插件必须已经适配目标版本, 否则es无法启动
+func (d *RollingEsComp) extraPlugins(ins *rollingInstance) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(ins.baseDir(), "plugins"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var plugins []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err = os.Stat(filepath.Join(d.newBase(), "plugins", e.Name())); err == nil {
+			continue
+		}
+		pluginDir := filepath.Join(ins.baseDir(), "plugins", e.Name())
+		esVersion, err := esutil.GetPluginEsVersion(pluginDir)
+		if err != nil {
+			logger.Error("读取插件 %s 版本失败, %v", pluginDir, err)
+			return nil, err
+		}
+		if esVersion != d.Params.EsVersion {
+			return nil, fmt.Errorf("%s 插件 %s 适配的版本为 %s, 不是目标版本 %s, 请先在安装包中加入适配的插件",
+				ins.NodeName, e.Name(), esVersion, d.Params.EsVersion)
+		}
+		plugins = append(plugins, e.Name())
+	}
+	return plugins, nil
+}
+
+// swapPkg 复制新版本目录, 保留原实例的config和新版本中没有的插件, 并切换es_{seq}软链
+func (d *RollingEsComp) swapPkg(ins *rollingInstance) error {
+	newBase := d.newBase()
+	newBaseIns := fmt.Sprintf("%s_%d", newBase, ins.Seq)
+	oldBaseIns := ins.baseDir()
+	plugins, err := d.extraPlugins(ins)
+	if err != nil {
+		return err
+	}
+	cmds := []string{
+		fmt.Sprintf("rm -rf %s", newBaseIns),
+		fmt.Sprintf("cp -a %s %s", newBase, newBaseIns),
+		fmt.Sprintf("cp -a %s/config/. %s/config/", oldBaseIns, newBaseIns),
+		fmt.Sprintf("mkdir -p %s/plugins", newBaseIns),
+	}
+	for _, p := range plugins {
+		cmds = append(cmds, fmt.Sprintf("cp -a %s/plugins/%s %s/plugins/", oldBaseIns, p, newBaseIns))
+	}
+	cmds = append(cmds,
+		fmt.Sprintf("ln -sfn %s %s", newBaseIns, ins.Link),
+		fmt.Sprintf("chown -R %s %s", cst.DefaultExecUser, newBaseIns),
+	)
+	for _, extraCmd := range cmds {
+		logger.Info("Exec command [%s]", extraCmd)
+		if output, err := osutil.ExecShellCommand(false, extraCmd); err != nil {
+			logger.Error("[%s] execute failed, %s, %v", extraCmd, output, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// rollingOne 处理一个实例: 关闭副本分配 -> flush -> 停止 -> (升级) -> 启动 -> 等待加入集群 -> 恢复分配 -> 等待集群状态
+func (d *RollingEsComp) rollingOne(ins *rollingInstance, upgrade func(*rollingInstance) error) (err error) {
+	e := d.esIns(ins)
+	logger.Info("开始处理 %s", ins.NodeName)
+
+	if err = e.SetAllocation(esutil.AllocationPrimaries); err != nil {
+		logger.Error("关闭副本分配失败, %v", err)
+		return err
+	}
+	ver, err := e.GetVersion()
+	if err != nil {
+		logger.Error("获取版本失败, %v", err)
+		return err
+	}
+	// synced flush 在7.6废弃, 8.0移除; 部分shard失败(409)不影响重启
+	v76, _ := version.NewVersion("7.6")
+	if err = e.Flush(ver.LessThan(v76)); err != nil {
+		logger.Warn("flush失败, 重启后恢复会变慢, %v", err)
+	}
+
+	extraCmd := fmt.Sprintf("supervisorctl stop %s", ins.process())
+	logger.Info("停止进程, [%s]", extraCmd)
+	if _, err = osutil.ExecShellCommand(false, extraCmd); err != nil {
+		logger.Error("[%s] execute failed, %v", extraCmd, err)
+		return err
+	}
+
+	if upgrade != nil {
+		if err = upgrade(ins); err != nil {
+			return err
+		}
+	}
+
+	extraCmd = fmt.Sprintf("supervisorctl start %s", ins.process())
+	logger.Info("启动进程, [%s]", extraCmd)
+	if _, err = osutil.ExecShellCommand(false, extraCmd); err != nil {
+		logger.Error("[%s] execute failed, %v", extraCmd, err)
+		return err
+	}
+
+	if err = d.waitJoined(e, ins); err != nil {
+		return err
+	}
+	// 需要先恢复分配, 否则重启节点上的副本无法恢复, 集群不会变成green
+	if err = e.SetAllocation(esutil.AllocationAll); err != nil {
+		logger.Error("恢复分片分配失败, %v", err)
+		return err
+	}
+	if err = d.waitStatus(e); err != nil {
+		return err
+	}
+	logger.Info("%s 处理完成", ins.NodeName)
+	return nil
+}
+
+func (d *RollingEsComp) waitJoined(e esutil.EsInsObject, ins *rollingInstance) error {
+	for i := 0; i < RollingRetryTimes; i++ {
+		time.Sleep(RollingWaitInterval)
+		joined, err := e.NodeJoined(ins.NodeName)
+		if err == nil && joined {
+			logger.Info("%s 已加入集群", ins.NodeName)
+			return nil
+		}
+		logger.Info("等待 %s 加入集群, %v", ins.NodeName, err)
+	}
+	return fmt.Errorf("经过%d次检查后，%s 仍未加入集群", RollingRetryTimes, ins.NodeName)
+}
+
+func (d *RollingEsComp) waitStatus(e esutil.EsInsObject) error {
+	for i := 0; i < RollingRetryTimes; i++ {
+		health, err := e.GetHealth(d.Params.WaitStatus, "30s")
+		if err == nil && !health.TimedOut {
+			logger.Info("集群状态 %s", health.Status)
+			return nil
+		}
+		if err != nil {
+			logger.Warn("获取集群状态失败, %v", err)
+			time.Sleep(RollingWaitInterval)
+			continue
+		}
+		logger.Info("等待集群变为%s, 当前 %s, initializing: %d, unassigned: %d", d.Params.WaitStatus,
+			health.Status, health.InitializingShards, health.UnassignedShards)
+	}
+	return fmt.Errorf("经过%d次检查后，集群仍未恢复到%s", RollingRetryTimes, d.Params.WaitStatus)
+}
@@ -0,0 +1,420 @@
+package elasticsearch
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/components"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/core/cst"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/esutil"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/osutil"
+	"dbm-services/common/go-pubpkg/logger"
+
+	"github.com/hashicorp/go-version"
+)
+
+const (
+	// RepoTypeFs 共享文件系统仓库, 所有节点需要挂载相同的目录
+	RepoTypeFs = "fs"
+	// RepoTypeS3 S3兼容的对象存储仓库, 如cos
+	RepoTypeS3 = "s3"
+	// DefaultSnapshotPrefix 默认快照名前缀
+	DefaultSnapshotPrefix = "snapshot"
+	// DefaultS3Client 默认s3 client名
+	DefaultS3Client = "default"
+	// SnapshotWaitInterval 等待快照完成的检查间隔
+	SnapshotWaitInterval = 10 * time.Second
+)
+
+// EsSnapshotComp 快照仓库注册, 备份与恢复
+type EsSnapshotComp struct {
+	GeneralParam    *components.GeneralParam
+	Params          *EsSnapshotParams
+	RollBackContext rollback.RollBackObjects
+}
+
+// EsSnapshotParams 快照相关参数, 不同的子命令使用其中一部分
+type EsSnapshotParams struct {
+	Host     string `json:"host" validate:"required,ip"`
+	HTTPPort int    `json:"http_port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	Repository string `json:"repository" validate:"required"` // 仓库名
+	RepoType   string `json:"repo_type"`                      // fs / s3
+	Location   string `json:"location"`                       // fs仓库目录, 需在path.repo中
+	Compress   bool   `json:"compress"`
+	ReadOnly   bool   `json:"readonly"` // 恢复到其他集群时以只读方式注册, 避免多个集群同时写入
+	S3         S3Repo `json:"s3"`
+
+	Snapshot       string   `json:"snapshot"`        // 快照名, 为空时使用 prefix-时间
+	SnapshotPrefix string   `json:"snapshot_prefix"` // 快照名前缀
+	Indices        []string `json:"indices"`         // index pattern, eg: log-*, 为空时备份全部
+
+	PolicyID      string `json:"policy_id"`      // SLM策略名
+	Schedule      string `json:"schedule"`       // cron表达式, eg: 0 30 1 * * ?
+	RetentionDays int    `json:"retention_days"` // 保留天数
+	MinCount      int    `json:"min_count"`      // 至少保留个数
+	MaxCount      int    `json:"max_count"`      // 最多保留个数
+
+	RenamePattern     string `json:"rename_pattern"`     // 恢复时重命名, eg: (.+)
+	RenameReplacement string `json:"rename_replacement"` // eg: restored_$1
+}
+
+// S3Repo S3兼容仓库配置, access_key与secret_key写入keystore
+type S3Repo struct {
+	Bucket          string `json:"bucket"`
+	BasePath        string `json:"base_path"`
+	Client          string `json:"client"`
+	Endpoint        string `json:"endpoint"`
+	Protocol        string `json:"protocol"`
+	PathStyleAccess bool   `json:"path_style_access"`
+	AccessKey       string `json:"access_key"`
+	SecretKey       string `json:"secret_key"`
+}
+
+// Init 初始化, 填充默认值
+func (d *EsSnapshotComp) Init() (err error) {
+	if d.Params.HTTPPort == 0 {
+		d.Params.HTTPPort = cst.DefaultHttpPort
+	}
+	if d.Params.RepoType == "" {
+		d.Params.RepoType = RepoTypeFs
+	}
+	if d.Params.RepoType != RepoTypeFs && d.Params.RepoType != RepoTypeS3 {
+		return fmt.Errorf("不支持的仓库类型 %s", d.Params.RepoType)
+	}
+	if d.Params.S3.Client == "" {
+		d.Params.S3.Client = DefaultS3Client
+	}
+	if d.Params.SnapshotPrefix == "" {
+		d.Params.SnapshotPrefix = DefaultSnapshotPrefix
+	}
+	if d.Params.PolicyID == "" {
+		d.Params.PolicyID = d.Params.Repository + "-" + d.Params.SnapshotPrefix
+	}
+	if d.Params.MinCount <= 0 {
+		d.Params.MinCount = 1
+	}
+	return nil
+}
+
+func (d *EsSnapshotComp) esIns() esutil.EsInsObject {
+	username, password := esutil.GetCredentials(d.Params.Username, d.Params.Password)
+	return esutil.EsInsObject{
+		Host:     d.Params.Host,
+		HTTPPort: d.Params.HTTPPort,
+		UserName: username,
+		Password: password,
+	}
+}
+
+func (d *EsSnapshotComp) repo() esutil.SnapshotRepo {
+	if d.Params.RepoType == RepoTypeS3 {
+		settings := map[string]interface{}{
+			"bucket": d.Params.S3.Bucket,
+			"client": d.Params.S3.Client,
+		}
+		if d.Params.S3.BasePath != "" {
+			settings["base_path"] = d.Params.S3.BasePath
+		}
+		if d.Params.ReadOnly {
+			settings["readonly"] = true
+		}
+		return esutil.SnapshotRepo{Type: RepoTypeS3, Settings: settings}
+	}
+	settings := map[string]interface{}{
+		"location": d.Params.Location,
+		"compress": d.Params.Compress,
+	}
+	if d.Params.ReadOnly {
+		settings["readonly"] = true
+	}
+	return esutil.SnapshotRepo{Type: RepoTypeFs, Settings: settings}
+}
+
+// ConfigRepo 在本机所有实例上配置仓库, 每个节点都需要执行
+// fs: 创建目录并写入path.repo; s3: 写入keystore和client配置
+func (d *EsSnapshotComp) ConfigRepo() (err error) {
+	yamlFiles, err := filepath.Glob(fmt.Sprintf("%s/es_*/config/elasticsearch.yml", cst.DefaulEsEnv))
+	if err != nil {
+		return err
+	}
+	if len(yamlFiles) == 0 {
+		return fmt.Errorf("本机没有找到es实例, %s/es_*", cst.DefaulEsEnv)
+	}
+
+	needRestart := false
+	switch d.Params.RepoType {
+	case RepoTypeFs:
+		if d.Params.Location == "" {
+			return errors.New("fs仓库location不能为空")
+		}
+		extraCmd := fmt.Sprintf("mkdir -p %s; chown -R %s %s", d.Params.Location, cst.DefaultExecUser,
+			d.Params.Location)
+		logger.Info("创建仓库目录 [%s]", extraCmd)
+		if _, err = osutil.ExecShellCommand(false, extraCmd); err != nil {
+			logger.Error("[%s] execute failed, %v", extraCmd, err)
+			return err
+		}
+		for _, f := range yamlFiles {
+			changed, err := esutil.AddPathRepo(f, []string{d.Params.Location})
+			if err != nil {
+				logger.Error("写入path.repo失败, %s, %v", f, err)
+				return err
+			}
+			needRestart = needRestart || changed
+		}
+	case RepoTypeS3:
+		if needRestart, err = d.configS3(yamlFiles); err != nil {
+			return err
+		}
+	}
+
+	if needRestart {
+		logger.Warn("elasticsearch.yml已修改, 需要重启实例后才能注册仓库")
+		return nil
+	}
+	if d.Params.RepoType == RepoTypeS3 {
+		if err = d.esIns().ReloadSecureSettings(); err != nil {
+			logger.Warn("reload_secure_settings失败, 需要重启实例, %v", err)
+		}
+	}
+	logger.Info("仓库配置完成")
+	return nil
+}
+
+func (d *EsSnapshotComp) configS3(yamlFiles []string) (needRestart bool, err error) {
+	s3 := d.Params.S3
+	if s3.Bucket == "" {
+		return false, errors.New("s3仓库bucket不能为空")
+	}
+	prefix := fmt.Sprintf("s3.client.%s.", s3.Client)
+	settings := map[string]string{}
+	if s3.Endpoint != "" {
+		settings[prefix+"endpoint"] = s3.Endpoint
+	}
+	if s3.Protocol != "" {
+		settings[prefix+"protocol"] = s3.Protocol
+	}
+	if s3.PathStyleAccess {
+		settings[prefix+"path_style_access"] = "true"
+	}
+
+	for _, f := range yamlFiles {
+		esDir := filepath.Dir(filepath.Dir(f))
+		if s3.AccessKey != "" {
+			for key, value := range map[string]string{"access_key": s3.AccessKey, "secret_key": s3.SecretKey} {
+				if err = esutil.AddKeystoreSetting(esDir, prefix+key, value); err != nil {
+					logger.Error("写入keystore %s%s 失败, %v", prefix, key, err)
+					return false, err
+				}
+			}
+		}
+		for key, value := range settings {
+			changed, err := esutil.SetYamlSetting(f, key, value)
+			if err != nil {
+				logger.Error("写入%s失败, %s, %v", key, f, err)
+				return false, err
+			}
+			needRestart = needRestart || changed
+		}
+	}
+
+	extraCmd := fmt.Sprintf("chown -R %s %s", cst.DefaultExecUser, cst.DefaulEsEnv)
+	if _, err = osutil.ExecShellCommand(false, extraCmd); err != nil {
+		logger.Error("exec [%s] failed, %v", extraCmd, err)
+		return false, err
+	}
+	return needRestart, nil
+}
+
+// RegisterRepo 注册快照仓库, 集群维度执行一次
+func (d *EsSnapshotComp) RegisterRepo() (err error) {
+	if err = d.esIns().PutSnapshotRepo(d.Params.Repository, d.repo()); err != nil {
+		logger.Error("注册快照仓库失败, %v", err)
+		return err
+	}
+	return nil
+}
+
+// CreateSnapshot 按index pattern创建快照并等待完成
+func (d *EsSnapshotComp) CreateSnapshot() (err error) {
+	e := d.esIns()
+	name := d.Params.Snapshot
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", d.Params.SnapshotPrefix, time.Now().Format("20060102150405"))
+	}
+	// 快照名只允许小写
+	name = strings.ToLower(name)
+	req := esutil.SnapshotRequest{
+		Indices:            esutil.JoinIndices(d.Params.Indices),
+		IgnoreUnavailable:  true,
+		IncludeGlobalState: false,
+	}
+	if err = e.CreateSnapshot(d.Params.Repository, name, req); err != nil {
+		logger.Error("创建快照失败, %v", err)
+		return err
+	}
+
+	for i := 0; i < RetryTimes; i++ {
+		snapshots, err := e.GetSnapshots(d.Params.Repository, name)
+		if err != nil {
+			logger.Error("查询快照状态失败, %v", err)
+			return err
+		}
+		if len(snapshots) == 0 {
+			return fmt.Errorf("快照 %s 不存在", name)
+		}
+		s := snapshots[0]
+		switch s.State {
+		case "SUCCESS":
+			logger.Info("快照 %s 完成, indices: %d", name, len(s.Indices))
+			components.PrintOutputCtx(map[string]interface{}{
+				"repository": d.Params.Repository,
+				"snapshot":   name,
+				"indices":    s.Indices,
+			})
+			return nil
+		case "PARTIAL", "FAILED":
+			return fmt.Errorf("快照 %s 状态 %s, failures: %v", name, s.State, s.Failures)
+		}
+		logger.Info("快照 %s 进行中", name)
+		time.Sleep(SnapshotWaitInterval)
+	}
+	return fmt.Errorf("经过%d次检查后，快照 %s 仍未完成", RetryTimes, name)
+}
+
+// PutSnapshotPolicy 创建SLM策略定时备份, 需要7.5及以上版本
+func (d *EsSnapshotComp) PutSnapshotPolicy() (err error) {
+	if d.Params.Schedule == "" {
+		return errors.New("schedule不能为空")
+	}
+	e := d.esIns()
+	ver, err := e.GetVersion()
+	if err != nil {
+		logger.Error("获取es版本失败, %v", err)
+		return err
+	}
+	minVer, _ := version.NewVersion("7.5")
+	if ver.LessThan(minVer) {
+		return fmt.Errorf("es %s 不支持SLM, 请使用crontab调用create_snapshot和prune_snapshot", ver)
+	}
+
+	policy := esutil.SnapshotPolicy{
+		Schedule:   d.Params.Schedule,
+		Name:       fmt.Sprintf("<%s-{now{yyyy.MM.dd-HH.mm}}>", strings.ToLower(d.Params.SnapshotPrefix)),
+		Repository: d.Params.Repository,
+		Config: esutil.SnapshotRequest{
+			Indices:            esutil.JoinIndices(d.Params.Indices),
+			IgnoreUnavailable:  true,
+			IncludeGlobalState: false,
+		},
+	}
+	retention := map[string]interface{}{}
+	if d.Params.RetentionDays > 0 {
+		retention["expire_after"] = fmt.Sprintf("%dd", d.Params.RetentionDays)
+		retention["min_count"] = d.Params.MinCount
+	}
+	if d.Params.MaxCount > 0 {
+		retention["max_count"] = d.Params.MaxCount
+	}
+	if len(retention) > 0 {
+		policy.Retention = retention
+	}
+	if err = e.PutSnapshotPolicy(d.Params.PolicyID, policy); err != nil {
+		logger.Error("创建快照策略失败, %v", err)
+		return err
+	}
+	return nil
+}
+
+// PruneSnapshots 按保留天数和保留个数清理前缀匹配的快照, 至少保留min_count个成功的快照
+func (d *EsSnapshotComp) PruneSnapshots() (err error) {
+	if d.Params.RetentionDays <= 0 && d.Params.MaxCount <= 0 {
+		return errors.New("retention_days和max_count不能同时为空")
+	}
+	e := d.esIns()
+	snapshots, err := e.GetSnapshots(d.Params.Repository, strings.ToLower(d.Params.SnapshotPrefix)+"*")
+	if err != nil {
+		logger.Error("获取快照列表失败, %v", err)
+		return err
+	}
+
+	expireTime := time.Now().AddDate(0, 0, -d.Params.RetentionDays)
+	kept := 0
+	var deleted []string
+	// snapshots按时间从新到旧
+	for _, s := range snapshots {
+		if s.State == "IN_PROGRESS" {
+			continue
+		}
+		keep := kept < d.Params.MinCount && s.State == "SUCCESS"
+		if !keep {
+			expired := d.Params.RetentionDays > 0 && s.StartTime().Before(expireTime)
+			exceeded := d.Params.MaxCount > 0 && kept >= d.Params.MaxCount
+			keep = !expired && !exceeded
+		}
+		if keep {
+			kept++
+			continue
+		}
+		if err = e.DeleteSnapshot(d.Params.Repository, s.Snapshot); err != nil {
+			logger.Error("删除快照 %s 失败, %v", s.Snapshot, err)
+			return err
+		}
+		deleted = append(deleted, s.Snapshot)
+	}
+	logger.Info("清理快照完成, 保留 %d 个, 删除 %d 个: %v", kept, len(deleted), deleted)
+	return nil
+}
+
+// RestoreSnapshot 恢复快照中的索引, 可以是当前集群或其他集群
+// 目标集群没有注册仓库时, 以只读方式注册
+func (d *EsSnapshotComp) RestoreSnapshot() (err error) {
+	e := d.esIns()
+	if !e.SnapshotRepoExists(d.Params.Repository) {
+		logger.Info("目标集群没有仓库 %s, 以只读方式注册", d.Params.Repository)
+		d.Params.ReadOnly = true
+		if err = e.PutSnapshotRepo(d.Params.Repository, d.repo()); err != nil {
+			logger.Error("注册快照仓库失败, %v", err)
+			return err
+		}
+	}
+
+	name := d.Params.Snapshot
+	if name == "" {
+		snapshots, err := e.GetSnapshots(d.Params.Repository, strings.ToLower(d.Params.SnapshotPrefix)+"*")
+		if err != nil {
+			logger.Error("获取快照列表失败, %v", err)
+			return err
+		}
+		for _, s := range snapshots {
+			if s.State == "SUCCESS" {
+				name = s.Snapshot
+				break
+			}
+		}
+		if name == "" {
+			return fmt.Errorf("仓库 %s 中没有前缀为 %s 的可用快照", d.Params.Repository, d.Params.SnapshotPrefix)
+		}
+		logger.Info("使用最新的快照 %s", name)
+	}
+
+	req := esutil.RestoreRequest{
+		Indices:            esutil.JoinIndices(d.Params.Indices),
+		IgnoreUnavailable:  true,
+		IncludeGlobalState: false,
+		RenamePattern:      d.Params.RenamePattern,
+		RenameReplacement:  d.Params.RenameReplacement,
+	}
+	if err = e.RestoreSnapshot(d.Params.Repository, name, req); err != nil {
+		logger.Error("恢复快照失败, %v", err)
+		return err
+	}
+	return nil
+}
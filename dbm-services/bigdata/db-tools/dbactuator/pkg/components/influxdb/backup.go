@@ -0,0 +1,365 @@
+package influxdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/components"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/core/cst"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/osutil"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/s3util"
+	"dbm-services/common/go-pubpkg/logger"
+)
+
+const (
+	// BackupTypeFull 全量备份
+	BackupTypeFull = "full"
+	// BackupTypeIncremental 增量备份, 备份上一个备份结束时间之后写入的数据
+	BackupTypeIncremental = "incremental"
+	// BackupInfoFile 备份信息文件
+	BackupInfoFile = "backup_info.json"
+)
+
+// BackupComp influxdb 备份恢复
+type BackupComp struct {
+	GeneralParam    *components.GeneralParam
+	Params          *BackupParams
+	RollBackContext rollback.RollBackObjects
+	target          *s3util.BackupTarget
+}
+
+// BackupParams 备份恢复参数, 备份存放在 backup_dir/{host}/{id} 或 s3://bucket/base_path/{host}/{id}
+type BackupParams struct {
+	Host          string         `json:"host" validate:"required,ip"`
+	Port          int            `json:"port"`     // http端口, 恢复增量时通过influx合并数据
+	RPCAddr       string         `json:"rpc_addr"` // influxd backup/restore 连接的rpc地址
+	Username      string         `json:"username"`
+	Password      string         `json:"password"`
+	Database      string         `json:"database"`    // 为空时备份全部库, 不支持增量
+	BackupDir     string         `json:"backup_dir"`  // 本地备份目录, 备份到S3时作为临时目录
+	S3            *s3util.Config `json:"s3"`          // 不为空时备份到对象存储
+	Incremental   bool           `json:"incremental"` // 增量备份, 没有可用的全量时自动做全量
+	BackupID      string         `json:"backup_id"`   // 恢复使用的备份, 为空时使用最新的备份
+	NewDatabase   string         `json:"new_database"`
+	RetentionDays int            `json:"retention_days"`
+	MinKeep       int            `json:"min_keep"`
+}
+
+// BackupInfo 备份信息, 增量备份通过Base找到依赖的备份
+type BackupInfo struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Database string `json:"database"`
+	Base     string `json:"base"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+}
+
+// Init 初始化, 填充默认值
+func (d *BackupComp) Init() (err error) {
+	if d.Params.Port == 0 {
+		d.Params.Port = cst.DefaultInfluxdbPort
+	}
+	if d.Params.RPCAddr == "" {
+		d.Params.RPCAddr = cst.DefaultInfluxdbRPCAddr
+	}
+	if d.Params.BackupDir == "" {
+		d.Params.BackupDir = cst.DefaultInfluxdbBackupDir
+	}
+	if d.Params.MinKeep <= 0 {
+		d.Params.MinKeep = 1
+	}
+	d.target = &s3util.BackupTarget{
+		LocalDir: d.Params.BackupDir,
+		S3:       d.Params.S3,
+		Host:     d.Params.Host,
+	}
+	return nil
+}
+
+func (d *BackupComp) influxd() string {
+	return fmt.Sprintf("%s/usr/bin/influxd", cst.DefaultInfluxdbDir)
+}
+
+func (d *BackupComp) loadInfo(id string) (*BackupInfo, error) {
+	b, err := d.target.ReadFile(id, BackupInfoFile)
+	if err != nil {
+		return nil, fmt.Errorf("read %s of %s failed: %w", BackupInfoFile, id, err)
+	}
+	var info BackupInfo
+	if err = json.Unmarshal(b, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// latest 最新的同库备份
+func (d *BackupComp) latest(database string) (*BackupInfo, error) {
+	ids, err := d.target.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	for k := len(ids) - 1; k >= 0; k-- {
+		info, err := d.loadInfo(ids[k])
+		if err != nil {
+			logger.Warn("跳过备份 %s, %v", ids[k], err)
+			continue
+		}
+		if info.Database == database {
+			return info, nil
+		}
+	}
+	return nil, nil
+}
+
+// Backup 使用 influxd backup -portable 备份, 增量备份使用 -start 只备份上一个备份之后的数据
+func (d *BackupComp) Backup() (err error) {
+	info := &BackupInfo{
+		ID:       s3util.NewBackupID(),
+		Type:     BackupTypeFull,
+		Database: d.Params.Database,
+		End:      time.Now().UTC().Format(time.RFC3339),
+	}
+	args := ""
+	if d.Params.Database != "" {
+		args += fmt.Sprintf(" -db %s", d.Params.Database)
+	}
+	if d.Params.Incremental {
+		if d.Params.Database == "" {
+			return errors.New("增量备份需要指定database")
+		}
+		base, err := d.latest(d.Params.Database)
+		if err != nil {
+			logger.Error("获取上一个备份失败, %v", err)
+			return err
+		}
+		if base != nil {
+			info.Type = BackupTypeIncremental
+			info.Base = base.ID
+			info.Start = base.End
+			args += fmt.Sprintf(" -start %s -end %s", info.Start, info.End)
+		} else {
+			logger.Info("没有可用的全量备份, 进行全量备份")
+		}
+	}
+
+	dir := d.target.Dir(info.ID)
+	extraCmd := fmt.Sprintf("mkdir -p %s; %s backup -portable -host %s%s %s", dir, d.influxd(), d.Params.RPCAddr,
+		args, dir)
+	logger.Info("开始%s备份, [%s]", info.Type, extraCmd)
+	if output, err := osutil.ExecShellCommand(false, extraCmd); err != nil {
+		logger.Error("influxd backup failed, %s, %v", output, err)
+		return err
+	}
+	b, _ := json.Marshal(info)
+	if err = os.WriteFile(filepath.Join(dir, BackupInfoFile), b, 0644); err != nil {
+		return err
+	}
+
+	if d.Params.S3.Enabled() {
+		c, err := s3util.NewClient(d.Params.S3)
+		if err != nil {
+			return err
+		}
+		if err = c.UploadDir(dir, d.target.Key(info.ID)); err != nil {
+			logger.Error("上传备份失败, %v", err)
+			return err
+		}
+		if err = os.RemoveAll(dir); err != nil {
+			logger.Warn("删除临时目录 %s 失败, %v", dir, err)
+		}
+	}
+	logger.Info("备份完成, backup_id: %s", info.ID)
+	components.PrintOutputCtx(info)
+	return nil
+}
+
+// chain 从全量到指定备份的恢复链
+func (d *BackupComp) chain(id string) ([]*BackupInfo, error) {
+	var chain []*BackupInfo
+	for id != "" {
+		info, err := d.loadInfo(id)
+		if err != nil {
+			return nil, err
+		}
+		chain = append([]*BackupInfo{info}, chain...)
+		if info.Type == BackupTypeFull {
+			return chain, nil
+		}
+		id = info.Base
+	}
+	return nil, errors.New("增量备份链不完整, 没有找到全量备份")
+}
+
+// Restore 恢复全量备份, 之后的增量备份先恢复到临时库再通过 SELECT INTO 合并
+func (d *BackupComp) Restore() (err error) {
+	id := d.Params.BackupID
+	if id == "" {
+		latest, err := d.latest(d.Params.Database)
+		if err != nil {
+			return err
+		}
+		if latest == nil {
+			return errors.New("没有可用的备份")
+		}
+		id = latest.ID
+	}
+	chain, err := d.chain(id)
+	if err != nil {
+		logger.Error("获取恢复链失败, %v", err)
+		return err
+	}
+
+	database := chain[0].Database
+	newDatabase := d.Params.NewDatabase
+	if newDatabase == "" {
+		newDatabase = database
+	}
+	for k, info := range chain {
+		dir, cleanup, err := d.fetch(info.ID)
+		if err != nil {
+			return err
+		}
+		if k == 0 {
+			err = d.restoreDir(dir, database, d.Params.NewDatabase)
+		} else {
+			err = d.mergeIncremental(dir, database, newDatabase)
+		}
+		cleanup()
+		if err != nil {
+			return err
+		}
+		logger.Info("恢复 %s(%s) 完成", info.ID, info.Type)
+	}
+	return nil
+}
+
+// fetch 备份在S3时下载到临时目录
+func (d *BackupComp) fetch(id string) (dir string, cleanup func(), err error) {
+	if !d.Params.S3.Enabled() {
+		return d.target.Dir(id), func() {}, nil
+	}
+	dir = filepath.Join(d.Params.BackupDir, "restore_"+id)
+	c, err := s3util.NewClient(d.Params.S3)
+	if err != nil {
+		return "", nil, err
+	}
+	if err = c.DownloadDir(d.target.Key(id), dir); err != nil {
+		logger.Error("下载备份 %s 失败, %v", id, err)
+		return "", nil, err
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+func (d *BackupComp) restoreDir(dir, database, newDatabase string) error {
+	args := ""
+	if database != "" {
+		args += fmt.Sprintf(" -db %s", database)
+	}
+	if newDatabase != "" {
+		args += fmt.Sprintf(" -newdb %s", newDatabase)
+	}
+	extraCmd := fmt.Sprintf("%s restore -portable -host %s%s %s", d.influxd(), d.Params.RPCAddr, args, dir)
+	logger.Info("开始恢复, [%s]", extraCmd)
+	if output, err := osutil.ExecShellCommand(false, extraCmd); err != nil {
+		logger.Error("influxd restore failed, %s, %v", output, err)
+		return err
+	}
+	return nil
+}
+
+// mergeIncremental influxd restore 不能恢复到已存在的库, 增量先恢复到临时库, 按retention policy合并
+func (d *BackupComp) mergeIncremental(dir, database, newDatabase string) error {
+	tmpDatabase := newDatabase + "_restore_tmp"
+	if err := d.restoreDir(dir, database, tmpDatabase); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := d.influxExec(fmt.Sprintf(`DROP DATABASE "%s"`, tmpDatabase)); err != nil {
+			logger.Warn("删除临时库 %s 失败, %v", tmpDatabase, err)
+		}
+	}()
+
+	output, err := d.influxExec(fmt.Sprintf(`SHOW RETENTION POLICIES ON "%s"`, tmpDatabase))
+	if err != nil {
+		return err
+	}
+	// csv: name,name,duration,...
+	for _, line := range strings.Split(output, "\n")[1:] {
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 || fields[1] == "" {
+			continue
+		}
+		rp := fields[1]
+		query := fmt.Sprintf(`SELECT * INTO "%s"."%s".:MEASUREMENT FROM "%s"."%s"./.*/ GROUP BY *`,
+			newDatabase, rp, tmpDatabase, rp)
+		if _, err = d.influxExec(query); err != nil {
+			logger.Error("合并增量数据失败, rp: %s, %v", rp, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *BackupComp) influxExec(query string) (string, error) {
+	logger.Info("influx execute [%s]", query)
+	output, err := d.influxCommand(query).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s, %w", string(output), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// influxCommand 不经过shell执行influx, 用户名密码通过环境变量传递, 不会出现在进程参数中
+func (d *BackupComp) influxCommand(query string) *exec.Cmd {
+	cmd := exec.Command(fmt.Sprintf("%s/usr/bin/influx", cst.DefaultInfluxdbDir),
+		"-host", "localhost", "-port", strconv.Itoa(d.Params.Port), "-format", "csv", "-execute", query)
+	cmd.Env = append(os.Environ(),
+		"INFLUX_USERNAME="+d.Params.Username,
+		"INFLUX_PASSWORD="+d.Params.Password,
+	)
+	return cmd
+}
+
+// CleanBackup 清理过期备份, 仍被未过期增量依赖的备份保留
+func (d *BackupComp) CleanBackup() (err error) {
+	if d.Params.RetentionDays <= 0 {
+		return errors.New("retention_days必须大于0")
+	}
+	ids, err := d.target.ListBackups()
+	if err != nil {
+		return err
+	}
+	expire := time.Now().AddDate(0, 0, -d.Params.RetentionDays).Format(s3util.BackupIDLayout)
+	needed := make(map[string]bool)
+	for k, id := range ids {
+		if id < expire && len(ids)-k > d.Params.MinKeep {
+			continue
+		}
+		chain, err := d.chain(id)
+		if err != nil {
+			logger.Warn("备份 %s 的恢复链不完整, %v", id, err)
+			continue
+		}
+		for _, info := range chain {
+			needed[info.ID] = true
+		}
+	}
+	deleted, err := d.target.Cleanup(d.Params.RetentionDays, d.Params.MinKeep, func(id string) bool {
+		return needed[id]
+	})
+	if err != nil {
+		logger.Error("清理过期备份失败, %v", err)
+		return err
+	}
+	logger.Info("清理过期备份完成, 删除: %v", deleted)
+	return nil
+}
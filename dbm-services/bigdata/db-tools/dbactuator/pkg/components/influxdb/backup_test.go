@@ -0,0 +1,111 @@
+package influxdb
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/s3util"
+)
+
+func TestInfluxCommand(t *testing.T) {
+	d := &BackupComp{Params: &BackupParams{Port: 8086, Username: "admin", Password: `p'a"ss$(id)`}}
+	cmd := d.influxCommand(`SHOW RETENTION POLICIES ON "db1"`)
+	wantArgs := []string{"-host", "localhost", "-port", "8086", "-format", "csv", "-execute",
+		`SHOW RETENTION POLICIES ON "db1"`}
+	if !reflect.DeepEqual(cmd.Args[1:], wantArgs) {
+		t.Fatalf("args got %q, want %q", cmd.Args[1:], wantArgs)
+	}
+	for _, arg := range cmd.Args {
+		if strings.Contains(arg, "p'a") {
+			t.Fatalf("password should not be passed by args, %q", cmd.Args)
+		}
+	}
+	for _, env := range []string{"INFLUX_USERNAME=admin", `INFLUX_PASSWORD=p'a"ss$(id)`} {
+		if !slices.Contains(cmd.Env, env) {
+			t.Errorf("env %s not found", env)
+		}
+	}
+}
+
+func writeBackupInfo(t *testing.T, d *BackupComp, info BackupInfo) {
+	dir := d.target.Dir(info.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(info)
+	if err := os.WriteFile(filepath.Join(dir, BackupInfoFile), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestBackupComp(t *testing.T) *BackupComp {
+	d := &BackupComp{Params: &BackupParams{Host: "1.1.1.1", BackupDir: t.TempDir(), Database: "db1"}}
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestChain(t *testing.T) {
+	d := newTestBackupComp(t)
+	writeBackupInfo(t, d, BackupInfo{ID: "20240101000000", Type: BackupTypeFull, Database: "db1"})
+	writeBackupInfo(t, d, BackupInfo{ID: "20240102000000", Type: BackupTypeIncremental, Database: "db1",
+		Base: "20240101000000"})
+	writeBackupInfo(t, d, BackupInfo{ID: "20240103000000", Type: BackupTypeIncremental, Database: "db1",
+		Base: "20240102000000"})
+	writeBackupInfo(t, d, BackupInfo{ID: "20240104000000", Type: BackupTypeIncremental, Database: "db1",
+		Base: "20231231000000"})
+
+	chain, err := d.chain("20240103000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, info := range chain {
+		ids = append(ids, info.ID)
+	}
+	if want := []string{"20240101000000", "20240102000000", "20240103000000"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got %v, want %v", ids, want)
+	}
+	if _, err = d.chain("20240104000000"); err == nil {
+		t.Fatal("chain without full backup should fail")
+	}
+
+	latest, err := d.latest("db1")
+	if err != nil || latest == nil || latest.ID != "20240104000000" {
+		t.Fatalf("latest got %+v, err %v", latest, err)
+	}
+}
+
+func TestCleanBackupKeepBase(t *testing.T) {
+	d := newTestBackupComp(t)
+	d.Params.RetentionDays = 7
+	old := time.Now().AddDate(0, 0, -30)
+	fullID := old.Format(s3util.BackupIDLayout)
+	expiredID := old.Add(time.Hour).Format(s3util.BackupIDLayout)
+	incrID := time.Now().Format(s3util.BackupIDLayout)
+	writeBackupInfo(t, d, BackupInfo{ID: fullID, Type: BackupTypeFull, Database: "db1"})
+	writeBackupInfo(t, d, BackupInfo{ID: expiredID, Type: BackupTypeFull, Database: "db1"})
+	writeBackupInfo(t, d, BackupInfo{ID: incrID, Type: BackupTypeIncremental, Database: "db1", Base: fullID})
+
+	if err := d.CleanBackup(); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := d.target.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{fullID, incrID}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got %v, want %v", ids, want)
+	}
+	if _, err = os.Stat(d.target.Dir(expiredID)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expired backup should be removed, %v", err)
+	}
+}
@@ -1,8 +1,11 @@
 package kafka
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
 	"os"
+	"strings"
 	"time"
 
 	"dbm-services/bigdata/db-tools/dbactuator/pkg/components"
@@ -24,7 +27,7 @@ type DecomBrokerComp struct {
 
 // DecomBrokerParams TODO
 type DecomBrokerParams struct {
-	ZookeeperIP    string   `json:"zookeeper_ip" validate:"required"`    // 连接zk
+	ZookeeperIP    string   `json:"zookeeper_ip"`                        // 连接zk, KRaft模式为空
 	Username       string   `json:"username"`                            // 管理用户
 	Password       string   `json:"password"`                            // 管理密码
 	ExcludeBrokers []string `json:"exclude_brokers" validate:"required"` // 要缩容的broker
@@ -43,6 +46,9 @@ func (d *DecomBrokerComp) Init() (err error) {
 
 // DoReplaceBrokers TODO
 func (d *DecomBrokerComp) DoReplaceBrokers() (err error) {
+	if kafkautil.UseAdminAPI() {
+		return d.doReassignByAdmin(true)
+	}
 
 	// 获取zk的地址
 	zkHost, zkPath, err := kafkautil.GetZookeeperConnect(cst.KafkaConfigFile)
@@ -123,6 +129,9 @@ func (d *DecomBrokerComp) DoReplaceBrokers() (err error) {
 
 // DoDecomBrokers 进行 Kafka broker 的缩容
 func (d *DecomBrokerComp) DoDecomBrokers() (err error) {
+	if kafkautil.UseAdminAPI() {
+		return d.doReassignByAdmin(false)
+	}
 	var id string
 	// 连接到 Zookeeper
 	zkHost, zkPath, err := kafkautil.GetZookeeperConnect(cst.KafkaConfigFile)
@@ -192,6 +201,9 @@ func (d *DecomBrokerComp) DoDecomBrokers() (err error) {
 // DoPartitionCheck 检查Kafka分区搬迁的状态。
 // 这个过程会重复检查搬迁状态，直到所有分区都成功搬迁或达到最大重试次数。
 func (d *DecomBrokerComp) DoPartitionCheck() (err error) {
+	if kafkautil.UseAdminAPI() {
+		return d.doPartitionCheckByAdmin()
+	}
 	// 定义最大重试次数为864次
 	const MaxRetry = 864
 	count := 0 // 初始化计数器
@@ -255,6 +267,17 @@ func (d *DecomBrokerComp) DoPartitionCheck() (err error) {
 
 // DoEmptyCheck 检查broker数据目录为空
 func (d *DecomBrokerComp) DoEmptyCheck() (err error) {
+	if kafkautil.UseAdminAPI() {
+		empty, err := d.isBrokerEmptyByAdmin()
+		if err == nil {
+			if !empty {
+				return fmt.Errorf("the broker is not empty")
+			}
+			return nil
+		}
+		// broker未启动时无法通过Admin API检查, 退化为检查数据目录
+		logger.Warn("check broker empty by admin api failed, %v, check data dirs instead", err)
+	}
 	// 从配置文件中读取数据目录
 	dataDirs, err := kafkautil.ReadDataDirs(cst.KafkaConfigFile)
 	if err != nil {
@@ -273,3 +296,253 @@ func (d *DecomBrokerComp) DoEmptyCheck() (err error) {
 	}
 	return nil
 }
+
+// doReassignByAdmin 通过Admin API生成并执行迁移计划, 支持zookeeper和KRaft模式
+// replace为true时将exclude_brokers上的副本替换到new_brokers, 否则将exclude_brokers上的副本迁移到其他broker
+func (d *DecomBrokerComp) doReassignByAdmin(replace bool) error {
+	admin, err := kafkautil.NewLocalClusterAdmin(d.Params.Username, d.Params.Password)
+	if err != nil {
+		logger.Error("connect kafka failed, %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	var excludeIds []int
+	for _, broker := range d.Params.ExcludeBrokers {
+		id, err := admin.BrokerIDByHost(broker)
+		if err != nil {
+			logger.Error("cant get %s broker id, %v", broker, err)
+			if replace {
+				return err
+			}
+			continue
+		}
+		excludeIds = append(excludeIds, id)
+	}
+	// 假如缩容的host已经不在kafka集群,例如机器故障已经关机了,这种情况不生成执行计划
+	if len(excludeIds) == 0 {
+		logger.Info("缩容的broker不在集群里面.")
+		return nil
+	}
+	logger.Info("excludeIds: %v", excludeIds)
+
+	var newIds []int
+	for _, broker := range d.Params.NewBrokers {
+		id, err := admin.BrokerIDByHost(broker)
+		if err != nil {
+			logger.Error("cant get %s broker id, %v", broker, err)
+			return err
+		}
+		newIds = append(newIds, id)
+	}
+
+	topics, err := admin.TopicNames()
+	if err != nil {
+		logger.Error("Get topics list failed, %v", err)
+		return err
+	}
+	if len(topics) == 0 {
+		logger.Info("No need to do reassignment.")
+		return nil
+	}
+	var tps []kafkautil.TP
+	for _, t := range topics {
+		tps = append(tps, kafkautil.TP{Topic: t})
+	}
+	b, err := json.Marshal(&kafkautil.TPs{Topics: tps, Version: 1})
+	if err != nil {
+		return err
+	}
+	logger.Info("Creating topic.json file")
+	topicJSONFile := fmt.Sprintf("%s/topic.json", cst.DefaultKafkaEnv)
+	if err = os.WriteFile(topicJSONFile, b, 0644); err != nil {
+		logger.Error("write %s failed, %s", topicJSONFile, err)
+		return err
+	}
+
+	current, err := admin.TopicAssignment(topics)
+	if err != nil {
+		logger.Error("Get topic assignment failed, %v", err)
+		return err
+	}
+	if err = kafkautil.WritePlanFile(cst.RollbackFile, current); err != nil {
+		logger.Error("write %s failed, %v", cst.RollbackFile, err)
+		return err
+	}
+
+	// 判断缩容的host是否还有partiton,对应已经提前均衡的情况，执行也不应该跑执行计划
+	if !kafkautil.HasReplicaOn(current, excludeIds) {
+		logger.Info("缩容的broker没有topic.将rollback.json做为执行计划")
+		return kafkautil.WritePlanFile(cst.PlanJSONFile, current)
+	}
+
+	var plan *kafkautil.ReassignmentPlan
+	if replace {
+		plan, err = kafkautil.LoadPlanFile(cst.RollbackFile)
+		if err != nil {
+			return err
+		}
+		kafkautil.ReplaceBrokerIds(plan, excludeIds, newIds)
+	} else {
+		brokerIds, err := admin.BrokerIDs()
+		if err != nil {
+			logger.Error("Get broker ids failed, %v", err)
+			return err
+		}
+		exclude := make(map[int]bool)
+		for _, id := range excludeIds {
+			exclude[id] = true
+		}
+		var remainIds []int
+		for _, id := range brokerIds {
+			if !exclude[id] {
+				remainIds = append(remainIds, id)
+			}
+		}
+		state, err := admin.ClusterState(topics)
+		if err != nil {
+			logger.Error("Get cluster state failed, %v", err)
+			return err
+		}
+		// 缩容只搬走缩容broker上的副本, 按磁盘占用和机架选择目标broker
+		balancePlan, err := kafkautil.GenBalancePlan(state, kafkautil.PlanOptions{TargetBrokers: remainIds})
+		if err != nil {
+			logger.Error("Create plan.json failed %s", err)
+			return err
+		}
+		logger.Info("reassignment plan:\n%s", balancePlan.String())
+		plan = balancePlan.ToReassignmentPlan()
+	}
+	logger.Info("Creating plan.json file")
+	if err = kafkautil.WritePlanFile(cst.PlanJSONFile, plan); err != nil {
+		logger.Error("write %s failed, %v", cst.PlanJSONFile, err)
+		return err
+	}
+
+	logger.Info("Execute the plan")
+	if err = admin.SetThrottle(plan, cst.KafkaDefaultThrottleRate); err != nil {
+		logger.Error("set throttle failed, %v", err)
+		return err
+	}
+	if err = admin.ExecuteReassignment(plan); err != nil {
+		logger.Error("Execute partitions reassignment failed %s", err)
+		return err
+	}
+	logger.Info("Doing patitions reassignment, default speed rate is 30MB/s")
+	return nil
+}
+
+// doPartitionCheckByAdmin 通过Admin API检查迁移进度, 完成后清理限速
+func (d *DecomBrokerComp) doPartitionCheckByAdmin() error {
+	const MaxRetry = 864
+	topicJSONFile := fmt.Sprintf("%s/topic.json", cst.DefaultKafkaEnv)
+	if !osutil.FileExist(topicJSONFile) {
+		// 没有缩容计划时检查 execute_reassignment 的分批迁移进度
+		if osutil.FileExist(cst.ReassignProgressFile) {
+			return d.checkBatchProgress()
+		}
+		logger.Info("[%s] no exist, no need to check progress.", topicJSONFile)
+		return nil
+	}
+	plan, err := kafkautil.LoadPlanFile(cst.PlanJSONFile)
+	if err != nil {
+		logger.Error("load %s failed, %v", cst.PlanJSONFile, err)
+		return err
+	}
+	admin, err := kafkautil.NewLocalClusterAdmin(d.Params.Username, d.Params.Password)
+	if err != nil {
+		logger.Error("connect kafka failed, %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	for count := 1; ; count++ {
+		logger.Info("检查搬迁状态，次数[%d]", count)
+		inProgress, err := admin.ReassignmentInProgress(plan)
+		if err != nil {
+			logger.Error("检查partition搬迁进度失败 %v", err)
+			return err
+		}
+		if len(inProgress) == 0 {
+			logger.Info("数据搬迁完成")
+			break
+		}
+		logger.Info("当前进度: %d partitions in progress, %v", len(inProgress), inProgress)
+		if count == MaxRetry {
+			logger.Error("检查数据搬迁超时,可以选择重试")
+			return fmt.Errorf("检查扩容状态超时,可以选择重试")
+		}
+		time.Sleep(100 * time.Second)
+	}
+	if err = admin.ClearThrottle(plan); err != nil {
+		logger.Error("clear throttle failed, %v", err)
+		return err
+	}
+
+	logger.Info("分区已搬空, 若有新增topic, 请检查分区分布")
+	logger.Info("清理计划文件")
+	extraCmd := fmt.Sprintf("rm -f %s %s %s", cst.PlanJSONFile, topicJSONFile, cst.RollbackFile)
+	logger.Info("cmd: [%s]", extraCmd)
+	osutil.ExecShellCommandBd(false, extraCmd)
+	return nil
+}
+
+// checkBatchProgress 等待分批迁移完成, 输出已完成的批次和数据量
+func (d *DecomBrokerComp) checkBatchProgress() error {
+	const MaxRetry = 864
+	for count := 1; ; count++ {
+		progress, err := loadReassignProgress()
+		if err != nil {
+			logger.Error("load %s failed, %v", cst.ReassignProgressFile, err)
+			return err
+		}
+		percent := 100.0
+		if progress.TotalBytes > 0 {
+			percent = float64(progress.DoneBytes) * 100 / float64(progress.TotalBytes)
+		}
+		logger.Info("当前进度: 批次[%d/%d], 数据量[%d/%d] %.1f%%, 迁移中分区[%d], 更新时间[%s]",
+			progress.DoneBatches, progress.TotalBatches, progress.DoneBytes, progress.TotalBytes, percent,
+			len(progress.InProgress), progress.UpdateTime.Format(time.RFC3339))
+		if progress.Finished {
+			logger.Info("数据搬迁完成")
+			return components.PrintOutputCtx(progress)
+		}
+		if count == MaxRetry {
+			logger.Error("检查数据搬迁超时,可以选择重试")
+			return fmt.Errorf("检查扩容状态超时,可以选择重试")
+		}
+		time.Sleep(100 * time.Second)
+	}
+}
+
+// isBrokerEmptyByAdmin 通过DescribeLogDirs检查本机broker是否还有分区副本
+func (d *DecomBrokerComp) isBrokerEmptyByAdmin() (bool, error) {
+	props, err := kafkautil.ReadProperties(cst.KafkaConfigFile)
+	if err != nil {
+		return false, err
+	}
+	// 仅controller角色的节点不存放分区数据
+	if roles, ok := props[cst.KafkaProcessRolesKey]; ok && !strings.Contains(roles, "broker") {
+		logger.Info("controller only node, no partition")
+		return true, nil
+	}
+	admin, err := kafkautil.NewLocalClusterAdmin(d.Params.Username, d.Params.Password)
+	if err != nil {
+		return false, err
+	}
+	defer admin.Close()
+	host, _, err := net.SplitHostPort(admin.Addrs[0])
+	if err != nil {
+		return false, err
+	}
+	id, err := admin.BrokerIDByHost(host)
+	if err != nil {
+		return false, err
+	}
+	count, err := admin.BrokerPartitionCount(id)
+	if err != nil {
+		return false, err
+	}
+	logger.Info("broker %d has %d partitions", id, count)
+	return count == 0, nil
+}
@@ -55,6 +55,18 @@ type InstallKafkaParams struct {
 	ServiceType    string          `json:"service_type"`
 	NoSecurity     int             `json:"no_security"`     // 兼容现有,0:有鉴权,1:无鉴权
 	RetentionBytes int             `json:"retention_bytes"` // log.retention.bytes 默认-1
+	// KRaft模式参数, metadata_mode=kraft 时不依赖zookeeper
+	MetadataMode           string `json:"metadata_mode"`            // zookeeper(默认) / kraft
+	NodeID                 int    `json:"node_id"`                  // node.id, 集群内唯一
+	ProcessRoles           string `json:"process_roles"`            // broker / controller / broker,controller
+	ControllerQuorumVoters string `json:"controller_quorum_voters"` // eg: 1@ip1:9093,2@ip2:9093,3@ip3:9093
+	ControllerPort         int    `json:"controller_port"`          // controller监听端口, 默认9093
+	ClusterID              string `json:"cluster_id"`               // 集群ID, 由 gen_cluster_id 生成, 所有节点一致
+}
+
+// IsKRaft 是否以KRaft模式安装
+func (p *InstallKafkaParams) IsKRaft() bool {
+	return p.MetadataMode == cst.KafkaMetadataModeKRaft
 }
 
 // InitDirs TODO
@@ -470,6 +482,11 @@ dynamicConfigFile=%s" > %s`
 
 // InitKafkaUser TODO
 func (i *InstallKafkaComp) InitKafkaUser() (err error) {
+	// KRaft模式在格式化存储时已写入SCRAM用户, 超级用户由super.users配置
+	if i.Params.IsKRaft() {
+		logger.Info("kraft mode, user created when format storage, skip")
+		return nil
+	}
 
 	var (
 		zookeeperIP  = i.Params.ZookeeperIP
@@ -541,6 +558,15 @@ func (i *InstallKafkaComp) InstallBroker() error {
 	if retentionBytes == 0 {
 		retentionBytes = -1
 	}
+	if i.Params.IsKRaft() {
+		if err := i.checkKRaftParams(); err != nil {
+			return err
+		}
+		// KRaft模式没有zookeeper, 这里只是占位, 写入配置后会删除zookeeper.*配置
+		if zookeeperIP == "" {
+			zookeeperIP = "127.0.0.1,127.0.0.1,127.0.0.1"
+		}
+	}
 
 	// ln -s /data/kafkaenv/kafka-$version /data/kafkaenv/kafka
 	kafkaLink := fmt.Sprintf("%s/kafka", cst.DefaultKafkaEnv)
@@ -638,6 +664,16 @@ func (i *InstallKafkaComp) InstallBroker() error {
 		return err
 	}
 
+	if i.Params.IsKRaft() {
+		if err := i.configKRaft(kafkaBaseDir); err != nil {
+			return err
+		}
+		// 仅controller角色不监听broker端口
+		if i.Params.ProcessRoles == "controller" {
+			port = i.Params.ControllerPort
+		}
+	}
+
 	if err := startKafka(i.KafkaEnvDir, noSecurity); err != nil {
 		return err
 	}
@@ -652,6 +688,70 @@ func (i *InstallKafkaComp) InstallBroker() error {
 	return nil
 }
 
+func (i *InstallKafkaComp) checkKRaftParams() error {
+	if i.Params.ControllerPort == 0 {
+		i.Params.ControllerPort = cst.KafkaControllerPort
+	}
+	switch i.Params.ProcessRoles {
+	case "broker", "controller", "broker,controller", "controller,broker":
+	default:
+		return fmt.Errorf("invalid process_roles [%s]", i.Params.ProcessRoles)
+	}
+	if i.Params.ControllerQuorumVoters == "" {
+		return errors.New("controller_quorum_voters is empty")
+	}
+	if i.Params.ClusterID == "" {
+		return errors.New("cluster_id is empty")
+	}
+	return nil
+}
+
+// configKRaft 改写server.properties为KRaft模式, 并格式化存储目录
+func (i *InstallKafkaComp) configKRaft(kafkaBaseDir string) error {
+	kraftConfig := kafkautil.KRaftConfig{
+		NodeID:                 i.Params.NodeID,
+		ProcessRoles:           i.Params.ProcessRoles,
+		ControllerQuorumVoters: i.Params.ControllerQuorumVoters,
+		ControllerListener:     fmt.Sprintf("%s:%d", i.Params.Host, i.Params.ControllerPort),
+	}
+	username, password := i.Params.Username, i.Params.Password
+	if i.Params.NoSecurity == 1 {
+		username, password = "", ""
+	}
+	kraftConfig.SuperUser, kraftConfig.SuperPassword = username, password
+	if err := kafkautil.ApplyKRaftConfig(cst.KafkaConfigFile, kraftConfig); err != nil {
+		logger.Error("apply kraft config failed, %v", err)
+		return err
+	}
+	if err := kafkautil.FormatStorage(kafkaBaseDir, i.Params.ClusterID, cst.KafkaConfigFile,
+		username, password); err != nil {
+		return err
+	}
+	// format以root执行, 数据目录属主需要改回
+	dataDirs, err := kafkautil.ReadDataDirs(cst.KafkaConfigFile)
+	if err != nil {
+		logger.Error("read log.dirs failed, %v", err)
+		return err
+	}
+	extraCmd := fmt.Sprintf("chown -R mysql %s", strings.Join(dataDirs, " "))
+	if _, err := osutil.ExecShellCommand(false, extraCmd); err != nil {
+		logger.Error("%s execute failed, %v", extraCmd, err)
+		return err
+	}
+	return nil
+}
+
+// GenClusterID 生成KRaft集群ID, 同一集群所有节点安装时使用相同的cluster_id
+func (i *InstallKafkaComp) GenClusterID() error {
+	clusterID, err := kafkautil.GenClusterID()
+	if err != nil {
+		logger.Error("gen cluster id failed, %v", err)
+		return err
+	}
+	logger.Info("cluster id: %s", clusterID)
+	return components.PrintOutputCtx(map[string]string{"cluster_id": clusterID})
+}
+
 func configKafka(username string, password string, kafkaLink string, jmxPort int) error {
 	logger.Info("配置jaas")
 	extraCmd := fmt.Sprintf(`echo 'KafkaServer {
@@ -1,11 +1,13 @@
 package kafka
 
 import (
+	"errors"
 	"fmt"
 
 	"dbm-services/bigdata/db-tools/dbactuator/pkg/components"
 	"dbm-services/bigdata/db-tools/dbactuator/pkg/core/cst"
 	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/kafkautil"
 	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/osutil"
 	"dbm-services/common/go-pubpkg/logger"
 )
@@ -38,6 +40,9 @@ func (r *ReconfigComp) Init() (err error) {
  *  @return
  */
 func (r *ReconfigComp) ReconfigAdd() (err error) {
+	if kafkautil.IsKRaft(cst.KafkaConfigFile) {
+		return errors.New("kraft mode uses static controller.quorum.voters, zookeeper reconfig is not supported")
+	}
 	// 增加zookeeper
 	extraCmd := fmt.Sprintf(`%s/zk/bin/zkCli.sh reconfig -file %s`, cst.DefaultKafkaEnv, cst.DefaultZookeeperDynamicConf)
 	osutil.ExecShellCommand(false, extraCmd)
@@ -57,6 +62,9 @@ func (r *ReconfigComp) ReconfigAdd() (err error) {
  *  @return
  */
 func (r *ReconfigComp) ReconfigRemove() (err error) {
+	if kafkautil.IsKRaft(cst.KafkaConfigFile) {
+		return errors.New("kraft mode uses static controller.quorum.voters, zookeeper reconfig is not supported")
+	}
 	// 减少zookeeper
 	extraCmd := fmt.Sprintf(`%s/zk/bin/zkCli.sh reconfig -remove %s`, cst.DefaultKafkaEnv, r.Params.Host)
 	osutil.ExecShellCommand(false, extraCmd)
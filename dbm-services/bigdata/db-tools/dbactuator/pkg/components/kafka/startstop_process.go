@@ -7,6 +7,7 @@ import (
 	"dbm-services/bigdata/db-tools/dbactuator/pkg/components"
 	"dbm-services/bigdata/db-tools/dbactuator/pkg/core/cst"
 	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/kafkautil"
 	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/osutil"
 	"dbm-services/common/go-pubpkg/logger"
 )
@@ -117,18 +118,23 @@ func (d *StartStopProcessComp) RestartProcess() (err error) {
  *  @return
  */
 func (d *StartStopProcessComp) RestartBroker() (err error) {
-
-	zookeeperIpList := strings.Split(d.Params.ZookeeperIp, ",")
-	// extraCmd := fmt.Sprintf("line=`sed -n -e '/zookeeper.connect=/=' %s`", cst.DefaultKafkaEnv+"/kafka/config/server.properties")
-	// if _, err = osutil.ExecShellCommand(false, extraCmd); err != nil {
-	//	logger.Error("%s execute failed, %v", extraCmd, err)
-	//	return err
-	// }
-	extraCmd := fmt.Sprintf("sed -i '29c zookeeper.connect=%s:2181,%s:2181,%s:2181/' %s", zookeeperIpList[0],
-		zookeeperIpList[1], zookeeperIpList[2], cst.DefaultKafkaEnv+"/kafka/config/server.properties")
-	if _, err = osutil.ExecShellCommand(false, extraCmd); err != nil {
-		logger.Error("%s execute failed, %v", extraCmd, err)
-		return err
+	var extraCmd string
+	// KRaft模式没有zookeeper.connect, 直接重启
+	if kafkautil.IsKRaft(cst.KafkaConfigFile) || d.Params.ZookeeperIp == "" {
+		logger.Info("kraft mode or zookeeper_ip is empty, skip update zookeeper.connect")
+	} else {
+		zookeeperIpList := strings.Split(d.Params.ZookeeperIp, ",")
+		// extraCmd := fmt.Sprintf("line=`sed -n -e '/zookeeper.connect=/=' %s`", cst.DefaultKafkaEnv+"/kafka/config/server.properties")
+		// if _, err = osutil.ExecShellCommand(false, extraCmd); err != nil {
+		//	logger.Error("%s execute failed, %v", extraCmd, err)
+		//	return err
+		// }
+		extraCmd = fmt.Sprintf("sed -i '29c zookeeper.connect=%s:2181,%s:2181,%s:2181/' %s", zookeeperIpList[0],
+			zookeeperIpList[1], zookeeperIpList[2], cst.DefaultKafkaEnv+"/kafka/config/server.properties")
+		if _, err = osutil.ExecShellCommand(false, extraCmd); err != nil {
+			logger.Error("%s execute failed, %v", extraCmd, err)
+			return err
+		}
 	}
 	// 重启broker进程
 	extraCmd = "supervisorctl restart kafka"
@@ -34,6 +34,41 @@ type TopicReassignParams struct {
 	Topics         []string `json:"topics"`          // List of topic patterns to filter
 	ExcludeBrokers []string `json:"exclude_brokers"` // 同时兼容
 	NewBrokers     []string `json:"new_brokers"`     // 替换单据
+	// 以下参数仅Admin API方式生效
+	BatchSize        int     `json:"batch_size"`        // 每批迁移的分区数, 默认10
+	BalanceThreshold float64 `json:"balance_threshold"` // broker磁盘最大最小差值小于平均值*threshold时不再搬迁, 默认0.1
+}
+
+// ReassignProgress 分批迁移进度, execute_reassignment 写入, check_reassign 读取
+type ReassignProgress struct {
+	TotalBatches int       `json:"total_batches"`
+	DoneBatches  int       `json:"done_batches"`
+	TotalBytes   int64     `json:"total_bytes"`
+	DoneBytes    int64     `json:"done_bytes"`
+	InProgress   []string  `json:"in_progress"`
+	Finished     bool      `json:"finished"`
+	UpdateTime   time.Time `json:"update_time"`
+}
+
+func loadReassignProgress() (*ReassignProgress, error) {
+	b, err := os.ReadFile(cst.ReassignProgressFile)
+	if err != nil {
+		return nil, err
+	}
+	progress := &ReassignProgress{}
+	if err = json.Unmarshal(b, progress); err != nil {
+		return nil, fmt.Errorf("unmarshal %s failed: %w", cst.ReassignProgressFile, err)
+	}
+	return progress, nil
+}
+
+func (r *ReassignProgress) save() error {
+	r.UpdateTime = time.Now()
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cst.ReassignProgressFile, b, 0644)
 }
 
 // TopicJSON represents the structure for topic reassignment JSON
@@ -62,6 +97,9 @@ func (t *TopicReassignComp) Init() error {
 func (t *TopicReassignComp) GenerateReassignmentPlans() error {
 	// 删除上次生成的文件
 	cleanFiles()
+	if kafkautil.UseAdminAPI() {
+		return t.generateByAdmin()
+	}
 	// Get Zookeeper connection string
 	zkHost, zkPath, err := kafkautil.GetZookeeperConnect(cst.KafkaConfigFile)
 	if err != nil {
@@ -207,6 +245,9 @@ func (t *TopicReassignComp) GenerateReassignmentPlans() error {
 
 // ExecuteReassignment executes the reassignment plans for all topics
 func (t *TopicReassignComp) ExecuteReassignment() error {
+	if kafkautil.UseAdminAPI() {
+		return t.executeByAdmin()
+	}
 	// Get Zookeeper connection string
 	zkHost, zkPath, err := kafkautil.GetZookeeperConnect(cst.KafkaConfigFile)
 	if err != nil {
@@ -299,6 +340,194 @@ func (t *TopicReassignComp) ExecuteReassignment() error {
 
 }
 
+// generateByAdmin 根据分区大小, 机架和leader分布生成均衡迁移计划, 计划写入 balance_plan.json 供评审
+func (t *TopicReassignComp) generateByAdmin() error {
+	for _, file := range []string{cst.BalancePlanFile, cst.BalanceRollbackFile, cst.ReassignProgressFile} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			logger.Warn("failed to remove file %s: %v", file, err)
+		}
+	}
+	admin, err := kafkautil.NewLocalClusterAdmin("", "")
+	if err != nil {
+		return fmt.Errorf("failed to connect kafka: %w", err)
+	}
+	defer admin.Close()
+
+	topics, err := admin.TopicNames()
+	if err != nil {
+		return fmt.Errorf("failed to get topic list: %w", err)
+	}
+	filterTopics := filterTopics(topics, t.Params.Topics)
+	logger.Info("filterTopics: %v", filterTopics)
+
+	replaceMode := len(t.Params.ExcludeBrokers) > 0
+	logger.Info("Replace mode: %v", replaceMode)
+
+	opts := kafkautil.PlanOptions{
+		BalanceDisk:    !replaceMode,
+		BalanceLeaders: !replaceMode,
+		Threshold:      t.Params.BalanceThreshold,
+	}
+	if replaceMode {
+		if len(t.Params.ExcludeBrokers) != len(t.Params.NewBrokers) {
+			return fmt.Errorf("exclude_brokers and new_brokers length mismatch")
+		}
+		// 替换模式, 副本从旧broker一一搬到新broker, 其他broker不动
+		exclude := make(map[int]bool)
+		opts.ReplaceBrokers = make(map[int]int)
+		for i, ip := range t.Params.ExcludeBrokers {
+			oldID, err := admin.BrokerIDByHost(ip)
+			if err != nil {
+				return fmt.Errorf("failed to get broker ID for exclude %s: %w", ip, err)
+			}
+			newID, err := admin.BrokerIDByHost(t.Params.NewBrokers[i])
+			if err != nil {
+				return fmt.Errorf("failed to get broker ID for new %s: %w", t.Params.NewBrokers[i], err)
+			}
+			exclude[oldID] = true
+			opts.ReplaceBrokers[oldID] = newID
+		}
+		allBrokerIDs, err := admin.BrokerIDs()
+		if err != nil {
+			return fmt.Errorf("failed to get all broker IDs: %w", err)
+		}
+		for _, id := range allBrokerIDs {
+			if !exclude[id] {
+				opts.TargetBrokers = append(opts.TargetBrokers, id)
+			}
+		}
+	} else {
+		for _, ip := range t.Params.Brokers {
+			id, err := admin.BrokerIDByHost(ip)
+			if err != nil {
+				return fmt.Errorf("failed to get broker ID for %s: %w", ip, err)
+			}
+			opts.TargetBrokers = append(opts.TargetBrokers, id)
+		}
+	}
+
+	state, err := admin.ClusterState(filterTopics)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster state: %w", err)
+	}
+	plan, err := kafkautil.GenBalancePlan(state, opts)
+	if err != nil {
+		return fmt.Errorf("failed to generate reassignment plan: %w", err)
+	}
+	logger.Info("reassignment plan:\n%s", plan.String())
+
+	b, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(cst.BalancePlanFile, b, 0644); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	if err = kafkautil.WritePlanFile(cst.BalanceRollbackFile, plan.RollbackPlan()); err != nil {
+		return fmt.Errorf("failed to write rollback JSON: %w", err)
+	}
+	logger.Info("plan: %s, rollback: %s", cst.BalancePlanFile, cst.BalanceRollbackFile)
+	return nil
+}
+
+// executeByAdmin 分批执行 balance_plan.json, 每批开始前重新读取限速, 进度写入 reassign_progress.json
+// 重复执行时从未完成的批次继续
+func (t *TopicReassignComp) executeByAdmin() error {
+	b, err := os.ReadFile(cst.BalancePlanFile)
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+	plan := &kafkautil.BalancePlan{}
+	if err = json.Unmarshal(b, plan); err != nil {
+		return fmt.Errorf("failed to unmarshal plan file: %w", err)
+	}
+	batchSize := t.Params.BatchSize
+	if batchSize <= 0 {
+		batchSize = cst.KafkaReassignBatchSize
+	}
+	batches := plan.Batches(batchSize)
+
+	progress, err := loadReassignProgress()
+	if err != nil || progress.TotalBatches != len(batches) {
+		progress = &ReassignProgress{TotalBatches: len(batches), TotalBytes: plan.MoveBytes}
+	}
+	if err = progress.save(); err != nil {
+		return fmt.Errorf("failed to write progress file: %w", err)
+	}
+
+	admin, err := kafkautil.NewLocalClusterAdmin("", "")
+	if err != nil {
+		return fmt.Errorf("failed to connect kafka: %w", err)
+	}
+	defer admin.Close()
+
+	// 中途失败时清理限速, 避免残留的限速影响正常复制; 重新执行时会再次设置
+	throttled := false
+	defer func() {
+		if !throttled {
+			return
+		}
+		if err := admin.ClearThrottle(plan.ToReassignmentPlan()); err != nil {
+			logger.Error("failed to clear throttle: %v", err)
+		}
+	}()
+
+	total := len(batches)
+	logger.Info("Total partitions to reassign: %d, batches: %d, bytes: %d", len(plan.Partitions), total, plan.MoveBytes)
+	for i := progress.DoneBatches; i < total; i++ {
+		batch := batches[i].ToReassignmentPlan()
+		// 读取 throttle_rate.txt 文件, 动态修改速度
+		throttle := int64(cst.KafkaDefaultThrottleRate)
+		if throttleBytes, err := os.ReadFile(cst.ThrottleFile); err == nil {
+			if rate, err := strconv.ParseInt(strings.TrimSpace(string(throttleBytes)), 10, 64); err == nil && rate > 0 {
+				throttle = rate
+			}
+		}
+
+		logger.Info("[%d/%d] Starting reassignment, partitions: %d, bytes: %d, throttle: %d",
+			i+1, total, len(batch.Partitions), batches[i].MoveBytes, throttle)
+		throttled = true
+		if err := admin.SetThrottle(batch, throttle); err != nil {
+			return fmt.Errorf("failed to set throttle: %w", err)
+		}
+		if err := admin.ExecuteReassignment(batch); err != nil {
+			return fmt.Errorf("failed to execute reassignment batch %d: %w", i+1, err)
+		}
+
+		for {
+			inProgress, err := admin.ReassignmentInProgress(batch)
+			if err != nil {
+				return fmt.Errorf("failed to verify reassignment batch %d: %w", i+1, err)
+			}
+			progress.InProgress = inProgress
+			_ = progress.save()
+			if len(inProgress) == 0 {
+				logger.Info("[%d/%d] batch reassignment completed", i+1, total)
+				break
+			}
+			logger.Info("[%d/%d] %d partitions in progress, waiting 10 seconds...", i+1, total, len(inProgress))
+			time.Sleep(10 * time.Second)
+		}
+
+		progress.DoneBatches = i + 1
+		progress.DoneBytes += batches[i].MoveBytes
+		if err := progress.save(); err != nil {
+			return fmt.Errorf("failed to write progress file: %w", err)
+		}
+	}
+
+	throttled = false
+	if err := admin.ClearThrottle(plan.ToReassignmentPlan()); err != nil {
+		return fmt.Errorf("failed to clear throttle: %w", err)
+	}
+	progress.Finished = true
+	if err := progress.save(); err != nil {
+		return fmt.Errorf("failed to write progress file: %w", err)
+	}
+	logger.Info("All reassignment batches completed!")
+	return nil
+}
+
 func cleanFiles() {
 	// Clean up files
 	filesToRemove := []string{cst.ThrottleFile, cst.TopicListFilePath, cst.DoneFile}
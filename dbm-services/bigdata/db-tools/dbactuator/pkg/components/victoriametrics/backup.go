@@ -0,0 +1,237 @@
+package victoriametrics
+
+import (
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/components"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/core/cst"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/rollback"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/osutil"
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/s3util"
+	"dbm-services/common/go-pubpkg/logger"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// BackupComp 是一个结构体，用于vmstorage的备份、恢复和过期备份清理。
+type BackupComp struct {
+	GeneralParam    *components.GeneralParam // GeneralParam 是通用参数。
+	Params          *BackupParams            // Params 是备份恢复的参数。
+	RollBackContext rollback.RollBackObjects // RollBackContext 是回滚上下文，用于在操作失败时进行回滚。
+	target          *s3util.BackupTarget
+}
+
+// BackupParams 是备份恢复的参数，备份存放在 backup_dir/{host}/{id} 或 s3://bucket/base_path/{host}/{id}。
+type BackupParams struct {
+	Host          string            `json:"host" validate:"required,ip"` // Host 是本机ip，用于区分不同vmstorage的备份。
+	DataPath      string            `json:"data_path"`                   // DataPath 是vmstorage的数据目录。
+	StoragePort   int               `json:"storage_port"`                // StoragePort 是vmstorage的http端口，用于创建快照。
+	BackupDir     string            `json:"backup_dir"`                  // BackupDir 是本地备份目录，可以是共享存储的挂载点。
+	S3            *s3util.Config    `json:"s3"`                          // S3 不为空时备份到对象存储。
+	Incremental   bool              `json:"incremental"`                 // Incremental 为true时以上一个备份为origin，只上传变化的文件。
+	BackupID      string            `json:"backup_id"`                   // BackupID 是恢复使用的备份，为空时使用最新的备份。
+	RetentionDays int               `json:"retention_days"`              // RetentionDays 是备份保留天数。
+	MinKeep       int               `json:"min_keep"`                    // MinKeep 是至少保留的备份个数。
+	ExtraArgs     map[string]string `json:"extra_args"`                  // ExtraArgs 是传给vmbackup/vmrestore的其他参数，如 concurrency。
+}
+
+// Init 是 BackupComp 的初始化函数，填充默认值。
+func (d *BackupComp) Init() (err error) {
+	if d.Params.DataPath == "" {
+		d.Params.DataPath = cst.DefaultVMDataDir
+	}
+	if d.Params.StoragePort == 0 {
+		d.Params.StoragePort = cst.VMStorageHTTPPort
+	}
+	if d.Params.BackupDir == "" {
+		d.Params.BackupDir = cst.DefaultVMBackupDir
+	}
+	if d.Params.MinKeep <= 0 {
+		d.Params.MinKeep = 1
+	}
+	d.target = &s3util.BackupTarget{
+		LocalDir: d.Params.BackupDir,
+		S3:       d.Params.S3,
+		Host:     d.Params.Host,
+	}
+	return nil
+}
+
+// toolArgs 生成vmbackup/vmrestore访问对象存储的公共参数，返回的凭证文件需要在执行后删除。
+func (d *BackupComp) toolArgs() (args string, credsFile string, err error) {
+	for k, v := range d.Params.ExtraArgs {
+		args += fmt.Sprintf(" -%s=%s", k, v)
+	}
+	if !d.Params.S3.Enabled() {
+		return args, "", nil
+	}
+	f, err := os.CreateTemp("", "vmbackup-creds-*")
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	// AWS shared credentials 格式
+	if _, err = fmt.Fprintf(f, "[default]\naws_access_key_id=%s\naws_secret_access_key=%s\n",
+		d.Params.S3.AccessKey, d.Params.S3.SecretKey); err != nil {
+		return "", "", err
+	}
+	args += fmt.Sprintf(" -credsFilePath=%s -customS3Endpoint=%s -s3ForcePathStyle=%t", f.Name(),
+		d.Params.S3.URL(), d.Params.S3.PathStyle)
+	return args, f.Name(), nil
+}
+
+func toolPath(tool string) (string, error) {
+	p := fmt.Sprintf("%s/bin/%s-prod", cst.VMHome, tool)
+	if !util.FileExists(p) {
+		return "", fmt.Errorf("%s 不存在, 请确认安装包中包含vmutils", p)
+	}
+	return p, nil
+}
+
+// Backup 是备份vmstorage的函数，它执行了以下操作：
+// 1. 通过vmstorage的snapshot接口创建快照
+// 2. 上传快照到备份目录，增量备份时以上一个备份为origin，未变化的文件直接复制
+// 3. vmbackup完成后删除快照
+func (d *BackupComp) Backup() (err error) {
+	bin, err := toolPath(cst.VMBackup)
+	if err != nil {
+		return err
+	}
+	args, credsFile, err := d.toolArgs()
+	if err != nil {
+		return err
+	}
+	if credsFile != "" {
+		defer os.Remove(credsFile)
+	}
+
+	id := s3util.NewBackupID()
+	if d.Params.Incremental {
+		ids, err := d.target.ListBackups()
+		if err != nil {
+			logger.Error("获取备份列表失败, %v", err)
+			return err
+		}
+		if len(ids) > 0 {
+			origin := ids[len(ids)-1]
+			logger.Info("增量备份, origin: %s", origin)
+			args += fmt.Sprintf(" -origin=%s", d.target.URL(origin))
+		}
+	}
+	if !d.Params.S3.Enabled() {
+		extraCmd := fmt.Sprintf("mkdir -p %s; chown -R %s %s", d.target.Dir(id), cst.DefaultExecUser,
+			d.Params.BackupDir)
+		if _, err = osutil.ExecShellCommand(false, extraCmd); err != nil {
+			logger.Error("[%s] execute failed, %v", extraCmd, err)
+			return err
+		}
+	}
+
+	snapshotURL := fmt.Sprintf("http://127.0.0.1:%d/snapshot", d.Params.StoragePort)
+	extraCmd := fmt.Sprintf("%s -storageDataPath=%s -snapshot.createURL=%s/create -snapshot.deleteURL=%s/delete -dst=%s%s",
+		bin, d.Params.DataPath, snapshotURL, snapshotURL, d.target.URL(id), args)
+	logger.Info("开始备份, dst: %s", d.target.URL(id))
+	if output, err := osutil.ExecShellCommand(false, extraCmd); err != nil {
+		logger.Error("vmbackup failed, %s, %v", output, err)
+		return err
+	}
+	logger.Info("备份完成, backup_id: %s", id)
+	components.PrintOutputCtx(map[string]string{
+		"backup_id": id,
+		"dst":       d.target.URL(id),
+	})
+	return nil
+}
+
+// Restore 是恢复vmstorage的函数，vmrestore需要停止vmstorage，恢复后再启动，恢复失败时也会重新启动。
+func (d *BackupComp) Restore() (err error) {
+	bin, err := toolPath(cst.VMRestore)
+	if err != nil {
+		return err
+	}
+	id := d.Params.BackupID
+	if id == "" {
+		ids, err := d.target.ListBackups()
+		if err != nil {
+			logger.Error("获取备份列表失败, %v", err)
+			return err
+		}
+		if len(ids) == 0 {
+			return errors.New("没有可用的备份")
+		}
+		id = ids[len(ids)-1]
+	}
+	args, credsFile, err := d.toolArgs()
+	if err != nil {
+		return err
+	}
+	if credsFile != "" {
+		defer os.Remove(credsFile)
+	}
+
+	err = withStorageStopped(func() error {
+		extraCmd := fmt.Sprintf("%s -src=%s -storageDataPath=%s%s", bin, d.target.URL(id), d.Params.DataPath, args)
+		logger.Info("开始恢复, src: %s", d.target.URL(id))
+		if output, err := osutil.ExecShellCommand(false, extraCmd); err != nil {
+			logger.Error("vmrestore failed, %s, %v", output, err)
+			return err
+		}
+		return nil
+	}, d.stopStorage, d.startStorage)
+	if err != nil {
+		return err
+	}
+	logger.Info("恢复完成, backup_id: %s", id)
+	return nil
+}
+
+// withStorageStopped 是停止vmstorage后执行fn的函数，fn执行失败时也会重新启动vmstorage，避免实例一直处于停止状态。
+func withStorageStopped(fn, stop, start func() error) (err error) {
+	if err = stop(); err != nil {
+		return err
+	}
+	defer func() {
+		startErr := start()
+		if err == nil {
+			err = startErr
+		} else if startErr != nil {
+			logger.Error("恢复失败后重新启动vmstorage失败, %v", startErr)
+		}
+	}()
+	return fn()
+}
+
+func (d *BackupComp) stopStorage() error {
+	extraCmd := fmt.Sprintf("supervisorctl stop %s", cst.VMStorage)
+	logger.Info("停止vmstorage, [%s]", extraCmd)
+	if _, err := osutil.ExecShellCommand(false, extraCmd); err != nil {
+		logger.Error("[%s] execute failed, %v", extraCmd, err)
+		return err
+	}
+	return nil
+}
+
+func (d *BackupComp) startStorage() error {
+	extraCmd := fmt.Sprintf("chown -R %s %s; supervisorctl start %s", cst.DefaultExecUser, d.Params.DataPath,
+		cst.VMStorage)
+	logger.Info("启动vmstorage, [%s]", extraCmd)
+	if _, err := osutil.ExecShellCommand(false, extraCmd); err != nil {
+		logger.Error("[%s] execute failed, %v", extraCmd, err)
+		return err
+	}
+	return nil
+}
+
+// CleanBackup 是清理过期备份的函数，每个备份都是完整的(增量只是复用origin的文件)，可以直接删除。
+func (d *BackupComp) CleanBackup() (err error) {
+	if d.Params.RetentionDays <= 0 {
+		return errors.New("retention_days必须大于0")
+	}
+	deleted, err := d.target.Cleanup(d.Params.RetentionDays, d.Params.MinKeep, nil)
+	if err != nil {
+		logger.Error("清理过期备份失败, %v", err)
+		return err
+	}
+	logger.Info("清理过期备份完成, 删除: %v", deleted)
+	return nil
+}
@@ -0,0 +1,73 @@
+package victoriametrics
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/s3util"
+)
+
+func TestWithStorageStopped(t *testing.T) {
+	restoreErr, startErr := errors.New("restore failed"), errors.New("start failed")
+	tests := []struct {
+		name     string
+		stopErr  error
+		fnErr    error
+		startErr error
+		wantErr  error
+		wantCall []string
+	}{
+		{name: "ok", wantCall: []string{"stop", "fn", "start"}},
+		{name: "restore failed", fnErr: restoreErr, wantErr: restoreErr, wantCall: []string{"stop", "fn", "start"}},
+		{name: "start failed", startErr: startErr, wantErr: startErr, wantCall: []string{"stop", "fn", "start"}},
+		{name: "both failed", fnErr: restoreErr, startErr: startErr, wantErr: restoreErr,
+			wantCall: []string{"stop", "fn", "start"}},
+		{name: "stop failed", stopErr: startErr, wantErr: startErr, wantCall: []string{"stop"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			call := func(name string, err error) func() error {
+				return func() error {
+					calls = append(calls, name)
+					return err
+				}
+			}
+			err := withStorageStopped(call("fn", tt.fnErr), call("stop", tt.stopErr), call("start", tt.startErr))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err got %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCall) {
+				t.Errorf("calls got %v, want %v", calls, tt.wantCall)
+			}
+		})
+	}
+}
+
+func TestToolArgs(t *testing.T) {
+	d := &BackupComp{Params: &BackupParams{ExtraArgs: map[string]string{"concurrency": "4"}}}
+	args, credsFile, err := d.toolArgs()
+	if err != nil || args != " -concurrency=4" || credsFile != "" {
+		t.Fatalf("args %q, creds %q, err %v", args, credsFile, err)
+	}
+
+	d.Params.S3 = &s3util.Config{Endpoint: "cos.example.com", Bucket: "b", AccessKey: "ak", SecretKey: "sk"}
+	args, credsFile, err = d.toolArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(credsFile)
+	if strings.Contains(args, "sk") || !strings.Contains(args, "-credsFilePath="+credsFile) {
+		t.Fatalf("args %q", args)
+	}
+	content, err := os.ReadFile(credsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[default]\naws_access_key_id=ak\naws_secret_access_key=sk\n"; string(content) != want {
+		t.Fatalf("creds got %q, want %q", content, want)
+	}
+}
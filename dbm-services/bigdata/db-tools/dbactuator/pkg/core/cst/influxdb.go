@@ -13,4 +13,8 @@ const (
 	DefaultInfluxdbDir = DefaultInfluxdbEnv + "/influxdb"
 	// DefaultInfluxdbSupervisorConf TODO
 	DefaultInfluxdbSupervisorConf = DefaultInfluxdbEnv + "/supervisor/conf"
+	// DefaultInfluxdbBackupDir influxdb本地备份目录
+	DefaultInfluxdbBackupDir = "/data/influxdbbackup"
+	// DefaultInfluxdbRPCAddr influxd backup/restore 使用的rpc地址, 配置文件中未修改时为默认值
+	DefaultInfluxdbRPCAddr = "127.0.0.1:8088"
 )
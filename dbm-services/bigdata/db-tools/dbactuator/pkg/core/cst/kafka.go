@@ -59,4 +59,24 @@ const (
 	ThrottleFile = "./throttle_rate.txt"
 	// KafkaUIPort kafkaui默认监听端口
 	KafkaUIPort = 9001
+	// KafkaDefaultThrottleRate 分区迁移默认限速 30MB/s
+	KafkaDefaultThrottleRate = 30000000
+	// KafkaReassignBatchSize 分批迁移时每批的分区数
+	KafkaReassignBatchSize = 10
+	// BalancePlanFile 均衡迁移计划, 包含迁移前后的broker负载, 用于评审
+	BalancePlanFile = DefaultKafkaEnv + "/balance_plan.json"
+	// BalanceRollbackFile 均衡迁移回退计划
+	BalanceRollbackFile = DefaultKafkaEnv + "/balance_rollback.json"
+	// ReassignProgressFile 分批迁移进度
+	ReassignProgressFile = DefaultKafkaEnv + "/reassign_progress.json"
+	// KafkaControllerPort KRaft controller默认监听端口
+	KafkaControllerPort = 9093
+	// KafkaControllerListenerName KRaft controller监听名
+	KafkaControllerListenerName = "CONTROLLER"
+	// KafkaProcessRolesKey KRaft模式的角色配置项
+	KafkaProcessRolesKey = "process.roles"
+	// KafkaMetadataModeKRaft 元数据存储在KRaft, 不依赖zookeeper
+	KafkaMetadataModeKRaft = "kraft"
+	// KafkaJaasFile kafka SCRAM认证配置
+	KafkaJaasFile = DefaultKafkaDir + "/config/kafka_server_scram_jaas.conf"
 )
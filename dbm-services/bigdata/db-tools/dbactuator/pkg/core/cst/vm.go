@@ -33,4 +33,12 @@ const (
 	VMAuthInsertConf = DefaultVMEnv + "/vm/" + "vmauth_insert.yml"
 	// VMAuthSelectConf TODO
 	VMAuthSelectConf = DefaultVMEnv + "/vm/" + "vmauth_select.yml"
+	// VMStorageHTTPPort vmstorage默认http端口, 用于创建快照
+	VMStorageHTTPPort = 8482
+	// DefaultVMBackupDir vm本地备份目录
+	DefaultVMBackupDir = "/data/vmbackup"
+	// VMBackup vmbackup工具
+	VMBackup = "vmbackup"
+	// VMRestore vmrestore工具
+	VMRestore = "vmrestore"
 )
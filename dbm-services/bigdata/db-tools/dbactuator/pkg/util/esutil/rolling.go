@@ -0,0 +1,114 @@
+package esutil
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dbm-services/common/go-pubpkg/logger"
+)
+
+const (
+	// AllocationPrimaries 只允许分配主分片, 滚动重启时使用
+	AllocationPrimaries = "primaries"
+	// AllocationAll 恢复默认的分片分配
+	AllocationAll = ""
+)
+
+// ClusterHealth _cluster/health 返回
+type ClusterHealth struct {
+	ClusterName        string `json:"cluster_name"`
+	Status             string `json:"status"`
+	TimedOut           bool   `json:"timed_out"`
+	NumberOfNodes      int    `json:"number_of_nodes"`
+	RelocatingShards   int    `json:"relocating_shards"`
+	InitializingShards int    `json:"initializing_shards"`
+	UnassignedShards   int    `json:"unassigned_shards"`
+}
+
+// SetAllocation 设置 cluster.routing.allocation.enable, 为空时恢复默认值
+func (o EsInsObject) SetAllocation(enable string) error {
+	var value interface{}
+	if enable != AllocationAll {
+		value = enable
+	}
+	body := map[string]interface{}{
+		"persistent": map[string]interface{}{
+			"cluster.routing.allocation.enable": value,
+		},
+	}
+	logger.Info("设置 cluster.routing.allocation.enable: %v", value)
+	return o.DoRequest(http.MethodPut, "/_cluster/settings", body, nil)
+}
+
+// Flush 刷新所有索引, synced为true时使用synced flush(7.6以下版本), 加速重启后的分片恢复
+func (o EsInsObject) Flush(synced bool) error {
+	path := "/_flush"
+	if synced {
+		path = "/_flush/synced"
+	}
+	logger.Info("执行 %s", path)
+	return o.DoRequest(http.MethodPost, path, nil, nil)
+}
+
+// GetHealth 获取集群健康状态, waitStatus不为空时最多等待timeout
+// 等待超时es返回408, 响应体仍是健康状态, 此时返回 TimedOut 为 true
+func (o EsInsObject) GetHealth(waitStatus string, timeout string) (*ClusterHealth, error) {
+	path := "/_cluster/health"
+	if waitStatus != "" {
+		path = fmt.Sprintf("%s?wait_for_status=%s&timeout=%s", path, waitStatus, timeout)
+	}
+	var health ClusterHealth
+	if err := o.doRequest(http.MethodGet, path, nil, &health, http.StatusRequestTimeout); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// NodeJoined 节点是否已加入集群
+func (o EsInsObject) NodeJoined(nodeName string) (bool, error) {
+	var nodes []struct {
+		Name string `json:"name"`
+	}
+	if err := o.DoRequest(http.MethodGet, "/_cat/nodes?h=name&format=json", nil, &nodes); err != nil {
+		return false, err
+	}
+	for _, n := range nodes {
+		if n.Name == nodeName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetYamlSetting 读取elasticsearch.yml中的单行配置, 不存在时返回空
+func GetYamlSetting(filePath, key string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	for _, l := range strings.Split(string(content), "\n") {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, key+":") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(l, key+":")), `"'`), nil
+		}
+	}
+	return "", nil
+}
+
+// GetPluginEsVersion 读取插件 plugin-descriptor.properties 中适配的es版本
+func GetPluginEsVersion(pluginDir string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(pluginDir, "plugin-descriptor.properties"))
+	if err != nil {
+		return "", err
+	}
+	for _, l := range strings.Split(string(content), "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(l), "=")
+		if ok && strings.TrimSpace(k) == "elasticsearch.version" {
+			return strings.TrimSpace(v), nil
+		}
+	}
+	return "", fmt.Errorf("%s 中没有 elasticsearch.version", pluginDir)
+}
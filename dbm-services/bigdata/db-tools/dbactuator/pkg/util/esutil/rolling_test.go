@@ -0,0 +1,66 @@
+package esutil_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/esutil"
+)
+
+func TestGetHealthTimedOut(t *testing.T) {
+	ins := newTestEsIns(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("wait_for_status") != "green" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		w.WriteHeader(http.StatusRequestTimeout)
+		_ = json.NewEncoder(w).Encode(esutil.ClusterHealth{Status: "yellow", TimedOut: true, UnassignedShards: 2})
+	})
+	health, err := ins.GetHealth("green", "30s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !health.TimedOut || health.Status != "yellow" || health.UnassignedShards != 2 {
+		t.Fatalf("got %+v", health)
+	}
+}
+
+func TestGetHealthError(t *testing.T) {
+	ins := newTestEsIns(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	if _, err := ins.GetHealth("green", "30s"); err == nil {
+		t.Fatal("expected error on 503")
+	}
+}
+
+func TestGetPluginEsVersion(t *testing.T) {
+	pluginDir := t.TempDir()
+	descriptor := "name=analysis-ik\n# comment\nelasticsearch.version = 7.10.2\nversion=7.10.2\n"
+	if err := os.WriteFile(filepath.Join(pluginDir, "plugin-descriptor.properties"), []byte(descriptor),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	esVersion, err := esutil.GetPluginEsVersion(pluginDir)
+	if err != nil || esVersion != "7.10.2" {
+		t.Fatalf("got %s, err %v", esVersion, err)
+	}
+	if _, err = esutil.GetPluginEsVersion(t.TempDir()); err == nil {
+		t.Fatal("expected error when descriptor is missing")
+	}
+}
+
+func TestGetYamlSetting(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "elasticsearch.yml")
+	if err := os.WriteFile(f, []byte("node.name: \"hot-127.0.0.1_1\"\nhttp.port: 9200\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := esutil.GetYamlSetting(f, "node.name"); v != "hot-127.0.0.1_1" {
+		t.Fatalf("got %s", v)
+	}
+	if v, _ := esutil.GetYamlSetting(f, "path.repo"); v != "" {
+		t.Fatalf("got %s", v)
+	}
+}
@@ -0,0 +1,311 @@
+package esutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"dbm-services/common/go-pubpkg/logger"
+
+	"github.com/hashicorp/go-version"
+)
+
+// SnapshotRepo 快照仓库, type为fs或s3
+type SnapshotRepo struct {
+	Type     string                 `json:"type"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// SnapshotInfo 快照信息
+type SnapshotInfo struct {
+	Snapshot          string   `json:"snapshot"`
+	State             string   `json:"state"` // IN_PROGRESS, SUCCESS, PARTIAL, FAILED
+	Indices           []string `json:"indices"`
+	StartTimeInMillis int64    `json:"start_time_in_millis"`
+	EndTimeInMillis   int64    `json:"end_time_in_millis"`
+	Failures          []struct {
+		Index  string `json:"index"`
+		Reason string `json:"reason"`
+	} `json:"failures"`
+}
+
+// StartTime 快照开始时间
+func (s SnapshotInfo) StartTime() time.Time {
+	return time.UnixMilli(s.StartTimeInMillis)
+}
+
+// SnapshotRequest 创建快照的请求
+type SnapshotRequest struct {
+	Indices            string `json:"indices"`
+	IgnoreUnavailable  bool   `json:"ignore_unavailable"`
+	IncludeGlobalState bool   `json:"include_global_state"`
+}
+
+// RestoreRequest 恢复快照的请求
+type RestoreRequest struct {
+	Indices            string `json:"indices"`
+	IgnoreUnavailable  bool   `json:"ignore_unavailable"`
+	IncludeGlobalState bool   `json:"include_global_state"`
+	RenamePattern      string `json:"rename_pattern,omitempty"`
+	RenameReplacement  string `json:"rename_replacement,omitempty"`
+}
+
+// SnapshotPolicy SLM策略, 7.5以上版本支持保留策略
+type SnapshotPolicy struct {
+	Schedule   string                 `json:"schedule"`
+	Name       string                 `json:"name"`
+	Repository string                 `json:"repository"`
+	Config     SnapshotRequest        `json:"config"`
+	Retention  map[string]interface{} `json:"retention,omitempty"`
+}
+
+// DoRequest 发送请求到es, body不为空时序列化为json, 响应码不是2xx时返回错误
+func (o EsInsObject) DoRequest(method, path string, body interface{}, result interface{}) error {
+	return o.doRequest(method, path, body, result)
+}
+
+// doRequest 同DoRequest, acceptCodes中的非2xx响应码也当作正常响应解析
+func (o EsInsObject) doRequest(method, path string, body interface{}, result interface{}, acceptCodes ...int) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s:%d%s", o.Host, o.HTTPPort, path), reader)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.SetBasicAuth(o.UserName, o.Password)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应体失败: %w", err)
+	}
+	if (resp.StatusCode < 200 || resp.StatusCode >= 300) && !containsCode(acceptCodes, resp.StatusCode) {
+		return fmt.Errorf("%s %s 响应码[%d]: %s", method, path, resp.StatusCode, string(respBody))
+	}
+	if result != nil {
+		if err = json.Unmarshal(respBody, result); err != nil {
+			return fmt.Errorf("解析响应体失败: %w", err)
+		}
+	}
+	return nil
+}
+
+func containsCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// GetVersion 获取es版本
+func (o EsInsObject) GetVersion() (*version.Version, error) {
+	var info struct {
+		Version struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	if err := o.DoRequest(http.MethodGet, "/", nil, &info); err != nil {
+		return nil, err
+	}
+	return version.NewVersion(info.Version.Number)
+}
+
+// PutSnapshotRepo 注册快照仓库并校验所有节点都能访问
+func (o EsInsObject) PutSnapshotRepo(name string, repo SnapshotRepo) error {
+	logger.Info("注册快照仓库 %s, type: %s", name, repo.Type)
+	if err := o.DoRequest(http.MethodPut, "/_snapshot/"+url.PathEscape(name), repo, nil); err != nil {
+		return err
+	}
+	var nodes struct {
+		Nodes map[string]struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	}
+	if err := o.DoRequest(http.MethodPost, "/_snapshot/"+url.PathEscape(name)+"/_verify", nil, &nodes); err != nil {
+		return fmt.Errorf("校验快照仓库失败, 请检查所有节点的仓库配置: %w", err)
+	}
+	logger.Info("快照仓库 %s 校验通过, 节点数: %d", name, len(nodes.Nodes))
+	return nil
+}
+
+// SnapshotRepoExists 快照仓库是否已注册
+func (o EsInsObject) SnapshotRepoExists(name string) bool {
+	return o.DoRequest(http.MethodGet, "/_snapshot/"+url.PathEscape(name), nil, nil) == nil
+}
+
+// CreateSnapshot 创建快照, 不等待完成
+func (o EsInsObject) CreateSnapshot(repo, snapshot string, r SnapshotRequest) error {
+	logger.Info("创建快照 %s/%s, indices: %s", repo, snapshot, r.Indices)
+	path := fmt.Sprintf("/_snapshot/%s/%s", url.PathEscape(repo), url.PathEscape(snapshot))
+	return o.DoRequest(http.MethodPut, path, r, nil)
+}
+
+// GetSnapshots 获取快照列表, pattern支持通配符, 按开始时间从新到旧排序
+func (o EsInsObject) GetSnapshots(repo, pattern string) ([]SnapshotInfo, error) {
+	var result struct {
+		Snapshots []SnapshotInfo `json:"snapshots"`
+	}
+	path := fmt.Sprintf("/_snapshot/%s/%s", url.PathEscape(repo), url.PathEscape(pattern))
+	if err := o.DoRequest(http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	sort.Slice(result.Snapshots, func(i, j int) bool {
+		return result.Snapshots[i].StartTimeInMillis > result.Snapshots[j].StartTimeInMillis
+	})
+	return result.Snapshots, nil
+}
+
+// DeleteSnapshot 删除快照
+func (o EsInsObject) DeleteSnapshot(repo, snapshot string) error {
+	logger.Info("删除快照 %s/%s", repo, snapshot)
+	path := fmt.Sprintf("/_snapshot/%s/%s", url.PathEscape(repo), url.PathEscape(snapshot))
+	return o.DoRequest(http.MethodDelete, path, nil, nil)
+}
+
+// RestoreSnapshot 恢复快照, 等待恢复完成
+func (o EsInsObject) RestoreSnapshot(repo, snapshot string, r RestoreRequest) error {
+	logger.Info("恢复快照 %s/%s, indices: %s, rename: %s -> %s", repo, snapshot, r.Indices,
+		r.RenamePattern, r.RenameReplacement)
+	path := fmt.Sprintf("/_snapshot/%s/%s/_restore?wait_for_completion=true", url.PathEscape(repo),
+		url.PathEscape(snapshot))
+	var result struct {
+		Snapshot struct {
+			Indices []string `json:"indices"`
+			Shards  struct {
+				Total      int `json:"total"`
+				Failed     int `json:"failed"`
+				Successful int `json:"successful"`
+			} `json:"shards"`
+		} `json:"snapshot"`
+	}
+	if err := o.DoRequest(http.MethodPost, path, r, &result); err != nil {
+		return err
+	}
+	logger.Info("恢复完成, indices: %v, shards total: %d, successful: %d, failed: %d",
+		result.Snapshot.Indices, result.Snapshot.Shards.Total, result.Snapshot.Shards.Successful,
+		result.Snapshot.Shards.Failed)
+	if result.Snapshot.Shards.Failed > 0 {
+		return fmt.Errorf("恢复失败的shard数: %d", result.Snapshot.Shards.Failed)
+	}
+	return nil
+}
+
+// PutSnapshotPolicy 创建或更新SLM策略
+func (o EsInsObject) PutSnapshotPolicy(id string, p SnapshotPolicy) error {
+	logger.Info("创建快照策略 %s, schedule: %s, repository: %s", id, p.Schedule, p.Repository)
+	return o.DoRequest(http.MethodPut, "/_slm/policy/"+url.PathEscape(id), p, nil)
+}
+
+// ReloadSecureSettings 重新加载keystore中的配置, s3的access_key等不需要重启
+func (o EsInsObject) ReloadSecureSettings() error {
+	return o.DoRequest(http.MethodPost, "/_nodes/reload_secure_settings", nil, nil)
+}
+
+// JoinIndices 拼接index pattern, 为空时返回 *
+func JoinIndices(indices []string) string {
+	var result []string
+	for _, i := range indices {
+		if i = strings.TrimSpace(i); i != "" {
+			result = append(result, i)
+		}
+	}
+	if len(result) == 0 {
+		return "*"
+	}
+	return strings.Join(result, ",")
+}
+
+// SetYamlSetting 设置elasticsearch.yml中的单行配置, 已存在且值相同时不修改, 返回是否有修改
+func SetYamlSetting(filePath, key, value string) (changed bool, err error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	line := fmt.Sprintf("%s: %s", key, value)
+	var lines []string
+	for _, l := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(l), key+":") {
+			if strings.TrimSpace(l) == line {
+				return false, nil
+			}
+			continue
+		}
+		lines = append(lines, l)
+	}
+	lines = append(lines, line)
+	return true, os.WriteFile(filePath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// GetPathRepo 读取elasticsearch.yml中的path.repo, 格式: path.repo: [/a, /b]
+func GetPathRepo(filePath string) ([]string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var repos []string
+	for _, l := range strings.Split(string(content), "\n") {
+		l = strings.TrimSpace(l)
+		if !strings.HasPrefix(l, "path.repo:") {
+			continue
+		}
+		v := strings.Trim(strings.TrimSpace(strings.TrimPrefix(l, "path.repo:")), "[]")
+		for _, r := range strings.Split(v, ",") {
+			if r = strings.Trim(strings.TrimSpace(r), `"'`); r != "" {
+				repos = append(repos, r)
+			}
+		}
+	}
+	return repos, nil
+}
+
+// AddPathRepo 将目录合并到elasticsearch.yml的path.repo中, 返回是否有修改
+func AddPathRepo(filePath string, dirs []string) (changed bool, err error) {
+	repos, err := GetPathRepo(filePath)
+	if err != nil {
+		return false, err
+	}
+	merged := append([]string{}, repos...)
+	for _, d := range dirs {
+		if d = strings.TrimSpace(d); d != "" && !containStr(merged, d) {
+			merged = append(merged, d)
+		}
+	}
+	if len(merged) == len(repos) {
+		return false, nil
+	}
+	return SetYamlSetting(filePath, "path.repo", fmt.Sprintf("[%s]", strings.Join(merged, ",")))
+}
+
+// AddKeystoreSetting 写入keystore配置, 已存在时覆盖.
+// 值通过stdin传给elasticsearch-keystore, 不经过shell, 也不会出现在进程参数中
+func AddKeystoreSetting(esDir, key, value string) error {
+	cmd := exec.Command(filepath.Join(esDir, "bin", "elasticsearch-keystore"), "add", "--stdin", "--force", key)
+	cmd.Stdin = strings.NewReader(value)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("add keystore setting %s failed, %s, %w", key, string(output), err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package esutil_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/util/esutil"
+)
+
+func TestAddKeystoreSetting(t *testing.T) {
+	esDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(esDir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(esDir, "keystore.out")
+	script := "#!/bin/sh\necho \"$@\" > " + out + "\ncat >> " + out + "\n"
+	if err := os.WriteFile(filepath.Join(esDir, "bin", "elasticsearch-keystore"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := `a'b"c$(touch pwned)` + "`id`"
+	if err := esutil.AddKeystoreSetting(esDir, "s3.client.default.secret_key", secret); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "add --stdin --force s3.client.default.secret_key\n" + secret
+	if string(content) != want {
+		t.Fatalf("got %q, want %q", content, want)
+	}
+	if _, err = os.Stat("pwned"); err == nil {
+		t.Fatal("secret should not be interpreted by shell")
+	}
+}
+
+func TestAddKeystoreSettingFailed(t *testing.T) {
+	if err := esutil.AddKeystoreSetting(t.TempDir(), "s3.client.default.access_key", "ak"); err == nil {
+		t.Fatal("expected error when elasticsearch-keystore is missing")
+	}
+}
+
+func TestSetYamlSetting(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "elasticsearch.yml")
+	if err := os.WriteFile(f, []byte("cluster.name: es\ns3.client.default.endpoint: old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := esutil.SetYamlSetting(f, "s3.client.default.endpoint", "cos.example.com")
+	if err != nil || !changed {
+		t.Fatalf("changed %v, err %v", changed, err)
+	}
+	changed, err = esutil.SetYamlSetting(f, "s3.client.default.endpoint", "cos.example.com")
+	if err != nil || changed {
+		t.Fatalf("same value changed %v, err %v", changed, err)
+	}
+	content, _ := os.ReadFile(f)
+	if want := "cluster.name: es\ns3.client.default.endpoint: cos.example.com\n"; string(content) != want {
+		t.Fatalf("got %q, want %q", content, want)
+	}
+}
+
+func TestAddPathRepo(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "elasticsearch.yml")
+	if err := os.WriteFile(f, []byte("path.repo: [\"/data/a\", /data/b]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := esutil.AddPathRepo(f, []string{"/data/b", " /data/c "})
+	if err != nil || !changed {
+		t.Fatalf("changed %v, err %v", changed, err)
+	}
+	repos, err := esutil.GetPathRepo(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/data/a", "/data/b", "/data/c"}; !reflect.DeepEqual(repos, want) {
+		t.Fatalf("got %v, want %v", repos, want)
+	}
+	if changed, _ = esutil.AddPathRepo(f, []string{"/data/a"}); changed {
+		t.Fatal("existing repo should not change the file")
+	}
+}
+
+func TestJoinIndices(t *testing.T) {
+	if got := esutil.JoinIndices(nil); got != "*" {
+		t.Fatalf("got %s", got)
+	}
+	if got := esutil.JoinIndices([]string{" log-* ", "", "app"}); got != "log-*,app" {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func newTestEsIns(t *testing.T, handler http.HandlerFunc) esutil.EsInsObject {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpPort, _ := strconv.Atoi(port)
+	return esutil.EsInsObject{Host: host, HTTPPort: httpPort, UserName: "u", Password: "p"}
+}
+
+func TestGetSnapshots(t *testing.T) {
+	ins := newTestEsIns(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_snapshot/repo/snapshot-*" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"snapshots": []esutil.SnapshotInfo{
+				{Snapshot: "snapshot-1", State: "SUCCESS", StartTimeInMillis: 1000},
+				{Snapshot: "snapshot-2", State: "SUCCESS", StartTimeInMillis: 2000},
+			},
+		})
+	})
+	snapshots, err := ins.GetSnapshots("repo", "snapshot-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Snapshot != "snapshot-2" {
+		t.Fatalf("snapshots should be sorted from newest, got %+v", snapshots)
+	}
+}
+
+func TestDoRequestError(t *testing.T) {
+	ins := newTestEsIns(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	if err := ins.ReloadSecureSettings(); err == nil {
+		t.Fatal("expected error on 500")
+	}
+}
@@ -0,0 +1,479 @@
+package kafkautil
+
+import (
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/core/cst"
+	"dbm-services/common/go-pubpkg/logger"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// SHA512 scram sha512
+var SHA512 scram.HashGeneratorFcn = sha512.New
+
+// XDGSCRAMClient scram client
+type XDGSCRAMClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+// Begin 开始认证
+func (x *XDGSCRAMClient) Begin(userName, password, authzID string) (err error) {
+	x.Client, err = x.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	x.ClientConversation = x.Client.NewConversation()
+	return nil
+}
+
+// Step 认证步骤
+func (x *XDGSCRAMClient) Step(challenge string) (response string, err error) {
+	response, err = x.ClientConversation.Step(challenge)
+	return
+}
+
+// Done 认证是否完成
+func (x *XDGSCRAMClient) Done() bool {
+	return x.ClientConversation.Done()
+}
+
+// KafkaAdmin 基于Admin API的集群管理, zookeeper和KRaft模式通用
+type KafkaAdmin struct {
+	sarama.ClusterAdmin
+	Addrs []string // bootstrap地址
+}
+
+// NewClusterAdmin 连接集群, username为空时不鉴权
+func NewClusterAdmin(addrs []string, username, password string) (*KafkaAdmin, error) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_4_0_0
+	config.Net.DialTimeout = 10 * time.Second
+	config.Admin.Timeout = 60 * time.Second
+	if username != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.User = username
+		config.Net.SASL.Password = password
+		config.Net.SASL.Handshake = true
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &XDGSCRAMClient{HashGeneratorFcn: SHA512}
+		}
+	}
+	admin, err := sarama.NewClusterAdmin(addrs, config)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %v failed: %w", addrs, err)
+	}
+	return &KafkaAdmin{ClusterAdmin: admin, Addrs: addrs}, nil
+}
+
+// NewLocalClusterAdmin 通过本机broker连接集群, 地址和用户从本机配置中读取
+// username为空时从kafka_server_scram_jaas.conf读取
+func NewLocalClusterAdmin(username, password string) (*KafkaAdmin, error) {
+	props, err := ReadProperties(cst.KafkaConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	listeners := props["advertised.listeners"]
+	if listeners == "" {
+		listeners = props["listeners"]
+	}
+	var addr string
+	var sasl bool
+	for _, l := range strings.Split(listeners, ",") {
+		// SASL_PLAINTEXT://ip:9092
+		fields := strings.SplitN(strings.TrimSpace(l), "://", 2)
+		if len(fields) != 2 || fields[0] == cst.KafkaControllerListenerName {
+			continue
+		}
+		addr, sasl = fields[1], strings.HasPrefix(fields[0], "SASL")
+		break
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("no broker listener found in %s", cst.KafkaConfigFile)
+	}
+	if !sasl {
+		username, password = "", ""
+	} else if username == "" {
+		if username, password, err = ReadJaasUser(cst.KafkaJaasFile); err != nil {
+			return nil, err
+		}
+	}
+	return NewClusterAdmin([]string{addr}, username, password)
+}
+
+// ReadJaasUser 从jaas文件中读取用户名密码
+func ReadJaasUser(jaasFile string) (username, password string, err error) {
+	content, err := os.ReadFile(jaasFile)
+	if err != nil {
+		return "", "", err
+	}
+	userRe := regexp.MustCompile(`username="([^"]*)"`)
+	passRe := regexp.MustCompile(`password="([^"]*)"`)
+	u, p := userRe.FindSubmatch(content), passRe.FindSubmatch(content)
+	if u == nil || p == nil {
+		return "", "", fmt.Errorf("username or password not found in %s", jaasFile)
+	}
+	return string(u[1]), string(p[1]), nil
+}
+
+// BrokerIDs 返回当前存活的broker id
+func (a *KafkaAdmin) BrokerIDs() ([]int, error) {
+	brokers, _, err := a.DescribeCluster()
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for _, b := range brokers {
+		ids = append(ids, int(b.ID()))
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// BrokerIDByHost 根据ip获取broker id
+func (a *KafkaAdmin) BrokerIDByHost(host string) (int, error) {
+	brokers, _, err := a.DescribeCluster()
+	if err != nil {
+		return 0, err
+	}
+	for _, b := range brokers {
+		h, _, err := net.SplitHostPort(b.Addr())
+		if err != nil {
+			continue
+		}
+		if h == host {
+			return int(b.ID()), nil
+		}
+	}
+	return 0, fmt.Errorf("broker %s not found", host)
+}
+
+// TopicNames 返回所有topic
+func (a *KafkaAdmin) TopicNames() ([]string, error) {
+	topics, err := a.ListTopics()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// TopicAssignment 获取topic当前的分区分布
+func (a *KafkaAdmin) TopicAssignment(topics []string) (*ReassignmentPlan, error) {
+	metas, err := a.DescribeTopics(topics)
+	if err != nil {
+		return nil, err
+	}
+	plan := &ReassignmentPlan{Version: 1}
+	for _, m := range metas {
+		if m.Err != sarama.ErrNoError {
+			return nil, fmt.Errorf("describe topic %s failed: %w", m.Name, m.Err)
+		}
+		for _, p := range m.Partitions {
+			replicas := make([]int, 0, len(p.Replicas))
+			for _, r := range p.Replicas {
+				replicas = append(replicas, int(r))
+			}
+			plan.Partitions = append(plan.Partitions, Partition{
+				Topic:     m.Name,
+				Partition: int(p.ID),
+				Replicas:  replicas,
+			})
+		}
+	}
+	sort.Slice(plan.Partitions, func(i, j int) bool {
+		if plan.Partitions[i].Topic != plan.Partitions[j].Topic {
+			return plan.Partitions[i].Topic < plan.Partitions[j].Topic
+		}
+		return plan.Partitions[i].Partition < plan.Partitions[j].Partition
+	})
+	return plan, nil
+}
+
+// ExecuteReassignment 提交分区迁移, 只提交副本分布有变化的分区, 其他分区不受影响
+func (a *KafkaAdmin) ExecuteReassignment(plan *ReassignmentPlan) error {
+	current, err := a.TopicAssignment(planTopics(plan))
+	if err != nil {
+		return err
+	}
+	moves := movedPartitions(current, plan)
+	if len(moves) == 0 {
+		logger.Info("all partitions are already on target replicas")
+		return nil
+	}
+	// ClusterAdmin.AlterPartitionReassignments 会提交topic的所有分区, 这里直接向controller提交需要迁移的分区
+	request := &sarama.AlterPartitionReassignmentsRequest{TimeoutMs: int32(60000), Version: int16(0)}
+	for _, p := range moves {
+		request.AddBlock(p.Topic, int32(p.Partition), intsToInt32s(p.Replicas))
+	}
+	controller, err := a.Controller()
+	if err != nil {
+		return fmt.Errorf("get controller failed: %w", err)
+	}
+	logger.Info("alter partition reassignments, partitions: %d", len(moves))
+	rsp, err := controller.AlterPartitionReassignments(request)
+	if err != nil {
+		return fmt.Errorf("reassign partitions failed: %w", err)
+	}
+	if rsp.ErrorCode != sarama.ErrNoError {
+		return fmt.Errorf("reassign partitions failed: %w", rsp.ErrorCode)
+	}
+	return a.checkReassignmentAccepted(moves)
+}
+
+// checkReassignmentAccepted 分区级别的错误码sarama未导出, 提交后检查分区是否在迁移中或已经迁移完成,
+// 两者都不是说明分区被controller拒绝
+func (a *KafkaAdmin) checkReassignmentAccepted(moves []Partition) error {
+	plan := &ReassignmentPlan{Version: 1, Partitions: moves}
+	partitions := make(map[string][]int32)
+	for _, p := range moves {
+		partitions[p.Topic] = append(partitions[p.Topic], int32(p.Partition))
+	}
+	inProgress := make(map[string]bool)
+	for topic, ps := range partitions {
+		status, err := a.ListPartitionReassignments(topic, ps)
+		if err != nil {
+			return err
+		}
+		for partition := range status[topic] {
+			inProgress[fmt.Sprintf("%s-%d", topic, partition)] = true
+		}
+	}
+	current, err := a.TopicAssignment(planTopics(plan))
+	if err != nil {
+		return err
+	}
+	var rejected []string
+	for _, p := range movedPartitions(current, plan) {
+		if key := fmt.Sprintf("%s-%d", p.Topic, p.Partition); !inProgress[key] {
+			rejected = append(rejected, key)
+		}
+	}
+	if len(rejected) > 0 {
+		return fmt.Errorf("reassignment of partitions %v is rejected by controller", rejected)
+	}
+	return nil
+}
+
+// movedPartitions 计划中副本分布与当前不同的分区
+func movedPartitions(current, plan *ReassignmentPlan) []Partition {
+	replicas := make(map[string][]int, len(current.Partitions))
+	for _, p := range current.Partitions {
+		replicas[fmt.Sprintf("%s-%d", p.Topic, p.Partition)] = p.Replicas
+	}
+	var moves []Partition
+	for _, p := range plan.Partitions {
+		if !reflect.DeepEqual(replicas[fmt.Sprintf("%s-%d", p.Topic, p.Partition)], p.Replicas) {
+			moves = append(moves, p)
+		}
+	}
+	return moves
+}
+
+// ReassignmentInProgress 返回计划中仍在迁移的分区
+func (a *KafkaAdmin) ReassignmentInProgress(plan *ReassignmentPlan) ([]string, error) {
+	partitions := make(map[string][]int32)
+	for _, p := range plan.Partitions {
+		partitions[p.Topic] = append(partitions[p.Topic], int32(p.Partition))
+	}
+	var inProgress []string
+	for topic, ps := range partitions {
+		status, err := a.ListPartitionReassignments(topic, ps)
+		if err != nil {
+			return nil, err
+		}
+		for partition, s := range status[topic] {
+			inProgress = append(inProgress, fmt.Sprintf("%s-%d adding:%v removing:%v",
+				topic, partition, s.AddingReplicas, s.RemovingReplicas))
+		}
+	}
+	sort.Strings(inProgress)
+	return inProgress, nil
+}
+
+// SetThrottle 设置迁移限速, rate单位 bytes/s. 需要在提交迁移前调用,
+// 只对迁移分区的副本限速: leader限速迁移前的副本, follower限速新增的副本, 与 kafka-reassign-partitions.sh 一致
+func (a *KafkaAdmin) SetThrottle(plan *ReassignmentPlan, rate int64) error {
+	rateStr := strconv.FormatInt(rate, 10)
+	brokerIds, err := a.BrokerIDs()
+	if err != nil {
+		return err
+	}
+	current, err := a.TopicAssignment(planTopics(plan))
+	if err != nil {
+		return err
+	}
+	for _, id := range brokerIds {
+		entries := map[string]sarama.IncrementalAlterConfigsEntry{
+			"leader.replication.throttled.rate":   {Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &rateStr},
+			"follower.replication.throttled.rate": {Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &rateStr},
+		}
+		if err = a.IncrementalAlterConfig(sarama.BrokerResource, strconv.Itoa(id), entries, false); err != nil {
+			return fmt.Errorf("set broker %d throttle failed: %w", id, err)
+		}
+	}
+	leaders, followers := throttledReplicas(current, plan)
+	for _, topic := range planTopics(plan) {
+		if leaders[topic] == "" {
+			continue
+		}
+		leader, follower := leaders[topic], followers[topic]
+		entries := map[string]sarama.IncrementalAlterConfigsEntry{
+			"leader.replication.throttled.replicas":   {Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &leader},
+			"follower.replication.throttled.replicas": {Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &follower},
+		}
+		if err = a.IncrementalAlterConfig(sarama.TopicResource, topic, entries, false); err != nil {
+			return fmt.Errorf("set topic %s throttle failed: %w", topic, err)
+		}
+	}
+	logger.Info("set throttle rate %d bytes/s", rate)
+	return nil
+}
+
+// throttledReplicas 按topic返回需要限速的副本, 格式为 partition:broker,partition:broker
+// leader为迁移分区当前的副本, follower为迁移后新增的副本
+func throttledReplicas(current, plan *ReassignmentPlan) (leaders, followers map[string]string) {
+	currentReplicas := make(map[string][]int, len(current.Partitions))
+	for _, p := range current.Partitions {
+		currentReplicas[fmt.Sprintf("%s-%d", p.Topic, p.Partition)] = p.Replicas
+	}
+	leaderPairs, followerPairs := make(map[string][]string), make(map[string][]string)
+	for _, p := range movedPartitions(current, plan) {
+		existing := currentReplicas[fmt.Sprintf("%s-%d", p.Topic, p.Partition)]
+		for _, broker := range existing {
+			leaderPairs[p.Topic] = append(leaderPairs[p.Topic], fmt.Sprintf("%d:%d", p.Partition, broker))
+		}
+		for _, broker := range p.Replicas {
+			if !slices.Contains(existing, broker) {
+				followerPairs[p.Topic] = append(followerPairs[p.Topic], fmt.Sprintf("%d:%d", p.Partition, broker))
+			}
+		}
+	}
+	leaders, followers = make(map[string]string), make(map[string]string)
+	for topic, pairs := range leaderPairs {
+		leaders[topic] = strings.Join(pairs, ",")
+		followers[topic] = strings.Join(followerPairs[topic], ",")
+	}
+	return leaders, followers
+}
+
+// ClearThrottle 迁移完成后清理限速配置
+func (a *KafkaAdmin) ClearThrottle(plan *ReassignmentPlan) error {
+	brokerIds, err := a.BrokerIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range brokerIds {
+		entries := map[string]sarama.IncrementalAlterConfigsEntry{
+			"leader.replication.throttled.rate":   {Operation: sarama.IncrementalAlterConfigsOperationDelete},
+			"follower.replication.throttled.rate": {Operation: sarama.IncrementalAlterConfigsOperationDelete},
+		}
+		if err = a.IncrementalAlterConfig(sarama.BrokerResource, strconv.Itoa(id), entries, false); err != nil {
+			return fmt.Errorf("clear broker %d throttle failed: %w", id, err)
+		}
+	}
+	for _, topic := range planTopics(plan) {
+		entries := map[string]sarama.IncrementalAlterConfigsEntry{
+			"leader.replication.throttled.replicas":   {Operation: sarama.IncrementalAlterConfigsOperationDelete},
+			"follower.replication.throttled.replicas": {Operation: sarama.IncrementalAlterConfigsOperationDelete},
+		}
+		if err = a.IncrementalAlterConfig(sarama.TopicResource, topic, entries, false); err != nil {
+			return fmt.Errorf("clear topic %s throttle failed: %w", topic, err)
+		}
+	}
+	logger.Info("throttle cleared")
+	return nil
+}
+
+// BrokerPartitionCount 返回broker上的分区副本数, 不包含KRaft元数据分区
+func (a *KafkaAdmin) BrokerPartitionCount(brokerID int) (int, error) {
+	logDirs, err := a.DescribeLogDirs([]int32{int32(brokerID)})
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, dir := range logDirs[int32(brokerID)] {
+		for _, t := range dir.Topics {
+			if t.Topic == "__cluster_metadata" {
+				continue
+			}
+			count += len(t.Partitions)
+		}
+	}
+	return count, nil
+}
+
+// WritePlanFile 写入迁移计划, 格式与 kafka-reassign-partitions.sh 一致
+func WritePlanFile(filePath string, plan *ReassignmentPlan) error {
+	b, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, b, 0644)
+}
+
+// LoadPlanFile 读取迁移计划
+func LoadPlanFile(filePath string) (*ReassignmentPlan, error) {
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	plan := &ReassignmentPlan{}
+	if err = json.Unmarshal(b, plan); err != nil {
+		return nil, fmt.Errorf("unmarshal %s failed: %w", filePath, err)
+	}
+	return plan, nil
+}
+
+// HasReplicaOn 计划中是否有分区副本在brokerIds上
+func HasReplicaOn(plan *ReassignmentPlan, brokerIds []int) bool {
+	for _, p := range plan.Partitions {
+		for _, r := range p.Replicas {
+			for _, id := range brokerIds {
+				if r == id {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func planTopics(plan *ReassignmentPlan) []string {
+	seen := make(map[string]bool)
+	var topics []string
+	for _, p := range plan.Partitions {
+		if !seen[p.Topic] {
+			seen[p.Topic] = true
+			topics = append(topics, p.Topic)
+		}
+	}
+	return topics
+}
+
+func intsToInt32s(ints []int) []int32 {
+	result := make([]int32, len(ints))
+	for i, n := range ints {
+		result[i] = int32(n)
+	}
+	return result
+}
@@ -0,0 +1,209 @@
+package kafkautil
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+func TestReadJaasUser(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		content  string
+		username string
+		password string
+		wantErr  bool
+	}{
+		{"scram", `KafkaServer {
+  org.apache.kafka.common.security.scram.ScramLoginModule required
+  username="admin"
+  password="pass=1";
+};`, "admin", "pass=1", false},
+		{"empty password", `username="admin" password=""`, "admin", "", false},
+		{"no password", `username="admin"`, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			username, password, err := ReadJaasUser(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err:%v, wantErr:%v", err, tt.wantErr)
+			}
+			if username != tt.username || password != tt.password {
+				t.Fatalf("got %q %q, want %q %q", username, password, tt.username, tt.password)
+			}
+		})
+	}
+	if _, _, err := ReadJaasUser(filepath.Join(dir, "not-exist")); err == nil {
+		t.Fatal("not exist file should fail")
+	}
+}
+
+func TestPlanFile(t *testing.T) {
+	plan := &ReassignmentPlan{Version: 1, Partitions: []Partition{
+		{Topic: "t1", Partition: 0, Replicas: []int{1, 2}},
+		{Topic: "t2", Partition: 0, Replicas: []int{2, 3}},
+		{Topic: "t1", Partition: 1, Replicas: []int{3, 1}},
+	}}
+	file := filepath.Join(t.TempDir(), "plan.json")
+	if err := WritePlanFile(file, plan); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadPlanFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, plan) {
+		t.Fatalf("got %+v, want %+v", got, plan)
+	}
+	if topics := planTopics(plan); !reflect.DeepEqual(topics, []string{"t1", "t2"}) {
+		t.Fatalf("planTopics got %v", topics)
+	}
+
+	tests := []struct {
+		brokerIds []int
+		want      bool
+	}{
+		{[]int{3}, true},
+		{[]int{4, 1}, true},
+		{[]int{4, 5}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := HasReplicaOn(plan, tt.brokerIds); got != tt.want {
+			t.Errorf("HasReplicaOn(%v)=%v, want %v", tt.brokerIds, got, tt.want)
+		}
+	}
+
+	if err = os.WriteFile(file, []byte("{bad"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadPlanFile(file); err == nil {
+		t.Fatal("bad plan file should fail")
+	}
+}
+
+func TestIntsToInt32s(t *testing.T) {
+	if got := intsToInt32s([]int{1, 2, 3}); !reflect.DeepEqual(got, []int32{1, 2, 3}) {
+		t.Fatalf("got %v", got)
+	}
+	if got := intsToInt32s(nil); len(got) != 0 {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestTopicAssignment(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("t2", 0, broker.BrokerID()).
+			SetLeader("t1", 1, broker.BrokerID()).
+			SetLeader("t1", 0, broker.BrokerID()),
+	})
+
+	admin, err := NewClusterAdmin([]string{broker.Addr()}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	ids, err := admin.BrokerIDs()
+	if err != nil || !reflect.DeepEqual(ids, []int{1}) {
+		t.Fatalf("BrokerIDs got %v, err:%v", ids, err)
+	}
+	plan, err := admin.TopicAssignment([]string{"t1", "t2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Partition{
+		{Topic: "t1", Partition: 0, Replicas: []int{1}},
+		{Topic: "t1", Partition: 1, Replicas: []int{1}},
+		{Topic: "t2", Partition: 0, Replicas: []int{1}},
+	}
+	if !reflect.DeepEqual(plan.Partitions, want) {
+		t.Fatalf("got %+v, want %+v", plan.Partitions, want)
+	}
+}
+
+func TestMovedPartitions(t *testing.T) {
+	current := &ReassignmentPlan{Version: 1, Partitions: []Partition{
+		{Topic: "t1", Partition: 0, Replicas: []int{1, 2}},
+		{Topic: "t1", Partition: 1, Replicas: []int{2, 3}},
+		{Topic: "t1", Partition: 2, Replicas: []int{3, 1}},
+	}}
+	plan := &ReassignmentPlan{Version: 1, Partitions: []Partition{
+		{Topic: "t1", Partition: 0, Replicas: []int{1, 2}},
+		{Topic: "t1", Partition: 1, Replicas: []int{2, 4}},
+		{Topic: "t1", Partition: 2, Replicas: []int{1, 3}},
+	}}
+	want := []Partition{
+		{Topic: "t1", Partition: 1, Replicas: []int{2, 4}},
+		{Topic: "t1", Partition: 2, Replicas: []int{1, 3}},
+	}
+	if got := movedPartitions(current, plan); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if got := movedPartitions(current, current); len(got) != 0 {
+		t.Fatalf("got %+v, want none", got)
+	}
+}
+
+func TestExecuteReassignmentNoMove(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("t1", 0, broker.BrokerID()),
+	})
+	admin, err := NewClusterAdmin([]string{broker.Addr()}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	plan := &ReassignmentPlan{Version: 1, Partitions: []Partition{{Topic: "t1", Partition: 0, Replicas: []int{1}}}}
+	if err = admin.ExecuteReassignment(plan); err != nil {
+		t.Fatal(err)
+	}
+	for _, rr := range broker.History() {
+		if _, ok := rr.Request.(*sarama.AlterPartitionReassignmentsRequest); ok {
+			t.Fatal("partitions on target replicas should not be submitted")
+		}
+	}
+}
+
+func TestThrottledReplicas(t *testing.T) {
+	current := &ReassignmentPlan{Version: 1, Partitions: []Partition{
+		{Topic: "t1", Partition: 0, Replicas: []int{1, 2}},
+		{Topic: "t1", Partition: 1, Replicas: []int{2, 3}},
+		{Topic: "t1", Partition: 2, Replicas: []int{3, 1}},
+		{Topic: "t2", Partition: 0, Replicas: []int{1, 2}},
+	}}
+	plan := &ReassignmentPlan{Version: 1, Partitions: []Partition{
+		{Topic: "t1", Partition: 0, Replicas: []int{1, 2}},
+		{Topic: "t1", Partition: 1, Replicas: []int{2, 4}},
+		{Topic: "t1", Partition: 2, Replicas: []int{4, 5}},
+		{Topic: "t2", Partition: 0, Replicas: []int{1, 2}},
+	}}
+	leaders, followers := throttledReplicas(current, plan)
+	wantLeaders := map[string]string{"t1": "1:2,1:3,2:3,2:1"}
+	wantFollowers := map[string]string{"t1": "1:4,2:4,2:5"}
+	if !reflect.DeepEqual(leaders, wantLeaders) {
+		t.Errorf("leaders got %v, want %v", leaders, wantLeaders)
+	}
+	if !reflect.DeepEqual(followers, wantFollowers) {
+		t.Errorf("followers got %v, want %v", followers, wantFollowers)
+	}
+}
@@ -0,0 +1,219 @@
+package kafkautil
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"dbm-services/bigdata/db-tools/dbactuator/pkg/core/cst"
+	"dbm-services/common/go-pubpkg/logger"
+)
+
+// KRaftConfig KRaft模式需要写入server.properties的配置
+type KRaftConfig struct {
+	NodeID                 int    // node.id
+	ProcessRoles           string // broker / controller / broker,controller
+	ControllerQuorumVoters string // 1@ip1:9093,2@ip2:9093,3@ip3:9093
+	ControllerListener     string // ip:9093, 仅controller角色需要
+	SuperUser              string // 管理用户, 写入super.users, 同时作为controller监听的SASL用户
+	SuperPassword          string // 管理用户密码
+}
+
+// HasRole 是否包含某个角色
+func (c KRaftConfig) HasRole(role string) bool {
+	for _, r := range strings.Split(c.ProcessRoles, ",") {
+		if strings.TrimSpace(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKRaft server.properties中配置了process.roles即为KRaft模式
+func IsKRaft(configFile string) bool {
+	props, err := ReadProperties(configFile)
+	if err != nil {
+		return false
+	}
+	_, ok := props[cst.KafkaProcessRolesKey]
+	return ok
+}
+
+// GetInstalledVersion 从 /data/kafkaenv/kafka -> kafka-$version 软链接中获取版本
+func GetInstalledVersion() string {
+	target, err := os.Readlink(cst.DefaultKafkaDir)
+	if err != nil {
+		logger.Warn("readlink %s failed, %v", cst.DefaultKafkaDir, err)
+		return ""
+	}
+	return strings.TrimPrefix(filepath.Base(target), "kafka-")
+}
+
+// UseAdminAPI 0.10.2 不支持 Admin API 的分区迁移, 仍然走zookeeper
+func UseAdminAPI() bool {
+	return GetInstalledVersion() != cst.Kafka0102
+}
+
+// ReadProperties 读取properties文件, 忽略空行和注释
+func ReadProperties(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		props[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return props, scanner.Err()
+}
+
+// WriteProperties 按key排序写入properties文件
+func WriteProperties(filePath string, props map[string]string) error {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", key, props[key])
+	}
+	return os.WriteFile(filePath, []byte(b.String()), 0644)
+}
+
+// ApplyKRaftConfig 将zookeeper模式的server.properties改写为KRaft模式
+func ApplyKRaftConfig(filePath string, c KRaftConfig) error {
+	props, err := ReadProperties(filePath)
+	if err != nil {
+		return fmt.Errorf("read %s failed: %w", filePath, err)
+	}
+
+	for key := range props {
+		if strings.HasPrefix(key, "zookeeper.") || key == "broker.id" {
+			delete(props, key)
+		}
+	}
+	props[cst.KafkaProcessRolesKey] = c.ProcessRoles
+	props["node.id"] = fmt.Sprintf("%d", c.NodeID)
+	props["controller.quorum.voters"] = c.ControllerQuorumVoters
+	props["controller.listener.names"] = cst.KafkaControllerListenerName
+
+	// 开启鉴权时controller监听使用SASL_PLAINTEXT, 未开启时使用PLAINTEXT, broker监听协议保持不变
+	controllerProtocol := "PLAINTEXT"
+	if c.SuperUser != "" {
+		controllerProtocol = "SASL_PLAINTEXT"
+	}
+	protocolMap := []string{cst.KafkaControllerListenerName + ":" + controllerProtocol}
+	for _, p := range []string{"PLAINTEXT", "SASL_PLAINTEXT"} {
+		protocolMap = append(protocolMap, p+":"+p)
+	}
+	props["listener.security.protocol.map"] = strings.Join(protocolMap, ",")
+
+	controllerListener := fmt.Sprintf("%s://%s", cst.KafkaControllerListenerName, c.ControllerListener)
+	switch {
+	case c.HasRole("broker") && c.HasRole("controller"):
+		props["listeners"] = props["listeners"] + "," + controllerListener
+	case c.HasRole("controller"):
+		props["listeners"] = controllerListener
+		for _, key := range []string{"advertised.listeners", "security.inter.broker.protocol",
+			"sasl.mechanism.inter.broker.protocol"} {
+			delete(props, key)
+		}
+	}
+
+	// zookeeper模式的ACL实现不能用于KRaft
+	if _, ok := props["authorizer.class.name"]; ok {
+		props["authorizer.class.name"] = "org.apache.kafka.metadata.authorizer.StandardAuthorizer"
+	}
+	if c.SuperUser != "" {
+		if err = applyControllerSasl(props, c.SuperUser, c.SuperPassword); err != nil {
+			return err
+		}
+		props["super.users"] = "User:" + c.SuperUser
+	}
+	return WriteProperties(filePath, props)
+}
+
+// applyControllerSasl controller监听的SASL配置.
+// SCRAM用户保存在元数据日志中, controller启动时还不可用, 所以controller监听使用PLAIN,
+// broker和controller之间以管理用户的身份认证
+func applyControllerSasl(props map[string]string, username, password string) error {
+	if password == "" {
+		return fmt.Errorf("password of %s is empty", username)
+	}
+	if strings.ContainsAny(username, "\"\\ =;") || strings.ContainsAny(password, "\"\\") {
+		return fmt.Errorf("username or password contains unsupported characters for controller sasl config")
+	}
+	listenerPrefix := "listener.name." + strings.ToLower(cst.KafkaControllerListenerName) + "."
+	props["sasl.mechanism.controller.protocol"] = "PLAIN"
+	props[listenerPrefix+"sasl.enabled.mechanisms"] = "PLAIN"
+	props[listenerPrefix+"plain.sasl.jaas.config"] = fmt.Sprintf(
+		`org.apache.kafka.common.security.plain.PlainLoginModule required username="%s" password="%s" user_%s="%s";`,
+		username, password, username, password)
+	return nil
+}
+
+// GenClusterID 生成KRaft集群ID, 与 kafka-storage.sh random-uuid 格式一致
+func GenClusterID() (string, error) {
+	for {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		id := base64.RawURLEncoding.EncodeToString(b)
+		// 以'-'开头的ID在命令行中会被当做参数
+		if !strings.HasPrefix(id, "-") {
+			return id, nil
+		}
+	}
+}
+
+// FormatStorage 格式化KRaft存储目录. 同时写入SCRAM用户, KRaft模式下没有zookeeper可以提前创建用户.
+// 参数直接传给kafka-storage.sh, 不经过shell, 密码中的特殊字符不会被shell解释
+func FormatStorage(kafkaBaseDir, clusterID, configFile, username, password string) error {
+	args, err := formatStorageArgs(clusterID, configFile, username, password)
+	if err != nil {
+		return err
+	}
+	logger.Info("format kafka storage, cluster id: %s", clusterID)
+	output, err := exec.Command(filepath.Join(kafkaBaseDir, "bin", "kafka-storage.sh"), args...).CombinedOutput()
+	if err != nil {
+		logger.Error("kafka-storage.sh format failed, %s, %s", string(output), err.Error())
+		return err
+	}
+	return nil
+}
+
+// formatStorageArgs kafka-storage.sh format 的参数.
+// --add-scram 的值按','切分且以'[]'为边界, 用户名和密码中不能包含这些字符和引号
+func formatStorageArgs(clusterID, configFile, username, password string) ([]string, error) {
+	args := []string{"format", "-t", clusterID, "-c", configFile, "--ignore-formatted"}
+	if username == "" {
+		return args, nil
+	}
+	if strings.ContainsAny(username, ",[]\"") || strings.ContainsAny(password, ",[]\"") {
+		return nil, fmt.Errorf("username or password contains unsupported characters ,[]\"")
+	}
+	return append(args,
+		"--add-scram", fmt.Sprintf("SCRAM-SHA-256=[name=%s,iterations=8192,password=%s]", username, password),
+		"--add-scram", fmt.Sprintf("SCRAM-SHA-512=[name=%s,password=%s]", username, password),
+	), nil
+}
@@ -0,0 +1,204 @@
+package kafkautil
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyKRaftConfig(t *testing.T) {
+	base := map[string]string{
+		"broker.id":                            "1",
+		"zookeeper.connect":                    "zk1:2181/kafka",
+		"zookeeper.connection.timeout.ms":      "6000",
+		"listeners":                            "SASL_PLAINTEXT://1.1.1.1:9092",
+		"advertised.listeners":                 "SASL_PLAINTEXT://1.1.1.1:9092",
+		"security.inter.broker.protocol":       "SASL_PLAINTEXT",
+		"sasl.mechanism.inter.broker.protocol": "SCRAM-SHA-512",
+		"authorizer.class.name":                "kafka.security.authorizer.AclAuthorizer",
+		"log.dirs":                             "/data/kafkadata/data",
+	}
+	common := map[string]string{
+		"controller.quorum.voters":  "1@1.1.1.1:9093,2@1.1.1.2:9093,3@1.1.1.3:9093",
+		"controller.listener.names": "CONTROLLER",
+		"log.dirs":                  "/data/kafkadata/data",
+	}
+	saslProtocolMap := "CONTROLLER:SASL_PLAINTEXT,PLAINTEXT:PLAINTEXT,SASL_PLAINTEXT:SASL_PLAINTEXT"
+	controllerJaas := `org.apache.kafka.common.security.plain.PlainLoginModule required ` +
+		`username="admin" password="pass" user_admin="pass";`
+	tests := []struct {
+		name  string
+		in    map[string]string
+		c     KRaftConfig
+		want  map[string]string
+		unset []string
+	}{
+		{
+			name: "combined",
+			in:   base,
+			c: KRaftConfig{NodeID: 1, ProcessRoles: "broker,controller", ControllerListener: "1.1.1.1:9093", SuperUser: "admin",
+				SuperPassword: "pass",
+			},
+			want: map[string]string{
+				"process.roles":                                    "broker,controller",
+				"node.id":                                          "1",
+				"listeners":                                        "SASL_PLAINTEXT://1.1.1.1:9092,CONTROLLER://1.1.1.1:9093",
+				"advertised.listeners":                             "SASL_PLAINTEXT://1.1.1.1:9092",
+				"security.inter.broker.protocol":                   "SASL_PLAINTEXT",
+				"sasl.mechanism.inter.broker.protocol":             "SCRAM-SHA-512",
+				"authorizer.class.name":                            "org.apache.kafka.metadata.authorizer.StandardAuthorizer",
+				"super.users":                                      "User:admin",
+				"listener.security.protocol.map":                   saslProtocolMap,
+				"sasl.mechanism.controller.protocol":               "PLAIN",
+				"listener.name.controller.sasl.enabled.mechanisms": "PLAIN",
+				"listener.name.controller.plain.sasl.jaas.config":  controllerJaas,
+			},
+			unset: []string{"broker.id", "zookeeper.connect", "zookeeper.connection.timeout.ms"},
+		},
+		{
+			name: "controller only",
+			in:   base,
+			c: KRaftConfig{NodeID: 2, ProcessRoles: "controller", ControllerListener: "1.1.1.2:9093", SuperUser: "admin",
+				SuperPassword: "pass",
+			},
+			want: map[string]string{
+				"process.roles":                  "controller",
+				"node.id":                        "2",
+				"listeners":                      "CONTROLLER://1.1.1.2:9093",
+				"authorizer.class.name":          "org.apache.kafka.metadata.authorizer.StandardAuthorizer",
+				"super.users":                    "User:admin",
+				"listener.security.protocol.map": saslProtocolMap,
+				"listener.name.controller.plain.sasl.jaas.config": controllerJaas,
+			},
+			unset: []string{"broker.id", "advertised.listeners", "security.inter.broker.protocol",
+				"sasl.mechanism.inter.broker.protocol"},
+		},
+		{
+			name: "broker without security",
+			in: map[string]string{
+				"broker.id":         "3",
+				"zookeeper.connect": "zk1:2181",
+				"listeners":         "PLAINTEXT://1.1.1.3:9092",
+				"log.dirs":          "/data/kafkadata/data",
+			},
+			c: KRaftConfig{NodeID: 3, ProcessRoles: "broker", ControllerListener: "1.1.1.3:9093"},
+			want: map[string]string{
+				"process.roles":                  "broker",
+				"node.id":                        "3",
+				"listeners":                      "PLAINTEXT://1.1.1.3:9092",
+				"listener.security.protocol.map": "CONTROLLER:PLAINTEXT,PLAINTEXT:PLAINTEXT,SASL_PLAINTEXT:SASL_PLAINTEXT",
+			},
+			unset: []string{"broker.id", "zookeeper.connect", "authorizer.class.name", "super.users",
+				"sasl.mechanism.controller.protocol", "listener.name.controller.plain.sasl.jaas.config"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "server.properties")
+			if err := WriteProperties(file, tt.in); err != nil {
+				t.Fatal(err)
+			}
+			tt.c.ControllerQuorumVoters = common["controller.quorum.voters"]
+			if err := ApplyKRaftConfig(file, tt.c); err != nil {
+				t.Fatal(err)
+			}
+			props, err := ReadProperties(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range common {
+				if props[key] != want {
+					t.Errorf("%s=%q, want %q", key, props[key], want)
+				}
+			}
+			for key, want := range tt.want {
+				if props[key] != want {
+					t.Errorf("%s=%q, want %q", key, props[key], want)
+				}
+			}
+			for _, key := range tt.unset {
+				if v, ok := props[key]; ok {
+					t.Errorf("%s=%q should be removed", key, v)
+				}
+			}
+			if !IsKRaft(file) {
+				t.Errorf("IsKRaft false")
+			}
+		})
+	}
+}
+
+func TestApplyKRaftConfigInvalidPassword(t *testing.T) {
+	for _, c := range []KRaftConfig{
+		{NodeID: 1, ProcessRoles: "broker", SuperUser: "admin"},
+		{NodeID: 1, ProcessRoles: "broker", SuperUser: "admin", SuperPassword: `pa"ss`},
+		{NodeID: 1, ProcessRoles: "broker", SuperUser: "ad min", SuperPassword: "pass"},
+	} {
+		file := filepath.Join(t.TempDir(), "server.properties")
+		if err := WriteProperties(file, map[string]string{"listeners": "SASL_PLAINTEXT://1.1.1.1:9092"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := ApplyKRaftConfig(file, c); err == nil {
+			t.Errorf("ApplyKRaftConfig(%+v) should fail", c)
+		}
+	}
+}
+
+func TestReadProperties(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "server.properties")
+	content := "# comment\n\n a = 1 \nb=x=y\nbad line\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	props, err := ReadProperties(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"a": "1", "b": "x=y"}; !reflect.DeepEqual(props, want) {
+		t.Fatalf("got %v, want %v", props, want)
+	}
+	if IsKRaft(file) {
+		t.Fatalf("IsKRaft true without process.roles")
+	}
+}
+
+func TestGenClusterID(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		id, err := GenClusterID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(id) != 22 || strings.HasPrefix(id, "-") {
+			t.Fatalf("bad cluster id %q", id)
+		}
+	}
+}
+
+func TestFormatStorageArgs(t *testing.T) {
+	args, err := formatStorageArgs("id", "/conf", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"format", "-t", "id", "-c", "/conf", "--ignore-formatted"}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("got %q, want %q", args, want)
+	}
+
+	args, err = formatStorageArgs("id", "/conf", "admin", "p'a$s `x` =")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"format", "-t", "id", "-c", "/conf", "--ignore-formatted",
+		"--add-scram", "SCRAM-SHA-256=[name=admin,iterations=8192,password=p'a$s `x` =]",
+		"--add-scram", "SCRAM-SHA-512=[name=admin,password=p'a$s `x` =]"}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("got %q, want %q", args, want)
+	}
+
+	for _, password := range []string{"a,b", "a]b", "a\"b"} {
+		if _, err = formatStorageArgs("id", "/conf", "admin", password); err == nil {
+			t.Errorf("password %q should fail", password)
+		}
+	}
+}
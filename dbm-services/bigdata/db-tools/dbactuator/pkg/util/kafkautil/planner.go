@@ -0,0 +1,526 @@
+package kafkautil
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BrokerState broker当前的负载
+type BrokerState struct {
+	ID          int              `json:"id"`
+	Rack        string           `json:"rack"`
+	DiskBytes   int64            `json:"disk_bytes"`    // 所有副本大小之和
+	LogDirBytes map[string]int64 `json:"log_dir_bytes"` // 每个数据目录的副本大小
+	Leaders     int              `json:"leaders"`       // preferred leader个数
+	Replicas    int              `json:"replicas"`
+}
+
+// PartitionState 分区当前的分布
+type PartitionState struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Replicas  []int  `json:"replicas"`
+	Size      int64  `json:"size"`    // 取各副本中最大的大小
+	Movable   bool   `json:"movable"` // 是否在本次迁移的topic范围内
+}
+
+// ClusterState 生成迁移计划需要的集群信息
+type ClusterState struct {
+	Brokers    map[int]*BrokerState
+	Partitions []*PartitionState
+}
+
+// PlanOptions 迁移计划参数
+type PlanOptions struct {
+	TargetBrokers  []int       // 分区最终分布的broker
+	BalanceDisk    bool        // 是否均衡磁盘, 缩容时只搬走目标之外broker上的副本
+	BalanceLeaders bool        // 是否均衡preferred leader, 只调整副本顺序, 不搬数据
+	Threshold      float64     // 磁盘最大最小差值小于平均值*Threshold时认为已均衡
+	MaxMoves       int         // 磁盘均衡最多搬迁的副本数
+	ReplaceBrokers map[int]int // 替换模式, 旧broker上的副本优先搬到对应的新broker
+}
+
+// PlannedPartition 计划中的一个分区
+type PlannedPartition struct {
+	Topic       string `json:"topic"`
+	Partition   int    `json:"partition"`
+	Replicas    []int  `json:"replicas"`
+	OldReplicas []int  `json:"old_replicas"`
+	Size        int64  `json:"size"`
+	MoveBytes   int64  `json:"move_bytes"` // 新增副本需要复制的数据量, 只调整顺序时为0
+}
+
+// BrokerSummary 迁移前后的broker负载, 用于评审计划
+type BrokerSummary struct {
+	ID             int    `json:"id"`
+	Rack           string `json:"rack"`
+	DiskBefore     int64  `json:"disk_before"`
+	DiskAfter      int64  `json:"disk_after"`
+	LeadersBefore  int    `json:"leaders_before"`
+	LeadersAfter   int    `json:"leaders_after"`
+	ReplicasBefore int    `json:"replicas_before"`
+	ReplicasAfter  int    `json:"replicas_after"`
+	// 迁移前每个数据目录的大小
+	LogDirs map[string]int64 `json:"log_dirs,omitempty"`
+}
+
+// BalancePlan 可评审的迁移计划
+type BalancePlan struct {
+	Version    int                `json:"version"`
+	MoveBytes  int64              `json:"move_bytes"`
+	Partitions []PlannedPartition `json:"partitions"`
+	Brokers    []BrokerSummary    `json:"brokers"`
+}
+
+// ToReassignmentPlan 转换为 kafka-reassign-partitions.sh 格式
+func (p *BalancePlan) ToReassignmentPlan() *ReassignmentPlan {
+	plan := &ReassignmentPlan{Version: 1}
+	for _, pp := range p.Partitions {
+		plan.Partitions = append(plan.Partitions, Partition{Topic: pp.Topic, Partition: pp.Partition, Replicas: pp.Replicas})
+	}
+	return plan
+}
+
+// RollbackPlan 原分布, 用于回滚
+func (p *BalancePlan) RollbackPlan() *ReassignmentPlan {
+	plan := &ReassignmentPlan{Version: 1}
+	for _, pp := range p.Partitions {
+		plan.Partitions = append(plan.Partitions, Partition{Topic: pp.Topic, Partition: pp.Partition, Replicas: pp.OldReplicas})
+	}
+	return plan
+}
+
+// Batches 按分区数拆分批次, 每批的分区数不超过batchSize
+func (p *BalancePlan) Batches(batchSize int) []*BalancePlan {
+	if batchSize <= 0 {
+		batchSize = len(p.Partitions)
+	}
+	var batches []*BalancePlan
+	for start := 0; start < len(p.Partitions); start += batchSize {
+		end := start + batchSize
+		if end > len(p.Partitions) {
+			end = len(p.Partitions)
+		}
+		batch := &BalancePlan{Version: p.Version, Partitions: p.Partitions[start:end]}
+		for _, pp := range batch.Partitions {
+			batch.MoveBytes += pp.MoveBytes
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// ClusterState 获取broker机架, 分区分布和每个数据目录中的分区大小
+func (a *KafkaAdmin) ClusterState(movableTopics []string) (*ClusterState, error) {
+	brokers, _, err := a.DescribeCluster()
+	if err != nil {
+		return nil, err
+	}
+	state := &ClusterState{Brokers: make(map[int]*BrokerState)}
+	var brokerIds []int32
+	for _, b := range brokers {
+		state.Brokers[int(b.ID())] = &BrokerState{ID: int(b.ID()), Rack: b.Rack(), LogDirBytes: make(map[string]int64)}
+		brokerIds = append(brokerIds, b.ID())
+	}
+
+	topics, err := a.TopicNames()
+	if err != nil {
+		return nil, err
+	}
+	current, err := a.TopicAssignment(topics)
+	if err != nil {
+		return nil, err
+	}
+
+	logDirs, err := a.DescribeLogDirs(brokerIds)
+	if err != nil {
+		return nil, err
+	}
+	// topic-partition -> broker -> size
+	replicaSize := make(map[string]map[int]int64)
+	for id, dirs := range logDirs {
+		for _, dir := range dirs {
+			for _, t := range dir.Topics {
+				for _, p := range t.Partitions {
+					key := t.Topic + "-" + strconv.Itoa(int(p.PartitionID))
+					if replicaSize[key] == nil {
+						replicaSize[key] = make(map[int]int64)
+					}
+					replicaSize[key][int(id)] += p.Size
+					if b, ok := state.Brokers[int(id)]; ok {
+						b.LogDirBytes[dir.Path] += p.Size
+					}
+				}
+			}
+		}
+	}
+
+	movable := make(map[string]bool)
+	for _, t := range movableTopics {
+		movable[t] = true
+	}
+	for _, p := range current.Partitions {
+		ps := &PartitionState{Topic: p.Topic, Partition: p.Partition, Replicas: p.Replicas, Movable: movable[p.Topic]}
+		for _, size := range replicaSize[p.Topic+"-"+strconv.Itoa(p.Partition)] {
+			if size > ps.Size {
+				ps.Size = size
+			}
+		}
+		state.Partitions = append(state.Partitions, ps)
+	}
+	return state, nil
+}
+
+// planner 基于贪心的迁移计划, 只在需要时搬迁, 优先搬迁能最大程度缩小差距的副本
+type planner struct {
+	state     *ClusterState
+	replace   map[int]int
+	brokers   map[int]*BrokerState
+	targets   map[int]bool
+	numRacks  int
+	parts     []*PartitionState
+	oldParts  map[*PartitionState][]int
+	threshold float64
+}
+
+// GenBalancePlan 根据集群当前分布生成迁移计划
+// 1. 目标broker之外的副本必须搬走, 搬到磁盘占用最小且满足机架约束的broker
+// 2. 磁盘均衡: 从磁盘占用最大的broker向最小的broker搬迁, 直到差值小于阈值
+// 3. leader均衡: 调整副本顺序使每个broker的preferred leader个数接近
+func GenBalancePlan(state *ClusterState, opts PlanOptions) (*BalancePlan, error) {
+	p, err := newPlanner(state, opts)
+	if err != nil {
+		return nil, err
+	}
+	before := p.snapshot()
+
+	if err = p.moveOutOfTargets(); err != nil {
+		return nil, err
+	}
+	if opts.BalanceDisk {
+		maxMoves := opts.MaxMoves
+		if maxMoves <= 0 {
+			maxMoves = 10000
+		}
+		p.balanceDisk(maxMoves)
+	}
+	if opts.BalanceLeaders {
+		p.balanceLeaders()
+	}
+	return p.result(before), nil
+}
+
+// newPlanner 复制一份分区分布, 计算每个broker的当前负载
+func newPlanner(state *ClusterState, opts PlanOptions) (*planner, error) {
+	if len(opts.TargetBrokers) == 0 {
+		return nil, fmt.Errorf("target broker list is empty")
+	}
+	p := &planner{
+		state:     state,
+		replace:   opts.ReplaceBrokers,
+		brokers:   make(map[int]*BrokerState),
+		targets:   make(map[int]bool),
+		oldParts:  make(map[*PartitionState][]int),
+		threshold: opts.Threshold,
+	}
+	if p.threshold <= 0 {
+		p.threshold = 0.1
+	}
+	racks := make(map[string]bool)
+	for _, id := range opts.TargetBrokers {
+		b, ok := state.Brokers[id]
+		if !ok {
+			return nil, fmt.Errorf("broker %d not found in cluster", id)
+		}
+		p.targets[id] = true
+		racks[b.Rack] = true
+	}
+	if !racks[""] {
+		p.numRacks = len(racks)
+	}
+	for id, b := range state.Brokers {
+		p.brokers[id] = &BrokerState{ID: id, Rack: b.Rack}
+	}
+	for _, ps := range state.Partitions {
+		cp := &PartitionState{Topic: ps.Topic, Partition: ps.Partition, Size: ps.Size, Movable: ps.Movable,
+			Replicas: append([]int(nil), ps.Replicas...)}
+		p.parts = append(p.parts, cp)
+		p.oldParts[cp] = ps.Replicas
+		p.addLoad(cp)
+	}
+	return p, nil
+}
+
+func (p *planner) addLoad(ps *PartitionState) {
+	for i, r := range ps.Replicas {
+		b, ok := p.brokers[r]
+		if !ok {
+			b = &BrokerState{ID: r}
+			p.brokers[r] = b
+		}
+		b.DiskBytes += ps.Size
+		b.Replicas++
+		if i == 0 {
+			b.Leaders++
+		}
+	}
+}
+
+func (p *planner) snapshot() map[int]BrokerState {
+	s := make(map[int]BrokerState)
+	for id, b := range p.brokers {
+		s[id] = *b
+	}
+	return s
+}
+
+// move 将分区在src上的副本搬到dst, 保持副本位置不变
+func (p *planner) move(ps *PartitionState, src, dst int) {
+	for i, r := range ps.Replicas {
+		if r != src {
+			continue
+		}
+		ps.Replicas[i] = dst
+		p.brokers[src].DiskBytes -= ps.Size
+		p.brokers[src].Replicas--
+		p.brokers[dst].DiskBytes += ps.Size
+		p.brokers[dst].Replicas++
+		if i == 0 {
+			p.brokers[src].Leaders--
+			p.brokers[dst].Leaders++
+		}
+		return
+	}
+}
+
+// canMove dst上没有该分区的副本, 且搬迁后分区跨的机架数不减少
+func (p *planner) canMove(ps *PartitionState, src, dst int, checkRack bool) bool {
+	if !p.targets[dst] {
+		return false
+	}
+	for _, r := range ps.Replicas {
+		if r == dst {
+			return false
+		}
+	}
+	if !checkRack || p.numRacks <= 1 {
+		return true
+	}
+	beforeRacks, afterRacks := make(map[string]bool), make(map[string]bool)
+	for _, r := range ps.Replicas {
+		beforeRacks[p.brokers[r].Rack] = true
+		if r != src {
+			afterRacks[p.brokers[r].Rack] = true
+		}
+	}
+	afterRacks[p.brokers[dst].Rack] = true
+	want := len(ps.Replicas)
+	if p.numRacks < want {
+		want = p.numRacks
+	}
+	return len(afterRacks) >= want || len(afterRacks) >= len(beforeRacks)
+}
+
+// targetsByDisk 目标broker按磁盘占用从小到大排序
+func (p *planner) targetsByDisk() []int {
+	var ids []int
+	for id := range p.targets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if p.brokers[ids[i]].DiskBytes != p.brokers[ids[j]].DiskBytes {
+			return p.brokers[ids[i]].DiskBytes < p.brokers[ids[j]].DiskBytes
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+func (p *planner) moveOutOfTargets() error {
+	// 大分区优先, 小分区用于后面填补
+	parts := append([]*PartitionState(nil), p.parts...)
+	sort.SliceStable(parts, func(i, j int) bool { return parts[i].Size > parts[j].Size })
+	for _, ps := range parts {
+		// 不在迁移范围内的topic保持原分布
+		if !ps.Movable {
+			continue
+		}
+		for _, src := range append([]int(nil), ps.Replicas...) {
+			if p.targets[src] {
+				continue
+			}
+			dst := -1
+			if id, ok := p.replace[src]; ok && p.canMove(ps, src, id, false) {
+				dst = id
+			}
+			for _, checkRack := range []bool{true, false} {
+				if dst >= 0 {
+					break
+				}
+				for _, id := range p.targetsByDisk() {
+					if p.canMove(ps, src, id, checkRack) {
+						dst = id
+						break
+					}
+				}
+			}
+			if dst < 0 {
+				return fmt.Errorf("no broker available for %s-%d replica on broker %d", ps.Topic, ps.Partition, src)
+			}
+			p.move(ps, src, dst)
+		}
+	}
+	return nil
+}
+
+func (p *planner) balanceDisk(maxMoves int) {
+	var total int64
+	for id := range p.targets {
+		total += p.brokers[id].DiskBytes
+	}
+	avg := float64(total) / float64(len(p.targets))
+	for moves := 0; moves < maxMoves; moves++ {
+		ids := p.targetsByDisk()
+		src := ids[len(ids)-1]
+		diff := p.brokers[src].DiskBytes - p.brokers[ids[0]].DiskBytes
+		if float64(diff) <= avg*p.threshold {
+			return
+		}
+		moved := false
+		for _, dst := range ids[:len(ids)-1] {
+			gap := p.brokers[src].DiskBytes - p.brokers[dst].DiskBytes
+			// 选择最接近差值一半的副本, 搬迁后两者差距最小
+			var best *PartitionState
+			for _, ps := range p.parts {
+				if !ps.Movable || ps.Size <= 0 || ps.Size >= gap || !containsInt(ps.Replicas, src) ||
+					!p.canMove(ps, src, dst, true) {
+					continue
+				}
+				if best == nil || abs64(gap/2-ps.Size) < abs64(gap/2-best.Size) {
+					best = ps
+				}
+			}
+			if best != nil {
+				p.move(best, src, dst)
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			return
+		}
+	}
+}
+
+func (p *planner) balanceLeaders() {
+	for {
+		improved := false
+		for _, ps := range p.parts {
+			if !ps.Movable || len(ps.Replicas) < 2 {
+				continue
+			}
+			leader := ps.Replicas[0]
+			best := 0
+			for i, r := range ps.Replicas[1:] {
+				if p.brokers[r].Leaders < p.brokers[leader].Leaders-1 &&
+					(best == 0 || p.brokers[r].Leaders < p.brokers[ps.Replicas[best]].Leaders) {
+					best = i + 1
+				}
+			}
+			if best == 0 {
+				continue
+			}
+			newLeader := ps.Replicas[best]
+			ps.Replicas[0], ps.Replicas[best] = newLeader, leader
+			p.brokers[leader].Leaders--
+			p.brokers[newLeader].Leaders++
+			improved = true
+		}
+		if !improved {
+			return
+		}
+	}
+}
+
+func (p *planner) result(before map[int]BrokerState) *BalancePlan {
+	plan := &BalancePlan{Version: 1}
+	for _, ps := range p.parts {
+		old := p.oldParts[ps]
+		if intsEqual(old, ps.Replicas) {
+			continue
+		}
+		pp := PlannedPartition{Topic: ps.Topic, Partition: ps.Partition, Replicas: ps.Replicas, OldReplicas: old, Size: ps.Size}
+		for _, r := range ps.Replicas {
+			if !containsInt(old, r) {
+				pp.MoveBytes += ps.Size
+			}
+		}
+		plan.MoveBytes += pp.MoveBytes
+		plan.Partitions = append(plan.Partitions, pp)
+	}
+	// 需要搬数据的分区在前, 同一批次中大小接近
+	sort.SliceStable(plan.Partitions, func(i, j int) bool {
+		return plan.Partitions[i].MoveBytes > plan.Partitions[j].MoveBytes
+	})
+
+	var ids []int
+	for id := range p.brokers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		b, old := p.brokers[id], before[id]
+		summary := BrokerSummary{
+			ID: id, Rack: b.Rack,
+			DiskBefore: old.DiskBytes, DiskAfter: b.DiskBytes,
+			LeadersBefore: old.Leaders, LeadersAfter: b.Leaders,
+			ReplicasBefore: old.Replicas, ReplicasAfter: b.Replicas,
+		}
+		if sb, ok := p.state.Brokers[id]; ok {
+			summary.LogDirs = sb.LogDirBytes
+		}
+		plan.Brokers = append(plan.Brokers, summary)
+	}
+	return plan
+}
+
+// String 迁移计划摘要
+func (p *BalancePlan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "partitions: %d, move bytes: %d\n", len(p.Partitions), p.MoveBytes)
+	for _, s := range p.Brokers {
+		fmt.Fprintf(&b, "broker %d rack[%s] disk %d -> %d, leaders %d -> %d, replicas %d -> %d\n",
+			s.ID, s.Rack, s.DiskBefore, s.DiskAfter, s.LeadersBefore, s.LeadersAfter, s.ReplicasBefore, s.ReplicasAfter)
+	}
+	return b.String()
+}
+
+func containsInt(arr []int, n int) bool {
+	for _, v := range arr {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+func intsEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
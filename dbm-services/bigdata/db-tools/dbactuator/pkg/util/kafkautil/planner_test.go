@@ -0,0 +1,334 @@
+package kafkautil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testState racks[i] 为 broker i+1 的机架, parts 为 [size, movable(0/1), replicas...]
+func testState(racks []string, parts ...[]int) *ClusterState {
+	state := &ClusterState{Brokers: make(map[int]*BrokerState)}
+	for i, rack := range racks {
+		state.Brokers[i+1] = &BrokerState{ID: i + 1, Rack: rack, LogDirBytes: map[string]int64{}}
+	}
+	for i, p := range parts {
+		state.Partitions = append(state.Partitions, &PartitionState{Topic: "t", Partition: i,
+			Size: int64(p[0]), Movable: p[1] == 1, Replicas: p[2:]})
+	}
+	return state
+}
+
+func partReplicas(p *planner) [][]int {
+	var replicas [][]int
+	for _, ps := range p.parts {
+		replicas = append(replicas, ps.Replicas)
+	}
+	return replicas
+}
+
+func TestGenBalancePlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    *ClusterState
+		opts     PlanOptions
+		wantErr  string
+		replicas map[int][]int // partition -> 新副本, 不在计划中的分区不变
+		bytes    int64
+	}{
+		{
+			name:    "empty targets",
+			state:   testState([]string{"", ""}, []int{10, 1, 1, 2}),
+			wantErr: "empty",
+		},
+		{
+			name:    "target not found",
+			state:   testState([]string{"", ""}, []int{10, 1, 1, 2}),
+			opts:    PlanOptions{TargetBrokers: []int{1, 3}},
+			wantErr: "not found",
+		},
+		{
+			name:    "no broker available",
+			state:   testState([]string{"", "", ""}, []int{10, 1, 1, 3}),
+			opts:    PlanOptions{TargetBrokers: []int{1}},
+			wantErr: "no broker available",
+		},
+		{
+			name:  "balanced",
+			state: testState([]string{"", ""}, []int{10, 1, 1, 2}, []int{10, 1, 2, 1}),
+			opts:  PlanOptions{TargetBrokers: []int{1, 2}, BalanceDisk: true, BalanceLeaders: true},
+		},
+		{
+			name: "decommission",
+			state: testState([]string{"", "", ""},
+				[]int{100, 1, 3, 1}, []int{50, 1, 2, 3}, []int{10, 1, 1, 2}, []int{70, 0, 3, 2}),
+			opts:     PlanOptions{TargetBrokers: []int{1, 2}},
+			replicas: map[int][]int{0: {2, 1}, 1: {2, 1}},
+			bytes:    150,
+		},
+		{
+			name: "replace",
+			state: testState([]string{"", "", "", ""},
+				[]int{100, 1, 3, 1}, []int{50, 1, 2, 3}, []int{10, 1, 1, 2}),
+			opts:     PlanOptions{TargetBrokers: []int{1, 2, 4}, ReplaceBrokers: map[int]int{3: 4}},
+			replicas: map[int][]int{0: {4, 1}, 1: {2, 4}},
+			bytes:    150,
+		},
+		{
+			name:     "leaders only",
+			state:    testState([]string{"", ""}, []int{10, 1, 1, 2}, []int{10, 1, 1, 2}),
+			opts:     PlanOptions{TargetBrokers: []int{1, 2}, BalanceLeaders: true},
+			replicas: map[int][]int{0: {2, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := GenBalancePlan(tt.state, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err:%v, want contains %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[int][]int)
+			for _, pp := range plan.Partitions {
+				got[pp.Partition] = pp.Replicas
+				if !reflect.DeepEqual(pp.OldReplicas, tt.state.Partitions[pp.Partition].Replicas) {
+					t.Errorf("partition %d old replicas %v", pp.Partition, pp.OldReplicas)
+				}
+			}
+			if len(got) != len(tt.replicas) || (len(got) > 0 && !reflect.DeepEqual(got, tt.replicas)) {
+				t.Fatalf("plan replicas %v, want %v", got, tt.replicas)
+			}
+			if plan.MoveBytes != tt.bytes {
+				t.Errorf("move bytes %d, want %d", plan.MoveBytes, tt.bytes)
+			}
+			if rollback := plan.RollbackPlan(); len(rollback.Partitions) != len(plan.Partitions) {
+				t.Errorf("rollback partitions %d", len(rollback.Partitions))
+			}
+			// 输入的集群状态不被修改
+			if tt.name == "decommission" && !reflect.DeepEqual(tt.state.Partitions[0].Replicas, []int{3, 1}) {
+				t.Errorf("state modified: %v", tt.state.Partitions[0].Replicas)
+			}
+		})
+	}
+}
+
+func TestBalanceDisk(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    *ClusterState
+		targets  []int
+		maxMoves int
+		replicas [][]int
+	}{
+		{
+			name:     "move closest to half gap",
+			state:    testState([]string{"", ""}, []int{100, 1, 1}, []int{80, 1, 1}, []int{10, 1, 2}),
+			targets:  []int{1, 2},
+			maxMoves: 10,
+			replicas: [][]int{{1}, {2}, {2}},
+		},
+		{
+			name:     "stop within threshold",
+			state:    testState([]string{"", ""}, []int{100, 1, 1}, []int{100, 1, 1}, []int{10, 1, 2}),
+			targets:  []int{1, 2},
+			maxMoves: 10,
+			replicas: [][]int{{2}, {1}, {2}},
+		},
+		{
+			name:     "not movable",
+			state:    testState([]string{"", ""}, []int{100, 0, 1}, []int{80, 0, 1}, []int{10, 1, 2}),
+			targets:  []int{1, 2},
+			maxMoves: 10,
+			replicas: [][]int{{1}, {1}, {2}},
+		},
+		{
+			name:     "max moves",
+			state:    testState([]string{"", ""}, []int{100, 1, 1}, []int{80, 1, 1}, []int{10, 1, 2}),
+			targets:  []int{1, 2},
+			maxMoves: 0,
+			replicas: [][]int{{1}, {1}, {2}},
+		},
+		{
+			name:     "keep rack spread",
+			state:    testState([]string{"a", "a", "b"}, []int{100, 1, 1, 3}, []int{100, 1, 1, 3}),
+			targets:  []int{1, 2, 3},
+			maxMoves: 10,
+			replicas: [][]int{{1, 3}, {1, 3}},
+		},
+		{
+			name:     "only move to targets",
+			state:    testState([]string{"", "", ""}, []int{100, 1, 1}, []int{80, 1, 1}),
+			targets:  []int{1, 2},
+			maxMoves: 10,
+			replicas: [][]int{{2}, {1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newPlanner(tt.state, PlanOptions{TargetBrokers: tt.targets})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.balanceDisk(tt.maxMoves)
+			if got := partReplicas(p); !reflect.DeepEqual(got, tt.replicas) {
+				t.Fatalf("replicas %v, want %v", got, tt.replicas)
+			}
+		})
+	}
+}
+
+func TestBalanceLeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    *ClusterState
+		replicas [][]int
+		leaders  map[int]int
+	}{
+		{
+			name: "swap leaders",
+			state: testState([]string{"", ""},
+				[]int{1, 1, 1, 2}, []int{1, 1, 1, 2}, []int{1, 1, 1, 2}, []int{1, 1, 1, 2}),
+			replicas: [][]int{{2, 1}, {2, 1}, {1, 2}, {1, 2}},
+			leaders:  map[int]int{1: 2, 2: 2},
+		},
+		{
+			name:     "pick least leaders",
+			state:    testState([]string{"", "", ""}, []int{1, 1, 1, 2, 3}, []int{1, 1, 1, 2, 3}, []int{1, 1, 2, 1}),
+			replicas: [][]int{{3, 2, 1}, {1, 2, 3}, {2, 1}},
+			leaders:  map[int]int{1: 1, 2: 1, 3: 1},
+		},
+		{
+			name:     "not movable and single replica",
+			state:    testState([]string{"", ""}, []int{1, 0, 1, 2}, []int{1, 0, 1, 2}, []int{1, 1, 1}),
+			replicas: [][]int{{1, 2}, {1, 2}, {1}},
+			leaders:  map[int]int{1: 3, 2: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newPlanner(tt.state, PlanOptions{TargetBrokers: []int{1, 2}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.balanceLeaders()
+			if got := partReplicas(p); !reflect.DeepEqual(got, tt.replicas) {
+				t.Fatalf("replicas %v, want %v", got, tt.replicas)
+			}
+			for id, want := range tt.leaders {
+				if got := p.brokers[id].Leaders; got != want {
+					t.Errorf("broker %d leaders %d, want %d", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMoveOutOfTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    *ClusterState
+		opts     PlanOptions
+		wantErr  bool
+		replicas [][]int
+	}{
+		{
+			name:     "least disk target",
+			state:    testState([]string{"", "", ""}, []int{100, 1, 3}, []int{50, 1, 1}),
+			opts:     PlanOptions{TargetBrokers: []int{1, 2}},
+			replicas: [][]int{{2}, {1}},
+		},
+		{
+			name:     "large partition first",
+			state:    testState([]string{"", "", ""}, []int{10, 1, 3}, []int{100, 1, 3}),
+			opts:     PlanOptions{TargetBrokers: []int{1, 2}},
+			replicas: [][]int{{2}, {1}},
+		},
+		{
+			name:     "keep position",
+			state:    testState([]string{"", "", ""}, []int{10, 1, 3, 1}),
+			opts:     PlanOptions{TargetBrokers: []int{1, 2}},
+			replicas: [][]int{{2, 1}},
+		},
+		{
+			name:     "replace broker",
+			state:    testState([]string{"", "", "", ""}, []int{10, 1, 3}, []int{100, 1, 4}),
+			opts:     PlanOptions{TargetBrokers: []int{1, 2, 4}, ReplaceBrokers: map[int]int{3: 4}},
+			replicas: [][]int{{4}, {4}},
+		},
+		{
+			name:     "rack",
+			state:    testState([]string{"a", "a", "b", "b"}, []int{10, 1, 1, 4}, []int{1000, 0, 3}),
+			opts:     PlanOptions{TargetBrokers: []int{1, 2, 3}},
+			replicas: [][]int{{1, 3}, {3}},
+		},
+		{
+			name:     "rack relaxed",
+			state:    testState([]string{"a", "a", "b", "b"}, []int{10, 1, 1, 3, 4}),
+			opts:     PlanOptions{TargetBrokers: []int{1, 2, 3}},
+			replicas: [][]int{{1, 3, 2}},
+		},
+		{
+			name:     "not movable",
+			state:    testState([]string{"", "", ""}, []int{10, 0, 3}),
+			opts:     PlanOptions{TargetBrokers: []int{1, 2}},
+			replicas: [][]int{{3}},
+		},
+		{
+			name:    "no broker",
+			state:   testState([]string{"", "", ""}, []int{10, 1, 1, 2, 3}),
+			opts:    PlanOptions{TargetBrokers: []int{1, 2}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newPlanner(tt.state, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = p.moveOutOfTargets()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err:%v, wantErr:%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := partReplicas(p); !reflect.DeepEqual(got, tt.replicas) {
+				t.Fatalf("replicas %v, want %v", got, tt.replicas)
+			}
+		})
+	}
+}
+
+func TestBalancePlanBatches(t *testing.T) {
+	plan := &BalancePlan{Version: 1}
+	for i := 0; i < 5; i++ {
+		plan.Partitions = append(plan.Partitions, PlannedPartition{Topic: "t", Partition: i, MoveBytes: int64(i)})
+	}
+	tests := []struct {
+		batchSize int
+		sizes     []int
+		bytes     []int64
+	}{
+		{2, []int{2, 2, 1}, []int64{1, 5, 4}},
+		{5, []int{5}, []int64{10}},
+		{0, []int{5}, []int64{10}},
+	}
+	for _, tt := range tests {
+		batches := plan.Batches(tt.batchSize)
+		var sizes []int
+		var bytes []int64
+		for _, b := range batches {
+			sizes = append(sizes, len(b.Partitions))
+			bytes = append(bytes, b.MoveBytes)
+		}
+		if !reflect.DeepEqual(sizes, tt.sizes) || !reflect.DeepEqual(bytes, tt.bytes) {
+			t.Errorf("Batches(%d) sizes:%v bytes:%v, want %v %v", tt.batchSize, sizes, bytes, tt.sizes, tt.bytes)
+		}
+	}
+}
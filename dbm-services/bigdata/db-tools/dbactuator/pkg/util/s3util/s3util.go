@@ -0,0 +1,164 @@
+// Package s3util S3兼容对象存储(cos/minio)的上传下载, 用于备份文件的存取
+package s3util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"dbm-services/common/go-pubpkg/logger"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Config S3兼容存储配置
+type Config struct {
+	Endpoint  string `json:"endpoint"`   // eg: http://127.0.0.1:9000, 不带scheme时默认https
+	Region    string `json:"region"`     //
+	Bucket    string `json:"bucket"`     //
+	BasePath  string `json:"base_path"`  // 备份存放的前缀
+	AccessKey string `json:"access_key"` //
+	SecretKey string `json:"secret_key"` //
+	PathStyle bool   `json:"path_style"` // minio需要为true
+}
+
+// Enabled 是否配置了S3
+func (c *Config) Enabled() bool {
+	return c != nil && c.Bucket != ""
+}
+
+// Host 去掉scheme的endpoint
+func (c *Config) Host() (host string, secure bool) {
+	if u, err := url.Parse(c.Endpoint); err == nil && u.Host != "" {
+		return u.Host, u.Scheme == "https"
+	}
+	return c.Endpoint, true
+}
+
+// URL 带scheme的endpoint
+func (c *Config) URL() string {
+	host, secure := c.Host()
+	if secure {
+		return "https://" + host
+	}
+	return "http://" + host
+}
+
+// Key 拼接对象路径
+func (c *Config) Key(elem ...string) string {
+	return path.Join(append([]string{strings.Trim(c.BasePath, "/")}, elem...)...)
+}
+
+// Client 对象存储客户端
+type Client struct {
+	*minio.Client
+	Conf *Config
+}
+
+// NewClient 创建客户端
+func NewClient(c *Config) (*Client, error) {
+	host, secure := c.Host()
+	lookup := minio.BucketLookupDNS
+	if c.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+	cli, err := minio.New(host, &minio.Options{
+		Creds:        credentials.NewStaticV4(c.AccessKey, c.SecretKey, ""),
+		Secure:       secure,
+		Region:       c.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new s3 client failed: %w", err)
+	}
+	return &Client{Client: cli, Conf: c}, nil
+}
+
+// UploadDir 上传目录下所有文件到 prefix/
+func (c *Client) UploadDir(localDir, prefix string) error {
+	return filepath.Walk(localDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		key := path.Join(prefix, filepath.ToSlash(rel))
+		logger.Info("upload %s to s3://%s/%s", p, c.Conf.Bucket, key)
+		_, err = c.FPutObject(context.Background(), c.Conf.Bucket, key, p, minio.PutObjectOptions{})
+		return err
+	})
+}
+
+// DownloadDir 下载 prefix/ 下所有文件到本地目录
+func (c *Client) DownloadDir(prefix, localDir string) error {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	for obj := range c.ListObjects(context.Background(), c.Conf.Bucket,
+		minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		dst := filepath.Join(localDir, filepath.FromSlash(strings.TrimPrefix(obj.Key, prefix)))
+		logger.Info("download s3://%s/%s to %s", c.Conf.Bucket, obj.Key, dst)
+		if err := c.FGetObject(context.Background(), c.Conf.Bucket, obj.Key, dst, minio.GetObjectOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListDirs 列出 prefix/ 下一级的"目录"名, 升序
+func (c *Client) ListDirs(prefix string) ([]string, error) {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	var dirs []string
+	for obj := range c.ListObjects(context.Background(), c.Conf.Bucket,
+		minio.ListObjectsOptions{Prefix: prefix}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		if strings.HasSuffix(obj.Key, "/") {
+			dirs = append(dirs, strings.TrimSuffix(strings.TrimPrefix(obj.Key, prefix), "/"))
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// ReadObject 读取小文件
+func (c *Client) ReadObject(key string) ([]byte, error) {
+	obj, err := c.Client.GetObject(context.Background(), c.Conf.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return io.ReadAll(obj)
+}
+
+// RemoveDir 删除 prefix/ 下所有对象
+func (c *Client) RemoveDir(prefix string) error {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	objectsCh := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objectsCh)
+		for obj := range c.ListObjects(context.Background(), c.Conf.Bucket,
+			minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if obj.Err == nil {
+				objectsCh <- obj
+			}
+		}
+	}()
+	for e := range c.RemoveObjects(context.Background(), c.Conf.Bucket, objectsCh, minio.RemoveObjectsOptions{}) {
+		if e.Err != nil {
+			return fmt.Errorf("remove %s failed: %w", e.ObjectName, e.Err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package s3util
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"dbm-services/common/go-pubpkg/logger"
+)
+
+// BackupIDLayout 备份ID即备份开始时间
+const BackupIDLayout = "20060102150405"
+
+// BackupTarget 备份存放位置, 配置了S3时存放在 s3://bucket/base_path/{host}/{id}, 否则存放在 local_dir/{host}/{id}
+type BackupTarget struct {
+	LocalDir string  `json:"local_dir"`
+	S3       *Config `json:"s3"`
+	Host     string  `json:"-"`
+}
+
+// NewBackupID 生成备份ID
+func NewBackupID() string {
+	return time.Now().Format(BackupIDLayout)
+}
+
+// Dir 本地目录 local_dir/{host}/{id}
+func (t *BackupTarget) Dir(id string) string {
+	return filepath.Join(t.LocalDir, t.Host, id)
+}
+
+// Key 对象存储前缀 base_path/{host}/{id}
+func (t *BackupTarget) Key(id string) string {
+	return t.S3.Key(t.Host, id)
+}
+
+// URL vmbackup等工具使用的地址, fs:///path 或 s3://bucket/path
+func (t *BackupTarget) URL(id string) string {
+	if t.S3.Enabled() {
+		return fmt.Sprintf("s3://%s/%s", t.S3.Bucket, t.Key(id))
+	}
+	return "fs://" + t.Dir(id)
+}
+
+// ListBackups 按时间升序列出备份ID
+func (t *BackupTarget) ListBackups() ([]string, error) {
+	var ids []string
+	if t.S3.Enabled() {
+		c, err := NewClient(t.S3)
+		if err != nil {
+			return nil, err
+		}
+		dirs, err := c.ListDirs(t.S3.Key(t.Host))
+		if err != nil {
+			return nil, err
+		}
+		ids = dirs
+	} else {
+		entries, err := os.ReadDir(filepath.Join(t.LocalDir, t.Host))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				ids = append(ids, e.Name())
+			}
+		}
+	}
+	var result []string
+	for _, id := range ids {
+		if _, err := time.ParseInLocation(BackupIDLayout, id, time.Local); err == nil {
+			result = append(result, id)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// ReadFile 读取备份中的小文件, 如备份信息
+func (t *BackupTarget) ReadFile(id, name string) ([]byte, error) {
+	if t.S3.Enabled() {
+		c, err := NewClient(t.S3)
+		if err != nil {
+			return nil, err
+		}
+		return c.ReadObject(path.Join(t.Key(id), name))
+	}
+	return os.ReadFile(filepath.Join(t.Dir(id), name))
+}
+
+// RemoveBackup 删除一个备份
+func (t *BackupTarget) RemoveBackup(id string) error {
+	if t.S3.Enabled() {
+		c, err := NewClient(t.S3)
+		if err != nil {
+			return err
+		}
+		return c.RemoveDir(t.Key(id))
+	}
+	return os.RemoveAll(t.Dir(id))
+}
+
+// Cleanup 删除超过保留天数的备份, 至少保留最近的minKeep个
+// isBase 返回true的备份仍被后续增量依赖, 不删除
+func (t *BackupTarget) Cleanup(retentionDays, minKeep int, isBase func(id string) bool) (deleted []string, err error) {
+	ids, err := t.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	expire := time.Now().AddDate(0, 0, -retentionDays).Format(BackupIDLayout)
+	for k, id := range ids {
+		if len(ids)-k <= minKeep {
+			break
+		}
+		if id >= expire || (isBase != nil && isBase(id)) {
+			continue
+		}
+		logger.Info("删除过期备份 %s", id)
+		if err = t.RemoveBackup(id); err != nil {
+			return deleted, fmt.Errorf("remove backup %s failed: %w", id, err)
+		}
+		deleted = append(deleted, id)
+	}
+	return deleted, nil
+}
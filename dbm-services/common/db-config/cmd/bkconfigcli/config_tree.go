@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"bk-dbconfig/internal/api"
+	"bk-dbconfig/internal/repository/model"
+	"bk-dbconfig/internal/service/simpleconfig"
+	"bk-dbconfig/pkg/constvar"
+	"bk-dbconfig/pkg/core/config"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export config tree to a directory of yaml files",
+	Long: `export conf_file definition, plat configs and level configs to a directory of yaml files, example:
+bkconfigcli export --dir ./dbconfig --namespace tendbha --conf-type dbconf --bk-biz-id -1
+--bk-biz-id 0 exports conf_file and plat configs only, -1 exports level configs of all bk_biz_id`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := config.GetString("dir")
+		num, err := simpleconfig.ExportConfigTree(dir, getConfTreeFilterFromFlags())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("export %d conf_file to %s\n", num, dir)
+		return nil
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "show plan of a config tree directory against db and apply it",
+	Long: `show plan of a config tree directory exported by bkconfigcli export, apply it in one transaction, example:
+bkconfigcli apply --dir ./dbconfig --namespace tendbha --conf-type dbconf --bk-biz-id -1 --dry-run
+use the same --namespace, --conf-type, --conf-file, --bk-biz-id as export, configs out of range are not touched`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		model.InitCache()
+		if _, err := model.CacheSetAndGetConfigFileList("", "", ""); err != nil {
+			return err
+		}
+		f := getConfTreeFilterFromFlags()
+		desired, err := simpleconfig.LoadConfigTree(config.GetString("dir"), f)
+		if err != nil {
+			return err
+		}
+		live, err := simpleconfig.QueryConfigTree(f)
+		if err != nil {
+			return err
+		}
+		plan := simpleconfig.PlanConfigTree(desired, live)
+		printConfTreePlan(plan)
+		if len(plan.Errors) > 0 {
+			return errors.Errorf("plan has %d errors, fix them before apply", len(plan.Errors))
+		}
+		if plan.IsEmpty() {
+			fmt.Println("no changes")
+			return nil
+		}
+		if config.GetBool("dry-run") {
+			return nil
+		}
+		if !config.GetBool("yes") {
+			prompt := promptui.Prompt{Label: "Are you sure to apply the plan", IsConfirm: true, Default: "N"}
+			if promptRes, _ := prompt.Run(); strings.ToLower(promptRes) != "y" {
+				fmt.Println("quit")
+				return nil
+			}
+		}
+		var confirm int8 = 0
+		if config.GetBool("confirm") {
+			confirm = 1
+		}
+		err = simpleconfig.ApplyConfigTreePlan(plan, confirm, config.GetString("description"),
+			config.GetString("op-user"))
+		if err != nil {
+			return errors.WithMessage(err, "apply failed and rolled back")
+		}
+		fmt.Println("apply success")
+		return nil
+	},
+}
+
+// getConfTreeFilterFromFlags 从全局参数获取配置树范围，bk-biz-id=-1 表示所有业务
+func getConfTreeFilterFromFlags() *api.ConfTreeFilter {
+	f := &api.ConfTreeFilter{
+		Namespace: config.GetString("namespace"),
+		ConfType:  config.GetString("conf-type"),
+		ConfFile:  config.GetString("conf-file"),
+	}
+	if bkBizID := config.GetInt("bk-biz-id"); bkBizID >= 0 {
+		f.BKBizID = strconv.Itoa(bkBizID)
+	}
+	return f
+}
+
+func printConfTreePlan(plan *api.ConfTreePlan) {
+	for _, w := range plan.Warnings {
+		fmt.Printf("warning: %s\n", w)
+	}
+	for _, e := range plan.Errors {
+		fmt.Printf("error: %s\n", e)
+	}
+	for _, fp := range plan.Files {
+		fmt.Printf("conf_file %s/%s/%s:\n", fp.Namespace, fp.ConfType, fp.ConfFile)
+		if fp.FileInfoChanged {
+			fmt.Printf("  ~ conf_type_lc=%s conf_file_lc=%s description=%s\n",
+				fp.ConfFileInfo.ConfTypeLC, fp.ConfFileInfo.ConfFileLC, fp.ConfFileInfo.Description)
+		}
+		if len(fp.ConfNames) > 0 {
+			fmt.Printf("  %s:%s\n", constvar.LevelPlat, constvar.BKBizIDForPlat)
+		}
+		for _, cn := range fp.ConfNames {
+			printConfTreeOp(cn.OPType, cn.ConfName, fp.ValuesBefore[cn.ConfName], cn.ValueDefault)
+		}
+		for _, lp := range fp.Levels {
+			fmt.Printf("  bk_biz_id=%s %s:%s\n", lp.BKBizID, lp.LevelName, lp.LevelValue)
+			for _, item := range lp.ConfItems {
+				printConfTreeOp(item.OPType, item.ConfName, lp.ValuesBefore[item.ConfName], item.ConfValue)
+			}
+		}
+	}
+}
+
+func printConfTreeOp(opType, confName, before, after string) {
+	switch opType {
+	case constvar.OPTypeAdd:
+		fmt.Printf("    + %s = %s\n", confName, after)
+	case constvar.OPTypeRemove:
+		fmt.Printf("    - %s = %s\n", confName, before)
+	default:
+		fmt.Printf("    ~ %s = %s -> %s\n", confName, before, after)
+	}
+}
+
+func init() {
+	exportCmd.Flags().String("dir", "", "config tree directory")
+	_ = exportCmd.MarkFlagRequired("dir")
+	applyCmd.Flags().String("dir", "", "config tree directory")
+	_ = applyCmd.MarkFlagRequired("dir")
+	// export 和 apply 不会同时执行，在各自执行前绑定 dir
+	exportCmd.PreRun = func(cmd *cobra.Command, args []string) {
+		_ = viper.BindPFlag("dir", cmd.Flags().Lookup("dir"))
+	}
+	applyCmd.PreRun = exportCmd.PreRun
+
+	applyCmd.Flags().Bool("dry-run", false, "only show plan")
+	applyCmd.Flags().Bool("yes", false, "apply without prompt")
+	applyCmd.Flags().Bool("confirm", false, "confirm to modify lower level configs conflicted with the plan")
+	applyCmd.Flags().String("description", "apply by bkconfigcli", "publish description")
+	applyCmd.Flags().String("op-user", "bkconfigcli", "operator")
+	_ = viper.BindPFlag("dry-run", applyCmd.Flags().Lookup("dry-run"))
+	_ = viper.BindPFlag("yes", applyCmd.Flags().Lookup("yes"))
+	_ = viper.BindPFlag("confirm", applyCmd.Flags().Lookup("confirm"))
+	_ = viper.BindPFlag("description", applyCmd.Flags().Lookup("description"))
+	_ = viper.BindPFlag("op-user", applyCmd.Flags().Lookup("op-user"))
+
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(applyCmd)
+}
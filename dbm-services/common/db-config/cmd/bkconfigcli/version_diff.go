@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"bk-dbconfig/internal/api"
+	"bk-dbconfig/internal/service/simpleconfig"
+	"bk-dbconfig/pkg/constvar"
+	"bk-dbconfig/pkg/core/config"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "diff config items between two versions of a level node",
+	Long: `diff config items between two versions of a level node, example:
+bkconfigcli diff --bk-biz-id 100 --namespace tendbha --conf-type dbconf --conf-file MySQL-5.7 \
+  --level-value cluster:a.b.c --from v_20220309161928 --to v_20220309215824`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		node, err := getLevelNodeFromFlags()
+		if err != nil {
+			return err
+		}
+		resp, err := simpleconfig.DiffConfigVersions(&api.DiffConfigVersionsReq{
+			BKBizIDDef:      node.BKBizIDDef,
+			BaseConfFileDef: node.BaseConfFileDef,
+			BaseLevelDef:    node.BaseLevelDef,
+			RevisionFrom:    config.GetString("from"),
+			RevisionTo:      config.GetString("to"),
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("diff %s -> %s: added:%d removed:%d updated:%d\n",
+			resp.RevisionFrom, resp.RevisionTo, resp.Added, resp.Removed, resp.Updated)
+		for _, item := range resp.Items {
+			switch item.OPType {
+			case constvar.OPTypeAdd:
+				fmt.Printf("+ %s = %s (%s)\n", item.ConfName, item.ValueAfter, item.LevelAfter)
+			case constvar.OPTypeRemove:
+				fmt.Printf("- %s = %s (%s)\n", item.ConfName, item.ValueBefore, item.LevelBefore)
+			default:
+				fmt.Printf("~ %s = %s (%s) -> %s (%s)\n", item.ConfName,
+					item.ValueBefore, item.LevelBefore, item.ValueAfter, item.LevelAfter)
+			}
+		}
+		return nil
+	},
+}
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge",
+	Short: "three-way merge up level version changes into a lower level node",
+	Long: `three-way merge up level version changes (--base -> --their) into a lower level node (--our), example:
+bkconfigcli merge --bk-biz-id 100 --namespace tendbha --conf-type dbconf --conf-file MySQL-5.7 \
+  --level-value cluster:a.b.c --up-level-value module:1 --base v_20220309161928 --their v_20220309215824`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		node, err := getLevelNodeFromFlags()
+		if err != nil {
+			return err
+		}
+		upLevel := strings.SplitN(config.GetString("up-level-value"), ":", 2)
+		if len(upLevel) != 2 {
+			return errors.Errorf("up-level-value format error, need level_name:level_value")
+		}
+		resp, err := simpleconfig.MergeConfigVersions(&api.MergeConfigVersionsReq{
+			BKBizIDDef:      node.BKBizIDDef,
+			BaseConfFileDef: node.BaseConfFileDef,
+			BaseLevelDef:    node.BaseLevelDef,
+			OurRevision:     config.GetString("our"),
+			UpLevelName:     upLevel[0],
+			UpLevelValue:    upLevel[1],
+			BaseRevision:    config.GetString("base"),
+			TheirRevision:   config.GetString("their"),
+		})
+		if err != nil {
+			return err
+		}
+		jsonBytes, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Printf("%s\n", string(jsonBytes))
+		if len(resp.Conflicts) > 0 {
+			return errors.Errorf("%d config items conflict", len(resp.Conflicts))
+		}
+		return nil
+	},
+}
+
+// getLevelNodeFromFlags 从全局参数获取 level node
+func getLevelNodeFromFlags() (*api.BaseConfigNode, error) {
+	level := strings.SplitN(config.GetString("level-value"), ":", 2)
+	if len(level) != 2 {
+		return nil, errors.Errorf("level-value format error, need level_name:level_value")
+	}
+	node := &api.BaseConfigNode{}
+	node.Set(strconv.Itoa(config.GetInt("bk-biz-id")), config.GetString("namespace"),
+		config.GetString("conf-type"), config.GetString("conf-file"), level[0], level[1])
+	return node, nil
+}
+
+func init() {
+	diffCmd.Flags().String("from", "", "old revision")
+	diffCmd.Flags().String("to", "", "new revision")
+	_ = diffCmd.MarkFlagRequired("from")
+	_ = diffCmd.MarkFlagRequired("to")
+	_ = viper.BindPFlag("from", diffCmd.Flags().Lookup("from"))
+	_ = viper.BindPFlag("to", diffCmd.Flags().Lookup("to"))
+
+	mergeCmd.Flags().String("up-level-value", "", "up level node, level_name:level_value, example module:1")
+	mergeCmd.Flags().String("base", "", "up level revision before change")
+	mergeCmd.Flags().String("their", "", "up level revision after change")
+	mergeCmd.Flags().String("our", "", "lower level revision, empty means published")
+	_ = mergeCmd.MarkFlagRequired("up-level-value")
+	_ = mergeCmd.MarkFlagRequired("base")
+	_ = mergeCmd.MarkFlagRequired("their")
+	_ = viper.BindPFlag("up-level-value", mergeCmd.Flags().Lookup("up-level-value"))
+	_ = viper.BindPFlag("base", mergeCmd.Flags().Lookup("base"))
+	_ = viper.BindPFlag("their", mergeCmd.Flags().Lookup("their"))
+	_ = viper.BindPFlag("our", mergeCmd.Flags().Lookup("our"))
+
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(mergeCmd)
+}
@@ -0,0 +1,110 @@
+package api
+
+// ConfTreeFilter 导出/应用配置树的范围
+type ConfTreeFilter struct {
+	Namespace string `json:"namespace"`
+	ConfType  string `json:"conf_type"`
+	ConfFile  string `json:"conf_file"`
+	// 为空表示所有业务，0 表示只包含平台配置
+	BKBizID string `json:"bk_biz_id"`
+}
+
+// ConfTreeFileDef 配置文件定义，对应 tb_config_file_def，导出为 file.yaml
+// 只有 conf_type_lc, conf_file_lc, description 允许通过 apply 修改，其它属性由 migration 维护
+type ConfTreeFileDef struct {
+	Namespace         string `json:"namespace" yaml:"namespace"`
+	ConfType          string `json:"conf_type" yaml:"conf_type"`
+	ConfFile          string `json:"conf_file" yaml:"conf_file"`
+	ConfTypeLC        string `json:"conf_type_lc" yaml:"conf_type_lc"`
+	ConfFileLC        string `json:"conf_file_lc" yaml:"conf_file_lc"`
+	NamespaceInfo     string `json:"namespace_info" yaml:"namespace_info"`
+	LevelNames        string `json:"level_names" yaml:"level_names"`
+	LevelVersioned    string `json:"level_versioned" yaml:"level_versioned"`
+	VersionKeepLimit  int    `json:"version_keep_limit" yaml:"version_keep_limit"`
+	VersionKeepDays   int    `json:"version_keep_days" yaml:"version_keep_days"`
+	ConfNameValidate  int8   `json:"conf_name_validate" yaml:"conf_name_validate"`
+	ConfValueValidate int8   `json:"conf_value_validate" yaml:"conf_value_validate"`
+	ValueTypeStrict   int8   `json:"value_type_strict" yaml:"value_type_strict"`
+	ConfNameOrder     int8   `json:"conf_name_order" yaml:"conf_name_order"`
+	Description       string `json:"description" yaml:"description"`
+}
+
+// ConfTreeName 平台配置项及其元数据，对应 tb_config_name_def 中 flag_status >= 1 的配置，导出为 plat.yaml
+// 只有 value_default, value_allowed, flag_locked 允许通过 apply 修改
+type ConfTreeName struct {
+	ConfName     string `json:"conf_name" yaml:"conf_name"`
+	ConfNameLC   string `json:"conf_name_lc" yaml:"conf_name_lc,omitempty"`
+	ValueType    string `json:"value_type" yaml:"value_type"`
+	ValueTypeSub string `json:"value_type_sub" yaml:"value_type_sub,omitempty"`
+	// flag_encrypt=1 的配置导出的是加密后的值，修改时填入明文即可
+	ValueDefault string `json:"value_default" yaml:"value_default"`
+	ValueAllowed string `json:"value_allowed" yaml:"value_allowed,omitempty"`
+	NeedRestart  int8   `json:"need_restart" yaml:"need_restart"`
+	FlagLocked   int8   `json:"flag_locked" yaml:"flag_locked"`
+	FlagEncrypt  int8   `json:"flag_encrypt" yaml:"flag_encrypt,omitempty"`
+	Description  string `json:"description" yaml:"description,omitempty"`
+}
+
+// ConfTreeItem 业务/模块/集群等层级的配置项，对应 tb_config_node
+type ConfTreeItem struct {
+	ConfName string `json:"conf_name" yaml:"conf_name"`
+	// 加密配置导出的是加密后的值，修改时填入明文即可
+	ConfValue   string `json:"conf_value" yaml:"conf_value"`
+	FlagLocked  int8   `json:"flag_locked" yaml:"flag_locked"`
+	FlagDisable int8   `json:"flag_disable" yaml:"flag_disable"`
+	Description string `json:"description" yaml:"description,omitempty"`
+}
+
+// ConfTreeLevel 一个 level node 的配置项，导出为 levels/<bk_biz_id>/<level_name>/<level_value>.yaml
+type ConfTreeLevel struct {
+	BKBizID    string          `json:"bk_biz_id" yaml:"bk_biz_id"`
+	LevelName  string          `json:"level_name" yaml:"level_name"`
+	LevelValue string          `json:"level_value" yaml:"level_value"`
+	ConfItems  []*ConfTreeItem `json:"conf_items" yaml:"conf_items"`
+}
+
+// ConfTreeNode 一个 conf_file 下的完整配置
+type ConfTreeNode struct {
+	FileDef   ConfTreeFileDef  `json:"file_def"`
+	ConfNames []*ConfTreeName  `json:"conf_names"`
+	Levels    []*ConfTreeLevel `json:"levels"`
+}
+
+// ConfTreeLevelPlan 一个 level node 需要变更的配置项
+type ConfTreeLevelPlan struct {
+	BKBizID    string            `json:"bk_biz_id"`
+	LevelName  string            `json:"level_name"`
+	LevelValue string            `json:"level_value"`
+	ConfItems  []*UpsertConfItem `json:"conf_items"`
+	// 变更前的值，key 为 conf_name，只用于展示
+	ValuesBefore map[string]string `json:"values_before"`
+}
+
+// ConfTreeFilePlan 一个 conf_file 需要执行的变更
+type ConfTreeFilePlan struct {
+	BaseConfFileDef
+	// 期望的 conf_file 描述信息，修改平台配置时需要整体提交
+	ConfFileInfo ConfFileDef `json:"conf_file_info"`
+	// conf_file 描述信息是否有变化
+	FileInfoChanged bool `json:"file_info_changed"`
+	// 平台配置变更
+	ConfNames []*UpsertConfNames `json:"conf_names"`
+	// 变更前的平台配置默认值，key 为 conf_name，只用于展示
+	ValuesBefore map[string]string `json:"values_before"`
+	// 按层级从上到下排序
+	Levels []*ConfTreeLevelPlan `json:"levels"`
+}
+
+// ConfTreePlan 期望的配置树与线上配置的差异
+type ConfTreePlan struct {
+	Files []*ConfTreeFilePlan `json:"files"`
+	// 无法通过 apply 完成的差异，如 conf_file 不存在、修改 level_names 等，需要通过 migration 处理
+	Warnings []string `json:"warnings"`
+	// 会导致错误结果的变更，存在时不允许 apply
+	Errors []string `json:"errors"`
+}
+
+// IsEmpty 没有需要执行的变更
+func (p *ConfTreePlan) IsEmpty() bool {
+	return len(p.Files) == 0
+}
@@ -73,3 +73,117 @@ type ChangeBkBizIdResp struct {
 	ClustersAffected int64 `json:"clusters_affected" form:"clusters_affected"`
 	ClusterReceived  int   `json:"cluster_received" form:"cluster_received"`
 }
+
+// WatchConfigVersionReq 订阅配置文件发布版本变更
+type WatchConfigVersionReq struct {
+	BKBizIDDef
+	BaseConfFileDef
+	BaseLevelDef
+	// 客户端当前持有的版本，为空时直接返回当前已发布版本
+	Revision string `json:"revision" form:"revision" example:"v_20220309215824"`
+	// 最长等待时间(秒)，默认 30s，最大 60s. 超时未发布新版本时返回 changed=false
+	Timeout int `json:"timeout" form:"timeout" example:"30"`
+	RespFormatDef
+} // @name WatchConfigVersionReq
+
+// WatchConfigVersionResp 配置文件发布版本变更
+type WatchConfigVersionResp struct {
+	// 是否有新的发布版本，为 false 时只返回 revision
+	Changed bool `json:"changed"`
+	// 当前已发布版本
+	Revision string `json:"revision"`
+	// 客户端请求时持有的版本
+	PreRevision string `json:"pre_revision"`
+	// 新版本的配置项，根据 format 会有不同的格式
+	Configs map[string]interface{} `json:"configs"`
+	// 相对 pre_revision 的差异，固定为 list 格式，带 op_type
+	ConfigsDiff map[string]interface{} `json:"configs_diff"`
+} // @name WatchConfigVersionResp
+
+// DiffConfigVersionsReq 比较同一个 level node 的两个版本
+type DiffConfigVersionsReq struct {
+	BKBizIDDef
+	BaseConfFileDef
+	BaseLevelDef
+	// 旧版本
+	RevisionFrom string `json:"revision_from" form:"revision_from" validate:"required" example:"v_20220309161928"`
+	// 新版本
+	RevisionTo string `json:"revision_to" form:"revision_to" validate:"required" example:"v_20220309215824"`
+} // @name DiffConfigVersionsReq
+
+// ConfItemVersionDiff 配置项在两个版本之间的差异
+type ConfItemVersionDiff struct {
+	ConfName string `json:"conf_name"`
+	// add, remove, update
+	OPType      string `json:"op_type"`
+	ValueBefore string `json:"value_before"`
+	ValueAfter  string `json:"value_after"`
+	// 旧值来源层级，格式 level_name:level_value
+	LevelBefore string `json:"level_before"`
+	// 新值来源层级，格式 level_name:level_value
+	LevelAfter string `json:"level_after"`
+	// 新值来源层级相对旧值来源层级，1: 更低层级(下层覆盖) -1: 更高层级(继承上层) 0: 相同层级或只有一方存在
+	LevelCompare int `json:"level_compare"`
+}
+
+// DiffConfigVersionsResp 两个版本的配置项差异
+type DiffConfigVersionsResp struct {
+	RevisionFrom string `json:"revision_from"`
+	RevisionTo   string `json:"revision_to"`
+	Added        int    `json:"added"`
+	Removed      int    `json:"removed"`
+	Updated      int    `json:"updated"`
+	// 按 conf_name 排序
+	Items []*ConfItemVersionDiff `json:"items"`
+} // @name DiffConfigVersionsResp
+
+// MergeConfigVersionsReq 三方合并
+// 上层 level node 从 base_revision 变更到 their_revision，合并到下层 level node 的 our_revision
+type MergeConfigVersionsReq struct {
+	BKBizIDDef
+	BaseConfFileDef
+	// 下层 level node
+	BaseLevelDef
+	// 下层 level node 的版本，为空时使用已发布版本
+	OurRevision string `json:"our_revision" form:"our_revision"`
+	// 上层 level node 的层级名
+	UpLevelName string `json:"up_level_name" form:"up_level_name" validate:"required" example:"module"`
+	// 上层 level node 的层级值
+	UpLevelValue string `json:"up_level_value" form:"up_level_value"`
+	// 上层变更前的版本
+	BaseRevision string `json:"base_revision" form:"base_revision" validate:"required"`
+	// 上层变更后的版本
+	TheirRevision string `json:"their_revision" form:"their_revision" validate:"required"`
+} // @name MergeConfigVersionsReq
+
+// ConfItemMerged 合并后的配置项
+type ConfItemMerged struct {
+	ConfName  string `json:"conf_name"`
+	ConfValue string `json:"conf_value"`
+	// 合并结果的来源 ours: 下层, theirs: 上层
+	From string `json:"from"`
+	// 值来源层级，格式 level_name:level_value
+	Level string `json:"level"`
+}
+
+// ConfItemMergeConflict 上层和下层对同一个配置项做了不同的修改
+// 合并结果保留下层的值，需要人工确认
+type ConfItemMergeConflict struct {
+	ConfName string `json:"conf_name"`
+	// 为 nil 表示该版本不存在此配置项
+	ValueBase   *string `json:"value_base"`
+	ValueTheirs *string `json:"value_theirs"`
+	ValueOurs   *string `json:"value_ours"`
+	// 下层值来源层级，格式 level_name:level_value
+	LevelOurs string `json:"level_ours"`
+}
+
+// MergeConfigVersionsResp 三方合并结果，不会写入配置
+type MergeConfigVersionsResp struct {
+	OurRevision   string `json:"our_revision"`
+	BaseRevision  string `json:"base_revision"`
+	TheirRevision string `json:"their_revision"`
+	// 按 conf_name 排序
+	Configs   []*ConfItemMerged        `json:"configs"`
+	Conflicts []*ConfItemMergeConflict `json:"conflicts"`
+} // @name MergeConfigVersionsResp
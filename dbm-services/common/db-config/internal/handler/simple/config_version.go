@@ -268,3 +268,91 @@ func (cf *Config) ListConfigFileVersions(ctx *gin.Context) {
 	}
 	handler.SendResponse(ctx, err, resp)
 }
+
+// WatchConfigVersion godoc
+//
+// @Summary      订阅配置文件发布版本变更(long-poll)
+// @Description  客户端带上当前持有的 revision，请求会阻塞直到该 level node 发布了不同的版本，或者等待 timeout 秒后返回 changed=false
+// @Description  有新版本时返回新版本的配置项 configs，以及相对 revision 的差异 configs_diff
+// @Description  revision 为空时，直接返回当前已发布版本。客户端收到响应后，应使用新的 revision 继续 watch
+// @Tags         config_version
+// @Produce      json
+// @Param        body query     api.WatchConfigVersionReq  true  "query"
+// @Success      200  {object}  api.WatchConfigVersionResp
+// @Failure      400  {object}  api.HTTPClientErrResp
+// @Router       /bkconfig/v1/version/watch [get]
+func (cf *Config) WatchConfigVersion(ctx *gin.Context) {
+	var r api.WatchConfigVersionReq
+	if err := ctx.BindQuery(&r); err != nil {
+		handler.SendResponse(ctx, err, nil)
+		return
+	}
+	if err := validate.GoValidateStruct(r, true); err != nil {
+		handler.SendResponse(ctx, err, nil)
+		return
+	}
+	resp, err := simpleconfig.WatchConfigVersion(ctx.Request.Context(), &r)
+	if err != nil {
+		handler.SendResponse(ctx, err, nil)
+		return
+	}
+	handler.SendResponse(ctx, nil, resp)
+}
+
+// DiffConfigVersions godoc
+//
+// @Summary      比较两个版本的配置项差异
+// @Description  比较同一个 level node 任意两个版本，返回新增、删除、修改的配置项，以及新旧值的来源层级
+// @Tags         config_version
+// @Produce      json
+// @Param        body query     api.DiffConfigVersionsReq  true  "query"
+// @Success      200  {object}  api.DiffConfigVersionsResp
+// @Failure      400  {object}  api.HTTPClientErrResp
+// @Router       /bkconfig/v1/version/diff [get]
+func (cf *Config) DiffConfigVersions(ctx *gin.Context) {
+	var r api.DiffConfigVersionsReq
+	if err := ctx.BindQuery(&r); err != nil {
+		handler.SendResponse(ctx, err, nil)
+		return
+	}
+	if err := validate.GoValidateStruct(r, true); err != nil {
+		handler.SendResponse(ctx, err, nil)
+		return
+	}
+	resp, err := simpleconfig.DiffConfigVersions(&r)
+	if err != nil {
+		handler.SendResponse(ctx, err, nil)
+		return
+	}
+	handler.SendResponse(ctx, nil, resp)
+}
+
+// MergeConfigVersions godoc
+//
+// @Summary      三方合并上层版本变更到下层
+// @Description  上层 level node 从 base_revision 变更到 their_revision，与下层 level node 的 our_revision 做三方合并
+// @Description  上下层对同一配置项做了不同修改时记为冲突，合并结果保留下层的值。只返回合并结果，不会写入配置
+// @Tags         config_version
+// @Accept       json
+// @Produce      json
+// @Param        body body     api.MergeConfigVersionsReq  true  "merge versions"
+// @Success      200  {object}  api.MergeConfigVersionsResp
+// @Failure      400  {object}  api.HTTPClientErrResp
+// @Router       /bkconfig/v1/version/merge [post]
+func (cf *Config) MergeConfigVersions(ctx *gin.Context) {
+	var r api.MergeConfigVersionsReq
+	if err := ctx.BindJSON(&r); err != nil {
+		handler.SendResponse(ctx, err, nil)
+		return
+	}
+	if err := validate.GoValidateStruct(r, true); err != nil {
+		handler.SendResponse(ctx, err, nil)
+		return
+	}
+	resp, err := simpleconfig.MergeConfigVersions(&r)
+	if err != nil {
+		handler.SendResponse(ctx, err, nil)
+		return
+	}
+	handler.SendResponse(ctx, nil, resp)
+}
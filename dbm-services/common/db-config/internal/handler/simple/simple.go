@@ -36,6 +36,9 @@ func (cf *Config) Routes() []*gin.RouteInfo {
 		{Method: http.MethodPost, Path: "/version/status", HandlerFunc: cf.VersionStat},
 		{Method: http.MethodPost, Path: "/version/applyitem", HandlerFunc: cf.ItemApply},
 		{Method: http.MethodPost, Path: "/version/change-bkbizid", HandlerFunc: cf.ChangeBizBizId},
+		{Method: http.MethodGet, Path: "/version/watch", HandlerFunc: cf.WatchConfigVersion},
+		{Method: http.MethodGet, Path: "/version/diff", HandlerFunc: cf.DiffConfigVersions},
+		{Method: http.MethodPost, Path: "/version/merge", HandlerFunc: cf.MergeConfigVersions},
 
 		// config_item
 		{Method: http.MethodPost, Path: "/confitem/query", HandlerFunc: cf.MergeAndGetConfigItems},
@@ -10,6 +10,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cast"
+	"gorm.io/gorm"
 )
 
 // GetConfigItemsAssociateNodes TODO
@@ -76,8 +77,8 @@ func (c *ConfigModelView) GetConfigItemsAssociateNodes() (map[string]interface{}
 // GetConfigItemsAssociate TODO
 // 根据 levelName, levelValue 批量获取配置项
 // 访问视图 v_tb_config_node_plat
-func (c *ConfigModelView) GetConfigItemsAssociate(bkBizID string, levelNodes map[string]interface{}) ([]*ConfigModel,
-	error) {
+func (c *ConfigModelView) GetConfigItemsAssociate(db *gorm.DB, bkBizID string,
+	levelNodes map[string]interface{}) ([]*ConfigModel, error) {
 	logger.Info("GetConfigItemsAssociate params: %s, %+v", bkBizID, levelNodes)
 	sqlSubs := []string{}
 	params := make([]interface{}, 0)
@@ -118,7 +119,7 @@ func (c *ConfigModelView) GetConfigItemsAssociate(bkBizID string, levelNodes map
 	if len(sqlStr) == 0 {
 		return configs, nil
 	}
-	if err := DB.Self.Debug().Raw(sqlStr, params...).Scan(&configs).Error; err != nil {
+	if err := db.Debug().Raw(sqlStr, params...).Scan(&configs).Error; err != nil {
 		return nil, err
 	}
 	logger.Info("GetConfigItemsAssociate result: %+v", configs)
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"bk-dbconfig/pkg/constvar"
+)
+
+// QueryConfigNamesPlatRaw 查询平台配置，与 QueryConfigNamesPlat 条件相同，但不解密 value_default
+// 用于导出配置树，避免明文密码落盘
+func QueryConfigNamesPlatRaw(namespace, confType, confFile string) ([]*ConfigNameDefModel, error) {
+	confNames := make([]*ConfigNameDefModel, 0)
+	err := DB.Self.Model(ConfigNameDefModel{}).
+		Where("namespace = ? and conf_type = ? and conf_file = ? and flag_status >= 1 and flag_disable = 0",
+			namespace, confType, confFile).
+		Order("conf_name").Find(&confNames).Error
+	if err != nil {
+		return nil, err
+	}
+	return confNames, nil
+}
+
+// QueryConfigNodesRaw 查询 conf_file 在平台以下层级的所有配置项，不解密 conf_value
+// bkBizID 为空表示查询所有业务
+func QueryConfigNodesRaw(namespace, confType, confFile, bkBizID string) ([]*ConfigModel, error) {
+	configs := make([]*ConfigModel, 0)
+	sqlRes := DB.Self.Model(&ConfigModel{}).
+		Where("namespace = ? and conf_type = ? and conf_file = ? and level_name != ?",
+			namespace, confType, confFile, constvar.LevelPlat)
+	if bkBizID != "" {
+		sqlRes = sqlRes.Where("bk_biz_id = ?", bkBizID)
+	}
+	if err := sqlRes.Order("bk_biz_id, level_name, level_value, conf_name").Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
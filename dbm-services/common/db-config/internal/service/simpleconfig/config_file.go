@@ -21,13 +21,13 @@ func GetConfigLockLevel(locked int8, levelName string) string {
 	}
 }
 
-func checkConfigFileExists(r *api.BaseConfFileDef) (bool, *model.ConfigFileDefModel, error) {
+func checkConfigFileExists(db *gorm.DB, r *api.BaseConfFileDef) (bool, *model.ConfigFileDefModel, error) {
 	cf := &model.ConfigFileDefModel{
 		Namespace: r.Namespace,
 		ConfType:  r.ConfType,
 		ConfFile:  r.ConfFile,
 	}
-	fileDefObj, err := model.RecordGet(model.DB.Self, cf.TableName(), cf.ID, cf.UniqueWhere())
+	fileDefObj, err := model.RecordGet(db, cf.TableName(), cf.ID, cf.UniqueWhere())
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
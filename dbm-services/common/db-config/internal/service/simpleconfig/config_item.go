@@ -295,8 +295,14 @@ func NewConfigModelsWithItemReq(r *api.UpsertConfItemsReq) ([]*model.ConfigModel
 
 // UpdateConfigFileItems 修改配置
 func UpdateConfigFileItems(r *api.UpsertConfItemsReq, opUser string) (*api.UpsertConfItemsResp, error) {
+	return updateConfigFileItems(model.DB.Self, r, opUser)
+}
+
+// updateConfigFileItems 同 UpdateConfigFileItems，查询和事务都在 db 上执行
+// db 是外层事务时，这里的事务会变成外层事务的 savepoint
+func updateConfigFileItems(db *gorm.DB, r *api.UpsertConfItemsReq, opUser string) (*api.UpsertConfItemsResp, error) {
 	fileDef := r.ConfFileInfo.BaseConfFileDef
-	exists, cf, err := checkConfigFileExists(&fileDef)
+	exists, cf, err := checkConfigFileExists(db, &fileDef)
 	defer util.LoggerErrorStack(logger.Error, err)
 	if err != nil {
 		return nil, err
@@ -310,7 +316,7 @@ func UpdateConfigFileItems(r *api.UpsertConfItemsReq, opUser string) (*api.Upser
 	}
 	configs, configsDiff := NewConfigModelsWithItemReq(r)
 	// 先判断上层级是否安全, 强制约束，confirm=1 无效
-	configsRef, err := BatchPreCheck(configs)
+	configsRef, err := BatchPreCheck(db, configs)
 	if err != nil {
 		return nil, err
 	}
@@ -326,7 +332,8 @@ func UpdateConfigFileItems(r *api.UpsertConfItemsReq, opUser string) (*api.Upser
 		}
 		return nil, errors.WithMessagef(errno.ErrConflictWithLowerConfigLevel, "%v", names)
 	}
-	txErr := model.DB.Self.Transaction(func(tx *gorm.DB) error {
+	db, notify := withPublishNotify(db)
+	txErr := db.Transaction(func(tx *gorm.DB) error {
 		// 保存到 to tb_config_file_node
 		levelNode := api.BaseConfigNode{}
 		levelNode.Set(r.BKBizID, cf.Namespace, cf.ConfType, cf.ConfFile, r.LevelName, r.LevelValue)
@@ -397,6 +404,7 @@ func UpdateConfigFileItems(r *api.UpsertConfItemsReq, opUser string) (*api.Upser
 		return nil
 	})
 	if txErr == nil {
+		notify()
 		model.CacheSetAndGetConfigFile(fileDef) // refresh cache
 	}
 	return resp, txErr
@@ -629,6 +637,7 @@ func GenerateAndPublish(db *gorm.DB, r *api.BaseConfigNode, o *api.QueryConfigOp
 		m.Cluster = r.LevelValue
 	}
 	// copier.Copy(&m, o)
+	db, notify := withPublishNotify(db)
 	txErr := db.Transaction(func(tx *gorm.DB) error { // new transaction
 		// 回写 tb_config_node 保存到层级树
 		configsLocked, err := UpsertConfigItems(tx, configsDiff, revision)
@@ -661,5 +670,7 @@ func GenerateAndPublish(db *gorm.DB, r *api.BaseConfigNode, o *api.QueryConfigOp
 	if txErr != nil {
 		return txErr
 	}
-	return txErr
+	// 事务提交后再通知 watch 请求
+	notify()
+	return nil
 }
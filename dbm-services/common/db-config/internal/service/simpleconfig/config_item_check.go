@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 // AddConfigsRefToDiff TODO
@@ -32,11 +33,12 @@ func AddConfigsRefToDiff(configsRef map[string]*ConfigModelRef) []*model.ConfigM
 // BatchPreCheckPlat TODO
 // 批量检查要写入的 conf_item 的上下层级合法性
 // 返回一个map， key是conf_name, value 是需要处理的下级配置
-func BatchPreCheckPlat(r *api.UpsertConfFilePlatReq, configs []*model.ConfigModel) (map[string]*ConfigModelRef, error) {
+func BatchPreCheckPlat(db *gorm.DB, r *api.UpsertConfFilePlatReq,
+	configs []*model.ConfigModel) (map[string]*ConfigModelRef, error) {
 	var errs []error
 	var configsRefMap = map[string]*ConfigModelRef{}
 	for _, cn := range r.ConfNames {
-		configsRef, err := PreCheckPlat(&r.ConfFileInfo.BaseConfFileDef, cn)
+		configsRef, err := PreCheckPlat(db, &r.ConfFileInfo.BaseConfFileDef, cn)
 		if err != nil {
 			errs = append(errs, err)
 		} else {
@@ -54,11 +56,11 @@ func BatchPreCheckPlat(r *api.UpsertConfFilePlatReq, configs []*model.ConfigMode
 }
 
 // BatchPreCheck TODO
-func BatchPreCheck(configs []*model.ConfigModelView) (map[string]*ConfigModelRef, error) {
+func BatchPreCheck(db *gorm.DB, configs []*model.ConfigModelView) (map[string]*ConfigModelRef, error) {
 	var errs []error
 	var configsRefMap = map[string]*ConfigModelRef{}
 	for _, cn := range configs {
-		configsRef, err := PreCheck(cn, false)
+		configsRef, err := PreCheck(db, cn, false)
 		if err != nil {
 			errs = append(errs, err)
 		} else {
@@ -75,15 +77,15 @@ func BatchPreCheck(configs []*model.ConfigModelView) (map[string]*ConfigModelRef
 }
 
 // PreCheck TODO
-func PreCheck(c *model.ConfigModelView, checkValue bool) (*ConfigModelRef, error) {
+func PreCheck(db *gorm.DB, c *model.ConfigModelView, checkValue bool) (*ConfigModelRef, error) {
 	if err := CheckConfNameAndValue(&c.ConfigModel, checkValue, "", "", ""); err != nil {
 		return nil, err
 	}
-	return PrecheckConfigItemUpsert(c)
+	return PrecheckConfigItemUpsert(db, c)
 }
 
 // PreCheckPlat TODO
-func PreCheckPlat(f *api.BaseConfFileDef, cn *api.UpsertConfNames) (*ConfigModelRef, error) {
+func PreCheckPlat(db *gorm.DB, f *api.BaseConfFileDef, cn *api.UpsertConfNames) (*ConfigModelRef, error) {
 	c := &model.ConfigModel{
 		Namespace: f.Namespace,
 		ConfFile:  f.ConfFile,
@@ -106,24 +108,24 @@ func PreCheckPlat(f *api.BaseConfFileDef, cn *api.UpsertConfNames) (*ConfigModel
 		ConfigModel: *c,
 		// no UpLevelInfo
 	}
-	return PrecheckConfigItemUpsert(cmv)
+	return PrecheckConfigItemUpsert(db, cmv)
 }
 
 // PrecheckConfigItemUpsert TODO
 // 检查当前配置项是否可以写入，add, update
-func PrecheckConfigItemUpsert(c *model.ConfigModelView) (*ConfigModelRef, error) {
+func PrecheckConfigItemUpsert(db *gorm.DB, c *model.ConfigModelView) (*ConfigModelRef, error) {
 
 	up, down, err := c.GetConfigItemsAssociateNodes()
 	if err != nil {
 		return nil, err
 	}
-	upConfigs, err := c.GetConfigItemsAssociate(c.BKBizID, up)
+	upConfigs, err := c.GetConfigItemsAssociate(db, c.BKBizID, up)
 	if err != nil {
 		return nil, err
 	}
 	downConfig := make([]*model.ConfigModel, 0)
 	if c.FlagLocked == 1 {
-		downConfig, err = c.GetConfigItemsAssociate(c.BKBizID, down)
+		downConfig, err = c.GetConfigItemsAssociate(db, c.BKBizID, down)
 		if err != nil {
 			return nil, err
 		}
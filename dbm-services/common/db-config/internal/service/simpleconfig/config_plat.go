@@ -73,8 +73,15 @@ func ConfigNamesBatchUpsert(db *gorm.DB, cf api.ConfFileDef, confNames []*api.Up
 // 新建 conf_file，保存操作在 def 表，发布时进入 node 表，生成revision并发布
 func UpsertConfigFilePlat(r *api.UpsertConfFilePlatReq, clientOPType, opUser string) (*api.UpsertConfFilePlatResp,
 	error) {
+	return upsertConfigFilePlat(model.DB.Self, r, clientOPType, opUser)
+}
+
+// upsertConfigFilePlat 同 UpsertConfigFilePlat，查询和事务都在 db 上执行
+// db 是外层事务时，这里的事务会变成外层事务的 savepoint
+func upsertConfigFilePlat(db *gorm.DB, r *api.UpsertConfFilePlatReq, clientOPType, opUser string) (
+	*api.UpsertConfFilePlatResp, error) {
 	fileDef := r.ConfFileInfo.BaseConfFileDef
-	exists, cf, err := checkConfigFileExists(&fileDef)
+	exists, cf, err := checkConfigFileExists(db, &fileDef)
 	if err != nil {
 		return nil, err
 	} else {
@@ -97,7 +104,7 @@ func UpsertConfigFilePlat(r *api.UpsertConfFilePlatReq, clientOPType, opUser str
 	// build config item model
 	configs, configsDiff := NewConfigModels(r)
 	// 平台配置永远可以修改，如果与下级存在锁冲突，后面会生成修复提示
-	configsRef, err := BatchPreCheckPlat(r, configs)
+	configsRef, err := BatchPreCheckPlat(db, r, configs)
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +123,8 @@ func UpsertConfigFilePlat(r *api.UpsertConfFilePlatReq, clientOPType, opUser str
 		return nil, errors.WithMessagef(errno.ErrConflictWithLowerConfigLevel, "%v", names)
 	}
 
-	txErr := model.DB.Self.Transaction(func(tx *gorm.DB) error {
+	db, notify := withPublishNotify(db)
+	txErr := db.Transaction(func(tx *gorm.DB) error {
 		// 保存逻辑
 		{
 			// 保存到 tb_config_file_def
@@ -175,6 +183,7 @@ func UpsertConfigFilePlat(r *api.UpsertConfFilePlatReq, clientOPType, opUser str
 		return nil
 	})
 	if txErr == nil {
+		notify()
 		model.CacheSetAndGetConfigFile(fileDef)
 	}
 	return resp, txErr
@@ -0,0 +1,474 @@
+package simpleconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"bk-dbconfig/internal/api"
+	"bk-dbconfig/internal/pkg/cst"
+	"bk-dbconfig/internal/repository/model"
+	"bk-dbconfig/pkg/constvar"
+	"bk-dbconfig/pkg/util/crypt"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func confTreeFileKey(f api.ConfTreeFileDef) string {
+	return fmt.Sprintf("%s/%s/%s", f.Namespace, f.ConfType, f.ConfFile)
+}
+
+func confTreeLevelKey(l *api.ConfTreeLevel) string {
+	return fmt.Sprintf("%s/%s/%s", l.BKBizID, l.LevelName, l.LevelValue)
+}
+
+// sortConfTree 对配置树排序，保证导出和 plan 的结果是确定的
+func sortConfTree(nodes []*api.ConfTreeNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return confTreeFileKey(nodes[i].FileDef) < confTreeFileKey(nodes[j].FileDef)
+	})
+	for _, n := range nodes {
+		sort.Slice(n.ConfNames, func(i, j int) bool {
+			return n.ConfNames[i].ConfName < n.ConfNames[j].ConfName
+		})
+		sort.Slice(n.Levels, func(i, j int) bool {
+			return confTreeLevelLess(n.Levels[i], n.Levels[j])
+		})
+		for _, l := range n.Levels {
+			items := l.ConfItems
+			sort.Slice(items, func(i, j int) bool {
+				return items[i].ConfName < items[j].ConfName
+			})
+		}
+	}
+}
+
+// confTreeLevelLess 按层级从上到下，再按 bk_biz_id, level_value 排序
+func confTreeLevelLess(a, b *api.ConfTreeLevel) bool {
+	if a.LevelName != b.LevelName {
+		return cst.ConfigLevelMap[a.LevelName] < cst.ConfigLevelMap[b.LevelName]
+	}
+	if a.BKBizID != b.BKBizID {
+		return a.BKBizID < b.BKBizID
+	}
+	return a.LevelValue < b.LevelValue
+}
+
+func newConfTreeFileDef(f *model.ConfigFileDefModel) api.ConfTreeFileDef {
+	return api.ConfTreeFileDef{
+		Namespace:         f.Namespace,
+		ConfType:          f.ConfType,
+		ConfFile:          f.ConfFile,
+		ConfTypeLC:        f.ConfTypeLC,
+		ConfFileLC:        f.ConfFileLC,
+		NamespaceInfo:     f.NamespaceInfo,
+		LevelNames:        f.LevelNames,
+		LevelVersioned:    f.LevelVersioned,
+		VersionKeepLimit:  f.VersionKeepLimit,
+		VersionKeepDays:   f.VersionKeepDays,
+		ConfNameValidate:  f.ConfNameValidate,
+		ConfValueValidate: f.ConfValueValidate,
+		ValueTypeStrict:   f.ValueTypeStrict,
+		ConfNameOrder:     f.ConfNameOrder,
+		Description:       f.Description,
+	}
+}
+
+// QueryConfigTree 查询线上的配置树，加密的配置值保持密文
+// 密文的 key 与 level_value 有关，只能原样 apply 回同一个 level node，见 checkConfTreeEncrypted
+func QueryConfigTree(f *api.ConfTreeFilter) ([]*api.ConfTreeNode, error) {
+	fileDefs, err := model.GetConfigFileList(f.Namespace, f.ConfType, f.ConfFile)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*api.ConfTreeNode, 0, len(fileDefs))
+	for _, fd := range fileDefs {
+		node := &api.ConfTreeNode{FileDef: newConfTreeFileDef(fd)}
+		confNames, err := model.QueryConfigNamesPlatRaw(fd.Namespace, fd.ConfType, fd.ConfFile)
+		if err != nil {
+			return nil, errors.WithMessage(err, confTreeFileKey(node.FileDef))
+		}
+		for _, cn := range confNames {
+			node.ConfNames = append(node.ConfNames, &api.ConfTreeName{
+				ConfName:     cn.ConfName,
+				ConfNameLC:   cn.ConfNameLC,
+				ValueType:    cn.ValueType,
+				ValueTypeSub: cn.ValueTypeSub,
+				ValueDefault: cn.ValueDefault,
+				ValueAllowed: cn.ValueAllowed,
+				NeedRestart:  cn.NeedRestart,
+				FlagLocked:   cn.FlagLocked,
+				FlagEncrypt:  cn.FlagEncrypt,
+				Description:  cn.Description,
+			})
+		}
+		if f.BKBizID != constvar.BKBizIDForPlat {
+			configs, err := model.QueryConfigNodesRaw(fd.Namespace, fd.ConfType, fd.ConfFile, f.BKBizID)
+			if err != nil {
+				return nil, errors.WithMessage(err, confTreeFileKey(node.FileDef))
+			}
+			node.Levels = groupConfTreeLevels(configs)
+		}
+		nodes = append(nodes, node)
+	}
+	sortConfTree(nodes)
+	return nodes, nil
+}
+
+// groupConfTreeLevels 将 tb_config_node 的配置项按 level node 分组
+func groupConfTreeLevels(configs []*model.ConfigModel) []*api.ConfTreeLevel {
+	levels := make([]*api.ConfTreeLevel, 0)
+	levelMap := make(map[string]*api.ConfTreeLevel)
+	for _, c := range configs {
+		level := &api.ConfTreeLevel{BKBizID: c.BKBizID, LevelName: c.LevelName, LevelValue: c.LevelValue}
+		key := confTreeLevelKey(level)
+		if l, ok := levelMap[key]; ok {
+			level = l
+		} else {
+			levelMap[key] = level
+			levels = append(levels, level)
+		}
+		level.ConfItems = append(level.ConfItems, &api.ConfTreeItem{
+			ConfName:    c.ConfName,
+			ConfValue:   c.ConfValue,
+			FlagLocked:  c.FlagLocked,
+			FlagDisable: c.FlagDisable,
+			Description: c.Description,
+		})
+	}
+	return levels
+}
+
+// PlanConfigTree 计算期望的配置树 desired 与线上配置树 live 的差异
+// 只对两边都存在的 conf_file 生成变更，conf_file 本身的新增、删除需要通过 migration 完成
+func PlanConfigTree(desired, live []*api.ConfTreeNode) *api.ConfTreePlan {
+	plan := &api.ConfTreePlan{}
+	liveMap := make(map[string]*api.ConfTreeNode, len(live))
+	for _, l := range live {
+		liveMap[confTreeFileKey(l.FileDef)] = l
+	}
+	desiredKeys := make(map[string]bool, len(desired))
+	for _, d := range desired {
+		key := confTreeFileKey(d.FileDef)
+		desiredKeys[key] = true
+		l, ok := liveMap[key]
+		if !ok {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s: conf_file not exists, add it by migration", key))
+			continue
+		}
+		if fp := planConfTreeFile(d, l, plan); fp != nil {
+			plan.Files = append(plan.Files, fp)
+		}
+	}
+	for _, l := range live {
+		if key := confTreeFileKey(l.FileDef); !desiredKeys[key] {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s: conf_file not found in desired tree, ignored", key))
+		}
+	}
+	return plan
+}
+
+// planConfTreeFile 计算一个 conf_file 的变更，没有变更时返回 nil
+func planConfTreeFile(d, l *api.ConfTreeNode, plan *api.ConfTreePlan) *api.ConfTreeFilePlan {
+	key := confTreeFileKey(l.FileDef)
+	fp := &api.ConfTreeFilePlan{
+		BaseConfFileDef: api.BaseConfFileDef{
+			Namespace: l.FileDef.Namespace,
+			ConfType:  l.FileDef.ConfType,
+			ConfFile:  l.FileDef.ConfFile,
+		},
+		ValuesBefore: map[string]string{},
+	}
+	fp.ConfFileInfo = api.ConfFileDef{
+		BaseConfFileDef: fp.BaseConfFileDef,
+		ConfTypeLC:      d.FileDef.ConfTypeLC,
+		ConfFileLC:      d.FileDef.ConfFileLC,
+		NamespaceInfo:   l.FileDef.NamespaceInfo,
+		Description:     d.FileDef.Description,
+	}
+	fp.FileInfoChanged = d.FileDef.ConfTypeLC != l.FileDef.ConfTypeLC ||
+		d.FileDef.ConfFileLC != l.FileDef.ConfFileLC || d.FileDef.Description != l.FileDef.Description
+	readonly := d.FileDef
+	readonly.ConfTypeLC, readonly.ConfFileLC, readonly.Description =
+		l.FileDef.ConfTypeLC, l.FileDef.ConfFileLC, l.FileDef.Description
+	if readonly != l.FileDef {
+		plan.Warnings = append(plan.Warnings,
+			fmt.Sprintf("%s: only conf_type_lc, conf_file_lc, description of conf_file can be applied", key))
+	}
+
+	fp.ConfNames = planConfTreeNames(d.ConfNames, l.ConfNames, fp.ValuesBefore, func(confName string) {
+		plan.Warnings = append(plan.Warnings,
+			fmt.Sprintf("%s: only value_default, value_allowed, flag_locked of %s can be applied", key, confName))
+	})
+
+	desiredLevels := make(map[string]*api.ConfTreeLevel, len(d.Levels))
+	for _, dl := range d.Levels {
+		desiredLevels[confTreeLevelKey(dl)] = dl
+	}
+	liveLevels := make(map[string]*api.ConfTreeLevel, len(l.Levels))
+	levels := make([]*api.ConfTreeLevel, 0, len(l.Levels))
+	for _, ll := range l.Levels {
+		liveLevels[confTreeLevelKey(ll)] = ll
+		levels = append(levels, ll)
+	}
+	for _, dl := range d.Levels {
+		if _, ok := liveLevels[confTreeLevelKey(dl)]; !ok {
+			levels = append(levels, dl)
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return confTreeLevelLess(levels[i], levels[j])
+	})
+	for _, level := range levels {
+		key := confTreeLevelKey(level)
+		lp := planConfTreeLevel(desiredLevels[key], liveLevels[key])
+		if len(lp.ConfItems) > 0 {
+			fp.Levels = append(fp.Levels, lp)
+		}
+	}
+	if !fp.FileInfoChanged && len(fp.ConfNames) == 0 && len(fp.Levels) == 0 {
+		return nil
+	}
+	checkConfTreeEncrypted(fp, plan)
+	return fp
+}
+
+// checkConfTreeEncrypted 加密值使用 encrypt.keyPrefix + level_value 加密，写入时已是密文不会重新加密
+// 新增或修改为密文的值可能来自其它 level node 或其它环境，写入后无法解密，要求改为明文
+func checkConfTreeEncrypted(fp *api.ConfTreeFilePlan, plan *api.ConfTreePlan) {
+	key := confTreeFileKey(api.ConfTreeFileDef{Namespace: fp.Namespace, ConfType: fp.ConfType, ConfFile: fp.ConfFile})
+	for _, cn := range fp.ConfNames {
+		if cn.OPType == constvar.OPTypeRemove {
+			continue
+		}
+		if _, ok := crypt.IsEncryptedString(cn.ValueDefault); ok && cn.ValueDefault != fp.ValuesBefore[cn.ConfName] {
+			plan.Errors = append(plan.Errors, fmt.Sprintf("%s: encrypted value_default of %s can not be applied, "+
+				"use plain text instead", key, cn.ConfName))
+		}
+	}
+	for _, lp := range fp.Levels {
+		for _, item := range lp.ConfItems {
+			if item.OPType == constvar.OPTypeRemove {
+				continue
+			}
+			if _, ok := crypt.IsEncryptedString(item.ConfValue); ok && item.ConfValue != lp.ValuesBefore[item.ConfName] {
+				plan.Errors = append(plan.Errors, fmt.Sprintf("%s: encrypted conf_value of %s at %s:%s can not be "+
+					"applied, use plain text instead", key, item.ConfName, lp.LevelName, lp.LevelValue))
+			}
+		}
+	}
+}
+
+// planConfTreeNames 比较平台配置项，onReadonly 在只读属性不一致时回调
+func planConfTreeNames(desired, live []*api.ConfTreeName, valuesBefore map[string]string,
+	onReadonly func(confName string)) []*api.UpsertConfNames {
+	liveMap := make(map[string]*api.ConfTreeName, len(live))
+	for _, cn := range live {
+		liveMap[cn.ConfName] = cn
+	}
+	desiredMap := make(map[string]*api.ConfTreeName, len(desired))
+	confNames := make([]string, 0, len(desired)+len(live))
+	for _, cn := range desired {
+		desiredMap[cn.ConfName] = cn
+		confNames = append(confNames, cn.ConfName)
+	}
+	for _, cn := range live {
+		if _, ok := desiredMap[cn.ConfName]; !ok {
+			confNames = append(confNames, cn.ConfName)
+		}
+	}
+	sort.Strings(confNames)
+
+	ops := make([]*api.UpsertConfNames, 0)
+	for _, confName := range confNames {
+		d, l := desiredMap[confName], liveMap[confName]
+		var opType string
+		switch {
+		case l == nil:
+			opType = constvar.OPTypeAdd
+		case d == nil:
+			opType = constvar.OPTypeRemove
+		default:
+			if d.ValueDefault == l.ValueDefault && d.ValueAllowed == l.ValueAllowed && d.FlagLocked == l.FlagLocked {
+				opType = ""
+			} else {
+				opType = constvar.OPTypeUpdate
+			}
+			readonly := *d
+			readonly.ValueDefault, readonly.ValueAllowed, readonly.FlagLocked =
+				l.ValueDefault, l.ValueAllowed, l.FlagLocked
+			if readonly != *l {
+				onReadonly(confName)
+			}
+		}
+		if opType == "" {
+			continue
+		}
+		if l != nil {
+			valuesBefore[confName] = l.ValueDefault
+		}
+		op := &api.UpsertConfNames{OperationType: api.OperationType{OPType: opType}}
+		if opType == constvar.OPTypeRemove {
+			// 从平台配置移除，放回配置名列表，不能带 flag_locked 否则会重新变成平台配置
+			op.ConfNameDef = api.ConfNameDef{ConfName: l.ConfName, ValueType: l.ValueType, FlagStatus: -1}
+		} else {
+			op.ConfNameDef = api.ConfNameDef{
+				ConfName:     d.ConfName,
+				ConfNameLC:   d.ConfNameLC,
+				ValueType:    d.ValueType,
+				ValueTypeSub: d.ValueTypeSub,
+				ValueAllowed: d.ValueAllowed,
+				ValueDefault: d.ValueDefault,
+				NeedRestart:  d.NeedRestart,
+				FlagLocked:   d.FlagLocked,
+				FlagStatus:   1,
+				Description:  d.Description,
+			}
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// planConfTreeLevel 比较一个 level node 的配置项，desired 或 live 可能为 nil
+func planConfTreeLevel(desired, live *api.ConfTreeLevel) *api.ConfTreeLevelPlan {
+	level := live
+	if level == nil {
+		level = desired
+	}
+	lp := &api.ConfTreeLevelPlan{
+		BKBizID:      level.BKBizID,
+		LevelName:    level.LevelName,
+		LevelValue:   level.LevelValue,
+		ValuesBefore: map[string]string{},
+	}
+	liveMap := make(map[string]*api.ConfTreeItem)
+	if live != nil {
+		for _, item := range live.ConfItems {
+			liveMap[item.ConfName] = item
+		}
+	}
+	desiredMap := make(map[string]*api.ConfTreeItem)
+	confNames := make([]string, 0)
+	if desired != nil {
+		for _, item := range desired.ConfItems {
+			desiredMap[item.ConfName] = item
+			confNames = append(confNames, item.ConfName)
+		}
+	}
+	for confName := range liveMap {
+		if _, ok := desiredMap[confName]; !ok {
+			confNames = append(confNames, confName)
+		}
+	}
+	sort.Strings(confNames)
+
+	for _, confName := range confNames {
+		d, l := desiredMap[confName], liveMap[confName]
+		var op *api.UpsertConfItem
+		switch {
+		case l == nil:
+			op = &api.UpsertConfItem{OperationType: api.OperationType{OPType: constvar.OPTypeAdd}}
+		case d == nil:
+			op = &api.UpsertConfItem{OperationType: api.OperationType{OPType: constvar.OPTypeRemove}}
+		case *d != *l:
+			op = &api.UpsertConfItem{OperationType: api.OperationType{OPType: constvar.OPTypeUpdate}}
+		default:
+			continue
+		}
+		if l != nil {
+			lp.ValuesBefore[confName] = l.ConfValue
+		}
+		if d == nil {
+			op.BaseConfItemDef = api.BaseConfItemDef{ConfName: l.ConfName, ConfValue: l.ConfValue}
+		} else {
+			op.BaseConfItemDef = api.BaseConfItemDef{
+				ConfName:    d.ConfName,
+				ConfValue:   d.ConfValue,
+				Description: d.Description,
+				FlagDisable: d.FlagDisable,
+				FlagLocked:  d.FlagLocked,
+			}
+		}
+		lp.ConfItems = append(lp.ConfItems, op)
+	}
+	return lp
+}
+
+// ApplyConfigTreePlan 在一个事务里执行 plan，任意一步失败全部回滚
+// 复用 UpsertConfigFilePlat, UpdateConfigFileItems 的校验和发布逻辑，它们内部的事务会变成外层事务的 savepoint
+// 发布通知延迟到外层事务提交后
+func ApplyConfigTreePlan(plan *api.ConfTreePlan, confirm int8, description, opUser string) error {
+	if len(plan.Errors) > 0 {
+		return errors.Errorf("plan has errors: %s", strings.Join(plan.Errors, "; "))
+	}
+	db, notify := withPublishNotify(model.DB.Self)
+	txErr := db.Transaction(func(tx *gorm.DB) error {
+		for _, fp := range plan.Files {
+			if err := applyConfTreeFile(tx, fp, confirm, description, opUser); err != nil {
+				return errors.WithMessagef(err, "%s/%s/%s", fp.Namespace, fp.ConfType, fp.ConfFile)
+			}
+		}
+		return nil
+	})
+	if txErr != nil {
+		return txErr
+	}
+	notify()
+	// 事务中刷新的缓存读到的是提交前的数据，提交后重新刷新
+	for _, fp := range plan.Files {
+		model.CacheSetAndGetConfigFile(fp.BaseConfFileDef)
+	}
+	return nil
+}
+
+func applyConfTreeFile(tx *gorm.DB, fp *api.ConfTreeFilePlan, confirm int8, description, opUser string) error {
+	if fp.FileInfoChanged || len(fp.ConfNames) > 0 {
+		r := &api.UpsertConfFilePlatReq{
+			RequestType:  api.RequestType{ReqType: constvar.MethodSaveAndPublish},
+			Confirm:      confirm,
+			Description:  description,
+			ConfFileInfo: fp.ConfFileInfo,
+			ConfNames:    fp.ConfNames,
+		}
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		if err := CheckValidConfType(fp.Namespace, fp.ConfType, fp.ConfFile, "", 2); err != nil {
+			return err
+		}
+		if _, err := upsertConfigFilePlat(tx, r, "edit", opUser); err != nil {
+			return errors.WithMessage(err, constvar.LevelPlat)
+		}
+	}
+	reqType := constvar.MethodSave
+	var needVersioned int8 = 0
+	if checkVersionable(fp.Namespace, fp.ConfType) {
+		reqType = constvar.MethodSaveAndPublish
+		needVersioned = 1
+	}
+	for _, lp := range fp.Levels {
+		r := &api.UpsertConfItemsReq{
+			RequestType: api.RequestType{ReqType: reqType},
+			SaveConfItemsReq: api.SaveConfItemsReq{
+				BKBizIDDef:   api.BKBizIDDef{BKBizID: lp.BKBizID},
+				Confirm:      confirm,
+				Description:  description,
+				BaseLevelDef: api.BaseLevelDef{LevelName: lp.LevelName, LevelValue: lp.LevelValue},
+				ConfFileInfo: api.ConfFileDef{BaseConfFileDef: fp.BaseConfFileDef},
+				ConfItems:    lp.ConfItems,
+			},
+		}
+		if err := r.SaveConfItemsReq.Validate(); err != nil {
+			return err
+		}
+		if err := CheckValidConfType(fp.Namespace, fp.ConfType, fp.ConfFile, lp.LevelName, needVersioned); err != nil {
+			return err
+		}
+		if _, err := updateConfigFileItems(tx, r, opUser); err != nil {
+			return errors.WithMessagef(err, "%s:%s", lp.LevelName, lp.LevelValue)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package simpleconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"bk-dbconfig/internal/api"
+	"bk-dbconfig/pkg/constvar"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// 配置树目录结构:
+// <dir>/<namespace>/<conf_type>/<conf_file>/file.yaml
+// <dir>/<namespace>/<conf_type>/<conf_file>/plat.yaml
+// <dir>/<namespace>/<conf_type>/<conf_file>/levels/<bk_biz_id>/<level_name>/<level_value>.yaml
+const (
+	confTreeFileDef   = "file.yaml"
+	confTreeFilePlat  = "plat.yaml"
+	confTreeDirLevels = "levels"
+	confTreeExt       = ".yaml"
+)
+
+// confTreePlat plat.yaml 文件内容
+type confTreePlat struct {
+	ConfNames []*api.ConfTreeName `yaml:"conf_names"`
+}
+
+// confTreePathElem 检查作为目录或文件名的值，避免写到配置树目录外
+func confTreePathElem(s string) (string, error) {
+	if s == "" || s == "." || s == ".." || strings.ContainsAny(s, `/\`) {
+		return "", errors.Errorf("invalid path element %q in config tree", s)
+	}
+	return s, nil
+}
+
+func confTreeFileDir(dir string, f api.ConfTreeFileDef) (string, error) {
+	elems := []string{dir}
+	for _, s := range []string{f.Namespace, f.ConfType, f.ConfFile} {
+		elem, err := confTreePathElem(s)
+		if err != nil {
+			return "", err
+		}
+		elems = append(elems, elem)
+	}
+	return filepath.Join(elems...), nil
+}
+
+func writeYamlFile(path string, v interface{}) error {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return errors.WithMessage(err, path)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+func readYamlFile(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return errors.WithMessage(yaml.UnmarshalStrict(b, v), path)
+}
+
+// ExportConfigTree 将配置树写到 dir，返回导出的 conf_file 数量
+// 会先清理本次导出范围内旧的 levels 目录，使导出结果与线上一致
+func ExportConfigTree(dir string, f *api.ConfTreeFilter) (int, error) {
+	nodes, err := QueryConfigTree(f)
+	if err != nil {
+		return 0, err
+	}
+	for _, n := range nodes {
+		fileDir, err := confTreeFileDir(dir, n.FileDef)
+		if err != nil {
+			return 0, err
+		}
+		if err = writeYamlFile(filepath.Join(fileDir, confTreeFileDef), n.FileDef); err != nil {
+			return 0, err
+		}
+		plat := confTreePlat{ConfNames: n.ConfNames}
+		if err = writeYamlFile(filepath.Join(fileDir, confTreeFilePlat), plat); err != nil {
+			return 0, err
+		}
+		if f.BKBizID == constvar.BKBizIDForPlat {
+			continue
+		}
+		levelsDir := filepath.Join(fileDir, confTreeDirLevels)
+		if f.BKBizID != "" {
+			levelsDir = filepath.Join(levelsDir, f.BKBizID)
+		}
+		if err = os.RemoveAll(levelsDir); err != nil {
+			return 0, err
+		}
+		for _, l := range n.Levels {
+			path, err := confTreeLevelPath(fileDir, l)
+			if err != nil {
+				return 0, errors.WithMessage(err, confTreeFileKey(n.FileDef))
+			}
+			if err = writeYamlFile(path, l); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(nodes), nil
+}
+
+func confTreeLevelPath(fileDir string, l *api.ConfTreeLevel) (string, error) {
+	elems := []string{fileDir, confTreeDirLevels}
+	for _, s := range []string{l.BKBizID, l.LevelName, l.LevelValue} {
+		elem, err := confTreePathElem(s)
+		if err != nil {
+			return "", err
+		}
+		elems = append(elems, elem)
+	}
+	elems[len(elems)-1] += confTreeExt
+	return filepath.Join(elems...), nil
+}
+
+// LoadConfigTree 从 dir 读取期望的配置树，只保留 f 范围内的配置
+func LoadConfigTree(dir string, f *api.ConfTreeFilter) ([]*api.ConfTreeNode, error) {
+	fileDefs, err := filepath.Glob(filepath.Join(dir, "*", "*", "*", confTreeFileDef))
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*api.ConfTreeNode, 0, len(fileDefs))
+	for _, fileDefPath := range fileDefs {
+		node := &api.ConfTreeNode{}
+		if err = readYamlFile(fileDefPath, &node.FileDef); err != nil {
+			return nil, err
+		}
+		fd := node.FileDef
+		if (f.Namespace != "" && f.Namespace != fd.Namespace) || (f.ConfType != "" && f.ConfType != fd.ConfType) ||
+			(f.ConfFile != "" && f.ConfFile != fd.ConfFile) {
+			continue
+		}
+		fileDir := filepath.Dir(fileDefPath)
+		if expect, err := confTreeFileDir(dir, fd); err != nil {
+			return nil, err
+		} else if expect != fileDir {
+			return nil, errors.Errorf("%s does not match its directory, expect %s", fileDefPath, expect)
+		}
+
+		plat := confTreePlat{}
+		platPath := filepath.Join(fileDir, confTreeFilePlat)
+		if _, err = os.Stat(platPath); err == nil {
+			if err = readYamlFile(platPath, &plat); err != nil {
+				return nil, err
+			}
+		}
+		node.ConfNames = plat.ConfNames
+
+		if f.BKBizID != constvar.BKBizIDForPlat {
+			if node.Levels, err = loadConfTreeLevels(fileDir, f.BKBizID); err != nil {
+				return nil, err
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	sortConfTree(nodes)
+	return nodes, nil
+}
+
+func loadConfTreeLevels(fileDir, bkBizID string) ([]*api.ConfTreeLevel, error) {
+	bizPattern := "*"
+	if bkBizID != "" {
+		bizPattern = bkBizID
+	}
+	paths, err := filepath.Glob(filepath.Join(fileDir, confTreeDirLevels, bizPattern, "*", "*"+confTreeExt))
+	if err != nil {
+		return nil, err
+	}
+	levels := make([]*api.ConfTreeLevel, 0, len(paths))
+	for _, path := range paths {
+		l := &api.ConfTreeLevel{}
+		if err = readYamlFile(path, l); err != nil {
+			return nil, err
+		}
+		if expect, err := confTreeLevelPath(fileDir, l); err != nil {
+			return nil, errors.WithMessage(err, path)
+		} else if expect != path {
+			return nil, errors.Errorf("%s does not match its level node, expect %s", path, expect)
+		}
+		levels = append(levels, l)
+	}
+	return levels, nil
+}
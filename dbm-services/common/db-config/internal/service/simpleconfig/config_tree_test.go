@@ -0,0 +1,146 @@
+package simpleconfig
+
+import (
+	"bk-dbconfig/internal/api"
+	"bk-dbconfig/pkg/constvar"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTestConfTree() *api.ConfTreeNode {
+	return &api.ConfTreeNode{
+		FileDef: api.ConfTreeFileDef{
+			Namespace: "tendbha", ConfType: "dbconf", ConfFile: "MySQL-5.7", LevelNames: "plat,app,module,cluster",
+		},
+		ConfNames: []*api.ConfTreeName{
+			{ConfName: "mysqld.port", ValueType: "INT", ValueDefault: "3306"},
+			{ConfName: "mysqld.sql_mode", ValueType: "STRING", ValueDefault: "''"},
+		},
+		Levels: []*api.ConfTreeLevel{
+			{BKBizID: "100", LevelName: "cluster", LevelValue: "c1", ConfItems: []*api.ConfTreeItem{
+				{ConfName: "mysqld.sql_mode", ConfValue: "STRICT"},
+			}},
+			{BKBizID: "100", LevelName: "app", LevelValue: "100", ConfItems: []*api.ConfTreeItem{
+				{ConfName: "mysqld.charset", ConfValue: "utf8"},
+				{ConfName: "mysqld.max_connections", ConfValue: "3000"},
+			}},
+		},
+	}
+}
+
+func TestPlanConfigTree(t *testing.T) {
+	Convey("Test plan config tree against live", t, func() {
+		live := newTestConfTree()
+		desired := newTestConfTree()
+		plan := PlanConfigTree([]*api.ConfTreeNode{desired}, []*api.ConfTreeNode{live})
+		So(plan.IsEmpty(), ShouldBeTrue)
+		So(len(plan.Warnings), ShouldEqual, 0)
+
+		desired.FileDef.Description = "mysql 5.7"
+		desired.FileDef.LevelNames = "plat,app"
+		desired.ConfNames[0].ValueDefault = "3307"
+		desired.ConfNames = append(desired.ConfNames,
+			&api.ConfTreeName{ConfName: "mysqld.charset", ValueType: "STRING"})
+		desired.Levels[1].ConfItems = desired.Levels[1].ConfItems[:1]
+		desired.Levels[1].ConfItems[0].ConfValue = "utf8mb4"
+		desired.Levels = append(desired.Levels[1:], &api.ConfTreeLevel{
+			BKBizID: "100", LevelName: "module", LevelValue: "1", ConfItems: []*api.ConfTreeItem{
+				{ConfName: "mysqld.sql_mode", ConfValue: "''", FlagLocked: 1},
+			},
+		})
+		plan = PlanConfigTree([]*api.ConfTreeNode{desired}, []*api.ConfTreeNode{live})
+		So(len(plan.Warnings), ShouldEqual, 1)
+		So(len(plan.Files), ShouldEqual, 1)
+		fp := plan.Files[0]
+		So(fp.FileInfoChanged, ShouldBeTrue)
+		So(fp.ConfFileInfo.Description, ShouldEqual, "mysql 5.7")
+
+		So(len(fp.ConfNames), ShouldEqual, 2)
+		So(fp.ConfNames[0].ConfName, ShouldEqual, "mysqld.charset")
+		So(fp.ConfNames[0].OPType, ShouldEqual, constvar.OPTypeAdd)
+		So(fp.ConfNames[1].OPType, ShouldEqual, constvar.OPTypeUpdate)
+		So(fp.ValuesBefore["mysqld.port"], ShouldEqual, "3306")
+
+		// 按层级从上到下: app, module, cluster
+		So(len(fp.Levels), ShouldEqual, 3)
+		So(fp.Levels[0].LevelName, ShouldEqual, "app")
+		So(len(fp.Levels[0].ConfItems), ShouldEqual, 2)
+		So(fp.Levels[0].ConfItems[0].OPType, ShouldEqual, constvar.OPTypeUpdate)
+		So(fp.Levels[0].ConfItems[1].ConfName, ShouldEqual, "mysqld.max_connections")
+		So(fp.Levels[0].ConfItems[1].OPType, ShouldEqual, constvar.OPTypeRemove)
+		So(fp.Levels[1].LevelName, ShouldEqual, "module")
+		So(fp.Levels[1].ConfItems[0].OPType, ShouldEqual, constvar.OPTypeAdd)
+		So(fp.Levels[1].ConfItems[0].FlagLocked, ShouldEqual, 1)
+		So(fp.Levels[2].LevelName, ShouldEqual, "cluster")
+		So(fp.Levels[2].ConfItems[0].OPType, ShouldEqual, constvar.OPTypeRemove)
+		So(fp.Levels[2].ConfItems[0].ConfValue, ShouldEqual, "STRICT")
+	})
+
+	Convey("Test plan config tree with missing conf_file", t, func() {
+		desired := newTestConfTree()
+		desired.FileDef.ConfFile = "MySQL-8.0"
+		plan := PlanConfigTree([]*api.ConfTreeNode{desired}, []*api.ConfTreeNode{newTestConfTree()})
+		So(plan.IsEmpty(), ShouldBeTrue)
+		So(len(plan.Warnings), ShouldEqual, 2)
+	})
+
+	Convey("Test plan config tree with encrypted values", t, func() {
+		live := newTestConfTree()
+		live.Levels[1].ConfItems[0].ConfValue = "**cipher-app-100"
+		desired := newTestConfTree()
+		desired.Levels[1].ConfItems[0].ConfValue = "**cipher-app-100"
+		// 原样保留的密文可以 apply
+		desired.Levels[1].ConfItems[1].ConfValue = "4000"
+		plan := PlanConfigTree([]*api.ConfTreeNode{desired}, []*api.ConfTreeNode{live})
+		So(len(plan.Errors), ShouldEqual, 0)
+		So(len(plan.Files), ShouldEqual, 1)
+
+		// 从其它 level node 复制来的密文不能 apply
+		desired.Levels[0].ConfItems[0].ConfValue = "**cipher-app-100"
+		desired.ConfNames[0].ValueDefault = "**cipher-plat"
+		plan = PlanConfigTree([]*api.ConfTreeNode{desired}, []*api.ConfTreeNode{live})
+		So(len(plan.Errors), ShouldEqual, 2)
+		So(ApplyConfigTreePlan(plan, 0, "", ""), ShouldNotBeNil)
+
+		// 明文会在写入时按目标 level node 加密
+		desired.Levels[0].ConfItems[0].ConfValue = "STRICT_ALL"
+		desired.ConfNames[0].ValueDefault = "3307"
+		plan = PlanConfigTree([]*api.ConfTreeNode{desired}, []*api.ConfTreeNode{live})
+		So(len(plan.Errors), ShouldEqual, 0)
+	})
+}
+
+func TestLoadConfigTree(t *testing.T) {
+	Convey("Test load config tree written in yaml", t, func() {
+		dir := t.TempDir()
+		tree := newTestConfTree()
+		fileDir, err := confTreeFileDir(dir, tree.FileDef)
+		So(err, ShouldBeNil)
+		So(writeYamlFile(filepath.Join(fileDir, confTreeFileDef), tree.FileDef), ShouldBeNil)
+		plat := confTreePlat{ConfNames: tree.ConfNames}
+		So(writeYamlFile(filepath.Join(fileDir, confTreeFilePlat), plat), ShouldBeNil)
+		for _, l := range tree.Levels {
+			path, err := confTreeLevelPath(fileDir, l)
+			So(err, ShouldBeNil)
+			So(writeYamlFile(path, l), ShouldBeNil)
+		}
+		sortConfTree([]*api.ConfTreeNode{tree})
+
+		nodes, err := LoadConfigTree(dir, &api.ConfTreeFilter{})
+		So(err, ShouldBeNil)
+		So(nodes, ShouldResemble, []*api.ConfTreeNode{tree})
+
+		nodes, err = LoadConfigTree(dir, &api.ConfTreeFilter{BKBizID: constvar.BKBizIDForPlat})
+		So(err, ShouldBeNil)
+		So(len(nodes[0].Levels), ShouldEqual, 0)
+
+		nodes, err = LoadConfigTree(dir, &api.ConfTreeFilter{ConfType: "backup"})
+		So(err, ShouldBeNil)
+		So(len(nodes), ShouldEqual, 0)
+
+		_, err = confTreeLevelPath(fileDir, &api.ConfTreeLevel{BKBizID: "100", LevelName: "cluster", LevelValue: ".."})
+		So(err, ShouldNotBeNil)
+	})
+}
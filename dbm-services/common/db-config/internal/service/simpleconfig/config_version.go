@@ -157,6 +157,7 @@ func (p *PublishConfig) PublishAndApplyVersioned(db *gorm.DB, isFromApplied bool
 		logger.Errorf("PublishConfig error: %+v", err)
 		return err
 	}
+	notifyPublished(db, c)
 
 	levelNode := api.BaseConfigNode{}
 	copier.Copy(&levelNode, c)
@@ -0,0 +1,237 @@
+package simpleconfig
+
+import (
+	"fmt"
+	"sort"
+
+	"bk-dbconfig/internal/api"
+	"bk-dbconfig/internal/pkg/cst"
+	"bk-dbconfig/internal/repository/model"
+	"bk-dbconfig/pkg/constvar"
+	"bk-dbconfig/pkg/util"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MergeFromOurs 合并结果来自下层
+	MergeFromOurs = "ours"
+	// MergeFromTheirs 合并结果来自上层
+	MergeFromTheirs = "theirs"
+)
+
+// getVersionConfigs 获取 level node 某个版本的配置项，revision 为空时获取已发布版本
+func getVersionConfigs(node api.BaseConfigNode, revision string) (*model.ConfigVersioned, error) {
+	v := &model.ConfigVersionedModel{
+		BKBizID:    node.BKBizID,
+		Namespace:  node.Namespace,
+		ConfType:   node.ConfType,
+		ConfFile:   node.ConfFile,
+		LevelName:  node.LevelName,
+		LevelValue: node.LevelValue,
+		Revision:   revision,
+	}
+	if revision == "" {
+		return v.GetVersionPublished(model.DB.Self)
+	}
+	versioned, err := v.GetVersion(model.DB.Self, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get version %s for %s:%s", revision, node.LevelName, node.LevelValue)
+	}
+	return versioned, nil
+}
+
+func configLevelOrigin(c *model.ConfigModel) string {
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s", c.LevelName, c.LevelValue)
+}
+
+// DiffConfigVersions 比较同一个 level node 的两个版本
+func DiffConfigVersions(r *api.DiffConfigVersionsReq) (*api.DiffConfigVersionsResp, error) {
+	node := api.BaseConfigNode{
+		BKBizIDDef:      r.BKBizIDDef,
+		BaseConfFileDef: r.BaseConfFileDef,
+		BaseLevelDef:    r.BaseLevelDef,
+	}
+	from, err := getVersionConfigs(node, r.RevisionFrom)
+	if err != nil {
+		return nil, err
+	}
+	to, err := getVersionConfigs(node, r.RevisionTo)
+	if err != nil {
+		return nil, err
+	}
+	items, err := CompareVersionConfigs(from.Configs, to.Configs)
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.DiffConfigVersionsResp{RevisionFrom: r.RevisionFrom, RevisionTo: r.RevisionTo, Items: items}
+	for _, item := range items {
+		switch item.OPType {
+		case constvar.OPTypeAdd:
+			resp.Added++
+		case constvar.OPTypeRemove:
+			resp.Removed++
+		case constvar.OPTypeUpdate:
+			resp.Updated++
+		}
+	}
+	return resp, nil
+}
+
+// CompareVersionConfigs 比较两个版本的配置项，带上新旧值的来源层级
+func CompareVersionConfigs(before, after []*model.ConfigModel) ([]*api.ConfItemVersionDiff, error) {
+	beforeMap := make(map[string]*model.ConfigModel, len(before))
+	for _, c := range before {
+		beforeMap[c.ConfName] = c
+	}
+	afterMap := make(map[string]*model.ConfigModel, len(after))
+	for _, c := range after {
+		afterMap[c.ConfName] = c
+	}
+	items := make([]*api.ConfItemVersionDiff, 0)
+	for _, c := range DiffVersionConfigs(before, after) {
+		item := &api.ConfItemVersionDiff{ConfName: c.Config.ConfName, OPType: c.OPType}
+		b, a := beforeMap[item.ConfName], afterMap[item.ConfName]
+		if b != nil {
+			item.ValueBefore = b.ConfValue
+			item.LevelBefore = configLevelOrigin(b)
+		}
+		if a != nil {
+			item.ValueAfter = a.ConfValue
+			item.LevelAfter = configLevelOrigin(a)
+		}
+		if a != nil && b != nil && a.LevelName != b.LevelName {
+			levelCompare, err := ConfigLevelCompare(a, b)
+			if err != nil {
+				return nil, err
+			}
+			item.LevelCompare = levelCompare
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ConfName < items[j].ConfName
+	})
+	return items, nil
+}
+
+// MergeConfigVersions 把上层 level node 从 base 到 their 的变更，合并到下层 level node
+// 只返回合并结果和冲突，不写入配置
+func MergeConfigVersions(r *api.MergeConfigVersionsReq) (*api.MergeConfigVersionsResp, error) {
+	levelMap := cst.GetConfigLevelMap(r.ConfType)
+	upLevel, ok := levelMap[r.UpLevelName]
+	if !ok {
+		return nil, errors.Errorf("unknown up_level_name %s", r.UpLevelName)
+	}
+	if level, ok := levelMap[r.LevelName]; !ok || upLevel >= level {
+		return nil, errors.Errorf("up_level_name %s should be upper than level_name %s", r.UpLevelName, r.LevelName)
+	}
+	ourNode := api.BaseConfigNode{
+		BKBizIDDef:      r.BKBizIDDef,
+		BaseConfFileDef: r.BaseConfFileDef,
+		BaseLevelDef:    r.BaseLevelDef,
+	}
+	upNode := ourNode
+	upNode.LevelName = r.UpLevelName
+	upNode.LevelValue = r.UpLevelValue
+	if r.UpLevelName == constvar.LevelPlat {
+		upNode.BKBizID = constvar.BKBizIDForPlat
+	}
+
+	ours, err := getVersionConfigs(ourNode, r.OurRevision)
+	if err != nil {
+		return nil, err
+	}
+	base, err := getVersionConfigs(upNode, r.BaseRevision)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := getVersionConfigs(upNode, r.TheirRevision)
+	if err != nil {
+		return nil, err
+	}
+	configs, conflicts := ThreeWayMergeConfigs(base.Configs, theirs.Configs, ours.Configs)
+	return &api.MergeConfigVersionsResp{
+		OurRevision:   ours.Versioned.Revision,
+		BaseRevision:  r.BaseRevision,
+		TheirRevision: r.TheirRevision,
+		Configs:       configs,
+		Conflicts:     conflicts,
+	}, nil
+}
+
+// ThreeWayMergeConfigs 三方合并配置项
+//   - 上层没有修改，保留下层
+//   - 下层没有偏离 base，使用上层的修改(包括删除)
+//   - 上下层修改结果一致，保留下层
+//   - 上下层做了不同的修改，记录冲突，保留下层的值
+func ThreeWayMergeConfigs(base, theirs, ours []*model.ConfigModel) (
+	[]*api.ConfItemMerged, []*api.ConfItemMergeConflict) {
+	toMap := func(configs []*model.ConfigModel) map[string]*model.ConfigModel {
+		m := make(map[string]*model.ConfigModel, len(configs))
+		for _, c := range configs {
+			m[c.ConfName] = c
+		}
+		return m
+	}
+	baseMap, theirMap, ourMap := toMap(base), toMap(theirs), toMap(ours)
+	confNames := make([]string, 0)
+	for _, m := range []map[string]*model.ConfigModel{baseMap, theirMap, ourMap} {
+		for confName := range m {
+			confNames = append(confNames, confName)
+		}
+	}
+	confNames = util.SliceUniq(confNames)
+	sort.Strings(confNames)
+
+	merged := make([]*api.ConfItemMerged, 0)
+	conflicts := make([]*api.ConfItemMergeConflict, 0)
+	for _, confName := range confNames {
+		b, t, o := baseMap[confName], theirMap[confName], ourMap[confName]
+		var result *model.ConfigModel
+		from := MergeFromOurs
+		switch {
+		case sameConfValue(t, b), sameConfValue(o, t):
+			result = o
+		case sameConfValue(o, b):
+			result, from = t, MergeFromTheirs
+		default:
+			conflicts = append(conflicts, &api.ConfItemMergeConflict{
+				ConfName:    confName,
+				ValueBase:   confValuePtr(b),
+				ValueTheirs: confValuePtr(t),
+				ValueOurs:   confValuePtr(o),
+				LevelOurs:   configLevelOrigin(o),
+			})
+			result = o
+		}
+		if result == nil { // 删除
+			continue
+		}
+		merged = append(merged, &api.ConfItemMerged{
+			ConfName:  confName,
+			ConfValue: result.ConfValue,
+			From:      from,
+			Level:     configLevelOrigin(result),
+		})
+	}
+	return merged, conflicts
+}
+
+// sameConfValue 同时不存在，或者值相同
+func sameConfValue(a, b *model.ConfigModel) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.ConfValue == b.ConfValue
+}
+
+func confValuePtr(c *model.ConfigModel) *string {
+	if c == nil {
+		return nil
+	}
+	return &c.ConfValue
+}
@@ -0,0 +1,81 @@
+package simpleconfig
+
+import (
+	"bk-dbconfig/internal/repository/model"
+	"bk-dbconfig/pkg/constvar"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompareVersionConfigs(t *testing.T) {
+	Convey("Test compare versioned configs with level origin", t, func() {
+		before := []*model.ConfigModel{
+			{ConfName: "charset", ConfValue: "utf8", LevelName: "module", LevelValue: "m1"},
+			{ConfName: "port", ConfValue: "3306", LevelName: "plat", LevelValue: "0"},
+			{ConfName: "sql_mode", ConfValue: "''", LevelName: "cluster", LevelValue: "c1"},
+		}
+		after := []*model.ConfigModel{
+			{ConfName: "charset", ConfValue: "utf8mb4", LevelName: "cluster", LevelValue: "c1"},
+			{ConfName: "port", ConfValue: "3306", LevelName: "plat", LevelValue: "0"},
+			{ConfName: "sql_mode", ConfValue: "STRICT", LevelName: "module", LevelValue: "m1"},
+			{ConfName: "innodb_io", ConfValue: "200", LevelName: "app", LevelValue: "100"},
+		}
+		items, err := CompareVersionConfigs(before, after)
+		So(err, ShouldBeNil)
+		So(len(items), ShouldEqual, 3)
+		So(items[0].ConfName, ShouldEqual, "charset")
+		So(items[0].OPType, ShouldEqual, constvar.OPTypeUpdate)
+		So(items[0].LevelBefore, ShouldEqual, "module:m1")
+		So(items[0].LevelAfter, ShouldEqual, "cluster:c1")
+		So(items[0].LevelCompare, ShouldEqual, 1)
+		So(items[1].ConfName, ShouldEqual, "innodb_io")
+		So(items[1].OPType, ShouldEqual, constvar.OPTypeAdd)
+		So(items[2].ConfName, ShouldEqual, "sql_mode")
+		So(items[2].LevelCompare, ShouldEqual, -1)
+	})
+}
+
+func TestThreeWayMergeConfigs(t *testing.T) {
+	Convey("Test three-way merge up level changes", t, func() {
+		base := []*model.ConfigModel{
+			{ConfName: "a", ConfValue: "1", LevelName: "module", LevelValue: "m1"},
+			{ConfName: "b", ConfValue: "1", LevelName: "module", LevelValue: "m1"},
+			{ConfName: "c", ConfValue: "1", LevelName: "module", LevelValue: "m1"},
+			{ConfName: "d", ConfValue: "1", LevelName: "module", LevelValue: "m1"},
+			{ConfName: "e", ConfValue: "1", LevelName: "module", LevelValue: "m1"},
+		}
+		theirs := []*model.ConfigModel{
+			{ConfName: "a", ConfValue: "2", LevelName: "module", LevelValue: "m1"}, // 上层修改，下层未修改
+			{ConfName: "b", ConfValue: "2", LevelName: "module", LevelValue: "m1"}, // 上下层不同修改，冲突
+			{ConfName: "c", ConfValue: "1", LevelName: "module", LevelValue: "m1"}, // 上层未修改
+			{ConfName: "f", ConfValue: "1", LevelName: "module", LevelValue: "m1"}, // 上层新增
+			// d 上层删除, e 上层删除而下层修改
+		}
+		ours := []*model.ConfigModel{
+			{ConfName: "a", ConfValue: "1", LevelName: "module", LevelValue: "m1"},
+			{ConfName: "b", ConfValue: "3", LevelName: "cluster", LevelValue: "c1"},
+			{ConfName: "c", ConfValue: "3", LevelName: "cluster", LevelValue: "c1"},
+			{ConfName: "d", ConfValue: "1", LevelName: "module", LevelValue: "m1"},
+			{ConfName: "e", ConfValue: "3", LevelName: "cluster", LevelValue: "c1"},
+		}
+		merged, conflicts := ThreeWayMergeConfigs(base, theirs, ours)
+		result := map[string]string{}
+		for _, c := range merged {
+			result[c.ConfName] = c.ConfValue + "/" + c.From
+		}
+		So(result, ShouldResemble, map[string]string{
+			"a": "2/theirs",
+			"b": "3/ours",
+			"c": "3/ours",
+			"e": "3/ours",
+			"f": "1/theirs",
+		})
+		So(len(conflicts), ShouldEqual, 2)
+		So(conflicts[0].ConfName, ShouldEqual, "b")
+		So(*conflicts[0].ValueTheirs, ShouldEqual, "2")
+		So(conflicts[0].LevelOurs, ShouldEqual, "cluster:c1")
+		So(conflicts[1].ConfName, ShouldEqual, "e")
+		So(conflicts[1].ValueTheirs, ShouldBeNil)
+	})
+}
@@ -0,0 +1,233 @@
+package simpleconfig
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"bk-dbconfig/internal/api"
+	"bk-dbconfig/internal/repository/model"
+	"bk-dbconfig/pkg/constvar"
+	"bk-dbconfig/pkg/core/logger"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// WatchTimeoutDefault watch 默认等待时间
+	WatchTimeoutDefault = 30 * time.Second
+	// WatchTimeoutMax watch 最大等待时间
+	WatchTimeoutMax = 60 * time.Second
+	// watchPollInterval 兜底轮询 db 的间隔。本实例的发布由 publishNotifier 唤醒，
+	// 轮询只用于发现多实例部署时其它实例上的发布
+	watchPollInterval = 15 * time.Second
+)
+
+// publishWaiter 同一个 key 上等待的 watch 请求共用一个 channel，refs 为等待者数量
+type publishWaiter struct {
+	ch   chan struct{}
+	refs int
+}
+
+// publishNotifier 本实例发布版本时，唤醒等待中的 watch 请求
+type publishNotifier struct {
+	mu      sync.Mutex
+	waiters map[string]*publishWaiter
+}
+
+var versionNotifier = &publishNotifier{waiters: make(map[string]*publishWaiter)}
+
+func watchKey(v *model.ConfigVersionedModel) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s",
+		v.BKBizID, v.Namespace, v.ConfType, v.ConfFile, v.LevelName, v.LevelValue)
+}
+
+// wait 返回的 channel 在 key 有新的发布时被关闭
+// 调用方不再等待时必须调用 release，最后一个等待者释放时删除 key，避免超时或取消的请求残留
+func (n *publishNotifier) wait(key string) (<-chan struct{}, func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	w, ok := n.waiters[key]
+	if !ok {
+		w = &publishWaiter{ch: make(chan struct{})}
+		n.waiters[key] = w
+	}
+	w.refs++
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			n.mu.Lock()
+			defer n.mu.Unlock()
+			// 已被 notify 删除或替换时不处理
+			if cur, ok := n.waiters[key]; ok && cur == w {
+				w.refs--
+				if w.refs == 0 {
+					delete(n.waiters, key)
+				}
+			}
+		})
+	}
+	return w.ch, release
+}
+
+func (n *publishNotifier) notify(key string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if w, ok := n.waiters[key]; ok {
+		close(w.ch)
+		delete(n.waiters, key)
+	}
+}
+
+// NotifyVersionPublished 通知 watch 请求重新检查已发布版本
+func NotifyVersionPublished(v *model.ConfigVersionedModel) {
+	versionNotifier.notify(watchKey(v))
+}
+
+type publishCollectorKey struct{}
+
+// publishCollector 收集事务中发布的版本，事务提交后再通知
+type publishCollector struct {
+	versions []*model.ConfigVersionedModel
+}
+
+// withPublishNotify 返回带发布收集器的 db，db 上的事务提交后调用 notify 通知期间发布的版本
+// 避免 watch 请求被唤醒时读到未提交的版本。外层已经在收集时 notify 为空，由外层统一通知
+func withPublishNotify(db *gorm.DB) (*gorm.DB, func()) {
+	if _, ok := db.Statement.Context.Value(publishCollectorKey{}).(*publishCollector); ok {
+		return db, func() {}
+	}
+	collector := &publishCollector{}
+	ctx := context.WithValue(db.Statement.Context, publishCollectorKey{}, collector)
+	return db.WithContext(ctx), func() {
+		for _, v := range collector.versions {
+			NotifyVersionPublished(v)
+		}
+	}
+}
+
+// notifyPublished 版本已发布。在 withPublishNotify 中时延迟到提交后通知，否则直接通知
+func notifyPublished(db *gorm.DB, v *model.ConfigVersionedModel) {
+	if collector, ok := db.Statement.Context.Value(publishCollectorKey{}).(*publishCollector); ok {
+		collector.versions = append(collector.versions, v)
+		return
+	}
+	NotifyVersionPublished(v)
+}
+
+// WatchConfigVersion 阻塞直到 level node 已发布版本与 r.Revision 不同，或者超时
+// 超时返回 changed=false
+func WatchConfigVersion(ctx context.Context, r *api.WatchConfigVersionReq) (*api.WatchConfigVersionResp, error) {
+	timeout := time.Duration(r.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = WatchTimeoutDefault
+	} else if timeout > WatchTimeoutMax {
+		timeout = WatchTimeoutMax
+	}
+	if r.Format == "" {
+		r.Format = constvar.FormatMap
+	}
+	v := &model.ConfigVersionedModel{
+		BKBizID:    r.BKBizID,
+		Namespace:  r.Namespace,
+		ConfType:   r.ConfType,
+		ConfFile:   r.ConfFile,
+		LevelName:  r.LevelName,
+		LevelValue: r.LevelValue,
+	}
+	key := watchKey(v)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	for {
+		resp, done, err := watchOnce(ctx, v, r, key, deadline.C, ticker.C)
+		if done || err != nil {
+			return resp, err
+		}
+	}
+}
+
+// watchOnce 检查一次已发布版本，未变化时等待发布通知、兜底轮询、超时或取消
+// done=false 表示需要重新检查
+func watchOnce(ctx context.Context, v *model.ConfigVersionedModel, r *api.WatchConfigVersionReq, key string,
+	deadline, poll <-chan time.Time) (resp *api.WatchConfigVersionResp, done bool, err error) {
+	// 先注册再查询，避免查询之后、等待之前的发布被漏掉
+	notified, release := versionNotifier.wait(key)
+	defer release()
+	published, err := v.GetVersionPublished(model.DB.Self)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, true, err
+	}
+	if published != nil && published.Versioned.Revision != r.Revision {
+		resp, err = buildWatchResp(v, published, r)
+		return resp, true, err
+	}
+	select {
+	case <-ctx.Done():
+		return &api.WatchConfigVersionResp{Revision: r.Revision, PreRevision: r.Revision}, true, nil
+	case <-deadline:
+		return &api.WatchConfigVersionResp{Revision: r.Revision, PreRevision: r.Revision}, true, nil
+	case <-notified:
+	case <-poll:
+	}
+	return nil, false, nil
+}
+
+// buildWatchResp 返回新版本配置，以及相对客户端持有版本的差异
+func buildWatchResp(v *model.ConfigVersionedModel, published *model.ConfigVersioned,
+	r *api.WatchConfigVersionReq) (*api.WatchConfigVersionResp, error) {
+	resp := &api.WatchConfigVersionResp{
+		Changed:     true,
+		Revision:    published.Versioned.Revision,
+		PreRevision: r.Revision,
+	}
+	var configsBefore []*model.ConfigModel
+	if r.Revision != "" {
+		before := *v
+		before.Revision = r.Revision
+		versioned, err := before.GetVersion(model.DB.Self, nil)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		} else if err != nil {
+			// 客户端持有的版本已被删除，返回全部配置作为差异
+			logger.Warnf("watch revision %s not found for %s", r.Revision, watchKey(v))
+		} else {
+			configsBefore = versioned.Configs
+		}
+	}
+	var err error
+	if resp.Configs, err = FormatConfItemForResp(r.Format, published.Configs); err != nil {
+		return nil, err
+	}
+	configsDiff := DiffVersionConfigs(configsBefore, published.Configs)
+	if resp.ConfigsDiff, err = FormatConfItemOpForResp(constvar.FormatList, configsDiff); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DiffVersionConfigs 比较两个版本的配置项，返回 after 相对 before 的变更
+func DiffVersionConfigs(before, after []*model.ConfigModel) []*model.ConfigModelOp {
+	configsDiff := make([]*model.ConfigModelOp, 0)
+	beforeMap := make(map[string]*model.ConfigModel, len(before))
+	for _, c := range before {
+		beforeMap[c.ConfName] = c
+	}
+	for _, c := range after {
+		if b, ok := beforeMap[c.ConfName]; !ok {
+			configsDiff = append(configsDiff, &model.ConfigModelOp{Config: c, OPType: constvar.OPTypeAdd})
+		} else if b.ConfValue != c.ConfValue {
+			configsDiff = append(configsDiff, &model.ConfigModelOp{Config: c, OPType: constvar.OPTypeUpdate})
+		}
+		delete(beforeMap, c.ConfName)
+	}
+	for _, c := range before {
+		if _, ok := beforeMap[c.ConfName]; ok {
+			configsDiff = append(configsDiff, &model.ConfigModelOp{Config: c, OPType: constvar.OPTypeRemove})
+		}
+	}
+	return configsDiff
+}
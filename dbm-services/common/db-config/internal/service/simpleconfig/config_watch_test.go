@@ -0,0 +1,97 @@
+package simpleconfig
+
+import (
+	"bk-dbconfig/internal/repository/model"
+	"bk-dbconfig/pkg/constvar"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestDiffVersionConfigs(t *testing.T) {
+	Convey("Test diff versioned configs", t, func() {
+		before := []*model.ConfigModel{
+			{ConfName: "charset", ConfValue: "utf8"},
+			{ConfName: "major_version", ConfValue: "mysql-5.7"},
+			{ConfName: "port", ConfValue: "3306"},
+		}
+		after := []*model.ConfigModel{
+			{ConfName: "charset", ConfValue: "utf8mb4"},
+			{ConfName: "port", ConfValue: "3306"},
+			{ConfName: "mycnf_template", ConfValue: "MySQL-5.7"},
+		}
+		opTypes := map[string]string{}
+		for _, c := range DiffVersionConfigs(before, after) {
+			opTypes[c.Config.ConfName] = c.OPType
+		}
+		So(opTypes, ShouldResemble, map[string]string{
+			"charset":        constvar.OPTypeUpdate,
+			"mycnf_template": constvar.OPTypeAdd,
+			"major_version":  constvar.OPTypeRemove,
+		})
+
+		// 客户端没有持有版本时，全部为新增
+		So(len(DiffVersionConfigs(nil, after)), ShouldEqual, 3)
+		So(len(DiffVersionConfigs(after, after)), ShouldEqual, 0)
+	})
+}
+
+func TestWithPublishNotify(t *testing.T) {
+	Convey("Test notify published versions after commit", t, func() {
+		db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:3306)/db",
+			SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+		So(err, ShouldBeNil)
+		v := &model.ConfigVersionedModel{BKBizID: "0", Namespace: "tendbha", ConfType: "dbconf",
+			ConfFile: "MySQL-5.7", LevelName: constvar.LevelPlat, LevelValue: "0"}
+		isNotified := func(ch <-chan struct{}) bool {
+			select {
+			case <-ch:
+				return true
+			default:
+				return false
+			}
+		}
+
+		notified, release := versionNotifier.wait(watchKey(v))
+		defer release()
+		outer, notify := withPublishNotify(db)
+		// 嵌套调用由最外层统一通知
+		inner, innerNotify := withPublishNotify(outer)
+		notifyPublished(inner, v)
+		innerNotify()
+		So(isNotified(notified), ShouldBeFalse)
+		notify()
+		So(isNotified(notified), ShouldBeTrue)
+
+		// 不在收集中时直接通知
+		notified, release2 := versionNotifier.wait(watchKey(v))
+		defer release2()
+		notifyPublished(db, v)
+		So(isNotified(notified), ShouldBeTrue)
+	})
+}
+
+func TestPublishNotifierRelease(t *testing.T) {
+	Convey("Test release waiters on timeout or cancel", t, func() {
+		n := &publishNotifier{waiters: make(map[string]*publishWaiter)}
+		ch1, release1 := n.wait("k")
+		ch2, release2 := n.wait("k")
+		So(ch1, ShouldEqual, ch2)
+		release1()
+		release1()
+		So(len(n.waiters), ShouldEqual, 1)
+		release2()
+		So(len(n.waiters), ShouldEqual, 0)
+
+		// notify 之后释放不影响新的等待者
+		_, release3 := n.wait("k")
+		n.notify("k")
+		ch4, release4 := n.wait("k")
+		release3()
+		So((<-chan struct{})(n.waiters["k"].ch) == ch4, ShouldBeTrue)
+		release4()
+		So(len(n.waiters), ShouldEqual, 0)
+	})
+}
@@ -14,11 +14,13 @@ type EncryptOpt struct {
 	// EncryptEnable 是否启用备份文件加密（对称加密），加密密码 passphrase 随机生成
 	// EncryptEnable 为 true 时，EncryptTool EncryptPublicKey 有效
 	EncryptEnable bool `ini:"EncryptEnable" json:"encrypt_enable" `
-	// 加密工具，支持 openssl,xbcrypt，如果是xbcrypt 请指定路径
+	// 加密工具，支持 openssl,xbcrypt,sio，如果是xbcrypt 请指定路径
+	// sio 为进程内加密，不依赖外部命令，每个文件的 data key 由 EncryptPublicKey 和 passphrase 加密后写在文件头
 	EncryptCmd string `ini:"EncryptCmd" json:"encrypt_cmd"`
 	// EncryptAlgo encrypt algorithm, leave it empty has default algorithm
 	//  openssl [aes-256-cbc, aes-128-cbc, sm4-cbc]
 	//  xbcrypt [AES256, AES192, AES128]
+	//  sio [DARE]
 	EncryptAlgo iocrypt.AlgoType `ini:"EncryptElgo" json:"encrypt_algo"`
 	// EncryptPublicKey public key 文件，对 passphrase 加密，上报加密字符串
 	// 需要对应的平台 私钥 secret key 才能对 加密后的passphrase 解密
@@ -59,8 +61,10 @@ func (e *EncryptOpt) Init() (err error) {
 	if e.EncryptCmd == "" {
 		e.EncryptCmd = "openssl"
 	}
-	if _, err = exec.LookPath(e.EncryptCmd); err != nil {
-		return err
+	if e.EncryptCmd != "sio" {
+		if _, err = exec.LookPath(e.EncryptCmd); err != nil {
+			return err
+		}
 	}
 	e.passPhrase = RandomString(32) // symmetric encrypt key to encrypt files // use lo.RandomString
 	if e.EncryptPublicKey == "" {
@@ -70,7 +74,16 @@ func (e *EncryptOpt) Init() (err error) {
 			return err
 		}
 	}
-	if strings.Contains(e.EncryptCmd, "openssl") {
+	if e.EncryptCmd == "sio" {
+		e.EncryptAlgo = iocrypt.AlgoDARE
+		sioCrypt := iocrypt.SioCrypt{Passphrase: e.passPhrase}
+		if e.EncryptPublicKey != "" {
+			if sioCrypt.PublicKey, err = iocrypt.ReadPublicKeyFile(e.EncryptPublicKey); err != nil {
+				return err
+			}
+		}
+		e.encryptTool = sioCrypt
+	} else if strings.Contains(e.EncryptCmd, "openssl") {
 		if e.EncryptAlgo == "" {
 			e.EncryptAlgo = iocrypt.AlgoAES256CBC
 		}
@@ -0,0 +1,165 @@
+// Package dbconfig db-config 配置中心客户端
+package dbconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// WatchPath db-config 订阅配置发布版本变更的接口
+	WatchPath = "/bkconfig/v1/version/watch"
+	// DefaultWatchTimeout 单次 watch 请求在服务端的最长等待时间
+	DefaultWatchTimeout = 30 * time.Second
+
+	watchRetryMin = 1 * time.Second
+	watchRetryMax = 30 * time.Second
+)
+
+// WatchReq 订阅的配置文件 level node
+type WatchReq struct {
+	BKBizID    string
+	Namespace  string
+	ConfType   string
+	ConfFile   string
+	LevelName  string
+	LevelValue string
+	// Revision 当前持有的版本，为空时第一次 watch 会直接返回已发布版本
+	Revision string
+	// Format 返回 configs 的格式，默认 map
+	Format string
+	// Timeout 服务端最长等待时间，最大 60s
+	Timeout time.Duration
+}
+
+// WatchResp 配置文件发布版本变更
+type WatchResp struct {
+	// Changed false 表示等待超时，没有新的发布版本
+	Changed     bool                   `json:"changed"`
+	Revision    string                 `json:"revision"`
+	PreRevision string                 `json:"pre_revision"`
+	Configs     map[string]interface{} `json:"configs"`
+	// ConfigsDiff 相对 PreRevision 的差异，conf_name: {conf_value, op_type}
+	ConfigsDiff map[string]ConfItemDiff `json:"configs_diff"`
+}
+
+// ConfItemDiff 配置项差异
+type ConfItemDiff struct {
+	ConfName  string `json:"conf_name"`
+	ConfValue string `json:"conf_value"`
+	// OPType add, update, remove
+	OPType string `json:"op_type"`
+}
+
+type apiResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Client db-config 客户端
+type Client struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewClient endpoint 如 http://bkconfig-svr:80, headers 为额外的请求头(如网关认证信息)
+func NewClient(endpoint string, headers map[string]string) *Client {
+	return &Client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		headers:  headers,
+		client:   &http.Client{Transport: &http.Transport{}},
+	}
+}
+
+// WatchOnce 发起一次 long-poll 请求，服务端在有新版本或者超时后返回
+func (c *Client) WatchOnce(ctx context.Context, req WatchReq) (*WatchResp, error) {
+	if req.Timeout <= 0 {
+		req.Timeout = DefaultWatchTimeout
+	}
+	params := url.Values{}
+	params.Set("bk_biz_id", req.BKBizID)
+	params.Set("namespace", req.Namespace)
+	params.Set("conf_type", req.ConfType)
+	params.Set("conf_file", req.ConfFile)
+	params.Set("level_name", req.LevelName)
+	params.Set("level_value", req.LevelValue)
+	params.Set("revision", req.Revision)
+	params.Set("format", req.Format)
+	params.Set("timeout", strconv.Itoa(int(req.Timeout.Seconds())))
+
+	// 客户端超时要比服务端等待时间长
+	ctx, cancel := context.WithTimeout(ctx, req.Timeout+10*time.Second)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s%s?%s", c.endpoint, WatchPath, params.Encode()), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, errors.Errorf("watch config http status %d: %s", resp.StatusCode, body)
+	}
+	var apiResp apiResponse
+	if err = json.Unmarshal(body, &apiResp); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal watch response: %s", body)
+	}
+	if apiResp.Code != 0 {
+		return nil, errors.Errorf("watch config failed, code=%d message=%s", apiResp.Code, apiResp.Message)
+	}
+	var watchResp WatchResp
+	if err = json.Unmarshal(apiResp.Data, &watchResp); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal watch data: %s", apiResp.Data)
+	}
+	return &watchResp, nil
+}
+
+// Watch 持续订阅配置发布版本，有新版本时调用 onChange，直到 ctx 结束或者 onChange 返回错误
+// 请求失败时按指数退避重试，onChange 成功后才会使用新的 revision 继续订阅
+func (c *Client) Watch(ctx context.Context, req WatchReq, onChange func(resp *WatchResp) error) error {
+	retryWait := watchRetryMin
+	for {
+		resp, err := c.WatchOnce(ctx, req)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryWait):
+			}
+			retryWait = min(retryWait*2, watchRetryMax)
+			continue
+		}
+		retryWait = watchRetryMin
+		if !resp.Changed {
+			continue
+		}
+		if err = onChange(resp); err != nil {
+			return errors.WithMessagef(err, "apply config revision %s", resp.Revision)
+		}
+		req.Revision = resp.Revision
+	}
+}
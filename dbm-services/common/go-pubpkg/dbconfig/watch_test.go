@@ -0,0 +1,57 @@
+package dbconfig_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dbm-services/common/go-pubpkg/dbconfig"
+)
+
+func TestWatch(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		revision := r.URL.Query().Get("revision")
+		switch {
+		case r.URL.Path != dbconfig.WatchPath:
+			w.WriteHeader(http.StatusNotFound)
+		case calls == 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case revision == "":
+			fmt.Fprint(w, `{"code":0,"data":{"changed":true,"revision":"v1","configs":{"a":"1"}}}`)
+		case revision == "v1" && calls == 3:
+			fmt.Fprint(w, `{"code":0,"data":{"changed":false,"revision":"v1"}}`)
+		default:
+			fmt.Fprintf(w, `{"code":0,"data":{"changed":true,"revision":"v2","pre_revision":"%s",`+
+				`"configs":{"a":"2"},"configs_diff":{"a":{"conf_name":"a","conf_value":"2","op_type":"update"}}}}`,
+				revision)
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var revisions []string
+	stop := fmt.Errorf("stop")
+	err := dbconfig.NewClient(server.URL, nil).Watch(ctx, dbconfig.WatchReq{BKBizID: "0", Timeout: time.Second},
+		func(resp *dbconfig.WatchResp) error {
+			revisions = append(revisions, resp.Revision)
+			if resp.Revision == "v2" {
+				if resp.PreRevision != "v1" || resp.ConfigsDiff["a"].OPType != "update" {
+					t.Errorf("unexpected diff: %+v", resp)
+				}
+				return stop
+			}
+			return nil
+		})
+	if err == nil || ctx.Err() != nil {
+		t.Fatalf("watch should stop by callback, err=%v", err)
+	}
+	if fmt.Sprint(revisions) != "[v1 v2]" {
+		t.Fatalf("unexpected revisions %v", revisions)
+	}
+}
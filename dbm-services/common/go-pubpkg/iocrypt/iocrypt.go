@@ -23,6 +23,9 @@ func FileEncryptWriter(cryptTool EncryptTool, w io.Writer) (io.WriteCloser, erro
 	if cryptTool == nil {
 		return nil, errors.New("no crypt tool provide")
 	}
+	if streamTool, ok := cryptTool.(StreamEncryptTool); ok {
+		return streamTool.NewEncryptWriter(w)
+	}
 	xbw := &FileEncrypter{CryptTool: cryptTool}
 	if err := xbw.InitWriter(w); err != nil {
 		return nil, err
@@ -3,6 +3,7 @@ package iocrypt
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
@@ -55,6 +56,9 @@ func PublicKeyToBytes(pub *rsa.PublicKey) ([]byte, error) {
 // BytesToPrivateKey bytes to private key
 func BytesToPrivateKey(priv []byte) (*rsa.PrivateKey, error) {
 	block, _ := pem.Decode(priv)
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
 	enc := x509.IsEncryptedPEMBlock(block)
 	b := block.Bytes
 	var err error
@@ -75,6 +79,9 @@ func BytesToPrivateKey(priv []byte) (*rsa.PrivateKey, error) {
 // BytesToPublicKey bytes to public key
 func BytesToPublicKey(pub []byte) (*rsa.PublicKey, error) {
 	block, _ := pem.Decode(pub)
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
 	enc := x509.IsEncryptedPEMBlock(block)
 	b := block.Bytes
 	var err error
@@ -130,6 +137,42 @@ func DecryptWithPrivateKey(ciphertext []byte, priv *rsa.PrivateKey) ([]byte, err
 	return plaintext, nil
 }
 
+// EncryptWithPublicKeyOAEP encrypts data with public key using RSA-OAEP(sha256)
+func EncryptWithPublicKeyOAEP(msg []byte, pub *rsa.PublicKey) ([]byte, error) {
+	return rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, msg, nil)
+}
+
+// DecryptWithPrivateKeyOAEP decrypts data with private key using RSA-OAEP(sha256)
+func DecryptWithPrivateKeyOAEP(ciphertext []byte, priv *rsa.PrivateKey) ([]byte, error) {
+	return rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, ciphertext, nil)
+}
+
+// ReadPublicKeyFile 读取 PEM 格式的 RSA 公钥文件
+func ReadPublicKeyFile(publicKeyFile string) (*rsa.PublicKey, error) {
+	bs, err := os.ReadFile(publicKeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to read encrypt public key file")
+	}
+	pubKey, err := BytesToPublicKey(bs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to parse public key file %s", publicKeyFile)
+	}
+	return pubKey, nil
+}
+
+// ReadPrivateKeyFile 读取 PEM 格式的 RSA 私钥文件
+func ReadPrivateKeyFile(privateKeyFile string) (*rsa.PrivateKey, error) {
+	bs, err := os.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to read decrypt private key file")
+	}
+	privKey, err := BytesToPrivateKey(bs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to parse private key file %s", privateKeyFile)
+	}
+	return privKey, nil
+}
+
 // EncryptStringWithPubicKey 使用RSA公钥，加密 对称密码
 // 返回base64
 func EncryptStringWithPubicKey(passPhrase string, publicKeyFile string) (string, error) {
@@ -0,0 +1,420 @@
+package iocrypt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/minio/sio"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 加密文件格式:
+//
+//	magic(8 bytes) | header length(4 bytes, big endian) | header(json) | DARE stream
+//
+// header 里记录了每个接收方(RSA 公钥 / passphrase)加密后的 data key，解密时不需要额外的元数据。
+// DARE stream 使用的 key 由 data key 和 header 通过 HMAC-SHA256 派生，header 被篡改会导致解密失败
+
+const (
+	// AlgoDARE sio 使用的加密格式
+	AlgoDARE AlgoType = "DARE"
+
+	// KekRSA data key 使用 RSA-OAEP-SHA256 加密
+	KekRSA = "rsa-oaep-sha256"
+	// KekScrypt data key 使用 passphrase 经 scrypt 派生的 key 加密(AES-256-GCM)
+	KekScrypt = "scrypt-aes-256-gcm"
+
+	sioHeaderVersion = 1
+	sioDataKeySize   = 32
+	sioMaxHeaderSize = 64 * 1024
+
+	// scrypt 参数，与 minio/sio 示例保持一致
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+
+	scryptMaxN  = 1 << 20
+	scryptMaxRP = 1 << 6
+)
+
+var sioMagic = []byte("DBMSIO\x00\x01")
+
+// StreamEncryptTool 进程内流式加密工具，不需要启动外部命令
+// FileEncryptWriter 会优先使用 NewEncryptWriter
+type StreamEncryptTool interface {
+	EncryptTool
+	NewEncryptWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// SioCrypt 纯 go 实现的 EncryptTool，基于 minio/sio (DARE) 的认证流式加密
+// 每个文件随机生成 data key，data key 使用 PublicKey 和/或 Passphrase 加密后写入文件头
+type SioCrypt struct {
+	// PublicKey RSA 公钥，持有对应私钥即可解密
+	PublicKey *rsa.PublicKey
+	// Passphrase 对称密码，通过 scrypt 派生 key encryption key
+	Passphrase string
+}
+
+// sioHeader 加密文件头
+type sioHeader struct {
+	Version    int            `json:"version"`
+	Algo       AlgoType       `json:"algo"`
+	Recipients []sioRecipient `json:"recipients"`
+}
+
+// sioRecipient 一个可以解开 data key 的接收方
+type sioRecipient struct {
+	KekType string `json:"kek_type"`
+	// KeyId RSA 公钥指纹 sha256(PKIX DER)
+	KeyId      string `json:"key_id,omitempty"`
+	Salt       []byte `json:"salt,omitempty"`
+	N          int    `json:"n,omitempty"`
+	R          int    `json:"r,omitempty"`
+	P          int    `json:"p,omitempty"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// BuildCommand sio 在进程内加密，没有外部命令
+func (e SioCrypt) BuildCommand(ctx context.Context) (*exec.Cmd, error) {
+	return nil, errors.New("sio is an in-process encrypt tool, no command to build")
+}
+
+// DefaultSuffix return default suffix for encrypt tool
+func (e SioCrypt) DefaultSuffix() string {
+	return "sio"
+}
+
+// Name return encrypt tool name
+func (e SioCrypt) Name() string {
+	return "sio"
+}
+
+// NewEncryptWriter 写入文件头，返回加密 writer
+// 写入结束后需要调用 Close，Close 不会关闭 w
+func (e SioCrypt) NewEncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	if e.PublicKey == nil && e.Passphrase == "" {
+		return nil, errors.New("no key provide")
+	}
+	dataKey := make([]byte, sioDataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, errors.Wrap(err, "generate data key")
+	}
+	header := sioHeader{Version: sioHeaderVersion, Algo: AlgoDARE}
+	if e.PublicKey != nil {
+		recipient, err := wrapKeyWithPublicKey(dataKey, e.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		header.Recipients = append(header.Recipients, recipient)
+	}
+	if e.Passphrase != "" {
+		recipient, err := wrapKeyWithPassphrase(dataKey, e.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		header.Recipients = append(header.Recipients, recipient)
+	}
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, len(sioMagic)+4+len(headerBytes)))
+	buf.Write(sioMagic)
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(headerBytes)))
+	buf.Write(headerBytes)
+	if _, err = w.Write(buf.Bytes()); err != nil {
+		return nil, errors.Wrap(err, "write encrypt header")
+	}
+	// sio 的 Close 会关闭实现了 io.Closer 的下层 writer，这里屏蔽掉，与 FileEncrypter 保持一致
+	return sio.EncryptWriter(struct{ io.Writer }{w}, sioConfig(dataKey, headerBytes))
+}
+
+// EncryptFile 加密文件 src 写入 dst，dst 可以与 src 相同
+// 先写到 dst 同目录的临时文件，成功后再 rename，不会留下不完整的文件
+func (e SioCrypt) EncryptFile(src string, dst string) error {
+	return rewriteFile(src, dst, func(w io.Writer, r io.Reader) error {
+		ew, err := e.NewEncryptWriter(w)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(ew, r); err != nil {
+			return errors.Wrap(err, "encrypt")
+		}
+		return errors.WithStack(ew.Close())
+	})
+}
+
+// SioDecrypter 解密 SioCrypt 加密的文件，根据文件头选择可用的 key
+// data key 由文件头里的接收方解出，恢复时只需要平台的 RSA 私钥或 passphrase
+type SioDecrypter struct {
+	PrivateKey *rsa.PrivateKey
+	Passphrase string
+}
+
+// NewSioDecrypter 使用 RSA 私钥文件和/或 passphrase 构造解密器，至少提供一个
+func NewSioDecrypter(privateKeyFile string, passphrase string) (*SioDecrypter, error) {
+	if privateKeyFile == "" && passphrase == "" {
+		return nil, errors.New("private key file or passphrase is required to decrypt")
+	}
+	d := &SioDecrypter{Passphrase: passphrase}
+	if privateKeyFile != "" {
+		privKey, err := ReadPrivateKeyFile(privateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		d.PrivateKey = privKey
+	}
+	return d, nil
+}
+
+// NewDecryptReader 读取文件头解出 data key，返回解密 reader
+// 数据被篡改时，Read 会返回 sio.Error
+func (d SioDecrypter) NewDecryptReader(r io.Reader) (io.Reader, error) {
+	headerBytes, err := readSioHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	var header sioHeader
+	if err = json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, errors.Wrap(err, "parse encrypt header")
+	}
+	if header.Version != sioHeaderVersion || header.Algo != AlgoDARE {
+		return nil, errors.Errorf("unsupported encrypt header version=%d algo=%s", header.Version, header.Algo)
+	}
+	dataKey, err := d.unwrapKey(header.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	// 加密时没有写入任何数据，sio 不会产生数据包，文件只有文件头
+	br := bufio.NewReader(r)
+	if _, err = br.Peek(1); err == io.EOF {
+		return bytes.NewReader(nil), nil
+	}
+	return sio.DecryptReader(br, sioConfig(dataKey, headerBytes))
+}
+
+// Decrypt 解密 src 写入 dst，返回写入的明文字节数
+func (d SioDecrypter) Decrypt(dst io.Writer, src io.Reader) (int64, error) {
+	reader, err := d.NewDecryptReader(src)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(dst, reader)
+	return written, errors.WithStack(err)
+}
+
+// DecryptFile 解密文件 src 写入 dst，dst 可以与 src 相同
+func (d SioDecrypter) DecryptFile(src string, dst string) error {
+	return rewriteFile(src, dst, func(w io.Writer, r io.Reader) error {
+		_, err := d.Decrypt(w, r)
+		return err
+	})
+}
+
+func (d SioDecrypter) unwrapKey(recipients []sioRecipient) ([]byte, error) {
+	var keyId string
+	if d.PrivateKey != nil {
+		var err error
+		if keyId, err = publicKeyId(&d.PrivateKey.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+	// 某个接收方解不开时继续尝试其它接收方，都失败才返回最后一个错误
+	var lastErr error
+	for _, recipient := range recipients {
+		switch recipient.KekType {
+		case KekRSA:
+			if d.PrivateKey == nil || recipient.KeyId != keyId {
+				continue
+			}
+			dataKey, err := DecryptWithPrivateKeyOAEP(recipient.WrappedKey, d.PrivateKey)
+			if err != nil {
+				lastErr = errors.Wrap(err, "decrypt data key with private key")
+				continue
+			}
+			return dataKey, nil
+		case KekScrypt:
+			if d.Passphrase == "" {
+				continue
+			}
+			dataKey, err := unwrapKeyWithPassphrase(recipient, d.Passphrase)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			return dataKey, nil
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, errors.New("no matched key to decrypt data key")
+}
+
+// IsSioEncrypted 判断文件头是否为 SioCrypt 格式
+func IsSioEncrypted(head []byte) bool {
+	return bytes.HasPrefix(head, sioMagic)
+}
+
+// IsSioEncryptedFile 判断文件是否为 SioCrypt 加密格式
+func IsSioEncryptedFile(name string) (bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer f.Close()
+	head := make([]byte, len(sioMagic))
+	if _, err = io.ReadFull(f, head); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return false, nil
+	} else if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return IsSioEncrypted(head), nil
+}
+
+// rewriteFile 读取 src 经 fn 处理后写入 dst，保留 src 的文件权限
+func rewriteFile(src string, dst string, fn func(w io.Writer, r io.Reader) error) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(tmp.Name())
+	if err = fn(tmp, in); err != nil {
+		tmp.Close()
+		return errors.WithMessagef(err, "rewrite %s", src)
+	}
+	if err = tmp.Chmod(fi.Mode().Perm()); err != nil {
+		tmp.Close()
+		return errors.WithStack(err)
+	}
+	if err = tmp.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp.Name(), dst))
+}
+
+func readSioHeader(r io.Reader) ([]byte, error) {
+	prefix := make([]byte, len(sioMagic)+4)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, errors.Wrap(err, "read encrypt header")
+	}
+	if !IsSioEncrypted(prefix) {
+		return nil, errors.New("not a sio encrypted file")
+	}
+	headerLen := binary.BigEndian.Uint32(prefix[len(sioMagic):])
+	if headerLen == 0 || headerLen > sioMaxHeaderSize {
+		return nil, errors.Errorf("invalid encrypt header length %d", headerLen)
+	}
+	headerBytes := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, headerBytes); err != nil {
+		return nil, errors.Wrap(err, "read encrypt header")
+	}
+	return headerBytes, nil
+}
+
+// sioConfig 由 data key 和文件头派生 stream key，使文件头也受认证保护
+func sioConfig(dataKey []byte, header []byte) sio.Config {
+	mac := hmac.New(sha256.New, dataKey)
+	mac.Write(header)
+	return sio.Config{
+		MinVersion: sio.Version20,
+		MaxVersion: sio.Version20,
+		Key:        mac.Sum(nil),
+	}
+}
+
+func publicKeyId(pub *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func wrapKeyWithPublicKey(dataKey []byte, pub *rsa.PublicKey) (sioRecipient, error) {
+	keyId, err := publicKeyId(pub)
+	if err != nil {
+		return sioRecipient{}, err
+	}
+	wrapped, err := EncryptWithPublicKeyOAEP(dataKey, pub)
+	if err != nil {
+		return sioRecipient{}, errors.Wrap(err, "encrypt data key with public key")
+	}
+	return sioRecipient{KekType: KekRSA, KeyId: keyId, WrappedKey: wrapped}, nil
+}
+
+func wrapKeyWithPassphrase(dataKey []byte, passphrase string) (sioRecipient, error) {
+	recipient := sioRecipient{KekType: KekScrypt, Salt: make([]byte, 32), N: scryptN, R: scryptR, P: scryptP}
+	if _, err := io.ReadFull(rand.Reader, recipient.Salt); err != nil {
+		return recipient, errors.Wrap(err, "generate salt")
+	}
+	aead, err := passphraseAEAD(passphrase, recipient)
+	if err != nil {
+		return recipient, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return recipient, errors.Wrap(err, "generate nonce")
+	}
+	recipient.WrappedKey = aead.Seal(nonce, nonce, dataKey, nil)
+	return recipient, nil
+}
+
+func unwrapKeyWithPassphrase(recipient sioRecipient, passphrase string) ([]byte, error) {
+	// scrypt 参数来自文件头，限制上限避免异常文件耗尽内存
+	if recipient.N <= 1 || recipient.N > scryptMaxN || recipient.R*recipient.P > scryptMaxRP {
+		return nil, errors.Errorf("invalid scrypt params n=%d r=%d p=%d", recipient.N, recipient.R, recipient.P)
+	}
+	aead, err := passphraseAEAD(passphrase, recipient)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipient.WrappedKey) < aead.NonceSize() {
+		return nil, errors.New("invalid wrapped key")
+	}
+	nonce, wrapped := recipient.WrappedKey[:aead.NonceSize()], recipient.WrappedKey[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, wrapped, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypt data key with passphrase")
+	}
+	return dataKey, nil
+}
+
+func passphraseAEAD(passphrase string, recipient sioRecipient) (cipher.AEAD, error) {
+	kek, err := scrypt.Key([]byte(passphrase), recipient.Salt, recipient.N, recipient.R, recipient.P, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "derive key from passphrase")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.WithStack(err)
+}
@@ -0,0 +1,29 @@
+package iocrypt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUnwrapKeyTriesAllRecipients(t *testing.T) {
+	dataKey := bytes.Repeat([]byte{1}, sioDataKeySize)
+	other, err := wrapKeyWithPassphrase(dataKey, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matched, err := wrapKeyWithPassphrase(dataKey, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := SioDecrypter{Passphrase: "passphrase"}
+	got, err := d.unwrapKey([]sioRecipient{other, matched})
+	if err != nil {
+		t.Fatalf("unwrap key: %v", err)
+	}
+	if !bytes.Equal(dataKey, got) {
+		t.Fatal("data key mismatch")
+	}
+	if _, err = d.unwrapKey([]sioRecipient{other}); err == nil {
+		t.Fatal("expect error when no recipient matched")
+	}
+}
@@ -0,0 +1,151 @@
+package iocrypt_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dbm-services/common/go-pubpkg/iocrypt"
+)
+
+func sioEncrypt(t *testing.T, tool iocrypt.SioCrypt, plain []byte) []byte {
+	var encrypted bytes.Buffer
+	w, err := iocrypt.FileEncryptWriter(tool, &encrypted)
+	if err != nil {
+		t.Fatalf("new encrypt writer: %v", err)
+	}
+	if _, err = io.Copy(w, bytes.NewReader(plain)); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("close encrypt writer: %v", err)
+	}
+	return encrypted.Bytes()
+}
+
+func TestSioCryptRoundTrip(t *testing.T) {
+	privKey, pubKey, err := iocrypt.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := make([]byte, 300*1024+7)
+	_, _ = rand.Read(plain)
+
+	encrypted := sioEncrypt(t, iocrypt.SioCrypt{PublicKey: pubKey, Passphrase: "passphrase"}, plain)
+	if !iocrypt.IsSioEncrypted(encrypted) {
+		t.Fatal("missing sio header")
+	}
+	decrypters := map[string]iocrypt.SioDecrypter{
+		"private_key": {PrivateKey: privKey},
+		"passphrase":  {Passphrase: "passphrase"},
+	}
+	for name, d := range decrypters {
+		var decrypted bytes.Buffer
+		if _, err = d.Decrypt(&decrypted, bytes.NewReader(encrypted)); err != nil {
+			t.Fatalf("%s decrypt: %v", name, err)
+		}
+		if !bytes.Equal(plain, decrypted.Bytes()) {
+			t.Fatalf("%s decrypted data mismatch", name)
+		}
+	}
+
+	otherKey, _, _ := iocrypt.GenerateKeyPair(2048)
+	for name, d := range map[string]iocrypt.SioDecrypter{
+		"other_private_key": {PrivateKey: otherKey},
+		"wrong_passphrase":  {Passphrase: "wrong"},
+	} {
+		if _, err = d.Decrypt(io.Discard, bytes.NewReader(encrypted)); err == nil {
+			t.Fatalf("%s: expect decrypt error", name)
+		}
+	}
+}
+
+func TestSioCryptTampered(t *testing.T) {
+	plain := []byte("select 1;")
+	encrypted := sioEncrypt(t, iocrypt.SioCrypt{Passphrase: "passphrase"}, plain)
+	d := iocrypt.SioDecrypter{Passphrase: "passphrase"}
+
+	tampered := bytes.Clone(encrypted)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := d.Decrypt(io.Discard, bytes.NewReader(tampered)); err == nil {
+		t.Fatal("expect error for tampered data")
+	}
+
+	truncated := encrypted[:len(encrypted)-1]
+	if _, err := d.Decrypt(io.Discard, bytes.NewReader(truncated)); err == nil {
+		t.Fatal("expect error for truncated data")
+	}
+
+	empty := sioEncrypt(t, iocrypt.SioCrypt{Passphrase: "passphrase"}, nil)
+	var decrypted bytes.Buffer
+	if _, err := d.Decrypt(&decrypted, bytes.NewReader(empty)); err != nil || decrypted.Len() != 0 {
+		t.Fatalf("decrypt empty file: %v", err)
+	}
+}
+
+func TestNewSioDecrypter(t *testing.T) {
+	if _, err := iocrypt.NewSioDecrypter("", ""); err == nil {
+		t.Fatal("expect error without key")
+	}
+	privKey, pubKey, err := iocrypt.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "private.pem")
+	if err = os.WriteFile(keyFile, iocrypt.PrivateKeyToBytes(privKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+	d, err := iocrypt.NewSioDecrypter(keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := []byte("select 1;")
+	encrypted := sioEncrypt(t, iocrypt.SioCrypt{PublicKey: pubKey}, plain)
+	var decrypted bytes.Buffer
+	if _, err = d.Decrypt(&decrypted, bytes.NewReader(encrypted)); err != nil {
+		t.Fatalf("decrypt with private key file: %v", err)
+	}
+	if !bytes.Equal(plain, decrypted.Bytes()) {
+		t.Fatal("decrypted data mismatch")
+	}
+	if _, err = iocrypt.NewSioDecrypter(filepath.Join(t.TempDir(), "missing.pem"), ""); err == nil {
+		t.Fatal("expect error for missing private key file")
+	}
+}
+
+func TestSioCryptFileInPlace(t *testing.T) {
+	plain := []byte("binlog content")
+	fileName := filepath.Join(t.TempDir(), "binlog20000.000001")
+	if err := os.WriteFile(fileName, plain, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := (iocrypt.SioCrypt{Passphrase: "passphrase"}).EncryptFile(fileName, fileName); err != nil {
+		t.Fatalf("encrypt file: %v", err)
+	}
+	if encrypted, err := iocrypt.IsSioEncryptedFile(fileName); err != nil || !encrypted {
+		t.Fatalf("expect encrypted file: %v", err)
+	}
+	d := iocrypt.SioDecrypter{Passphrase: "passphrase"}
+	if err := d.DecryptFile(fileName, fileName); err != nil {
+		t.Fatalf("decrypt file: %v", err)
+	}
+	decrypted, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain, decrypted) {
+		t.Fatal("decrypted data mismatch")
+	}
+	if fi, _ := os.Stat(fileName); fi.Mode().Perm() != 0640 {
+		t.Fatalf("file mode changed to %s", fi.Mode())
+	}
+	if encrypted, err := iocrypt.IsSioEncryptedFile(fileName); err != nil || encrypted {
+		t.Fatalf("expect plain file: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(fileName)); len(entries) != 1 {
+		t.Fatalf("temp file left: %v", entries)
+	}
+}
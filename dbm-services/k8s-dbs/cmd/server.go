@@ -32,6 +32,7 @@ import (
 	_ "k8s-dbs/router/dataweb"
 	_ "k8s-dbs/router/metadata"
 	_ "k8s-dbs/router/terminal"
+	routerutil "k8s-dbs/router/util"
 	"log"
 	"log/slog"
 	"net/http"
@@ -46,8 +47,9 @@ import (
 // main 函数是程序的入口点，执行以下步骤：
 // 1. 初始化系统核心配置
 // 2. 创建并配置 Gin 路由引擎
-// 3. 启动 HTTP 服务并监听终止信号
-// 4. 在接收到终止信号时优雅关闭服务器
+// 3. 启动元数据差异巡检
+// 4. 启动 HTTP 服务并监听终止信号
+// 5. 在接收到终止信号时停止巡检并优雅关闭服务器
 func main() {
 	slog.Info("Start initial configuration...")
 
@@ -57,13 +59,17 @@ func main() {
 
 	r := router.NewRouter(util.Db.GormDb)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	routerutil.BuildDriftReconciler(util.Db.GormDb).Start(ctx)
+
 	slog.Info("Finish initial configuration...")
 
-	startServer(r.Engine)
+	startServer(r.Engine, cancel)
 }
 
-// startServer 启动 HTTP 服务并处理优雅关闭
-func startServer(r *gin.Engine) {
+// startServer 启动 HTTP 服务并处理优雅关闭，关闭前先调用 stopBackground 停止后台任务
+func startServer(r *gin.Engine, stopBackground context.CancelFunc) {
 	server := &http.Server{
 		Addr:    ":8000",
 		Handler: r,
@@ -81,6 +87,7 @@ func startServer(r *gin.Engine) {
 	<-quit
 
 	slog.Info("Shutdown Server ...")
+	stopBackground()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
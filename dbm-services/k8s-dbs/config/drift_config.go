@@ -0,0 +1,30 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "time"
+
+// DriftReconcilerConfig 元数据差异巡检配置
+type DriftReconcilerConfig struct {
+	// Interval 巡检间隔，小于等于 0 时不启动巡检
+	Interval time.Duration `env:"DRIFT_RECONCILE_INTERVAL"`
+	// AutoRepair 是否根据 k8s 集群的实际状态自动修复元数据
+	AutoRepair bool `env:"DRIFT_AUTO_REPAIR"`
+}
@@ -0,0 +1,29 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+// TerminalConfig 容器终端审计配置
+type TerminalConfig struct {
+	// DenyCommands 禁止执行的命令，逗号分隔，为空时使用默认禁止列表
+	// 按命令字面匹配，只用于审计和防止误操作，不能作为权限控制手段
+	DenyCommands []string `env:"TERMINAL_DENY_COMMANDS"`
+	// RecordingMaxSize 单个会话录像的最大字节数，超出部分不再录制
+	RecordingMaxSize int `env:"TERMINAL_RECORDING_MAX_SIZE"`
+}
@@ -247,6 +247,102 @@ func (c *ClusterController) VolumeExpansion(ctx *gin.Context) {
 	api.SuccessResponse(ctx, responseData, commconst.Success)
 }
 
+// BackupCluster 备份集群
+func (c *ClusterController) BackupCluster(ctx *gin.Context) {
+	request := &coreentity.Request{}
+	if err := ctx.ShouldBindJSON(request); err != nil {
+		api.ErrorResponse(ctx, dbserrors.NewK8sDbsError(dbserrors.ParameterInvalidError, err))
+		return
+	}
+	dbsCtx := &commentity.DbsContext{
+		BkAuth:      &request.BKAuth,
+		RequestType: coreconst.BackupCluster,
+	}
+	responseData, err := c.opsRequestProvider.BackupCluster(dbsCtx, request)
+	if err != nil {
+		api.ErrorResponse(ctx, dbserrors.NewK8sDbsError(dbserrors.BackupClusterError, err))
+		return
+	}
+	api.SuccessResponse(ctx, responseData, commconst.Success)
+}
+
+// RestoreCluster 从备份恢复集群
+func (c *ClusterController) RestoreCluster(ctx *gin.Context) {
+	request := &coreentity.Request{}
+	if err := ctx.ShouldBindJSON(request); err != nil {
+		api.HandleValidationError(ctx, err, request)
+		return
+	}
+	dbsCtx := &commentity.DbsContext{
+		BkAuth:      &request.BKAuth,
+		RequestType: coreconst.RestoreCluster,
+	}
+	responseData, err := c.opsRequestProvider.RestoreCluster(dbsCtx, request)
+	if err != nil {
+		api.ErrorResponse(ctx, dbserrors.NewK8sDbsError(dbserrors.RestoreClusterError, err))
+		return
+	}
+	api.SuccessResponse(ctx, responseData, commconst.Success)
+}
+
+// ListBackups 查询集群备份列表
+func (c *ClusterController) ListBackups(ctx *gin.Context) {
+	request := &coreentity.Request{}
+	if err := ctx.ShouldBindJSON(request); err != nil {
+		api.ErrorResponse(ctx, dbserrors.NewK8sDbsError(dbserrors.ParameterInvalidError, err))
+		return
+	}
+	backups, err := c.opsRequestProvider.ListBackups(request)
+	if err != nil {
+		api.ErrorResponse(ctx, dbserrors.NewK8sDbsError(dbserrors.ListBackupError, err))
+		return
+	}
+	var data []coreresp.BackupResponse
+	if err := copier.Copy(&data, backups); err != nil {
+		api.ErrorResponse(ctx, dbserrors.NewK8sDbsError(dbserrors.ListBackupError, err))
+		return
+	}
+	api.SuccessResponse(ctx, data, commconst.Success)
+}
+
+// Switchover 组件主从切换
+func (c *ClusterController) Switchover(ctx *gin.Context) {
+	request := &coreentity.Request{}
+	if err := ctx.ShouldBindJSON(request); err != nil {
+		api.ErrorResponse(ctx, dbserrors.NewK8sDbsError(dbserrors.ParameterInvalidError, err))
+		return
+	}
+	dbsCtx := &commentity.DbsContext{
+		BkAuth:      &request.BKAuth,
+		RequestType: coreconst.SwitchoverComp,
+	}
+	responseData, err := c.opsRequestProvider.Switchover(dbsCtx, request)
+	if err != nil {
+		api.ErrorResponse(ctx, dbserrors.NewK8sDbsError(dbserrors.SwitchoverError, err))
+		return
+	}
+	api.SuccessResponse(ctx, responseData, commconst.Success)
+}
+
+// Reconfigure 组件参数变更
+func (c *ClusterController) Reconfigure(ctx *gin.Context) {
+	request := &coreentity.Request{}
+	if err := ctx.ShouldBindJSON(request); err != nil {
+		api.ErrorResponse(ctx, dbserrors.NewK8sDbsError(dbserrors.ParameterInvalidError, err))
+		return
+	}
+	dbsCtx := &commentity.DbsContext{
+		BkAuth:      &request.BKAuth,
+		RequestType: coreconst.ReconfigureComp,
+	}
+	responseData, err := c.opsRequestProvider.Reconfigure(dbsCtx, request)
+	if err != nil {
+		api.ErrorResponse(ctx, dbserrors.NewK8sDbsError(dbserrors.ReconfigureError, err))
+		return
+	}
+	api.SuccessResponse(ctx, responseData, commconst.Success)
+}
+
 // DescribeOpsRequest 查看 opsRequest 详情
 func (c *ClusterController) DescribeOpsRequest(ctx *gin.Context) {
 	request := &coreentity.Request{}
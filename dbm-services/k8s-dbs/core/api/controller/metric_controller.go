@@ -0,0 +1,68 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s-dbs/common/api"
+	commconst "k8s-dbs/common/constant"
+	commutil "k8s-dbs/common/util"
+	"k8s-dbs/core/entity"
+	"k8s-dbs/core/provider"
+	"k8s-dbs/errors"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricController 集群监控指标 controller
+type MetricController struct {
+	metricProvider *provider.MetricProvider
+}
+
+// NewMetricController 创建 MetricController 实例
+func NewMetricController(metricProvider *provider.MetricProvider) *MetricController {
+	return &MetricController{metricProvider}
+}
+
+// ListMetricDefinitions 获取 addon 支持的监控指标
+func (m *MetricController) ListMetricDefinitions(ctx *gin.Context) {
+	definitions := m.metricProvider.ListMetricDefinitions(ctx.Query("addonType"))
+	api.SuccessResponse(ctx, definitions, commconst.Success)
+}
+
+// QueryClusterMetrics 按时间范围查询集群或组件的监控指标趋势
+func (m *MetricController) QueryClusterMetrics(ctx *gin.Context) {
+	var params entity.ClusterMetricQueryParams
+	targetMap := map[string]reflect.Type{
+		"start": reflect.TypeOf(int64(0)),
+		"end":   reflect.TypeOf(int64(0)),
+		"step":  reflect.TypeOf(int64(0)),
+	}
+	if err := commutil.DecodeParams(ctx, commutil.BuildParams, &params, targetMap); err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	results, err := m.metricProvider.QueryClusterMetrics(&params)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetClusterMetricError, err))
+		return
+	}
+	api.SuccessResponse(ctx, results, commconst.Success)
+}
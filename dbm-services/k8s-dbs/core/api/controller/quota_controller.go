@@ -0,0 +1,60 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s-dbs/common/api"
+	commconst "k8s-dbs/common/constant"
+	commutil "k8s-dbs/common/util"
+	"k8s-dbs/core/entity"
+	"k8s-dbs/core/provider"
+	"k8s-dbs/errors"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaController 资源配额和资源使用统计 controller
+type QuotaController struct {
+	quotaProvider *provider.QuotaProvider
+}
+
+// NewQuotaController 创建 QuotaController 实例
+func NewQuotaController(quotaProvider *provider.QuotaProvider) *QuotaController {
+	return &QuotaController{quotaProvider}
+}
+
+// GetUsageReport 查询业务或命名空间的资源分配量、存储使用量和配额
+func (q *QuotaController) GetUsageReport(ctx *gin.Context) {
+	var params entity.ResourceUsageQueryParams
+	targetMap := map[string]reflect.Type{
+		"bkBizId": reflect.TypeOf(uint64(0)),
+	}
+	if err := commutil.DecodeParams(ctx, commutil.BuildParams, &params, targetMap); err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	report, err := q.quotaProvider.GetUsageReport(&params)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetResourceUsageError, err))
+		return
+	}
+	api.SuccessResponse(ctx, report, commconst.Success)
+}
@@ -85,6 +85,10 @@ const (
 	VExpansion           OperationType = "VolumeExpansion"
 	UpgradeComp          OperationType = "UpgradeComp"
 	ExposeService        OperationType = "ExposeService"
+	BackupCluster        OperationType = "BackupCluster"
+	RestoreCluster       OperationType = "RestoreCluster"
+	SwitchoverComp       OperationType = "SwitchoverComponent"
+	ReconfigureComp      OperationType = "ReconfigureComponent"
 	CreateK8sNs          OperationType = "CreateK8sNamespace"
 	DeleteK8sPod         OperationType = "DeleteK8sPod"
 )
@@ -0,0 +1,49 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package constant
+
+import "time"
+
+// 元数据差异巡检的默认配置
+const (
+	DefaultDriftReconcileInterval = 10 * time.Minute
+	DriftReconcilerOperator       = "drift-reconciler"
+	// DriftReconcilerLockName 多副本部署时巡检使用的 MySQL 命名锁
+	DriftReconcilerLockName = "k8s_dbs_drift_reconciler"
+)
+
+// 元数据差异的资源类型
+const (
+	DriftResourceCluster   = "cluster"
+	DriftResourceComponent = "component"
+	DriftResourceRelease   = "release"
+)
+
+// 元数据差异类型
+const (
+	ClusterMissingInK8s    = "ClusterMissingInK8s"
+	ClusterMissingInMeta   = "ClusterMissingInMeta"
+	TerminationPolicyDrift = "TerminationPolicyDrift"
+	ComponentMissingInK8s  = "ComponentMissingInK8s"
+	ComponentMissingInMeta = "ComponentMissingInMeta"
+	ReleaseMissingInK8s    = "ReleaseMissingInK8s"
+	ReleaseMissingInMeta   = "ReleaseMissingInMeta"
+	ReleaseVersionDrift    = "ReleaseVersionDrift"
+)
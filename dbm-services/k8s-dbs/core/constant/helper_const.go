@@ -25,6 +25,8 @@ var ResourceInGlobal = map[string]struct{}{
 	ClusterDefinition:   {},
 	ComponentDefinition: {},
 	ComponentVersion:    {},
+	ParamConfigRenderer: {},
+	ParametersDef:       {},
 }
 
 const (
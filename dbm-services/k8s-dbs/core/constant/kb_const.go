@@ -32,6 +32,8 @@ const (
 	Cluster             = "Cluster"
 	OpsRequest          = "OpsRequest"
 	BackupPolicy        = "BackupPolicy"
+	ParamConfigRenderer = "ParamConfigRenderer"
+	ParametersDef       = "ParametersDefinition"
 )
 
 const APIVersion = "apps.kubeblocks.io/v1alpha1"
@@ -50,6 +52,23 @@ const (
 	PodName       = "apps.kubeblocks.io/pod-name"
 	ManagedBy     = "app.kubernetes.io/managed-by"
 	ServiceType   = "dbs_k8s_service_type"
+	BackupType    = "dataprotection.kubeblocks.io/backup-type"
+)
+
+// ContinuousBackup 持续备份类型，用于按时间点恢复
+const ContinuousBackup = "Continuous"
+
+// PrimaryRoles 主节点角色，不同存储插件的命名不同
+var PrimaryRoles = map[string]struct{}{
+	"primary": {},
+	"leader":  {},
+	"master":  {},
+}
+
+// 参数变更 opsRequest 注解，标识变更的参数是否需要重启实例
+const (
+	RestartRequired = "dbs_k8s_restart_required"
+	RestartParams   = "dbs_k8s_restart_params"
 )
 
 // Kubeblocks kb 常量
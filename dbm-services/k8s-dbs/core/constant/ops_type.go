@@ -36,6 +36,10 @@ const (
 	VExpansion           = "VolumeExpansion"
 	UpgradeComp          = "UpgradeComp"
 	ExposeService        = "ExposeService"
+	BackupCluster        = "BackupCluster"
+	RestoreCluster       = "RestoreCluster"
+	SwitchoverComp       = "SwitchoverComponent"
+	ReconfigureComp      = "ReconfigureComponent"
 	CreateK8sNs          = "CreateK8sNamespace"
 	DeleteK8sPod         = "DeleteK8sPod"
 )
@@ -0,0 +1,90 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package entity
+
+import (
+	coreconst "k8s-dbs/core/constant"
+
+	dpv1 "github.com/apecloud/kubeblocks/apis/dataprotection/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// BackupData 集群备份信息
+type BackupData struct {
+	BackupName       string            `json:"backupName,omitempty"`
+	Namespace        string            `json:"namespace,omitempty"`
+	ClusterName      string            `json:"clusterName,omitempty"`
+	BackupPolicyName string            `json:"backupPolicyName,omitempty"`
+	BackupMethod     string            `json:"backupMethod,omitempty"`
+	BackupType       string            `json:"backupType,omitempty"`
+	Phase            dpv1.BackupPhase  `json:"phase,omitempty"`
+	StartTime        *metav1.Time      `json:"startTime,omitempty"`
+	CompleteTime     *metav1.Time      `json:"completeTime,omitempty"`
+	Expiration       *metav1.Time      `json:"expiration,omitempty"`
+	TotalSize        string            `json:"totalSize,omitempty"`
+	BackupRepoName   string            `json:"backupRepoName,omitempty"`
+	Path             string            `json:"path,omitempty"`
+	TimeRange        *BackupTimeRange  `json:"timeRange,omitempty"`
+	FailureReason    string            `json:"failureReason,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	CreateTime       metav1.Time       `json:"createTime,omitempty"`
+}
+
+// BackupTimeRange 备份数据的时间范围，持续备份的可恢复时间范围
+type BackupTimeRange struct {
+	Start *metav1.Time `json:"start,omitempty"`
+	End   *metav1.Time `json:"end,omitempty"`
+}
+
+// GetBackupData 解析 dataprotection Backup 资源
+func GetBackupData(backup *unstructured.Unstructured) (*BackupData, error) {
+	var data *dpv1.Backup
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(backup.Object, &data)
+	if err != nil {
+		return nil, err
+	}
+	backupData := &BackupData{
+		BackupName:       data.Name,
+		Namespace:        data.Namespace,
+		ClusterName:      data.Labels[coreconst.InstanceName],
+		BackupPolicyName: data.Spec.BackupPolicyName,
+		BackupMethod:     data.Spec.BackupMethod,
+		BackupType:       data.Labels[coreconst.BackupType],
+		Phase:            data.Status.Phase,
+		StartTime:        data.Status.StartTimestamp,
+		CompleteTime:     data.Status.CompletionTimestamp,
+		Expiration:       data.Status.Expiration,
+		TotalSize:        data.Status.TotalSize,
+		BackupRepoName:   data.Status.BackupRepoName,
+		Path:             data.Status.Path,
+		FailureReason:    data.Status.FailureReason,
+		Labels:           data.Labels,
+		CreateTime:       data.CreationTimestamp,
+	}
+	if data.Status.TimeRange != nil {
+		backupData.TimeRange = &BackupTimeRange{
+			Start: data.GetStartTime(),
+			End:   data.GetEndTime(),
+		}
+	}
+	return backupData, nil
+}
@@ -62,6 +62,7 @@ type Spec struct {
 	opv1.SpecificOpsRequest `json:",inline"`
 	OpsService              `json:",inline"`
 	ObserveConfig           *ObserveConfig `json:"observeConfig,omitempty"`
+	RestoreClusterName      string         `json:"restoreClusterName,omitempty" binding:"omitempty,k8sReleaseName" msg:"恢复集群名称格式不合法"` //nolint:lll
 }
 
 // ClusterStatus cluster status
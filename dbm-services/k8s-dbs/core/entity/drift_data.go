@@ -0,0 +1,41 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package entity
+
+import (
+	metaentity "k8s-dbs/metadata/entity"
+
+	kbv1 "github.com/apecloud/kubeblocks/apis/apps/v1alpha1"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// DriftMetaState 一个 k8s 集群上 dbs 记录的元数据
+type DriftMetaState struct {
+	Clusters []*metaentity.K8sCrdClusterEntity
+	// Components key 为 crdClusterID
+	Components map[uint64][]*metaentity.K8sCrdComponentEntity
+	Releases   []*metaentity.AddonClusterReleaseEntity
+}
+
+// DriftActualState 一个 k8s 集群上实际的 kubeblocks 集群和 helm release
+type DriftActualState struct {
+	Clusters []*kbv1.Cluster
+	Releases []*release.Release
+}
@@ -39,6 +39,7 @@ type OpsRequestStatus struct {
 	StartTime    metav1.Time        `json:"startTime,omitempty"`
 	CompleteTime metav1.Time        `json:"completeTime,omitempty"`
 	Messages     []metav1.Condition `json:"messages,omitempty"`
+	Backup       *BackupData        `json:"backup,omitempty"`
 }
 
 // GetOpsRequestData returns the data parameter of the operation
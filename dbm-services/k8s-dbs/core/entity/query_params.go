@@ -44,3 +44,16 @@ type K8sPodLogQueryParams struct {
 	Container      string `json:"container,omitempty"`
 	Previous       bool   `json:"previous,omitempty"`
 }
+
+// ClusterMetricQueryParams 封装集群监控指标请求参数
+// metrics 为逗号分隔的指标名称，start、end 为 unix 秒，step 单位为秒
+type ClusterMetricQueryParams struct {
+	K8sClusterName string `json:"k8sClusterName" binding:"required"`
+	ClusterName    string `json:"clusterName" binding:"required"`
+	Namespace      string `json:"namespace" binding:"required"`
+	ComponentName  string `json:"componentName,omitempty"`
+	Metrics        string `json:"metrics,omitempty"`
+	Start          int64  `json:"start,omitempty"`
+	End            int64  `json:"end,omitempty"`
+	Step           int64  `json:"step,omitempty"`
+}
@@ -0,0 +1,144 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package entity
+
+import (
+	"fmt"
+	coreconst "k8s-dbs/core/constant"
+	"strings"
+)
+
+// ResourceAmount 资源量，CPU 单位为 core，Memory 和 Storage 单位为 GB
+type ResourceAmount struct {
+	CPU     float64 `json:"cpu"`
+	Memory  float64 `json:"memory"`
+	Storage float64 `json:"storage"`
+}
+
+// Add 累加资源量
+func (r *ResourceAmount) Add(other ResourceAmount) {
+	r.CPU += other.CPU
+	r.Memory += other.Memory
+	r.Storage += other.Storage
+}
+
+// Scale 按实例数放大资源量
+func (r ResourceAmount) Scale(instances int32) ResourceAmount {
+	return ResourceAmount{
+		CPU:     r.CPU * float64(instances),
+		Memory:  r.Memory * float64(instances),
+		Storage: r.Storage * float64(instances),
+	}
+}
+
+// Sub 计算资源量的差值
+func (r ResourceAmount) Sub(other ResourceAmount) ResourceAmount {
+	return ResourceAmount{
+		CPU:     r.CPU - other.CPU,
+		Memory:  r.Memory - other.Memory,
+		Storage: r.Storage - other.Storage,
+	}
+}
+
+// HasIncrease 是否有任意一项资源增加，只减少资源的变更不需要检查配额
+func (r ResourceAmount) HasIncrease() bool {
+	return r.CPU > 0 || r.Memory > 0 || r.Storage > 0
+}
+
+// QuotaCheckItem 单项资源的配额检查明细
+type QuotaCheckItem struct {
+	Resource  string  `json:"resource"`
+	Used      float64 `json:"used"`
+	Requested float64 `json:"requested"`
+	Quota     float64 `json:"quota"`
+	Exceeded  bool    `json:"exceeded"`
+}
+
+// QuotaCheckResult 业务或命名空间的配额检查结果
+type QuotaCheckResult struct {
+	QuotaType      string            `json:"quotaType"`
+	BkBizID        uint64            `json:"bkBizId,omitempty"`
+	K8sClusterName string            `json:"k8sClusterName,omitempty"`
+	Namespace      string            `json:"namespace,omitempty"`
+	Items          []*QuotaCheckItem `json:"items"`
+}
+
+// Exceeded 是否有资源超出配额
+func (q *QuotaCheckResult) Exceeded() bool {
+	for _, item := range q.Items {
+		if item.Exceeded {
+			return true
+		}
+	}
+	return false
+}
+
+// String 输出超出配额的资源明细
+func (q *QuotaCheckResult) String() string {
+	scope := fmt.Sprintf("命名空间 %s/%s", q.K8sClusterName, q.Namespace)
+	if q.QuotaType == coreconst.BusinessQuota {
+		scope = fmt.Sprintf("业务 %d", q.BkBizID)
+	}
+	var details []string
+	for _, item := range q.Items {
+		if !item.Exceeded {
+			continue
+		}
+		unit := "GB"
+		if item.Resource == coreconst.QuotaResourceCPU {
+			unit = "core"
+		}
+		details = append(details, fmt.Sprintf("%s 已分配 %.3f%s + 本次申请 %.3f%s > 配额 %.3f%s",
+			item.Resource, item.Used, unit, item.Requested, unit, item.Quota, unit))
+	}
+	return fmt.Sprintf("%s 资源配额不足: %s", scope, strings.Join(details, "; "))
+}
+
+// ResourceUsageQueryParams 资源使用报告查询参数，按业务或 k8s 集群命名空间统计
+type ResourceUsageQueryParams struct {
+	BkBizID        uint64 `json:"bkBizId"`
+	K8sClusterName string `json:"k8sClusterName"`
+	Namespace      string `json:"namespace"`
+}
+
+// ClusterResourceUsage 集群的资源分配和存储实际使用量
+type ClusterResourceUsage struct {
+	K8sClusterName string         `json:"k8sClusterName"`
+	ClusterName    string         `json:"clusterName"`
+	Namespace      string         `json:"namespace"`
+	AddonType      string         `json:"addonType"`
+	BkBizID        uint64         `json:"bkBizId"`
+	Requested      ResourceAmount `json:"requested"`
+	StorageUsed    float64        `json:"storageUsed"`
+	// StorageUsedComplete 是否所有实例都获取到了存储使用量
+	StorageUsedComplete bool `json:"storageUsedComplete"`
+}
+
+// ResourceUsageReport 业务或命名空间的资源使用报告
+type ResourceUsageReport struct {
+	QuotaType      string                  `json:"quotaType"`
+	BkBizID        uint64                  `json:"bkBizId,omitempty"`
+	K8sClusterName string                  `json:"k8sClusterName,omitempty"`
+	Namespace      string                  `json:"namespace,omitempty"`
+	Quota          *ResourceAmount         `json:"quota"`
+	Requested      ResourceAmount          `json:"requested"`
+	StorageUsed    float64                 `json:"storageUsed"`
+	Clusters       []*ClusterResourceUsage `json:"clusters"`
+}
@@ -34,6 +34,7 @@ import (
 	metautil "k8s-dbs/metadata/util"
 	"log/slog"
 	"sort"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -63,6 +64,7 @@ type ClusterProvider struct {
 	releaseMetaProvider     metaprovider.AddonClusterReleaseProvider
 	clusterHelmRepoProvider metaprovider.AddonClusterHelmRepoProvider
 	ClusterTagProvider      metaprovider.K8sCrdClusterTagProvider
+	quotaProvider           *QuotaProvider
 }
 
 // ClusterProviderOptions ClusterProvider 的函数选项
@@ -143,6 +145,15 @@ func (c *ClusterProviderBuilder) WithClusterTagsMeta(
 	}
 }
 
+// WithQuota 设置 QuotaProvider
+func (c *ClusterProviderBuilder) WithQuota(
+	p *QuotaProvider,
+) ClusterProviderOptions {
+	return func(c *ClusterProvider) {
+		c.quotaProvider = p
+	}
+}
+
 // validateProvider 验证 ClusterProvider 必要字段
 func (c *ClusterProvider) validateProvider() error {
 	if c.clusterMetaProvider == nil {
@@ -169,6 +180,9 @@ func (c *ClusterProvider) validateProvider() error {
 	if c.ClusterTagProvider == nil {
 		return errors.New("ClusterTagProvider is required")
 	}
+	if c.quotaProvider == nil {
+		return errors.New("quotaProvider is required")
+	}
 	return nil
 }
 
@@ -215,6 +229,16 @@ func (c *ClusterProvider) CreateCluster(ctx *commentity.DbsContext, request *cor
 			fmt.Errorf("集群 %s 已存在，请勿重复创建", request.ClusterName))
 	}
 
+	// check resource quota
+	if err = c.quotaProvider.CheckQuota(
+		k8sClusterConfig,
+		request.BkBizID,
+		request.Namespace,
+		coreutil.CreateClusterResourceDelta(request),
+	); err != nil {
+		return err
+	}
+
 	// save audit log
 	addedRequestEntity, err := metautil.SaveAuditLog(c.reqRecordProvider, request, ctx.RequestType)
 	if err != nil {
@@ -643,6 +667,66 @@ func (c *ClusterProvider) saveComponentMeta(
 	return compEntityList, nil
 }
 
+// saveRestoredClusterMeta 按源集群元数据登记恢复出的新集群及其组件
+func (c *ClusterProvider) saveRestoredClusterMeta(
+	request *coreentity.Request,
+	requestID string,
+	k8sClusterConfigID uint64,
+) (*metaentity.K8sCrdClusterEntity, error) {
+	srcCluster, err := c.clusterMetaProvider.FindByParams(&metaentity.ClusterQueryParams{
+		K8sClusterConfigID: k8sClusterConfigID,
+		ClusterName:        request.ClusterName,
+		Namespace:          request.Namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if srcCluster == nil {
+		return nil, fmt.Errorf("源集群 %s 元数据不存在", request.ClusterName)
+	}
+	srcComponents, err := c.componentMetaProvider.FindComponentsByClusterID(srcCluster.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterEntity := &metaentity.K8sCrdClusterEntity{
+		AddonID:             srcCluster.AddonID,
+		AddonClusterVersion: srcCluster.AddonClusterVersion,
+		ServiceVersion:      srcCluster.ServiceVersion,
+		TopoName:            srcCluster.TopoName,
+		TerminationPolicy:   srcCluster.TerminationPolicy,
+		ClusterName:         request.RestoreClusterName,
+		ClusterAlias:        srcCluster.ClusterAlias,
+		Namespace:           request.Namespace,
+		RequestID:           requestID,
+		K8sClusterConfigID:  k8sClusterConfigID,
+		BkBizID:             srcCluster.BkBizID,
+		BkBizName:           srcCluster.BkBizName,
+		BkAppAbbr:           srcCluster.BkAppAbbr,
+		BkAppCode:           srcCluster.BkAppCode,
+		Description:         srcCluster.Description,
+		CreatedBy:           request.BKAuth.BkUserName,
+		UpdatedBy:           request.BKAuth.BkUserName,
+	}
+	addedClusterEntity, err := c.clusterMetaProvider.CreateCluster(clusterEntity)
+	if err != nil {
+		return nil, err
+	}
+	for _, comp := range srcComponents {
+		compName := request.RestoreClusterName + strings.TrimPrefix(comp.ComponentName, request.ClusterName)
+		componentEntity := &metaentity.K8sCrdComponentEntity{
+			ComponentName: compName,
+			CrdClusterID:  addedClusterEntity.ID,
+			CreatedBy:     request.BKAuth.BkUserName,
+			UpdatedBy:     request.BKAuth.BkUserName,
+		}
+		if _, err = c.componentMetaProvider.CreateComponent(componentEntity); err != nil {
+			return nil, fmt.Errorf("failed to create component entity %s : %w", compName, err)
+		}
+	}
+	return addedClusterEntity, nil
+}
+
 // getClusterDataResp Get cluster details
 func (c *ClusterProvider) getClusterDataResp(request *coreentity.Request) (*coreentity.ClusterResponseData, error) {
 	k8sClusterConfig, err := c.clusterConfigProvider.FindConfigByName(request.K8sClusterName)
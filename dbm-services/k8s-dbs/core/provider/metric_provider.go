@@ -0,0 +1,139 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	coreentity "k8s-dbs/core/entity"
+	dbserrors "k8s-dbs/errors"
+	metaentity "k8s-dbs/metadata/entity"
+	metaprovider "k8s-dbs/metadata/provider"
+	dbsmetric "k8s-dbs/metric"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MetricProvider 集群监控指标查询服务
+type MetricProvider struct {
+	clusterConfigProvider metaprovider.K8sClusterConfigProvider
+	clusterMetaProvider   metaprovider.K8sCrdClusterProvider
+	componentMetaProvider metaprovider.K8sCrdComponentProvider
+}
+
+// NewMetricProvider 创建 MetricProvider 实例
+func NewMetricProvider(
+	clusterConfigProvider metaprovider.K8sClusterConfigProvider,
+	clusterMetaProvider metaprovider.K8sCrdClusterProvider,
+	componentMetaProvider metaprovider.K8sCrdComponentProvider,
+) *MetricProvider {
+	return &MetricProvider{
+		clusterConfigProvider,
+		clusterMetaProvider,
+		componentMetaProvider,
+	}
+}
+
+// ListMetricDefinitions 获取 addon 支持的监控指标定义
+func (m *MetricProvider) ListMetricDefinitions(addonType string) []*dbsmetric.MetricDefinition {
+	return dbsmetric.GetMetricDefinitions(addonType)
+}
+
+// QueryClusterMetrics 按时间范围查询集群或组件的监控指标趋势，未指定指标时返回当前范围支持的全部指标
+func (m *MetricProvider) QueryClusterMetrics(
+	params *coreentity.ClusterMetricQueryParams,
+) ([]*dbsmetric.MetricResult, error) {
+	if params.K8sClusterName == "" || params.ClusterName == "" || params.Namespace == "" {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.ParameterInvalidError,
+			fmt.Errorf("k8sClusterName、clusterName 和 namespace 不能为空"))
+	}
+	k8sClusterConfig, err := m.clusterConfigProvider.FindConfigByName(params.K8sClusterName)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	clusterEntity, err := m.clusterMetaProvider.FindByParams(&metaentity.ClusterQueryParams{
+		K8sClusterConfigID: k8sClusterConfig.ID,
+		ClusterName:        params.ClusterName,
+		Namespace:          params.Namespace,
+	})
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	if clusterEntity == nil || clusterEntity.AddonInfo == nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError,
+			fmt.Errorf("集群 %s 不存在，请确认集群是否已部署", params.ClusterName))
+	}
+
+	components, err := m.componentMetaProvider.FindComponentsByClusterID(clusterEntity.ID)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	componentNames := make([]string, 0, len(components))
+	for _, component := range components {
+		componentNames = append(componentNames, component.ComponentName)
+	}
+	if params.ComponentName != "" && !slices.Contains(componentNames, params.ComponentName) {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.ParameterInvalidError,
+			fmt.Errorf("集群 %s 不存在组件 %s", params.ClusterName, params.ComponentName))
+	}
+
+	addonType := clusterEntity.AddonInfo.AddonType
+	queryParams := &dbsmetric.MetricRangeQueryParams{
+		AddonType:      addonType,
+		K8sClusterName: params.K8sClusterName,
+		Namespace:      params.Namespace,
+		ClusterName:    params.ClusterName,
+		ComponentName:  params.ComponentName,
+		ComponentNames: componentNames,
+		MetricNames:    parseMetricNames(params.Metrics, addonType, params.ComponentName),
+		Step:           time.Duration(params.Step) * time.Second,
+	}
+	if params.Start > 0 {
+		queryParams.Start = time.Unix(params.Start, 0)
+	}
+	if params.End > 0 {
+		queryParams.End = time.Unix(params.End, 0)
+	}
+	results, err := dbsmetric.FetcherFactory.QueryRange(queryParams)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetClusterMetricError, err)
+	}
+	return results, nil
+}
+
+// parseMetricNames 解析逗号分隔的指标名称，为空时返回集群或组件范围内的全部指标
+func parseMetricNames(metrics string, addonType string, componentName string) []string {
+	var metricNames []string
+	for _, name := range strings.Split(metrics, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			metricNames = append(metricNames, name)
+		}
+	}
+	if len(metricNames) > 0 {
+		return metricNames
+	}
+	for _, definition := range dbsmetric.GetMetricDefinitions(addonType) {
+		if definition.Scope == dbsmetric.ComponentScope && componentName == "" {
+			continue
+		}
+		metricNames = append(metricNames, definition.Name)
+	}
+	return metricNames
+}
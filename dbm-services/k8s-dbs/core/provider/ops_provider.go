@@ -23,6 +23,7 @@ import (
 	"fmt"
 	commentity "k8s-dbs/common/entity"
 	commutil "k8s-dbs/common/util"
+	coreconst "k8s-dbs/core/constant"
 	coreentity "k8s-dbs/core/entity"
 	coreutil "k8s-dbs/core/util"
 	dbserrors "k8s-dbs/errors"
@@ -49,6 +50,7 @@ type OpsRequestProvider struct {
 	clusterConfigProvider metaprovider.K8sClusterConfigProvider
 	reqRecordProvider     metaprovider.ClusterRequestRecordProvider
 	releaseMetaProvider   metaprovider.AddonClusterReleaseProvider
+	quotaProvider         *QuotaProvider
 }
 
 // OpsRequestProviderOption OpsRequestProvider 的函数选项
@@ -124,6 +126,15 @@ func (o *OpsRequestProviderBuilder) WithClusterProvider(
 	}
 }
 
+// WithQuota 设置 QuotaProvider
+func (o *OpsRequestProviderBuilder) WithQuota(
+	provider *QuotaProvider,
+) OpsRequestProviderOption {
+	return func(p *OpsRequestProvider) {
+		p.quotaProvider = provider
+	}
+}
+
 // ClusterOperationFn 集群运维操作函数定义
 type ClusterOperationFn func(*commentity.DbsContext, *coreentity.Request) (*coreentity.Metadata, error)
 
@@ -194,6 +205,13 @@ func (o *OpsRequestProvider) VerticalScaling(
 	ctx *commentity.DbsContext,
 	request *coreentity.Request,
 ) (*coreentity.Metadata, error) {
+	clusterEntity, err := o.checkClusterExists(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if err = o.checkResourceQuota(ctx, request, clusterEntity, coreutil.VerticalScalingResourceDelta); err != nil {
+		return nil, err
+	}
 	return o.withMetaDataSync(ctx, request, o.doVerticalScaling, metautil.UpdateValWithCompList)
 }
 
@@ -259,6 +277,9 @@ func (o *OpsRequestProvider) HorizontalScaling(
 			return nil, err
 		}
 	}
+	if err = o.checkResourceQuota(ctx, request, clusterEntity, coreutil.HorizontalScalingResourceDelta); err != nil {
+		return nil, err
+	}
 
 	return o.withMetaDataSync(ctx, request, o.doHorizontalScaling, metautil.UpdateValWithHScaling)
 }
@@ -290,6 +311,32 @@ func (o *OpsRequestProvider) checkClusterExists(
 	return clusterEntity, nil
 }
 
+// ResourceDeltaFn 计算运维请求相对集群当前规格的资源变化量
+type ResourceDeltaFn func(*coreentity.Request, *kbv1.Cluster) coreentity.ResourceAmount
+
+// checkResourceQuota 检查运维请求是否超出业务和命名空间的资源配额
+func (o *OpsRequestProvider) checkResourceQuota(
+	ctx *commentity.DbsContext,
+	request *coreentity.Request,
+	clusterEntity *metaentity.K8sCrdClusterEntity,
+	deltaFn ResourceDeltaFn,
+) error {
+	k8sClient, err := commutil.NewK8sClient(ctx.K8sClusterConfig)
+	if err != nil {
+		return dbserrors.NewK8sDbsError(dbserrors.CreateK8sClientError, err)
+	}
+	clusterInfo, err := getClusterInfo(request, k8sClient)
+	if err != nil {
+		return dbserrors.NewK8sDbsError(dbserrors.GetClusterError, err)
+	}
+	return o.quotaProvider.CheckQuota(
+		ctx.K8sClusterConfig,
+		clusterEntity.BkBizID,
+		request.Namespace,
+		deltaFn(request, clusterInfo),
+	)
+}
+
 // doHorizontalScaling 水平扩容具体实现
 func (o *OpsRequestProvider) doHorizontalScaling(
 	ctx *commentity.DbsContext,
@@ -353,6 +400,9 @@ func (o *OpsRequestProvider) VolumeExpansion(
 			return nil, err
 		}
 	}
+	if err = o.checkResourceQuota(ctx, request, clusterEntity, coreutil.VolumeExpansionResourceDelta); err != nil {
+		return nil, err
+	}
 
 	return o.withMetaDataSync(ctx, request, o.doVolumeExpansion, metautil.UpdateValWithCompList)
 }
@@ -667,6 +717,238 @@ func (o *OpsRequestProvider) doExposeCluster(
 	return &responseData.Metadata, nil
 }
 
+// BackupCluster 集群备份
+func (o *OpsRequestProvider) BackupCluster(
+	ctx *commentity.DbsContext,
+	request *coreentity.Request,
+) (*coreentity.Metadata, error) {
+	return o.withMetaDataSync(ctx, request, o.doBackupCluster, nil)
+}
+
+// doBackupCluster 集群备份
+func (o *OpsRequestProvider) doBackupCluster(
+	ctx *commentity.DbsContext,
+	request *coreentity.Request,
+) (*coreentity.Metadata, error) {
+	k8sClusterConfig, err := o.clusterConfigProvider.FindConfigByName(request.K8sClusterName)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	k8sClient, err := commutil.NewK8sClient(k8sClusterConfig)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.CreateK8sClientError, err)
+	}
+
+	backup, err := coreutil.CreateBackupObject(k8sClient, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = metautil.CreateOpsRequestMetaData(
+		o.opsRequestProvider,
+		o.clusterMetaProvider,
+		request,
+		backup,
+		ctx.RequestID,
+		k8sClusterConfig.ID,
+	); err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.CreateMetaDataError, err)
+	}
+
+	if err = coreutil.CreateCRD(k8sClient, backup); err != nil {
+		return nil, fmt.Errorf("下发集群备份任务失败: %w", err)
+	}
+
+	responseData, err := coreentity.GetOpsRequestData(backup.ResourceObject)
+	if err != nil {
+		return nil, fmt.Errorf("获取集群备份任务详情失败: %w", err)
+	}
+	return &responseData.Metadata, nil
+}
+
+// RestoreCluster 从备份恢复出新集群，操作记录关联到原集群
+func (o *OpsRequestProvider) RestoreCluster(
+	ctx *commentity.DbsContext,
+	request *coreentity.Request,
+) (*coreentity.Metadata, error) {
+	return o.withMetaDataSync(ctx, request, o.doRestoreCluster, nil)
+}
+
+// doRestoreCluster 从备份恢复出新集群
+func (o *OpsRequestProvider) doRestoreCluster(
+	ctx *commentity.DbsContext,
+	request *coreentity.Request,
+) (*coreentity.Metadata, error) {
+	k8sClusterConfig, err := o.clusterConfigProvider.FindConfigByName(request.K8sClusterName)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	k8sClient, err := commutil.NewK8sClient(k8sClusterConfig)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.CreateK8sClientError, err)
+	}
+
+	targetCluster, err := o.clusterMetaProvider.FindByParams(&metaentity.ClusterQueryParams{
+		K8sClusterConfigID: k8sClusterConfig.ID,
+		ClusterName:        request.RestoreClusterName,
+		Namespace:          request.Namespace,
+	})
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	if targetCluster != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.ParameterValueError,
+			fmt.Errorf("集群 %s 已存在，请指定新的恢复集群名称", request.RestoreClusterName))
+	}
+
+	restore, err := coreutil.CreateRestoreObject(k8sClient, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = metautil.CreateOpsRequestMetaData(
+		o.opsRequestProvider,
+		o.clusterMetaProvider,
+		request,
+		restore,
+		ctx.RequestID,
+		k8sClusterConfig.ID,
+	); err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.CreateMetaDataError, err)
+	}
+
+	if err = coreutil.CreateCRD(k8sClient, restore); err != nil {
+		return nil, fmt.Errorf("下发集群恢复任务失败: %w", err)
+	}
+
+	if _, err = o.clusterProvider.saveRestoredClusterMeta(request, ctx.RequestID, k8sClusterConfig.ID); err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.CreateMetaDataError,
+			fmt.Errorf("登记恢复集群 %s 元数据失败: %w", request.RestoreClusterName, err))
+	}
+
+	responseData, err := coreentity.GetOpsRequestData(restore.ResourceObject)
+	if err != nil {
+		return nil, fmt.Errorf("获取集群恢复任务详情失败: %w", err)
+	}
+	return &responseData.Metadata, nil
+}
+
+// ListBackups 查询集群的备份列表
+func (o *OpsRequestProvider) ListBackups(request *coreentity.Request) ([]*coreentity.BackupData, error) {
+	k8sClusterConfig, err := o.clusterConfigProvider.FindConfigByName(request.K8sClusterName)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	k8sClient, err := commutil.NewK8sClient(k8sClusterConfig)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.CreateK8sClientError, err)
+	}
+	return coreutil.ListBackups(k8sClient, request.Namespace, request.ClusterName)
+}
+
+// Switchover 组件主从切换，未指定候选实例时由 kubeblocks 选择新的主节点
+func (o *OpsRequestProvider) Switchover(
+	ctx *commentity.DbsContext,
+	request *coreentity.Request,
+) (*coreentity.Metadata, error) {
+	return o.withMetaDataSync(ctx, request, o.doSwitchover, nil)
+}
+
+// doSwitchover 组件主从切换
+func (o *OpsRequestProvider) doSwitchover(
+	ctx *commentity.DbsContext,
+	request *coreentity.Request,
+) (*coreentity.Metadata, error) {
+	k8sClusterConfig, err := o.clusterConfigProvider.FindConfigByName(request.K8sClusterName)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	k8sClient, err := commutil.NewK8sClient(k8sClusterConfig)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.CreateK8sClientError, err)
+	}
+
+	switchover, err := coreutil.CreateSwitchoverObject(k8sClient, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = metautil.CreateOpsRequestMetaData(
+		o.opsRequestProvider,
+		o.clusterMetaProvider,
+		request,
+		switchover,
+		ctx.RequestID,
+		k8sClusterConfig.ID,
+	); err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.CreateMetaDataError, err)
+	}
+
+	if err = coreutil.CreateCRD(k8sClient, switchover); err != nil {
+		return nil, fmt.Errorf("下发主从切换任务失败: %w", err)
+	}
+
+	responseData, err := coreentity.GetOpsRequestData(switchover.ResourceObject)
+	if err != nil {
+		return nil, fmt.Errorf("获取主从切换任务详情失败: %w", err)
+	}
+	return &responseData.Metadata, nil
+}
+
+// Reconfigure 组件参数变更，参数需要在组件的参数定义中，是否需要重启记录在 opsRequest 注解中
+func (o *OpsRequestProvider) Reconfigure(
+	ctx *commentity.DbsContext,
+	request *coreentity.Request,
+) (*coreentity.Metadata, error) {
+	return o.withMetaDataSync(ctx, request, o.doReconfigure, nil)
+}
+
+// doReconfigure 组件参数变更
+func (o *OpsRequestProvider) doReconfigure(
+	ctx *commentity.DbsContext,
+	request *coreentity.Request,
+) (*coreentity.Metadata, error) {
+	k8sClusterConfig, err := o.clusterConfigProvider.FindConfigByName(request.K8sClusterName)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	k8sClient, err := commutil.NewK8sClient(k8sClusterConfig)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.CreateK8sClientError, err)
+	}
+
+	cluster, err := getClusterInfo(request, k8sClient)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetClusterError, err)
+	}
+
+	reconfigure, err := coreutil.CreateReconfigureObject(k8sClient, request, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = metautil.CreateOpsRequestMetaData(
+		o.opsRequestProvider,
+		o.clusterMetaProvider,
+		request,
+		reconfigure,
+		ctx.RequestID,
+		k8sClusterConfig.ID,
+	); err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.CreateMetaDataError, err)
+	}
+
+	if err = coreutil.CreateCRD(k8sClient, reconfigure); err != nil {
+		return nil, fmt.Errorf("下发参数变更任务失败: %w", err)
+	}
+
+	responseData, err := coreentity.GetOpsRequestData(reconfigure.ResourceObject)
+	if err != nil {
+		return nil, fmt.Errorf("获取参数变更任务详情失败: %w", err)
+	}
+	return &responseData.Metadata, nil
+}
+
 // DescribeOpsRequest describe OpsRequest
 func (o *OpsRequestProvider) DescribeOpsRequest(request *coreentity.Request) (*coreentity.OpsRequestData, error) {
 	k8sClusterConfig, err := o.clusterConfigProvider.FindConfigByName(request.K8sClusterName)
@@ -692,9 +974,25 @@ func (o *OpsRequestProvider) DescribeOpsRequest(request *coreentity.Request) (*c
 	if err != nil {
 		return nil, err
 	}
+	fillBackupStatus(k8sClient, responseData)
 	return responseData, nil
 }
 
+// fillBackupStatus 备份类型的 opsRequest 补充对应 Backup 资源的状态
+func fillBackupStatus(k8sClient *commutil.K8sClient, responseData *coreentity.OpsRequestData) {
+	spec, ok := responseData.Spec.(opv1.OpsRequestSpec)
+	if !ok || spec.Type != coreconst.Backup || spec.GetBackup() == nil || spec.GetBackup().BackupName == "" {
+		return
+	}
+	backup, err := coreutil.GetBackup(k8sClient, responseData.Metadata.Namespace, spec.GetBackup().BackupName)
+	if err != nil {
+		// opsRequest 执行前 Backup 资源还未创建
+		slog.Warn("failed to get backup", "backupName", spec.GetBackup().BackupName, "error", err)
+		return
+	}
+	responseData.OpsRequestStatus.Backup = backup
+}
+
 // validateProvider 验证 OpsRequestProvider 必要字段
 func (o *OpsRequestProvider) validateProvider() error {
 	if o.opsRequestProvider == nil {
@@ -712,6 +1010,9 @@ func (o *OpsRequestProvider) validateProvider() error {
 	if o.clusterConfigProvider == nil {
 		return fmt.Errorf("missing clusterConfigProvider")
 	}
+	if o.quotaProvider == nil {
+		return fmt.Errorf("missing quotaProvider")
+	}
 	return nil
 }
 
@@ -0,0 +1,336 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	commutil "k8s-dbs/common/util"
+	coreconst "k8s-dbs/core/constant"
+	coreentity "k8s-dbs/core/entity"
+	coreutil "k8s-dbs/core/util"
+	dbserrors "k8s-dbs/errors"
+	metaentity "k8s-dbs/metadata/entity"
+	metaprovider "k8s-dbs/metadata/provider"
+	"log/slog"
+	"strings"
+
+	kbv1 "github.com/apecloud/kubeblocks/apis/apps/v1alpha1"
+)
+
+// QuotaProvider 资源配额检查和资源使用统计服务
+type QuotaProvider struct {
+	quotaMetaProvider     metaprovider.ResourceQuotaProvider
+	clusterMetaProvider   metaprovider.K8sCrdClusterProvider
+	clusterConfigProvider metaprovider.K8sClusterConfigProvider
+}
+
+// NewQuotaProvider 创建 QuotaProvider 实例
+func NewQuotaProvider(
+	quotaMetaProvider metaprovider.ResourceQuotaProvider,
+	clusterMetaProvider metaprovider.K8sCrdClusterProvider,
+	clusterConfigProvider metaprovider.K8sClusterConfigProvider,
+) *QuotaProvider {
+	return &QuotaProvider{
+		quotaMetaProvider,
+		clusterMetaProvider,
+		clusterConfigProvider,
+	}
+}
+
+// quotaClusterCache 单次统计中按 k8s 集群缓存 client 和 kubeblocks 集群列表，访问失败的 k8s 集群也缓存错误，不重复访问
+type quotaClusterCache struct {
+	configs  map[uint64]*metaentity.K8sClusterConfigEntity
+	clients  map[uint64]*commutil.K8sClient
+	clusters map[uint64]map[string]*kbv1.Cluster
+	errs     map[uint64]error
+}
+
+func newQuotaClusterCache() *quotaClusterCache {
+	return &quotaClusterCache{
+		configs:  make(map[uint64]*metaentity.K8sClusterConfigEntity),
+		clients:  make(map[uint64]*commutil.K8sClient),
+		clusters: make(map[uint64]map[string]*kbv1.Cluster),
+		errs:     make(map[uint64]error),
+	}
+}
+
+// CheckQuota 检查业务和命名空间配额，delta 为本次请求的资源变化量，只减少资源时不做检查
+func (q *QuotaProvider) CheckQuota(
+	k8sClusterConfig *metaentity.K8sClusterConfigEntity,
+	bkBizID uint64,
+	namespace string,
+	delta coreentity.ResourceAmount,
+) error {
+	if !delta.HasIncrease() {
+		return nil
+	}
+	var quotas []*metaentity.ResourceQuotaEntity
+	if bkBizID != 0 {
+		bizQuota, err := q.quotaMetaProvider.FindActiveQuota(&metaentity.ResourceQuotaQueryParams{
+			QuotaType: coreconst.BusinessQuota,
+			BkBizID:   bkBizID,
+		})
+		if err != nil {
+			return dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+		}
+		if bizQuota != nil {
+			quotas = append(quotas, bizQuota)
+		}
+	}
+	nsQuota, err := q.quotaMetaProvider.FindActiveQuota(&metaentity.ResourceQuotaQueryParams{
+		QuotaType:          coreconst.NamespaceQuota,
+		K8sClusterConfigID: k8sClusterConfig.ID,
+		Namespace:          namespace,
+	})
+	if err != nil {
+		return dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	if nsQuota != nil {
+		quotas = append(quotas, nsQuota)
+	}
+	if len(quotas) == 0 {
+		return nil
+	}
+
+	cache := newQuotaClusterCache()
+	cache.configs[k8sClusterConfig.ID] = k8sClusterConfig
+	var exceededDetails []string
+	for _, quota := range quotas {
+		clusters, err := q.clusterMetaProvider.FindAllClusters(quotaClusterParams(quota))
+		if err != nil {
+			return dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+		}
+		var used coreentity.ResourceAmount
+		for _, cluster := range clusters {
+			requested, err := q.clusterRequested(cache, cluster)
+			if err != nil {
+				// 业务配额跨 k8s 集群统计，其它 k8s 集群不可访问时跳过，不影响目标 k8s 集群上的操作
+				if cluster.K8sClusterConfigID != k8sClusterConfig.ID {
+					slog.Warn("k8s cluster unreachable, skip its clusters in quota check",
+						"quotaId", quota.ID,
+						"k8sClusterConfigId", cluster.K8sClusterConfigID,
+						"clusterName", cluster.ClusterName,
+						"error", err)
+					continue
+				}
+				return dbserrors.NewK8sDbsError(dbserrors.GetResourceUsageError, err)
+			}
+			used.Add(requested)
+		}
+		result := coreutil.CheckResourceQuota(quota, used, delta)
+		result.K8sClusterName = k8sClusterConfig.ClusterName
+		if result.Exceeded() {
+			slog.Warn("resource quota exceeded", "quotaId", quota.ID, "detail", result.String())
+			exceededDetails = append(exceededDetails, result.String())
+		}
+	}
+	if len(exceededDetails) > 0 {
+		return dbserrors.NewK8sDbsError(dbserrors.ResourceQuotaExceededError,
+			errors.New(strings.Join(exceededDetails, "; ")))
+	}
+	return nil
+}
+
+// GetUsageReport 查询业务或命名空间的资源分配量和存储实际使用量
+func (q *QuotaProvider) GetUsageReport(
+	params *coreentity.ResourceUsageQueryParams,
+) (*coreentity.ResourceUsageReport, error) {
+	quotaParams := &metaentity.ResourceQuotaQueryParams{
+		QuotaType: coreconst.BusinessQuota,
+		BkBizID:   params.BkBizID,
+	}
+	report := &coreentity.ResourceUsageReport{
+		QuotaType: coreconst.BusinessQuota,
+		BkBizID:   params.BkBizID,
+		Clusters:  make([]*coreentity.ClusterResourceUsage, 0),
+	}
+	if params.BkBizID == 0 {
+		if params.K8sClusterName == "" || params.Namespace == "" {
+			return nil, dbserrors.NewK8sDbsError(dbserrors.ParameterInvalidError,
+				fmt.Errorf("bkBizId 或 k8sClusterName 和 namespace 不能为空"))
+		}
+		k8sClusterConfig, err := q.clusterConfigProvider.FindConfigByName(params.K8sClusterName)
+		if err != nil {
+			return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+		}
+		quotaParams = &metaentity.ResourceQuotaQueryParams{
+			QuotaType:          coreconst.NamespaceQuota,
+			K8sClusterConfigID: k8sClusterConfig.ID,
+			Namespace:          params.Namespace,
+		}
+		report.QuotaType = coreconst.NamespaceQuota
+		report.K8sClusterName = params.K8sClusterName
+		report.Namespace = params.Namespace
+	}
+
+	quota, err := q.quotaMetaProvider.FindActiveQuota(quotaParams)
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	if quota != nil {
+		report.Quota = &coreentity.ResourceAmount{CPU: quota.CPU, Memory: quota.Memory, Storage: quota.Storage}
+	}
+
+	clusters, err := q.clusterMetaProvider.FindAllClusters(quotaClusterParams(&metaentity.ResourceQuotaEntity{
+		QuotaType:          quotaParams.QuotaType,
+		BkBizID:            quotaParams.BkBizID,
+		K8sClusterConfigID: quotaParams.K8sClusterConfigID,
+		Namespace:          quotaParams.Namespace,
+	}))
+	if err != nil {
+		return nil, dbserrors.NewK8sDbsError(dbserrors.GetMetaDataError, err)
+	}
+	cache := newQuotaClusterCache()
+	for _, cluster := range clusters {
+		usage, err := q.clusterUsage(cache, cluster)
+		if err != nil {
+			return nil, dbserrors.NewK8sDbsError(dbserrors.GetResourceUsageError, err)
+		}
+		report.Requested.Add(usage.Requested)
+		report.StorageUsed += usage.StorageUsed
+		report.Clusters = append(report.Clusters, usage)
+	}
+	report.Requested = roundResourceAmount(report.Requested)
+	report.StorageUsed = commutil.RoundToDecimal(report.StorageUsed, 3)
+	return report, nil
+}
+
+// clusterUsage 统计单个集群的资源分配量和存储实际使用量
+func (q *QuotaProvider) clusterUsage(
+	cache *quotaClusterCache,
+	cluster *metaentity.K8sCrdClusterEntity,
+) (*coreentity.ClusterResourceUsage, error) {
+	requested, err := q.clusterRequested(cache, cluster)
+	if err != nil {
+		return nil, err
+	}
+	k8sClusterConfig := cache.configs[cluster.K8sClusterConfigID]
+	usage := &coreentity.ClusterResourceUsage{
+		K8sClusterName: k8sClusterConfig.ClusterName,
+		ClusterName:    cluster.ClusterName,
+		Namespace:      cluster.Namespace,
+		BkBizID:        cluster.BkBizID,
+		Requested:      roundResourceAmount(requested),
+	}
+	if cluster.AddonInfo != nil {
+		usage.AddonType = cluster.AddonInfo.AddonType
+	}
+	usage.StorageUsed, usage.StorageUsedComplete, err = coreutil.ClusterStorageUsed(
+		cache.clients[cluster.K8sClusterConfigID],
+		usage.AddonType,
+		usage.K8sClusterName,
+		cluster.Namespace,
+		cluster.ClusterName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// clusterRequested 统计单个集群当前分配的资源，集群资源对象不存在时视为未分配
+func (q *QuotaProvider) clusterRequested(
+	cache *quotaClusterCache,
+	cluster *metaentity.K8sCrdClusterEntity,
+) (coreentity.ResourceAmount, error) {
+	kbClusters, err := q.listKbClusters(cache, cluster.K8sClusterConfigID)
+	if err != nil {
+		return coreentity.ResourceAmount{}, err
+	}
+	kbCluster, ok := kbClusters[cluster.Namespace+"/"+cluster.ClusterName]
+	if !ok {
+		slog.Warn("kubeblocks cluster not found, skip resource statistics",
+			"k8sClusterConfigId", cluster.K8sClusterConfigID,
+			"namespace", cluster.Namespace,
+			"clusterName", cluster.ClusterName)
+		return coreentity.ResourceAmount{}, nil
+	}
+	return coreutil.ClusterRequestedResource(kbCluster), nil
+}
+
+// listKbClusters 查询 k8s 集群下的 kubeblocks 集群，key 为 namespace/name
+func (q *QuotaProvider) listKbClusters(
+	cache *quotaClusterCache,
+	k8sClusterConfigID uint64,
+) (map[string]*kbv1.Cluster, error) {
+	if kbClusters, ok := cache.clusters[k8sClusterConfigID]; ok {
+		return kbClusters, nil
+	}
+	if err, ok := cache.errs[k8sClusterConfigID]; ok {
+		return nil, err
+	}
+	kbClusters, err := q.fetchKbClusters(cache, k8sClusterConfigID)
+	if err != nil {
+		cache.errs[k8sClusterConfigID] = err
+		return nil, err
+	}
+	return kbClusters, nil
+}
+
+// fetchKbClusters 访问 k8s 集群查询 kubeblocks 集群列表并缓存 client
+func (q *QuotaProvider) fetchKbClusters(
+	cache *quotaClusterCache,
+	k8sClusterConfigID uint64,
+) (map[string]*kbv1.Cluster, error) {
+	k8sClusterConfig, ok := cache.configs[k8sClusterConfigID]
+	if !ok {
+		var err error
+		k8sClusterConfig, err = q.clusterConfigProvider.FindConfigByID(k8sClusterConfigID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find k8s cluster config %d: %w", k8sClusterConfigID, err)
+		}
+		cache.configs[k8sClusterConfigID] = k8sClusterConfig
+	}
+	k8sClient, err := commutil.NewK8sClient(k8sClusterConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client for %s: %w", k8sClusterConfig.ClusterName, err)
+	}
+	clusterList, err := coreutil.ListKbClusters(k8sClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kubeblocks clusters in %s: %w", k8sClusterConfig.ClusterName, err)
+	}
+	kbClusters := make(map[string]*kbv1.Cluster, len(clusterList))
+	for _, kbCluster := range clusterList {
+		kbClusters[kbCluster.Namespace+"/"+kbCluster.Name] = kbCluster
+	}
+	cache.clients[k8sClusterConfigID] = k8sClient
+	cache.clusters[k8sClusterConfigID] = kbClusters
+	return kbClusters, nil
+}
+
+// quotaClusterParams 构建配额范围内的集群查询条件，业务配额跨 k8s 集群统计
+func quotaClusterParams(quota *metaentity.ResourceQuotaEntity) *metaentity.ClusterQueryParams {
+	if quota.QuotaType == coreconst.BusinessQuota {
+		return &metaentity.ClusterQueryParams{BkBizIDs: []uint64{quota.BkBizID}}
+	}
+	return &metaentity.ClusterQueryParams{
+		K8sClusterConfigID: quota.K8sClusterConfigID,
+		Namespace:          quota.Namespace,
+	}
+}
+
+func roundResourceAmount(amount coreentity.ResourceAmount) coreentity.ResourceAmount {
+	return coreentity.ResourceAmount{
+		CPU:     commutil.RoundToDecimal(amount.CPU, 3),
+		Memory:  commutil.RoundToDecimal(amount.Memory, 3),
+		Storage: commutil.RoundToDecimal(amount.Storage, 3),
+	}
+}
@@ -0,0 +1,301 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reconciler 后台巡检元数据与 k8s 集群实际状态的差异
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	commutil "k8s-dbs/common/util"
+	"k8s-dbs/config"
+	coreconst "k8s-dbs/core/constant"
+	coreentity "k8s-dbs/core/entity"
+	coreutil "k8s-dbs/core/util"
+	metaentity "k8s-dbs/metadata/entity"
+	metaprovider "k8s-dbs/metadata/provider"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/copier"
+)
+
+// DriftReconciler 定期对比每个 k8s 集群上的 kubeblocks 集群、helm release 与元数据，记录差异并按配置修复元数据
+type DriftReconciler struct {
+	cfg                   *config.DriftReconcilerConfig
+	clusterConfigProvider metaprovider.K8sClusterConfigProvider
+	clusterMetaProvider   metaprovider.K8sCrdClusterProvider
+	componentMetaProvider metaprovider.K8sCrdComponentProvider
+	releaseMetaProvider   metaprovider.AddonClusterReleaseProvider
+	driftMetaProvider     metaprovider.K8sClusterDriftProvider
+}
+
+// NewDriftReconciler 创建 DriftReconciler，巡检配置从环境变量读取
+func NewDriftReconciler(
+	clusterConfigProvider metaprovider.K8sClusterConfigProvider,
+	clusterMetaProvider metaprovider.K8sCrdClusterProvider,
+	componentMetaProvider metaprovider.K8sCrdComponentProvider,
+	releaseMetaProvider metaprovider.AddonClusterReleaseProvider,
+	driftMetaProvider metaprovider.K8sClusterDriftProvider,
+) *DriftReconciler {
+	return &DriftReconciler{
+		cfg:                   driftReconcilerConfig(),
+		clusterConfigProvider: clusterConfigProvider,
+		clusterMetaProvider:   clusterMetaProvider,
+		componentMetaProvider: componentMetaProvider,
+		releaseMetaProvider:   releaseMetaProvider,
+		driftMetaProvider:     driftMetaProvider,
+	}
+}
+
+func driftReconcilerConfig() *config.DriftReconcilerConfig {
+	cfg := &config.DriftReconcilerConfig{Interval: coreconst.DefaultDriftReconcileInterval}
+	if interval := os.Getenv("DRIFT_RECONCILE_INTERVAL"); interval != "" {
+		duration, err := time.ParseDuration(interval)
+		if err != nil {
+			slog.Warn("invalid DRIFT_RECONCILE_INTERVAL, use default", "interval", interval, "error", err)
+		} else {
+			cfg.Interval = duration
+		}
+	}
+	cfg.AutoRepair, _ = strconv.ParseBool(os.Getenv("DRIFT_AUTO_REPAIR"))
+	return cfg
+}
+
+// Start 启动后台巡检，启动后立即巡检一次，之后按间隔巡检，ctx 取消后退出
+func (r *DriftReconciler) Start(ctx context.Context) {
+	if r.cfg.Interval <= 0 {
+		slog.Info("Drift reconciler is disabled")
+		return
+	}
+	slog.Info("Start drift reconciler", "interval", r.cfg.Interval, "autoRepair", r.cfg.AutoRepair)
+	go func() {
+		r.reconcileWithLock(ctx)
+		ticker := time.NewTicker(r.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("Drift reconciler exited")
+				return
+			case <-ticker.C:
+				r.reconcileWithLock(ctx)
+			}
+		}
+	}()
+}
+
+// reconcileWithLock 持有巡检锁时执行一轮巡检，未获取到锁说明其他副本正在巡检，跳过本轮
+func (r *DriftReconciler) reconcileWithLock(ctx context.Context) {
+	release, locked, err := r.driftMetaProvider.TryLock(ctx, coreconst.DriftReconcilerLockName)
+	if err != nil {
+		slog.Error("failed to get drift reconciler lock", "error", err)
+		return
+	}
+	if !locked {
+		slog.Info("Drift reconciler lock is held by another replica, skip")
+		return
+	}
+	defer release()
+	r.ReconcileAll()
+}
+
+// ReconcileAll 巡检所有有效的 k8s 集群，单个 k8s 集群失败不影响其他集群
+func (r *DriftReconciler) ReconcileAll() {
+	k8sClusterConfigs, err := r.clusterConfigProvider.ListActiveConfigs()
+	if err != nil {
+		slog.Error("failed to list k8s cluster config", "error", err)
+		return
+	}
+	for _, k8sClusterConfig := range k8sClusterConfigs {
+		if err = r.Reconcile(k8sClusterConfig); err != nil {
+			slog.Error("failed to reconcile k8s cluster",
+				"k8sClusterName", k8sClusterConfig.ClusterName,
+				"error", err,
+			)
+		}
+	}
+}
+
+// Reconcile 巡检一个 k8s 集群
+func (r *DriftReconciler) Reconcile(k8sClusterConfig *metaentity.K8sClusterConfigEntity) error {
+	k8sClient, err := commutil.NewK8sClient(k8sClusterConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	actualState, err := r.getActualState(k8sClient)
+	if err != nil {
+		return err
+	}
+	metaState, err := r.getMetaState(k8sClusterConfig.ID)
+	if err != nil {
+		return err
+	}
+
+	var drifts []*metaentity.K8sClusterDriftEntity
+	for _, drift := range coreutil.DiffClusterMetadata(metaState, actualState) {
+		if r.isSettling(drift, metaState, actualState) {
+			continue
+		}
+		if r.cfg.AutoRepair {
+			repaired, err := r.repair(drift, metaState, actualState)
+			if err != nil {
+				slog.Warn("failed to repair metadata", "drift", drift.DriftKey(), "error", err)
+			}
+			drift.Repaired = repaired
+		}
+		drifts = append(drifts, drift)
+	}
+	if err = r.driftMetaProvider.SyncDrifts(k8sClusterConfig.ID, drifts, coreconst.DriftReconcilerOperator); err != nil {
+		return fmt.Errorf("failed to save drift: %w", err)
+	}
+	slog.Info("Finish reconcile k8s cluster",
+		"k8sClusterName", k8sClusterConfig.ClusterName,
+		"drifts", len(drifts),
+	)
+	return nil
+}
+
+// getActualState 查询 k8s 集群上实际的集群和 release
+func (r *DriftReconciler) getActualState(k8sClient *commutil.K8sClient) (*coreentity.DriftActualState, error) {
+	clusters, err := coreutil.ListKbClusters(k8sClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kubeblocks cluster: %w", err)
+	}
+	releases, err := coreutil.ListHelmReleases(k8sClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list helm release: %w", err)
+	}
+	return &coreentity.DriftActualState{Clusters: clusters, Releases: releases}, nil
+}
+
+// getMetaState 查询 k8s 集群上的元数据
+func (r *DriftReconciler) getMetaState(k8sClusterConfigID uint64) (*coreentity.DriftMetaState, error) {
+	clusters, err := r.clusterMetaProvider.FindClustersByK8sClusterConfigID(k8sClusterConfigID)
+	if err != nil {
+		return nil, err
+	}
+	components := make(map[uint64][]*metaentity.K8sCrdComponentEntity, len(clusters))
+	for _, cluster := range clusters {
+		if components[cluster.ID], err = r.componentMetaProvider.FindComponentsByClusterID(cluster.ID); err != nil {
+			return nil, err
+		}
+	}
+	releases, err := r.releaseMetaProvider.FindClusterReleasesByK8sClusterConfigID(k8sClusterConfigID)
+	if err != nil {
+		return nil, err
+	}
+	return &coreentity.DriftMetaState{Clusters: clusters, Components: components, Releases: releases}, nil
+}
+
+// isSettling 刚创建的集群元数据和资源可能还没有同步完成，一个巡检周期内不记录差异
+func (r *DriftReconciler) isSettling(
+	drift *metaentity.K8sClusterDriftEntity,
+	metaState *coreentity.DriftMetaState,
+	actualState *coreentity.DriftActualState,
+) bool {
+	settled := time.Now().Add(-r.cfg.Interval)
+	for _, cluster := range actualState.Clusters {
+		if cluster.Namespace == drift.Namespace && cluster.Name == drift.ClusterName {
+			return cluster.CreationTimestamp.After(settled)
+		}
+	}
+	for _, cluster := range metaState.Clusters {
+		if cluster.Namespace == drift.Namespace && cluster.ClusterName == drift.ClusterName {
+			return time.Time(cluster.CreatedAt).After(settled)
+		}
+	}
+	return false
+}
+
+// repair 根据 k8s 集群的实际状态修复元数据，只补充缺失的元数据或更新不一致的字段
+// 集群、组件和 release 在 k8s 中缺失时只记录差异，删除元数据需要人工确认后处理
+func (r *DriftReconciler) repair(
+	drift *metaentity.K8sClusterDriftEntity,
+	metaState *coreentity.DriftMetaState,
+	actualState *coreentity.DriftActualState,
+) (bool, error) {
+	switch drift.DriftType {
+	case coreconst.TerminationPolicyDrift:
+		for _, cluster := range metaState.Clusters {
+			if cluster.ID != drift.CrdClusterID {
+				continue
+			}
+			clusterEntity := &metaentity.K8sCrdClusterEntity{}
+			if err := copier.Copy(clusterEntity, cluster); err != nil {
+				return false, err
+			}
+			clusterEntity.TerminationPolicy = coreconst.TerminationPolicy(drift.ActualValue)
+			clusterEntity.UpdatedBy = coreconst.DriftReconcilerOperator
+			if _, err := r.clusterMetaProvider.UpdateCluster(clusterEntity); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	case coreconst.ComponentMissingInMeta:
+		if _, err := r.componentMetaProvider.CreateComponent(&metaentity.K8sCrdComponentEntity{
+			CrdClusterID:  drift.CrdClusterID,
+			ComponentName: drift.ResourceName,
+			CreatedBy:     coreconst.DriftReconcilerOperator,
+			UpdatedBy:     coreconst.DriftReconcilerOperator,
+		}); err != nil {
+			return false, err
+		}
+		return true, nil
+	case coreconst.ReleaseVersionDrift:
+		return r.repairRelease(drift, metaState, actualState)
+	}
+	return false, nil
+}
+
+// repairRelease 使用实际 release 的 chart 版本和 values 更新 release 元数据
+func (r *DriftReconciler) repairRelease(
+	drift *metaentity.K8sClusterDriftEntity,
+	metaState *coreentity.DriftMetaState,
+	actualState *coreentity.DriftActualState,
+) (bool, error) {
+	for _, rel := range actualState.Releases {
+		if rel.Namespace != drift.Namespace || rel.Name != drift.ResourceName {
+			continue
+		}
+		for _, metaRelease := range metaState.Releases {
+			if metaRelease.Namespace != drift.Namespace || metaRelease.ReleaseName != drift.ResourceName {
+				continue
+			}
+			values, err := coreutil.ReleaseValuesJSON(rel)
+			if err != nil {
+				return false, err
+			}
+			releaseEntity := &metaentity.AddonClusterReleaseEntity{}
+			if err = copier.Copy(releaseEntity, metaRelease); err != nil {
+				return false, err
+			}
+			releaseEntity.ChartVersion = drift.ActualValue
+			releaseEntity.ChartValues = values
+			releaseEntity.UpdatedBy = coreconst.DriftReconcilerOperator
+			if _, err = r.releaseMetaProvider.UpdateClusterRelease(releaseEntity); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
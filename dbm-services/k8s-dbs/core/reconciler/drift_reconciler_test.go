@@ -0,0 +1,171 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	commtypes "k8s-dbs/common/types"
+	"k8s-dbs/config"
+	coreconst "k8s-dbs/core/constant"
+	coreentity "k8s-dbs/core/entity"
+	metaentity "k8s-dbs/metadata/entity"
+	metaprovider "k8s-dbs/metadata/provider"
+	"testing"
+	"time"
+
+	kbv1 "github.com/apecloud/kubeblocks/apis/apps/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeClusterMetaProvider 只实现巡检修复用到的方法
+type fakeClusterMetaProvider struct {
+	metaprovider.K8sCrdClusterProvider
+	updated []*metaentity.K8sCrdClusterEntity
+}
+
+func (f *fakeClusterMetaProvider) UpdateCluster(entity *metaentity.K8sCrdClusterEntity) (uint64, error) {
+	f.updated = append(f.updated, entity)
+	return 1, nil
+}
+
+// fakeComponentMetaProvider 只实现巡检修复用到的方法，删除方法未实现，调用时会 panic
+type fakeComponentMetaProvider struct {
+	metaprovider.K8sCrdComponentProvider
+	created []*metaentity.K8sCrdComponentEntity
+}
+
+func (f *fakeComponentMetaProvider) CreateComponent(
+	entity *metaentity.K8sCrdComponentEntity,
+) (*metaentity.K8sCrdComponentEntity, error) {
+	f.created = append(f.created, entity)
+	return entity, nil
+}
+
+func newTestReconciler() (*DriftReconciler, *fakeClusterMetaProvider, *fakeComponentMetaProvider) {
+	clusterMeta := &fakeClusterMetaProvider{}
+	componentMeta := &fakeComponentMetaProvider{}
+	r := &DriftReconciler{
+		cfg:                   &config.DriftReconcilerConfig{Interval: 5 * time.Minute, AutoRepair: true},
+		clusterMetaProvider:   clusterMeta,
+		componentMetaProvider: componentMeta,
+	}
+	return r, clusterMeta, componentMeta
+}
+
+func newTestMetaState() *coreentity.DriftMetaState {
+	return &coreentity.DriftMetaState{
+		Clusters: []*metaentity.K8sCrdClusterEntity{
+			{
+				ID:                1,
+				ClusterName:       "mysql-test",
+				Namespace:         "test-ns",
+				TerminationPolicy: "Delete",
+				CreatedAt:         commtypes.JSONDatetime(time.Now().Add(-time.Hour)),
+			},
+		},
+		Components: map[uint64][]*metaentity.K8sCrdComponentEntity{
+			1: {{ID: 10, CrdClusterID: 1, ComponentName: "mysql-test-proxy"}},
+		},
+	}
+}
+
+func TestRepairTerminationPolicy(t *testing.T) {
+	r, clusterMeta, _ := newTestReconciler()
+	repaired, err := r.repair(&metaentity.K8sClusterDriftEntity{
+		CrdClusterID: 1,
+		DriftType:    coreconst.TerminationPolicyDrift,
+		ActualValue:  "WipeOut",
+	}, newTestMetaState(), &coreentity.DriftActualState{})
+	assert.NoError(t, err)
+	assert.True(t, repaired)
+	assert.Len(t, clusterMeta.updated, 1)
+	assert.Equal(t, coreconst.TerminationPolicy("WipeOut"), clusterMeta.updated[0].TerminationPolicy)
+	assert.Equal(t, "mysql-test", clusterMeta.updated[0].ClusterName)
+	assert.Equal(t, coreconst.DriftReconcilerOperator, clusterMeta.updated[0].UpdatedBy)
+}
+
+func TestRepairComponentMissingInMeta(t *testing.T) {
+	r, _, componentMeta := newTestReconciler()
+	repaired, err := r.repair(&metaentity.K8sClusterDriftEntity{
+		CrdClusterID: 1,
+		ResourceName: "mysql-test-mysql",
+		DriftType:    coreconst.ComponentMissingInMeta,
+	}, newTestMetaState(), &coreentity.DriftActualState{})
+	assert.NoError(t, err)
+	assert.True(t, repaired)
+	assert.Len(t, componentMeta.created, 1)
+	assert.Equal(t, "mysql-test-mysql", componentMeta.created[0].ComponentName)
+	assert.Equal(t, uint64(1), componentMeta.created[0].CrdClusterID)
+}
+
+func TestRepairSkipMissingInK8s(t *testing.T) {
+	r, clusterMeta, componentMeta := newTestReconciler()
+	for _, driftType := range []string{
+		coreconst.ComponentMissingInK8s,
+		coreconst.ClusterMissingInK8s,
+		coreconst.ReleaseMissingInK8s,
+	} {
+		repaired, err := r.repair(&metaentity.K8sClusterDriftEntity{
+			CrdClusterID: 1,
+			ResourceName: "mysql-test-proxy",
+			DriftType:    driftType,
+		}, newTestMetaState(), &coreentity.DriftActualState{})
+		assert.NoError(t, err)
+		assert.False(t, repaired, driftType)
+	}
+	assert.Empty(t, clusterMeta.updated)
+	assert.Empty(t, componentMeta.created)
+}
+
+func TestIsSettling(t *testing.T) {
+	r, _, _ := newTestReconciler()
+	metaState := newTestMetaState()
+	metaState.Clusters = append(metaState.Clusters, &metaentity.K8sCrdClusterEntity{
+		ID:          2,
+		ClusterName: "redis-test",
+		Namespace:   "test-ns",
+		CreatedAt:   commtypes.JSONDatetime(time.Now()),
+	})
+	actualState := &coreentity.DriftActualState{
+		Clusters: []*kbv1.Cluster{
+			{ObjectMeta: metav1.ObjectMeta{
+				Name: "mysql-test", Namespace: "test-ns",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+			}},
+			{ObjectMeta: metav1.ObjectMeta{
+				Name: "pg-test", Namespace: "test-ns",
+				CreationTimestamp: metav1.NewTime(time.Now()),
+			}},
+		},
+	}
+	newDrift := func(clusterName string) *metaentity.K8sClusterDriftEntity {
+		return &metaentity.K8sClusterDriftEntity{ClusterName: clusterName, Namespace: "test-ns"}
+	}
+
+	// k8s 中的集群创建时间早于一个巡检周期
+	assert.False(t, r.isSettling(newDrift("mysql-test"), metaState, actualState))
+	// k8s 中刚创建的集群
+	assert.True(t, r.isSettling(newDrift("pg-test"), metaState, actualState))
+	// k8s 中不存在，元数据刚创建的集群
+	assert.True(t, r.isSettling(newDrift("redis-test"), metaState, actualState))
+	// 其他命名空间的同名集群
+	assert.False(t, r.isSettling(
+		&metaentity.K8sClusterDriftEntity{ClusterName: "pg-test", Namespace: "other-ns"}, metaState, actualState))
+}
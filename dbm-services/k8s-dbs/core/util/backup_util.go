@@ -0,0 +1,345 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"k8s-dbs/common/util"
+	coreconst "k8s-dbs/core/constant"
+	"k8s-dbs/core/entity"
+	"sort"
+	"time"
+
+	kbtypes "github.com/apecloud/kbcli/pkg/types"
+	dpv1 "github.com/apecloud/kubeblocks/apis/dataprotection/v1alpha1"
+	opv1 "github.com/apecloud/kubeblocks/apis/operations/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// RestoreTimeLayout kubeblocks 支持的按时间点恢复的时间格式，同时支持 RFC3339
+const RestoreTimeLayout = "Jan 02,2006 15:04:05 UTC-0700"
+
+// CreateBackupObject 创建备份操作请求对象
+// 集群需要有 BackupPolicy 才能备份，未指定 backupPolicyName/backupMethod 时由 kubeblocks 使用默认策略
+// 参数:
+//
+//	k8sClient - k8s 客户端，用于校验备份策略
+//	request - 包含操作请求信息的结构体
+//
+// 返回值:
+//
+//	*entity.CustomResourceDefinition - 创建的CRD对象
+//	error - 错误信息(如果有)
+func CreateBackupObject(k8sClient *util.K8sClient, request *entity.Request) (*entity.CustomResourceDefinition, error) {
+	backupSpec := &opv1.Backup{}
+	if request.Spec.Backup != nil {
+		backupSpec = request.Spec.Backup.DeepCopy()
+	}
+	if err := checkBackupPolicy(k8sClient, request, backupSpec); err != nil {
+		return nil, err
+	}
+	// 由 dbs 生成备份名称，便于通过 opsRequest 查询备份状态
+	if backupSpec.BackupName == "" {
+		backupSpec.BackupName = util.ResourceName(
+			fmt.Sprintf("backup-%s-", request.Metadata.ClusterName), OpsNameSuffixLength)
+	}
+
+	objectName := util.ResourceName("ops-backup-", OpsNameSuffixLength)
+	backup := &opv1.OpsRequest{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: coreconst.APIVersion,
+			Kind:       coreconst.OpsRequest,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      objectName,
+			Namespace: request.Metadata.Namespace,
+		},
+		// 备份耗时与数据量相关，不设置超时时间
+		Spec: opv1.OpsRequestSpec{
+			ClusterName:                 request.Metadata.ClusterName,
+			Type:                        coreconst.Backup,
+			TTLSecondsAfterSucceed:      TTLSecondsAfterSucceed,
+			PreConditionDeadlineSeconds: util.Int32Ptr(PreConditionDeadlineSeconds),
+			SpecificOpsRequest: opv1.SpecificOpsRequest{
+				Backup: backupSpec,
+			},
+		},
+	}
+
+	unstructuredObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&backup)
+	if err != nil {
+		return nil, fmt.Errorf("转换对象为Unstructured类型失败: %v", err)
+	}
+	crd := &entity.CustomResourceDefinition{
+		Namespace:            request.Metadata.Namespace,
+		ResourceType:         coreconst.Backup,
+		ResourceName:         objectName,
+		GroupVersionResource: kbtypes.OpsGVR(),
+		ResourceObject: &unstructured.Unstructured{
+			Object: unstructuredObj,
+		},
+	}
+	return crd, nil
+}
+
+// checkBackupPolicy 校验集群的备份策略和备份方式
+func checkBackupPolicy(k8sClient *util.K8sClient, request *entity.Request, backupSpec *opv1.Backup) error {
+	policies, err := ListBackupPolicies(k8sClient, request.Metadata.Namespace, request.Metadata.ClusterName)
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 {
+		return fmt.Errorf("集群 %s 没有可用的备份策略，该存储插件不支持备份", request.Metadata.ClusterName)
+	}
+	if backupSpec.BackupPolicyName == "" && backupSpec.BackupMethod == "" {
+		return nil
+	}
+	for _, policy := range policies {
+		if backupSpec.BackupPolicyName != "" && policy.Name != backupSpec.BackupPolicyName {
+			continue
+		}
+		if backupSpec.BackupMethod == "" {
+			return nil
+		}
+		for _, method := range policy.Spec.BackupMethods {
+			if method.Name == backupSpec.BackupMethod {
+				return nil
+			}
+		}
+		if backupSpec.BackupPolicyName != "" {
+			return fmt.Errorf("备份策略 %s 不支持备份方式 %s", policy.Name, backupSpec.BackupMethod)
+		}
+	}
+	if backupSpec.BackupPolicyName != "" {
+		return fmt.Errorf("集群 %s 不存在备份策略 %s", request.Metadata.ClusterName, backupSpec.BackupPolicyName)
+	}
+	return fmt.Errorf("集群 %s 的备份策略不支持备份方式 %s", request.Metadata.ClusterName, backupSpec.BackupMethod)
+}
+
+// CreateRestoreObject 创建恢复操作请求对象
+// kubeblocks 会根据备份中的集群快照创建一个新的集群，新集群名称为 restoreClusterName
+// 指定 restorePointInTime 且未指定 backupName 时，使用覆盖该时间点的持续备份进行按时间点恢复
+// 参数和返回值同 CreateBackupObject
+func CreateRestoreObject(k8sClient *util.K8sClient, request *entity.Request) (*entity.CustomResourceDefinition, error) {
+	if request.Spec.Restore == nil {
+		return nil, fmt.Errorf("恢复参数 restore 不能为空")
+	}
+	if request.RestoreClusterName == "" || request.RestoreClusterName == request.Metadata.ClusterName {
+		return nil, fmt.Errorf("恢复集群名称 restoreClusterName 不能为空且不能与原集群相同")
+	}
+	restoreSpec := request.Spec.Restore.DeepCopy()
+	if restoreSpec.BackupNamespace == "" {
+		restoreSpec.BackupNamespace = request.Metadata.Namespace
+	}
+
+	var err error
+	if restoreSpec.RestorePointInTime != "" {
+		err = checkPointInTimeRestore(k8sClient, request, restoreSpec)
+	} else {
+		err = checkBackupRestore(k8sClient, restoreSpec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	objectName := util.ResourceName("ops-restore-", OpsNameSuffixLength)
+	restore := &opv1.OpsRequest{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: coreconst.APIVersion,
+			Kind:       coreconst.OpsRequest,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      objectName,
+			Namespace: request.Metadata.Namespace,
+		},
+		// 恢复耗时与数据量相关，不设置超时时间
+		Spec: opv1.OpsRequestSpec{
+			ClusterName:                 request.RestoreClusterName,
+			Type:                        coreconst.Restore,
+			TTLSecondsAfterSucceed:      TTLSecondsAfterSucceed,
+			PreConditionDeadlineSeconds: util.Int32Ptr(PreConditionDeadlineSeconds),
+			SpecificOpsRequest: opv1.SpecificOpsRequest{
+				Restore: restoreSpec,
+			},
+		},
+	}
+
+	unstructuredObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&restore)
+	if err != nil {
+		return nil, fmt.Errorf("转换对象为Unstructured类型失败: %v", err)
+	}
+	crd := &entity.CustomResourceDefinition{
+		Namespace:            request.Metadata.Namespace,
+		ResourceType:         coreconst.Restore,
+		ResourceName:         objectName,
+		GroupVersionResource: kbtypes.OpsGVR(),
+		ResourceObject: &unstructured.Unstructured{
+			Object: unstructuredObj,
+		},
+	}
+	return crd, nil
+}
+
+// checkBackupRestore 校验全量恢复使用的备份已完成
+func checkBackupRestore(k8sClient *util.K8sClient, restoreSpec *opv1.Restore) error {
+	if restoreSpec.BackupName == "" {
+		return fmt.Errorf("backupName 和 restorePointInTime 不能同时为空")
+	}
+	backup, err := GetBackup(k8sClient, restoreSpec.BackupNamespace, restoreSpec.BackupName)
+	if err != nil {
+		return fmt.Errorf("查询备份 %s 失败: %w", restoreSpec.BackupName, err)
+	}
+	if backup.BackupType == coreconst.ContinuousBackup {
+		return fmt.Errorf("备份 %s 是持续备份，需要指定恢复时间点 restorePointInTime", backup.BackupName)
+	}
+	if backup.Phase != dpv1.BackupPhaseCompleted {
+		return fmt.Errorf("备份 %s 状态为 %s，只能使用已完成的备份进行恢复", backup.BackupName, backup.Phase)
+	}
+	return nil
+}
+
+// checkPointInTimeRestore 校验按时间点恢复，未指定备份时查找覆盖该时间点的持续备份
+func checkPointInTimeRestore(k8sClient *util.K8sClient, request *entity.Request, restoreSpec *opv1.Restore) error {
+	restoreTime, err := ParseRestoreTime(restoreSpec.RestorePointInTime)
+	if err != nil {
+		return err
+	}
+	if restoreSpec.BackupName != "" {
+		backup, err := GetBackup(k8sClient, restoreSpec.BackupNamespace, restoreSpec.BackupName)
+		if err != nil {
+			return fmt.Errorf("查询备份 %s 失败: %w", restoreSpec.BackupName, err)
+		}
+		if backup.BackupType != coreconst.ContinuousBackup {
+			return fmt.Errorf("备份 %s 不是持续备份，不支持按时间点恢复", backup.BackupName)
+		}
+		if !backupCovers(backup, restoreTime) {
+			return fmt.Errorf("恢复时间点 %s 不在备份 %s 的可恢复时间范围内", restoreSpec.RestorePointInTime, backup.BackupName)
+		}
+		return nil
+	}
+
+	backups, err := ListBackups(k8sClient, restoreSpec.BackupNamespace, request.Metadata.ClusterName)
+	if err != nil {
+		return err
+	}
+	hasContinuous := false
+	for _, backup := range backups {
+		if backup.BackupType != coreconst.ContinuousBackup {
+			continue
+		}
+		hasContinuous = true
+		if backupCovers(backup, restoreTime) {
+			restoreSpec.BackupName = backup.BackupName
+			return nil
+		}
+	}
+	if !hasContinuous {
+		return fmt.Errorf("集群 %s 没有持续备份，该存储插件不支持或未开启按时间点恢复", request.Metadata.ClusterName)
+	}
+	return fmt.Errorf("集群 %s 没有覆盖时间点 %s 的持续备份", request.Metadata.ClusterName,
+		restoreSpec.RestorePointInTime)
+}
+
+// ParseRestoreTime 解析恢复时间点，支持 RFC3339 和 kubeblocks 的时间格式
+func ParseRestoreTime(restoreTime string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, restoreTime)
+	if err == nil {
+		return t, nil
+	}
+	t, err = time.Parse(RestoreTimeLayout, restoreTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("恢复时间点 %s 格式错误，支持 RFC3339 或 %q", restoreTime, RestoreTimeLayout)
+	}
+	return t, nil
+}
+
+// backupCovers 判断备份的时间范围是否覆盖恢复时间点
+func backupCovers(backup *entity.BackupData, t time.Time) bool {
+	if backup.TimeRange == nil || backup.TimeRange.Start == nil || backup.TimeRange.End == nil {
+		return false
+	}
+	return !t.Before(backup.TimeRange.Start.Time) && !t.After(backup.TimeRange.End.Time)
+}
+
+// GetBackup 查询 dataprotection Backup 资源
+func GetBackup(k8sClient *util.K8sClient, namespace, backupName string) (*entity.BackupData, error) {
+	crd := &entity.CustomResourceDefinition{
+		ResourceType:         coreconst.Backup,
+		ResourceName:         backupName,
+		Namespace:            namespace,
+		GroupVersionResource: kbtypes.BackupGVR(),
+	}
+	backup, err := GetCRD(k8sClient, crd)
+	if err != nil {
+		return nil, err
+	}
+	return entity.GetBackupData(backup)
+}
+
+// ListBackups 查询集群的备份列表，按创建时间倒序
+func ListBackups(k8sClient *util.K8sClient, namespace, clusterName string) ([]*entity.BackupData, error) {
+	crd := &entity.CustomResourceDefinition{
+		ResourceType:         coreconst.Backup,
+		Namespace:            namespace,
+		GroupVersionResource: kbtypes.BackupGVR(),
+		Labels:               map[string]string{coreconst.InstanceName: clusterName},
+	}
+	list, err := ListCRD(k8sClient, crd)
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]*entity.BackupData, 0, len(list.Items))
+	for i := range list.Items {
+		backup, err := entity.GetBackupData(&list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup)
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[j].CreateTime.Before(&backups[i].CreateTime)
+	})
+	return backups, nil
+}
+
+// ListBackupPolicies 查询集群的备份策略
+func ListBackupPolicies(k8sClient *util.K8sClient, namespace, clusterName string) ([]dpv1.BackupPolicy, error) {
+	crd := &entity.CustomResourceDefinition{
+		ResourceType:         coreconst.BackupPolicy,
+		Namespace:            namespace,
+		GroupVersionResource: kbtypes.BackupPolicyGVR(),
+		Labels:               map[string]string{coreconst.InstanceName: clusterName},
+	}
+	list, err := ListCRD(k8sClient, crd)
+	if err != nil {
+		return nil, err
+	}
+	policies := make([]dpv1.BackupPolicy, 0, len(list.Items))
+	for _, item := range list.Items {
+		var policy dpv1.BackupPolicy
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &policy); err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
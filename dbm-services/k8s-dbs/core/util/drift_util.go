@@ -0,0 +1,255 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	commutil "k8s-dbs/common/util"
+	coreconst "k8s-dbs/core/constant"
+	"k8s-dbs/core/entity"
+	metaentity "k8s-dbs/metadata/entity"
+	"sort"
+
+	kbtypes "github.com/apecloud/kbcli/pkg/types"
+	kbv1 "github.com/apecloud/kubeblocks/apis/apps/v1alpha1"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ListKbClusters 查询 k8s 集群所有命名空间下的 kubeblocks 集群
+func ListKbClusters(k8sClient *commutil.K8sClient) ([]*kbv1.Cluster, error) {
+	clusterList, err := ListCRD(k8sClient, &entity.CustomResourceDefinition{
+		GroupVersionResource: kbtypes.ClusterGVR(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	clusters := make([]*kbv1.Cluster, 0, len(clusterList.Items))
+	for _, item := range clusterList.Items {
+		cluster := &kbv1.Cluster{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, cluster); err != nil {
+			return nil, fmt.Errorf("failed to convert cluster %s: %w", item.GetName(), err)
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil
+}
+
+// ListHelmReleases 查询 k8s 集群所有命名空间下的 helm release
+func ListHelmReleases(k8sClient *commutil.K8sClient) ([]*release.Release, error) {
+	actionConfig, err := k8sClient.BuildHelmConfig("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to build helm config: %w", err)
+	}
+	listAction := action.NewList(actionConfig)
+	listAction.AllNamespaces = true
+	listAction.All = true
+	return listAction.Run()
+}
+
+// ReleaseValuesJSON 获取 release 的自定义 values，格式与 release 元数据中的 chartValues 一致
+func ReleaseValuesJSON(rel *release.Release) (string, error) {
+	values, err := json.Marshal(rel.Config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal release values: %w", err)
+	}
+	return string(values), nil
+}
+
+// DiffClusterMetadata 对比一个 k8s 集群上的元数据与实际资源，返回差异列表
+// 比较的内容:
+//  1. 集群是否存在，以及 terminationPolicy 是否一致
+//  2. 集群的组件是否一致，组件元数据名称为 {clusterName}-{componentName}，分片组件按 sharding 名称比较
+//  3. 集群 release 是否存在，以及 chart 版本是否一致
+func DiffClusterMetadata(
+	metaState *entity.DriftMetaState,
+	actualState *entity.DriftActualState,
+) []*metaentity.K8sClusterDriftEntity {
+	var drifts []*metaentity.K8sClusterDriftEntity
+
+	actualClusters := make(map[string]*kbv1.Cluster, len(actualState.Clusters))
+	for _, cluster := range actualState.Clusters {
+		actualClusters[namespacedName(cluster.Namespace, cluster.Name)] = cluster
+	}
+	metaClusters := make(map[string]*metaentity.K8sCrdClusterEntity, len(metaState.Clusters))
+	for _, metaCluster := range metaState.Clusters {
+		key := namespacedName(metaCluster.Namespace, metaCluster.ClusterName)
+		metaClusters[key] = metaCluster
+		cluster, ok := actualClusters[key]
+		if !ok {
+			drifts = append(drifts, &metaentity.K8sClusterDriftEntity{
+				CrdClusterID: metaCluster.ID,
+				ClusterName:  metaCluster.ClusterName,
+				Namespace:    metaCluster.Namespace,
+				ResourceType: coreconst.DriftResourceCluster,
+				ResourceName: metaCluster.ClusterName,
+				DriftType:    coreconst.ClusterMissingInK8s,
+				MetaValue:    metaCluster.ClusterName,
+				Description:  "元数据中的集群在 k8s 中不存在",
+			})
+			continue
+		}
+		drifts = append(drifts, diffCluster(metaCluster, metaState.Components[metaCluster.ID], cluster)...)
+	}
+	for key, cluster := range actualClusters {
+		if _, ok := metaClusters[key]; ok {
+			continue
+		}
+		drifts = append(drifts, &metaentity.K8sClusterDriftEntity{
+			ClusterName:  cluster.Name,
+			Namespace:    cluster.Namespace,
+			ResourceType: coreconst.DriftResourceCluster,
+			ResourceName: cluster.Name,
+			DriftType:    coreconst.ClusterMissingInMeta,
+			ActualValue:  cluster.Name,
+			Description:  "k8s 中的集群没有元数据",
+		})
+	}
+
+	drifts = append(drifts, diffReleases(metaClusters, metaState.Releases, actualState.Releases)...)
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].DriftKey() < drifts[j].DriftKey()
+	})
+	return drifts
+}
+
+// diffCluster 对比集群的配置和组件
+func diffCluster(
+	metaCluster *metaentity.K8sCrdClusterEntity,
+	metaComponents []*metaentity.K8sCrdComponentEntity,
+	cluster *kbv1.Cluster,
+) []*metaentity.K8sClusterDriftEntity {
+	var drifts []*metaentity.K8sClusterDriftEntity
+	newDrift := func(resourceType, resourceName, driftType, metaValue, actualValue, description string) {
+		drifts = append(drifts, &metaentity.K8sClusterDriftEntity{
+			CrdClusterID: metaCluster.ID,
+			ClusterName:  metaCluster.ClusterName,
+			Namespace:    metaCluster.Namespace,
+			ResourceType: resourceType,
+			ResourceName: resourceName,
+			DriftType:    driftType,
+			MetaValue:    metaValue,
+			ActualValue:  actualValue,
+			Description:  description,
+		})
+	}
+
+	metaPolicy, actualPolicy := string(metaCluster.TerminationPolicy), string(cluster.Spec.TerminationPolicy)
+	if metaPolicy != "" && metaPolicy != actualPolicy {
+		newDrift(coreconst.DriftResourceCluster, metaCluster.ClusterName, coreconst.TerminationPolicyDrift,
+			metaPolicy, actualPolicy, "集群 terminationPolicy 与元数据不一致")
+	}
+
+	actualComponents := make(map[string]struct{}, len(cluster.Spec.ComponentSpecs)+len(cluster.Spec.ShardingSpecs))
+	for _, compSpec := range cluster.Spec.ComponentSpecs {
+		actualComponents[cluster.Name+"-"+compSpec.Name] = struct{}{}
+	}
+	for _, shardingSpec := range cluster.Spec.ShardingSpecs {
+		actualComponents[cluster.Name+"-"+shardingSpec.Name] = struct{}{}
+	}
+	metaComponentNames := make(map[string]struct{}, len(metaComponents))
+	for _, metaComponent := range metaComponents {
+		metaComponentNames[metaComponent.ComponentName] = struct{}{}
+		if _, ok := actualComponents[metaComponent.ComponentName]; !ok {
+			newDrift(coreconst.DriftResourceComponent, metaComponent.ComponentName, coreconst.ComponentMissingInK8s,
+				metaComponent.ComponentName, "", "元数据中的组件在 k8s 集群中不存在")
+		}
+	}
+	for componentName := range actualComponents {
+		if _, ok := metaComponentNames[componentName]; !ok {
+			newDrift(coreconst.DriftResourceComponent, componentName, coreconst.ComponentMissingInMeta,
+				"", componentName, "k8s 集群中的组件没有元数据")
+		}
+	}
+	return drifts
+}
+
+// diffReleases 对比集群 release，只关心有集群元数据的 release，addon 等其他 release 不在比较范围内
+func diffReleases(
+	metaClusters map[string]*metaentity.K8sCrdClusterEntity,
+	metaReleases []*metaentity.AddonClusterReleaseEntity,
+	actualReleases []*release.Release,
+) []*metaentity.K8sClusterDriftEntity {
+	var drifts []*metaentity.K8sClusterDriftEntity
+	releases := make(map[string]*release.Release, len(actualReleases))
+	for _, rel := range actualReleases {
+		releases[namespacedName(rel.Namespace, rel.Name)] = rel
+	}
+	metaReleaseKeys := make(map[string]struct{}, len(metaReleases))
+	for _, metaRelease := range metaReleases {
+		key := namespacedName(metaRelease.Namespace, metaRelease.ReleaseName)
+		metaReleaseKeys[key] = struct{}{}
+		drift := &metaentity.K8sClusterDriftEntity{
+			ClusterName:  metaRelease.ReleaseName,
+			Namespace:    metaRelease.Namespace,
+			ResourceType: coreconst.DriftResourceRelease,
+			ResourceName: metaRelease.ReleaseName,
+			MetaValue:    metaRelease.ChartVersion,
+		}
+		if metaCluster, ok := metaClusters[key]; ok {
+			drift.CrdClusterID = metaCluster.ID
+		}
+		rel, ok := releases[key]
+		if !ok {
+			drift.DriftType = coreconst.ReleaseMissingInK8s
+			drift.Description = "元数据中的 release 在 k8s 中不存在"
+			drifts = append(drifts, drift)
+			continue
+		}
+		if chartVersion := releaseChartVersion(rel); chartVersion != metaRelease.ChartVersion {
+			drift.DriftType = coreconst.ReleaseVersionDrift
+			drift.ActualValue = chartVersion
+			drift.Description = "release chart 版本与元数据不一致"
+			drifts = append(drifts, drift)
+		}
+	}
+	for key, metaCluster := range metaClusters {
+		if _, ok := metaReleaseKeys[key]; ok {
+			continue
+		}
+		if _, ok := releases[key]; !ok {
+			continue
+		}
+		drifts = append(drifts, &metaentity.K8sClusterDriftEntity{
+			CrdClusterID: metaCluster.ID,
+			ClusterName:  metaCluster.ClusterName,
+			Namespace:    metaCluster.Namespace,
+			ResourceType: coreconst.DriftResourceRelease,
+			ResourceName: metaCluster.ClusterName,
+			DriftType:    coreconst.ReleaseMissingInMeta,
+			ActualValue:  releaseChartVersion(releases[key]),
+			Description:  "集群 release 没有元数据",
+		})
+	}
+	return drifts
+}
+
+func releaseChartVersion(rel *release.Release) string {
+	if rel.Chart == nil || rel.Chart.Metadata == nil {
+		return ""
+	}
+	return rel.Chart.Metadata.Version
+}
+
+func namespacedName(namespace, name string) string {
+	return namespace + "/" + name
+}
@@ -492,6 +492,128 @@ func CreateVolumeExpansionObject(request *entity.Request, clusterObject *kbv1.Cl
 	return crd, err
 }
 
+// CreateSwitchoverObject 创建主从切换操作请求对象
+// 未指定 instanceName 时使用组件当前的主节点，未指定 candidateName 时由 kubeblocks 选择新的主节点
+// 参数:
+//
+//	k8sClient - k8s 客户端，用于查询组件实例
+//	request - 包含操作请求信息的结构体
+//
+// 返回值:
+//
+//	*entity.CustomResourceDefinition - 创建的CRD对象
+//	error - 错误信息(如果有)
+func CreateSwitchoverObject(k8sClient *commutil.K8sClient, request *entity.Request) (
+	*entity.CustomResourceDefinition, error,
+) {
+	if len(request.SwitchoverList) == 0 {
+		return nil, fmt.Errorf("主从切换参数 switchover 不能为空")
+	}
+	objectName := util.ResourceName("ops-switchover-", OpsNameSuffixLength)
+	switchoverList := make([]opv1.Switchover, 0, len(request.SwitchoverList))
+	for _, item := range request.SwitchoverList {
+		switchover, err := checkSwitchover(k8sClient, request, item)
+		if err != nil {
+			return nil, err
+		}
+		switchoverList = append(switchoverList, *switchover)
+	}
+
+	switchover := &opv1.OpsRequest{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: coreconst.APIVersion,
+			Kind:       coreconst.OpsRequest,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      objectName,
+			Namespace: request.Metadata.Namespace,
+		},
+		Spec: opv1.OpsRequestSpec{
+			ClusterName:                 request.Metadata.ClusterName,
+			Type:                        coreconst.Switchover,
+			TTLSecondsAfterSucceed:      TTLSecondsAfterSucceed,
+			PreConditionDeadlineSeconds: util.Int32Ptr(PreConditionDeadlineSeconds),
+			TimeoutSeconds:              util.Int32Ptr(TimeoutSeconds),
+			SpecificOpsRequest: opv1.SpecificOpsRequest{
+				SwitchoverList: switchoverList,
+			},
+		},
+	}
+
+	unstructuredObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&switchover)
+	if err != nil {
+		return nil, fmt.Errorf("转换对象为Unstructured类型失败: %v", err)
+	}
+	crd := &entity.CustomResourceDefinition{
+		Namespace:            request.Metadata.Namespace,
+		ResourceType:         coreconst.Switchover,
+		ResourceName:         objectName,
+		GroupVersionResource: kbtypes.OpsGVR(),
+		ResourceObject: &unstructured.Unstructured{
+			Object: unstructuredObj,
+		},
+	}
+	return crd, nil
+}
+
+// checkSwitchover 校验切换的原主节点和候选节点属于该组件，并补全原主节点
+func checkSwitchover(k8sClient *commutil.K8sClient, request *entity.Request, item opv1.Switchover) (
+	*opv1.Switchover, error,
+) {
+	if item.ComponentName == "" {
+		return nil, fmt.Errorf("主从切换的组件名称 componentName 不能为空")
+	}
+	crd := &entity.CustomResourceDefinition{
+		GroupVersionResource: kbtypes.PodGVR(),
+		Namespace:            request.Metadata.Namespace,
+		Labels: map[string]string{
+			coreconst.InstanceName:  request.Metadata.ClusterName,
+			coreconst.ComponentName: item.ComponentName,
+		},
+	}
+	podList, err := ListCRD(k8sClient, crd)
+	if err != nil {
+		return nil, err
+	}
+	if len(podList.Items) == 0 {
+		return nil, fmt.Errorf("组件 %s 没有实例", item.ComponentName)
+	}
+
+	podRoles := make(map[string]string, len(podList.Items))
+	for _, podItem := range podList.Items {
+		pod, err := ConvertUnstructuredToPod(podItem)
+		if err != nil {
+			return nil, err
+		}
+		podRoles[pod.Name] = GetPodRole(pod)
+	}
+
+	switchover := item.DeepCopy()
+	if switchover.InstanceName == "" {
+		for podName, role := range podRoles {
+			if _, ok := coreconst.PrimaryRoles[role]; ok {
+				switchover.InstanceName = podName
+				break
+			}
+		}
+		if switchover.InstanceName == "" {
+			return nil, fmt.Errorf("未找到组件 %s 的主节点，请指定 instanceName", item.ComponentName)
+		}
+	} else if _, ok := podRoles[switchover.InstanceName]; !ok {
+		return nil, fmt.Errorf("实例 %s 不属于组件 %s", switchover.InstanceName, item.ComponentName)
+	}
+
+	if switchover.CandidateName != "" {
+		if _, ok := podRoles[switchover.CandidateName]; !ok {
+			return nil, fmt.Errorf("候选实例 %s 不属于组件 %s", switchover.CandidateName, item.ComponentName)
+		}
+		if switchover.CandidateName == switchover.InstanceName {
+			return nil, fmt.Errorf("候选实例 %s 已经是主节点", switchover.CandidateName)
+		}
+	}
+	return switchover, nil
+}
+
 // CreateExposeClusterObject 创建暴露服务操作请求对象
 // 参数:
 //
@@ -0,0 +1,297 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"k8s-dbs/common/util"
+	coreconst "k8s-dbs/core/constant"
+	"k8s-dbs/core/entity"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	kbtypes "github.com/apecloud/kbcli/pkg/types"
+	kbv1 "github.com/apecloud/kubeblocks/apis/apps/v1alpha1"
+	opv1 "github.com/apecloud/kubeblocks/apis/operations/v1alpha1"
+	paramv1 "github.com/apecloud/kubeblocks/apis/parameters/v1alpha1"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ParamConfigRendererGVR kbcli v0.9.3 未提供 kubeblocks 1.0 参数相关资源的 GVR
+func ParamConfigRendererGVR() schema.GroupVersionResource {
+	return paramv1.GroupVersion.WithResource("paramconfigrenderers")
+}
+
+// ParametersDefinitionGVR kbcli v0.9.3 未提供 kubeblocks 1.0 参数相关资源的 GVR
+func ParametersDefinitionGVR() schema.GroupVersionResource {
+	return paramv1.GroupVersion.WithResource("parametersdefinitions")
+}
+
+// CreateReconfigureObject 创建参数变更操作请求对象
+// 变更的参数需要在组件对应的 ParametersDefinition 中定义，是否需要重启实例记录在 opsRequest 的注解中
+// 参数:
+//
+//	k8sClient - k8s 客户端，用于查询参数定义
+//	request - 包含操作请求信息的结构体
+//	cluster - 集群信息，用于获取组件的 componentDef 和 serviceVersion
+//
+// 返回值:
+//
+//	*entity.CustomResourceDefinition - 创建的CRD对象
+//	error - 错误信息(如果有)
+func CreateReconfigureObject(k8sClient *util.K8sClient, request *entity.Request, cluster *kbv1.Cluster) (
+	*entity.CustomResourceDefinition, error,
+) {
+	if len(request.Reconfigures) == 0 {
+		return nil, fmt.Errorf("参数变更参数 reconfigures 不能为空")
+	}
+	var restartParams []string
+	for _, reconfigure := range request.Reconfigures {
+		if len(reconfigure.Parameters) == 0 {
+			return nil, fmt.Errorf("组件 %s 变更的参数不能为空", reconfigure.ComponentName)
+		}
+		compSpec := findComponentSpec(cluster, reconfigure.ComponentName)
+		if compSpec == nil {
+			return nil, fmt.Errorf("集群 %s 不存在组件 %s", cluster.Name, reconfigure.ComponentName)
+		}
+		paramsDefs, err := getParametersDefinitions(k8sClient, compSpec)
+		if err != nil {
+			return nil, err
+		}
+		if len(paramsDefs) == 0 {
+			return nil, fmt.Errorf("组件 %s 没有参数定义，不支持参数变更", reconfigure.ComponentName)
+		}
+		for _, param := range reconfigure.Parameters {
+			needRestart, err := checkParameter(paramsDefs, param)
+			if err != nil {
+				return nil, fmt.Errorf("组件 %s 参数校验失败: %w", reconfigure.ComponentName, err)
+			}
+			if needRestart {
+				restartParams = append(restartParams, param.Key)
+			}
+		}
+	}
+
+	objectName := util.ResourceName("ops-reconfigure-", OpsNameSuffixLength)
+	reconfigure := &opv1.OpsRequest{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: coreconst.APIVersion,
+			Kind:       coreconst.OpsRequest,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      objectName,
+			Namespace: request.Metadata.Namespace,
+			Annotations: map[string]string{
+				coreconst.RestartRequired: strconv.FormatBool(len(restartParams) > 0),
+				coreconst.RestartParams:   strings.Join(restartParams, ","),
+			},
+		},
+		Spec: opv1.OpsRequestSpec{
+			ClusterName:                 request.Metadata.ClusterName,
+			Type:                        coreconst.Reconfiguring,
+			TTLSecondsAfterSucceed:      TTLSecondsAfterSucceed,
+			PreConditionDeadlineSeconds: util.Int32Ptr(PreConditionDeadlineSeconds),
+			TimeoutSeconds:              util.Int32Ptr(TimeoutSeconds),
+			SpecificOpsRequest: opv1.SpecificOpsRequest{
+				Reconfigures: request.Reconfigures,
+			},
+		},
+	}
+
+	unstructuredObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&reconfigure)
+	if err != nil {
+		return nil, fmt.Errorf("转换对象为Unstructured类型失败: %v", err)
+	}
+	crd := &entity.CustomResourceDefinition{
+		Namespace:            request.Metadata.Namespace,
+		ResourceType:         coreconst.Reconfiguring,
+		ResourceName:         objectName,
+		GroupVersionResource: kbtypes.OpsGVR(),
+		ResourceObject: &unstructured.Unstructured{
+			Object: unstructuredObj,
+		},
+	}
+	return crd, nil
+}
+
+func findComponentSpec(cluster *kbv1.Cluster, componentName string) *kbv1.ClusterComponentSpec {
+	for i := range cluster.Spec.ComponentSpecs {
+		if cluster.Spec.ComponentSpecs[i].Name == componentName {
+			return &cluster.Spec.ComponentSpecs[i]
+		}
+	}
+	return nil
+}
+
+// getParametersDefinitions 通过 ParamConfigRenderer 查找组件使用的参数定义
+func getParametersDefinitions(k8sClient *util.K8sClient, compSpec *kbv1.ClusterComponentSpec) (
+	[]*paramv1.ParametersDefinition, error,
+) {
+	rendererList, err := ListCRD(k8sClient, &entity.CustomResourceDefinition{
+		ResourceType:         coreconst.ParamConfigRenderer,
+		GroupVersionResource: ParamConfigRendererGVR(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询 ParamConfigRenderer 失败: %w", err)
+	}
+	var paramsDefs []*paramv1.ParametersDefinition
+	for _, item := range rendererList.Items {
+		var renderer paramv1.ParamConfigRenderer
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &renderer); err != nil {
+			return nil, err
+		}
+		if !prefixOrRegexMatched(compSpec.ComponentDef, renderer.Spec.ComponentDef) {
+			continue
+		}
+		if renderer.Spec.ServiceVersion != "" && compSpec.ServiceVersion != "" &&
+			renderer.Spec.ServiceVersion != compSpec.ServiceVersion {
+			continue
+		}
+		for _, defName := range renderer.Spec.ParametersDefs {
+			defCR, err := GetCRD(k8sClient, &entity.CustomResourceDefinition{
+				ResourceType:         coreconst.ParametersDef,
+				ResourceName:         defName,
+				GroupVersionResource: ParametersDefinitionGVR(),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("查询 ParametersDefinition %s 失败: %w", defName, err)
+			}
+			paramsDef := &paramv1.ParametersDefinition{}
+			if err = runtime.DefaultUnstructuredConverter.FromUnstructured(defCR.Object, paramsDef); err != nil {
+				return nil, err
+			}
+			paramsDefs = append(paramsDefs, paramsDef)
+		}
+	}
+	return paramsDefs, nil
+}
+
+// prefixOrRegexMatched 与 kubeblocks 匹配 componentDef 的规则一致，支持前缀和正则
+// 未指定 componentDef 的 renderer 不匹配任何组件
+func prefixOrRegexMatched(defName, defNamePattern string) bool {
+	if defNamePattern == "" {
+		return false
+	}
+	if strings.HasPrefix(defName, defNamePattern) {
+		return true
+	}
+	if regexp.QuoteMeta(defNamePattern) == defNamePattern {
+		return false
+	}
+	regex, err := regexp.Compile(defNamePattern)
+	if err != nil {
+		return false
+	}
+	return regex.MatchString(defName)
+}
+
+// checkParameter 校验参数是否可以修改及取值是否合法，返回修改后是否需要重启实例
+func checkParameter(paramsDefs []*paramv1.ParametersDefinition, param opv1.ParameterPair) (bool, error) {
+	for _, paramsDef := range paramsDefs {
+		spec := paramsDef.Spec
+		if slices.Contains(spec.ImmutableParameters, param.Key) {
+			return false, fmt.Errorf("参数 %s 不允许修改", param.Key)
+		}
+		prop := findSchemaProperty(spec.ParametersSchema, param.Key)
+		if prop == nil && !slices.Contains(spec.StaticParameters, param.Key) &&
+			!slices.Contains(spec.DynamicParameters, param.Key) {
+			continue
+		}
+		if prop != nil && param.Value != nil {
+			if err := checkParameterValue(prop, param.Key, *param.Value); err != nil {
+				return false, err
+			}
+		}
+		return needRestart(&spec, param.Key), nil
+	}
+	return false, fmt.Errorf("参数 %s 未定义", param.Key)
+}
+
+// needRestart 与 kubeblocks 判断参数是否需要重启的规则一致
+func needRestart(spec *paramv1.ParametersDefinitionSpec, key string) bool {
+	if spec.ReloadAction == nil || slices.Contains(spec.StaticParameters, key) {
+		return true
+	}
+	if len(spec.DynamicParameters) > 0 {
+		return !slices.Contains(spec.DynamicParameters, key)
+	}
+	return len(spec.StaticParameters) == 0
+}
+
+// findSchemaProperty 查找参数的 schema 定义，参数可能在顶层或者下一层对象中（如 spec、mysqld）
+func findSchemaProperty(paramsSchema *paramv1.ParametersSchema, key string) *apiext.JSONSchemaProps {
+	if paramsSchema == nil || paramsSchema.SchemaInJSON == nil {
+		return nil
+	}
+	props := paramsSchema.SchemaInJSON.Properties
+	if prop, ok := props[key]; ok {
+		return &prop
+	}
+	for _, prop := range props {
+		if prop.Type != "object" {
+			continue
+		}
+		if sub, ok := prop.Properties[key]; ok {
+			return &sub
+		}
+	}
+	return nil
+}
+
+// checkParameterValue 校验参数取值的类型、枚举和范围
+func checkParameterValue(prop *apiext.JSONSchemaProps, key, value string) error {
+	if len(prop.Enum) > 0 {
+		for _, enum := range prop.Enum {
+			var enumValue interface{}
+			if err := json.Unmarshal(enum.Raw, &enumValue); err != nil {
+				continue
+			}
+			if fmt.Sprint(enumValue) == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("参数 %s 的取值 %s 不在可选范围内", key, value)
+	}
+	switch prop.Type {
+	case "integer", "number":
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || (prop.Type == "integer" && number != float64(int64(number))) {
+			return fmt.Errorf("参数 %s 的取值 %s 不是合法的 %s", key, value, prop.Type)
+		}
+		if prop.Minimum != nil && number < *prop.Minimum {
+			return fmt.Errorf("参数 %s 的取值 %s 小于最小值 %v", key, value, *prop.Minimum)
+		}
+		if prop.Maximum != nil && number > *prop.Maximum {
+			return fmt.Errorf("参数 %s 的取值 %s 大于最大值 %v", key, value, *prop.Maximum)
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("参数 %s 的取值 %s 不是合法的 boolean", key, value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	commutil "k8s-dbs/common/util"
+	coreconst "k8s-dbs/core/constant"
+	"k8s-dbs/core/entity"
+	metaentity "k8s-dbs/metadata/entity"
+	dbsmetric "k8s-dbs/metric"
+	"log/slog"
+	"sync"
+
+	kbtypes "github.com/apecloud/kbcli/pkg/types"
+
+	kbv1 "github.com/apecloud/kubeblocks/apis/apps/v1alpha1"
+	opv1 "github.com/apecloud/kubeblocks/apis/operations/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ClusterRequestedResource 统计 kubeblocks 集群分配的资源总量，包括所有组件和分片
+func ClusterRequestedResource(cluster *kbv1.Cluster) entity.ResourceAmount {
+	var total entity.ResourceAmount
+	for i := range cluster.Spec.ComponentSpecs {
+		compSpec := &cluster.Spec.ComponentSpecs[i]
+		total.Add(replicaResourceFromSpec(compSpec).Scale(compSpec.Replicas))
+	}
+	for i := range cluster.Spec.ShardingSpecs {
+		shardingSpec := &cluster.Spec.ShardingSpecs[i]
+		total.Add(replicaResourceFromSpec(&shardingSpec.Template).
+			Scale(shardingSpec.Template.Replicas * shardingSpec.Shards))
+	}
+	return total
+}
+
+// CreateClusterResourceDelta 计算创建集群请求需要的资源量
+func CreateClusterResourceDelta(request *entity.Request) entity.ResourceAmount {
+	var delta entity.ResourceAmount
+	for i := range request.ComponentList {
+		comp := &request.ComponentList[i]
+		replicaResource := replicaResourceFromRequest(comp.Request, comp.Limit)
+		storage := comp.Storage
+		if comp.VolumeClaimTemplates != nil && !comp.VolumeClaimTemplates.Storage.IsZero() {
+			storage = comp.VolumeClaimTemplates.Storage
+		}
+		replicaResource.Storage = commutil.ConvertMemoryToGB(&storage)
+		delta.Add(replicaResource.Scale(comp.Replicas))
+	}
+	return delta
+}
+
+// VerticalScalingResourceDelta 计算垂直扩缩容请求相对集群当前规格的资源变化量
+func VerticalScalingResourceDelta(request *entity.Request, cluster *kbv1.Cluster) entity.ResourceAmount {
+	var delta entity.ResourceAmount
+	for i := range request.ComponentList {
+		comp := &request.ComponentList[i]
+		compSpec, instances := findScalableSpec(cluster, comp.ComponentName)
+		if compSpec == nil {
+			continue
+		}
+		current := replicaResourceFromSpec(compSpec)
+		target := replicaResourceFromRequest(comp.Request, comp.Limit)
+		if comp.Request == nil && comp.Limit == nil {
+			target = current
+		}
+		// 垂直扩缩容不涉及存储
+		target.Storage = current.Storage
+		delta.Add(target.Sub(current).Scale(instances))
+	}
+	return delta
+}
+
+// HorizontalScalingResourceDelta 计算水平扩缩容请求的资源变化量，缩容时为负值
+func HorizontalScalingResourceDelta(request *entity.Request, cluster *kbv1.Cluster) entity.ResourceAmount {
+	var delta entity.ResourceAmount
+	for i := range request.HorizontalScalingList {
+		hs := &request.HorizontalScalingList[i]
+		compSpec, _ := findScalableSpec(cluster, hs.ComponentName)
+		if compSpec == nil {
+			continue
+		}
+		replicaResource := replicaResourceFromSpec(compSpec)
+		shardingSpec := findShardingSpec(cluster, hs.ComponentName)
+		if shardingSpec == nil {
+			delta.Add(replicaResource.Scale(replicaChanges(hs)))
+			continue
+		}
+		// 分片数变更，新增分片的副本数与模板一致
+		shards := shardingSpec.Shards
+		if hs.Shards != nil {
+			delta.Add(replicaResource.Scale((*hs.Shards - shards) * shardingSpec.Template.Replicas))
+			shards = *hs.Shards
+		}
+		// 分片集群的副本数变更作用于每个分片
+		delta.Add(replicaResource.Scale(replicaChanges(hs) * shards))
+	}
+	return delta
+}
+
+// VolumeExpansionResourceDelta 计算磁盘扩容请求的存储增量，请求中的 storage 为每个存储卷的增量
+func VolumeExpansionResourceDelta(request *entity.Request, cluster *kbv1.Cluster) entity.ResourceAmount {
+	var delta entity.ResourceAmount
+	for i := range request.ComponentList {
+		comp := &request.ComponentList[i]
+		compSpec, instances := findScalableSpec(cluster, comp.ComponentName)
+		if compSpec == nil {
+			continue
+		}
+		storage := commutil.ConvertMemoryToGB(&comp.Storage) * float64(len(compSpec.VolumeClaimTemplates))
+		delta.Add(entity.ResourceAmount{Storage: storage}.Scale(instances))
+	}
+	return delta
+}
+
+// CheckResourceQuota 检查已分配资源加上本次申请是否超出配额，配额为 0 的资源项不限制
+func CheckResourceQuota(
+	quota *metaentity.ResourceQuotaEntity,
+	used entity.ResourceAmount,
+	delta entity.ResourceAmount,
+) *entity.QuotaCheckResult {
+	result := &entity.QuotaCheckResult{
+		QuotaType: quota.QuotaType,
+		BkBizID:   quota.BkBizID,
+		Namespace: quota.Namespace,
+	}
+	items := []struct {
+		name                   string
+		used, requested, limit float64
+	}{
+		{coreconst.QuotaResourceCPU, used.CPU, delta.CPU, quota.CPU},
+		{coreconst.QuotaResourceMemory, used.Memory, delta.Memory, quota.Memory},
+		{coreconst.QuotaResourceStorage, used.Storage, delta.Storage, quota.Storage},
+	}
+	for _, item := range items {
+		if item.limit <= 0 {
+			continue
+		}
+		result.Items = append(result.Items, &entity.QuotaCheckItem{
+			Resource:  item.name,
+			Used:      commutil.RoundToDecimal(item.used, 3),
+			Requested: commutil.RoundToDecimal(item.requested, 3),
+			Quota:     item.limit,
+			// 只减少资源的项即使已超出配额也允许执行，便于回收资源
+			Exceeded: item.requested > 0 && item.used+item.requested > item.limit,
+		})
+	}
+	return result
+}
+
+// storageUsageQueryConcurrency 统计集群存储使用量时并发查询 Pod 的数量
+const storageUsageQueryConcurrency = 8
+
+// ClusterStorageUsed 汇总集群所有 Pod 的存储实际使用量，单位 GB，部分 Pod 获取失败时 complete 返回 false
+func ClusterStorageUsed(
+	k8sClient *commutil.K8sClient,
+	addonType string,
+	k8sClusterName string,
+	namespace string,
+	clusterName string,
+) (storageUsed float64, complete bool, err error) {
+	podList, err := ListCRD(k8sClient, &entity.CustomResourceDefinition{
+		GroupVersionResource: kbtypes.PodGVR(),
+		Namespace:            namespace,
+		Labels: map[string]string{
+			coreconst.InstanceName: clusterName,
+		},
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to list pods of cluster %s: %w", clusterName, err)
+	}
+	pods := make([]*corev1.Pod, 0, len(podList.Items))
+	for _, item := range podList.Items {
+		pod := &corev1.Pod{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, pod); err != nil {
+			return 0, false, fmt.Errorf("failed to convert pod %s: %w", item.GetName(), err)
+		}
+		pods = append(pods, pod)
+	}
+
+	// 单个 pod 的查询带重试，逐个查询耗时随 pod 数线性增长，这里并发查询
+	storageGBs := make([]float64, len(pods))
+	errs := make([]error, len(pods))
+	sem := make(chan struct{}, storageUsageQueryConcurrency)
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, pod *corev1.Pod) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			storageGBs[i], errs[i] = dbsmetric.FetcherFactory.GetStorageUsage(&dbsmetric.ClusterMetricQueryParams{
+				AddonType:      addonType,
+				K8sClusterName: k8sClusterName,
+				Namespace:      pod.Namespace,
+				PodName:        pod.Name,
+				JobName:        fmt.Sprintf("%s-headless", pod.Labels["workloads.kubeblocks.io/instance"]),
+			})
+		}(i, pod)
+	}
+	wg.Wait()
+
+	complete = true
+	for i, pod := range pods {
+		if errs[i] != nil {
+			slog.Warn("failed to get storage usage", "podName", pod.Name, "error", errs[i])
+			complete = false
+			continue
+		}
+		storageUsed += storageGBs[i]
+	}
+	return commutil.RoundToDecimal(storageUsed, 3), complete, nil
+}
+
+// replicaResourceFromSpec 计算组件单个副本分配的资源，未设置 requests 时使用 limits
+func replicaResourceFromSpec(compSpec *kbv1.ClusterComponentSpec) entity.ResourceAmount {
+	cpu := resourceFromList(compSpec.Resources, corev1.ResourceCPU)
+	memory := resourceFromList(compSpec.Resources, corev1.ResourceMemory)
+	var storage float64
+	for _, vct := range compSpec.VolumeClaimTemplates {
+		storage += commutil.ConvertMemoryToGB(vct.Spec.Resources.Requests.Storage())
+	}
+	return entity.ResourceAmount{
+		CPU:     commutil.ConvertCPUToCores(&cpu),
+		Memory:  commutil.ConvertMemoryToGB(&memory),
+		Storage: storage,
+	}
+}
+
+// replicaResourceFromRequest 计算请求中单个副本的 CPU 和内存，未设置 request 时使用 limit
+func replicaResourceFromRequest(request, limit *entity.Resource) entity.ResourceAmount {
+	var cpu, memory resource.Quantity
+	if request != nil {
+		cpu, memory = request.CPU, request.Memory
+	}
+	if limit != nil {
+		if cpu.IsZero() {
+			cpu = limit.CPU
+		}
+		if memory.IsZero() {
+			memory = limit.Memory
+		}
+	}
+	return entity.ResourceAmount{
+		CPU:    commutil.ConvertCPUToCores(&cpu),
+		Memory: commutil.ConvertMemoryToGB(&memory),
+	}
+}
+
+func resourceFromList(requirements corev1.ResourceRequirements, name corev1.ResourceName) resource.Quantity {
+	if quantity, ok := requirements.Requests[name]; ok && !quantity.IsZero() {
+		return quantity
+	}
+	return requirements.Limits[name]
+}
+
+// findScalableSpec 按名称查找组件或分片模板，返回模板和实例总数
+func findScalableSpec(cluster *kbv1.Cluster, name string) (*kbv1.ClusterComponentSpec, int32) {
+	if compSpec := findComponentSpec(cluster, name); compSpec != nil {
+		return compSpec, compSpec.Replicas
+	}
+	if shardingSpec := findShardingSpec(cluster, name); shardingSpec != nil {
+		return &shardingSpec.Template, shardingSpec.Template.Replicas * shardingSpec.Shards
+	}
+	return nil, 0
+}
+
+func findShardingSpec(cluster *kbv1.Cluster, name string) *kbv1.ShardingSpec {
+	for i := range cluster.Spec.ShardingSpecs {
+		if cluster.Spec.ShardingSpecs[i].Name == name {
+			return &cluster.Spec.ShardingSpecs[i]
+		}
+	}
+	return nil
+}
+
+// replicaChanges 计算水平扩缩容的副本变化数，扩容为正，缩容为负
+func replicaChanges(hs *opv1.HorizontalScaling) int32 {
+	var changes int32
+	if hs.ScaleOut != nil {
+		changes += changerReplicas(&hs.ScaleOut.ReplicaChanger)
+		for _, instance := range hs.ScaleOut.NewInstances {
+			if instance.Replicas != nil {
+				changes += *instance.Replicas
+			} else {
+				changes++
+			}
+		}
+		changes += int32(len(hs.ScaleOut.OfflineInstancesToOnline))
+	}
+	if hs.ScaleIn != nil {
+		changes -= changerReplicas(&hs.ScaleIn.ReplicaChanger)
+		changes -= int32(len(hs.ScaleIn.OnlineInstancesToOffline))
+	}
+	return changes
+}
+
+func changerReplicas(changer *opv1.ReplicaChanger) int32 {
+	if changer.ReplicaChanges != nil {
+		return *changer.ReplicaChanges
+	}
+	var changes int32
+	for _, instance := range changer.Instances {
+		changes += instance.ReplicaChanges
+	}
+	return changes
+}
@@ -0,0 +1,228 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tests
+
+import (
+	commutil "k8s-dbs/common/util"
+	"k8s-dbs/core/constant"
+	"k8s-dbs/core/entity"
+	"k8s-dbs/core/util"
+	"testing"
+
+	kbtypes "github.com/apecloud/kbcli/pkg/types"
+	opv1 "github.com/apecloud/kubeblocks/apis/operations/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newBackupPolicy(name, clusterName string, methods ...string) *unstructured.Unstructured {
+	backupMethods := make([]interface{}, 0, len(methods))
+	for _, m := range methods {
+		backupMethods = append(backupMethods, map[string]interface{}{"name": m})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": constant.DataProAPIVersion,
+		"kind":       constant.BackupPolicy,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "test-ns",
+			"labels":    map[string]interface{}{constant.InstanceName: clusterName},
+		},
+		"spec": map[string]interface{}{
+			"backupMethods": backupMethods,
+		},
+	}}
+}
+
+func newBackup(name, clusterName, backupType, phase, created, start, end string) *unstructured.Unstructured {
+	status := map[string]interface{}{"phase": phase}
+	if start != "" {
+		status["timeRange"] = map[string]interface{}{"start": start, "end": end}
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": constant.DataProAPIVersion,
+		"kind":       constant.Backup,
+		"metadata": map[string]interface{}{
+			"name":              name,
+			"namespace":         "test-ns",
+			"creationTimestamp": created,
+			"labels": map[string]interface{}{
+				constant.InstanceName: clusterName,
+				constant.BackupType:   backupType,
+			},
+		},
+		"spec": map[string]interface{}{
+			"backupPolicyName": clusterName + "-backup-policy",
+			"backupMethod":     "xtrabackup",
+		},
+		"status": status,
+	}}
+}
+
+func newFakeBackupClient(objects ...runtime.Object) *commutil.K8sClient {
+	fakeClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			kbtypes.BackupGVR():       "BackupList",
+			kbtypes.BackupPolicyGVR(): "BackupPolicyList",
+		}, objects...)
+	return &commutil.K8sClient{DynamicClient: fakeClient}
+}
+
+func newBackupRequest() *entity.Request {
+	return &entity.Request{
+		K8sClusterName: "test-k8s",
+		Metadata: entity.Metadata{
+			ClusterName: "mysql-test",
+			Namespace:   "test-ns",
+		},
+	}
+}
+
+func TestCreateBackupObject(t *testing.T) {
+	k8sClient := newFakeBackupClient(newBackupPolicy("mysql-test-backup-policy", "mysql-test", "xtrabackup"))
+	request := newBackupRequest()
+
+	crd, err := util.CreateBackupObject(k8sClient, request)
+	assert.NoError(t, err)
+	assert.Equal(t, constant.Backup, crd.ResourceType)
+	assert.Equal(t, kbtypes.OpsGVR(), crd.GroupVersionResource)
+
+	var ops opv1.OpsRequest
+	assert.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(crd.ResourceObject.Object, &ops))
+	assert.Equal(t, opv1.OpsType(constant.Backup), ops.Spec.Type)
+	assert.Equal(t, "mysql-test", ops.Spec.ClusterName)
+	assert.Contains(t, ops.Spec.GetBackup().BackupName, "backup-mysql-test-")
+
+	request.Spec.Backup = &opv1.Backup{BackupMethod: "volume-snapshot"}
+	_, err = util.CreateBackupObject(k8sClient, request)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "volume-snapshot")
+
+	request.Spec.Backup = &opv1.Backup{BackupPolicyName: "not-exists"}
+	_, err = util.CreateBackupObject(k8sClient, request)
+	assert.Error(t, err)
+}
+
+func TestCreateBackupObjectWithoutPolicy(t *testing.T) {
+	k8sClient := newFakeBackupClient(newBackupPolicy("other-backup-policy", "other", "xtrabackup"))
+
+	_, err := util.CreateBackupObject(k8sClient, newBackupRequest())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "不支持备份")
+}
+
+func TestCreateRestoreObject(t *testing.T) {
+	k8sClient := newFakeBackupClient(
+		newBackup("full-1", "mysql-test", "Full", "Completed", "2025-08-01T00:00:00Z", "", ""),
+		newBackup("full-2", "mysql-test", "Full", "Failed", "2025-08-02T00:00:00Z", "", ""),
+	)
+	request := newBackupRequest()
+	request.Spec.Restore = &opv1.Restore{BackupName: "full-1"}
+
+	_, err := util.CreateRestoreObject(k8sClient, request)
+	assert.Error(t, err, "restoreClusterName is required")
+
+	request.RestoreClusterName = "mysql-restore"
+	crd, err := util.CreateRestoreObject(k8sClient, request)
+	assert.NoError(t, err)
+	assert.Equal(t, constant.Restore, crd.ResourceType)
+
+	var ops opv1.OpsRequest
+	assert.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(crd.ResourceObject.Object, &ops))
+	assert.Equal(t, "mysql-restore", ops.Spec.ClusterName)
+	assert.Equal(t, "full-1", ops.Spec.GetRestore().BackupName)
+	assert.Equal(t, "test-ns", ops.Spec.GetRestore().BackupNamespace)
+
+	request.Spec.Restore = &opv1.Restore{BackupName: "full-2"}
+	_, err = util.CreateRestoreObject(k8sClient, request)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed")
+}
+
+func TestCreatePointInTimeRestoreObject(t *testing.T) {
+	k8sClient := newFakeBackupClient(
+		newBackup("full-1", "mysql-test", "Full", "Completed", "2025-08-01T00:00:00Z", "", ""),
+		newBackup("binlog-1", "mysql-test", constant.ContinuousBackup, "Running", "2025-08-01T00:00:00Z",
+			"2025-08-01T00:00:00Z", "2025-08-03T00:00:00Z"),
+	)
+	request := newBackupRequest()
+	request.RestoreClusterName = "mysql-restore"
+	request.Spec.Restore = &opv1.Restore{RestorePointInTime: "2025-08-02T12:00:00Z"}
+
+	crd, err := util.CreateRestoreObject(k8sClient, request)
+	assert.NoError(t, err)
+	var ops opv1.OpsRequest
+	assert.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(crd.ResourceObject.Object, &ops))
+	assert.Equal(t, "binlog-1", ops.Spec.GetRestore().BackupName)
+	assert.Equal(t, "2025-08-02T12:00:00Z", ops.Spec.GetRestore().RestorePointInTime)
+
+	request.Spec.Restore = &opv1.Restore{RestorePointInTime: "Aug 02,2025 20:00:00 UTC+0800"}
+	_, err = util.CreateRestoreObject(k8sClient, request)
+	assert.NoError(t, err)
+
+	request.Spec.Restore = &opv1.Restore{RestorePointInTime: "2025-08-05T00:00:00Z"}
+	_, err = util.CreateRestoreObject(k8sClient, request)
+	assert.Error(t, err)
+
+	request.Spec.Restore = &opv1.Restore{BackupName: "full-1", RestorePointInTime: "2025-08-02T12:00:00Z"}
+	_, err = util.CreateRestoreObject(k8sClient, request)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "不是持续备份")
+
+	request.Spec.Restore = &opv1.Restore{RestorePointInTime: "2025/08/02"}
+	_, err = util.CreateRestoreObject(k8sClient, request)
+	assert.Error(t, err)
+}
+
+func TestPointInTimeRestoreNotSupported(t *testing.T) {
+	k8sClient := newFakeBackupClient(
+		newBackup("full-1", "mysql-test", "Full", "Completed", "2025-08-01T00:00:00Z", "", ""),
+	)
+	request := newBackupRequest()
+	request.RestoreClusterName = "mysql-restore"
+	request.Spec.Restore = &opv1.Restore{RestorePointInTime: "2025-08-02T12:00:00Z"}
+
+	_, err := util.CreateRestoreObject(k8sClient, request)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "不支持或未开启按时间点恢复")
+}
+
+func TestListBackups(t *testing.T) {
+	k8sClient := newFakeBackupClient(
+		newBackup("full-1", "mysql-test", "Full", "Completed", "2025-08-01T00:00:00Z", "", ""),
+		newBackup("full-2", "mysql-test", "Full", "Running", "2025-08-02T00:00:00Z", "", ""),
+		newBackup("other-1", "other", "Full", "Completed", "2025-08-03T00:00:00Z", "", ""),
+	)
+
+	backups, err := util.ListBackups(k8sClient, "test-ns", "mysql-test")
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+	assert.Equal(t, "full-2", backups[0].BackupName)
+	assert.Equal(t, "mysql-test", backups[0].ClusterName)
+	assert.Equal(t, "xtrabackup", backups[0].BackupMethod)
+
+	backup, err := util.GetBackup(k8sClient, "test-ns", "full-1")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "Completed", backup.Phase)
+	assert.Equal(t, "Full", backup.BackupType)
+}
@@ -0,0 +1,132 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tests
+
+import (
+	"k8s-dbs/core/constant"
+	"k8s-dbs/core/entity"
+	"k8s-dbs/core/util"
+	metaentity "k8s-dbs/metadata/entity"
+	"testing"
+
+	kbv1 "github.com/apecloud/kubeblocks/apis/apps/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newKbCluster(name, terminationPolicy string, componentNames ...string) *kbv1.Cluster {
+	cluster := &kbv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+		Spec:       kbv1.ClusterSpec{TerminationPolicy: kbv1.TerminationPolicyType(terminationPolicy)},
+	}
+	for _, componentName := range componentNames {
+		cluster.Spec.ComponentSpecs = append(cluster.Spec.ComponentSpecs, kbv1.ClusterComponentSpec{Name: componentName})
+	}
+	return cluster
+}
+
+func newHelmRelease(name, chartVersion string) *release.Release {
+	return &release.Release{
+		Name:      name,
+		Namespace: "test-ns",
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Version: chartVersion}},
+	}
+}
+
+func TestDiffClusterMetadataNoDrift(t *testing.T) {
+	metaState := &entity.DriftMetaState{
+		Clusters: []*metaentity.K8sCrdClusterEntity{
+			{ID: 1, ClusterName: "mysql-test", Namespace: "test-ns", TerminationPolicy: "Delete"},
+		},
+		Components: map[uint64][]*metaentity.K8sCrdComponentEntity{
+			1: {{CrdClusterID: 1, ComponentName: "mysql-test-mysql"}},
+		},
+		Releases: []*metaentity.AddonClusterReleaseEntity{
+			{ReleaseName: "mysql-test", Namespace: "test-ns", ChartVersion: "1.0.0"},
+		},
+	}
+	actualState := &entity.DriftActualState{
+		Clusters: []*kbv1.Cluster{newKbCluster("mysql-test", "Delete", "mysql")},
+		Releases: []*release.Release{newHelmRelease("mysql-test", "1.0.0"), newHelmRelease("kubeblocks", "1.0.0")},
+	}
+	assert.Empty(t, util.DiffClusterMetadata(metaState, actualState))
+}
+
+func TestDiffClusterMetadata(t *testing.T) {
+	metaState := &entity.DriftMetaState{
+		Clusters: []*metaentity.K8sCrdClusterEntity{
+			{ID: 1, ClusterName: "mysql-test", Namespace: "test-ns", TerminationPolicy: "Delete"},
+			{ID: 2, ClusterName: "redis-test", Namespace: "test-ns", TerminationPolicy: "Delete"},
+		},
+		Components: map[uint64][]*metaentity.K8sCrdComponentEntity{
+			1: {{CrdClusterID: 1, ComponentName: "mysql-test-proxy"}},
+		},
+		Releases: []*metaentity.AddonClusterReleaseEntity{
+			{ReleaseName: "mysql-test", Namespace: "test-ns", ChartVersion: "1.0.0"},
+		},
+	}
+	actualState := &entity.DriftActualState{
+		Clusters: []*kbv1.Cluster{
+			newKbCluster("mysql-test", "WipeOut", "mysql"),
+			newKbCluster("pg-test", "Delete", "postgresql"),
+		},
+		Releases: []*release.Release{newHelmRelease("mysql-test", "1.0.1")},
+	}
+
+	drifts := util.DiffClusterMetadata(metaState, actualState)
+	driftTypes := make(map[string]string, len(drifts))
+	for _, drift := range drifts {
+		driftTypes[drift.ResourceName] += drift.DriftType + ";"
+	}
+	assert.Len(t, drifts, 6)
+	assert.Equal(t, constant.TerminationPolicyDrift+";"+constant.ReleaseVersionDrift+";", driftTypes["mysql-test"])
+	assert.Equal(t, constant.ComponentMissingInK8s+";", driftTypes["mysql-test-proxy"])
+	assert.Equal(t, constant.ComponentMissingInMeta+";", driftTypes["mysql-test-mysql"])
+	assert.Equal(t, constant.ClusterMissingInK8s+";", driftTypes["redis-test"])
+	assert.Equal(t, constant.ClusterMissingInMeta+";", driftTypes["pg-test"])
+
+	for _, drift := range drifts {
+		if drift.DriftType == constant.ReleaseVersionDrift {
+			assert.Equal(t, uint64(1), drift.CrdClusterID)
+			assert.Equal(t, "1.0.0", drift.MetaValue)
+			assert.Equal(t, "1.0.1", drift.ActualValue)
+		}
+	}
+}
+
+func TestDiffClusterMetadataShardingComponent(t *testing.T) {
+	metaState := &entity.DriftMetaState{
+		Clusters: []*metaentity.K8sCrdClusterEntity{
+			{ID: 1, ClusterName: "redis-test", Namespace: "test-ns", TerminationPolicy: "Delete"},
+		},
+		Components: map[uint64][]*metaentity.K8sCrdComponentEntity{
+			1: {
+				{CrdClusterID: 1, ComponentName: "redis-test-proxy"},
+				{CrdClusterID: 1, ComponentName: "redis-test-shard"},
+			},
+		},
+	}
+	cluster := newKbCluster("redis-test", "Delete", "proxy")
+	cluster.Spec.ShardingSpecs = []kbv1.ShardingSpec{{Name: "shard", Shards: 3}}
+	actualState := &entity.DriftActualState{Clusters: []*kbv1.Cluster{cluster}}
+	assert.Empty(t, util.DiffClusterMetadata(metaState, actualState))
+}
@@ -0,0 +1,184 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tests
+
+import (
+	"k8s-dbs/core/constant"
+	"k8s-dbs/core/entity"
+	"k8s-dbs/core/util"
+	metaentity "k8s-dbs/metadata/entity"
+	"testing"
+
+	kbv1 "github.com/apecloud/kubeblocks/apis/apps/v1alpha1"
+	opv1 "github.com/apecloud/kubeblocks/apis/operations/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newQuotaComponentSpec(name string, replicas int32, cpu, memory, storage string) kbv1.ClusterComponentSpec {
+	return kbv1.ClusterComponentSpec{
+		Name:     name,
+		Replicas: replicas,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+		},
+		VolumeClaimTemplates: []kbv1.ClusterComponentVolumeClaimTemplate{
+			{
+				Name: "data",
+				Spec: kbv1.PersistentVolumeClaimSpec{
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(storage)},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newQuotaCluster() *kbv1.Cluster {
+	return &kbv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "quota-test", Namespace: "test-ns"},
+		Spec: kbv1.ClusterSpec{
+			ComponentSpecs: []kbv1.ClusterComponentSpec{
+				newQuotaComponentSpec("mysql", 2, "2", "4Gi", "100Gi"),
+			},
+			ShardingSpecs: []kbv1.ShardingSpec{
+				{
+					Name:     "shard",
+					Shards:   3,
+					Template: newQuotaComponentSpec("shard", 2, "1", "2Gi", "50Gi"),
+				},
+			},
+		},
+	}
+}
+
+func TestClusterRequestedResource(t *testing.T) {
+	requested := util.ClusterRequestedResource(newQuotaCluster())
+	// mysql: 2 * (2c, 4G, 100G), shard: 3 * 2 * (1c, 2G, 50G)
+	assert.Equal(t, entity.ResourceAmount{CPU: 10, Memory: 20, Storage: 500}, requested)
+}
+
+func TestCreateClusterResourceDelta(t *testing.T) {
+	request := &entity.Request{}
+	request.ComponentList = []entity.ComponentResource{
+		{
+			ComponentName: "mysql",
+			Replicas:      3,
+			Request:       &entity.Resource{CPU: resource.MustParse("500m"), Memory: resource.MustParse("1Gi")},
+			VolumeClaimTemplates: &entity.VolumeClaimTemplates{
+				Storage: resource.MustParse("100Gi"),
+			},
+		},
+		{
+			ComponentName: "proxy",
+			Replicas:      2,
+			Limit:         &entity.Resource{CPU: resource.MustParse("1"), Memory: resource.MustParse("2Gi")},
+		},
+	}
+	delta := util.CreateClusterResourceDelta(request)
+	assert.Equal(t, entity.ResourceAmount{CPU: 3.5, Memory: 7, Storage: 300}, delta)
+}
+
+func TestVerticalScalingResourceDelta(t *testing.T) {
+	request := &entity.Request{}
+	request.ComponentList = []entity.ComponentResource{
+		{
+			ComponentName: "mysql",
+			Request:       &entity.Resource{CPU: resource.MustParse("4"), Memory: resource.MustParse("8Gi")},
+		},
+		{
+			ComponentName: "shard",
+			Request:       &entity.Resource{CPU: resource.MustParse("500m"), Memory: resource.MustParse("2Gi")},
+		},
+	}
+	delta := util.VerticalScalingResourceDelta(request, newQuotaCluster())
+	// mysql: 2 * (+2c, +4G), shard: 6 * (-0.5c, 0G)
+	assert.Equal(t, entity.ResourceAmount{CPU: 1, Memory: 8}, delta)
+	assert.True(t, delta.HasIncrease())
+}
+
+func TestHorizontalScalingResourceDelta(t *testing.T) {
+	replicaChanges := int32(2)
+	shards := int32(4)
+	request := &entity.Request{}
+	request.HorizontalScalingList = []opv1.HorizontalScaling{
+		{
+			ComponentOps: opv1.ComponentOps{ComponentName: "mysql"},
+			ScaleOut:     &opv1.ScaleOut{ReplicaChanger: opv1.ReplicaChanger{ReplicaChanges: &replicaChanges}},
+		},
+		{
+			ComponentOps: opv1.ComponentOps{ComponentName: "shard"},
+			Shards:       &shards,
+		},
+	}
+	delta := util.HorizontalScalingResourceDelta(request, newQuotaCluster())
+	// mysql: 2 * (2c, 4G, 100G), shard: 1 * 2 * (1c, 2G, 50G)
+	assert.Equal(t, entity.ResourceAmount{CPU: 6, Memory: 12, Storage: 300}, delta)
+
+	scaleIn := &entity.Request{}
+	scaleIn.HorizontalScalingList = []opv1.HorizontalScaling{
+		{
+			ComponentOps: opv1.ComponentOps{ComponentName: "mysql"},
+			ScaleIn:      &opv1.ScaleIn{ReplicaChanger: opv1.ReplicaChanger{ReplicaChanges: &replicaChanges}},
+		},
+	}
+	assert.False(t, util.HorizontalScalingResourceDelta(scaleIn, newQuotaCluster()).HasIncrease())
+}
+
+func TestVolumeExpansionResourceDelta(t *testing.T) {
+	request := &entity.Request{}
+	request.ComponentList = []entity.ComponentResource{
+		{ComponentName: "mysql", Storage: resource.MustParse("20Gi")},
+		{ComponentName: "shard", Storage: resource.MustParse("10Gi")},
+	}
+	delta := util.VolumeExpansionResourceDelta(request, newQuotaCluster())
+	// mysql: 2 * 20G, shard: 6 * 10G
+	assert.Equal(t, entity.ResourceAmount{Storage: 100}, delta)
+}
+
+func TestCheckResourceQuota(t *testing.T) {
+	quota := &metaentity.ResourceQuotaEntity{
+		QuotaType: constant.BusinessQuota,
+		BkBizID:   100,
+		CPU:       16,
+		Memory:    32,
+	}
+	used := entity.ResourceAmount{CPU: 10, Memory: 20, Storage: 5000}
+
+	result := util.CheckResourceQuota(quota, used, entity.ResourceAmount{CPU: 4, Memory: 8, Storage: 100})
+	assert.False(t, result.Exceeded())
+	// storage 配额为 0，不做限制
+	assert.Equal(t, 2, len(result.Items))
+
+	result = util.CheckResourceQuota(quota, used, entity.ResourceAmount{CPU: 8, Memory: 8})
+	assert.True(t, result.Exceeded())
+	assert.Equal(t, "业务 100 资源配额不足: cpu 已分配 10.000core + 本次申请 8.000core > 配额 16.000core",
+		result.String())
+
+	// 已超出配额时，减少资源的请求仍然允许
+	result = util.CheckResourceQuota(quota, entity.ResourceAmount{CPU: 20}, entity.ResourceAmount{CPU: -2})
+	assert.False(t, result.Exceeded())
+}
@@ -0,0 +1,222 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tests
+
+import (
+	commutil "k8s-dbs/common/util"
+	"k8s-dbs/core/constant"
+	"k8s-dbs/core/entity"
+	"k8s-dbs/core/util"
+	"testing"
+
+	kbtypes "github.com/apecloud/kbcli/pkg/types"
+	kbv1 "github.com/apecloud/kubeblocks/apis/apps/v1alpha1"
+	opv1 "github.com/apecloud/kubeblocks/apis/operations/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newComponentPod(name, componentName, role string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "test-ns",
+			"labels": map[string]interface{}{
+				constant.InstanceName:  "mysql-test",
+				constant.ComponentName: componentName,
+				"kubeblocks.io/role":   role,
+			},
+		},
+	}}
+}
+
+func newParamConfigRenderer(name, componentDef string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "parameters.kubeblocks.io/v1alpha1",
+		"kind":       constant.ParamConfigRenderer,
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"componentDef":   componentDef,
+			"parametersDefs": []interface{}{"mysql-8.0-pd"},
+		},
+	}}
+}
+
+func newParametersDefinition() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "parameters.kubeblocks.io/v1alpha1",
+		"kind":       constant.ParametersDef,
+		"metadata":   map[string]interface{}{"name": "mysql-8.0-pd"},
+		"spec": map[string]interface{}{
+			"reloadAction": map[string]interface{}{
+				"shellTrigger": map[string]interface{}{"command": []interface{}{"reload"}},
+			},
+			"staticParameters":    []interface{}{"innodb_buffer_pool_instances"},
+			"dynamicParameters":   []interface{}{"max_connections", "binlog_format"},
+			"immutableParameters": []interface{}{"datadir"},
+			"parametersSchema": map[string]interface{}{
+				"schemaInJSON": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"max_connections": map[string]interface{}{
+									"type": "integer", "minimum": float64(1), "maximum": float64(100000),
+								},
+								"binlog_format": map[string]interface{}{
+									"type": "string", "enum": []interface{}{"ROW", "MIXED", "STATEMENT"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func newFakeOpsClient(objects ...runtime.Object) *commutil.K8sClient {
+	fakeClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			kbtypes.PodGVR():               "PodList",
+			util.ParamConfigRendererGVR():  "ParamConfigRendererList",
+			util.ParametersDefinitionGVR(): "ParametersDefinitionList",
+		}, objects...)
+	return &commutil.K8sClient{DynamicClient: fakeClient}
+}
+
+func newMySQLCluster() *kbv1.Cluster {
+	return &kbv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "mysql-test", Namespace: "test-ns"},
+		Spec: kbv1.ClusterSpec{
+			ComponentSpecs: []kbv1.ClusterComponentSpec{
+				{Name: "mysql", ComponentDef: "mysql-8.0-1.0.0", ServiceVersion: "8.0.39"},
+			},
+		},
+	}
+}
+
+func TestCreateSwitchoverObject(t *testing.T) {
+	k8sClient := newFakeOpsClient(
+		newComponentPod("mysql-test-mysql-0", "mysql", "primary"),
+		newComponentPod("mysql-test-mysql-1", "mysql", "secondary"),
+	)
+	request := newBackupRequest()
+
+	_, err := util.CreateSwitchoverObject(k8sClient, request)
+	assert.Error(t, err)
+
+	request.SwitchoverList = []opv1.Switchover{{ComponentName: "mysql"}}
+	crd, err := util.CreateSwitchoverObject(k8sClient, request)
+	assert.NoError(t, err)
+	assert.Equal(t, constant.Switchover, crd.ResourceType)
+
+	var ops opv1.OpsRequest
+	assert.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(crd.ResourceObject.Object, &ops))
+	assert.Equal(t, opv1.OpsType(constant.Switchover), ops.Spec.Type)
+	assert.Equal(t, "mysql-test-mysql-0", ops.Spec.SwitchoverList[0].InstanceName)
+	assert.Empty(t, ops.Spec.SwitchoverList[0].CandidateName)
+
+	request.SwitchoverList[0].CandidateName = "mysql-test-mysql-1"
+	_, err = util.CreateSwitchoverObject(k8sClient, request)
+	assert.NoError(t, err)
+
+	request.SwitchoverList[0].CandidateName = "mysql-test-mysql-0"
+	_, err = util.CreateSwitchoverObject(k8sClient, request)
+	assert.Error(t, err)
+
+	request.SwitchoverList[0].CandidateName = "mysql-test-mysql-9"
+	_, err = util.CreateSwitchoverObject(k8sClient, request)
+	assert.Error(t, err)
+
+	request.SwitchoverList[0] = opv1.Switchover{
+		ComponentName: "mysql",
+		InstanceName:  "mysql-test-mysql-9",
+	}
+	_, err = util.CreateSwitchoverObject(k8sClient, request)
+	assert.Error(t, err)
+}
+
+func newReconfigureRequest(params map[string]string) *entity.Request {
+	request := newBackupRequest()
+	var pairs []opv1.ParameterPair
+	for k, v := range params {
+		pairs = append(pairs, opv1.ParameterPair{Key: k, Value: &v})
+	}
+	request.Reconfigures = []opv1.Reconfigure{{
+		ComponentOps: opv1.ComponentOps{ComponentName: "mysql"},
+		Parameters:   pairs,
+	}}
+	return request
+}
+
+func TestCreateReconfigureObject(t *testing.T) {
+	k8sClient := newFakeOpsClient(newParamConfigRenderer("mysql-8.0-pcr", "^mysql-8.0"), newParametersDefinition())
+	cluster := newMySQLCluster()
+
+	crd, err := util.CreateReconfigureObject(k8sClient,
+		newReconfigureRequest(map[string]string{"max_connections": "2000", "binlog_format": "ROW"}), cluster)
+	assert.NoError(t, err)
+	assert.Equal(t, constant.Reconfiguring, crd.ResourceType)
+	var ops opv1.OpsRequest
+	assert.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(crd.ResourceObject.Object, &ops))
+	assert.Equal(t, opv1.OpsType(constant.Reconfiguring), ops.Spec.Type)
+	assert.Len(t, ops.Spec.Reconfigures[0].Parameters, 2)
+	assert.Equal(t, "false", ops.Annotations[constant.RestartRequired])
+
+	crd, err = util.CreateReconfigureObject(k8sClient,
+		newReconfigureRequest(map[string]string{"innodb_buffer_pool_instances": "8"}), cluster)
+	assert.NoError(t, err)
+	assert.Equal(t, "true", crd.ResourceObject.GetAnnotations()[constant.RestartRequired])
+	assert.Equal(t, "innodb_buffer_pool_instances", crd.ResourceObject.GetAnnotations()[constant.RestartParams])
+
+	for _, params := range []map[string]string{
+		{"datadir": "/data"},
+		{"not_exists": "1"},
+		{"max_connections": "abc"},
+		{"max_connections": "0"},
+		{"binlog_format": "NONE"},
+	} {
+		_, err = util.CreateReconfigureObject(k8sClient, newReconfigureRequest(params), cluster)
+		assert.Error(t, err, params)
+	}
+
+	cluster.Spec.ComponentSpecs[0].ComponentDef = "redis-7-1.0.0"
+	_, err = util.CreateReconfigureObject(k8sClient,
+		newReconfigureRequest(map[string]string{"max_connections": "2000"}), cluster)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "不支持参数变更")
+}
+
+func TestCreateReconfigureObjectEmptyRendererComponentDef(t *testing.T) {
+	// componentDef 为空的 renderer 不能匹配所有组件
+	k8sClient := newFakeOpsClient(newParamConfigRenderer("empty-pcr", ""), newParametersDefinition())
+	_, err := util.CreateReconfigureObject(k8sClient,
+		newReconfigureRequest(map[string]string{"max_connections": "2000"}), newMySQLCluster())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "不支持参数变更")
+}
@@ -0,0 +1,51 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package response
+
+import (
+	dpv1 "github.com/apecloud/kubeblocks/apis/dataprotection/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BackupResponse backup detail response
+type BackupResponse struct {
+	BackupName       string                   `json:"backupName,omitempty"`
+	Namespace        string                   `json:"namespace,omitempty"`
+	ClusterName      string                   `json:"clusterName,omitempty"`
+	BackupPolicyName string                   `json:"backupPolicyName,omitempty"`
+	BackupMethod     string                   `json:"backupMethod,omitempty"`
+	BackupType       string                   `json:"backupType,omitempty"`
+	Phase            dpv1.BackupPhase         `json:"phase,omitempty"`
+	StartTime        *metav1.Time             `json:"startTime,omitempty"`
+	CompleteTime     *metav1.Time             `json:"completeTime,omitempty"`
+	Expiration       *metav1.Time             `json:"expiration,omitempty"`
+	TotalSize        string                   `json:"totalSize,omitempty"`
+	BackupRepoName   string                   `json:"backupRepoName,omitempty"`
+	Path             string                   `json:"path,omitempty"`
+	TimeRange        *BackupTimeRangeResponse `json:"timeRange,omitempty"`
+	FailureReason    string                   `json:"failureReason,omitempty"`
+	CreateTime       metav1.Time              `json:"createTime,omitempty"`
+}
+
+// BackupTimeRangeResponse backup time range response
+type BackupTimeRangeResponse struct {
+	Start *metav1.Time `json:"start,omitempty"`
+	End   *metav1.Time `json:"end,omitempty"`
+}
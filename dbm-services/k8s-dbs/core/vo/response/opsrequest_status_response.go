@@ -26,7 +26,8 @@ import (
 
 // OpsRequestStatusResponse OpsRequest status response
 type OpsRequestStatusResponse struct {
-	Phase        opv1.OpsPhase `json:"phase,omitempty"`
-	StartTime    metav1.Time   `json:"startTime,omitempty"`
-	CompleteTime metav1.Time   `json:"completeTime,omitempty"`
+	Phase        opv1.OpsPhase   `json:"phase,omitempty"`
+	StartTime    metav1.Time     `json:"startTime,omitempty"`
+	CompleteTime metav1.Time     `json:"completeTime,omitempty"`
+	Backup       *BackupResponse `json:"backup,omitempty"`
 }
@@ -74,6 +74,14 @@ const (
 	GetClusterEventError
 	PartialUpdateClusterError
 	GetClusterSvcError
+	BackupClusterError
+	RestoreClusterError
+	ListBackupError
+	SwitchoverError
+	ReconfigureError
+	ResourceQuotaExceededError
+	GetResourceUsageError
+	GetClusterMetricError
 )
 
 // 存储集群 component 操作异常
@@ -141,6 +149,16 @@ var codeTag = map[ErrorCode]string{
 	UpdateClusterError:        "更新集群失败",
 	PartialUpdateClusterError: "局部更新集群失败",
 	GetClusterSvcError:        "获取集群连接失败",
+	BackupClusterError:        "集群备份失败",
+	RestoreClusterError:       "集群恢复失败",
+	ListBackupError:           "查询集群备份列表失败",
+	SwitchoverError:           "组件主从切换失败",
+	ReconfigureError:          "组件参数变更失败",
+
+	// 资源配额和监控指标查询异常
+	ResourceQuotaExceededError: "资源配额不足",
+	GetResourceUsageError:      "查询资源使用量失败",
+	GetClusterMetricError:      "查询集群监控指标失败",
 
 	// k8s api server 调用异常
 	CreateK8sNsError:         "创建命名空间失败",
@@ -0,0 +1,100 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s-dbs/common/api"
+	commconst "k8s-dbs/common/constant"
+	commutil "k8s-dbs/common/util"
+	"k8s-dbs/errors"
+	metaentity "k8s-dbs/metadata/entity"
+	"k8s-dbs/metadata/provider"
+	corevo "k8s-dbs/metadata/vo/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+)
+
+// K8sClusterDriftController manages metadata drift of k8s clusters.
+type K8sClusterDriftController struct {
+	clusterDriftProvider provider.K8sClusterDriftProvider
+}
+
+// NewK8sClusterDriftController creates a new instance of K8sClusterDriftController.
+func NewK8sClusterDriftController(
+	clusterDriftProvider provider.K8sClusterDriftProvider,
+) *K8sClusterDriftController {
+	return &K8sClusterDriftController{clusterDriftProvider}
+}
+
+// ListDrifts 分页检索元数据与 k8s 集群的差异记录，默认只返回当前有效的差异.
+func (k *K8sClusterDriftController) ListDrifts(ctx *gin.Context) {
+	pagination, err := commutil.BuildPagination(ctx)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	queryParams, err := k.buildListParams(ctx)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	drifts, count, err := k.clusterDriftProvider.ListDrifts(queryParams, pagination)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	var data []corevo.K8sClusterDriftResponse
+	if err = copier.Copy(&data, drifts); err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	var responseData = corevo.PageResult{
+		Count:  count,
+		Result: data,
+	}
+	api.SuccessResponse(ctx, responseData, commconst.Success)
+}
+
+func (k *K8sClusterDriftController) buildListParams(ctx *gin.Context) (
+	*metaentity.ClusterDriftQueryParams,
+	error,
+) {
+	queryParams := metaentity.ClusterDriftQueryParams{
+		ClusterName:   ctx.Query("clusterName"),
+		Namespace:     ctx.Query("namespace"),
+		ResourceTypes: ctx.QueryArray("resourceType"),
+		DriftTypes:    ctx.QueryArray("driftType"),
+	}
+	if k8sClusterConfigID := ctx.Query("k8sClusterConfigId"); k8sClusterConfigID != "" {
+		id, err := strconv.ParseUint(k8sClusterConfigID, 10, 64)
+		if err != nil {
+			return nil, errors.NewK8sDbsError(errors.ParameterValueError, err)
+		}
+		queryParams.K8sClusterConfigID = id
+	}
+	active, err := strconv.ParseBool(ctx.DefaultQuery("active", "true"))
+	if err != nil {
+		return nil, errors.NewK8sDbsError(errors.ParameterValueError, err)
+	}
+	queryParams.Active = &active
+	return &queryParams, nil
+}
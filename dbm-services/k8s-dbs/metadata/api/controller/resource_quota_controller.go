@@ -0,0 +1,222 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"k8s-dbs/common/api"
+	commconst "k8s-dbs/common/constant"
+	commutil "k8s-dbs/common/util"
+	coreconst "k8s-dbs/core/constant"
+	"k8s-dbs/errors"
+	metaentity "k8s-dbs/metadata/entity"
+	"k8s-dbs/metadata/provider"
+	"k8s-dbs/metadata/vo/request"
+	"k8s-dbs/metadata/vo/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+)
+
+// ResourceQuotaController manages the metadata for resource quota.
+type ResourceQuotaController struct {
+	quotaProvider provider.ResourceQuotaProvider
+}
+
+// NewResourceQuotaController creates a new instance of ResourceQuotaController.
+func NewResourceQuotaController(quotaProvider provider.ResourceQuotaProvider) *ResourceQuotaController {
+	return &ResourceQuotaController{quotaProvider}
+}
+
+// ListQuotas 分页检索资源配额
+func (r *ResourceQuotaController) ListQuotas(ctx *gin.Context) {
+	pagination, err := commutil.BuildPagination(ctx)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	queryParams := metaentity.ResourceQuotaQueryParams{
+		QuotaType: ctx.Query("quotaType"),
+		Namespace: ctx.Query("namespace"),
+	}
+	if bkBizID := ctx.Query("bkBizId"); bkBizID != "" {
+		if queryParams.BkBizID, err = strconv.ParseUint(bkBizID, 10, 64); err != nil {
+			api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterValueError, err))
+			return
+		}
+	}
+	if k8sClusterConfigID := ctx.Query("k8sClusterConfigId"); k8sClusterConfigID != "" {
+		if queryParams.K8sClusterConfigID, err = strconv.ParseUint(k8sClusterConfigID, 10, 64); err != nil {
+			api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterValueError, err))
+			return
+		}
+	}
+	quotas, count, err := r.quotaProvider.ListQuotas(&queryParams, pagination)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	var data []response.ResourceQuotaResponse
+	if err = copier.Copy(&data, quotas); err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	var responseData = response.PageResult{
+		Count:  count,
+		Result: data,
+	}
+	api.SuccessResponse(ctx, responseData, commconst.Success)
+}
+
+// GetQuota 根据 ID 查询资源配额
+func (r *ResourceQuotaController) GetQuota(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	quota, err := r.quotaProvider.FindQuotaByID(id)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	if quota == nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError,
+			fmt.Errorf("资源配额 %d 不存在", id)))
+		return
+	}
+	var data response.ResourceQuotaResponse
+	if err = copier.Copy(&data, quota); err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	api.SuccessResponse(ctx, data, commconst.Success)
+}
+
+// CreateQuota 创建资源配额
+func (r *ResourceQuotaController) CreateQuota(ctx *gin.Context) {
+	var reqVo request.ResourceQuotaRequest
+	if err := ctx.ShouldBindJSON(&reqVo); err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	quotaEntity, err := buildQuotaEntity(&reqVo)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	quotaEntity.CreatedBy = reqVo.BkUserName
+	quotaEntity.UpdatedBy = reqVo.BkUserName
+	added, err := r.quotaProvider.CreateQuota(quotaEntity)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.CreateMetaDataError, err))
+		return
+	}
+	var data response.ResourceQuotaResponse
+	if err = copier.Copy(&data, added); err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.CreateMetaDataError, err))
+		return
+	}
+	api.SuccessResponse(ctx, data, commconst.Success)
+}
+
+// UpdateQuota 更新资源配额
+func (r *ResourceQuotaController) UpdateQuota(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	var reqVo request.ResourceQuotaRequest
+	if err = ctx.ShouldBindJSON(&reqVo); err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	origin, err := r.quotaProvider.FindQuotaByID(id)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	if origin == nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.UpdateMetaDataError,
+			fmt.Errorf("资源配额 %d 不存在", id)))
+		return
+	}
+	quotaEntity, err := buildQuotaEntity(&reqVo)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	quotaEntity.ID = id
+	quotaEntity.UpdatedBy = reqVo.BkUserName
+	rows, err := r.quotaProvider.UpdateQuota(quotaEntity)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.UpdateMetaDataError, err))
+		return
+	}
+	api.SuccessResponse(ctx, map[string]uint64{"rows": rows}, commconst.Success)
+}
+
+// DeleteQuota 删除资源配额
+func (r *ResourceQuotaController) DeleteQuota(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	rows, err := r.quotaProvider.DeleteQuotaByID(id)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.DeleteMetaDataError, err))
+		return
+	}
+	api.SuccessResponse(ctx, map[string]uint64{"rows": rows}, commconst.Success)
+}
+
+// buildQuotaEntity 校验配额范围并构建 entity，业务配额不区分 k8s 集群，命名空间配额不区分业务
+func buildQuotaEntity(reqVo *request.ResourceQuotaRequest) (*metaentity.ResourceQuotaEntity, error) {
+	quotaEntity := &metaentity.ResourceQuotaEntity{
+		QuotaType:   reqVo.QuotaType,
+		CPU:         reqVo.CPU,
+		Memory:      reqVo.Memory,
+		Storage:     reqVo.Storage,
+		Active:      true,
+		Description: reqVo.Description,
+	}
+	if reqVo.Active != nil {
+		quotaEntity.Active = *reqVo.Active
+	}
+	switch reqVo.QuotaType {
+	case coreconst.BusinessQuota:
+		if reqVo.BkBizID == 0 {
+			return nil, fmt.Errorf("业务配额 bkBizId 不能为空")
+		}
+		quotaEntity.BkBizID = reqVo.BkBizID
+	case coreconst.NamespaceQuota:
+		if reqVo.K8sClusterConfigID == 0 || reqVo.Namespace == "" {
+			return nil, fmt.Errorf("命名空间配额 k8sClusterConfigId 和 namespace 不能为空")
+		}
+		quotaEntity.K8sClusterConfigID = reqVo.K8sClusterConfigID
+		quotaEntity.Namespace = reqVo.Namespace
+	default:
+		return nil, fmt.Errorf("不支持的配额类型 %s", reqVo.QuotaType)
+	}
+	return quotaEntity, nil
+}
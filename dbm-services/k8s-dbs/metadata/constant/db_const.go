@@ -41,4 +41,9 @@ const (
 	TbAddonCategory        = "tb_addon_category"
 	TbAddonType            = "tb_addon_type"
 	TbAddonTopology        = "tb_addon_topology"
+	TbK8sClusterDrift      = "tb_k8s_cluster_drift"
+	TbTerminalSession      = "tb_terminal_session"
+	TbTerminalCommand      = "tb_terminal_command"
+	TbTerminalRecording    = "tb_terminal_recording"
+	TbResourceQuota        = "tb_resource_quota"
 )
@@ -37,6 +37,7 @@ type AddonClusterReleaseDbAccess interface {
 	FindByParams(params *metaentity.ClusterReleaseQueryParams) (*metamodel.AddonClusterReleaseModel, error)
 	Update(model *metamodel.AddonClusterReleaseModel) (uint64, error)
 	ListByPage(pagination commentity.Pagination) ([]metamodel.AddonClusterReleaseModel, int64, error)
+	FindByK8sClusterConfigID(k8sClusterConfigID uint64) ([]*metamodel.AddonClusterReleaseModel, error)
 }
 
 // AddonClusterReleaseDbAccessImpl AddonClusterReleaseDbAccess 的具体实现
@@ -111,6 +112,18 @@ func (a *AddonClusterReleaseDbAccessImpl) ListByPage(pagination commentity.Pagin
 	return releaseModels, int64(len(releaseModels)), nil
 }
 
+// FindByK8sClusterConfigID 查找 k8s 集群的 release
+func (a *AddonClusterReleaseDbAccessImpl) FindByK8sClusterConfigID(k8sClusterConfigID uint64) (
+	[]*metamodel.AddonClusterReleaseModel, error,
+) {
+	var releaseModels []*metamodel.AddonClusterReleaseModel
+	if err := a.db.Where("k8s_cluster_config_id = ?", k8sClusterConfigID).Find(&releaseModels).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find addoncluster release with k8sClusterConfigID=%d",
+			k8sClusterConfigID)
+	}
+	return releaseModels, nil
+}
+
 // NewAddonClusterReleaseDbAccess 创建 K8sCrdStorageAddonDbAccess 接口实现实例
 func NewAddonClusterReleaseDbAccess(db *gorm.DB) AddonClusterReleaseDbAccess {
 	return &AddonClusterReleaseDbAccessImpl{db: db}
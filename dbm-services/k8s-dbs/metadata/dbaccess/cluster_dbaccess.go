@@ -38,6 +38,8 @@ type K8sCrdClusterDbAccess interface {
 	Update(model *models.K8sCrdClusterModel) (uint64, error)
 	ListByPage(params *metaentity.ClusterQueryParams, pagination *entity.Pagination) (
 		[]*models.K8sCrdClusterModel, uint64, error)
+	FindByK8sClusterConfigID(k8sClusterConfigID uint64) ([]*models.K8sCrdClusterModel, error)
+	FindAllByParams(params *metaentity.ClusterQueryParams) ([]*models.K8sCrdClusterModel, error)
 }
 
 // K8sCrdClusterDbAccessImpl K8sCrdClusterDbAccess 的具体实现
@@ -162,6 +164,38 @@ func (k *K8sCrdClusterDbAccessImpl) ListByPage(
 	return clusterModels, uint64(count), nil
 }
 
+// FindByK8sClusterConfigID 查找 k8s 集群上的 cluster
+func (k *K8sCrdClusterDbAccessImpl) FindByK8sClusterConfigID(k8sClusterConfigID uint64) (
+	[]*models.K8sCrdClusterModel, error,
+) {
+	var clusterModels []*models.K8sCrdClusterModel
+	if err := k.db.Where("k8s_cluster_config_id = ?", k8sClusterConfigID).Find(&clusterModels).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find cluster with k8sClusterConfigID=%d", k8sClusterConfigID)
+	}
+	return clusterModels, nil
+}
+
+// FindAllByParams 根据 k8s 集群、命名空间和业务查找全部 cluster，不分页
+func (k *K8sCrdClusterDbAccessImpl) FindAllByParams(params *metaentity.ClusterQueryParams) (
+	[]*models.K8sCrdClusterModel, error,
+) {
+	var clusterModels []*models.K8sCrdClusterModel
+	query := k.db.Model(&models.K8sCrdClusterModel{})
+	if params.K8sClusterConfigID > 0 {
+		query = query.Where("k8s_cluster_config_id = ?", params.K8sClusterConfigID)
+	}
+	if params.Namespace != "" {
+		query = query.Where("namespace = ?", params.Namespace)
+	}
+	if len(params.BkBizIDs) > 0 {
+		query = query.Where("bk_biz_id in (?)", params.BkBizIDs)
+	}
+	if err := query.Find(&clusterModels).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find cluster with params %+v", params)
+	}
+	return clusterModels, nil
+}
+
 // NewCrdClusterDbAccess 创建 K8sCrdClusterDbAccess 接口实现实例
 func NewCrdClusterDbAccess(db *gorm.DB) K8sCrdClusterDbAccess {
 	return &K8sCrdClusterDbAccessImpl{db: db}
@@ -0,0 +1,179 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dbaccess
+
+import (
+	"context"
+	"database/sql"
+	commconst "k8s-dbs/common/constant"
+	"k8s-dbs/common/entity"
+	metaentity "k8s-dbs/metadata/entity"
+	metamodel "k8s-dbs/metadata/model"
+	"log/slog"
+
+	"github.com/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// K8sClusterDriftDbAccess 定义 cluster drift 元数据的数据库访问接口
+type K8sClusterDriftDbAccess interface {
+	Create(model *metamodel.K8sClusterDriftModel) (*metamodel.K8sClusterDriftModel, error)
+	Update(model *metamodel.K8sClusterDriftModel) (uint64, error)
+	FindActiveByK8sClusterConfigID(k8sClusterConfigID uint64) ([]*metamodel.K8sClusterDriftModel, error)
+	DeactivateByIDs(ids []uint64, updatedBy string) (uint64, error)
+	ListByPage(params *metaentity.ClusterDriftQueryParams, pagination *entity.Pagination) (
+		[]*metamodel.K8sClusterDriftModel, uint64, error)
+	TryLock(ctx context.Context, name string) (func(), bool, error)
+}
+
+// K8sClusterDriftDbAccessImpl K8sClusterDriftDbAccess 的具体实现
+type K8sClusterDriftDbAccessImpl struct {
+	db *gorm.DB
+}
+
+// Create 创建元数据接口实现
+func (k *K8sClusterDriftDbAccessImpl) Create(model *metamodel.K8sClusterDriftModel) (
+	*metamodel.K8sClusterDriftModel, error,
+) {
+	if err := k.db.Create(model).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to create cluster drift with model %+v", model)
+	}
+	return model, nil
+}
+
+// Update 更新元数据接口实现
+func (k *K8sClusterDriftDbAccessImpl) Update(model *metamodel.K8sClusterDriftModel) (uint64, error) {
+	result := k.db.Omit("CreatedAt", "CreatedBy").Save(model)
+	if result.Error != nil {
+		return 0, errors.Wrapf(result.Error, "failed to update cluster drift with model %+v", model)
+	}
+	return uint64(result.RowsAffected), nil
+}
+
+// FindActiveByK8sClusterConfigID 查找 k8s 集群当前有效的差异记录
+func (k *K8sClusterDriftDbAccessImpl) FindActiveByK8sClusterConfigID(k8sClusterConfigID uint64) (
+	[]*metamodel.K8sClusterDriftModel, error,
+) {
+	var driftModels []*metamodel.K8sClusterDriftModel
+	if err := k.db.
+		Where("k8s_cluster_config_id = ? AND active = ?", k8sClusterConfigID, true).
+		Find(&driftModels).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find active cluster drift with k8sClusterConfigID=%d",
+			k8sClusterConfigID)
+	}
+	return driftModels, nil
+}
+
+// DeactivateByIDs 将差异记录标记为已消除
+func (k *K8sClusterDriftDbAccessImpl) DeactivateByIDs(ids []uint64, updatedBy string) (uint64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := k.db.Model(&metamodel.K8sClusterDriftModel{}).
+		Where("id in (?)", ids).
+		Updates(map[string]interface{}{"active": false, "updated_by": updatedBy})
+	if result.Error != nil {
+		return 0, errors.Wrapf(result.Error, "failed to deactivate cluster drift with ids %v", ids)
+	}
+	return uint64(result.RowsAffected), nil
+}
+
+// ListByPage 分页查询元数据接口实现
+func (k *K8sClusterDriftDbAccessImpl) ListByPage(
+	params *metaentity.ClusterDriftQueryParams,
+	pagination *entity.Pagination,
+) ([]*metamodel.K8sClusterDriftModel, uint64, error) {
+	var driftModels []*metamodel.K8sClusterDriftModel
+	var count int64
+	query := k.db.Model(&metamodel.K8sClusterDriftModel{})
+	if params.K8sClusterConfigID != 0 {
+		query = query.Where("k8s_cluster_config_id = ?", params.K8sClusterConfigID)
+	}
+	if params.ClusterName != "" {
+		query = query.Where("cluster_name = ?", params.ClusterName)
+	}
+	if params.Namespace != "" {
+		query = query.Where("namespace = ?", params.Namespace)
+	}
+	if len(params.ResourceTypes) > 0 {
+		query = query.Where("resource_type in (?)", params.ResourceTypes)
+	}
+	if len(params.DriftTypes) > 0 {
+		query = query.Where("drift_type in (?)", params.DriftTypes)
+	}
+	if params.Active != nil {
+		query = query.Where("active = ?", *params.Active)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to count cluster drift with params %+v", params)
+	}
+	page, limit := pagination.Page, pagination.Limit
+	if page < 1 {
+		page = commconst.DefaultPage
+	}
+	if limit < 1 {
+		limit = commconst.DefaultPageLimit
+	}
+	if err := query.
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Order("updated_at DESC").
+		Find(&driftModels).
+		Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to list cluster drift with pagination %+v", pagination)
+	}
+	return driftModels, uint64(count), nil
+}
+
+// TryLock 非阻塞获取 MySQL 命名锁，获取成功时返回释放函数
+// 锁与数据库连接绑定，进程异常退出、连接断开时由 MySQL 自动释放
+func (k *K8sClusterDriftDbAccessImpl) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	sqlDB, err := k.db.DB()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to get sql db")
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to get db connection")
+	}
+	var locked sql.NullInt64
+	if err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&locked); err != nil {
+		_ = conn.Close()
+		return nil, false, errors.Wrapf(err, "failed to get lock %s", name)
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+	release := func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name); err != nil {
+			slog.Warn("failed to release lock", "name", name, "error", err)
+		}
+		_ = conn.Close()
+	}
+	return release, true, nil
+}
+
+// NewK8sClusterDriftDbAccess 创建 K8sClusterDriftDbAccess 接口实现实例
+func NewK8sClusterDriftDbAccess(db *gorm.DB) K8sClusterDriftDbAccess {
+	return &K8sClusterDriftDbAccessImpl{db: db}
+}
@@ -37,6 +37,7 @@ type K8sCrdComponentDbAccess interface {
 	Update(model *models.K8sCrdComponentModel) (uint64, error)
 	ListByPage(pagination entity.Pagination) ([]models.K8sCrdComponentModel, int64, error)
 	DeleteByClusterID(id uint64) (uint64, error)
+	FindByClusterID(clusterID uint64) ([]*models.K8sCrdComponentModel, error)
 }
 
 // K8sCrdComponentDbAccessImpl K8sCrdComponentDbAccess 的具体实现
@@ -94,6 +95,15 @@ func (k *K8sCrdComponentDbAccessImpl) ListByPage(_ entity.Pagination) ([]models.
 	return nil, 0, fmt.Errorf("not implemented yet")
 }
 
+// FindByClusterID 查找集群的 component
+func (k *K8sCrdComponentDbAccessImpl) FindByClusterID(clusterID uint64) ([]*models.K8sCrdComponentModel, error) {
+	var componentModels []*models.K8sCrdComponentModel
+	if err := k.db.Where("crd_cluster_id = ?", clusterID).Find(&componentModels).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find component with clusterID=%d", clusterID)
+	}
+	return componentModels, nil
+}
+
 // NewK8sCrdComponentAccess 创建 K8sCrdComponentAccess 接口实现实例
 func NewK8sCrdComponentAccess(db *gorm.DB) K8sCrdComponentDbAccess {
 	return &K8sCrdComponentDbAccessImpl{db: db}
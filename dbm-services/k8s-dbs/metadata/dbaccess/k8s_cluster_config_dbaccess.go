@@ -40,6 +40,7 @@ type K8sClusterConfigDbAccess interface {
 	Update(model *models.K8sClusterConfigModel) (uint64, error)
 	ListByPage(pagination entity.Pagination) ([]models.K8sClusterConfigModel, int64, error)
 	FindRegionsByParams(params *metaentity.RegionQueryParams) ([]*models.RegionModel, error)
+	FindActive() ([]*models.K8sClusterConfigModel, error)
 }
 
 // K8sClusterConfigDbAccessImpl K8sClusterConfigDbAccess 的具体实现
@@ -114,6 +115,15 @@ func (k *K8sClusterConfigDbAccessImpl) ListByPage(_ entity.Pagination) ([]models
 	return nil, 0, fmt.Errorf("not implemented yet")
 }
 
+// FindActive 查找所有有效的 k8s 集群配置
+func (k *K8sClusterConfigDbAccessImpl) FindActive() ([]*models.K8sClusterConfigModel, error) {
+	var configModels []*models.K8sClusterConfigModel
+	if err := k.db.Where("active = ?", true).Find(&configModels).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to find active k8s cluster config")
+	}
+	return configModels, nil
+}
+
 // NewK8sClusterConfigDbAccess 创建 K8sClusterConfigDbAccess 接口实现实例
 func NewK8sClusterConfigDbAccess(db *gorm.DB) K8sClusterConfigDbAccess {
 	return &K8sClusterConfigDbAccessImpl{db: db}
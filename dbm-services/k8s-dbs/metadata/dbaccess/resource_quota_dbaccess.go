@@ -0,0 +1,153 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dbaccess
+
+import (
+	commconst "k8s-dbs/common/constant"
+	"k8s-dbs/common/entity"
+	metaentity "k8s-dbs/metadata/entity"
+	metamodel "k8s-dbs/metadata/model"
+
+	"github.com/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// ResourceQuotaDbAccess 定义 resource quota 元数据的数据库访问接口
+type ResourceQuotaDbAccess interface {
+	Create(model *metamodel.ResourceQuotaModel) (*metamodel.ResourceQuotaModel, error)
+	Update(model *metamodel.ResourceQuotaModel) (uint64, error)
+	DeleteByID(id uint64) (uint64, error)
+	FindByID(id uint64) (*metamodel.ResourceQuotaModel, error)
+	FindActiveByParams(params *metaentity.ResourceQuotaQueryParams) (*metamodel.ResourceQuotaModel, error)
+	ListByPage(params *metaentity.ResourceQuotaQueryParams, pagination *entity.Pagination) (
+		[]*metamodel.ResourceQuotaModel, uint64, error)
+}
+
+// ResourceQuotaDbAccessImpl ResourceQuotaDbAccess 的具体实现
+type ResourceQuotaDbAccessImpl struct {
+	db *gorm.DB
+}
+
+// Create 创建元数据接口实现
+func (k *ResourceQuotaDbAccessImpl) Create(model *metamodel.ResourceQuotaModel) (
+	*metamodel.ResourceQuotaModel, error,
+) {
+	if err := k.db.Create(model).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to create resource quota with model %+v", model)
+	}
+	return model, nil
+}
+
+// Update 更新元数据接口实现
+func (k *ResourceQuotaDbAccessImpl) Update(model *metamodel.ResourceQuotaModel) (uint64, error) {
+	result := k.db.Omit("CreatedAt", "CreatedBy").Save(model)
+	if result.Error != nil {
+		return 0, errors.Wrapf(result.Error, "failed to update resource quota with model %+v", model)
+	}
+	return uint64(result.RowsAffected), nil
+}
+
+// DeleteByID 删除元数据接口实现
+func (k *ResourceQuotaDbAccessImpl) DeleteByID(id uint64) (uint64, error) {
+	result := k.db.Delete(&metamodel.ResourceQuotaModel{}, id)
+	if result.Error != nil {
+		return 0, errors.Wrapf(result.Error, "failed to delete resource quota with id %d", id)
+	}
+	return uint64(result.RowsAffected), nil
+}
+
+// FindByID 查找元数据接口实现
+func (k *ResourceQuotaDbAccessImpl) FindByID(id uint64) (*metamodel.ResourceQuotaModel, error) {
+	var quotaModel metamodel.ResourceQuotaModel
+	result := k.db.First(&quotaModel, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, errors.Wrapf(result.Error, "failed to find resource quota with id %d", id)
+	}
+	return &quotaModel, nil
+}
+
+// FindActiveByParams 查找业务或命名空间当前生效的配额，不存在时返回 nil
+func (k *ResourceQuotaDbAccessImpl) FindActiveByParams(params *metaentity.ResourceQuotaQueryParams) (
+	*metamodel.ResourceQuotaModel, error,
+) {
+	var quotaModel metamodel.ResourceQuotaModel
+	result := k.db.
+		Where("quota_type = ? AND bk_biz_id = ? AND k8s_cluster_config_id = ? AND namespace = ? AND active = ?",
+			params.QuotaType, params.BkBizID, params.K8sClusterConfigID, params.Namespace, true).
+		First(&quotaModel)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, errors.Wrapf(result.Error, "failed to find resource quota with params %+v", params)
+	}
+	return &quotaModel, nil
+}
+
+// ListByPage 分页查询元数据接口实现
+func (k *ResourceQuotaDbAccessImpl) ListByPage(
+	params *metaentity.ResourceQuotaQueryParams,
+	pagination *entity.Pagination,
+) ([]*metamodel.ResourceQuotaModel, uint64, error) {
+	var quotaModels []*metamodel.ResourceQuotaModel
+	var count int64
+	query := k.db.Model(&metamodel.ResourceQuotaModel{})
+	if params.QuotaType != "" {
+		query = query.Where("quota_type = ?", params.QuotaType)
+	}
+	if params.BkBizID != 0 {
+		query = query.Where("bk_biz_id = ?", params.BkBizID)
+	}
+	if params.K8sClusterConfigID != 0 {
+		query = query.Where("k8s_cluster_config_id = ?", params.K8sClusterConfigID)
+	}
+	if params.Namespace != "" {
+		query = query.Where("namespace = ?", params.Namespace)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to count resource quota with params %+v", params)
+	}
+	page, limit := pagination.Page, pagination.Limit
+	if page < 1 {
+		page = commconst.DefaultPage
+	}
+	if limit < 1 {
+		limit = commconst.DefaultPageLimit
+	}
+	if err := query.
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Order("updated_at DESC").
+		Find(&quotaModels).
+		Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to list resource quota with pagination %+v", pagination)
+	}
+	return quotaModels, uint64(count), nil
+}
+
+// NewResourceQuotaDbAccess 创建 ResourceQuotaDbAccess 接口实现实例
+func NewResourceQuotaDbAccess(db *gorm.DB) ResourceQuotaDbAccess {
+	return &ResourceQuotaDbAccessImpl{db: db}
+}
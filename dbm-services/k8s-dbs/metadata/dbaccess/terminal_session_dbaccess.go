@@ -0,0 +1,210 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dbaccess
+
+import (
+	commconst "k8s-dbs/common/constant"
+	"k8s-dbs/common/entity"
+	metaentity "k8s-dbs/metadata/entity"
+	metamodel "k8s-dbs/metadata/model"
+
+	"github.com/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// TerminalSessionDbAccess 定义 terminal session 元数据的数据库访问接口
+type TerminalSessionDbAccess interface {
+	Create(model *metamodel.TerminalSessionModel) (*metamodel.TerminalSessionModel, error)
+	Update(model *metamodel.TerminalSessionModel) (uint64, error)
+	FindByID(id uint64) (*metamodel.TerminalSessionModel, error)
+	ListByPage(params *metaentity.TerminalSessionQueryParams, pagination *entity.Pagination) (
+		[]*metamodel.TerminalSessionModel, uint64, error)
+}
+
+// TerminalSessionDbAccessImpl TerminalSessionDbAccess 的具体实现
+type TerminalSessionDbAccessImpl struct {
+	db *gorm.DB
+}
+
+// Create 创建元数据接口实现
+func (k *TerminalSessionDbAccessImpl) Create(model *metamodel.TerminalSessionModel) (
+	*metamodel.TerminalSessionModel, error,
+) {
+	if err := k.db.Create(model).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to create terminal session for pod %s/%s",
+			model.Namespace, model.PodName)
+	}
+	return model, nil
+}
+
+// Update 更新元数据接口实现
+func (k *TerminalSessionDbAccessImpl) Update(model *metamodel.TerminalSessionModel) (uint64, error) {
+	result := k.db.Omit("CreatedAt", "CreatedBy").Save(model)
+	if result.Error != nil {
+		return 0, errors.Wrapf(result.Error, "failed to update terminal session with id %d", model.ID)
+	}
+	return uint64(result.RowsAffected), nil
+}
+
+// FindByID 查找元数据接口实现
+func (k *TerminalSessionDbAccessImpl) FindByID(id uint64) (*metamodel.TerminalSessionModel, error) {
+	var sessionModel metamodel.TerminalSessionModel
+	if err := k.db.First(&sessionModel, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find terminal session with id %d", id)
+	}
+	return &sessionModel, nil
+}
+
+// ListByPage 分页查询元数据接口实现
+func (k *TerminalSessionDbAccessImpl) ListByPage(
+	params *metaentity.TerminalSessionQueryParams,
+	pagination *entity.Pagination,
+) ([]*metamodel.TerminalSessionModel, uint64, error) {
+	var sessionModels []*metamodel.TerminalSessionModel
+	var count int64
+	query := k.db.Model(&metamodel.TerminalSessionModel{})
+	if params.K8sClusterName != "" {
+		query = query.Where("k8s_cluster_name = ?", params.K8sClusterName)
+	}
+	if params.ClusterName != "" {
+		query = query.Where("cluster_name = ?", params.ClusterName)
+	}
+	if params.Namespace != "" {
+		query = query.Where("namespace = ?", params.Namespace)
+	}
+	if params.PodName != "" {
+		query = query.Where("pod_name = ?", params.PodName)
+	}
+	if len(params.Creators) > 0 {
+		query = query.Where("created_by in (?)", params.Creators)
+	}
+	if !params.StartTime.IsZero() {
+		query = query.Where("start_time >= ?", params.StartTime)
+	}
+	if !params.EndTime.IsZero() {
+		query = query.Where("start_time <= ?", params.EndTime)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to count terminal session with params %+v", params)
+	}
+	page, limit := pagination.Page, pagination.Limit
+	if page < 1 {
+		page = commconst.DefaultPage
+	}
+	if limit < 1 {
+		limit = commconst.DefaultPageLimit
+	}
+	if err := query.
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Order("start_time DESC").
+		Find(&sessionModels).
+		Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to list terminal session with pagination %+v", pagination)
+	}
+	return sessionModels, uint64(count), nil
+}
+
+// NewTerminalSessionDbAccess 创建 TerminalSessionDbAccess 接口实现实例
+func NewTerminalSessionDbAccess(db *gorm.DB) TerminalSessionDbAccess {
+	return &TerminalSessionDbAccessImpl{db: db}
+}
+
+// TerminalCommandDbAccess 定义 terminal command 元数据的数据库访问接口
+type TerminalCommandDbAccess interface {
+	Create(model *metamodel.TerminalCommandModel) (*metamodel.TerminalCommandModel, error)
+	FindBySessionID(sessionID uint64) ([]*metamodel.TerminalCommandModel, error)
+}
+
+// TerminalCommandDbAccessImpl TerminalCommandDbAccess 的具体实现
+type TerminalCommandDbAccessImpl struct {
+	db *gorm.DB
+}
+
+// Create 创建元数据接口实现
+func (k *TerminalCommandDbAccessImpl) Create(model *metamodel.TerminalCommandModel) (
+	*metamodel.TerminalCommandModel, error,
+) {
+	if err := k.db.Create(model).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to create terminal command with session id %d", model.SessionID)
+	}
+	return model, nil
+}
+
+// FindBySessionID 按执行顺序查找会话的命令记录
+func (k *TerminalCommandDbAccessImpl) FindBySessionID(sessionID uint64) ([]*metamodel.TerminalCommandModel, error) {
+	var commandModels []*metamodel.TerminalCommandModel
+	if err := k.db.
+		Where("session_id = ?", sessionID).
+		Order("id ASC").
+		Find(&commandModels).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find terminal command with session id %d", sessionID)
+	}
+	return commandModels, nil
+}
+
+// NewTerminalCommandDbAccess 创建 TerminalCommandDbAccess 接口实现实例
+func NewTerminalCommandDbAccess(db *gorm.DB) TerminalCommandDbAccess {
+	return &TerminalCommandDbAccessImpl{db: db}
+}
+
+// TerminalRecordingDbAccess 定义 terminal recording 元数据的数据库访问接口
+type TerminalRecordingDbAccess interface {
+	Create(model *metamodel.TerminalRecordingModel) (*metamodel.TerminalRecordingModel, error)
+	ListBySessionID(sessionID uint64, fromSeq int, limit int) ([]*metamodel.TerminalRecordingModel, error)
+}
+
+// TerminalRecordingDbAccessImpl TerminalRecordingDbAccess 的具体实现
+type TerminalRecordingDbAccessImpl struct {
+	db *gorm.DB
+}
+
+// Create 创建元数据接口实现
+func (k *TerminalRecordingDbAccessImpl) Create(model *metamodel.TerminalRecordingModel) (
+	*metamodel.TerminalRecordingModel, error,
+) {
+	if err := k.db.Create(model).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to create terminal recording with session id %d seq %d",
+			model.SessionID, model.Seq)
+	}
+	return model, nil
+}
+
+// ListBySessionID 按 seq 顺序查找会话从 fromSeq 开始的 limit 个录像分块
+func (k *TerminalRecordingDbAccessImpl) ListBySessionID(sessionID uint64, fromSeq int, limit int) (
+	[]*metamodel.TerminalRecordingModel, error,
+) {
+	var recordingModels []*metamodel.TerminalRecordingModel
+	if err := k.db.
+		Where("session_id = ? AND seq >= ?", sessionID, fromSeq).
+		Order("seq ASC").
+		Limit(limit).
+		Find(&recordingModels).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to list terminal recording with session id %d", sessionID)
+	}
+	return recordingModels, nil
+}
+
+// NewTerminalRecordingDbAccess 创建 TerminalRecordingDbAccess 接口实现实例
+func NewTerminalRecordingDbAccess(db *gorm.DB) TerminalRecordingDbAccess {
+	return &TerminalRecordingDbAccessImpl{db: db}
+}
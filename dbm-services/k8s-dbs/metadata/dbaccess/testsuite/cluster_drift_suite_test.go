@@ -0,0 +1,162 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testsuite
+
+import (
+	"context"
+	"k8s-dbs/common/entity"
+	"k8s-dbs/metadata/constant"
+	"k8s-dbs/metadata/dbaccess"
+	metaentity "k8s-dbs/metadata/entity"
+	"k8s-dbs/metadata/helper/testhelper"
+	"k8s-dbs/metadata/model"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+var batchClusterDriftSamples = []*model.K8sClusterDriftModel{
+	{
+		K8sClusterConfigID: 1,
+		CrdClusterID:       1,
+		ClusterName:        "mysql-test",
+		Namespace:          "test-ns",
+		ResourceType:       "cluster",
+		ResourceName:       "mysql-test",
+		DriftType:          "TerminationPolicyDrift",
+		MetaValue:          "Delete",
+		ActualValue:        "WipeOut",
+		Active:             true,
+		CreatedBy:          "admin",
+		UpdatedBy:          "admin",
+	},
+	{
+		K8sClusterConfigID: 1,
+		ClusterName:        "pg-test",
+		Namespace:          "test-ns",
+		ResourceType:       "cluster",
+		ResourceName:       "pg-test",
+		DriftType:          "ClusterMissingInMeta",
+		ActualValue:        "pg-test",
+		Active:             true,
+		CreatedBy:          "admin",
+		UpdatedBy:          "admin",
+	},
+}
+
+type ClusterDriftDbAccessTestSuite struct {
+	suite.Suite
+	mySqlContainer *testhelper.MySQLContainerWrapper
+	dbAccess       dbaccess.K8sClusterDriftDbAccess
+	ctx            context.Context
+}
+
+func (suite *ClusterDriftDbAccessTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+	mySqlContainer, err := testhelper.NewMySQLContainerWrapper(suite.ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	suite.mySqlContainer = mySqlContainer
+	db, err := testhelper.InitDBConnection(mySqlContainer.ConnStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	suite.dbAccess = dbaccess.NewK8sClusterDriftDbAccess(db)
+}
+
+func (suite *ClusterDriftDbAccessTestSuite) TearDownSuite() {
+	if err := suite.mySqlContainer.Terminate(suite.ctx); err != nil {
+		log.Fatalf("error terminating mysql container: %s", err)
+	}
+}
+
+func (suite *ClusterDriftDbAccessTestSuite) SetupTest() {
+	testhelper.InitTestTable(suite.mySqlContainer.ConnStr, constant.TbK8sClusterDrift, &model.K8sClusterDriftModel{})
+}
+
+func (suite *ClusterDriftDbAccessTestSuite) TestFindActiveAndDeactivate() {
+	t := suite.T()
+	var ids []uint64
+	for _, sample := range batchClusterDriftSamples {
+		drift, err := suite.dbAccess.Create(sample)
+		assert.NoError(t, err)
+		assert.NotZero(t, drift.ID)
+		ids = append(ids, drift.ID)
+	}
+
+	activeDrifts, err := suite.dbAccess.FindActiveByK8sClusterConfigID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, len(batchClusterDriftSamples), len(activeDrifts))
+
+	rows, err := suite.dbAccess.DeactivateByIDs(ids[:1], "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), rows)
+
+	activeDrifts, err = suite.dbAccess.FindActiveByK8sClusterConfigID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(activeDrifts))
+	assert.Equal(t, ids[1], activeDrifts[0].ID)
+}
+
+func (suite *ClusterDriftDbAccessTestSuite) TestListClusterDriftByPage() {
+	t := suite.T()
+	for _, sample := range batchClusterDriftSamples {
+		_, err := suite.dbAccess.Create(sample)
+		assert.NoError(t, err)
+	}
+
+	pagination := &entity.Pagination{
+		Page:  0,
+		Limit: 10,
+	}
+	params := &metaentity.ClusterDriftQueryParams{
+		K8sClusterConfigID: 1,
+		DriftTypes:         []string{"ClusterMissingInMeta"},
+	}
+	drifts, count, err := suite.dbAccess.ListByPage(params, pagination)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, "pg-test", drifts[0].ClusterName)
+}
+
+func (suite *ClusterDriftDbAccessTestSuite) TestTryLock() {
+	t := suite.T()
+	release, locked, err := suite.dbAccess.TryLock(suite.ctx, "drift_test_lock")
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	// 锁与连接绑定，其他连接获取失败
+	_, locked, err = suite.dbAccess.TryLock(suite.ctx, "drift_test_lock")
+	assert.NoError(t, err)
+	assert.False(t, locked)
+
+	release()
+	release, locked, err = suite.dbAccess.TryLock(suite.ctx, "drift_test_lock")
+	assert.NoError(t, err)
+	assert.True(t, locked)
+	release()
+}
+
+func TestClusterDriftDbAccess(t *testing.T) {
+	suite.Run(t, new(ClusterDriftDbAccessTestSuite))
+}
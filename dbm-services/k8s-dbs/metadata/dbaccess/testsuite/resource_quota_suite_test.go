@@ -0,0 +1,179 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testsuite
+
+import (
+	"context"
+	"k8s-dbs/common/entity"
+	"k8s-dbs/metadata/constant"
+	"k8s-dbs/metadata/dbaccess"
+	metaentity "k8s-dbs/metadata/entity"
+	"k8s-dbs/metadata/helper/testhelper"
+	"k8s-dbs/metadata/model"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+var batchResourceQuotaSamples = []*model.ResourceQuotaModel{
+	{
+		QuotaType: "business",
+		BkBizID:   100,
+		CPU:       32,
+		Memory:    64,
+		Storage:   1000,
+		Active:    true,
+		CreatedBy: "admin",
+		UpdatedBy: "admin",
+	},
+	{
+		QuotaType:          "namespace",
+		K8sClusterConfigID: 1,
+		Namespace:          "test-ns",
+		CPU:                16,
+		Active:             true,
+		CreatedBy:          "admin",
+		UpdatedBy:          "admin",
+	},
+	{
+		QuotaType:          "namespace",
+		K8sClusterConfigID: 1,
+		Namespace:          "inactive-ns",
+		CPU:                8,
+		Active:             false,
+		CreatedBy:          "admin",
+		UpdatedBy:          "admin",
+	},
+}
+
+type ResourceQuotaDbAccessTestSuite struct {
+	suite.Suite
+	mySqlContainer *testhelper.MySQLContainerWrapper
+	dbAccess       dbaccess.ResourceQuotaDbAccess
+	ctx            context.Context
+}
+
+func (suite *ResourceQuotaDbAccessTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+	mySqlContainer, err := testhelper.NewMySQLContainerWrapper(suite.ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	suite.mySqlContainer = mySqlContainer
+	db, err := testhelper.InitDBConnection(mySqlContainer.ConnStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	suite.dbAccess = dbaccess.NewResourceQuotaDbAccess(db)
+}
+
+func (suite *ResourceQuotaDbAccessTestSuite) TearDownSuite() {
+	if err := suite.mySqlContainer.Terminate(suite.ctx); err != nil {
+		log.Fatalf("error terminating mysql container: %s", err)
+	}
+}
+
+func (suite *ResourceQuotaDbAccessTestSuite) SetupTest() {
+	testhelper.InitTestTable(suite.mySqlContainer.ConnStr, constant.TbResourceQuota, &model.ResourceQuotaModel{})
+}
+
+func (suite *ResourceQuotaDbAccessTestSuite) TestFindActiveByParams() {
+	t := suite.T()
+	for _, sample := range batchResourceQuotaSamples {
+		quota, err := suite.dbAccess.Create(sample)
+		assert.NoError(t, err)
+		assert.NotZero(t, quota.ID)
+	}
+
+	bizQuota, err := suite.dbAccess.FindActiveByParams(&metaentity.ResourceQuotaQueryParams{
+		QuotaType: "business",
+		BkBizID:   100,
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, bizQuota)
+	assert.Equal(t, float64(64), bizQuota.Memory)
+
+	nsQuota, err := suite.dbAccess.FindActiveByParams(&metaentity.ResourceQuotaQueryParams{
+		QuotaType:          "namespace",
+		K8sClusterConfigID: 1,
+		Namespace:          "test-ns",
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, nsQuota)
+	assert.Equal(t, float64(16), nsQuota.CPU)
+
+	inactiveQuota, err := suite.dbAccess.FindActiveByParams(&metaentity.ResourceQuotaQueryParams{
+		QuotaType:          "namespace",
+		K8sClusterConfigID: 1,
+		Namespace:          "inactive-ns",
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, inactiveQuota)
+}
+
+func (suite *ResourceQuotaDbAccessTestSuite) TestUpdateAndDeleteResourceQuota() {
+	t := suite.T()
+	quota, err := suite.dbAccess.Create(batchResourceQuotaSamples[0])
+	assert.NoError(t, err)
+
+	quota.CPU = 64
+	quota.UpdatedBy = "bob"
+	rows, err := suite.dbAccess.Update(quota)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), rows)
+
+	updated, err := suite.dbAccess.FindByID(quota.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(64), updated.CPU)
+	assert.Equal(t, "admin", updated.CreatedBy)
+
+	rows, err = suite.dbAccess.DeleteByID(quota.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), rows)
+
+	deleted, err := suite.dbAccess.FindByID(quota.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, deleted)
+}
+
+func (suite *ResourceQuotaDbAccessTestSuite) TestListResourceQuotaByPage() {
+	t := suite.T()
+	for _, sample := range batchResourceQuotaSamples {
+		_, err := suite.dbAccess.Create(sample)
+		assert.NoError(t, err)
+	}
+
+	pagination := &entity.Pagination{
+		Page:  0,
+		Limit: 10,
+	}
+	quotas, count, err := suite.dbAccess.ListByPage(&metaentity.ResourceQuotaQueryParams{
+		QuotaType: "namespace",
+	}, pagination)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+	assert.Equal(t, 2, len(quotas))
+}
+
+func TestResourceQuotaDbAccess(t *testing.T) {
+	suite.Run(t, new(ResourceQuotaDbAccessTestSuite))
+}
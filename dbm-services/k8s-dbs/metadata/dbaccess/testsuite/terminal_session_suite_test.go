@@ -0,0 +1,177 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testsuite
+
+import (
+	"context"
+	"fmt"
+	"k8s-dbs/common/entity"
+	commtypes "k8s-dbs/common/types"
+	"k8s-dbs/metadata/constant"
+	"k8s-dbs/metadata/dbaccess"
+	metaentity "k8s-dbs/metadata/entity"
+	"k8s-dbs/metadata/helper/testhelper"
+	"k8s-dbs/metadata/model"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func newTerminalSessionSample() *model.TerminalSessionModel {
+	return &model.TerminalSessionModel{
+		K8sClusterName: "BCS-K8S-00000",
+		ClusterName:    "mysql-test",
+		Namespace:      "default",
+		PodName:        "mysql-test-mysql-0",
+		ContainerName:  "mysql",
+		Status:         "Running",
+		StartTime:      commtypes.JSONDatetime(time.Now()),
+		CreatedBy:      "admin",
+		UpdatedBy:      "admin",
+	}
+}
+
+type TerminalSessionDbAccessTestSuite struct {
+	suite.Suite
+	mySqlContainer    *testhelper.MySQLContainerWrapper
+	sessionDbAccess   dbaccess.TerminalSessionDbAccess
+	commandDbAccess   dbaccess.TerminalCommandDbAccess
+	recordingDbAccess dbaccess.TerminalRecordingDbAccess
+	ctx               context.Context
+}
+
+func (suite *TerminalSessionDbAccessTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+	mySqlContainer, err := testhelper.NewMySQLContainerWrapper(suite.ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	suite.mySqlContainer = mySqlContainer
+	db, err := testhelper.InitDBConnection(mySqlContainer.ConnStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	suite.sessionDbAccess = dbaccess.NewTerminalSessionDbAccess(db)
+	suite.commandDbAccess = dbaccess.NewTerminalCommandDbAccess(db)
+	suite.recordingDbAccess = dbaccess.NewTerminalRecordingDbAccess(db)
+}
+
+func (suite *TerminalSessionDbAccessTestSuite) TearDownSuite() {
+	if err := suite.mySqlContainer.Terminate(suite.ctx); err != nil {
+		log.Fatalf("error terminating mysql container: %s", err)
+	}
+}
+
+func (suite *TerminalSessionDbAccessTestSuite) SetupTest() {
+	testhelper.InitTestTable(suite.mySqlContainer.ConnStr, constant.TbTerminalSession, &model.TerminalSessionModel{})
+	testhelper.InitTestTable(suite.mySqlContainer.ConnStr, constant.TbTerminalCommand, &model.TerminalCommandModel{})
+	testhelper.InitTestTable(suite.mySqlContainer.ConnStr, constant.TbTerminalRecording,
+		&model.TerminalRecordingModel{})
+}
+
+func (suite *TerminalSessionDbAccessTestSuite) TestUpdateAndFindSession() {
+	t := suite.T()
+	session, err := suite.sessionDbAccess.Create(newTerminalSessionSample())
+	assert.NoError(t, err)
+	assert.NotZero(t, session.ID)
+
+	endTime := commtypes.JSONDatetime(time.Now())
+	session.Status = "Closed"
+	session.EndTime = &endTime
+	session.RecordingSize = 1024
+	rows, err := suite.sessionDbAccess.Update(session)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), rows)
+
+	foundSession, err := suite.sessionDbAccess.FindByID(session.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Closed", foundSession.Status)
+	assert.Equal(t, session.RecordingSize, foundSession.RecordingSize)
+	assert.NotNil(t, foundSession.EndTime)
+}
+
+func (suite *TerminalSessionDbAccessTestSuite) TestListSessionByPage() {
+	t := suite.T()
+	sample := newTerminalSessionSample()
+	_, err := suite.sessionDbAccess.Create(sample)
+	assert.NoError(t, err)
+
+	params := &metaentity.TerminalSessionQueryParams{
+		K8sClusterName: sample.K8sClusterName,
+		Creators:       []string{"admin"},
+	}
+	sessions, count, err := suite.sessionDbAccess.ListByPage(params, &entity.Pagination{Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, sample.PodName, sessions[0].PodName)
+}
+
+func (suite *TerminalSessionDbAccessTestSuite) TestCreateAndFindCommand() {
+	t := suite.T()
+	for _, command := range []string{"ls", "reboot"} {
+		_, err := suite.commandDbAccess.Create(&model.TerminalCommandModel{
+			SessionID:  1,
+			Command:    command,
+			Blocked:    command == "reboot",
+			ExecutedAt: commtypes.JSONDatetime(time.Now()),
+			CreatedBy:  "admin",
+		})
+		assert.NoError(t, err)
+	}
+	commands, err := suite.commandDbAccess.FindBySessionID(1)
+	assert.NoError(t, err)
+	assert.Len(t, commands, 2)
+	assert.Equal(t, "ls", commands[0].Command)
+	assert.True(t, commands[1].Blocked)
+}
+
+func (suite *TerminalSessionDbAccessTestSuite) TestCreateAndListRecording() {
+	t := suite.T()
+	// 乱序写入，按 seq 顺序读取
+	for _, seq := range []int{2, 0, 1} {
+		_, err := suite.recordingDbAccess.Create(&model.TerminalRecordingModel{
+			SessionID: 1,
+			Seq:       seq,
+			Data:      fmt.Sprintf("chunk-%d\n", seq),
+		})
+		assert.NoError(t, err)
+	}
+	recordings, err := suite.recordingDbAccess.ListBySessionID(1, 0, 2)
+	assert.NoError(t, err)
+	assert.Len(t, recordings, 2)
+	assert.Equal(t, "chunk-0\n", recordings[0].Data)
+	assert.Equal(t, 1, recordings[1].Seq)
+
+	recordings, err = suite.recordingDbAccess.ListBySessionID(1, 2, 2)
+	assert.NoError(t, err)
+	assert.Len(t, recordings, 1)
+	assert.Equal(t, 2, recordings[0].Seq)
+
+	// 同一分块不能重复写入
+	_, err = suite.recordingDbAccess.Create(&model.TerminalRecordingModel{SessionID: 1, Seq: 0, Data: "dup"})
+	assert.Error(t, err)
+}
+
+func TestTerminalSessionDbAccess(t *testing.T) {
+	suite.Run(t, new(TerminalSessionDbAccessTestSuite))
+}
@@ -131,3 +131,32 @@ type AddonTopologyQueryParams struct {
 	AddonVersion  string `gorm:"column:addon_version" json:"addonVersion"`
 	TopologyName  string `gorm:"column:topology_name" json:"topologyName"`
 }
+
+// ClusterDriftQueryParams cluster drift 元数据查询参数
+type ClusterDriftQueryParams struct {
+	K8sClusterConfigID uint64   `json:"k8sClusterConfigId"`
+	ClusterName        string   `json:"clusterName"`
+	Namespace          string   `json:"namespace"`
+	ResourceTypes      []string `json:"resourceTypes"`
+	DriftTypes         []string `json:"driftTypes"`
+	Active             *bool    `json:"active"`
+}
+
+// TerminalSessionQueryParams terminal session 元数据查询参数
+type TerminalSessionQueryParams struct {
+	K8sClusterName string    `json:"k8sClusterName"`
+	ClusterName    string    `json:"clusterName"`
+	Namespace      string    `json:"namespace"`
+	PodName        string    `json:"podName"`
+	Creators       []string  `json:"creators"`
+	StartTime      time.Time `json:"startTime"`
+	EndTime        time.Time `json:"endTime"`
+}
+
+// ResourceQuotaQueryParams resource quota 元数据查询参数
+type ResourceQuotaQueryParams struct {
+	QuotaType          string `json:"quotaType"`
+	BkBizID            uint64 `json:"bkBizId"`
+	K8sClusterConfigID uint64 `json:"k8sClusterConfigId"`
+	Namespace          string `json:"namespace"`
+}
@@ -0,0 +1,48 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	commtypes "k8s-dbs/common/types"
+	"k8s-dbs/metadata/constant"
+)
+
+// ResourceQuotaModel 业务或命名空间的资源配额，配额为 0 表示该项资源不做限制
+type ResourceQuotaModel struct {
+	ID                 uint64                 `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	QuotaType          string                 `gorm:"size:32;not null;column:quota_type" json:"quotaType"`
+	BkBizID            uint64                 `gorm:"not null;default:0;column:bk_biz_id" json:"bkBizId"`
+	K8sClusterConfigID uint64                 `gorm:"not null;default:0;column:k8s_cluster_config_id" json:"k8sClusterConfigId"` //nolint:lll
+	Namespace          string                 `gorm:"size:32;not null;default:'';column:namespace" json:"namespace"`
+	CPU                float64                `gorm:"type:decimal(12,3);not null;default:0;column:cpu" json:"cpu"`
+	Memory             float64                `gorm:"type:decimal(12,3);not null;default:0;column:memory" json:"memory"`
+	Storage            float64                `gorm:"type:decimal(12,3);not null;default:0;column:storage" json:"storage"`
+	Active             bool                   `gorm:"type:tinyint(1);not null;column:active" json:"active"`
+	Description        string                 `gorm:"size:100;column:description" json:"description"`
+	CreatedBy          string                 `gorm:"size:50;not null;column:created_by" json:"createdBy"`
+	CreatedAt          commtypes.JSONDatetime `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:created_at" json:"createdAt"` //nolint:lll
+	UpdatedBy          string                 `gorm:"size:50;not null;column:updated_by" json:"updatedBy"`
+	UpdatedAt          commtypes.JSONDatetime `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP on update CURRENT_TIMESTAMP;column:updated_at" json:"updatedAt"` //nolint:lll
+}
+
+// TableName 获取 model 对应的数据库表名
+func (ResourceQuotaModel) TableName() string {
+	return constant.TbResourceQuota
+}
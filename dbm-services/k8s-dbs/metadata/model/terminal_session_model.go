@@ -0,0 +1,81 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	commtypes "k8s-dbs/common/types"
+	"k8s-dbs/metadata/constant"
+)
+
+// TerminalSessionModel 容器终端会话记录，会话录像分块保存在 tb_terminal_recording
+type TerminalSessionModel struct {
+	ID             uint64                  `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	K8sClusterName string                  `gorm:"size:32;not null;column:k8s_cluster_name" json:"k8sClusterName"`
+	ClusterName    string                  `gorm:"size:32;not null;column:cluster_name" json:"clusterName"`
+	Namespace      string                  `gorm:"size:32;not null;column:namespace" json:"namespace"`
+	PodName        string                  `gorm:"size:128;not null;column:pod_name" json:"podName"`
+	ContainerName  string                  `gorm:"size:64;column:container_name" json:"containerName"`
+	Status         string                  `gorm:"size:32;not null;column:status" json:"status"`
+	StartTime      commtypes.JSONDatetime  `gorm:"type:timestamp;not null;column:start_time" json:"startTime"`
+	EndTime        *commtypes.JSONDatetime `gorm:"type:timestamp;column:end_time" json:"endTime"`
+	RecordingSize  uint64                  `gorm:"not null;default:0;column:recording_size" json:"recordingSize"`
+	Truncated      bool                    `gorm:"type:tinyint(1);not null;default:0;column:truncated" json:"truncated"`
+	Description    string                  `gorm:"size:255;column:description" json:"description"`
+	CreatedBy      string                  `gorm:"size:50;not null;column:created_by" json:"createdBy"`
+	CreatedAt      commtypes.JSONDatetime  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:created_at" json:"createdAt"` //nolint:lll
+	UpdatedBy      string                  `gorm:"size:50;not null;column:updated_by" json:"updatedBy"`
+	UpdatedAt      commtypes.JSONDatetime  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP on update CURRENT_TIMESTAMP;column:updated_at" json:"updatedAt"` //nolint:lll
+}
+
+// TableName 获取 model 对应的数据库表名
+func (TerminalSessionModel) TableName() string {
+	return constant.TbTerminalSession
+}
+
+// TerminalCommandModel 容器终端命令审计记录
+type TerminalCommandModel struct {
+	ID         uint64                 `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	SessionID  uint64                 `gorm:"not null;column:session_id" json:"sessionId"`
+	Command    string                 `gorm:"type:text;not null;column:command" json:"command"`
+	Blocked    bool                   `gorm:"type:tinyint(1);not null;default:0;column:blocked" json:"blocked"`
+	DenyRule   string                 `gorm:"size:255;column:deny_rule" json:"denyRule"`
+	ExecutedAt commtypes.JSONDatetime `gorm:"type:timestamp;not null;column:executed_at" json:"executedAt"`
+	CreatedBy  string                 `gorm:"size:50;not null;column:created_by" json:"createdBy"`
+	CreatedAt  commtypes.JSONDatetime `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:created_at" json:"createdAt"` //nolint:lll
+}
+
+// TableName 获取 model 对应的数据库表名
+func (TerminalCommandModel) TableName() string {
+	return constant.TbTerminalCommand
+}
+
+// TerminalRecordingModel 容器终端会话录像分块，按 seq 顺序拼接为 asciicast v2 格式的录像
+type TerminalRecordingModel struct {
+	ID        uint64                 `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	SessionID uint64                 `gorm:"not null;column:session_id" json:"sessionId"`
+	Seq       int                    `gorm:"not null;column:seq" json:"seq"`
+	Data      string                 `gorm:"type:mediumtext;not null;column:data" json:"data"`
+	CreatedAt commtypes.JSONDatetime `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:created_at" json:"createdAt"` //nolint:lll
+}
+
+// TableName 获取 model 对应的数据库表名
+func (TerminalRecordingModel) TableName() string {
+	return constant.TbTerminalRecording
+}
@@ -38,6 +38,8 @@ type AddonClusterReleaseProvider interface {
 	FindByParams(params *metaentity.ClusterReleaseQueryParams) (*metaentity.AddonClusterReleaseEntity, error)
 	UpdateClusterRelease(entity *metaentity.AddonClusterReleaseEntity) (uint64, error)
 	ListClusterReleases(pagination entity.Pagination) ([]*metaentity.AddonClusterReleaseEntity, error)
+	FindClusterReleasesByK8sClusterConfigID(k8sClusterConfigID uint64) (
+		[]*metaentity.AddonClusterReleaseEntity, error)
 }
 
 // AddonClusterReleaseProviderImpl AddonClusterReleaseProvider 具体实现
@@ -138,6 +140,22 @@ func (a *AddonClusterReleaseProviderImpl) ListClusterReleases(pagination entity.
 	return releaseEntities, nil
 }
 
+// FindClusterReleasesByK8sClusterConfigID 查找 k8s 集群的 release
+func (a *AddonClusterReleaseProviderImpl) FindClusterReleasesByK8sClusterConfigID(k8sClusterConfigID uint64) (
+	[]*metaentity.AddonClusterReleaseEntity, error,
+) {
+	releaseModels, err := a.dbAccess.FindByK8sClusterConfigID(k8sClusterConfigID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find addoncluster release with k8sClusterConfigID %d",
+			k8sClusterConfigID)
+	}
+	var releaseEntities []*metaentity.AddonClusterReleaseEntity
+	if err = copier.Copy(&releaseEntities, releaseModels); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return releaseEntities, nil
+}
+
 // NewAddonClusterReleaseProvider 创建 AddonClusterReleaseDbAccess 接口实现实例
 func NewAddonClusterReleaseProvider(dbAccess dbaccess.AddonClusterReleaseDbAccess) AddonClusterReleaseProvider {
 	return &AddonClusterReleaseProviderImpl{dbAccess: dbAccess}
@@ -0,0 +1,133 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"k8s-dbs/common/entity"
+	"k8s-dbs/metadata/dbaccess"
+	metaentity "k8s-dbs/metadata/entity"
+	metamodel "k8s-dbs/metadata/model"
+
+	"github.com/pkg/errors"
+
+	"github.com/jinzhu/copier"
+)
+
+// K8sClusterDriftProvider 定义 cluster drift 业务逻辑层访问接口
+type K8sClusterDriftProvider interface {
+	SyncDrifts(k8sClusterConfigID uint64, drifts []*metaentity.K8sClusterDriftEntity, operator string) error
+	ListDrifts(
+		params *metaentity.ClusterDriftQueryParams,
+		pagination *entity.Pagination,
+	) ([]*metaentity.K8sClusterDriftEntity, uint64, error)
+	TryLock(ctx context.Context, name string) (func(), bool, error)
+}
+
+// K8sClusterDriftProviderImpl K8sClusterDriftProvider 具体实现
+type K8sClusterDriftProviderImpl struct {
+	dbAccess dbaccess.K8sClusterDriftDbAccess
+}
+
+// SyncDrifts 同步 k8s 集群本轮检测到的差异
+// 已存在的有效差异更新检测结果，新出现的差异新增记录，本轮未再检测到的差异标记为已消除
+// 已自动修复的差异记录为无效，仅保留事件
+func (k *K8sClusterDriftProviderImpl) SyncDrifts(
+	k8sClusterConfigID uint64,
+	drifts []*metaentity.K8sClusterDriftEntity,
+	operator string,
+) error {
+	activeModels, err := k.dbAccess.FindActiveByK8sClusterConfigID(k8sClusterConfigID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find active drift with k8sClusterConfigID %d", k8sClusterConfigID)
+	}
+	activeDrifts := make(map[string]*metamodel.K8sClusterDriftModel, len(activeModels))
+	for _, activeModel := range activeModels {
+		activeEntity := &metaentity.K8sClusterDriftEntity{}
+		if err = copier.Copy(activeEntity, activeModel); err != nil {
+			return errors.Wrap(err, "failed to copy")
+		}
+		activeDrifts[activeEntity.DriftKey()] = activeModel
+	}
+
+	for _, drift := range drifts {
+		drift.K8sClusterConfigID = k8sClusterConfigID
+		drift.Active = !drift.Repaired
+		drift.UpdatedBy = operator
+		driftModel, ok := activeDrifts[drift.DriftKey()]
+		if !ok {
+			drift.CreatedBy = operator
+			newModel := &metamodel.K8sClusterDriftModel{}
+			if err = copier.Copy(newModel, drift); err != nil {
+				return errors.Wrap(err, "failed to copy")
+			}
+			if _, err = k.dbAccess.Create(newModel); err != nil {
+				return errors.Wrapf(err, "failed to create drift with entity: %+v", drift)
+			}
+			continue
+		}
+		delete(activeDrifts, drift.DriftKey())
+		driftModel.CrdClusterID = drift.CrdClusterID
+		driftModel.MetaValue = drift.MetaValue
+		driftModel.ActualValue = drift.ActualValue
+		driftModel.Active = drift.Active
+		driftModel.Repaired = drift.Repaired
+		driftModel.Description = drift.Description
+		driftModel.UpdatedBy = operator
+		if _, err = k.dbAccess.Update(driftModel); err != nil {
+			return errors.Wrapf(err, "failed to update drift with entity: %+v", drift)
+		}
+	}
+
+	resolvedIDs := make([]uint64, 0, len(activeDrifts))
+	for _, driftModel := range activeDrifts {
+		resolvedIDs = append(resolvedIDs, driftModel.ID)
+	}
+	if _, err = k.dbAccess.DeactivateByIDs(resolvedIDs, operator); err != nil {
+		return errors.Wrapf(err, "failed to deactivate resolved drift with ids %v", resolvedIDs)
+	}
+	return nil
+}
+
+// ListDrifts 查询差异记录列表
+func (k *K8sClusterDriftProviderImpl) ListDrifts(
+	params *metaentity.ClusterDriftQueryParams,
+	pagination *entity.Pagination,
+) ([]*metaentity.K8sClusterDriftEntity, uint64, error) {
+	driftModels, count, err := k.dbAccess.ListByPage(params, pagination)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to list drift with params: %+v", params)
+	}
+	var driftEntities []*metaentity.K8sClusterDriftEntity
+	if err = copier.Copy(&driftEntities, driftModels); err != nil {
+		return nil, 0, errors.Wrap(err, "failed to copy")
+	}
+	return driftEntities, count, nil
+}
+
+// TryLock 获取巡检锁，多副本部署时同一时刻只有一个副本执行巡检
+func (k *K8sClusterDriftProviderImpl) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	return k.dbAccess.TryLock(ctx, name)
+}
+
+// NewK8sClusterDriftProvider 创建 K8sClusterDriftProvider 接口实现实例
+func NewK8sClusterDriftProvider(dbAccess dbaccess.K8sClusterDriftDbAccess) K8sClusterDriftProvider {
+	return &K8sClusterDriftProviderImpl{dbAccess: dbAccess}
+}
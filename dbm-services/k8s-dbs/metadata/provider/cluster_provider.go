@@ -50,6 +50,8 @@ type K8sCrdClusterProvider interface {
 		pagination *entity.Pagination,
 	) ([]*metaentity.K8sCrdClusterEntity, uint64, error)
 	FindClusterTopology(id uint64) (*metaentity.ClusterTopologyEntity, error)
+	FindClustersByK8sClusterConfigID(k8sClusterConfigID uint64) ([]*metaentity.K8sCrdClusterEntity, error)
+	FindAllClusters(params *metaentity.ClusterQueryParams) ([]*metaentity.K8sCrdClusterEntity, error)
 }
 
 // K8sCrdClusterProviderImpl K8sCrlClusterProvider 具体实现
@@ -377,6 +379,52 @@ func (k *K8sCrdClusterProviderImpl) ListClusters(
 	return clusterEntities, count, nil
 }
 
+// FindClustersByK8sClusterConfigID 查找 k8s 集群上的 cluster，只返回元数据，不查询集群实际状态
+func (k *K8sCrdClusterProviderImpl) FindClustersByK8sClusterConfigID(k8sClusterConfigID uint64) (
+	[]*metaentity.K8sCrdClusterEntity, error,
+) {
+	clusterModels, err := k.clusterDbAccess.FindByK8sClusterConfigID(k8sClusterConfigID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find cluster with k8sClusterConfigID %d", k8sClusterConfigID)
+	}
+	var clusterEntities []*metaentity.K8sCrdClusterEntity
+	if err = copier.Copy(&clusterEntities, clusterModels); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return clusterEntities, nil
+}
+
+// FindAllClusters 根据 k8s 集群、命名空间和业务查找全部 cluster，只补充 addon 信息，不查询集群实际状态
+func (k *K8sCrdClusterProviderImpl) FindAllClusters(params *metaentity.ClusterQueryParams) (
+	[]*metaentity.K8sCrdClusterEntity, error,
+) {
+	clusterModels, err := k.clusterDbAccess.FindAllByParams(params)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find cluster with params %+v", params)
+	}
+	var clusterEntities []*metaentity.K8sCrdClusterEntity
+	if err = copier.Copy(&clusterEntities, clusterModels); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	addonEntities := make(map[uint64]*metaentity.K8sCrdStorageAddonEntity)
+	for _, clusterEntity := range clusterEntities {
+		addonEntity, ok := addonEntities[clusterEntity.AddonID]
+		if !ok {
+			addonModel, err := k.addonDbAccess.FindByID(clusterEntity.AddonID)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to find addon with id %d", clusterEntity.AddonID)
+			}
+			addonEntity = &metaentity.K8sCrdStorageAddonEntity{}
+			if err = copier.Copy(addonEntity, addonModel); err != nil {
+				return nil, errors.Wrap(err, "failed to copy")
+			}
+			addonEntities[clusterEntity.AddonID] = addonEntity
+		}
+		clusterEntity.AddonInfo = addonEntity
+	}
+	return clusterEntities, nil
+}
+
 // getClusterResource 获取 cluster 资源对象
 func (k *K8sCrdClusterProviderImpl) getClusterResource(
 	clusterEntity *metaentity.K8sCrdClusterEntity,
@@ -36,6 +36,7 @@ type K8sCrdComponentProvider interface {
 	FindComponentByID(id uint64) (*entitys.K8sCrdComponentEntity, error)
 	UpdateComponent(entity *entitys.K8sCrdComponentEntity) (uint64, error)
 	DeleteComponentByClusterID(id uint64) (uint64, error)
+	FindComponentsByClusterID(clusterID uint64) ([]*entitys.K8sCrdComponentEntity, error)
 }
 
 // K8sCrdComponentProviderImpl K8sCrdComponentProvider 具体实现
@@ -103,6 +104,21 @@ func (k K8sCrdComponentProviderImpl) UpdateComponent(entity *entitys.K8sCrdCompo
 	return rows, nil
 }
 
+// FindComponentsByClusterID 根据 cluster ID 查找 component
+func (k K8sCrdComponentProviderImpl) FindComponentsByClusterID(clusterID uint64) (
+	[]*entitys.K8sCrdComponentEntity, error,
+) {
+	componentModels, err := k.dbAccess.FindByClusterID(clusterID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find component with cluster id %d", clusterID)
+	}
+	var componentEntities []*entitys.K8sCrdComponentEntity
+	if err = copier.Copy(&componentEntities, componentModels); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return componentEntities, nil
+}
+
 // NewK8sCrdComponentProvider 创建 K8sCrdComponentDbAccess 接口实现实例
 func NewK8sCrdComponentProvider(dbAccess dbaccess.K8sCrdComponentDbAccess) K8sCrdComponentProvider {
 	return &K8sCrdComponentProviderImpl{dbAccess}
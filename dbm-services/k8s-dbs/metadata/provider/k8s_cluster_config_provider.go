@@ -37,6 +37,7 @@ type K8sClusterConfigProvider interface {
 	FindConfigByName(name string) (*metaentity.K8sClusterConfigEntity, error)
 	UpdateConfig(entity *metaentity.K8sClusterConfigEntity) (uint64, error)
 	GetRegionsByVisibility(public bool) ([]*metaentity.RegionEntity, error)
+	ListActiveConfigs() ([]*metaentity.K8sClusterConfigEntity, error)
 }
 
 // K8sClusterConfigProviderImpl K8sClusterConfigProvider 具体实现
@@ -127,6 +128,19 @@ func (k *K8sClusterConfigProviderImpl) UpdateConfig(entity *metaentity.K8sCluste
 	return rows, nil
 }
 
+// ListActiveConfigs 查询所有有效的 k8s 集群配置
+func (k *K8sClusterConfigProviderImpl) ListActiveConfigs() ([]*metaentity.K8sClusterConfigEntity, error) {
+	configModels, err := k.dbAccess.FindActive()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find active cluster config")
+	}
+	var configEntities []*metaentity.K8sClusterConfigEntity
+	if err = copier.Copy(&configEntities, configModels); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return configEntities, nil
+}
+
 // NewK8sClusterConfigProvider 创建 K8sClusterConfigDbAccess 接口实现实例
 func NewK8sClusterConfigProvider(dbAccess dbaccess.K8sClusterConfigDbAccess) K8sClusterConfigProvider {
 	return &K8sClusterConfigProviderImpl{dbAccess}
@@ -0,0 +1,131 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"k8s-dbs/common/entity"
+	"k8s-dbs/metadata/dbaccess"
+	metaentity "k8s-dbs/metadata/entity"
+	metamodel "k8s-dbs/metadata/model"
+
+	"github.com/pkg/errors"
+
+	"github.com/jinzhu/copier"
+)
+
+// ResourceQuotaProvider 定义 resource quota 业务逻辑层访问接口
+type ResourceQuotaProvider interface {
+	CreateQuota(entity *metaentity.ResourceQuotaEntity) (*metaentity.ResourceQuotaEntity, error)
+	UpdateQuota(entity *metaentity.ResourceQuotaEntity) (uint64, error)
+	DeleteQuotaByID(id uint64) (uint64, error)
+	FindQuotaByID(id uint64) (*metaentity.ResourceQuotaEntity, error)
+	FindActiveQuota(params *metaentity.ResourceQuotaQueryParams) (*metaentity.ResourceQuotaEntity, error)
+	ListQuotas(
+		params *metaentity.ResourceQuotaQueryParams,
+		pagination *entity.Pagination,
+	) ([]*metaentity.ResourceQuotaEntity, uint64, error)
+}
+
+// ResourceQuotaProviderImpl ResourceQuotaProvider 具体实现
+type ResourceQuotaProviderImpl struct {
+	dbAccess dbaccess.ResourceQuotaDbAccess
+}
+
+// CreateQuota 创建配额
+func (k *ResourceQuotaProviderImpl) CreateQuota(
+	entity *metaentity.ResourceQuotaEntity,
+) (*metaentity.ResourceQuotaEntity, error) {
+	quotaModel := &metamodel.ResourceQuotaModel{}
+	if err := copier.Copy(quotaModel, entity); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	addedModel, err := k.dbAccess.Create(quotaModel)
+	if err != nil {
+		return nil, err
+	}
+	addedEntity := &metaentity.ResourceQuotaEntity{}
+	if err = copier.Copy(addedEntity, addedModel); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return addedEntity, nil
+}
+
+// UpdateQuota 更新配额
+func (k *ResourceQuotaProviderImpl) UpdateQuota(entity *metaentity.ResourceQuotaEntity) (uint64, error) {
+	quotaModel := &metamodel.ResourceQuotaModel{}
+	if err := copier.Copy(quotaModel, entity); err != nil {
+		return 0, errors.Wrap(err, "failed to copy")
+	}
+	return k.dbAccess.Update(quotaModel)
+}
+
+// DeleteQuotaByID 删除配额
+func (k *ResourceQuotaProviderImpl) DeleteQuotaByID(id uint64) (uint64, error) {
+	return k.dbAccess.DeleteByID(id)
+}
+
+// FindQuotaByID 查找配额，不存在时返回 nil
+func (k *ResourceQuotaProviderImpl) FindQuotaByID(id uint64) (*metaentity.ResourceQuotaEntity, error) {
+	quotaModel, err := k.dbAccess.FindByID(id)
+	if err != nil || quotaModel == nil {
+		return nil, err
+	}
+	quotaEntity := &metaentity.ResourceQuotaEntity{}
+	if err = copier.Copy(quotaEntity, quotaModel); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return quotaEntity, nil
+}
+
+// FindActiveQuota 查找业务或命名空间当前生效的配额，不存在时返回 nil
+func (k *ResourceQuotaProviderImpl) FindActiveQuota(
+	params *metaentity.ResourceQuotaQueryParams,
+) (*metaentity.ResourceQuotaEntity, error) {
+	quotaModel, err := k.dbAccess.FindActiveByParams(params)
+	if err != nil || quotaModel == nil {
+		return nil, err
+	}
+	quotaEntity := &metaentity.ResourceQuotaEntity{}
+	if err = copier.Copy(quotaEntity, quotaModel); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return quotaEntity, nil
+}
+
+// ListQuotas 分页查询配额
+func (k *ResourceQuotaProviderImpl) ListQuotas(
+	params *metaentity.ResourceQuotaQueryParams,
+	pagination *entity.Pagination,
+) ([]*metaentity.ResourceQuotaEntity, uint64, error) {
+	quotaModels, count, err := k.dbAccess.ListByPage(params, pagination)
+	if err != nil {
+		return nil, 0, err
+	}
+	var quotaEntities []*metaentity.ResourceQuotaEntity
+	if err = copier.Copy(&quotaEntities, quotaModels); err != nil {
+		return nil, 0, errors.Wrap(err, "failed to copy")
+	}
+	return quotaEntities, count, nil
+}
+
+// NewResourceQuotaProvider 创建 ResourceQuotaProvider 接口实现实例
+func NewResourceQuotaProvider(dbAccess dbaccess.ResourceQuotaDbAccess) ResourceQuotaProvider {
+	return &ResourceQuotaProviderImpl{dbAccess: dbAccess}
+}
@@ -0,0 +1,184 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"k8s-dbs/common/entity"
+	"k8s-dbs/metadata/dbaccess"
+	metaentity "k8s-dbs/metadata/entity"
+	metamodel "k8s-dbs/metadata/model"
+
+	"github.com/pkg/errors"
+
+	"github.com/jinzhu/copier"
+)
+
+// TerminalSessionProvider 定义 terminal session 业务逻辑层访问接口
+type TerminalSessionProvider interface {
+	CreateSession(entity *metaentity.TerminalSessionEntity) (*metaentity.TerminalSessionEntity, error)
+	UpdateSession(entity *metaentity.TerminalSessionEntity) (uint64, error)
+	FindSessionByID(id uint64) (*metaentity.TerminalSessionEntity, error)
+	ListSessions(
+		params *metaentity.TerminalSessionQueryParams,
+		pagination *entity.Pagination,
+	) ([]*metaentity.TerminalSessionEntity, uint64, error)
+	CreateCommand(entity *metaentity.TerminalCommandEntity) (*metaentity.TerminalCommandEntity, error)
+	FindCommandsBySessionID(sessionID uint64) ([]*metaentity.TerminalCommandEntity, error)
+	CreateRecording(entity *metaentity.TerminalRecordingEntity) error
+	ListRecordings(sessionID uint64, fromSeq int, limit int) ([]*metaentity.TerminalRecordingEntity, error)
+}
+
+// TerminalSessionProviderImpl TerminalSessionProvider 具体实现
+type TerminalSessionProviderImpl struct {
+	sessionDbAccess   dbaccess.TerminalSessionDbAccess
+	commandDbAccess   dbaccess.TerminalCommandDbAccess
+	recordingDbAccess dbaccess.TerminalRecordingDbAccess
+}
+
+// CreateSession 创建终端会话记录
+func (k *TerminalSessionProviderImpl) CreateSession(
+	entity *metaentity.TerminalSessionEntity,
+) (*metaentity.TerminalSessionEntity, error) {
+	sessionModel := &metamodel.TerminalSessionModel{}
+	if err := copier.Copy(sessionModel, entity); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	addedModel, err := k.sessionDbAccess.Create(sessionModel)
+	if err != nil {
+		return nil, err
+	}
+	addedEntity := &metaentity.TerminalSessionEntity{}
+	if err = copier.Copy(addedEntity, addedModel); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return addedEntity, nil
+}
+
+// UpdateSession 更新终端会话记录
+func (k *TerminalSessionProviderImpl) UpdateSession(entity *metaentity.TerminalSessionEntity) (uint64, error) {
+	sessionModel := &metamodel.TerminalSessionModel{}
+	if err := copier.Copy(sessionModel, entity); err != nil {
+		return 0, errors.Wrap(err, "failed to copy")
+	}
+	return k.sessionDbAccess.Update(sessionModel)
+}
+
+// FindSessionByID 查找终端会话记录
+func (k *TerminalSessionProviderImpl) FindSessionByID(id uint64) (*metaentity.TerminalSessionEntity, error) {
+	sessionModel, err := k.sessionDbAccess.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	sessionEntity := &metaentity.TerminalSessionEntity{}
+	if err = copier.Copy(sessionEntity, sessionModel); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return sessionEntity, nil
+}
+
+// ListSessions 分页查询终端会话记录
+func (k *TerminalSessionProviderImpl) ListSessions(
+	params *metaentity.TerminalSessionQueryParams,
+	pagination *entity.Pagination,
+) ([]*metaentity.TerminalSessionEntity, uint64, error) {
+	sessionModels, count, err := k.sessionDbAccess.ListByPage(params, pagination)
+	if err != nil {
+		return nil, 0, err
+	}
+	var sessionEntities []*metaentity.TerminalSessionEntity
+	if err = copier.Copy(&sessionEntities, sessionModels); err != nil {
+		return nil, 0, errors.Wrap(err, "failed to copy")
+	}
+	return sessionEntities, count, nil
+}
+
+// CreateCommand 创建终端命令审计记录
+func (k *TerminalSessionProviderImpl) CreateCommand(
+	entity *metaentity.TerminalCommandEntity,
+) (*metaentity.TerminalCommandEntity, error) {
+	commandModel := &metamodel.TerminalCommandModel{}
+	if err := copier.Copy(commandModel, entity); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	addedModel, err := k.commandDbAccess.Create(commandModel)
+	if err != nil {
+		return nil, err
+	}
+	addedEntity := &metaentity.TerminalCommandEntity{}
+	if err = copier.Copy(addedEntity, addedModel); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return addedEntity, nil
+}
+
+// FindCommandsBySessionID 查找终端会话的命令审计记录
+func (k *TerminalSessionProviderImpl) FindCommandsBySessionID(
+	sessionID uint64,
+) ([]*metaentity.TerminalCommandEntity, error) {
+	commandModels, err := k.commandDbAccess.FindBySessionID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var commandEntities []*metaentity.TerminalCommandEntity
+	if err = copier.Copy(&commandEntities, commandModels); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return commandEntities, nil
+}
+
+// CreateRecording 保存一个会话录像分块
+func (k *TerminalSessionProviderImpl) CreateRecording(entity *metaentity.TerminalRecordingEntity) error {
+	recordingModel := &metamodel.TerminalRecordingModel{}
+	if err := copier.Copy(recordingModel, entity); err != nil {
+		return errors.Wrap(err, "failed to copy")
+	}
+	_, err := k.recordingDbAccess.Create(recordingModel)
+	return err
+}
+
+// ListRecordings 按顺序查找会话从 fromSeq 开始的 limit 个录像分块
+func (k *TerminalSessionProviderImpl) ListRecordings(
+	sessionID uint64,
+	fromSeq int,
+	limit int,
+) ([]*metaentity.TerminalRecordingEntity, error) {
+	recordingModels, err := k.recordingDbAccess.ListBySessionID(sessionID, fromSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	var recordingEntities []*metaentity.TerminalRecordingEntity
+	if err = copier.Copy(&recordingEntities, recordingModels); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return recordingEntities, nil
+}
+
+// NewTerminalSessionProvider 创建 TerminalSessionProvider 接口实现实例
+func NewTerminalSessionProvider(
+	sessionDbAccess dbaccess.TerminalSessionDbAccess,
+	commandDbAccess dbaccess.TerminalCommandDbAccess,
+	recordingDbAccess dbaccess.TerminalRecordingDbAccess,
+) TerminalSessionProvider {
+	return &TerminalSessionProviderImpl{
+		sessionDbAccess:   sessionDbAccess,
+		commandDbAccess:   commandDbAccess,
+		recordingDbAccess: recordingDbAccess,
+	}
+}
@@ -0,0 +1,45 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package response
+
+import (
+	commtypes "k8s-dbs/common/types"
+)
+
+// K8sClusterDriftResponse response vo 定义
+type K8sClusterDriftResponse struct {
+	ID                 uint64                 `json:"id"`
+	K8sClusterConfigID uint64                 `json:"k8sClusterConfigId"`
+	CrdClusterID       uint64                 `json:"crdClusterId"`
+	ClusterName        string                 `json:"clusterName"`
+	Namespace          string                 `json:"namespace"`
+	ResourceType       string                 `json:"resourceType"`
+	ResourceName       string                 `json:"resourceName"`
+	DriftType          string                 `json:"driftType"`
+	MetaValue          string                 `json:"metaValue"`
+	ActualValue        string                 `json:"actualValue"`
+	Active             bool                   `json:"active"`
+	Repaired           bool                   `json:"repaired"`
+	Description        string                 `json:"description"`
+	CreatedBy          string                 `json:"createdBy"`
+	CreatedAt          commtypes.JSONDatetime `json:"createdAt"`
+	UpdatedBy          string                 `json:"updatedBy"`
+	UpdatedAt          commtypes.JSONDatetime `json:"updatedAt"`
+}
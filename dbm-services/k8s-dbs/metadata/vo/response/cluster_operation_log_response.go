@@ -40,6 +40,10 @@ var requestTypeMapping = map[string]string{
 	"VolumeExpansion":      "磁盘扩容",
 	"UpgradeComp":          "升级组件",
 	"ExposeService":        "暴露服务",
+	"BackupCluster":        "集群备份",
+	"RestoreCluster":       "集群恢复",
+	"SwitchoverComponent":  "组件主从切换",
+	"ReconfigureComponent": "组件参数变更",
 	"Undefined":            "未知操作",
 	"CreateK8sNs":          "创建命名空间",
 	"DeleteK8sPod":         "删除 Pod 实例",
@@ -0,0 +1,229 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metric
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MetricScope 指标的统计范围
+type MetricScope string
+
+// 指标统计范围，cluster 指标按集群所有 Pod 统计，component 指标需要指定组件
+const (
+	ClusterScope   MetricScope = "cluster"
+	ComponentScope MetricScope = "component"
+)
+
+// MetricDefinition 指标定义，Template 为 PromQL 模板，按 Pod 分组返回时间序列
+//
+// 模板可用变量：
+//   - Selector: 按 k8s 集群、命名空间和 Pod 过滤的标签选择器
+//   - Window: rate 等函数使用的时间窗口，随查询步长自动放大
+type MetricDefinition struct {
+	Name        string      `json:"name"`
+	Unit        string      `json:"unit"`
+	Description string      `json:"description"`
+	Scope       MetricScope `json:"scope"`
+	Template    string      `json:"-"`
+}
+
+// 容器通用指标，所有 addon 类型都支持
+var commonMetricDefinitions = []*MetricDefinition{
+	{
+		Name:        "cpu_usage",
+		Unit:        "core",
+		Description: "CPU 使用量",
+		Scope:       ClusterScope,
+		Template: `sum by (pod) (rate(container_cpu_usage_seconds_total{ {{- .Selector}}, container!="", ` +
+			`container!="POD"}[{{.Window}}]))`,
+	},
+	{
+		Name:        "memory_usage",
+		Unit:        "GB",
+		Description: "内存使用量",
+		Scope:       ClusterScope,
+		Template: `sum by (pod) (container_memory_working_set_bytes{ {{- .Selector}}, container!="", ` +
+			`container!="POD"}) / 1073741824`,
+	},
+	{
+		Name:        "read_iops",
+		Unit:        "ops/s",
+		Description: "磁盘读 IOPS",
+		Scope:       ClusterScope,
+		Template:    `sum by (pod) (rate(container_fs_reads_total{ {{- .Selector}}, container!=""}[{{.Window}}]))`,
+	},
+	{
+		Name:        "write_iops",
+		Unit:        "ops/s",
+		Description: "磁盘写 IOPS",
+		Scope:       ClusterScope,
+		Template:    `sum by (pod) (rate(container_fs_writes_total{ {{- .Selector}}, container!=""}[{{.Window}}]))`,
+	},
+	{
+		Name:        "network_receive",
+		Unit:        "B/s",
+		Description: "网络接收速率",
+		Scope:       ClusterScope,
+		Template:    `sum by (pod) (rate(container_network_receive_bytes_total{ {{- .Selector}}}[{{.Window}}]))`,
+	},
+	{
+		Name:        "network_transmit",
+		Unit:        "B/s",
+		Description: "网络发送速率",
+		Scope:       ClusterScope,
+		Template:    `sum by (pod) (rate(container_network_transmit_bytes_total{ {{- .Selector}}}[{{.Window}}]))`,
+	},
+}
+
+// addon 特有指标，依赖 addon 自身 exporter 上报的指标
+var addonMetricDefinitions = map[string][]*MetricDefinition{
+	"victoriametrics": {
+		{
+			Name:        "ingestion_rate",
+			Unit:        "rows/s",
+			Description: "数据写入速率",
+			Scope:       ComponentScope,
+			Template:    `sum by (pod) (rate(vm_rows_inserted_total{ {{- .Selector}}}[{{.Window}}]))`,
+		},
+		{
+			Name:        "query_qps",
+			Unit:        "req/s",
+			Description: "查询请求 QPS",
+			Scope:       ComponentScope,
+			Template: `sum by (pod) (rate(vm_http_requests_total{ {{- .Selector}}, ` +
+				`path=~".*/api/v1/query.*"}[{{.Window}}]))`,
+		},
+		{
+			Name:        "data_size",
+			Unit:        "GB",
+			Description: "存储数据量",
+			Scope:       ComponentScope,
+			Template:    `sum by (pod) (vm_data_size_bytes_value{ {{- .Selector}}}) / 1073741824`,
+		},
+	},
+	"milvus": {
+		{
+			Name:        "query_qps",
+			Unit:        "req/s",
+			Description: "Proxy 请求 QPS",
+			Scope:       ComponentScope,
+			Template:    `sum by (pod) (rate(milvus_proxy_req_count{ {{- .Selector}}}[{{.Window}}]))`,
+		},
+		{
+			Name:        "search_latency_p99",
+			Unit:        "ms",
+			Description: "检索请求 P99 延迟",
+			Scope:       ComponentScope,
+			Template: `histogram_quantile(0.99, sum by (pod, le) ` +
+				`(rate(milvus_proxy_sq_latency_bucket{ {{- .Selector}}}[{{.Window}}])))`,
+		},
+	},
+	"mysql": {
+		{
+			Name:        "query_qps",
+			Unit:        "req/s",
+			Description: "查询 QPS",
+			Scope:       ComponentScope,
+			Template:    `sum by (pod) (rate(mysql_global_status_queries{ {{- .Selector}}}[{{.Window}}]))`,
+		},
+		{
+			Name:        "replication_lag",
+			Unit:        "s",
+			Description: "主从复制延迟",
+			Scope:       ComponentScope,
+			Template:    `max by (pod) (mysql_slave_status_seconds_behind_master{ {{- .Selector}}})`,
+		},
+	},
+}
+
+// GetMetricDefinitions 获取 addon 支持的指标定义，包括容器通用指标和 addon 特有指标
+func GetMetricDefinitions(addonType string) []*MetricDefinition {
+	definitions := make([]*MetricDefinition, 0, len(commonMetricDefinitions)+len(addonMetricDefinitions[addonType]))
+	definitions = append(definitions, commonMetricDefinitions...)
+	return append(definitions, addonMetricDefinitions[addonType]...)
+}
+
+// FindMetricDefinition 按名称查找 addon 的指标定义
+func FindMetricDefinition(addonType string, name string) (*MetricDefinition, error) {
+	for _, definition := range GetMetricDefinitions(addonType) {
+		if definition.Name == name {
+			return definition, nil
+		}
+	}
+	return nil, fmt.Errorf("metric %s is not supported by addon type %s", name, addonType)
+}
+
+// BuildPromQL 根据指标定义和查询参数渲染 PromQL
+func (m *MetricDefinition) BuildPromQL(params *MetricRangeQueryParams, window time.Duration) (string, error) {
+	if m.Scope == ComponentScope && params.ComponentName == "" {
+		return "", fmt.Errorf("metric %s requires componentName", m.Name)
+	}
+	if params.ComponentName == "" && len(params.ComponentNames) == 0 {
+		return "", fmt.Errorf("metric %s requires component names of cluster %s", m.Name, params.ClusterName)
+	}
+	tmpl, err := template.New(m.Name).Parse(m.Template)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]string{
+		"Selector": buildPodSelector(params),
+		"Window":   formatPromDuration(window),
+	})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// buildPodSelector 构建 Pod 标签选择器，kubeblocks 的 Pod 名称格式为 {cluster}-{component}-{ordinal}
+// 按完整的组件名称和序号匹配，避免匹配到以 {cluster}- 为前缀的其它集群的 Pod
+func buildPodSelector(params *MetricRangeQueryParams) string {
+	componentNames := params.ComponentNames
+	if params.ComponentName != "" {
+		componentNames = []string{params.ComponentName}
+	}
+	quotedNames := make([]string, 0, len(componentNames))
+	for _, name := range componentNames {
+		quotedNames = append(quotedNames, regexp.QuoteMeta(name))
+	}
+	podRegex := fmt.Sprintf(`%s-(%s)-\d+`, regexp.QuoteMeta(params.ClusterName), strings.Join(quotedNames, "|"))
+	labels := []string{
+		fmt.Sprintf(`bcs_cluster_id="%s"`, escapeLabelValue(params.K8sClusterName)),
+		fmt.Sprintf(`namespace="%s"`, escapeLabelValue(params.Namespace)),
+		fmt.Sprintf(`pod=~"%s"`, escapeLabelValue(podRegex)),
+	}
+	return strings.Join(labels, ", ")
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
+
+// formatPromDuration 将时间间隔格式化为 PromQL 的时间窗口，单位秒
+func formatPromDuration(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d.Seconds()))
+}
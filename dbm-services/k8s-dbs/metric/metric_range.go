@@ -0,0 +1,147 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metric
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultMetricRange 未指定开始时间时默认查询最近 1 小时
+	DefaultMetricRange = time.Hour
+	// MaxMetricRange 单次查询允许的最大时间范围
+	MaxMetricRange = 30 * 24 * time.Hour
+	// MaxDataPoints 未指定步长时每条时间序列返回的最大点数，用于自动降采样
+	MaxDataPoints = 300
+	// MinStep 最小查询步长，与采集间隔保持一致
+	MinStep = 30 * time.Second
+	// MinRateWindow rate 等函数的最小时间窗口，需覆盖至少两个采集点
+	MinRateWindow = 2 * time.Minute
+)
+
+// MetricRangeQueryParams 指标时间范围查询参数
+type MetricRangeQueryParams struct {
+	AddonType      string `json:"addonType"`
+	K8sClusterName string `json:"k8sClusterName"`
+	Namespace      string `json:"namespace"`
+	ClusterName    string `json:"clusterName"`
+	ComponentName  string `json:"componentName"`
+	// ComponentNames 集群的全部组件名称，未指定 ComponentName 时按这些组件匹配集群的 Pod
+	ComponentNames []string      `json:"componentNames"`
+	MetricNames    []string      `json:"metricNames"`
+	Start          time.Time     `json:"start"`
+	End            time.Time     `json:"end"`
+	Step           time.Duration `json:"step"`
+}
+
+// MetricPoint 时间序列的数据点，Timestamp 为 unix 秒
+type MetricPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// MetricSeries 单条时间序列
+type MetricSeries struct {
+	Labels map[string]string `json:"labels"`
+	Points []MetricPoint     `json:"points"`
+}
+
+// MetricResult 单个指标的查询结果
+type MetricResult struct {
+	Name        string          `json:"name"`
+	Unit        string          `json:"unit"`
+	Description string          `json:"description"`
+	Start       int64           `json:"start"`
+	End         int64           `json:"end"`
+	Step        int64           `json:"step"`
+	Series      []*MetricSeries `json:"series"`
+}
+
+// MetricRangeQuerier 按时间范围执行 PromQL 查询的接口
+type MetricRangeQuerier interface {
+	QueryRange(promQL string, start, end time.Time, step time.Duration) ([]*MetricSeries, error)
+}
+
+// QueryRange 按时间范围查询集群或组件的多个指标
+func (c *ClusterMetricFetcherFactory) QueryRange(params *MetricRangeQueryParams) ([]*MetricResult, error) {
+	return queryMetricRange(&VMClusterMetricFetcher{}, params)
+}
+
+func queryMetricRange(querier MetricRangeQuerier, params *MetricRangeQueryParams) ([]*MetricResult, error) {
+	if err := NormalizeTimeRange(params); err != nil {
+		return nil, err
+	}
+	if len(params.MetricNames) == 0 {
+		return nil, fmt.Errorf("metricNames can't be empty")
+	}
+	// rate 的时间窗口不小于步长，保证降采样后每个点覆盖整个步长区间
+	window := time.Duration(math.Max(float64(params.Step), float64(MinRateWindow)))
+	var results []*MetricResult
+	for _, name := range params.MetricNames {
+		definition, err := FindMetricDefinition(params.AddonType, name)
+		if err != nil {
+			return nil, err
+		}
+		promQL, err := definition.BuildPromQL(params, window)
+		if err != nil {
+			return nil, err
+		}
+		series, err := querier.QueryRange(promQL, params.Start, params.End, params.Step)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query metric %s: %w", name, err)
+		}
+		results = append(results, &MetricResult{
+			Name:        definition.Name,
+			Unit:        definition.Unit,
+			Description: definition.Description,
+			Start:       params.Start.Unix(),
+			End:         params.End.Unix(),
+			Step:        int64(params.Step.Seconds()),
+			Series:      series,
+		})
+	}
+	return results, nil
+}
+
+// NormalizeTimeRange 补全默认时间范围并计算查询步长
+// 未指定步长时按 MaxDataPoints 自动降采样，步长向上取整为 MinStep 的整数倍
+func NormalizeTimeRange(params *MetricRangeQueryParams) error {
+	if params.End.IsZero() {
+		params.End = time.Now()
+	}
+	if params.Start.IsZero() {
+		params.Start = params.End.Add(-DefaultMetricRange)
+	}
+	timeRange := params.End.Sub(params.Start)
+	if timeRange <= 0 {
+		return fmt.Errorf("start time must be before end time")
+	}
+	if timeRange > MaxMetricRange {
+		return fmt.Errorf("time range %s exceeds the maximum %s", timeRange, MaxMetricRange)
+	}
+	minStep := time.Duration(math.Ceil(float64(timeRange) / MaxDataPoints))
+	if params.Step < minStep {
+		params.Step = minStep
+	}
+	params.Step = time.Duration(math.Ceil(float64(params.Step)/float64(MinStep))) * MinStep
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metric
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakePrometheusServer 模拟 VictoriaMetrics 的 query_range 接口，记录收到的 PromQL
+func newFakePrometheusServer(t *testing.T, queries *[]url.Values) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/select/0/prometheus/api/v1/query_range", r.URL.Path)
+		assert.NoError(t, r.ParseForm())
+		*queries = append(*queries, r.PostForm)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"pod":"vm-test-vmstorage-0"},
+			 "values":[[1756000000,"0.5"],[1756000060,"NaN"],[1756000120,"1.25"]]},
+			{"metric":{"pod":"vm-test-vmstorage-1"},"values":[[1756000000,"0.75"]]}]}}`)
+	}))
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	assert.NoError(t, err)
+	_ = os.Setenv("VM_METRIC_SERVER_HOST", host)
+	_ = os.Setenv("VM_METRIC_SERVER_PORT", port)
+	return server
+}
+
+func TestQueryRange(t *testing.T) {
+	var queries []url.Values
+	server := newFakePrometheusServer(t, &queries)
+	defer server.Close()
+
+	end := time.Unix(1756003600, 0)
+	params := &MetricRangeQueryParams{
+		AddonType:      "victoriametrics",
+		K8sClusterName: "BCS-K8S-0000",
+		Namespace:      "vm-dbm-0000",
+		ClusterName:    "vm-test",
+		ComponentName:  "vmstorage",
+		MetricNames:    []string{"cpu_usage", "ingestion_rate"},
+		Start:          end.Add(-6 * time.Hour),
+		End:            end,
+	}
+	results, err := FetcherFactory.QueryRange(params)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(results))
+	assert.Equal(t, "core", results[0].Unit)
+	assert.Equal(t, int64(90), results[0].Step)
+	assert.Equal(t, 2, len(results[0].Series))
+	// NaN 数据点被过滤
+	assert.Equal(t, []MetricPoint{{1756000000, 0.5}, {1756000120, 1.25}}, results[0].Series[0].Points)
+
+	assert.Equal(t, 2, len(queries))
+	assert.Equal(t, "90s", queries[0].Get("step"))
+	assert.Equal(t, "1756003600", queries[0].Get("end"))
+	assert.Equal(t, `sum by (pod) (rate(container_cpu_usage_seconds_total{bcs_cluster_id="BCS-K8S-0000", `+
+		`namespace="vm-dbm-0000", pod=~"vm-test-(vmstorage)-\\d+", container!="", container!="POD"}[120s]))`,
+		queries[0].Get("query"))
+	assert.Contains(t, queries[1].Get("query"), "vm_rows_inserted_total")
+}
+
+func TestQueryRangeUnsupportedMetric(t *testing.T) {
+	params := &MetricRangeQueryParams{
+		AddonType:   "surrealdb",
+		ClusterName: "surreal-test",
+		MetricNames: []string{"replication_lag"},
+	}
+	_, err := FetcherFactory.QueryRange(params)
+	assert.Error(t, err)
+
+	// 组件级指标需要指定组件名称
+	params.AddonType = "mysql"
+	_, err = FetcherFactory.QueryRange(params)
+	assert.ErrorContains(t, err, "requires componentName")
+}
+
+func TestBuildPromQLPodSelector(t *testing.T) {
+	definition, err := FindMetricDefinition("victoriametrics", "memory_usage")
+	assert.NoError(t, err)
+	params := &MetricRangeQueryParams{
+		K8sClusterName: "BCS-K8S-0000",
+		Namespace:      "vm-dbm-0000",
+		ClusterName:    "vm-test",
+	}
+	// 没有组件名称时无法精确匹配集群的 Pod
+	_, err = definition.BuildPromQL(params, time.Minute)
+	assert.ErrorContains(t, err, "requires component names")
+
+	params.ComponentNames = []string{"vmstorage", "vminsert"}
+	promQL, err := definition.BuildPromQL(params, time.Minute)
+	assert.NoError(t, err)
+	assert.Contains(t, promQL, `pod=~"vm-test-(vmstorage|vminsert)-\\d+"`)
+
+	pattern := regexp.MustCompile(`^vm-test-(vmstorage|vminsert)-\d+$`)
+	assert.True(t, pattern.MatchString("vm-test-vmstorage-0"))
+	// 以 vm-test- 为前缀的其它集群的 Pod 不匹配
+	assert.False(t, pattern.MatchString("vm-test-2-vmstorage-0"))
+}
+
+func TestNormalizeTimeRange(t *testing.T) {
+	end := time.Unix(1756003600, 0)
+	params := &MetricRangeQueryParams{End: end}
+	assert.NoError(t, NormalizeTimeRange(params))
+	assert.Equal(t, end.Add(-DefaultMetricRange), params.Start)
+	assert.Equal(t, MinStep, params.Step)
+
+	// 步长过小时按最大点数降采样，并对齐到 MinStep 的整数倍
+	params = &MetricRangeQueryParams{Start: end.Add(-7 * 24 * time.Hour), End: end, Step: time.Minute}
+	assert.NoError(t, NormalizeTimeRange(params))
+	assert.Equal(t, 2040*time.Second, params.Step)
+
+	params = &MetricRangeQueryParams{Start: end, End: end.Add(-time.Hour)}
+	assert.Error(t, NormalizeTimeRange(params))
+
+	params = &MetricRangeQueryParams{Start: end.Add(-31 * 24 * time.Hour), End: end}
+	assert.Error(t, NormalizeTimeRange(params))
+}
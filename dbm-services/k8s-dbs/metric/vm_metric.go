@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 	"text/template"
 	"time"
@@ -33,8 +34,9 @@ import (
 )
 
 const (
-	BaseVMApiV1Path     = "http://%s:%s/select/0/prometheus/api/v1/"
-	VMApiV1QueryPattern = BaseVMApiV1Path + "query"
+	BaseVMApiV1Path          = "http://%s:%s/select/0/prometheus/api/v1/"
+	VMApiV1QueryPattern      = BaseVMApiV1Path + "query"
+	VMApiV1QueryRangePattern = BaseVMApiV1Path + "query_range"
 )
 
 var pvcStorageUsageTemplate = `sum(vm_data_size_bytes_value{bcs_cluster_id="{{.ClusterID}}", 
@@ -64,9 +66,7 @@ func (v *VMClusterMetricFetcher) buildPvcStorageUsagePromQL(params *ClusterMetri
 
 // GetStorageUsage 获取存储使用量
 func (v *VMClusterMetricFetcher) GetStorageUsage(params *ClusterMetricQueryParams) (float64, error) {
-	vmMetricServerHost := env.GetString("VM_METRIC_SERVER_HOST", "localhost")
-	vmMetricServerPort := env.GetString("VM_METRIC_SERVER_PORT", "8080")
-	url := fmt.Sprintf(VMApiV1QueryPattern, vmMetricServerHost, vmMetricServerPort)
+	url := buildVMApiURL(VMApiV1QueryPattern)
 	promQL, err := v.buildPvcStorageUsagePromQL(params)
 	if err != nil {
 		return 0, err
@@ -102,15 +102,85 @@ func (v *VMClusterMetricFetcher) GetStorageUsage(params *ClusterMetricQueryParam
 	return storageSizeGB, nil
 }
 
+// QueryRange 按时间范围执行 PromQL 查询，返回 matrix 类型的时间序列，忽略 NaN 和 Inf 数据点
+func (v *VMClusterMetricFetcher) QueryRange(
+	promQL string,
+	start, end time.Time,
+	step time.Duration,
+) ([]*MetricSeries, error) {
+	requestParams := map[string]string{
+		"query": promQL,
+		"start": strconv.FormatInt(start.Unix(), 10),
+		"end":   strconv.FormatInt(end.Unix(), 10),
+		"step":  formatPromDuration(step),
+	}
+	httpResponse, err := commutil.BaseHTTPClient.PostForm(buildVMApiURL(VMApiV1QueryRangePattern), requestParams)
+	if err != nil {
+		return nil, err
+	}
+	var vmQueryResponse VMQueryResponse
+	if err = json.Unmarshal(httpResponse, &vmQueryResponse); err != nil {
+		return nil, err
+	}
+	if vmQueryResponse.Status != "success" {
+		return nil, fmt.Errorf("prometheus range query failed, status: %s, error: %s",
+			vmQueryResponse.Status, vmQueryResponse.Error)
+	}
+	series := make([]*MetricSeries, 0, len(vmQueryResponse.Data.Result))
+	for _, result := range vmQueryResponse.Data.Result {
+		metricSeries := &MetricSeries{
+			Labels: result.Metric,
+			Points: make([]MetricPoint, 0, len(result.Values)),
+		}
+		for _, value := range result.Values {
+			point, ok := parseMetricPoint(value)
+			if !ok {
+				continue
+			}
+			metricSeries.Points = append(metricSeries.Points, point)
+		}
+		series = append(series, metricSeries)
+	}
+	return series, nil
+}
+
+// parseMetricPoint 解析 [timestamp, "value"] 格式的数据点
+func parseMetricPoint(value []interface{}) (MetricPoint, bool) {
+	if len(value) < 2 {
+		return MetricPoint{}, false
+	}
+	timestamp, ok := value[0].(float64)
+	if !ok {
+		return MetricPoint{}, false
+	}
+	metricValue, err := strconv.ParseFloat(fmt.Sprintf("%v", value[1]), 64)
+	if err != nil || math.IsNaN(metricValue) || math.IsInf(metricValue, 0) {
+		return MetricPoint{}, false
+	}
+	return MetricPoint{
+		Timestamp: int64(timestamp),
+		Value:     commutil.RoundToDecimal(metricValue, 3),
+	}, true
+}
+
+// buildVMApiURL 根据环境变量构建 VictoriaMetrics 查询地址
+func buildVMApiURL(pattern string) string {
+	vmMetricServerHost := env.GetString("VM_METRIC_SERVER_HOST", "localhost")
+	vmMetricServerPort := env.GetString("VM_METRIC_SERVER_PORT", "8080")
+	return fmt.Sprintf(pattern, vmMetricServerHost, vmMetricServerPort)
+}
+
 // VMQueryResponse vm http 请求响应结构体
 type VMQueryResponse struct {
 	Status    string `json:"status"`
+	Error     string `json:"error"`
 	IsPartial bool   `json:"isPartial"`
 	Data      struct {
 		ResultType string `json:"resultType"`
 		Result     []struct {
 			Metric map[string]string `json:"metric"`
 			Value  []interface{}     `json:"value"`
+			Values [][]interface{}   `json:"values"`
 		}
 	}
 	//nolint:unused
@@ -58,6 +58,11 @@ func BuildClusterRouter(db *gorm.DB, baseRouter *gin.RouterGroup) {
 		opsRequestGroup.POST("/upgrade", clusterController.UpgradeCluster)
 		opsRequestGroup.POST("/vexpansion", clusterController.VolumeExpansion)
 		opsRequestGroup.POST("/expose", clusterController.ExposeCluster)
+		opsRequestGroup.POST("/backup", clusterController.BackupCluster)
+		opsRequestGroup.POST("/restore", clusterController.RestoreCluster)
+		opsRequestGroup.POST("/backup/list", clusterController.ListBackups)
+		opsRequestGroup.POST("/switchover", clusterController.Switchover)
+		opsRequestGroup.POST("/reconfigure", clusterController.Reconfigure)
 		opsRequestGroup.POST("/describe", clusterController.DescribeOpsRequest)
 		opsRequestGroup.POST("/status", clusterController.GetOpsRequestStatus)
 	}
@@ -115,7 +120,8 @@ func BuildOpsRequestProvider(
 		opsRequestProviderBuilder.WithClusterConfigMeta(coreAPIProviders.ClusterConfigProvider),
 		opsRequestProviderBuilder.WithReqRecordMeta(coreAPIProviders.RequestRecordProvider),
 		opsRequestProviderBuilder.WithReleaseMeta(coreAPIProviders.ClusterReleaseProvider),
-		opsRequestProviderBuilder.WithClusterProvider(clusterProvider))
+		opsRequestProviderBuilder.WithClusterProvider(clusterProvider),
+		opsRequestProviderBuilder.WithQuota(routerutil.BuildQuotaProvider(db)))
 
 	if err != nil {
 		slog.Error("build ops request provider error", "error", err)
@@ -0,0 +1,52 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"k8s-dbs/core/api/controller"
+	coreprovider "k8s-dbs/core/provider"
+	metadbaccess "k8s-dbs/metadata/dbaccess"
+	metaprovider "k8s-dbs/metadata/provider"
+	routerutil "k8s-dbs/router/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BuildMetricRouter 集群监控指标路由构建
+func BuildMetricRouter(db *gorm.DB, baseRouter *gin.RouterGroup) {
+	k8sClusterConfigProvider := metaprovider.NewK8sClusterConfigProvider(metadbaccess.NewK8sClusterConfigDbAccess(db))
+	componentMetaProvider := metaprovider.NewK8sCrdComponentProvider(metadbaccess.NewK8sCrdComponentAccess(db))
+	metricProvider := coreprovider.NewMetricProvider(
+		k8sClusterConfigProvider,
+		routerutil.BuildClusterMetaProvider(db),
+		componentMetaProvider,
+	)
+	metricController := controller.NewMetricController(metricProvider)
+	metricGroup := baseRouter.Group("/metric")
+	{
+		metricGroup.GET("/definitions", metricController.ListMetricDefinitions)
+		metricGroup.GET("/query_range", metricController.QueryClusterMetrics)
+	}
+}
+
+func init() {
+	routerutil.RegisterAPIRouterBuilder(BuildMetricRouter)
+}
@@ -37,13 +37,30 @@ func BuildTerminalRouter(db *gorm.DB, baseRouter *gin.RouterGroup) {
 	{
 		terminalRouter.GET("", terminalController.OpenTerminal)
 	}
+	sessionController := controller.NewSessionController(initSessionProvider(db))
+	sessionGroup := terminalRouter.Group("/session")
+	{
+		sessionGroup.GET("", sessionController.ListSessions)
+		sessionGroup.GET("/:id/commands", sessionController.ListCommands)
+		sessionGroup.GET("/:id/replay", sessionController.ReplaySession)
+		sessionGroup.GET("/:id/download", sessionController.DownloadSession)
+	}
+}
+
+// initSessionProvider 初始化 TerminalSessionProvider
+func initSessionProvider(db *gorm.DB) metaprovider.TerminalSessionProvider {
+	return metaprovider.NewTerminalSessionProvider(
+		metadbaccess.NewTerminalSessionDbAccess(db),
+		metadbaccess.NewTerminalCommandDbAccess(db),
+		metadbaccess.NewTerminalRecordingDbAccess(db),
+	)
 }
 
 // initClusterController 初始化 ClusterController
 func initTerminalController(db *gorm.DB) *controller.ContainerController {
 	k8sClusterConfigDbAccess := metadbaccess.NewK8sClusterConfigDbAccess(db)
 	k8sClusterConfigProvider := metaprovider.NewK8sClusterConfigProvider(k8sClusterConfigDbAccess)
-	containerProvider := terminalprovider.NewTerminalProvider(k8sClusterConfigProvider)
+	containerProvider := terminalprovider.NewTerminalProvider(k8sClusterConfigProvider, initSessionProvider(db))
 	terminalController := controller.NewContainerController(containerProvider)
 	return terminalController
 }
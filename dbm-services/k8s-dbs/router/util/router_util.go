@@ -22,6 +22,7 @@ package util
 import (
 	"k8s-dbs/common/api"
 	coreprovider "k8s-dbs/core/provider"
+	"k8s-dbs/core/reconciler"
 	metadbaccess "k8s-dbs/metadata/dbaccess"
 	metaprovider "k8s-dbs/metadata/provider"
 	"log/slog"
@@ -71,6 +72,7 @@ func BuildClusterProvider(db *gorm.DB) *coreprovider.ClusterProvider {
 		clusterProviderBuilder.WithReleaseMeta(coreAPIProviders.ClusterReleaseProvider),
 		clusterProviderBuilder.WithAddonMeta(coreAPIProviders.AddonMetaProvider),
 		clusterProviderBuilder.WithClusterTagsMeta(coreAPIProviders.ClusterTagProvider),
+		clusterProviderBuilder.WithQuota(BuildQuotaProvider(db)),
 	)
 	if err != nil {
 		slog.Error("failed to build cluster provider", "error", err)
@@ -79,6 +81,33 @@ func BuildClusterProvider(db *gorm.DB) *coreprovider.ClusterProvider {
 	return clusterProvider
 }
 
+// BuildQuotaProvider 构建资源配额 QuotaProvider
+func BuildQuotaProvider(db *gorm.DB) *coreprovider.QuotaProvider {
+	quotaMetaProvider := metaprovider.NewResourceQuotaProvider(metadbaccess.NewResourceQuotaDbAccess(db))
+	return coreprovider.NewQuotaProvider(
+		quotaMetaProvider,
+		BuildClusterMetaProvider(db),
+		metaprovider.NewK8sClusterConfigProvider(metadbaccess.NewK8sClusterConfigDbAccess(db)),
+	)
+}
+
+// BuildDriftReconciler 构建元数据差异巡检 DriftReconciler
+func BuildDriftReconciler(db *gorm.DB) *reconciler.DriftReconciler {
+	coreAPIProviders, err := BuildCoreAPIProviders(db)
+	if err != nil {
+		slog.Error("build common providers error", "error", err)
+		panic(err)
+	}
+	driftMetaProvider := metaprovider.NewK8sClusterDriftProvider(metadbaccess.NewK8sClusterDriftDbAccess(db))
+	return reconciler.NewDriftReconciler(
+		coreAPIProviders.ClusterConfigProvider,
+		coreAPIProviders.ClusterMetaProvider,
+		coreAPIProviders.ComponentMetaProvider,
+		coreAPIProviders.ClusterReleaseProvider,
+		driftMetaProvider,
+	)
+}
+
 // BuildCoreAPIProviders 构建 core api providers
 func BuildCoreAPIProviders(db *gorm.DB) (*CoreAPIProviders, error) {
 	clusterMetaProvider := BuildClusterMetaProvider(db)
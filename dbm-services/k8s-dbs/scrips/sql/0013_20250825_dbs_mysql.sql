@@ -0,0 +1,14 @@
+-- Create a database and set character set and collation
+
+USE bkbase_dbs;
+SET NAMES utf8;
+
+ALTER TABLE tb_k8s_crd_opsrequest MODIFY COLUMN opsrequest_type varchar(100) COMMENT '操作类型 Restart/Start/Stop/Switchover/Upgrade/HorizontalScaling/VerticalScaling/VolumeExpansion/Expose/Backup/Restore/Reconfiguring';
+
+INSERT INTO tb_operation_definition (operation_name, operation_target, description, created_by, updated_by)
+SELECT 'SwitchoverComponent', 'component', '组件主从切换', 'admin', 'admin' FROM DUAL
+WHERE NOT EXISTS (SELECT 1 FROM tb_operation_definition WHERE operation_name = 'SwitchoverComponent');
+
+INSERT INTO tb_operation_definition (operation_name, operation_target, description, created_by, updated_by)
+SELECT 'ReconfigureComponent', 'component', '组件参数变更', 'admin', 'admin' FROM DUAL
+WHERE NOT EXISTS (SELECT 1 FROM tb_operation_definition WHERE operation_name = 'ReconfigureComponent');
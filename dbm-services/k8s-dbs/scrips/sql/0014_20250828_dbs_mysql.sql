@@ -0,0 +1,29 @@
+-- Create a database and set character set and collation
+
+USE bkbase_dbs;
+SET NAMES utf8;
+
+--
+-- Table structure for table tb_k8s_cluster_drift
+--
+CREATE TABLE IF NOT EXISTS tb_k8s_cluster_drift (
+    id bigint PRIMARY KEY AUTO_INCREMENT COMMENT '主键 id',
+    k8s_cluster_config_id bigint NOT NULL COMMENT '关联 tb_k8s_cluster_config 主键 id',
+    crd_cluster_id bigint NOT NULL DEFAULT 0 COMMENT '关联 tb_k8s_crd_cluster 主键 id，元数据中不存在时为 0',
+    cluster_name varchar(32) NOT NULL COMMENT '集群名称',
+    namespace varchar(32) NOT NULL COMMENT '命名空间',
+    resource_type varchar(32) NOT NULL COMMENT '资源类型 cluster/component/release',
+    resource_name varchar(64) NOT NULL COMMENT '资源名称',
+    drift_type varchar(64) NOT NULL COMMENT '差异类型',
+    meta_value varchar(255) Null COMMENT '元数据中的值',
+    actual_value varchar(255) Null COMMENT 'k8s 集群中的实际值',
+    active tinyint(1) NOT NULL DEFAULT 1 COMMENT '0:已消除，1:当前存在',
+    repaired tinyint(1) NOT NULL DEFAULT 0 COMMENT '元数据是否已自动修复，0:未修复，1:已修复',
+    description varchar(255) Null COMMENT '差异描述',
+    created_by varchar(50) NOT NULL COMMENT '创建者',
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    updated_by varchar(50) NOT NULL COMMENT '更新者',
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    INDEX idx_config_active (k8s_cluster_config_id, active),
+    INDEX idx_cluster_name (cluster_name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT '元数据与 k8s 集群差异记录表';
@@ -0,0 +1,55 @@
+-- Create a database and set character set and collation
+
+USE bkbase_dbs;
+SET NAMES utf8;
+
+--
+-- Table structure for table tb_terminal_session
+--
+CREATE TABLE IF NOT EXISTS tb_terminal_session (
+    id bigint PRIMARY KEY AUTO_INCREMENT COMMENT '主键 id',
+    k8s_cluster_name varchar(32) NOT NULL COMMENT 'k8s 集群名称',
+    cluster_name varchar(32) NOT NULL COMMENT '集群名称',
+    namespace varchar(32) NOT NULL COMMENT '命名空间',
+    pod_name varchar(128) NOT NULL COMMENT 'pod 名称',
+    container_name varchar(64) Null COMMENT '容器名称',
+    status varchar(32) NOT NULL COMMENT '会话状态 Running/Closed/Failed',
+    start_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '会话开始时间',
+    end_time timestamp Null COMMENT '会话结束时间',
+    recording_size bigint NOT NULL DEFAULT 0 COMMENT '会话录像字节数',
+    truncated tinyint(1) NOT NULL DEFAULT 0 COMMENT '会话录像是否因超过大小限制被截断，0:否，1:是',
+    description varchar(255) Null COMMENT '会话描述',
+    created_by varchar(50) NOT NULL COMMENT '创建者',
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    updated_by varchar(50) NOT NULL COMMENT '更新者',
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    INDEX idx_cluster_pod (k8s_cluster_name, namespace, pod_name),
+    INDEX idx_start_time (start_time)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT '容器终端会话记录表';
+
+--
+-- Table structure for table tb_terminal_command
+--
+CREATE TABLE IF NOT EXISTS tb_terminal_command (
+    id bigint PRIMARY KEY AUTO_INCREMENT COMMENT '主键 id',
+    session_id bigint NOT NULL COMMENT '关联 tb_terminal_session 主键 id',
+    command text NOT NULL COMMENT '执行的命令',
+    blocked tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否被禁止执行，0:否，1:是',
+    deny_rule varchar(255) Null COMMENT '命中的禁止规则',
+    executed_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '执行时间',
+    created_by varchar(50) NOT NULL COMMENT '创建者',
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    INDEX idx_session_id (session_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT '容器终端命令审计表';
+
+--
+-- Table structure for table tb_terminal_recording
+--
+CREATE TABLE IF NOT EXISTS tb_terminal_recording (
+    id bigint PRIMARY KEY AUTO_INCREMENT COMMENT '主键 id',
+    session_id bigint NOT NULL COMMENT '关联 tb_terminal_session 主键 id',
+    seq int NOT NULL COMMENT '分块序号，从 0 开始',
+    data mediumtext NOT NULL COMMENT 'asciicast v2 格式的会话录像分块',
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY uk_session_seq (session_id, seq)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT '容器终端会话录像分块表';
@@ -0,0 +1,25 @@
+-- Create a database and set character set and collation
+
+USE bkbase_dbs;
+SET NAMES utf8;
+
+--
+-- Table structure for table tb_resource_quota
+--
+CREATE TABLE IF NOT EXISTS tb_resource_quota (
+    id bigint PRIMARY KEY AUTO_INCREMENT COMMENT '主键 id',
+    quota_type varchar(32) NOT NULL COMMENT '配额类型 business/namespace',
+    bk_biz_id bigint NOT NULL DEFAULT 0 COMMENT '业务 id，业务配额使用',
+    k8s_cluster_config_id bigint NOT NULL DEFAULT 0 COMMENT '关联 tb_k8s_cluster_config 主键 id，命名空间配额使用',
+    namespace varchar(32) NOT NULL DEFAULT '' COMMENT '命名空间，命名空间配额使用',
+    cpu decimal(12,3) NOT NULL DEFAULT 0 COMMENT 'CPU 配额，单位 core，0 表示不限制',
+    memory decimal(12,3) NOT NULL DEFAULT 0 COMMENT '内存配额，单位 GB，0 表示不限制',
+    storage decimal(12,3) NOT NULL DEFAULT 0 COMMENT '存储配额，单位 GB，0 表示不限制',
+    active tinyint(1) NOT NULL DEFAULT 1 COMMENT '配额是否生效，0:否，1:是',
+    description varchar(100) Null COMMENT '配额描述',
+    created_by varchar(50) NOT NULL COMMENT '创建者',
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    updated_by varchar(50) NOT NULL COMMENT '更新者',
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    UNIQUE KEY uk_quota_scope (quota_type, bk_biz_id, k8s_cluster_config_id, namespace)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT '业务和命名空间资源配额表';
@@ -54,6 +54,11 @@ func (k *ContainerController) OpenTerminal(c *gin.Context) {
 		api.ErrorResponse(c, errors.NewK8sDbsError(errors.ServerError, err))
 		return
 	}
+	// mapstructure 不会展开内嵌的 BKAuth，单独解析操作人信息用于会话审计
+	if err := commutil.DecodeParams(c, commutil.BuildParams, &req.BKAuth, nil); err != nil {
+		api.ErrorResponse(c, errors.NewK8sDbsError(errors.ServerError, err))
+		return
+	}
 	slog.Info("OpenTerminal")
 	slog.Info("go req ", "req", req)
 	// ⚠️ 基础必填参数校验（建议根据实际业务补充）
@@ -77,6 +82,8 @@ func (k *ContainerController) OpenTerminal(c *gin.Context) {
 		ClusterName:    req.ClusterName,
 		Namespace:      req.Namespace,
 		PodName:        req.PodName,
+		ContainerName:  req.ContainerName,
+		BKAuth:         req.BKAuth,
 	}
 
 	// 4. 调用 Provider 打开终端交互
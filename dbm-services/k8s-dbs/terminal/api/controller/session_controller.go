@@ -0,0 +1,182 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"k8s-dbs/common/api"
+	commconst "k8s-dbs/common/constant"
+	commutil "k8s-dbs/common/util"
+	"k8s-dbs/errors"
+	metaentity "k8s-dbs/metadata/entity"
+	metaprovider "k8s-dbs/metadata/provider"
+	metavo "k8s-dbs/metadata/vo/response"
+	terminalconst "k8s-dbs/terminal/constant"
+	terminalvo "k8s-dbs/terminal/vo/response"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+)
+
+// SessionController 容器终端会话审计 controller
+type SessionController struct {
+	sessionProvider metaprovider.TerminalSessionProvider
+}
+
+// ListSessions 分页检索终端会话记录
+func (s *SessionController) ListSessions(ctx *gin.Context) {
+	pagination, err := commutil.BuildPagination(ctx)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	queryParams, err := s.buildListParams(ctx)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	sessions, count, err := s.sessionProvider.ListSessions(queryParams, pagination)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	var data []terminalvo.TerminalSessionResponse
+	if err = copier.Copy(&data, sessions); err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	api.SuccessResponse(ctx, metavo.PageResult{Count: count, Result: data}, commconst.Success)
+}
+
+// ListCommands 查询终端会话的命令审计记录
+func (s *SessionController) ListCommands(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	commands, err := s.sessionProvider.FindCommandsBySessionID(id)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	var data []terminalvo.TerminalCommandResponse
+	if err = copier.Copy(&data, commands); err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	api.SuccessResponse(ctx, data, commconst.Success)
+}
+
+// ReplaySession 返回 asciicast v2 格式的会话录像，供前端播放器回放
+func (s *SessionController) ReplaySession(ctx *gin.Context) {
+	s.writeRecording(ctx, false)
+}
+
+// DownloadSession 以附件形式下载会话录像
+func (s *SessionController) DownloadSession(ctx *gin.Context) {
+	s.writeRecording(ctx, true)
+}
+
+func (s *SessionController) writeRecording(ctx *gin.Context, attachment bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.ParameterInvalidError, err))
+		return
+	}
+	session, err := s.sessionProvider.FindSessionByID(id)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	if session.Status == terminalconst.SessionRunning {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError,
+			fmt.Errorf("terminal session %d is still running", id)))
+		return
+	}
+	// 先读取第一批分块，读取失败时仍可返回错误信息
+	recordings, err := s.sessionProvider.ListRecordings(id, 0, terminalconst.RecordingChunkBatch)
+	if err != nil {
+		api.ErrorResponse(ctx, errors.NewK8sDbsError(errors.GetMetaDataError, err))
+		return
+	}
+	if attachment {
+		ctx.Header("Content-Disposition",
+			fmt.Sprintf("attachment; filename=%s-%d.cast", session.PodName, session.ID))
+	}
+	ctx.Header("Content-Type", terminalconst.AsciicastContentType)
+	ctx.Status(http.StatusOK)
+	// 分批读取录像分块写入响应，内存中只保留一批分块
+	for {
+		nextSeq := 0
+		for _, recording := range recordings {
+			if _, err = ctx.Writer.WriteString(recording.Data); err != nil {
+				slog.Error("failed to write terminal recording", "sessionID", id, "error", err)
+				return
+			}
+			nextSeq = recording.Seq + 1
+		}
+		ctx.Writer.Flush()
+		if len(recordings) < terminalconst.RecordingChunkBatch {
+			return
+		}
+		recordings, err = s.sessionProvider.ListRecordings(id, nextSeq, terminalconst.RecordingChunkBatch)
+		if err != nil {
+			slog.Error("failed to list terminal recording", "sessionID", id, "error", err)
+			return
+		}
+	}
+}
+
+func (s *SessionController) buildListParams(ctx *gin.Context) (*metaentity.TerminalSessionQueryParams, error) {
+	queryParams := metaentity.TerminalSessionQueryParams{
+		K8sClusterName: ctx.Query("k8sClusterName"),
+		ClusterName:    ctx.Query("clusterName"),
+		Namespace:      ctx.Query("namespace"),
+		PodName:        ctx.Query("podName"),
+		Creators:       ctx.QueryArray("creator"),
+	}
+	if startTime := ctx.Query("startTime"); startTime != "" {
+		parsedTime, err := time.Parse(time.DateTime, startTime)
+		if err != nil {
+			return nil, errors.NewK8sDbsError(errors.ParameterValueError, err)
+		}
+		queryParams.StartTime = parsedTime
+	}
+	if endTime := ctx.Query("endTime"); endTime != "" {
+		parsedTime, err := time.Parse(time.DateTime, endTime)
+		if err != nil {
+			return nil, errors.NewK8sDbsError(errors.ParameterValueError, err)
+		}
+		queryParams.EndTime = parsedTime
+	}
+	return &queryParams, nil
+}
+
+// NewSessionController 构造函数
+func NewSessionController(sessionProvider metaprovider.TerminalSessionProvider) *SessionController {
+	return &SessionController{
+		sessionProvider: sessionProvider,
+	}
+}
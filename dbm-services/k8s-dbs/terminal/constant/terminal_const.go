@@ -0,0 +1,67 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package constant terminal 常量定义
+package constant
+
+// 终端会话状态
+const (
+	SessionRunning = "Running"
+	SessionClosed  = "Closed"
+	SessionFailed  = "Failed"
+)
+
+// 终端会话录像的默认配置
+const (
+	DefaultRecordingMaxSize = 32 * 1024 * 1024
+	DefaultTerminalWidth    = 80
+	DefaultTerminalHeight   = 24
+	DefaultShell            = "sh"
+	DefaultTerm             = "xterm"
+	AsciicastVersion        = 2
+	AsciicastContentType    = "application/x-asciicast"
+	// RecordingChunkSize 录像分块大小，录制和回放时内存中只保留少量分块
+	RecordingChunkSize = 64 * 1024
+	// RecordingChunkBatch 回放时每次从数据库读取的分块数
+	RecordingChunkBatch = 16
+)
+
+// asciicast v2 事件类型
+const (
+	AsciicastInputEvent  = "i"
+	AsciicastOutputEvent = "o"
+)
+
+// DefaultDenyCommands 默认禁止在容器终端中执行的命令
+// 不带参数的规则匹配命令名，带参数的规则需要命令包含规则中的全部选项和参数
+// 选项和参数与顺序无关，组合短选项会拆分后比较，如 rm -fr /、rm -r -f / 均命中 rm -rf /
+var DefaultDenyCommands = []string{
+	"rm -rf /",
+	"rm -rf /*",
+	"shutdown",
+	"reboot",
+	"halt",
+	"poweroff",
+	"init 0",
+	"init 6",
+	"mkfs",
+	"mkfs.ext4",
+	"mkfs.xfs",
+	"kill -9 1",
+}
@@ -22,31 +22,44 @@ package provider
 import (
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
 	"log"
+	"log/slog"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/remotecommand"
 
+	commtypes "k8s-dbs/common/types"
 	commutil "k8s-dbs/common/util"
+	"k8s-dbs/config"
 
+	terminalconst "k8s-dbs/terminal/constant"
 	terminalentity "k8s-dbs/terminal/entity"
+	terminalutil "k8s-dbs/terminal/util"
 
 	dbserrors "k8s-dbs/errors"
 
+	metaentity "k8s-dbs/metadata/entity"
 	metaprovider "k8s-dbs/metadata/provider"
 )
 
+// maxDescriptionLength 会话描述字段的最大长度
+const maxDescriptionLength = 255
+
 // TerminalProvider TerminalProvider 结构体
 type TerminalProvider struct {
 	clusterConfigProvider metaprovider.K8sClusterConfigProvider
+	sessionProvider       metaprovider.TerminalSessionProvider
+	terminalConfig        *config.TerminalConfig
 }
 
-// OpenTerminal 开启与 Kubernetes Pod 容器的交互式终端
+// OpenTerminal 开启与 Kubernetes Pod 容器的交互式终端，会话以 asciicast v2 格式录制，并对输入命令进行审计
 func (k *TerminalProvider) OpenTerminal(
 	entity *terminalentity.TerminalEntity,
 	conn *websocket.Conn,
@@ -74,17 +87,18 @@ func (k *TerminalProvider) OpenTerminal(
 		SubResource("exec")
 
 	// 可以允许 entity 传入自定义 command，默认为 /bin/bash
-	command := []string{"sh"}
+	command := []string{terminalconst.DefaultShell}
 	/*if len(entity.Command) > 0 {
 		command = entity.Command
 	}*/
 
 	req.VersionedParams(&corev1.PodExecOptions{
-		Command: command,
-		Stdin:   true,
-		Stdout:  true,
-		Stderr:  true,
-		TTY:     true, // 可由前端控制是否开启 TTY
+		Container: entity.ContainerName,
+		Command:   command,
+		Stdin:     true,
+		Stdout:    true,
+		Stderr:    true,
+		TTY:       true, // 可由前端控制是否开启 TTY
 	}, scheme.ParameterCodec)
 
 	// 4. 创建 SPDY Executor
@@ -94,22 +108,97 @@ func (k *TerminalProvider) OpenTerminal(
 		return fmt.Errorf("创建 SPDY Executor 失败: %w", err)
 	}
 
-	// 5. 执行交互式流：WebSocket <-> Pod Shell (stdin/stdout/stderr)
+	// 5. 创建会话记录，无法审计的会话不允许打开
+	session, err := k.startSession(entity, conn)
+	if err != nil {
+		writeWSMessage(conn, fmt.Sprintf("[ERROR] 创建终端会话记录失败: %v", err))
+		return dbserrors.NewK8sDbsError(dbserrors.CreateMetaDataError, err)
+	}
+
+	// 6. 执行交互式流：WebSocket <-> Pod Shell (stdin/stdout/stderr)
 	err = exec.StreamWithContext(c.Request.Context(), remotecommand.StreamOptions{
-		Stdin:  &wsStdin{conn},
-		Stdout: &wsStdout{conn},
-		Stderr: &wsStdout{conn},
+		Stdin:  &wsStdin{session: session},
+		Stdout: &wsStdout{session: session},
+		Stderr: &wsStdout{session: session},
 		Tty:    true,
 	})
+	k.finishSession(session, err)
 
 	if err != nil {
-		writeWSMessage(conn, fmt.Sprintf("[ERROR] Stream 执行失败: %v", err))
+		session.writeMessage(fmt.Sprintf("[ERROR] Stream 执行失败: %v", err))
 		return fmt.Errorf("stream 执行失败: %w", err)
 	}
 
 	return nil
 }
 
+// startSession 创建终端会话记录和录像，录像按分块写入数据库
+func (k *TerminalProvider) startSession(
+	entity *terminalentity.TerminalEntity,
+	conn *websocket.Conn,
+) (*terminalSession, error) {
+	sessionEntity, err := k.sessionProvider.CreateSession(&metaentity.TerminalSessionEntity{
+		K8sClusterName: entity.K8sClusterName,
+		ClusterName:    entity.ClusterName,
+		Namespace:      entity.Namespace,
+		PodName:        entity.PodName,
+		ContainerName:  entity.ContainerName,
+		Status:         terminalconst.SessionRunning,
+		StartTime:      commtypes.JSONDatetime(time.Now()),
+		CreatedBy:      entity.BkUserName,
+		UpdatedBy:      entity.BkUserName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recorder, err := terminalutil.NewAsciicastRecorder(
+		fmt.Sprintf("%s/%s/%s", entity.K8sClusterName, entity.Namespace, entity.PodName),
+		k.terminalConfig.RecordingMaxSize,
+		terminalconst.RecordingChunkSize,
+		func(seq int, chunk []byte) error {
+			return k.sessionProvider.CreateRecording(&metaentity.TerminalRecordingEntity{
+				SessionID: sessionEntity.ID,
+				Seq:       seq,
+				Data:      string(chunk),
+			})
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &terminalSession{
+		conn:            conn,
+		entity:          sessionEntity,
+		recorder:        recorder,
+		sessionProvider: k.sessionProvider,
+		denyCommands:    k.terminalConfig.DenyCommands,
+	}, nil
+}
+
+// finishSession 保存剩余的会话录像并结束会话
+func (k *TerminalProvider) finishSession(session *terminalSession, streamErr error) {
+	endTime := commtypes.JSONDatetime(time.Now())
+	sessionEntity := session.entity
+	sessionEntity.EndTime = &endTime
+	sessionEntity.Status = terminalconst.SessionClosed
+	if streamErr != nil {
+		sessionEntity.Status = terminalconst.SessionFailed
+		sessionEntity.Description = streamErr.Error()
+		if len(sessionEntity.Description) > maxDescriptionLength {
+			sessionEntity.Description = sessionEntity.Description[:maxDescriptionLength]
+		}
+	}
+	if err := session.recorder.Close(); err != nil {
+		slog.Error("failed to save terminal recording", "sessionID", sessionEntity.ID, "error", err)
+		sessionEntity.Truncated = true
+	}
+	sessionEntity.RecordingSize = uint64(session.recorder.Size())
+	sessionEntity.Truncated = sessionEntity.Truncated || session.recorder.Truncated()
+	if _, err := k.sessionProvider.UpdateSession(sessionEntity); err != nil {
+		slog.Error("failed to save terminal session", "sessionID", sessionEntity.ID, "error", err)
+	}
+}
+
 // 辅助函数：向 WebSocket 发送文本消息
 func writeWSMessage(conn *websocket.Conn, msg string) {
 	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
@@ -117,36 +206,83 @@ func writeWSMessage(conn *websocket.Conn, msg string) {
 	}
 }
 
+// terminalSession 一次终端会话的运行时状态，WebSocket 同一时间只允许一个写入者
+type terminalSession struct {
+	conn            *websocket.Conn
+	writeMu         sync.Mutex
+	entity          *metaentity.TerminalSessionEntity
+	recorder        *terminalutil.AsciicastRecorder
+	sessionProvider metaprovider.TerminalSessionProvider
+	denyCommands    []string
+}
+
+// writeMessage 向 WebSocket 发送提示信息，并录制到会话录像中
+func (s *terminalSession) writeMessage(msg string) {
+	s.recorder.RecordOutput([]byte("\r\n" + msg + "\r\n"))
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	writeWSMessage(s.conn, msg)
+}
+
+// auditCommand 审计一行完整的命令，返回是否允许执行
+func (s *terminalSession) auditCommand(line string) bool {
+	command := strings.TrimSpace(line)
+	if command == "" {
+		return true
+	}
+	denyRule, blocked := terminalutil.MatchDenyCommand(command, s.denyCommands)
+	if _, err := s.sessionProvider.CreateCommand(&metaentity.TerminalCommandEntity{
+		SessionID:  s.entity.ID,
+		Command:    command,
+		Blocked:    blocked,
+		DenyRule:   denyRule,
+		ExecutedAt: commtypes.JSONDatetime(time.Now()),
+		CreatedBy:  s.entity.CreatedBy,
+	}); err != nil {
+		slog.Error("failed to save terminal command", "sessionID", s.entity.ID, "error", err)
+	}
+	if blocked {
+		s.writeMessage(fmt.Sprintf("[BLOCKED] 命令 %q 命中禁止规则 %q，已拒绝执行", command, denyRule))
+	}
+	return !blocked
+}
+
 // --- WebSocket --> Pod Stdin (客户端输入发送到容器) ---
 // wsStdin 实现了 io.Reader，用于从 WebSocket 读取客户端输入，作为容器的 stdin
+// 客户端按行发送命令，整行审计后再转发到容器，命中禁止规则的命令不会执行
 type wsStdin struct {
-	conn *websocket.Conn
+	session *terminalSession
+	pending []byte
 }
 
 // Read 从 WebSocket 读取消息，并将数据拷贝到 p 中，供 remotecommand 使用
 func (w *wsStdin) Read(p []byte) (n int, err error) {
-	_, data, err := w.conn.ReadMessage()
-	fmt.Printf("[STDOUT] 收到客户端输入: %s\n", string(p)) // 调试用
-	if err != nil {
-		return 0, err
-	}
+	for len(w.pending) == 0 {
+		var data []byte
+		_, data, err = w.session.conn.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
 
-	// 关键点：确保输入以换行符结尾
-	if !strings.HasSuffix(string(data), "\n") {
-		data = append(data, '\n')
+		w.session.recorder.RecordInput(data)
+		w.pending = terminalutil.AuditInputLines(data, w.session.auditCommand)
 	}
-	return copy(p, data), nil
+	n = copy(p, w.pending)
+	w.pending = w.pending[n:]
+	return n, nil
 }
 
 // --- Pod Stdout/Stderr --> WebSocket (容器输出发送到客户端) ---
 // wsStdout 实现了 io.Writer，用于将容器的 stdout/stderr 写入到 WebSocket 客户端
 type wsStdout struct {
-	conn *websocket.Conn
+	session *terminalSession
 }
 
 func (w *wsStdout) Write(p []byte) (n int, err error) {
-	fmt.Printf("[STDOUT] 收到容器输出: %s\n", string(p)) // 调试用
-	err = w.conn.WriteMessage(websocket.BinaryMessage, p)
+	w.session.recorder.RecordOutput(p)
+	w.session.writeMu.Lock()
+	defer w.session.writeMu.Unlock()
+	err = w.session.conn.WriteMessage(websocket.BinaryMessage, p)
 	if err != nil {
 		return 0, err
 	}
@@ -156,8 +292,11 @@ func (w *wsStdout) Write(p []byte) (n int, err error) {
 // NewTerminalProvider 创建 TerminalProvider 实例
 func NewTerminalProvider(
 	clusterConfigProvider metaprovider.K8sClusterConfigProvider,
+	sessionProvider metaprovider.TerminalSessionProvider,
 ) *TerminalProvider {
 	return &TerminalProvider{
-		clusterConfigProvider,
+		clusterConfigProvider: clusterConfigProvider,
+		sessionProvider:       sessionProvider,
+		terminalConfig:        terminalutil.LoadTerminalConfig(),
 	}
 }
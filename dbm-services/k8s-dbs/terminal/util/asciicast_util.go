@@ -0,0 +1,180 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	terminalconst "k8s-dbs/terminal/constant"
+	"log/slog"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// AsciicastHeader asciicast v2 录像文件头
+type AsciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// RecordingSink 持久化一个录像分块，seq 从 0 开始递增
+type RecordingSink func(seq int, chunk []byte) error
+
+// AsciicastRecorder 以 asciicast v2 格式录制终端会话，并发安全
+// 录像按事件行累积，超过 chunkSize 后交给 sink 持久化，内存中只保留一个分块
+// 录像超过 maxSize 后不再录制后续事件，并标记为截断
+type AsciicastRecorder struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	start     time.Time
+	maxSize   int
+	chunkSize int
+	size      int
+	seq       int
+	sink      RecordingSink
+	truncated bool
+	// pending 输出中被截断的不完整 utf-8 字符，与下一次输出合并后再录制
+	pending []byte
+}
+
+// NewAsciicastRecorder 创建 AsciicastRecorder，并写入录像文件头
+func NewAsciicastRecorder(title string, maxSize, chunkSize int, sink RecordingSink) (*AsciicastRecorder, error) {
+	start := time.Now()
+	header, err := json.Marshal(&AsciicastHeader{
+		Version:   terminalconst.AsciicastVersion,
+		Width:     terminalconst.DefaultTerminalWidth,
+		Height:    terminalconst.DefaultTerminalHeight,
+		Timestamp: start.Unix(),
+		Title:     title,
+		Env: map[string]string{
+			"SHELL": terminalconst.DefaultShell,
+			"TERM":  terminalconst.DefaultTerm,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal asciicast header: %w", err)
+	}
+	recorder := &AsciicastRecorder{start: start, maxSize: maxSize, chunkSize: chunkSize, sink: sink}
+	recorder.writeLine(header)
+	return recorder, nil
+}
+
+// RecordInput 录制终端输入
+func (r *AsciicastRecorder) RecordInput(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writeEvent(terminalconst.AsciicastInputEvent, data)
+}
+
+// RecordOutput 录制终端输出
+func (r *AsciicastRecorder) RecordOutput(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data = append(r.pending, data...)
+	r.pending = nil
+	if tail := incompleteRuneTail(data); tail > 0 {
+		r.pending = append([]byte(nil), data[len(data)-tail:]...)
+		data = data[:len(data)-tail]
+	}
+	r.writeEvent(terminalconst.AsciicastOutputEvent, data)
+}
+
+// Close 持久化剩余的录像内容
+func (r *AsciicastRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.flush()
+}
+
+// Size 返回已录制的字节数
+func (r *AsciicastRecorder) Size() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.size
+}
+
+// Truncated 录像是否因超过大小限制被截断
+func (r *AsciicastRecorder) Truncated() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.truncated
+}
+
+func (r *AsciicastRecorder) writeEvent(eventType string, data []byte) {
+	if len(data) == 0 || r.truncated {
+		return
+	}
+	elapsed := float64(time.Since(r.start).Microseconds()) / float64(time.Second/time.Microsecond)
+	event, err := json.Marshal([]interface{}{elapsed, eventType, string(data)})
+	if err != nil {
+		return
+	}
+	if r.maxSize > 0 && r.size+len(event)+1 > r.maxSize {
+		r.truncated = true
+		return
+	}
+	r.writeLine(event)
+	if r.buf.Len() >= r.chunkSize {
+		if err = r.flush(); err != nil {
+			slog.Error("failed to save terminal recording chunk, stop recording", "seq", r.seq, "error", err)
+			r.truncated = true
+		}
+	}
+}
+
+func (r *AsciicastRecorder) writeLine(line []byte) {
+	r.buf.Write(line)
+	r.buf.WriteByte('\n')
+	r.size += len(line) + 1
+}
+
+// flush 将缓冲的事件作为一个分块交给 sink
+func (r *AsciicastRecorder) flush() error {
+	if r.buf.Len() == 0 {
+		return nil
+	}
+	if err := r.sink(r.seq, r.buf.Bytes()); err != nil {
+		return err
+	}
+	r.seq++
+	r.buf.Reset()
+	return nil
+}
+
+// incompleteRuneTail 返回 data 末尾不完整的 utf-8 字符的字节数
+func incompleteRuneTail(data []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		b := data[len(data)-i]
+		if !utf8.RuneStart(b) {
+			continue
+		}
+		if !utf8.FullRune(data[len(data)-i:]) {
+			return i
+		}
+		return 0
+	}
+	return 0
+}
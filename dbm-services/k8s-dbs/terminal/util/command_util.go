@@ -0,0 +1,152 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package util terminal 工具函数
+package util
+
+import (
+	"k8s-dbs/config"
+	terminalconst "k8s-dbs/terminal/constant"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// commandSeparators 命令行中分隔多条命令的操作符
+var commandSeparators = strings.NewReplacer("&&", ";", "||", ";", "|", ";", "&", ";", "\n", ";", "\r", ";")
+
+// LoadTerminalConfig 从环境变量读取终端审计配置
+func LoadTerminalConfig() *config.TerminalConfig {
+	cfg := &config.TerminalConfig{
+		DenyCommands:     terminalconst.DefaultDenyCommands,
+		RecordingMaxSize: terminalconst.DefaultRecordingMaxSize,
+	}
+	if denyCommands := os.Getenv("TERMINAL_DENY_COMMANDS"); denyCommands != "" {
+		cfg.DenyCommands = nil
+		for _, denyCommand := range strings.Split(denyCommands, ",") {
+			if denyCommand = strings.TrimSpace(denyCommand); denyCommand != "" {
+				cfg.DenyCommands = append(cfg.DenyCommands, denyCommand)
+			}
+		}
+	}
+	if maxSize := os.Getenv("TERMINAL_RECORDING_MAX_SIZE"); maxSize != "" {
+		size, err := strconv.Atoi(maxSize)
+		if err != nil {
+			slog.Warn("invalid TERMINAL_RECORDING_MAX_SIZE, use default", "maxSize", maxSize, "error", err)
+		} else {
+			cfg.RecordingMaxSize = size
+		}
+	}
+	return cfg
+}
+
+// MatchDenyCommand 检查命令行是否命中禁止规则，返回命中的规则
+// 命令行会按 ; && || | & 拆分为多条命令分别检查，sudo 前缀和命令路径会被忽略
+// 只做字面匹配，用于审计和防止误操作，不是安全边界：通过 bash -c、eval、变量展开、
+// 引号拼接、脚本文件等方式执行的命令都无法识别，容器权限需要通过 RBAC 等方式控制
+func MatchDenyCommand(commandLine string, denyCommands []string) (string, bool) {
+	for _, command := range strings.Split(commandSeparators.Replace(commandLine), ";") {
+		fields := strings.Fields(command)
+		if len(fields) > 0 && fields[0] == "sudo" {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = path.Base(fields[0])
+		parsed := parseCommandFields(fields)
+		for _, denyCommand := range denyCommands {
+			ruleFields := strings.Fields(denyCommand)
+			if len(ruleFields) > 0 && parsed.contains(parseCommandFields(ruleFields)) {
+				return denyCommand, true
+			}
+		}
+	}
+	return "", false
+}
+
+// parsedCommand 拆分后的命令，选项和参数均与顺序无关
+type parsedCommand struct {
+	name  string
+	flags map[string]bool
+	args  map[string]bool
+}
+
+// parseCommandFields 拆分命令名、选项和参数
+// 组合短选项拆分为单个选项，如 -rf 拆分为 -r -f；-- 之后的字段均视为参数
+// 参数去掉引号，绝对路径规整为 path.Clean 的结果，如 // 与 / 等价
+func parseCommandFields(fields []string) *parsedCommand {
+	command := &parsedCommand{name: fields[0], flags: make(map[string]bool), args: make(map[string]bool)}
+	endOfFlags := false
+	for _, field := range fields[1:] {
+		field = strings.Trim(field, `"'`)
+		switch {
+		case endOfFlags || field == "-" || !strings.HasPrefix(field, "-"):
+			if strings.HasPrefix(field, "/") {
+				field = path.Clean(field)
+			}
+			command.args[field] = true
+		case field == "--":
+			endOfFlags = true
+		case strings.HasPrefix(field, "--"):
+			command.flags[field] = true
+		default:
+			for _, flag := range field[1:] {
+				command.flags["-"+string(flag)] = true
+			}
+		}
+	}
+	return command
+}
+
+// contains 命令名相同，且包含规则中的全部选项和参数
+func (c *parsedCommand) contains(rule *parsedCommand) bool {
+	if c.name != rule.name {
+		return false
+	}
+	for flag := range rule.flags {
+		if !c.flags[flag] {
+			return false
+		}
+	}
+	for arg := range rule.args {
+		if !c.args[arg] {
+			return false
+		}
+	}
+	return true
+}
+
+// AuditInputLines 审计一条客户端输入，返回需要转发到容器的数据
+// 客户端按行发送命令，每条消息可能包含多行，缺少结尾换行符时补齐；
+// 每行在转发前审计，audit 返回 false 的行被丢弃，不会发送到容器
+func AuditInputLines(data []byte, audit func(line string) bool) []byte {
+	input := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	out := make([]byte, 0, len(data)+1)
+	for _, line := range strings.Split(input, "\n") {
+		if !audit(line) {
+			continue
+		}
+		out = append(out, line...)
+		out = append(out, '\n')
+	}
+	return out
+}
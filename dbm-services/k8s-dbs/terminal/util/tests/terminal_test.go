@@ -0,0 +1,174 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	terminalconst "k8s-dbs/terminal/constant"
+	"k8s-dbs/terminal/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchDenyCommand(t *testing.T) {
+	denyCommands := terminalconst.DefaultDenyCommands
+	cases := []struct {
+		command  string
+		denyRule string
+		blocked  bool
+	}{
+		{"ls -al /data", "", false},
+		{"rm -rf /tmp/test", "", false},
+		{"rm -rf /", "rm -rf /", true},
+		{"rm  -rf  /*", "rm -rf /*", true},
+		{"/sbin/reboot", "reboot", true},
+		{"sudo shutdown -h now", "shutdown", true},
+		{"echo ok && poweroff", "poweroff", true},
+		{"ps aux | grep mysqld; halt", "halt", true},
+		{"init 3", "", false},
+		{"echo reboot", "", false},
+		{"rm -fr /", "rm -rf /", true},
+		{"rm -r -f /", "rm -rf /", true},
+		{"rm -rf / --no-preserve-root", "rm -rf /", true},
+		{"rm -rf -- //", "rm -rf /", true},
+		{"rm -rf '/'", "rm -rf /", true},
+		{"rm -Rf /", "", false},
+		{"rm -r /", "", false},
+		{"rm -rf /data", "", false},
+		{"kill -9 1", "kill -9 1", true},
+		{"kill -9 10", "", false},
+	}
+	for _, c := range cases {
+		denyRule, blocked := util.MatchDenyCommand(c.command, denyCommands)
+		assert.Equal(t, c.blocked, blocked, c.command)
+		assert.Equal(t, c.denyRule, denyRule, c.command)
+	}
+}
+
+func TestAuditInputLines(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  string
+		lines  []string
+		output string
+	}{
+		{"append newline", "ls -l", []string{"ls -l"}, "ls -l\n"},
+		{"keep newline", "ls -l\n", []string{"ls -l"}, "ls -l\n"},
+		{"crlf", "ls -l\r\n", []string{"ls -l"}, "ls -l\n"},
+		{"blocked", "reboot", []string{"reboot"}, ""},
+		{"multi lines", "ls\nreboot\npwd\n", []string{"ls", "reboot", "pwd"}, "ls\npwd\n"},
+		{"empty line", "", []string{""}, "\n"},
+	}
+	for _, c := range cases {
+		var lines []string
+		audit := func(line string) bool {
+			lines = append(lines, line)
+			_, blocked := util.MatchDenyCommand(line, terminalconst.DefaultDenyCommands)
+			return !blocked
+		}
+		output := util.AuditInputLines([]byte(c.input), audit)
+		assert.Equal(t, c.lines, lines, c.name)
+		assert.Equal(t, c.output, string(output), c.name)
+	}
+}
+
+// recordingSink 将录像分块保存在内存中
+type recordingSink struct {
+	chunks []string
+}
+
+func (s *recordingSink) save(seq int, chunk []byte) error {
+	if seq != len(s.chunks) {
+		return fmt.Errorf("unexpected seq %d", seq)
+	}
+	s.chunks = append(s.chunks, string(chunk))
+	return nil
+}
+
+func TestAsciicastRecorder(t *testing.T) {
+	sink := &recordingSink{}
+	recorder, err := util.NewAsciicastRecorder("k8s/ns/pod", 0, 1024, sink.save)
+	assert.NoError(t, err)
+	recorder.RecordInput([]byte("ls\n"))
+	// 多字节字符被拆分到两次输出中
+	output := []byte("数据\r\n")
+	recorder.RecordOutput(output[:4])
+	recorder.RecordOutput(output[4:])
+
+	assert.NoError(t, recorder.Close())
+	assert.Len(t, sink.chunks, 1)
+	assert.Equal(t, len(sink.chunks[0]), recorder.Size())
+	lines := strings.Split(strings.TrimSpace(sink.chunks[0]), "\n")
+	assert.Len(t, lines, 4)
+
+	var header util.AsciicastHeader
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+	assert.Equal(t, terminalconst.AsciicastVersion, header.Version)
+	assert.Equal(t, "k8s/ns/pod", header.Title)
+
+	var events [][]interface{}
+	for _, line := range lines[1:] {
+		var event []interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+	assert.Equal(t, terminalconst.AsciicastInputEvent, events[0][1])
+	assert.Equal(t, "ls\n", events[0][2])
+	assert.Equal(t, terminalconst.AsciicastOutputEvent, events[1][1])
+	assert.Equal(t, "数", events[1][2])
+	assert.Equal(t, "据\r\n", events[2][2])
+	assert.False(t, recorder.Truncated())
+}
+
+func TestAsciicastRecorderChunks(t *testing.T) {
+	sink := &recordingSink{}
+	recorder, err := util.NewAsciicastRecorder("", 0, 256, sink.save)
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		recorder.RecordOutput([]byte(strings.Repeat("x", 64)))
+	}
+	assert.NoError(t, recorder.Close())
+	assert.Greater(t, len(sink.chunks), 1)
+	// 分块以完整的事件行结束，拼接后为完整录像
+	for _, chunk := range sink.chunks {
+		assert.True(t, strings.HasSuffix(chunk, "\n"))
+	}
+	recording := strings.Join(sink.chunks, "")
+	assert.Equal(t, len(recording), recorder.Size())
+	assert.Len(t, strings.Split(strings.TrimSpace(recording), "\n"), 11)
+	assert.False(t, recorder.Truncated())
+}
+
+func TestAsciicastRecorderTruncated(t *testing.T) {
+	sink := &recordingSink{}
+	recorder, err := util.NewAsciicastRecorder("", 256, 1024, sink.save)
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		recorder.RecordOutput([]byte(strings.Repeat("x", 64)))
+	}
+	assert.NoError(t, recorder.Close())
+	assert.True(t, recorder.Truncated())
+	assert.LessOrEqual(t, recorder.Size(), 256)
+	assert.Equal(t, recorder.Size(), len(strings.Join(sink.chunks, "")))
+}
@@ -0,0 +1,53 @@
+/*
+TencentBlueKing is pleased to support the open source community by making
+蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+
+Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+
+Licensed under the MIT License (the "License");
+you may not use this file except in compliance with the License.
+
+You may obtain a copy of the License at
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package response
+
+import commtypes "k8s-dbs/common/types"
+
+// TerminalSessionResponse terminal session response vo 定义，不包含会话录像
+type TerminalSessionResponse struct {
+	ID             uint64                  `json:"id"`
+	K8sClusterName string                  `json:"k8sClusterName"`
+	ClusterName    string                  `json:"clusterName"`
+	Namespace      string                  `json:"namespace"`
+	PodName        string                  `json:"podName"`
+	ContainerName  string                  `json:"containerName"`
+	Status         string                  `json:"status"`
+	StartTime      commtypes.JSONDatetime  `json:"startTime"`
+	EndTime        *commtypes.JSONDatetime `json:"endTime"`
+	RecordingSize  uint64                  `json:"recordingSize"`
+	Truncated      bool                    `json:"truncated"`
+	Description    string                  `json:"description"`
+	CreatedBy      string                  `json:"createdBy"`
+	CreatedAt      commtypes.JSONDatetime  `json:"createdAt"`
+	UpdatedBy      string                  `json:"updatedBy"`
+	UpdatedAt      commtypes.JSONDatetime  `json:"updatedAt"`
+}
+
+// TerminalCommandResponse terminal command response vo 定义
+type TerminalCommandResponse struct {
+	ID         uint64                 `json:"id"`
+	SessionID  uint64                 `json:"sessionId"`
+	Command    string                 `json:"command"`
+	Blocked    bool                   `json:"blocked"`
+	DenyRule   string                 `json:"denyRule"`
+	ExecutedAt commtypes.JSONDatetime `json:"executedAt"`
+	CreatedBy  string                 `json:"createdBy"`
+}
@@ -0,0 +1,320 @@
+package atommongodb
+
+import (
+	"context"
+	"dbm-services/mongodb/db-tools/dbactuator/pkg/jobruntime"
+	"dbm-services/mongodb/db-tools/mongo-toolkit-go/pkg/mymongo"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// index_advisor 分析 system.profile, 给出索引建议
+// 1. 读取各库 system.profile 中的 COLLSCAN 和选择性差的 IXSCAN 查询
+// 2. 按 ESR(Equality, Sort, Range) 规则生成复合索引
+// 3. 与已有索引比较, 去掉已被覆盖的建议, 标记可删除的前缀索引
+// 4. 输出报告. 只有 execute=true 时才在 primary 上创建索引
+
+// indexAdvisorParams 原子任务参数
+type indexAdvisorParams struct {
+	IP            string `json:"ip"`
+	Port          int    `json:"port"`
+	AdminUsername string `json:"adminUsername"`
+	AdminPassword string `json:"adminPassword"`
+	Args          struct {
+		DbList          []string `json:"dbList"`          // 为空时分析所有非系统库
+		SinceMinutes    int      `json:"sinceMinutes"`    // 只分析最近N分钟的记录, 0 表示不限
+		MaxProfileDocs  int64    `json:"maxProfileDocs"`  // 每个库最多读取的记录数, 默认 10000
+		MinDocsRatio    float64  `json:"minDocsRatio"`    // IXSCAN 扫描文档数/返回文档数 超过此值视为选择性差, 默认 10
+		MinDocsExamined int64    `json:"minDocsExamined"` // 扫描文档数小于此值的查询忽略, 默认 100
+		Execute         bool     `json:"execute"`         // 为 true 时创建建议的索引
+	} `json:"args"`
+}
+
+// indexAdvisorReport 报告
+type indexAdvisorReport struct {
+	Instance      string               `json:"instance"`
+	ProfileDocs   int64                `json:"profileDocs"`
+	PoorQueries   int64                `json:"poorQueries"`
+	Suggestions   []*indexSuggestion   `json:"suggestions"`
+	Skipped       []*skippedSuggestion `json:"skipped"` // 已有索引覆盖, 需要检查查询为何未使用该索引
+	NoProfileDbs  []string             `json:"noProfileDbs,omitempty"`
+	ExecuteResult string               `json:"executeResult"`
+}
+
+type indexAdvisorJob struct {
+	BaseJob
+	ConfParams  *indexAdvisorParams
+	MongoInst   *mymongo.MongoHost
+	MongoClient *mongo.Client
+	tmp         struct {
+		queries []*profileQuery
+		report  *indexAdvisorReport
+	}
+}
+
+func (s *indexAdvisorJob) Param() string {
+	o, _ := json.MarshalIndent(indexAdvisorParams{}, "", "\t")
+	return string(o)
+}
+
+// NewIndexAdvisorJob 实例化结构体
+func NewIndexAdvisorJob() jobruntime.JobRunner {
+	return &indexAdvisorJob{}
+}
+
+// Name 获取原子任务的名字
+func (s *indexAdvisorJob) Name() string {
+	return "mongodb_index_advisor"
+}
+
+// Run 运行原子任务
+func (s *indexAdvisorJob) Run() error {
+	defer s.MongoClient.Disconnect(context.TODO())
+	return s.runSteps([]stepFunc{
+		{"readProfile", s.readProfile},
+		{"makeSuggestions", s.makeSuggestions},
+		{"createIndexes", s.createIndexes},
+		{"outputReport", s.outputReport},
+	})
+}
+
+// Init 初始化
+func (s *indexAdvisorJob) Init(runtime *jobruntime.JobGenericRuntime) error {
+	s.runtime = runtime
+	s.OsUser = ""
+	if err := json.Unmarshal([]byte(s.runtime.PayloadDecoded), &s.ConfParams); err != nil {
+		tmpErr := errors.Wrap(err, "payload json.Unmarshal failed")
+		s.runtime.Logger.Error(tmpErr.Error())
+		return tmpErr
+	}
+	args := &s.ConfParams.Args
+	if args.MaxProfileDocs <= 0 {
+		args.MaxProfileDocs = 10000
+	}
+	if args.MinDocsRatio <= 0 {
+		args.MinDocsRatio = 10
+	}
+	if args.MinDocsExamined <= 0 {
+		args.MinDocsExamined = 100
+	}
+
+	s.MongoInst = mymongo.NewMongoHost(
+		s.ConfParams.IP, fmt.Sprintf("%d", s.ConfParams.Port),
+		"admin", s.ConfParams.AdminUsername, s.ConfParams.AdminPassword, "", s.ConfParams.IP)
+	// 直连目标实例, secondary 上的 profile 也需要读取
+	var err error
+	s.MongoClient, err = s.MongoInst.ConnectWithDirect(true)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Connect to %s:%d failed", s.ConfParams.IP, s.ConfParams.Port))
+	}
+	s.tmp.report = &indexAdvisorReport{Instance: s.MongoInst.Addr()}
+	return nil
+}
+
+// getDbList 待分析的库
+func (s *indexAdvisorJob) getDbList() ([]string, error) {
+	if len(s.ConfParams.Args.DbList) > 0 {
+		return s.ConfParams.Args.DbList, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	dbs, err := s.MongoClient.ListDatabaseNames(ctx, bson.D{})
+	if err != nil {
+		return nil, errors.Wrap(err, "ListDatabaseNames")
+	}
+	var dbList []string
+	for _, db := range dbs {
+		if !mymongo.IsSysDb(db) {
+			dbList = append(dbList, db)
+		}
+	}
+	return dbList, nil
+}
+
+func (s *indexAdvisorJob) readProfile() error {
+	dbList, err := s.getDbList()
+	if err != nil {
+		return err
+	}
+	args := s.ConfParams.Args
+	filter := bson.D{
+		{Key: "op", Value: bson.D{{Key: "$in", Value: bson.A{"query", "command", "getmore", "update", "remove"}}}},
+		{Key: "planSummary", Value: bson.D{{Key: "$exists", Value: true}}},
+	}
+	if args.SinceMinutes > 0 {
+		since := time.Now().Add(-time.Duration(args.SinceMinutes) * time.Minute)
+		filter = append(filter, bson.E{Key: "ts", Value: bson.D{{Key: "$gte", Value: since}}})
+	}
+	report := s.tmp.report
+	for _, db := range dbList {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		cursor, err := s.MongoClient.Database(db).Collection("system.profile").Find(ctx, filter,
+			options.Find().SetSort(bson.D{{Key: "$natural", Value: -1}}).SetLimit(args.MaxProfileDocs))
+		if err != nil {
+			cancel()
+			return errors.Wrap(err, fmt.Sprintf("read %s.system.profile", db))
+		}
+		var count int64
+		for cursor.Next(ctx) {
+			count++
+			var doc profileDoc
+			if err = cursor.Decode(&doc); err != nil {
+				s.runtime.Logger.Warn("decode %s.system.profile failed, err:%v", db, err)
+				continue
+			}
+			q := doc.toProfileQuery()
+			if q == nil || !isPoorQuery(q, args.MinDocsRatio, args.MinDocsExamined) {
+				continue
+			}
+			s.tmp.queries = append(s.tmp.queries, q)
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		cancel()
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("read %s.system.profile", db))
+		}
+		if count == 0 {
+			report.NoProfileDbs = append(report.NoProfileDbs, db)
+		}
+		report.ProfileDocs += count
+		s.runtime.Logger.Info("read %s.system.profile: %d docs", db, count)
+	}
+	report.PoorQueries = int64(len(s.tmp.queries))
+	s.runtime.Logger.Info("profile docs:%d, poor queries:%d", report.ProfileDocs, report.PoorQueries)
+	return nil
+}
+
+func (s *indexAdvisorJob) makeSuggestions() error {
+	report := s.tmp.report
+	existingByNs := map[string]map[string]indexKey{}
+	for _, sug := range mergeSuggestions(s.tmp.queries) {
+		existing, ok := existingByNs[sug.Ns]
+		if !ok {
+			var err error
+			existing, err = s.listIndexes(sug.Ns)
+			if err != nil {
+				return err
+			}
+			existingByNs[sug.Ns] = existing
+		}
+		if coveredBy := sug.checkExistingIndexes(existing); coveredBy != "" {
+			report.Skipped = append(report.Skipped, &skippedSuggestion{
+				Ns: sug.Ns, KeyString: sug.KeyString, CoveredBy: coveredBy, Count: sug.QueryCount,
+			})
+			continue
+		}
+		sug.CreateCommand = createIndexCommand(sug.Ns, sug.Key)
+		sug.CollectionCount = s.estimatedCount(sug.Ns)
+		report.Suggestions = append(report.Suggestions, sug)
+	}
+	return nil
+}
+
+// listIndexes 已有索引 name => key
+func (s *indexAdvisorJob) listIndexes(ns string) (map[string]indexKey, error) {
+	db, col, _ := strings.Cut(ns, ".")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cursor, err := s.MongoClient.Database(db).Collection(col).Indexes().List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("listIndexes %s", ns))
+	}
+	var rows []struct {
+		Name string `bson:"name"`
+		Key  bson.D `bson:"key"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("listIndexes %s", ns))
+	}
+	indexes := make(map[string]indexKey, len(rows))
+	for _, row := range rows {
+		indexes[row.Name] = parseIndexKey(row.Key)
+	}
+	return indexes, nil
+}
+
+func (s *indexAdvisorJob) estimatedCount(ns string) int64 {
+	db, col, _ := strings.Cut(ns, ".")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	count, err := s.MongoClient.Database(db).Collection(col).EstimatedDocumentCount(ctx)
+	if err != nil {
+		s.runtime.Logger.Warn("EstimatedDocumentCount %s failed, err:%v", ns, err)
+		return -1
+	}
+	return count
+}
+
+// createIndexes 只有 execute=true 时执行, 在 primary 上创建
+func (s *indexAdvisorJob) createIndexes() error {
+	report := s.tmp.report
+	if !s.ConfParams.Args.Execute {
+		report.ExecuteResult = "not executed, set args.execute=true to create indexes"
+		return nil
+	}
+	if len(report.Suggestions) == 0 {
+		report.ExecuteResult = "no suggestion"
+		return nil
+	}
+	primaryConn, err := connectPrimary(s.MongoInst)
+	if err != nil {
+		return errors.Wrap(err, "connectPrimary")
+	}
+	defer primaryConn.Disconnect(context.TODO())
+	var failed int
+	for _, sug := range report.Suggestions {
+		db, col, _ := strings.Cut(sug.Ns, ".")
+		keys := bson.D{}
+		for _, f := range sug.Key {
+			keys = append(keys, bson.E{Key: f.Field, Value: f.Direction})
+		}
+		model := mongo.IndexModel{Keys: keys, Options: options.Index().SetBackground(true)}
+		s.runtime.Logger.Info("exec %s", sug.CreateCommand)
+		name, err := primaryConn.Database(db).Collection(col).Indexes().CreateOne(context.Background(), model)
+		if err != nil {
+			failed++
+			sug.ExecuteError = err.Error()
+			s.runtime.Logger.Error("create index %s %s failed, err:%v", sug.Ns, sug.KeyString, err)
+			continue
+		}
+		sug.Executed = true
+		s.runtime.Logger.Info("create index %s %s success, name:%s", sug.Ns, sug.KeyString, name)
+	}
+	report.ExecuteResult = fmt.Sprintf("created %d, failed %d", len(report.Suggestions)-failed, failed)
+	if failed > 0 {
+		return errors.Errorf("create index failed %d", failed)
+	}
+	return nil
+}
+
+func (s *indexAdvisorJob) outputReport() error {
+	report := s.tmp.report
+	for _, sug := range report.Suggestions {
+		s.runtime.Logger.Info("suggest %s queries:%d totalMillis:%d docsExamined:%d nreturned:%d plans:%q",
+			sug.CreateCommand, sug.QueryCount, sug.TotalMillis, sug.DocsExamined, sug.Nreturned,
+			strings.Join(sug.PlanSummaries, "; "))
+		if len(sug.RedundantIndexes) > 0 {
+			s.runtime.Logger.Info("index %q on %s is a prefix of %s, could be dropped after creation",
+				strings.Join(sug.RedundantIndexes, ","), sug.Ns, sug.KeyString)
+		}
+	}
+	for _, sk := range report.Skipped {
+		s.runtime.Logger.Info("skip %s %s, covered by existing index %s", sk.Ns, sk.KeyString, sk.CoveredBy)
+	}
+	if len(report.NoProfileDbs) > 0 {
+		s.runtime.Logger.Warn("no profile data in db: %s, use mongo_set_profiler first",
+			strings.Join(report.NoProfileDbs, ","))
+	}
+	out, _ := json.Marshal(report)
+	s.runtime.Logger.Info("index advisor report: %s", out)
+	s.runtime.PipeContextData = report
+	return nil
+}
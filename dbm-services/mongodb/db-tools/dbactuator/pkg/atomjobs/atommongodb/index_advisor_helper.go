@@ -0,0 +1,433 @@
+package atommongodb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// 索引建议的分析逻辑, 不依赖数据库连接
+
+// indexKeyField 索引字段
+type indexKeyField struct {
+	Field     string `json:"field"`
+	Direction int    `json:"direction"`
+}
+
+type indexKey []indexKeyField
+
+// String 输出为 {a: 1, b: -1}
+func (k indexKey) String() string {
+	items := make([]string, 0, len(k))
+	for _, f := range k {
+		items = append(items, fmt.Sprintf("%s: %d", f.Field, f.Direction))
+	}
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+// isPrefixOf k 是否为 other 的前缀(包括相同)
+// 索引可以反向遍历, 只要求各字段方向的相对关系一致: {a: 1} 是 {a: -1, b: 1} 的前缀,
+// {a: 1, b: -1} 是 {a: -1, b: 1, c: 1} 的前缀, 但不是 {a: 1, b: 1} 的前缀.
+// 方向为0的特殊索引(hashed/text等)字段只与方向相同的字段匹配
+func (k indexKey) isPrefixOf(other indexKey) bool {
+	if len(k) > len(other) {
+		return false
+	}
+	sign := 0
+	for i := range k {
+		if k[i].Field != other[i].Field {
+			return false
+		}
+		a, b := k[i].Direction, other[i].Direction
+		if a == 0 || b == 0 {
+			if a != b {
+				return false
+			}
+			continue
+		}
+		s := 1
+		if (a > 0) != (b > 0) {
+			s = -1
+		}
+		if sign == 0 {
+			sign = s
+		} else if sign != s {
+			return false
+		}
+	}
+	return true
+}
+
+// profileQuery 从 system.profile 中提取的一次查询
+type profileQuery struct {
+	Ns           string
+	Op           string
+	PlanSummary  string
+	Filter       bson.D
+	Sort         bson.D
+	Millis       int64
+	DocsExamined int64
+	KeysExamined int64
+	Nreturned    int64
+}
+
+// profileDoc system.profile 文档, 3.x 的查询条件在 query 中, 4.x 在 command 中
+type profileDoc struct {
+	Op             string `bson:"op"`
+	Ns             string `bson:"ns"`
+	Command        bson.D `bson:"command"`
+	Query          bson.D `bson:"query"`
+	OriginatingCmd bson.D `bson:"originatingCommand"`
+	PlanSummary    string `bson:"planSummary"`
+	Millis         int64  `bson:"millis"`
+	DocsExamined   int64  `bson:"docsExamined"`
+	KeysExamined   int64  `bson:"keysExamined"`
+	Nreturned      int64  `bson:"nreturned"`
+}
+
+// toProfileQuery 提取查询条件和排序, 没有查询条件时返回nil
+func (p *profileDoc) toProfileQuery() *profileQuery {
+	q := &profileQuery{
+		Ns: p.Ns, Op: p.Op, PlanSummary: p.PlanSummary, Millis: p.Millis,
+		DocsExamined: p.DocsExamined, KeysExamined: p.KeysExamined, Nreturned: p.Nreturned,
+	}
+	cmd := p.Command
+	if p.Op == "getmore" && len(p.OriginatingCmd) > 0 {
+		cmd = p.OriginatingCmd
+	}
+	if len(cmd) > 0 {
+		m := cmd.Map()
+		switch cmd[0].Key {
+		case "find":
+			q.Filter, q.Sort = toD(m["filter"]), toD(m["sort"])
+		case "count", "distinct":
+			q.Filter = toD(m["query"])
+		case "findAndModify", "findandmodify":
+			q.Filter, q.Sort = toD(m["query"]), toD(m["sort"])
+		case "aggregate":
+			pipeline, _ := m["pipeline"].(bson.A)
+			for _, stage := range pipeline {
+				d := toD(stage)
+				if len(d) == 0 {
+					break
+				}
+				if d[0].Key == "$match" && q.Filter == nil {
+					q.Filter = toD(d[0].Value)
+				} else if d[0].Key == "$sort" && q.Sort == nil {
+					q.Sort = toD(d[0].Value)
+				} else if d[0].Key != "$match" && d[0].Key != "$sort" {
+					break
+				}
+			}
+		default:
+			// update/remove 的 command 为 {q: {}, u: {}}
+			q.Filter = toD(m["q"])
+		}
+	} else if len(p.Query) > 0 {
+		// 3.x: {$query: {}, $orderby: {}} 或直接是查询条件
+		m := p.Query.Map()
+		if inner, ok := m["$query"]; ok {
+			q.Filter, q.Sort = toD(inner), toD(m["$orderby"])
+		} else {
+			q.Filter = p.Query
+		}
+	}
+	if len(q.Filter) == 0 && len(q.Sort) == 0 {
+		return nil
+	}
+	return q
+}
+
+func toD(v interface{}) bson.D {
+	d, _ := v.(bson.D)
+	return d
+}
+
+// rangeOps 范围类操作符, 放在索引最后
+var rangeOps = map[string]bool{
+	"$gt": true, "$gte": true, "$lt": true, "$lte": true,
+	"$ne": true, "$nin": true, "$exists": true, "$regex": true, "$type": true,
+}
+
+// suggestIndexKey 按 ESR(Equality, Sort, Range) 规则生成索引.
+// $or/$text/$where/$expr 等无法用单个复合索引优化的条件忽略
+func suggestIndexKey(filter, sortSpec bson.D) indexKey {
+	var equality, ranges []string
+	seen := map[string]bool{}
+	var walk func(d bson.D)
+	walk = func(d bson.D) {
+		for _, e := range d {
+			if e.Key == "$and" {
+				for _, sub := range toA(e.Value) {
+					walk(toD(sub))
+				}
+				continue
+			}
+			if strings.HasPrefix(e.Key, "$") || seen[e.Key] {
+				continue
+			}
+			seen[e.Key] = true
+			cond := fieldCondType(e.Value)
+			// 有排序时 $in 会导致内存排序, 按范围条件处理
+			if cond == "in" {
+				cond = "eq"
+				if len(sortSpec) > 0 {
+					cond = "range"
+				}
+			}
+			switch cond {
+			case "eq":
+				equality = append(equality, e.Key)
+			case "range":
+				ranges = append(ranges, e.Key)
+			}
+		}
+	}
+	walk(filter)
+	// 等值字段之间的顺序不影响查询, 排序后便于合并相同的建议
+	sort.Strings(equality)
+
+	var key indexKey
+	used := map[string]bool{}
+	for _, f := range equality {
+		key = append(key, indexKeyField{Field: f, Direction: 1})
+		used[f] = true
+	}
+	for _, e := range sortSpec {
+		if used[e.Key] {
+			continue
+		}
+		dir := 1
+		if toInt(e.Value) < 0 {
+			dir = -1
+		}
+		key = append(key, indexKeyField{Field: e.Key, Direction: dir})
+		used[e.Key] = true
+	}
+	for _, f := range ranges {
+		if !used[f] {
+			key = append(key, indexKeyField{Field: f, Direction: 1})
+			used[f] = true
+		}
+	}
+	// 只有 _id 等值查询时已有 _id 索引
+	if len(key) == 1 && key[0].Field == "_id" {
+		return nil
+	}
+	return key
+}
+
+// fieldCondType 字段条件类型: eq, in, range, 或空(不可用索引优化的条件)
+func fieldCondType(v interface{}) string {
+	d, ok := v.(bson.D)
+	if !ok || len(d) == 0 || !strings.HasPrefix(d[0].Key, "$") {
+		// 标量或嵌入文档的等值匹配
+		return "eq"
+	}
+	cond := ""
+	for _, op := range d {
+		switch {
+		case op.Key == "$eq":
+			if cond == "" {
+				cond = "eq"
+			}
+		case op.Key == "$in":
+			if cond == "" || cond == "eq" {
+				cond = "in"
+			}
+		case rangeOps[op.Key]:
+			cond = "range"
+		case op.Key == "$options":
+		default:
+			// $elemMatch/$size/$all/$not 等
+			return ""
+		}
+	}
+	return cond
+}
+
+func toA(v interface{}) bson.A {
+	a, _ := v.(bson.A)
+	return a
+}
+
+func toInt(v interface{}) int64 {
+	switch val := v.(type) {
+	case int32:
+		return int64(val)
+	case int64:
+		return val
+	case float64:
+		return int64(val)
+	}
+	return 0
+}
+
+// isPoorQuery COLLSCAN, 或 IXSCAN 但扫描文档数远大于返回文档数
+func isPoorQuery(q *profileQuery, minDocsRatio float64, minDocsExamined int64) bool {
+	if q.DocsExamined < minDocsExamined {
+		return false
+	}
+	if strings.HasPrefix(q.PlanSummary, "COLLSCAN") {
+		return true
+	}
+	if strings.Contains(q.PlanSummary, "IXSCAN") {
+		nreturned := q.Nreturned
+		if nreturned < 1 {
+			nreturned = 1
+		}
+		return float64(q.DocsExamined)/float64(nreturned) >= minDocsRatio
+	}
+	return false
+}
+
+// indexSuggestion 一个索引建议
+type indexSuggestion struct {
+	Ns            string   `json:"ns"`
+	Key           indexKey `json:"key"`
+	KeyString     string   `json:"keyString"`
+	CreateCommand string   `json:"createCommand"`
+	PlanSummaries []string `json:"planSummaries"` // 当前的执行计划
+	// 影响评估
+	QueryCount       int64   `json:"queryCount"`
+	TotalMillis      int64   `json:"totalMillis"`
+	AvgMillis        float64 `json:"avgMillis"`
+	DocsExamined     int64   `json:"docsExamined"`
+	Nreturned        int64   `json:"nreturned"`
+	DocsExaminedSave int64   `json:"docsExaminedSave"` // 理想情况下可减少的扫描文档数
+	CollectionCount  int64   `json:"collectionCount"`
+	// 与已有索引的关系
+	RedundantIndexes []string `json:"redundantIndexes,omitempty"` // 已有索引是本建议的前缀, 创建后可考虑删除
+	Executed         bool     `json:"executed"`
+	ExecuteError     string   `json:"executeError,omitempty"`
+}
+
+// skippedSuggestion 已有索引覆盖的建议
+type skippedSuggestion struct {
+	Ns        string `json:"ns"`
+	KeyString string `json:"keyString"`
+	CoveredBy string `json:"coveredBy"`
+	Count     int64  `json:"queryCount"`
+}
+
+// mergeSuggestions 按 ns+key 聚合, 同一ns下是其他建议前缀的合并到较长的建议中
+func mergeSuggestions(queries []*profileQuery) []*indexSuggestion {
+	byKey := map[string]*indexSuggestion{}
+	for _, q := range queries {
+		key := suggestIndexKey(q.Filter, q.Sort)
+		if len(key) == 0 {
+			continue
+		}
+		id := q.Ns + " " + key.String()
+		s, ok := byKey[id]
+		if !ok {
+			s = &indexSuggestion{Ns: q.Ns, Key: key, KeyString: key.String()}
+			byKey[id] = s
+		}
+		s.addQuery(q)
+	}
+	list := make([]*indexSuggestion, 0, len(byKey))
+	for _, s := range byKey {
+		list = append(list, s)
+	}
+	// 长的在前, 前缀合并到第一个匹配的长索引
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i].Key) != len(list[j].Key) {
+			return len(list[i].Key) > len(list[j].Key)
+		}
+		return list[i].Ns+list[i].KeyString < list[j].Ns+list[j].KeyString
+	})
+	var merged []*indexSuggestion
+	for _, s := range list {
+		var target *indexSuggestion
+		for _, m := range merged {
+			if m.Ns == s.Ns && s.Key.isPrefixOf(m.Key) {
+				target = m
+				break
+			}
+		}
+		if target == nil {
+			merged = append(merged, s)
+			continue
+		}
+		target.QueryCount += s.QueryCount
+		target.TotalMillis += s.TotalMillis
+		target.DocsExamined += s.DocsExamined
+		target.Nreturned += s.Nreturned
+		for _, p := range s.PlanSummaries {
+			target.addPlanSummary(p)
+		}
+	}
+	for _, s := range merged {
+		s.AvgMillis = float64(s.TotalMillis) / float64(s.QueryCount)
+		if s.DocsExamined > s.Nreturned {
+			s.DocsExaminedSave = s.DocsExamined - s.Nreturned
+		}
+	}
+	// 按总耗时倒序
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].TotalMillis > merged[j].TotalMillis
+	})
+	return merged
+}
+
+func (s *indexSuggestion) addQuery(q *profileQuery) {
+	s.QueryCount++
+	s.TotalMillis += q.Millis
+	s.DocsExamined += q.DocsExamined
+	s.Nreturned += q.Nreturned
+	s.addPlanSummary(q.PlanSummary)
+}
+
+func (s *indexSuggestion) addPlanSummary(p string) {
+	for _, v := range s.PlanSummaries {
+		if v == p {
+			return
+		}
+	}
+	if len(s.PlanSummaries) < 5 {
+		s.PlanSummaries = append(s.PlanSummaries, p)
+	}
+}
+
+// checkExistingIndexes 检查已有索引. 已有索引以建议为前缀(含相同)时返回覆盖它的索引名
+func (s *indexSuggestion) checkExistingIndexes(existing map[string]indexKey) (coveredBy string) {
+	names := make([]string, 0, len(existing))
+	for name := range existing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := existing[name]
+		if s.Key.isPrefixOf(key) {
+			return name
+		}
+		if key.isPrefixOf(s.Key) && name != "_id_" {
+			s.RedundantIndexes = append(s.RedundantIndexes, name)
+		}
+	}
+	return ""
+}
+
+// createIndexCommand 生成 mongo shell 命令
+func createIndexCommand(ns string, key indexKey) string {
+	db, col, _ := strings.Cut(ns, ".")
+	items := make([]string, 0, len(key))
+	for _, f := range key {
+		items = append(items, fmt.Sprintf("%q: %d", f.Field, f.Direction))
+	}
+	return fmt.Sprintf("db.getSiblingDB(%q).getCollection(%q).createIndex({%s}, {background: true})",
+		db, col, strings.Join(items, ", "))
+}
+
+// parseIndexKey 已有索引的key, 非普通索引(text/hashed/2d等)方向记为0
+func parseIndexKey(d bson.D) indexKey {
+	key := make(indexKey, 0, len(d))
+	for _, e := range d {
+		dir := int(toInt(e.Value))
+		key = append(key, indexKeyField{Field: e.Key, Direction: dir})
+	}
+	return key
+}
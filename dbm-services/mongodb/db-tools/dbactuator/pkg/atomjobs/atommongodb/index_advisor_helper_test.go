@@ -0,0 +1,140 @@
+package atommongodb
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// doc 按 key, value 顺序生成 bson.D
+func doc(kv ...interface{}) bson.D {
+	d := make(bson.D, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		d = append(d, bson.E{Key: kv[i].(string), Value: kv[i+1]})
+	}
+	return d
+}
+
+func TestSuggestIndexKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter bson.D
+		sort   bson.D
+		want   string
+	}{
+		{"equality sorted", doc("b", 1, "a", "x"), nil, "{a: 1, b: 1}"},
+		{"esr", doc("age", doc("$gt", 10), "status", "A"), doc("ts", int32(-1)),
+			"{status: 1, ts: -1, age: 1}"},
+		{"in without sort is equality", doc("b", doc("$gt", 1), "a", doc("$in", bson.A{1, 2})), nil,
+			"{a: 1, b: 1}"},
+		{"in with sort is range", doc("a", doc("$in", bson.A{1, 2}), "b", 1), doc("c", 1),
+			"{b: 1, c: 1, a: 1}"},
+		{"only _id", doc("_id", 1), nil, "{}"},
+		{"and", doc("$and", bson.A{doc("a", 1), doc("b", doc("$lt", 5))}), nil, "{a: 1, b: 1}"},
+		{"or ignored", doc("$or", bson.A{doc("a", 1), doc("b", 1)}), nil, "{}"},
+		{"elemMatch ignored", doc("tags", doc("$elemMatch", doc("x", 1)), "a", 1), nil, "{a: 1}"},
+		{"sort field already equality", doc("a", 1), doc("a", -1, "b", float64(-1)), "{a: 1, b: -1}"},
+		{"regex with options is range", doc("name", doc("$regex", "^a", "$options", "i"), "k", 1), nil,
+			"{k: 1, name: 1}"},
+		{"embedded document equality", doc("doc", doc("x", 1)), nil, "{doc: 1}"},
+		{"sort only", nil, doc("ts", int64(-1)), "{ts: -1}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggestIndexKey(tt.filter, tt.sort).String(); got != tt.want {
+				t.Fatalf("suggestIndexKey=%s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPrefixOf(t *testing.T) {
+	k := func(fields ...interface{}) indexKey {
+		var key indexKey
+		for i := 0; i+1 < len(fields); i += 2 {
+			key = append(key, indexKeyField{Field: fields[i].(string), Direction: fields[i+1].(int)})
+		}
+		return key
+	}
+	tests := []struct {
+		name   string
+		k, o   indexKey
+		prefix bool
+	}{
+		{"same", k("a", 1, "b", -1), k("a", 1, "b", -1), true},
+		{"single field reversed", k("a", 1), k("a", -1, "b", 1), true},
+		{"compound reversed", k("a", 1, "b", -1), k("a", -1, "b", 1, "c", 1), true},
+		{"compound relative direction differs", k("a", 1, "b", -1), k("a", 1, "b", 1), false},
+		{"longer", k("a", 1, "b", 1), k("a", 1), false},
+		{"field differs", k("a", 1), k("b", 1), false},
+		{"hashed", k("a", 1), k("a", 0), false},
+		{"hashed same", k("a", 0), k("a", 0, "b", 1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.k.isPrefixOf(tt.o); got != tt.prefix {
+				t.Fatalf("%s isPrefixOf %s=%v, want %v", tt.k, tt.o, got, tt.prefix)
+			}
+		})
+	}
+}
+
+func TestMergeSuggestions(t *testing.T) {
+	queries := []*profileQuery{
+		{Ns: "db.c", Filter: doc("a", 1), Millis: 10, DocsExamined: 100, Nreturned: 1, PlanSummary: "COLLSCAN"},
+		{Ns: "db.c", Filter: doc("a", 1, "b", 2), Millis: 20, DocsExamined: 200, Nreturned: 2,
+			PlanSummary: "IXSCAN { a: 1 }"},
+		{Ns: "db.c", Sort: doc("a", -1), Millis: 30, DocsExamined: 300, Nreturned: 300, PlanSummary: "COLLSCAN"},
+		{Ns: "db.d", Filter: doc("a", 1), Millis: 5, DocsExamined: 50, Nreturned: 0, PlanSummary: "COLLSCAN"},
+		{Ns: "db.c", Filter: doc("_id", 1), Millis: 100},
+	}
+	got := mergeSuggestions(queries)
+	if len(got) != 2 {
+		t.Fatalf("got %d suggestions, want 2", len(got))
+	}
+	s := got[0]
+	if s.Ns != "db.c" || s.KeyString != "{a: 1, b: 1}" {
+		t.Fatalf("first suggestion %s %s", s.Ns, s.KeyString)
+	}
+	if s.QueryCount != 3 || s.TotalMillis != 60 || s.AvgMillis != 20 || s.DocsExamined != 600 ||
+		s.Nreturned != 303 || s.DocsExaminedSave != 297 {
+		t.Fatalf("first suggestion stat %+v", s)
+	}
+	if !reflect.DeepEqual(s.PlanSummaries, []string{"IXSCAN { a: 1 }", "COLLSCAN"}) {
+		t.Fatalf("plan summaries %v", s.PlanSummaries)
+	}
+	if got[1].Ns != "db.d" || got[1].KeyString != "{a: 1}" || got[1].DocsExaminedSave != 50 {
+		t.Fatalf("second suggestion %+v", got[1])
+	}
+}
+
+func TestCheckExistingIndexes(t *testing.T) {
+	key := indexKey{{"a", 1}, {"b", -1}}
+	tests := []struct {
+		name      string
+		existing  map[string]indexKey
+		coveredBy string
+		redundant []string
+	}{
+		{"only _id", map[string]indexKey{"_id_": {{"_id", 1}}}, "", nil},
+		{"same", map[string]indexKey{"a_1_b_-1": {{"a", 1}, {"b", -1}}}, "a_1_b_-1", nil},
+		{"covered by reversed longer",
+			map[string]indexKey{"a_-1_b_1_c_1": {{"a", -1}, {"b", 1}, {"c", 1}}}, "a_-1_b_1_c_1", nil},
+		{"relative direction differs", map[string]indexKey{"a_1_b_1_c_1": {{"a", 1}, {"b", 1}, {"c", 1}}}, "", nil},
+		{"redundant prefixes", map[string]indexKey{
+			"a_1": {{"a", 1}}, "a_-1": {{"a", -1}}, "a_hashed": {{"a", 0}}, "_id_": {{"_id", 1}},
+		}, "", []string{"a_-1", "a_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &indexSuggestion{Ns: "db.c", Key: key, KeyString: key.String()}
+			if got := s.checkExistingIndexes(tt.existing); got != tt.coveredBy {
+				t.Fatalf("coveredBy=%q, want %q", got, tt.coveredBy)
+			}
+			if !reflect.DeepEqual(s.RedundantIndexes, tt.redundant) {
+				t.Fatalf("redundant=%v, want %v", s.RedundantIndexes, tt.redundant)
+			}
+		})
+	}
+}
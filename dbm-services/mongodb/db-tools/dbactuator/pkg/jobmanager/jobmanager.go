@@ -153,6 +153,7 @@ func (m *JobGenericManager) RegisterAtomJob() {
 			atommongodb.NewHelloJob,
 			atommongodb.NewInstOpJob,
 			atommongodb.NewPitrRebuildClusterJobJob,
+			atommongodb.NewIndexAdvisorJob,
 		} {
 			m.atomJobMapper[f().Name()] = f
 		}
@@ -6,6 +6,7 @@ import (
 	"dbm-services/mongodb/db-tools/dbmon/config"
 	"dbm-services/mongodb/db-tools/dbmon/mylog"
 	"dbm-services/mongodb/db-tools/dbmon/pkg/linuxproc"
+	"dbm-services/mongodb/db-tools/dbmon/pkg/slowshape"
 	"fmt"
 	"os"
 	"slices"
@@ -42,6 +43,15 @@ var (
 		},
 	}
 
+	slowShapesCmd = &cobra.Command{
+		Use:   "slowshapes",
+		Short: "slowshapes",
+		Long:  `slow query shapes report. --file: analyze mongo.log offline; --port: show the latest periodic report`,
+		Run: func(cmd *cobra.Command, args []string) {
+			slowShapesMain()
+		},
+	}
+
 	checkPortInUseCmd = &cobra.Command{
 		Use:   "checkportinuse",
 		Short: "checkportinuse",
@@ -60,6 +70,8 @@ var logFilePattern string
 var outputDir string
 var outputFile string
 var follow bool
+var slowShapeFile string
+var slowShapeTopN int
 
 func init() {
 	sendMsgCmd.Flags().StringVar(&msgType, "type", "event|ts", "msg type")
@@ -74,9 +86,13 @@ func init() {
 	ParseMongoLogCmd.Flags().StringVar(&outputFile, "outputFile", "", "output fileName prefix")
 	ParseMongoLogCmd.Flags().BoolVar(&follow, "follow", false, "tail -f logFile")
 	checkPortInUseCmd.Flags().IntVar(&instancePort, "port", 27017, "port")
+	slowShapesCmd.Flags().StringVar(&slowShapeFile, "file", "", "mongo log file, analyze offline")
+	slowShapesCmd.Flags().IntVar(&instancePort, "port", 27017, "port, show the latest report if --file is empty")
+	slowShapesCmd.Flags().IntVar(&slowShapeTopN, "top", 20, "top n shapes order by total time")
 	debugCmd.AddCommand(sendMsgCmd)
 	debugCmd.AddCommand(ParseMongoLogCmd)
 	debugCmd.AddCommand(checkPortInUseCmd)
+	debugCmd.AddCommand(slowShapesCmd)
 }
 
 // debugCmdMain go run main.go debug
@@ -124,10 +140,44 @@ func sendmsgCmdMain() {
 func parseMongoLog() {
 	fmt.Printf("logFilePattern:%s, outputDir:%s\n", logFilePattern, outputDir)
 	succ, fail, err := logparserjob.ParseFile(logFilePattern, outputDir, outputFile, follow,
-		context.TODO(), context.TODO(), nil, mylog.Logger)
+		context.TODO(), context.TODO(), nil, nil, mylog.Logger)
 	fmt.Printf("succ %d fail %d err %v\n", succ, fail, err)
 }
 
+// slowShapesMain go run main.go debug slowshapes --file=/data/mongolog/27017/mongo.log --top=20
+func slowShapesMain() {
+	var report *slowshape.Report
+	if slowShapeFile != "" {
+		agg := slowshape.NewAggregator()
+		count, err := slowshape.AnalyzeFile(slowShapeFile, agg)
+		if err != nil {
+			log.Fatalf("analyze %s failed: %v", slowShapeFile, err)
+		}
+		fmt.Printf("analyze %s, slow ops: %d\n", slowShapeFile, count)
+		report = agg.Report(slowShapeTopN)
+		report.Instance = slowShapeFile
+	} else {
+		preRun(true)
+		servers := dbmonConf.Config.Servers
+		idx := slices.IndexFunc(servers, func(s config.ConfServerItem) bool {
+			return s.Port == instancePort
+		})
+		if idx < 0 {
+			log.Fatalf("config文件:%q中不存在port==%d的server\n", cfgFile, instancePort)
+		}
+		reportFile := logparserjob.SlowShapeReportFile(&servers[idx], mylog.Logger)
+		var err error
+		report, err = slowshape.LoadReport(reportFile)
+		if err != nil {
+			log.Fatalf("load %s failed: %v", reportFile, err)
+		}
+		if slowShapeTopN > 0 && len(report.Shapes) > slowShapeTopN {
+			report.Shapes = report.Shapes[:slowShapeTopN]
+		}
+	}
+	report.Print(os.Stdout)
+}
+
 func checkPortInUse() {
 	tcpRows, err := linuxproc.ProcNetTcp(nil)
 	fmt.Printf("ProcNetTcp %d return %d rows\n", instancePort, len(tcpRows))
@@ -6,6 +6,7 @@ import (
 	"dbm-services/mongodb/db-tools/dbmon/pkg/fileinfo"
 	"dbm-services/mongodb/db-tools/dbmon/pkg/fileutil"
 	"dbm-services/mongodb/db-tools/dbmon/pkg/mongologparser"
+	"dbm-services/mongodb/db-tools/dbmon/pkg/slowshape"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -141,8 +142,9 @@ func precheck(srcFileName, dirName, outFileName string) (err error) {
 
 // ParseFile parse the log file and write the parsed log messages to the output file
 // ParseFile 要保证ctx.Done()时能正确退出。
+// shapes 不为nil时, 慢日志按查询模式聚合
 func ParseFile(srcFileName string, dirName, outFileName string, follow bool,
-	ctx, osCtx context.Context, metaInfo []byte, logger *zap.Logger) (
+	ctx, osCtx context.Context, metaInfo []byte, shapes *slowshape.Aggregator, logger *zap.Logger) (
 
 	succ int, fail int, err error) {
 	if err = precheck(srcFileName, dirName, outFileName); err != nil {
@@ -244,6 +246,14 @@ func ParseFile(srcFileName string, dirName, outFileName string, follow bool,
 				fail++
 			}
 
+			if shapes != nil && msg.Id == mongologparser.LogTypeSlowlog {
+				if op, err := slowshape.ParseSlowOp(msg, []byte(line.Text)); op != nil {
+					shapes.Add(op)
+				} else if err != nil {
+					logger.Debug("failed to parse slow op", zap.Error(err))
+				}
+			}
+
 			msg.Line.Num, msg.Line.Time, msg.Line.OffSet = line.Num, line.Time, line.SeekInfo.Offset
 			msg.Line.TimeDiff = int64(msg.DateTime.Time().Sub(msg.Line.Time).Seconds())
 			msgJson, _ := json.Marshal(msg)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"dbm-services/mongodb/db-tools/dbmon/config"
 	"dbm-services/mongodb/db-tools/dbmon/pkg/mongoconf"
+	"dbm-services/mongodb/db-tools/dbmon/pkg/slowshape"
 	"fmt"
 	"path"
 	"strconv"
@@ -58,6 +59,7 @@ func NewWorker(server *config.ConfServerItem, maxTime int64, logger *zap.Logger,
 		Logger:      logger.With(zap.String("instance", server.Addr())),
 		LoopTime:    maxTime,
 		OsCtx:       osCtx,
+		Shapes:      slowshape.NewAggregator(),
 	}
 
 	return w
@@ -74,6 +76,7 @@ type Worker struct {
 	Ctx                 context.Context
 	CancelFunc          context.CancelFunc
 	OsCtx               context.Context
+	Shapes              *slowshape.Aggregator // 慢日志按查询模式聚合, 每次Run为一个统计窗口
 }
 
 const sizeG = 1024 * 1024 * 1024
@@ -94,8 +97,46 @@ func (w *Worker) Run() {
 	defer w.CancelFunc()
 	succ, fail, err := ParseFile(
 		w.LogFilePath, w.DstDir, "mongo.log", true, w.Ctx, w.OsCtx,
-		[]byte(w.Server.MetaForLog()), w.Logger)
+		[]byte(w.Server.MetaForLog()), w.Shapes, w.Logger)
 	w.Logger.Info(fmt.Sprintf("succ %d fail %d err %v", succ, fail, err))
+	w.reportSlowShapes()
+}
+
+// SlowShapeReportFile 慢查询模式报告文件, 每个统计窗口结束时覆盖
+func SlowShapeReportFile(server *config.ConfServerItem, logger *zap.Logger) string {
+	mongoLogFile := getMongoLogDir(server.Port, server.MetaRole == "mongos", logger)
+	return path.Join(path.Dir(mongoLogFile), "jsonlog", slowShapeReportName)
+}
+
+const slowShapeReportName = "slowshapes.json"
+
+// reportSlowShapes 输出本窗口 topN 慢查询模式, 并开始新的窗口
+func (w *Worker) reportSlowShapes() {
+	topN, err := config.ClusterConfig.GetInt64(w.Server, config.SegmentParseLog, config.KeySlowShapeTopN, 20)
+	if err != nil {
+		w.Logger.Warn(fmt.Sprintf("get parselog.%s failed: %v", config.KeySlowShapeTopN, err))
+	}
+	report := w.Shapes.Report(int(topN))
+	w.Shapes.Reset()
+	report.Instance = w.Server.Addr()
+	if report.TotalOps == 0 {
+		return
+	}
+	reportFile := path.Join(w.DstDir, slowShapeReportName)
+	if err = report.WriteFile(reportFile); err != nil {
+		w.Logger.Error(fmt.Sprintf("write slow shape report %s failed: %v", reportFile, err))
+	}
+	for i, s := range report.Shapes {
+		if i >= 3 {
+			break
+		}
+		w.Logger.Info("top slow shape", zap.String("fingerprint", s.Fingerprint),
+			zap.String("op", s.Op), zap.String("ns", s.Ns), zap.String("filter", s.Filter),
+			zap.String("plan", s.PlanSummary), zap.Int64("count", s.Count),
+			zap.Int64("totalMillis", s.TotalMillis), zap.Int64("p95Millis", s.P95Millis))
+	}
+	w.Logger.Info(fmt.Sprintf("slow shape report %s: total_ops %d shapes %d",
+		reportFile, report.TotalOps, report.ShapeCount))
 }
 
 // Stop 解析日志文件
@@ -88,6 +88,7 @@ const KeyMinDiskUsage = "min-disk-usage"
 const KeyMaxRecordPerSecond = "max-record-per-second"
 const KeyMaxTime = "maxtime"
 const KeyMaxSizeG = "maxsizeg"
+const KeySlowShapeTopN = "slowshape-topn"
 const ValueTrue = "true"
 const ValueFalse = "false"
 const KeyNumParallelCollections = "concurrent"
@@ -112,6 +113,7 @@ func GetAllClusterConfigRows() []ClusterConfigItem {
 		{Segment: SegmentAlarm, Key: ShieldEndTimeKey, Value: ""},              // 屏蔽结束时间，为空为0都表示永久屏蔽
 		{Segment: SegmentParseLog, Key: KeyEnable, Value: ValueTrue},           // 是否开启日志解析
 		{Segment: SegmentParseLog, Key: KeyMaxRecordPerSecond, Value: "10000"}, // 每秒解析的最大日志数
+		{Segment: SegmentParseLog, Key: KeySlowShapeTopN, Value: "20"},         // 慢查询模式报告保留的模式数
 		// mongo.log.* 文件最大时间，超过这个时间就删除 2592000 = 30天
 		{Segment: SegmentLog, Key: KeyMaxTime, Value: "2592000"},
 		// mongo.log.* 文件最大大小，超过这个大小就删除，从最旧的开始删除
@@ -0,0 +1,207 @@
+package slowshape
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxShapes 单个窗口内最多统计的模式数, 超过的计入 DroppedOps
+	maxShapes = 10000
+	// maxSamples 每个模式最多保留的耗时样本数, 用于计算分位数
+	maxSamples = 1000
+)
+
+// ShapeStat 单个查询模式的统计
+type ShapeStat struct {
+	Fingerprint string `json:"fingerprint"`
+	Shape
+	Count        int64     `json:"count"`
+	TotalMillis  int64     `json:"total_millis"`
+	MaxMillis    int64     `json:"max_millis"`
+	P50Millis    int64     `json:"p50_millis"`
+	P95Millis    int64     `json:"p95_millis"`
+	P99Millis    int64     `json:"p99_millis"`
+	DocsExamined int64     `json:"docs_examined"`
+	KeysExamined int64     `json:"keys_examined"`
+	Nreturned    int64     `json:"nreturned"`
+	DocsRatio    float64   `json:"docs_examined_ratio"` // docsExamined/nreturned, 越大说明扫描越多无用文档
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	samples      []int64
+}
+
+// Report 一个窗口的统计报告
+type Report struct {
+	Instance   string      `json:"instance"`
+	Start      time.Time   `json:"start"`
+	End        time.Time   `json:"end"`
+	TotalOps   int64       `json:"total_ops"`
+	DroppedOps int64       `json:"dropped_ops"`
+	ShapeCount int         `json:"shape_count"`
+	Shapes     []ShapeStat `json:"shapes"` // 按总耗时倒序, 只保留 topN
+}
+
+// Aggregator 按查询模式聚合慢操作
+type Aggregator struct {
+	mu         sync.Mutex
+	start      time.Time
+	totalOps   int64
+	droppedOps int64
+	shapes     map[string]*ShapeStat
+}
+
+// NewAggregator new
+func NewAggregator() *Aggregator {
+	return &Aggregator{start: time.Now(), shapes: make(map[string]*ShapeStat)}
+}
+
+// Add 统计一条慢操作
+func (a *Aggregator) Add(op *SlowOp) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.totalOps++
+	fp := op.Fingerprint()
+	stat, ok := a.shapes[fp]
+	if !ok {
+		if len(a.shapes) >= maxShapes {
+			a.droppedOps++
+			return
+		}
+		stat = &ShapeStat{Fingerprint: fp, Shape: op.Shape, FirstSeen: op.Time}
+		a.shapes[fp] = stat
+	}
+	stat.Count++
+	stat.TotalMillis += op.DurationMillis
+	if op.DurationMillis > stat.MaxMillis {
+		stat.MaxMillis = op.DurationMillis
+	}
+	stat.DocsExamined += op.DocsExamined
+	stat.KeysExamined += op.KeysExamined
+	stat.Nreturned += op.Nreturned
+	if op.Time.Before(stat.FirstSeen) {
+		stat.FirstSeen = op.Time
+	}
+	if op.Time.After(stat.LastSeen) {
+		stat.LastSeen = op.Time
+	}
+	// 蓄水池采样, 样本数固定时分位数仍近似均匀
+	if len(stat.samples) < maxSamples {
+		stat.samples = append(stat.samples, op.DurationMillis)
+	} else if i := rand.Int63n(stat.Count); i < maxSamples {
+		stat.samples[i] = op.DurationMillis
+	}
+}
+
+// Report 生成报告, topN <= 0 时返回全部模式
+func (a *Aggregator) Report(topN int) *Report {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r := &Report{
+		Start:      a.start,
+		End:        time.Now(),
+		TotalOps:   a.totalOps,
+		DroppedOps: a.droppedOps,
+		ShapeCount: len(a.shapes),
+		Shapes:     make([]ShapeStat, 0, len(a.shapes)),
+	}
+	for _, stat := range a.shapes {
+		s := *stat
+		samples := append([]int64(nil), stat.samples...)
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		s.P50Millis = percentile(samples, 0.50)
+		s.P95Millis = percentile(samples, 0.95)
+		s.P99Millis = percentile(samples, 0.99)
+		if s.Nreturned > 0 {
+			s.DocsRatio = float64(s.DocsExamined) / float64(s.Nreturned)
+		} else {
+			s.DocsRatio = float64(s.DocsExamined)
+		}
+		s.samples = nil
+		r.Shapes = append(r.Shapes, s)
+	}
+	sort.Slice(r.Shapes, func(i, j int) bool {
+		if r.Shapes[i].TotalMillis != r.Shapes[j].TotalMillis {
+			return r.Shapes[i].TotalMillis > r.Shapes[j].TotalMillis
+		}
+		return r.Shapes[i].Fingerprint < r.Shapes[j].Fingerprint
+	})
+	if topN > 0 && len(r.Shapes) > topN {
+		r.Shapes = r.Shapes[:topN]
+	}
+	return r
+}
+
+// Reset 开始新的统计窗口
+func (a *Aggregator) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.start = time.Now()
+	a.totalOps, a.droppedOps = 0, 0
+	a.shapes = make(map[string]*ShapeStat)
+}
+
+// percentile samples 已排序
+func percentile(samples []int64, p float64) int64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	idx := int(float64(len(samples))*p+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(samples) {
+		idx = len(samples) - 1
+	}
+	return samples[idx]
+}
+
+// WriteFile 保存报告, 先写临时文件再改名, 避免读到不完整的文件
+func (r *Report) WriteFile(fileName string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal report")
+	}
+	tmpFile := fileName + ".tmp"
+	if err = os.WriteFile(tmpFile, content, 0644); err != nil {
+		return errors.Wrap(err, "write report")
+	}
+	return os.Rename(tmpFile, fileName)
+}
+
+// LoadReport 读取报告文件
+func LoadReport(fileName string) (*Report, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.Wrap(err, "read report")
+	}
+	r := &Report{}
+	if err = json.Unmarshal(content, r); err != nil {
+		return nil, errors.Wrap(err, "unmarshal report")
+	}
+	return r, nil
+}
+
+// Print 以表格形式输出
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "instance:%s window:[%s, %s] total_ops:%d dropped_ops:%d shapes:%d\n",
+		r.Instance, r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339),
+		r.TotalOps, r.DroppedOps, r.ShapeCount)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FINGERPRINT\tCOUNT\tTOTAL_MS\tP50\tP95\tP99\tMAX\tDOCS_RATIO\tOP\tNS\tPLAN\tFILTER\tSORT")
+	for _, s := range r.Shapes {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\n",
+			s.Fingerprint, s.Count, s.TotalMillis, s.P50Millis, s.P95Millis, s.P99Millis, s.MaxMillis,
+			s.DocsRatio, s.Op, s.Ns, s.PlanSummary, s.Filter, s.Sort)
+	}
+	tw.Flush()
+}
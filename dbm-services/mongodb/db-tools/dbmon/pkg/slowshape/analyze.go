@@ -0,0 +1,51 @@
+package slowshape
+
+import (
+	"bufio"
+	"bytes"
+	"dbm-services/mongodb/db-tools/dbmon/pkg/mongologparser"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// AnalyzeFile 离线分析 mongo.log, 返回慢操作条数
+func AnalyzeFile(fileName string, agg *Aggregator) (int64, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return 0, errors.Wrap(err, "open log file")
+	}
+	defer f.Close()
+	var count int64
+	reader := bufio.NewReaderSize(f, 1024*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 {
+			if op := parseLine(line); op != nil {
+				agg.Add(op)
+				count++
+			}
+		}
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, errors.Wrap(err, "read log file")
+		}
+	}
+}
+
+func parseLine(line []byte) *SlowOp {
+	p, err := mongologparser.GetParser(line)
+	if err != nil {
+		return nil
+	}
+	msg, err := p.Parse(line)
+	if err != nil || msg == nil {
+		return nil
+	}
+	op, _ := ParseSlowOp(msg, line)
+	return op
+}
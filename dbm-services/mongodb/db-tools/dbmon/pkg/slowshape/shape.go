@@ -0,0 +1,332 @@
+// Package slowshape 慢操作指纹: 将慢日志归一化为查询模式(去掉字面量), 按模式聚合统计
+package slowshape
+
+import (
+	"crypto/md5"
+	"dbm-services/mongodb/db-tools/dbmon/pkg/mongologparser"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Shape 查询模式
+type Shape struct {
+	Op          string `json:"op"`           // find/update/aggregate...
+	Ns          string `json:"ns"`           // db.collection
+	Filter      string `json:"filter"`       // 去掉字面量的过滤条件, 如 {a: ?, b: {$in: ?}}
+	Sort        string `json:"sort"`         // 排序条件, 保留 1/-1
+	PlanSummary string `json:"plan_summary"` // COLLSCAN, IXSCAN { a: 1 } ...
+}
+
+// Fingerprint 查询模式的指纹
+func (s *Shape) Fingerprint() string {
+	sum := md5.Sum([]byte(strings.Join([]string{s.Op, s.Ns, s.Filter, s.Sort, s.PlanSummary}, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SlowOp 一条慢操作
+type SlowOp struct {
+	Shape
+	Time           time.Time
+	DurationMillis int64
+	DocsExamined   int64
+	KeysExamined   int64
+	Nreturned      int64
+}
+
+// ParseSlowOp 从解析后的日志中提取慢操作. 不是慢日志时返回 nil, nil
+// line 为原始日志行, v3(json) 日志需要重新按顺序解析 attr
+func ParseSlowOp(msg *mongologparser.MongoLogMsg, line []byte) (*SlowOp, error) {
+	if msg == nil || msg.Id != mongologparser.LogTypeSlowlog {
+		return nil, nil
+	}
+	var op *SlowOp
+	var err error
+	if len(line) > 0 && line[0] == '{' {
+		op, err = parseV3SlowOp(line)
+	} else {
+		op, err = parseV2SlowOp(msg)
+	}
+	if op != nil {
+		op.Time = msg.DateTime.Time()
+	}
+	return op, err
+}
+
+// parseV3SlowOp mongodb 4.4+ json 日志
+func parseV3SlowOp(line []byte) (*SlowOp, error) {
+	var row struct {
+		Attr bson.D `bson:"attr"`
+	}
+	if err := bson.UnmarshalExtJSON(line, false, &row); err != nil {
+		return nil, errors.Wrap(err, "unmarshal slowlog")
+	}
+	attr := row.Attr.Map()
+	op := &SlowOp{}
+	op.Ns, _ = attr["ns"].(string)
+	op.PlanSummary, _ = attr["planSummary"].(string)
+	op.DurationMillis = toInt64(attr["durationMillis"])
+	op.DocsExamined = toInt64(attr["docsExamined"])
+	op.KeysExamined = toInt64(attr["keysExamined"])
+	op.Nreturned = toInt64(attr["nreturned"])
+	cmd, _ := attr["command"].(bson.D)
+	if len(cmd) == 0 {
+		op.Op, _ = attr["type"].(string)
+		return op, nil
+	}
+	op.Op = cmd[0].Key
+	if op.Op == "getMore" {
+		// getMore 的查询条件在 originatingCommand 中
+		if origin, ok := attr["originatingCommand"].(bson.D); ok && len(origin) > 0 {
+			cmd = origin
+		}
+	} else if _, ok := cmd.Map()["q"]; ok {
+		// 4.4 以前的 OP_QUERY 写操作: {q: {}, u: {}}
+		op.Op, _ = attr["type"].(string)
+	}
+	op.setCmdShape(cmd)
+	return op, nil
+}
+
+// parseV2SlowOp mongodb 2.4 - 4.2 文本日志, 格式如:
+// command db.$cmd command: find { find: "col", filter: { a: 1 } } planSummary: IXSCAN { a: 1 } ... 10ms
+// query db.col query: { a: 1 } planSummary: COLLSCAN ... 10ms
+func parseV2SlowOp(msg *mongologparser.MongoLogMsg) (*SlowOp, error) {
+	attr, ok := msg.Attr.(*mongologparser.SlowlogAttr)
+	if !ok || attr == nil {
+		return nil, errors.New("bad slowlog attr")
+	}
+	op := &SlowOp{DurationMillis: int64(attr.DurationMillis)}
+	op.DocsExamined = intPtrValue(attr.DocsExamined)
+	op.KeysExamined = intPtrValue(attr.KeysExamined)
+	op.Nreturned = intPtrValue(attr.Nreturned)
+
+	text := msg.Msg
+	// 超长日志被截断, 只能拿到类型和ns
+	if strings.HasPrefix(text, "warning:") {
+		if idx := strings.Index(text, " ... "); idx > 0 {
+			text = text[idx+len(" ... "):]
+		}
+	}
+	words := strings.SplitN(text, " ", 3)
+	if len(words) < 3 {
+		return nil, errors.Errorf("bad slowlog %q", text)
+	}
+	op.Op, op.Ns = words[0], words[1]
+	op.PlanSummary = getV2PlanSummary(text)
+	if op.Op == "command" {
+		idx := strings.Index(text, " command: ")
+		if idx < 0 {
+			return op, nil
+		}
+		rest := text[idx+len(" command: "):]
+		if sp := strings.IndexByte(rest, ' '); sp > 0 {
+			op.Op = rest[:sp]
+			cmd, _, err := parseShellDoc(rest[sp+1:])
+			if err != nil {
+				return op, nil
+			}
+			op.setCmdShape(cmd)
+		}
+		return op, nil
+	}
+
+	op.Op = legacyOpName(op.Op)
+	if idx := strings.Index(text, " query: "); idx >= 0 {
+		query, _, err := parseShellDoc(text[idx+len(" query: "):])
+		if err != nil || op.Op == "insert" {
+			return op, nil
+		}
+		// 旧版本 query 可能为 { $query: {}, $orderby: {} }
+		m := query.Map()
+		for _, key := range []string{"$query", "query"} {
+			if q, ok := m[key].(bson.D); ok {
+				op.Filter = filterShape(q)
+				for _, sortKey := range []string{"$orderby", "orderby"} {
+					if s, ok := m[sortKey].(bson.D); ok {
+						op.Sort = sortShape(s)
+					}
+				}
+				return op, nil
+			}
+		}
+		op.Filter = filterShape(query)
+	}
+	return op, nil
+}
+
+// legacyOpName 旧版本日志中的操作类型转换为命令名
+func legacyOpName(op string) string {
+	switch op {
+	case "query":
+		return "find"
+	case "getmore":
+		return "getMore"
+	case "remove":
+		return "delete"
+	}
+	return op
+}
+
+// getV2PlanSummary planSummary: IXSCAN { a: 1 } 或 planSummary: COLLSCAN
+func getV2PlanSummary(text string) string {
+	idx := strings.Index(text, "planSummary: ")
+	if idx < 0 {
+		return ""
+	}
+	rest := text[idx+len("planSummary: "):]
+	sp := strings.IndexByte(rest, ' ')
+	if sp < 0 {
+		return rest
+	}
+	plan := rest[:sp]
+	if strings.HasPrefix(rest[sp+1:], "{") {
+		if end := strings.IndexByte(rest, '}'); end > sp {
+			plan = rest[:end+1]
+		}
+	}
+	return plan
+}
+
+// setCmdShape 从命令中提取过滤条件和排序
+func (op *SlowOp) setCmdShape(cmd bson.D) {
+	m := cmd.Map()
+	// command 中的集合名比 db.$cmd 更准确
+	if db, col, ok := strings.Cut(op.Ns, "."); ok && col == "$cmd" {
+		if name, ok := cmd[0].Value.(string); ok {
+			op.Ns = db + "." + name
+		}
+	}
+	var filter, sortSpec interface{}
+	switch cmd[0].Key {
+	case "find":
+		filter, sortSpec = m["filter"], m["sort"]
+	case "count", "distinct":
+		filter = m["query"]
+	case "findAndModify", "findandmodify":
+		filter, sortSpec = m["query"], m["sort"]
+	case "update":
+		filter = firstArrayField(m["updates"], "q")
+	case "delete":
+		filter = firstArrayField(m["deletes"], "q")
+	case "aggregate":
+		// 只取最前面的 $match 和 $sort
+		pipeline, _ := m["pipeline"].(bson.A)
+		for _, stage := range pipeline {
+			d, ok := stage.(bson.D)
+			if !ok || len(d) == 0 {
+				break
+			}
+			if d[0].Key == "$match" && filter == nil {
+				filter = d[0].Value
+			} else if d[0].Key == "$sort" && sortSpec == nil {
+				sortSpec = d[0].Value
+			} else if d[0].Key != "$match" && d[0].Key != "$sort" {
+				break
+			}
+		}
+	default:
+		filter = m["q"]
+	}
+	if d, ok := filter.(bson.D); ok {
+		op.Filter = filterShape(d)
+	}
+	if d, ok := sortSpec.(bson.D); ok {
+		op.Sort = sortShape(d)
+	}
+}
+
+func firstArrayField(v interface{}, key string) interface{} {
+	arr, ok := v.(bson.A)
+	if !ok || len(arr) == 0 {
+		return nil
+	}
+	if d, ok := arr[0].(bson.D); ok {
+		return d.Map()[key]
+	}
+	return nil
+}
+
+// filterShape 去掉字面量, 键按字母排序. {b: 1, a: {$gt: 2}} => {a: {$gt: ?}, b: ?}
+func filterShape(d bson.D) string {
+	items := make([]string, 0, len(d))
+	for _, e := range d {
+		items = append(items, e.Key+": "+valueShape(e.Key, e.Value))
+	}
+	sort.Strings(items)
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+func valueShape(key string, v interface{}) string {
+	switch val := v.(type) {
+	case bson.D:
+		// 嵌套文档只有操作符才展开, 否则视为字面量
+		if len(val) > 0 && strings.HasPrefix(val[0].Key, "$") {
+			return filterShape(val)
+		}
+		return "?"
+	case bson.A:
+		if key == "$and" || key == "$or" || key == "$nor" {
+			items := make([]string, 0, len(val))
+			seen := map[string]bool{}
+			for _, sub := range val {
+				d, ok := sub.(bson.D)
+				if !ok {
+					continue
+				}
+				s := filterShape(d)
+				if !seen[s] {
+					seen[s] = true
+					items = append(items, s)
+				}
+			}
+			sort.Strings(items)
+			return "[" + strings.Join(items, ", ") + "]"
+		}
+		return "?"
+	}
+	return "?"
+}
+
+// sortShape 排序条件保留顺序和方向
+func sortShape(d bson.D) string {
+	items := make([]string, 0, len(d))
+	for _, e := range d {
+		items = append(items, fmt.Sprintf("%s: %v", e.Key, e.Value))
+	}
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+func toInt64(v interface{}) int64 {
+	switch val := v.(type) {
+	case int32:
+		return int64(val)
+	case int64:
+		return val
+	case int:
+		return int64(val)
+	case float64:
+		return int64(val)
+	case json.Number:
+		i, _ := val.Int64()
+		return i
+	case string:
+		i, _ := strconv.ParseInt(val, 10, 64)
+		return i
+	}
+	return 0
+}
+
+func intPtrValue(v *int) int64 {
+	if v == nil {
+		return 0
+	}
+	return int64(*v)
+}
@@ -0,0 +1,169 @@
+package slowshape
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// shellDocParser 解析 v2 日志中 mongo shell 格式的文档, 如 { a: 1, b: { $in: [ 1, 2 ] }, c: ObjectId('...') }
+// 只关心结构, 标量值原样保留为字符串
+type shellDocParser struct {
+	s   string
+	pos int
+}
+
+// parseShellDoc 从 s 的开头解析一个文档, 返回文档和结束位置
+func parseShellDoc(s string) (bson.D, int, error) {
+	p := &shellDocParser{s: s}
+	p.skipSpace()
+	doc, err := p.parseDoc()
+	if err != nil {
+		return nil, p.pos, err
+	}
+	return doc, p.pos, nil
+}
+
+func (p *shellDocParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *shellDocParser) peek() byte {
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *shellDocParser) parseDoc() (bson.D, error) {
+	if p.peek() != '{' {
+		return nil, errors.Errorf("expect '{' at %d", p.pos)
+	}
+	p.pos++
+	doc := bson.D{}
+	for {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.pos++
+			return doc, nil
+		}
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		doc = append(doc, bson.E{Key: key, Value: val})
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			return nil, errors.Errorf("expect ',' or '}' at %d", p.pos)
+		}
+	}
+}
+
+func (p *shellDocParser) parseArray() (bson.A, error) {
+	p.pos++
+	arr := bson.A{}
+	for {
+		p.skipSpace()
+		if p.peek() == ']' {
+			p.pos++
+			return arr, nil
+		}
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, val)
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, errors.Errorf("expect ',' or ']' at %d", p.pos)
+		}
+	}
+}
+
+// parseKey 键可以带引号, 也可以是裸字符串
+func (p *shellDocParser) parseKey() (string, error) {
+	if c := p.peek(); c == '"' || c == '\'' {
+		end := strings.IndexByte(p.s[p.pos+1:], c)
+		if end < 0 {
+			return "", errors.Errorf("unterminated key at %d", p.pos)
+		}
+		key := p.s[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		p.skipSpace()
+		if p.peek() != ':' {
+			return "", errors.Errorf("expect ':' at %d", p.pos)
+		}
+		p.pos++
+		return key, nil
+	}
+	end := strings.IndexByte(p.s[p.pos:], ':')
+	if end <= 0 {
+		return "", errors.Errorf("expect key at %d", p.pos)
+	}
+	key := strings.TrimSpace(p.s[p.pos : p.pos+end])
+	p.pos += end + 1
+	return key, nil
+}
+
+func (p *shellDocParser) parseValue() (interface{}, error) {
+	switch p.peek() {
+	case '{':
+		return p.parseDoc()
+	case '[':
+		return p.parseArray()
+	case 0:
+		return nil, errors.New("unexpected end of doc")
+	}
+	return p.parseScalar()
+}
+
+// parseScalar 读到 , } ] 为止, 括号和引号内的分隔符不算, 如 ObjectId('a,b') /a,b/
+func (p *shellDocParser) parseScalar() (string, error) {
+	start := p.pos
+	depth := 0
+	var quote byte
+	if p.peek() == '/' {
+		quote = '/'
+		p.pos++
+	}
+	for ; p.pos < len(p.s); p.pos++ {
+		c := p.s[p.pos]
+		if quote != 0 {
+			if c == '\\' {
+				p.pos++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',', '}', ']':
+			if depth <= 0 {
+				return strings.TrimSpace(p.s[start:p.pos]), nil
+			}
+		}
+	}
+	return "", errors.Errorf("unterminated value at %d", start)
+}
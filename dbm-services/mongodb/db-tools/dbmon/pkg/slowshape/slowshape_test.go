@@ -0,0 +1,79 @@
+package slowshape
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseSlowOp(t *testing.T) {
+	input := []struct {
+		line string
+		want Shape
+	}{
+		{
+			line: `2024-03-26T10:11:46.240+0800 I COMMAND  [conn652482] command xxxxx.yyyy command: find { find: "node_247", filter: { copyFromCredentialsKey: { $ne: null }, _id: { $gt: ObjectId('000000000000000000000000') } }, sort: { _id: 1 }, limit: 1000, $db: "bkrepo_prod" } planSummary: IXSCAN { _id: 1 } keysExamined:669527 docsExamined:669527 cursorExhausted:1 numYields:5233 nreturned:0 reslen:93 protocol:op_msg 46719ms`,
+			want: Shape{Op: "find", Ns: "xxxxx.yyyy", Filter: "{_id: {$gt: ?}, copyFromCredentialsKey: {$ne: ?}}",
+				Sort: "{_id: 1}", PlanSummary: "IXSCAN { _id: 1 }"},
+		},
+		{
+			line: `2024-05-06T08:10:06.332+0800 I COMMAND  [conn77742] query xxxxx.yyyy query: { base_handbook_id: 100231 } planSummary: IXSCAN { base_handbook_id: 1 } ntoskip:0 nscanned:0 nscannedObjects:0 keyUpdates:0 writeConflicts:0 numYields:0 nreturned:0 reslen:20 584ms`,
+			want: Shape{Op: "find", Ns: "xxxxx.yyyy", Filter: "{base_handbook_id: ?}",
+				PlanSummary: "IXSCAN { base_handbook_id: 1 }"},
+		},
+		{
+			line: `{"t":{"$date":"2024-04-29T18:04:28.239+08:00"},"s":"I",  "c":"COMMAND",  "id":51803,   "ctx":"conn15148443","msg":"Slow query","attr":{"type":"command","ns":"game.tradesellorders","command":{"find":"tradesellorders","filter":{"aid":{"$oid":"5fd8d1a8b4396631a4bbc6b0"},"$or":[{"x":1},{"y":{"$gt":2}},{"x":3}]},"sort":{"ts":-1},"$db":"game"},"planSummary":"COLLSCAN","docsExamined":1000,"nreturned":2,"reslen":249,"durationMillis":105}}`,
+			want: Shape{Op: "find", Ns: "game.tradesellorders", Filter: "{$or: [{x: ?}, {y: {$gt: ?}}], aid: ?}",
+				Sort: "{ts: -1}", PlanSummary: "COLLSCAN"},
+		},
+		{
+			line: `{"t":{"$date":"2024-04-29T18:04:28.239+08:00"},"s":"I",  "c":"COMMAND",  "id":51803,   "ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"game.orders","command":{"aggregate":"orders","pipeline":[{"$match":{"uid":5}},{"$sort":{"ts":1}},{"$limit":10}],"$db":"game"},"planSummary":"IXSCAN { uid: 1 }","durationMillis":300}}`,
+			want: Shape{Op: "aggregate", Ns: "game.orders", Filter: "{uid: ?}", Sort: "{ts: 1}", PlanSummary: "IXSCAN { uid: 1 }"},
+		},
+	}
+
+	agg := NewAggregator()
+	for i, item := range input {
+		op := parseLine([]byte(item.line))
+		if op == nil {
+			t.Errorf("error case %d, parse failed", i)
+			continue
+		}
+		if op.Shape != item.want {
+			t.Errorf("error case %d, want:%+v, got:%+v", i, item.want, op.Shape)
+			continue
+		}
+		agg.Add(op)
+		agg.Add(op)
+		t.Logf("ok case %d, fingerprint:%s shape:%+v", i, op.Fingerprint(), op.Shape)
+	}
+
+	report := agg.Report(2)
+	if report.TotalOps != int64(2*len(input)) || report.ShapeCount != len(input) || len(report.Shapes) != 2 {
+		t.Errorf("bad report total_ops:%d shape_count:%d shapes:%d",
+			report.TotalOps, report.ShapeCount, len(report.Shapes))
+	}
+	if report.Shapes[0].TotalMillis != 2*46719 || report.Shapes[0].P95Millis != 46719 {
+		t.Errorf("bad top shape %+v", report.Shapes[0])
+	}
+	var buf bytes.Buffer
+	report.Print(&buf)
+	t.Logf("report:\n%s", buf.String())
+}
+
+func TestParseShellDoc(t *testing.T) {
+	input := []string{
+		`{ a: 1, b: { $in: [ 1, 2 ] }, c: ObjectId('5fd8,d1a8'), d: /ab,c/, "e.f": "x}y" } rest`,
+		`{}`,
+	}
+	for i, s := range input {
+		doc, _, err := parseShellDoc(s)
+		if err != nil {
+			t.Errorf("error case %d, err:%v", i, err)
+			continue
+		}
+		t.Logf("ok case %d, doc:%v", i, doc)
+	}
+	if _, _, err := parseShellDoc(`{ a: [ 1, 2, 3 .......... 4 ] }`[:15]); err == nil {
+		t.Errorf("error case truncated doc, want err")
+	}
+}
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"dbm-services/mongodb/db-tools/mongo-toolkit-go/pkg/mymongo"
+	"dbm-services/mongodb/db-tools/mongo-toolkit-go/toolkit/logical"
 	"dbm-services/mongodb/db-tools/mongo-toolkit-go/toolkit/pitr"
 	"os"
 
@@ -26,11 +27,17 @@ Recover PITR backup. This command executes mongorestore locally to restore backu
 The recovery process follows these steps:
 1. First imports the full backup
 2. Then imports incremental backups in sequence
+
+With --ns-filter, only matched namespaces are restored from the full backup and
+only matched oplog entries are replayed. With --target-ns-rename, they are restored
+into renamed namespaces.
 */
 
 // Global variables for command line flags
 var src string
 var recoverTimeStr string
+var nsFilterStr string
+var targetNsRenameStr string
 
 // init initializes the command line flags for the recover command
 func init() {
@@ -46,6 +53,10 @@ func init() {
 	recoverCmd.Flags().StringVar(&src, "src", "", "src mongodb instance, ip:port")
 	recoverCmd.Flags().StringVar(&recoverTimeStr, "recover-time", "", "recoverTime yyyy-mm-ddTHH:MM:SS")
 	recoverCmd.Flags().StringVar(&logLevel, "logLevel", "info", "logLevel")
+	recoverCmd.Flags().StringVar(&nsFilterStr, "ns-filter", "",
+		"only recover matched ns, like db1,db2.col*,!*.tmp ('!' means exclude)")
+	recoverCmd.Flags().StringVar(&targetNsRenameStr, "target-ns-rename", "",
+		"recover into renamed ns, like db1=db1_bak,db2.col1=db2_bak.col1")
 	rootCmd.AddCommand(recoverCmd)
 }
 
@@ -62,6 +73,12 @@ func recoverMain() {
 		os.Exit(1)
 	}
 
+	nsOpt, err := parseRecoverNsOption(nsFilterStr, targetNsRenameStr)
+	if err != nil {
+		pitr.ExitFailed("bad ns option, err: %v", err)
+		os.Exit(1)
+	}
+
 	// Initialize connection to target MongoDB
 	dstConn := mymongo.NewMongoHost(host, port, authDb, user, pass, "", "")
 	log.Printf("TODO: check dst connect ok and dst db is empty")
@@ -128,7 +145,7 @@ func recoverMain() {
 	}
 
 	// Execute the recovery process
-	if err = pitr.DoRecover(mongoRestoreBin, dstConn, full, incrList, recoverTime, dir); err == nil {
+	if err = pitr.DoRecover(mongoRestoreBin, dstConn, full, incrList, recoverTime, dir, nsOpt); err == nil {
 		pitr.ExitSuccess("DoRecover Success")
 	} else {
 		pitr.ExitFailed("DoRecover failed, error: %s", err.Error())
@@ -142,6 +159,31 @@ func recoverMain() {
 	}
 }
 
+// parseRecoverNsOption parses --ns-filter and --target-ns-rename.
+// Returns nil if both are empty, which means recover all namespaces.
+func parseRecoverNsOption(nsFilter, nsRename string) (*pitr.RecoverNsOption, error) {
+	if nsFilter == "" && nsRename == "" {
+		return nil, nil
+	}
+	nsOpt := &pitr.RecoverNsOption{}
+	if nsFilter != "" {
+		filter, err := logical.ParseNsFilter(nsFilter)
+		if err != nil {
+			return nil, err
+		}
+		nsOpt.Filter = filter
+	}
+	if nsRename != "" {
+		rename, err := pitr.ParseNsRename(nsRename)
+		if err != nil {
+			return nil, err
+		}
+		nsOpt.Rename = rename
+	}
+	log.Infof("recover ns option, filter: %+v, rename: %+v", nsOpt.Filter, nsOpt.Rename)
+	return nsOpt, nil
+}
+
 // getFiles retrieves all backup files from the specified directory and parses their backup information.
 // It only returns files associated with the specified source instance.
 // Parameters:
@@ -1,6 +1,7 @@
 package logical
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -158,3 +159,95 @@ func (f *NsFilter) FilterTbV2_Cartesian(db string, tbList []string) (matchList,
 
 	return
 }
+
+// IsNsMatched 判断 db.col 是否匹配, 规则同 FilterTbV2
+func (f *NsFilter) IsNsMatched(db, col string) bool {
+	return f.IsDbMatched(db) && f.isTbMatched(db, col)
+}
+
+// MongoRestoreNsArgs 转换为 mongorestore 的 --nsInclude/--nsExclude 模式
+// 白名单为库表笛卡尔积, 黑名单库排除整个库, 黑名单表在所有库中排除
+func (f *NsFilter) MongoRestoreNsArgs() (nsInclude, nsExclude []string) {
+	whiteDbs := nonEmptyOrAll(f.WhiteDbList)
+	whiteTbs := nonEmptyOrAll(f.WhiteTbList)
+	for _, db := range whiteDbs {
+		for _, tb := range whiteTbs {
+			nsInclude = append(nsInclude, db+"."+tb)
+		}
+	}
+	for _, db := range f.BlackDbList {
+		if db != "" {
+			nsExclude = append(nsExclude, db+".*")
+		}
+	}
+	for _, tb := range f.BlackTbList {
+		if tb != "" {
+			nsExclude = append(nsExclude, "*."+tb)
+		}
+	}
+	return
+}
+
+// nonEmptyOrAll 去掉空值, 为空时返回 ["*"]
+func nonEmptyOrAll(list []string) []string {
+	var ret []string
+	for _, item := range list {
+		if item == "" {
+			// 白名单中有空值表示匹配全部
+			return []string{"*"}
+		}
+		ret = append(ret, item)
+	}
+	if len(ret) == 0 {
+		return []string{"*"}
+	}
+	return ret
+}
+
+// ParseNsFilter 解析 --ns-filter 参数, 逗号分隔, 每项为 db 或 db.col, 支持 * 通配, ! 开头表示排除
+// 例: "db1,db2.col*,!db2.tmp*"
+// 包含项的库和表取笛卡尔积, 所以不能同时包含整库和指定表; 排除表时库名只能为 *, 即在所有库中排除该表
+func ParseNsFilter(s string) (*NsFilter, error) {
+	f := NewNsFilter(nil, nil, nil, nil)
+	var wholeDb bool
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		exclude := strings.HasPrefix(item, "!")
+		item = strings.TrimPrefix(item, "!")
+		db, col, hasCol := strings.Cut(item, ".")
+		if db == "" || (hasCol && col == "") {
+			return nil, fmt.Errorf("bad ns filter item %q", item)
+		}
+		switch {
+		case !exclude:
+			f.WhiteDbList = appendUniq(f.WhiteDbList, db)
+			if hasCol && col != "*" {
+				f.WhiteTbList = appendUniq(f.WhiteTbList, col)
+			} else {
+				wholeDb = true
+			}
+		case !hasCol:
+			f.BlackDbList = appendUniq(f.BlackDbList, db)
+		case db == "*":
+			f.BlackTbList = appendUniq(f.BlackTbList, col)
+		default:
+			return nil, fmt.Errorf("bad ns filter item !%s, exclude collection must be like !*.col", item)
+		}
+	}
+	if wholeDb && len(f.WhiteTbList) > 0 {
+		return nil, fmt.Errorf("bad ns filter %q, cannot include whole db and db.col at the same time", s)
+	}
+	return f, nil
+}
+
+func appendUniq(list []string, item string) []string {
+	for _, v := range list {
+		if v == item {
+			return list
+		}
+	}
+	return append(list, item)
+}
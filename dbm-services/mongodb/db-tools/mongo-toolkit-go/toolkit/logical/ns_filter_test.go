@@ -2,6 +2,7 @@ package logical
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -719,3 +720,61 @@ func TestNewNsFilterInV2_Cartesian(t *testing.T) {
 		}
 	}
 }
+
+func TestParseNsFilter(t *testing.T) {
+	input := []struct {
+		filter    string
+		wantErr   bool
+		nsInclude []string
+		nsExclude []string
+		matched   []string
+		notMatch  []string
+	}{
+		{
+			filter:    "db1,db2,!*.tmp*",
+			nsInclude: []string{"db1.*", "db2.*"},
+			nsExclude: []string{"*.tmp*"},
+			matched:   []string{"db1.col1", "db2.col2"},
+			notMatch:  []string{"db1.tmp_1", "db3.col1"},
+		},
+		{
+			filter:    "db1.col1,!db1_bak",
+			nsInclude: []string{"db1.col1"},
+			nsExclude: []string{"db1_bak.*"},
+			matched:   []string{"db1.col1"},
+			notMatch:  []string{"db1.col2", "db2.col1"},
+		},
+		{filter: "db1,db2.col1", wantErr: true},
+		{filter: "!db1.col1", wantErr: true},
+		{filter: "db1.", wantErr: true},
+	}
+
+	for i, item := range input {
+		filter, err := ParseNsFilter(item.filter)
+		if item.wantErr {
+			if err == nil {
+				t.Errorf("error case %d, want err, got filter:%+v", i, filter)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("error case %d, err:%v", i, err)
+		}
+		nsInclude, nsExclude := filter.MongoRestoreNsArgs()
+		if !reflect.DeepEqual(nsInclude, item.nsInclude) || !reflect.DeepEqual(nsExclude, item.nsExclude) {
+			t.Errorf("error case %d, want:%v %v, got:%v %v", i, item.nsInclude, item.nsExclude, nsInclude, nsExclude)
+		}
+		for _, ns := range item.matched {
+			db, col, _ := strings.Cut(ns, ".")
+			if !filter.IsNsMatched(db, col) {
+				t.Errorf("error case %d, %s should match", i, ns)
+			}
+		}
+		for _, ns := range item.notMatch {
+			db, col, _ := strings.Cut(ns, ".")
+			if filter.IsNsMatched(db, col) {
+				t.Errorf("error case %d, %s should not match", i, ns)
+			}
+		}
+	}
+}
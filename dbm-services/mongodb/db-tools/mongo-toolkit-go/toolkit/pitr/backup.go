@@ -229,14 +229,26 @@ func DoBackup(connInfo *mymongo.MongoHost, backupType, dir string, zip bool,
 		zip = true // 使用zstd压缩
 	}
 
+	var result *BackupFileName
 	switch backupType {
 	case BackupTypeFull:
-		return DoBackupFull(connInfo, backupType, dir, zip, archive, lastBackup, numParallelCollections)
+		result, err = DoBackupFull(connInfo, backupType, dir, zip, archive, lastBackup, numParallelCollections)
 	case BackupTypeIncr:
-		return DoBackupIncr(connInfo, backupType, dir, zip, archive, lastBackup, maxTs)
+		result, err = DoBackupIncr(connInfo, backupType, dir, zip, archive, lastBackup, maxTs)
 	default:
 		return nil, errors.Errorf("bad backupType: %s", backupType)
 	}
+	if err != nil || result == nil {
+		return result, err
+	}
+
+	// 写入校验清单，供 verify 命令使用. 写入失败不影响备份结果
+	if m, mErr := WriteManifest(result); mErr != nil {
+		log.Warnf("WriteManifest %s failed, err: %v", result.GetFullPath(), mErr)
+	} else {
+		log.Infof("WriteManifest %s succ, sha256: %s", GetManifestFile(result.GetFullPath()), m.Sha256)
+	}
+	return result, nil
 }
 
 // DoBackupFull 执行全量备份
@@ -0,0 +1,87 @@
+package pitr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ManifestSuffix 校验清单文件的后缀. 备份文件 xxx.tar.gz 对应的清单文件为 xxx.tar.gz.manifest.json
+const ManifestSuffix = ".manifest.json"
+
+// BackupManifest 备份文件的校验清单, 在备份完成时写入, 用于 verify 时检查文件是否完整
+type BackupManifest struct {
+	FileName   string    `json:"file_name"`
+	FileSize   int64     `json:"file_size"`
+	Sha256     string    `json:"sha256"`
+	Type       string    `json:"type"`
+	FirstTs    TS        `json:"first_ts"`
+	LastTs     TS        `json:"last_ts"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+// GetManifestFile 返回备份文件对应的清单文件路径
+func GetManifestFile(backupFilePath string) string {
+	return backupFilePath + ManifestSuffix
+}
+
+// FileSha256 计算文件的sha256和大小
+func FileSha256(filePath string) (string, int64, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// WriteManifest 计算备份文件的sha256，并写入清单文件
+func WriteManifest(f *BackupFileName) (*BackupManifest, error) {
+	filePath := f.GetFullPath()
+	sum, size, err := FileSha256(filePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "sha256")
+	}
+	m := &BackupManifest{
+		FileName:   f.FileName,
+		FileSize:   size,
+		Sha256:     sum,
+		Type:       f.Type,
+		FirstTs:    f.FirstTs,
+		LastTs:     f.LastTs,
+		CreateTime: time.Now(),
+	}
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	manifestFile := GetManifestFile(filePath)
+	tmpFile := manifestFile + ".tmp"
+	if err = os.WriteFile(tmpFile, content, 0644); err != nil {
+		return nil, errors.Wrap(err, "write manifest")
+	}
+	return m, os.Rename(tmpFile, manifestFile)
+}
+
+// LoadManifest 读取清单文件
+func LoadManifest(manifestFile string) (*BackupManifest, error) {
+	content, err := os.ReadFile(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	m := &BackupManifest{}
+	if err = json.Unmarshal(content, m); err != nil {
+		return nil, errors.Wrap(err, "unmarshal manifest")
+	}
+	return m, nil
+}
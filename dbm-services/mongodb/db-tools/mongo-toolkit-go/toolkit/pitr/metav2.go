@@ -91,6 +91,7 @@ func (b *BackupMetaV2) RemoveOldFileFirst(maxDiskUsage int, minDiskUsage int) {
 			deleted += 1
 			doRemoveFile(fullPath)
 			doRemoveInfoFile(&r, taskInfo)
+			_ = os.Remove(GetManifestFile(fullPath))
 		}
 	}
 	log.Infof("RemoveOldFileFirst done. total: %d, deleted: %d", total, deleted)
@@ -0,0 +1,129 @@
+package pitr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NsFilter 库表过滤, 由 logical.NsFilter 实现
+type NsFilter interface {
+	IsNsMatched(db, col string) bool
+	MongoRestoreNsArgs() (nsInclude, nsExclude []string)
+}
+
+// nsRenameRule 库表改名规则, FromCol 为空时表示整库改名
+type nsRenameRule struct {
+	FromDb  string
+	FromCol string
+	ToDb    string
+	ToCol   string
+}
+
+// NsRename 恢复到新的库表名, 避免覆盖线上数据
+type NsRename struct {
+	rules []nsRenameRule
+}
+
+// ParseNsRename 解析 --target-ns-rename 参数
+// 逗号分隔, 每项为 db=newDb 或 db.col=newDb.newCol
+func ParseNsRename(s string) (*NsRename, error) {
+	r := &NsRename{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(item, "=")
+		if !ok || strings.Contains(item, "*") {
+			return nil, fmt.Errorf("bad ns rename item %q, require db=newDb or db.col=newDb.newCol", item)
+		}
+		fromDb, fromCol, fromHasCol := strings.Cut(from, ".")
+		toDb, toCol, toHasCol := strings.Cut(to, ".")
+		if fromDb == "" || toDb == "" || fromHasCol != toHasCol || (fromHasCol && (fromCol == "" || toCol == "")) {
+			return nil, fmt.Errorf("bad ns rename item %q, require db=newDb or db.col=newDb.newCol", item)
+		}
+		rule := nsRenameRule{FromDb: fromDb, FromCol: fromCol, ToDb: toDb, ToCol: toCol}
+		// 指定表的规则优先
+		if fromHasCol {
+			r.rules = append([]nsRenameRule{rule}, r.rules...)
+		} else {
+			r.rules = append(r.rules, rule)
+		}
+	}
+	if len(r.rules) == 0 {
+		return nil, fmt.Errorf("empty ns rename %q", s)
+	}
+	return r, nil
+}
+
+// Rename 返回改名后的库表名, 没有匹配的规则时原样返回
+func (r *NsRename) Rename(db, col string) (string, string) {
+	if r == nil {
+		return db, col
+	}
+	for _, rule := range r.rules {
+		if rule.FromDb != db {
+			continue
+		}
+		if rule.FromCol == "" {
+			return rule.ToDb, col
+		}
+		if rule.FromCol == col {
+			return rule.ToDb, rule.ToCol
+		}
+	}
+	return db, col
+}
+
+// MongoRestoreNsArgs 转换为 mongorestore 的 --nsFrom/--nsTo 参数
+func (r *NsRename) MongoRestoreNsArgs() []interface{} {
+	var args []interface{}
+	if r == nil {
+		return args
+	}
+	for _, rule := range r.rules {
+		if rule.FromCol == "" {
+			args = append(args, "--nsFrom", rule.FromDb+".*", "--nsTo", rule.ToDb+".*")
+		} else {
+			args = append(args, "--nsFrom", rule.FromDb+"."+rule.FromCol, "--nsTo", rule.ToDb+"."+rule.ToCol)
+		}
+	}
+	return args
+}
+
+// RecoverNsOption 按库表恢复的参数, 为nil时恢复全部
+type RecoverNsOption struct {
+	Filter NsFilter
+	Rename *NsRename
+}
+
+// Enabled 是否需要按库表过滤或改名
+func (o *RecoverNsOption) Enabled() bool {
+	return o != nil && (o.Filter != nil || o.Rename != nil)
+}
+
+// IsNsMatched 未指定过滤条件时全部匹配
+func (o *RecoverNsOption) IsNsMatched(db, col string) bool {
+	if o.Filter == nil {
+		return true
+	}
+	return o.Filter.IsNsMatched(db, col)
+}
+
+// MongoRestoreArgs 全备导入时传给 mongorestore 的库表参数
+func (o *RecoverNsOption) MongoRestoreArgs() []interface{} {
+	var args []interface{}
+	if !o.Enabled() {
+		return args
+	}
+	if o.Filter != nil {
+		nsInclude, nsExclude := o.Filter.MongoRestoreNsArgs()
+		for _, ns := range nsInclude {
+			args = append(args, "--nsInclude", ns)
+		}
+		for _, ns := range nsExclude {
+			args = append(args, "--nsExclude", ns)
+		}
+	}
+	return append(args, o.Rename.MongoRestoreNsArgs()...)
+}
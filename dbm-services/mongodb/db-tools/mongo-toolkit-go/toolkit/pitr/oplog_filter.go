@@ -0,0 +1,265 @@
+package pitr
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// OplogFilterStat oplog 过滤统计
+type OplogFilterStat struct {
+	Total   int64
+	Kept    int64
+	Skipped int64
+	// Unsafe 无法按库表拆分而丢弃的命令(dropDatabase, 跨过滤范围的renameCollection等)
+	Unsafe int64
+}
+
+// 第一个字段值为集合名的命令
+var oplogCollectionCmds = map[string]bool{
+	"create":           true,
+	"drop":             true,
+	"createIndexes":    true,
+	"dropIndexes":      true,
+	"deleteIndexes":    true,
+	"collMod":          true,
+	"emptycapped":      true,
+	"convertToCapped":  true,
+	"startIndexBuild":  true,
+	"commitIndexBuild": true,
+	"abortIndexBuild":  true,
+}
+
+// FilterOplogFile 按库表过滤 oplog.bson, 并按改名规则改写 ns. 源文件可以是gzip压缩的, 目标文件不压缩
+func FilterOplogFile(srcFile, dstFile string, nsOpt *RecoverNsOption) (stat OplogFilterStat, err error) {
+	src, err := os.Open(srcFile)
+	if err != nil {
+		return stat, errors.Wrap(err, "open oplog file")
+	}
+	defer src.Close()
+	var reader io.Reader = bufio.NewReaderSize(src, 4*1024*1024)
+	// mongodump --gzip 产生的oplog.bson, 文件名没有.gz后缀
+	if magic, _ := reader.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return stat, errors.Wrap(err, "gzip.NewReader")
+		}
+		defer gzReader.Close()
+		reader = gzReader
+	}
+
+	dst, err := os.OpenFile(dstFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return stat, errors.Wrap(err, "open dst oplog file")
+	}
+	defer dst.Close()
+	writer := bufio.NewWriterSize(dst, 4*1024*1024)
+
+	for {
+		raw, err := readBsonDoc(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stat, errors.Wrapf(err, "read oplog %s at doc %d", srcFile, stat.Total)
+		}
+		stat.Total++
+		var op bson.D
+		if err = bson.Unmarshal(raw, &op); err != nil {
+			return stat, errors.Wrapf(err, "unmarshal oplog %s at doc %d", srcFile, stat.Total)
+		}
+		newOp, keep, unsafe := nsOpt.rewriteOplog(op)
+		if unsafe {
+			stat.Unsafe++
+			log.Warnf("skip oplog can not be filtered by ns: %v", op)
+		}
+		if !keep {
+			stat.Skipped++
+			continue
+		}
+		out, err := bson.Marshal(newOp)
+		if err != nil {
+			return stat, errors.Wrap(err, "marshal oplog")
+		}
+		if _, err = writer.Write(out); err != nil {
+			return stat, errors.Wrap(err, "write oplog")
+		}
+		stat.Kept++
+	}
+	if err = writer.Flush(); err != nil {
+		return stat, errors.Wrap(err, "flush oplog")
+	}
+	return stat, nil
+}
+
+// readBsonDoc 读取一个bson文档, 前4字节为文档长度(小端,包含自身)
+func readBsonDoc(reader io.Reader) ([]byte, error) {
+	var sizeBuf [4]byte
+	if _, err := io.ReadFull(reader, sizeBuf[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("truncated bson size")
+		}
+		return nil, err
+	}
+	size := int(binary.LittleEndian.Uint32(sizeBuf[:]))
+	if size < 5 || size > 64*1024*1024 {
+		return nil, fmt.Errorf("bad bson size %d", size)
+	}
+	doc := make([]byte, size)
+	copy(doc, sizeBuf[:])
+	if _, err := io.ReadFull(reader, doc[4:]); err != nil {
+		return nil, fmt.Errorf("truncated bson doc, size %d", size)
+	}
+	return doc, nil
+}
+
+func getField(d bson.D, key string) (interface{}, int) {
+	for i, e := range d {
+		if e.Key == key {
+			return e.Value, i
+		}
+	}
+	return nil, -1
+}
+
+func splitNs(ns string) (db, col string) {
+	db, col, _ = strings.Cut(ns, ".")
+	return
+}
+
+// rewriteOplog 返回改写后的oplog, keep=false 表示丢弃. unsafe=true 表示该命令涉及过滤范围但无法拆分
+func (o *RecoverNsOption) rewriteOplog(op bson.D) (newOp bson.D, keep bool, unsafe bool) {
+	opType, _ := getField(op, "op")
+	nsVal, nsIdx := getField(op, "ns")
+	ns, _ := nsVal.(string)
+	switch opType {
+	case "n":
+		return nil, false, false
+	case "i", "u", "d":
+		db, col := splitNs(ns)
+		if col == "system.indexes" {
+			// 3.x 版本建索引写入 db.system.indexes, 目标集合在 o.ns
+			return o.rewriteLegacyIndexOp(op)
+		}
+		if !o.IsNsMatched(db, col) {
+			return nil, false, false
+		}
+		db, col = o.Rename.Rename(db, col)
+		op[nsIdx].Value = db + "." + col
+		return op, true, false
+	case "c":
+		return o.rewriteCmdOplog(op, ns, nsIdx)
+	}
+	return nil, false, true
+}
+
+func (o *RecoverNsOption) rewriteLegacyIndexOp(op bson.D) (bson.D, bool, bool) {
+	objVal, _ := getField(op, "o")
+	obj, ok := objVal.(bson.D)
+	if !ok {
+		return nil, false, true
+	}
+	targetVal, targetIdx := getField(obj, "ns")
+	target, _ := targetVal.(string)
+	db, col := splitNs(target)
+	if targetIdx < 0 || !o.IsNsMatched(db, col) {
+		return nil, false, false
+	}
+	newDb, newCol := o.Rename.Rename(db, col)
+	obj[targetIdx].Value = newDb + "." + newCol
+	_, nsIdx := getField(op, "ns")
+	op[nsIdx].Value = newDb + ".system.indexes"
+	return op, true, false
+}
+
+func (o *RecoverNsOption) rewriteCmdOplog(op bson.D, ns string, nsIdx int) (bson.D, bool, bool) {
+	objVal, objIdx := getField(op, "o")
+	obj, ok := objVal.(bson.D)
+	if !ok || len(obj) == 0 {
+		return nil, false, true
+	}
+	db, _ := splitNs(ns)
+	cmd := obj[0].Key
+	switch {
+	case cmd == "applyOps":
+		// 事务或批量操作, 逐条过滤
+		subOps, ok := obj[0].Value.(bson.A)
+		if !ok {
+			return nil, false, true
+		}
+		var kept bson.A
+		var unsafe bool
+		for _, sub := range subOps {
+			subOp, ok := sub.(bson.D)
+			if !ok {
+				unsafe = true
+				continue
+			}
+			newSub, keep, subUnsafe := o.rewriteOplog(subOp)
+			unsafe = unsafe || subUnsafe
+			if keep {
+				kept = append(kept, newSub)
+			}
+		}
+		if len(kept) == 0 {
+			return nil, false, unsafe
+		}
+		obj[0].Value = kept
+		op[objIdx].Value = obj
+		return op, true, unsafe
+	case oplogCollectionCmds[cmd]:
+		col, _ := obj[0].Value.(string)
+		if !o.IsNsMatched(db, col) {
+			return nil, false, false
+		}
+		newDb, newCol := o.Rename.Rename(db, col)
+		obj[0].Value = newCol
+		// create 命令的 idIndex 中带有 ns
+		if idIndexVal, idx := getField(obj, "idIndex"); idx >= 0 {
+			if idIndex, ok := idIndexVal.(bson.D); ok {
+				if _, i := getField(idIndex, "ns"); i >= 0 {
+					idIndex[i].Value = newDb + "." + newCol
+				}
+			}
+		}
+		op[nsIdx].Value = newDb + ".$cmd"
+		op[objIdx].Value = obj
+		return op, true, false
+	case cmd == "renameCollection":
+		from, _ := obj[0].Value.(string)
+		toVal, toIdx := getField(obj, "to")
+		to, _ := toVal.(string)
+		fromDb, fromCol := splitNs(from)
+		toDb, toCol := splitNs(to)
+		fromMatched := o.IsNsMatched(fromDb, fromCol)
+		toMatched := o.IsNsMatched(toDb, toCol)
+		if !fromMatched && !toMatched {
+			return nil, false, false
+		}
+		if fromMatched != toMatched || toIdx < 0 {
+			return nil, false, true
+		}
+		fromDb, fromCol = o.Rename.Rename(fromDb, fromCol)
+		toDb, toCol = o.Rename.Rename(toDb, toCol)
+		obj[0].Value = fromDb + "." + fromCol
+		obj[toIdx].Value = toDb + "." + toCol
+		op[objIdx].Value = obj
+		return op, true, false
+	case cmd == "dropDatabase":
+		// 部分恢复时整库删除会误删未选中的表, 不重放
+		return nil, false, true
+	}
+	// commitTransaction 等命令不涉及具体库表, 其余未知命令丢弃
+	if cmd == "commitTransaction" || cmd == "abortTransaction" {
+		return op, true, false
+	}
+	return nil, false, true
+}
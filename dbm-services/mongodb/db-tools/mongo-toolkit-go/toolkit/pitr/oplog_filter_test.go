@@ -0,0 +1,97 @@
+package pitr
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testNsFilter 只匹配 db1.col1 和 db1.col2
+type testNsFilter struct{}
+
+func (f testNsFilter) IsNsMatched(db, col string) bool {
+	return db == "db1" && (col == "col1" || col == "col2")
+}
+
+func (f testNsFilter) MongoRestoreNsArgs() (nsInclude, nsExclude []string) {
+	return []string{"db1.col1", "db1.col2"}, nil
+}
+
+func newOplog(ts primitive.Timestamp, op, ns string, o interface{}) bson.D {
+	return bson.D{{Key: "ts", Value: ts}, {Key: "op", Value: op}, {Key: "ns", Value: ns}, {Key: "o", Value: o}}
+}
+
+func TestFilterOplogFile(t *testing.T) {
+	rename, err := ParseNsRename("db1=db1_bak,db1.col2=db2_bak.col2")
+	if err != nil {
+		t.Fatalf("ParseNsRename err:%v", err)
+	}
+	nsOpt := &RecoverNsOption{Filter: testNsFilter{}, Rename: rename}
+	ts := primitive.Timestamp{T: 1700000000, I: 1}
+	ops := []bson.D{
+		newOplog(ts, "i", "db1.col1", bson.M{"_id": 1}),
+		newOplog(ts, "i", "db1.col3", bson.M{"_id": 2}),
+		newOplog(ts, "n", "", bson.M{"msg": "periodic noop"}),
+		newOplog(ts, "c", "db1.$cmd", bson.M{"drop": "col2"}),
+		newOplog(ts, "c", "db1.$cmd", bson.M{"dropDatabase": 1}),
+		newOplog(ts, "c", "admin.$cmd", bson.M{"applyOps": bson.A{
+			newOplog(ts, "u", "db1.col1", bson.M{"$set": bson.M{"a": 1}}),
+			newOplog(ts, "d", "db9.col1", bson.M{"_id": 1}),
+		}}),
+	}
+	want := []bson.D{
+		newOplog(ts, "i", "db1_bak.col1", bson.D{{Key: "_id", Value: int32(1)}}),
+		newOplog(ts, "c", "db2_bak.$cmd", bson.D{{Key: "drop", Value: "col2"}}),
+		newOplog(ts, "c", "admin.$cmd", bson.D{{Key: "applyOps", Value: bson.A{
+			newOplog(ts, "u", "db1_bak.col1", bson.D{{Key: "$set", Value: bson.D{{Key: "a", Value: int32(1)}}}}),
+		}}}),
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "oplog.bson")
+	var data []byte
+	for _, op := range ops {
+		raw, err := bson.Marshal(op)
+		if err != nil {
+			t.Fatalf("bson.Marshal err:%v", err)
+		}
+		data = append(data, raw...)
+	}
+	if err = os.WriteFile(src, data, 0644); err != nil {
+		t.Fatalf("WriteFile err:%v", err)
+	}
+
+	dst := filepath.Join(dir, "oplog.bson.filtered")
+	stat, err := FilterOplogFile(src, dst, nsOpt)
+	if err != nil {
+		t.Fatalf("FilterOplogFile err:%v", err)
+	}
+	if stat.Total != 6 || stat.Kept != 3 || stat.Unsafe != 1 {
+		t.Errorf("FilterOplogFile stat:%+v", stat)
+	}
+
+	f, err := os.Open(dst)
+	if err != nil {
+		t.Fatalf("Open err:%v", err)
+	}
+	defer f.Close()
+	var got []bson.D
+	for {
+		raw, err := readBsonDoc(f)
+		if err != nil {
+			break
+		}
+		var op bson.D
+		if err = bson.Unmarshal(raw, &op); err != nil {
+			t.Fatalf("bson.Unmarshal err:%v", err)
+		}
+		got = append(got, op)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterOplogFile want:%v, got:%v", want, got)
+	}
+}
@@ -160,6 +160,14 @@ func UntarFull(backupFileDir string, file *BackupFileName) (
 // DoMongoRestoreFULL 导入全量备份
 func DoMongoRestoreFULL(bin string, conn *mymongo.MongoHost, file *BackupFileName,
 	backupFileDir string, logChan chan *ProcessLog) (string, error) {
+	return DoMongoRestoreFULLWithNs(bin, conn, file, backupFileDir, nil, logChan)
+}
+
+// DoMongoRestoreFULLWithNs 导入全量备份, nsOpt 不为空时只导入匹配的库表.
+// 此时不使用 mongorestore --oplogReplay, 非archive备份由本工具过滤 dump/oplog.bson 后再重放,
+// archive备份要求第一个INCR覆盖全备开始时间(由 DoRecover 检查)
+func DoMongoRestoreFULLWithNs(bin string, conn *mymongo.MongoHost, file *BackupFileName,
+	backupFileDir string, nsOpt *RecoverNsOption, logChan chan *ProcessLog) (string, error) {
 	// fmt.Printf("DoMongoRestore: %s %s to %s:%s\n", file.Type, file.FileName, conn.Host, conn.Port)
 
 	SendProcessLog(logChan, fmt.Sprintf("start to untar %s ", file.FileName))
@@ -196,7 +204,12 @@ func DoMongoRestoreFULL(bin string, conn *mymongo.MongoHost, file *BackupFileNam
 	restoreLogfile := path.Join(fullTmpDir, "restore.log")
 
 	restoreCmd := mycmd.New(bin, "--host", conn.Host, "--port", conn.Port,
-		"--authenticationDatabase", conn.AuthDb, "--oplogReplay")
+		"--authenticationDatabase", conn.AuthDb)
+	if nsOpt.Enabled() {
+		restoreCmd.Append(nsOpt.MongoRestoreArgs()...)
+	} else {
+		restoreCmd.Append("--oplogReplay")
+	}
 	if len(conn.User) > 0 {
 		restoreCmd.Append("-u", conn.User)
 	}
@@ -270,9 +283,41 @@ func DoMongoRestoreFULL(bin string, conn *mymongo.MongoHost, file *BackupFileNam
 		return "", errors.Wrap(errorsList[0], "DoMongoRestoreFULL")
 	}
 
+	if nsOpt.Enabled() && !archive {
+		if err = replayFullOplogWithNs(bin, conn, fullTmpDir, dumpDir, nsOpt, logChan); err != nil {
+			SendErrorProcessLog(logChan, fmt.Sprintf("replayFullOplogWithNs return %s", err.Error()))
+			return "", errors.Wrap(err, "replayFullOplogWithNs")
+		}
+	}
+
 	return dumpDir, nil
 }
 
+// replayFullOplogWithNs 过滤全备中的 oplog.bson 并重放
+func replayFullOplogWithNs(bin string, conn *mymongo.MongoHost, fullTmpDir, dumpDir string,
+	nsOpt *RecoverNsOption, logChan chan *ProcessLog) error {
+	srcOplog := path.Join(dumpDir, "oplog.bson")
+	if _, err := os.Stat(srcOplog); err != nil {
+		// mongodump 未使用 --oplog
+		SendProcessLog(logChan, fmt.Sprintf("no oplog.bson in %s, skip oplog replay", dumpDir))
+		return nil
+	}
+	oplogDir := path.Join(fullTmpDir, "oplog")
+	if err := os.MkdirAll(oplogDir, os.FileMode(0755)); err != nil {
+		return fmt.Errorf("mkdir %s err:%v", oplogDir, err)
+	}
+	dstOplog := path.Join(oplogDir, "oplog.bson")
+	stat, err := FilterOplogFile(srcOplog, dstOplog, nsOpt)
+	if err != nil {
+		return err
+	}
+	SendProcessLog(logChan, fmt.Sprintf("filter %s: %+v", srcOplog, stat))
+	if stat.Kept == 0 {
+		return nil
+	}
+	return DoReplayOplog(bin, conn, dstOplog, oplogDir, 0, false, false, logChan)
+}
+
 // DoReplayOplog oplog dir/oplog.bson
 func DoReplayOplog(bin string, conn *mymongo.MongoHost, backupFilePath string, tmpDirPath string, recoverTime uint32,
 	gzip bool, archive bool, logChan chan *ProcessLog) error {
@@ -361,6 +406,12 @@ func DoReplayOplog(bin string, conn *mymongo.MongoHost, backupFilePath string, t
 // DoMongoRestoreINCR 导入INCR. zstd 场景下，需要先解压，再导入.
 func DoMongoRestoreINCR(bin string, conn *mymongo.MongoHost, full *BackupFileName, incrList []*BackupFileName,
 	recoverTime uint32, backupFileDir string, idx int, logChan chan *ProcessLog) error {
+	return DoMongoRestoreINCRWithNs(bin, conn, full, incrList, recoverTime, backupFileDir, idx, nil, logChan)
+}
+
+// DoMongoRestoreINCRWithNs 导入INCR, nsOpt 不为空时只重放匹配库表的oplog
+func DoMongoRestoreINCRWithNs(bin string, conn *mymongo.MongoHost, full *BackupFileName, incrList []*BackupFileName,
+	recoverTime uint32, backupFileDir string, idx int, nsOpt *RecoverNsOption, logChan chan *ProcessLog) error {
 	file := incrList[idx]
 	// fmt.Printf("DoMongoRestoreINCR: %s [%d] %s to %s:%s\n", file.Type, idx, file.FileName, conn.Host, conn.Port)
 
@@ -431,6 +482,18 @@ func DoMongoRestoreINCR(bin string, conn *mymongo.MongoHost, full *BackupFileNam
 			//gzip is false after bsonfilter ...
 			gzip = false
 		}
+		if nsOpt.Enabled() {
+			oplogPath := path.Join(incrTmpDir, oplogNewName)
+			stat, err := FilterOplogFile(oplogPath, oplogPath+".filtered", nsOpt)
+			if err != nil {
+				return errors.Wrap(err, "FilterOplogFile")
+			}
+			if err = os.Rename(oplogPath+".filtered", oplogPath); err != nil {
+				return errors.Wrap(err, "rename filtered oplog")
+			}
+			SendProcessLog(logChan, fmt.Sprintf("filter %s: %+v", file.FileName, stat))
+			gzip = false
+		}
 		return DoReplayOplog(bin, conn, path.Join(incrTmpDir, oplogNewName),
 			incrTmpDir, recoverTime, gzip, archive, logChan)
 	}
@@ -473,19 +536,23 @@ func receiveLogBg() (*sync.WaitGroup, chan *ProcessLog) {
 	return wg, logChan
 }
 
-// DoRecover 从全量和增量文件中恢复到指定时间点.
+// DoRecover 从全量和增量文件中恢复到指定时间点. nsOpt 不为空时只恢复匹配的库表, 可改名恢复到新库表.
 func DoRecover(mongorestoreBin string, conn *mymongo.MongoHost, full *BackupFileName, incrList []*BackupFileName,
-	recoverTime uint32, backupFileDir string) error {
+	recoverTime uint32, backupFileDir string, nsOpt *RecoverNsOption) error {
 	wd, _ := os.Getwd()
 	log.Printf("WorkDir %s", wd)
 	Output("WorkDir is %s", wd)
 
+	if err := checkNsRecoverFiles(full, incrList, nsOpt); err != nil {
+		return err
+	}
+
 	// 处理日志 日志会通过logChan发送到主进程
 	wg, logChan := receiveLogBg()
 
 	var err error
 	// 导入日志时间可能比较长.
-	_, err = DoMongoRestoreFULL(mongorestoreBin, conn, full, backupFileDir, logChan)
+	_, err = DoMongoRestoreFULLWithNs(mongorestoreBin, conn, full, backupFileDir, nsOpt, logChan)
 
 	if err != nil {
 		SendErrorProcessLog(logChan, fmt.Sprintf("DoMongoRestoreFULL return %s", err.Error()))
@@ -495,8 +562,8 @@ func DoRecover(mongorestoreBin string, conn *mymongo.MongoHost, full *BackupFile
 	for idx := range incrList {
 		os.Chdir(wd)
 		SendProcessLog(logChan, fmt.Sprintf("DoMongoRestoreINCR %s start", incrList[idx].FileName))
-		if err = DoMongoRestoreINCR(mongorestoreBin, conn, full, incrList,
-			recoverTime, backupFileDir, idx, logChan); err != nil {
+		if err = DoMongoRestoreINCRWithNs(mongorestoreBin, conn, full, incrList,
+			recoverTime, backupFileDir, idx, nsOpt, logChan); err != nil {
 			SendErrorProcessLog(logChan, fmt.Sprintf("DoMongoRestoreINCR %s return %s",
 				incrList[idx].FileName, err.Error()))
 			goto End
@@ -512,6 +579,22 @@ End:
 	return err
 }
 
+// checkNsRecoverFiles archive 全备无法拆出其中的oplog, 按库表恢复时由第一个INCR覆盖全备期间的oplog
+func checkNsRecoverFiles(full *BackupFileName, incrList []*BackupFileName, nsOpt *RecoverNsOption) error {
+	if !nsOpt.Enabled() || !strings.Contains(full.FileName, ".archive") {
+		return nil
+	}
+	if len(incrList) == 0 || incrList[0].Version != BackupFileVersionV1 {
+		return fmt.Errorf("ns filtered recover from archive full backup %s requires INCR backups", full.FileName)
+	}
+	first := incrList[0].FirstTs
+	if first.Sec > full.FirstTs.Sec || (first.Sec == full.FirstTs.Sec && first.I > full.FirstTs.I) {
+		return fmt.Errorf("ns filtered recover: first INCR %s FirstTs %+v is after full backup FirstTs %+v",
+			incrList[0].FileName, first, full.FirstTs)
+	}
+	return nil
+}
+
 // FindNeedFiles 找到需要的全量和增量文件
 func FindNeedFiles(fileObjList []*BackupFileName, recoverTime uint32) (*BackupFileName, []*BackupFileName, error) {
 	var fullList []*BackupFileName
@@ -0,0 +1,395 @@
+package pitr
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TS 的来源. record 表示来自文件名或meta记录, 文件名中的全备时间只精确到秒
+const (
+	TsSourceDumpLog  = "dump.log"
+	TsSourceManifest = "manifest"
+	TsSourceRecord   = "record"
+)
+
+// archiveMagic mongodump --archive 文件头
+const archiveMagic = 0x8199e26d
+
+// maxBsonDocSize 16MB + 16KB, 单个oplog文档不会超过这个大小
+const maxBsonDocSize = 16*1024*1024 + 16*1024
+
+// FileVerifyResult 单个备份文件的校验结果
+type FileVerifyResult struct {
+	File       *BackupFileName `json:"file"`
+	TsSource   string          `json:"ts_source"`
+	ManifestOk bool            `json:"manifest_ok"`
+	Readable   bool            `json:"readable"`
+	Warnings   []string        `json:"warnings"`
+	Errors     []string        `json:"errors"`
+}
+
+// Ok 文件是否可用于恢复
+func (r *FileVerifyResult) Ok() bool {
+	return len(r.Errors) == 0
+}
+
+func (r *FileVerifyResult) addError(format string, a ...interface{}) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, a...))
+}
+
+func (r *FileVerifyResult) addWarning(format string, a ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, a...))
+}
+
+// RecoverWindow 一个全备及其连续的增量备份, 可以恢复到 [Start, End] 中的任意时间点
+type RecoverWindow struct {
+	Full     *BackupFileName   `json:"full"`
+	IncrList []*BackupFileName `json:"incr_list"`
+	Start    TS                `json:"start"`
+	End      TS                `json:"end"`
+	Gap      string            `json:"gap"` // 链条断开的原因, 为空表示增量链完整
+}
+
+// VerifyReport verify 命令的输出
+type VerifyReport struct {
+	Files   []*FileVerifyResult `json:"files"`
+	Windows []*RecoverWindow    `json:"windows"`
+}
+
+// Ok 所有文件校验通过，且增量链没有断开
+func (r *VerifyReport) Ok() bool {
+	for _, f := range r.Files {
+		if !f.Ok() {
+			return false
+		}
+	}
+	for _, w := range r.Windows {
+		if w.Gap != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Print 以表格形式输出
+func (r *VerifyReport) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tFILE\tSIZE\tFIRST_TS\tLAST_TS\tTS_SOURCE\tMANIFEST\tREADABLE\tERRORS")
+	for _, f := range r.Files {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d|%d\t%d|%d\t%s\t%v\t%v\t%s\n",
+			f.File.Type, f.File.FileName, f.File.FileSize,
+			f.File.FirstTs.Sec, f.File.FirstTs.I, f.File.LastTs.Sec, f.File.LastTs.I,
+			f.TsSource, f.ManifestOk, f.Readable, strings.Join(append(f.Errors, f.Warnings...), "; "))
+	}
+	tw.Flush()
+	for _, win := range r.Windows {
+		fmt.Fprintf(w, "window: %s ~ %s full:%s incr:%d",
+			tsToTimeStr(win.Start), tsToTimeStr(win.End), win.Full.FileName, len(win.IncrList))
+		if win.Gap != "" {
+			fmt.Fprintf(w, " gap:%s", win.Gap)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func tsToTimeStr(ts TS) string {
+	return time.Unix(int64(ts.Sec), 0).Format("2006-01-02T15:04:05")
+}
+
+// tsLess a < b
+func tsLess(a, b TS) bool {
+	return a.Sec < b.Sec || (a.Sec == b.Sec && a.I < b.I)
+}
+
+// VerifyBackupFiles 校验文件列表中的每一个文件, 并计算可恢复的时间范围.
+// checkContent 为 true 时, 会解压并读取文件内容, 检查文件是否可读.
+// skipMissingManifest 为 true 时, 没有清单文件的旧备份只记录warning, 否则视为校验失败
+func VerifyBackupFiles(fileObjList []*BackupFileName, checkContent, skipMissingManifest bool) *VerifyReport {
+	report := &VerifyReport{}
+	var okList []*BackupFileName
+	for _, f := range fileObjList {
+		r := VerifyFile(f, checkContent, skipMissingManifest)
+		log.Infof("verify %s ok:%v errors:%v warnings:%v", f.FileName, r.Ok(), r.Errors, r.Warnings)
+		report.Files = append(report.Files, r)
+		if r.Ok() {
+			okList = append(okList, f)
+		}
+	}
+	report.Windows = BuildRecoverWindows(okList)
+	return report
+}
+
+// VerifyFile 校验单个文件:
+// 1. 与清单文件中的大小和sha256比对
+// 2. 从 dump.log 中读取 firstTS/lastTS, 没有 dump.log 时使用清单或文件名中的时间
+// 3. checkContent 为 true 时, 读取文件内容, 检查压缩格式和bson格式
+// 清单文件不存在时无法确认文件完整, 除非 skipMissingManifest 为 true, 否则校验失败
+func VerifyFile(f *BackupFileName, checkContent, skipMissingManifest bool) *FileVerifyResult {
+	r := &FileVerifyResult{File: f}
+	filePath := f.GetFullPath()
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		r.addError("stat failed: %v", err)
+		return r
+	}
+	f.FileSize = fileInfo.Size()
+
+	manifest, err := LoadManifest(GetManifestFile(filePath))
+	if err != nil && skipMissingManifest {
+		// 旧版本的备份没有清单文件
+		r.addWarning("manifest not found: %v", err)
+	} else if err != nil {
+		r.addError("manifest not found: %v", err)
+	} else if manifest.FileSize != f.FileSize {
+		r.addError("size mismatch, manifest:%d file:%d", manifest.FileSize, f.FileSize)
+	} else if sum, _, err := FileSha256(filePath); err != nil {
+		r.addError("sha256 failed: %v", err)
+	} else if sum != manifest.Sha256 {
+		r.addError("sha256 mismatch, manifest:%s file:%s", manifest.Sha256, sum)
+	} else {
+		r.ManifestOk = true
+	}
+
+	r.TsSource = loadFileTs(f, manifest)
+	if f.Type == BackupTypeIncr && f.FirstTs.Sec == 0 {
+		r.addError("first ts unknown")
+	}
+
+	if checkContent {
+		oplogFirst, oplogLast, err := checkReadable(filePath)
+		if err != nil {
+			r.addError("not readable: %v", err)
+		} else {
+			r.Readable = true
+		}
+		// oplog文件内的首尾ts应与dump.log中一致
+		if err == nil && f.Type == BackupTypeIncr && r.TsSource != TsSourceRecord && oplogFirst != nil {
+			if *oplogFirst != f.FirstTs || *oplogLast != f.LastTs {
+				r.addWarning("oplog ts mismatch, content:[%v %v] %s:[%v %v]",
+					*oplogFirst, *oplogLast, r.TsSource, f.FirstTs, f.LastTs)
+			}
+		}
+	}
+	return r
+}
+
+// GetDumpLogFile 备份文件对应的 dump.log. 非archive的全备, dump.log 在tar包内
+func GetDumpLogFile(f *BackupFileName) string {
+	name := f.FileName
+	for _, suffix := range []string{".tar", ".tar.gz", ".archive", ".archive.gz", ".archive.zst", ".archive.zstd",
+		"-oplog.rs.bson", "-oplog.rs.bson.gz", "-oplog.rs.bson.zst", ".oplog.rs.bson", ".oplog.rs.bson.gz"} {
+		if strings.HasSuffix(name, suffix) {
+			return path.Join(f.Dir, strings.TrimSuffix(name, suffix)+".dump.log")
+		}
+	}
+	return ""
+}
+
+// loadFileTs 按 dump.log > manifest > record 的优先级设置 FirstTs/LastTs, 返回来源
+func loadFileTs(f *BackupFileName, manifest *BackupManifest) string {
+	if dumpLog := GetDumpLogFile(f); dumpLog != "" {
+		if content, err := os.ReadFile(dumpLog); err == nil {
+			firstTs, lastTs, err := ParseTs(bytes.NewBuffer(content))
+			if err == nil && lastTs.Sec > 0 {
+				f.FirstTs, f.LastTs = *firstTs, *lastTs
+				return TsSourceDumpLog
+			}
+		}
+	}
+	if manifest != nil && manifest.LastTs.Sec > 0 {
+		f.FirstTs, f.LastTs = manifest.FirstTs, manifest.LastTs
+		return TsSourceManifest
+	}
+	return TsSourceRecord
+}
+
+// checkReadable 读取整个文件, 检查压缩格式和内容格式. 如果是oplog文件, 返回首尾oplog的ts
+func checkReadable(filePath string) (firstTs, lastTs *TS, err error) {
+	fh, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fh.Close()
+	var reader io.Reader = bufio.NewReaderSize(fh, 1024*1024)
+	name := path.Base(filePath)
+
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		gzReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "gzip")
+		}
+		defer gzReader.Close()
+		reader = gzReader
+		name = strings.TrimSuffix(name, ".gz")
+	case strings.HasSuffix(name, ".zst"), strings.HasSuffix(name, ".zstd"):
+		zstdReader, err := zstd.NewReader(reader)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "zstd")
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+		name = strings.TrimSuffix(strings.TrimSuffix(name, ".zst"), ".zstd")
+	}
+
+	switch {
+	case strings.HasSuffix(name, ".tar"):
+		return nil, nil, readTar(reader)
+	case strings.HasSuffix(name, ".archive"):
+		return nil, nil, readArchive(reader)
+	case strings.HasSuffix(name, "oplog.rs.bson"):
+		return readOplogBson(reader)
+	}
+	return nil, nil, errors.Errorf("unknown file type: %s", name)
+}
+
+func readTar(reader io.Reader) error {
+	tarReader := tar.NewReader(reader)
+	n := 0
+	for {
+		_, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "tar")
+		}
+		if _, err = io.Copy(io.Discard, tarReader); err != nil {
+			return errors.Wrap(err, "tar")
+		}
+		n++
+	}
+	if n == 0 {
+		return errors.New("tar: empty")
+	}
+	return nil
+}
+
+func readArchive(reader io.Reader) error {
+	var magic uint32
+	if err := binary.Read(reader, binary.LittleEndian, &magic); err != nil {
+		return errors.Wrap(err, "archive")
+	}
+	if magic != archiveMagic {
+		return errors.Errorf("archive: bad magic %x", magic)
+	}
+	_, err := io.Copy(io.Discard, reader)
+	return errors.Wrap(err, "archive")
+}
+
+// readOplogBson 逐个读取bson文档, 返回首尾文档的ts
+func readOplogBson(reader io.Reader) (firstTs, lastTs *TS, err error) {
+	var sizeBuf [4]byte
+	buf := make([]byte, 0, 64*1024)
+	for n := 0; ; n++ {
+		if _, err = io.ReadFull(reader, sizeBuf[:]); err == io.EOF {
+			return firstTs, lastTs, nil
+		} else if err != nil {
+			return nil, nil, errors.Wrapf(err, "bson doc %d", n)
+		}
+		size := int(binary.LittleEndian.Uint32(sizeBuf[:]))
+		if size < 5 || size > maxBsonDocSize {
+			return nil, nil, errors.Errorf("bson doc %d: bad size %d", n, size)
+		}
+		if cap(buf) < size {
+			buf = make([]byte, 0, size)
+		}
+		doc := buf[:size]
+		copy(doc, sizeBuf[:])
+		if _, err = io.ReadFull(reader, doc[4:]); err != nil {
+			return nil, nil, errors.Wrapf(err, "bson doc %d", n)
+		}
+		if err = bson.Raw(doc).Validate(); err != nil {
+			return nil, nil, errors.Wrapf(err, "bson doc %d", n)
+		}
+		t, i, ok := bson.Raw(doc).Lookup("ts").TimestampOK()
+		if !ok {
+			continue
+		}
+		lastTs = &TS{Sec: t, I: i}
+		if firstTs == nil {
+			firstTs = &TS{Sec: t, I: i}
+		}
+	}
+}
+
+// BuildRecoverWindows 为每个全备计算可恢复的时间范围.
+// 第一个增量需覆盖全备的一致性时间点, 之后每个增量的 FirstTs 需等于上一个增量的 LastTs.
+func BuildRecoverWindows(fileObjList []*BackupFileName) []*RecoverWindow {
+	var windows []*RecoverWindow
+	for _, full := range fileObjList {
+		if full.Type != BackupTypeFull {
+			continue
+		}
+		windows = append(windows, buildRecoverWindow(full, fileObjList))
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		return tsLess(windows[i].Start, windows[j].Start)
+	})
+	return windows
+}
+
+func buildRecoverWindow(full *BackupFileName, fileObjList []*BackupFileName) *RecoverWindow {
+	w := &RecoverWindow{Full: full, Start: full.LastTs, End: full.LastTs}
+	var candidates []*BackupFileName
+	fullStr, _ := full.GetV0FullStr()
+	for _, f := range fileObjList {
+		if f.Type != BackupTypeIncr || f.Version != full.Version {
+			continue
+		}
+		if full.Version == BackupFileVersionV0 && f.V0FullStr != fullStr {
+			continue
+		}
+		// INCR LastTs 小于全备一致性时间 抛弃
+		if f.LastTs.Sec < full.LastTs.Sec {
+			continue
+		}
+		candidates = append(candidates, f)
+	}
+	if len(candidates) == 0 {
+		return w
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].V0IncrSeq != candidates[j].V0IncrSeq {
+			return candidates[i].V0IncrSeq < candidates[j].V0IncrSeq
+		}
+		return tsLess(candidates[i].LastTs, candidates[j].LastTs)
+	})
+
+	// 全备文件名中的 LastTs 只精确到秒, 第一个增量按秒比较
+	first := candidates[0]
+	if !(first.FirstTs.Sec <= full.LastTs.Sec && first.LastTs.Sec >= full.LastTs.Sec) {
+		w.Gap = fmt.Sprintf("first incr %s [%v %v] not cover full lastTs %v",
+			first.FileName, first.FirstTs, first.LastTs, full.LastTs)
+		return w
+	}
+	for i, f := range candidates {
+		if i > 0 {
+			prev := candidates[i-1]
+			if prev.LastTs != f.FirstTs {
+				w.Gap = fmt.Sprintf("gap between %s lastTs %v and %s firstTs %v",
+					prev.FileName, prev.LastTs, f.FileName, f.FirstTs)
+				return w
+			}
+		}
+		w.IncrList = append(w.IncrList, f)
+		w.End = f.LastTs
+	}
+	return w
+}
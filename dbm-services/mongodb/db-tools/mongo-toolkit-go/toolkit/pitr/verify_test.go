@@ -0,0 +1,98 @@
+package pitr
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func writeTestBackupFile(t *testing.T, f *BackupFileName, content []byte, firstTs, lastTs TS) {
+	if err := os.WriteFile(f.GetFullPath(), content, 0644); err != nil {
+		t.Fatalf("write %s failed, err: %v", f.FileName, err)
+	}
+	dumpLog := fmt.Sprintf("2025-04-01T16:55:07.883+0800\tfirstTS=(%d %d)\n2025-04-01T16:55:07.883+0800\tlastTS=(%d %d)\n",
+		firstTs.Sec, firstTs.I, lastTs.Sec, lastTs.I)
+	if err := os.WriteFile(GetDumpLogFile(f), []byte(dumpLog), 0644); err != nil {
+		t.Fatalf("write dump.log failed, err: %v", err)
+	}
+	f.FirstTs, f.LastTs = firstTs, lastTs
+	if _, err := WriteManifest(f); err != nil {
+		t.Fatalf("WriteManifest %s failed, err: %v", f.FileName, err)
+	}
+}
+
+func makeTestOplog(t *testing.T, tsList ...TS) []byte {
+	var buf bytes.Buffer
+	for _, ts := range tsList {
+		doc, err := bson.Marshal(bson.D{{Key: "ts", Value: primitive.Timestamp{T: ts.Sec, I: ts.I}}, {Key: "op", Value: "n"}})
+		if err != nil {
+			t.Fatalf("marshal oplog failed, err: %v", err)
+		}
+		buf.Write(doc)
+	}
+	return buf.Bytes()
+}
+
+func TestVerifyBackupFiles(t *testing.T) {
+	dir := t.TempDir()
+	full := &BackupFileName{Version: BackupFileVersionV0, Type: BackupTypeFull, Dir: dir, V0FullStr: "2025040116",
+		FileName: "mongodump-rs1-FULL-1.1.1.1-27017-2025040116-20250401165507.archive"}
+	writeTestBackupFile(t, full, []byte{0x6d, 0xe2, 0x99, 0x81, 1, 2, 3}, TS{900, 1}, TS{1000, 5})
+
+	tsList := []TS{{1000, 5}, {2000, 1}, {3000, 1}, {4000, 2}}
+	fileObjList := []*BackupFileName{full}
+	for i := 0; i < 3; i++ {
+		incr := &BackupFileName{Version: BackupFileVersionV0, Type: BackupTypeIncr, Dir: dir, V0FullStr: "2025040116",
+			V0IncrSeq: uint32(i + 1),
+			FileName:  fmt.Sprintf("mongodump-rs1-INCR-1.1.1.1-27017-2025040116-%d-2025040117000%d-oplog.rs.bson", i+1, i)}
+		writeTestBackupFile(t, incr, makeTestOplog(t, tsList[i], TS{tsList[i].Sec + 1, 1}, tsList[i+1]),
+			tsList[i], tsList[i+1])
+		fileObjList = append(fileObjList, incr)
+	}
+
+	report := VerifyBackupFiles(fileObjList, true, false)
+	var buf bytes.Buffer
+	report.Print(&buf)
+	t.Logf("report:\n%s", buf.String())
+	if !report.Ok() || len(report.Windows) != 1 {
+		t.Fatalf("want ok, got %+v", report)
+	}
+	if w := report.Windows[0]; w.Start != (TS{1000, 5}) || w.End != (TS{4000, 2}) || len(w.IncrList) != 3 {
+		t.Errorf("bad window %+v", w)
+	}
+
+	// 改坏第2个增量, 恢复窗口只能到第1个增量
+	if err := os.WriteFile(fileObjList[2].GetFullPath(), []byte("bad"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report = VerifyBackupFiles(fileObjList, true, false)
+	if report.Ok() || report.Files[2].Ok() || report.Files[2].ManifestOk {
+		t.Fatalf("want incr2 failed, got %+v", report.Files[2])
+	}
+	if w := report.Windows[0]; w.End != (TS{2000, 1}) || w.Gap == "" {
+		t.Errorf("bad window %+v", w)
+	}
+	t.Logf("gap: %s", report.Windows[0].Gap)
+}
+
+func TestVerifyFileMissingManifest(t *testing.T) {
+	dir := t.TempDir()
+	incr := &BackupFileName{Version: BackupFileVersionV0, Type: BackupTypeIncr, Dir: dir, V0FullStr: "2025040116",
+		V0IncrSeq: 1, FileName: "mongodump-rs1-INCR-1.1.1.1-27017-2025040116-1-20250401170000-oplog.rs.bson"}
+	writeTestBackupFile(t, incr, makeTestOplog(t, TS{1000, 5}, TS{2000, 1}), TS{1000, 5}, TS{2000, 1})
+	if err := os.Remove(GetManifestFile(incr.GetFullPath())); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := VerifyFile(incr, true, false); r.Ok() || r.ManifestOk {
+		t.Fatalf("want failed without manifest, got %+v", r)
+	}
+	r := VerifyFile(incr, true, true)
+	if !r.Ok() || r.ManifestOk || len(r.Warnings) == 0 {
+		t.Fatalf("want ok with warning when skip missing manifest, got %+v", r)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/spf13/cast"
 
 	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/common/go-pubpkg/iocrypt"
 	"dbm-services/common/go-pubpkg/logger"
 	"dbm-services/common/go-pubpkg/reportlog"
 	"dbm-services/mysql/db-tools/dbactuator/pkg/components"
@@ -94,6 +95,8 @@ type RecoverBinlog struct {
 	// 当 quick_mode=false 时，recover_opt 里的 databases 等选项无效，会应用全部 binlog
 	QuickMode          bool   `json:"quick_mode"`
 	SourceBinlogFormat string `json:"source_binlog_format" enums:",ROW,STATEMENT,MIXED"`
+	// DecryptKeyFile binlog 是 rotatebinlog 加密上传的，解密使用的 RSA 私钥文件
+	DecryptKeyFile string `json:"decrypt_key_file"`
 
 	// 恢复用到的客户端工具，不提供时会有默认值
 	tools.ToolSet
@@ -593,6 +596,34 @@ func (r *RecoverBinlog) GetBinlogFilesFromDir(binlogDir, namePrefix string) ([]s
 	return binlogFiles, nil
 }
 
+// decryptBinlogFiles 把 BinlogDir 下加密的 binlog 原地解密
+// 加密文件与 binlog 同名，通过文件头识别，未加密的 binlog 不处理
+func (r *RecoverBinlog) decryptBinlogFiles() error {
+	binlogFiles, err := r.GetBinlogFilesFromDir(r.BinlogDir, "")
+	if err != nil {
+		return err
+	}
+	var decrypter *iocrypt.SioDecrypter
+	for _, f := range binlogFiles {
+		fileName := filepath.Join(r.BinlogDir, f)
+		if encrypted, err := iocrypt.IsSioEncryptedFile(fileName); err != nil {
+			return err
+		} else if !encrypted {
+			continue
+		}
+		if decrypter == nil {
+			if decrypter, err = iocrypt.NewSioDecrypter(r.DecryptKeyFile, ""); err != nil {
+				return errors.WithMessagef(err, "binlog %s is encrypted", fileName)
+			}
+		}
+		logger.Info("decrypt binlog %s", fileName)
+		if err = decrypter.DecryptFile(fileName, fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // PreCheck TODO
 // r.BinlogFiles 是已经过滤后的 binlog 文件列表
 func (r *RecoverBinlog) PreCheck() error {
@@ -604,6 +635,9 @@ func (r *RecoverBinlog) PreCheck() error {
 	if err = r.buildBinlogOptions(); err != nil {
 		return err
 	}
+	if err = r.decryptBinlogFiles(); err != nil {
+		return err
+	}
 	if err = r.checkBinlogFiles(); err != nil {
 		logger.Warn("check binlog files error: %s. try to get binlog file from recover dir", err.Error())
 	}
@@ -25,12 +25,20 @@ func init() {
 		"backup index file, overwrite LogicalLoad.IndexFilePath, PhysicalLoad.IndexFilePath")
 	loadCmd.PersistentFlags().Int("threads", 8, "threads for myloader or xtrabackup, "+
 		"default os logic cores")
+	loadCmd.PersistentFlags().String("decrypt-key-file", "",
+		"rsa private key file to decrypt sio encrypted backup, overwrite DecryptKeyFile")
+	loadCmd.PersistentFlags().String("decrypt-passphrase", "",
+		"passphrase to decrypt sio encrypted backup, overwrite DecryptPassphrase")
 	viper.BindPFlag("LogicalLoad.MysqlLoadDir", loadCmd.PersistentFlags().Lookup("load-dir"))
 	viper.BindPFlag("LogicalLoad.IndexFilePath", loadCmd.PersistentFlags().Lookup("load-index-file"))
 	viper.BindPFlag("LogicalLoad.Threads", loadCmd.PersistentFlags().Lookup("threads"))
 	viper.BindPFlag("PhysicalLoad.MysqlLoadDir", loadCmd.PersistentFlags().Lookup("load-dir"))
 	viper.BindPFlag("PhysicalLoad.IndexFilePath", loadCmd.PersistentFlags().Lookup("load-index-file"))
 	viper.BindPFlag("PhysicalLoad.Threads", loadCmd.PersistentFlags().Lookup("threads"))
+	viper.BindPFlag("LogicalLoad.DecryptKeyFile", loadCmd.PersistentFlags().Lookup("decrypt-key-file"))
+	viper.BindPFlag("LogicalLoad.DecryptPassphrase", loadCmd.PersistentFlags().Lookup("decrypt-passphrase"))
+	viper.BindPFlag("PhysicalLoad.DecryptKeyFile", loadCmd.PersistentFlags().Lookup("decrypt-key-file"))
+	viper.BindPFlag("PhysicalLoad.DecryptPassphrase", loadCmd.PersistentFlags().Lookup("decrypt-passphrase"))
 
 	loadCmd.AddCommand(loadLogicalCmd)
 	loadCmd.AddCommand(loadPhysicalCmd)
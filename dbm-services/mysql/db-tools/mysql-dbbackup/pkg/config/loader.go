@@ -36,6 +36,11 @@ type LogicalLoad struct {
 	// CreateTableIfNotExists true will add --append-if-not-exist for myloader
 	CreateTableIfNotExists bool `ini:"CreateTableIfNotExists"`
 
+	// DecryptKeyFile sio 加密备份解密使用的 RSA 私钥文件，与 DecryptPassphrase 至少提供一个
+	DecryptKeyFile string `ini:"DecryptKeyFile"`
+	// DecryptPassphrase sio 加密备份的 passphrase，即上报的 encrypted_key 解密后的明文
+	DecryptPassphrase string `ini:"DecryptPassphrase"`
+
 	// filterType form, regex, tables
 	TableFilter `ini:"LogicalLoad" mapstructure:",squash"` // viper squash is used to simplify code
 }
@@ -57,6 +62,10 @@ type PhysicalLoad struct {
 	Threads      int    `ini:"Threads"`
 	CopyBack     bool   `ini:"CopyBack"` // use copy-back or move-back
 	ExtraOpt     string `ini:"ExtraOpt"` // other xtrabackup recover options string to be appended
+	// DecryptKeyFile sio 加密备份解密使用的 RSA 私钥文件，与 DecryptPassphrase 至少提供一个
+	DecryptKeyFile string `ini:"DecryptKeyFile"`
+	// DecryptPassphrase sio 加密备份的 passphrase，即上报的 encrypted_key 解密后的明文
+	DecryptPassphrase string `ini:"DecryptPassphrase"`
 
 	/* TODO: 后续如果物理备份需要连接数据库，不使用 Public 里的，直接放在这里
 	MysqlHost     string `ini:"MysqlHost"`
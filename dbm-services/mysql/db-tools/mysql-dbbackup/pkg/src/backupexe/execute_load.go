@@ -1,10 +1,21 @@
 package backupexe
 
 import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/pkg/errors"
+
+	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/common/go-pubpkg/iocrypt"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/util"
 )
 
 // ExecuteLoad execute load backup command
@@ -13,6 +24,10 @@ func ExecuteLoad(cnf *config.BackupConfig, indexFileContent *dbareport.IndexCont
 		return envErr
 	}
 
+	if err := untarEncryptedBackup(cnf, indexFileContent); err != nil {
+		return err
+	}
+
 	backupStorageEngine := strings.ToLower(indexFileContent.StorageEngine)
 	loader, err := BuildLoader(cnf, indexFileContent.BackupType, indexFileContent.BackupTool, backupStorageEngine)
 	if err != nil {
@@ -31,3 +46,68 @@ func ExecuteLoad(cnf *config.BackupConfig, indexFileContent *dbareport.IndexCont
 
 	return nil
 }
+
+// untarEncryptedBackup 加密备份文件不能直接 tar 解包，load 之前先解密解包到 MysqlLoadDir 的上级目录
+// MysqlLoadDir 已存在时认为已经解包过了
+func untarEncryptedBackup(cnf *config.BackupConfig, indexContent *dbareport.IndexContent) error {
+	if !indexContent.EncryptEnable {
+		return nil
+	}
+	var loadDir, indexFilePath, keyFile, passphrase string
+	if strings.ToLower(indexContent.BackupType) == cst.BackupPhysical {
+		loadDir, indexFilePath = cnf.PhysicalLoad.MysqlLoadDir, cnf.PhysicalLoad.IndexFilePath
+		keyFile, passphrase = cnf.PhysicalLoad.DecryptKeyFile, cnf.PhysicalLoad.DecryptPassphrase
+	} else {
+		loadDir, indexFilePath = cnf.LogicalLoad.MysqlLoadDir, cnf.LogicalLoad.IndexFilePath
+		keyFile, passphrase = cnf.LogicalLoad.DecryptKeyFile, cnf.LogicalLoad.DecryptPassphrase
+	}
+	if loadDir == "" || cmutil.FileExists(loadDir) {
+		return nil
+	}
+	decrypter, err := iocrypt.NewSioDecrypter(keyFile, passphrase)
+	if err != nil {
+		return errors.WithMessage(err, "backup is encrypted")
+	}
+
+	backupDir := filepath.Dir(indexFilePath)
+	untarDir := filepath.Dir(filepath.Clean(loadDir))
+	var splitParts []string
+	for _, f := range indexContent.FileList {
+		switch f.FileType {
+		case cst.FileTar:
+			logger.Log.Infof("decrypt and untar %s to %s", f.FileName, untarDir)
+			if err = util.UntarFiles(untarDir, decrypter, filepath.Join(backupDir, f.FileName)); err != nil {
+				return err
+			}
+		case cst.FilePart:
+			splitParts = append(splitParts, filepath.Join(backupDir, f.FileName))
+		}
+	}
+	// part 文件是一个加密流切分出来的，需要按序号拼接
+	if len(splitParts) > 0 {
+		sortSplitParts(splitParts)
+		logger.Log.Infof("decrypt and untar %d split parts to %s", len(splitParts), untarDir)
+		if err = util.UntarFiles(untarDir, decrypter, splitParts...); err != nil {
+			return err
+		}
+	}
+	if !cmutil.FileExists(loadDir) {
+		return errors.Errorf("load dir %s not found after untar encrypted backup", loadDir)
+	}
+	return nil
+}
+
+// sortSplitParts 按 .part_N 的序号排序
+func sortSplitParts(parts []string) {
+	reSplitPart := regexp.MustCompile(`\.part_(\d+)$`)
+	partNo := func(name string) int {
+		if m := reSplitPart.FindStringSubmatch(name); len(m) == 2 {
+			n, _ := strconv.Atoi(m[1])
+			return n
+		}
+		return -1
+	}
+	sort.SliceStable(parts, func(i, j int) bool {
+		return partNo(parts[i]) < partNo(parts[j])
+	})
+}
@@ -0,0 +1,113 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package util
+
+import (
+	"archive/tar"
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/common/go-pubpkg/iocrypt"
+)
+
+// UntarFiles 把 tarFiles 按顺序拼接成一个 tar 流解包到 dstDir
+// split 出来的 part 文件需要一起传入，独立的 tar 文件需要分别调用
+// 文件是 sio 加密的，使用 decrypter 解密；decrypter 为 nil 时不能解包加密文件
+func UntarFiles(dstDir string, decrypter *iocrypt.SioDecrypter, tarFiles ...string) error {
+	readers := make([]io.Reader, 0, len(tarFiles))
+	for _, tarFile := range tarFiles {
+		f, err := os.Open(tarFile)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	if err := UntarStream(io.MultiReader(readers...), dstDir, decrypter); err != nil {
+		return errors.WithMessagef(err, "untar %v", tarFiles)
+	}
+	return nil
+}
+
+// UntarStream 解包 tar 流到 dstDir，只解包目录、普通文件和软链
+func UntarStream(r io.Reader, dstDir string, decrypter *iocrypt.SioDecrypter) error {
+	br := bufio.NewReaderSize(r, 128*1024)
+	var src io.Reader = br
+	if head, _ := br.Peek(16); iocrypt.IsSioEncrypted(head) {
+		if decrypter == nil {
+			return errors.New("tar file is encrypted, decrypt key is required")
+		}
+		var err error
+		if src, err = decrypter.NewDecryptReader(br); err != nil {
+			return err
+		}
+	}
+	tr := tar.NewReader(src)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "read tar header")
+		}
+		target, err := untarTargetPath(dstDir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, os.FileMode(header.Mode).Perm()|0700); err != nil {
+				return errors.WithStack(err)
+			}
+		case tar.TypeReg:
+			if err = untarRegularFile(tr, target, os.FileMode(header.Mode).Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return errors.WithStack(err)
+			}
+			if err = os.Symlink(header.Linkname, target); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+}
+
+// untarTargetPath tar 里的文件只能解包到 dstDir 下
+func untarTargetPath(dstDir string, name string) (string, error) {
+	cleanDir := filepath.Clean(dstDir)
+	target := filepath.Join(cleanDir, name)
+	if target != cleanDir && !strings.HasPrefix(target, cleanDir+string(os.PathSeparator)) {
+		return "", errors.Errorf("illegal file path in tar: %s", name)
+	}
+	return target, nil
+}
+
+func untarRegularFile(r io.Reader, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "write %s", target)
+	}
+	return errors.WithStack(f.Close())
+}
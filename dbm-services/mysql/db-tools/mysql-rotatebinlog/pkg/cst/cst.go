@@ -40,3 +40,6 @@ const (
 var BackupEnableAllowed = []string{BackupEnableTrue, BackupEnableFalse, BackupEnableAuto, ""}
 
 const OldRotateDir = "/home/mysql/rotate_logbin"
+
+// EncryptedBinlogDir binlog 目录下存放待上传加密 binlog 的子目录，文件名与 binlog 相同
+const EncryptedBinlogDir = ".encrypted"
@@ -14,6 +14,7 @@ import (
 	"github.com/samber/lo"
 	"github.com/spf13/viper"
 
+	"dbm-services/common/go-pubpkg/iocrypt"
 	"dbm-services/common/go-pubpkg/logger"
 	meta "dbm-services/common/reverseapi/define/mysql"
 	"dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/cst"
@@ -69,6 +70,24 @@ type ReportCfg struct {
 type EncryptCfg struct {
 	Enable    bool   `json:"enable" mapstructure:"enable"`
 	KeyPrefix string `json:"key_prefix" mapstructure:"key_prefix"`
+	// PublicKey RSA 公钥文件，上传备份系统的 binlog 使用 sio 加密，每个文件的 data key 由公钥加密后写在文件头
+	// 恢复时使用平台对应的私钥解密，不需要额外上报密钥
+	PublicKey string `json:"public_key" mapstructure:"public_key"`
+}
+
+// GetEncryptTool enable=false 时返回 nil
+func (c EncryptCfg) GetEncryptTool() (*iocrypt.SioCrypt, error) {
+	if !c.Enable {
+		return nil, nil
+	}
+	if c.PublicKey == "" {
+		return nil, errors.New("encrypt.public_key is required when encrypt is enabled")
+	}
+	pubKey, err := iocrypt.ReadPublicKeyFile(c.PublicKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "read encrypt.public_key")
+	}
+	return &iocrypt.SioCrypt{PublicKey: pubKey}, nil
 }
 
 // ScheduleCfg schedule config
@@ -113,6 +113,12 @@ func (c *RotateBinlogComp) Start() (err error) {
 				continue
 			}
 			inst.backupClient = backupClient // if nil, ignore backup
+			if inst.encryptTool, err = c.ConfigObj.Encrypt.GetEncryptTool(); err != nil {
+				err = errs.WithMessagef(err, "init encrypt")
+				logger.Error("%+v", err.Error())
+				errRet = errors.Join(errRet, err)
+				continue
+			}
 		} else {
 			logger.Info("instance %d backup_client is disabled", inst.Port)
 		}
@@ -14,6 +14,7 @@ import (
 	"modernc.org/mathutil"
 
 	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/common/go-pubpkg/iocrypt"
 	"dbm-services/common/go-pubpkg/logger"
 	"dbm-services/common/go-pubpkg/reportlog"
 	"dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/backup"
@@ -42,6 +43,7 @@ type BinlogRotate struct {
 	purgeInterval     time.Duration
 	rotateInterval    time.Duration
 	maxKeepDuration   time.Duration
+	encryptTool       *iocrypt.SioCrypt
 }
 
 // String 用于打印
@@ -366,7 +368,12 @@ func (r *BinlogRotate) Backup(backupClient backup.BackupClient) error {
 				}
 			}
 			logger.Info("backup_client upload register file %s", filename)
-			if taskid, err := backupClient.Upload(filename); err != nil {
+			uploadFile, err := r.uploadFile(f.Filename)
+			if err != nil {
+				logger.Error("fail to encrypt file %s. err: %v", filename, err.Error())
+				f.BackupStatus = models.IBStatusClientFail
+				f.BackupStatusInfo = err.Error()
+			} else if taskid, err := backupClient.Upload(uploadFile); err != nil {
 				logger.Error("fail to upload register file %s. err: %v", filename, err.Error())
 				f.BackupStatus = models.IBStatusClientFail
 				f.BackupStatusInfo = err.Error()
@@ -387,6 +394,7 @@ func (r *BinlogRotate) Backup(backupClient backup.BackupClient) error {
 
 				if taskStatus == models.IBStatusSuccess {
 					f.BackupStatus = taskStatus
+					r.removeEncrypted(f.Filename)
 					log.Reporter().Result.Println(f)
 					ev := log.MysqlBinlogResultEvent(*f)
 					if resp, err := reapi.SyncReport(reportCore, &ev); err != nil {
@@ -458,6 +466,7 @@ func (r *BinlogRotate) Remove(sizeBytesToFree, sizeBytesBurstToFree int64, succe
 			break
 		}
 		fileFullPath := filepath.Join(r.binlogDir, f.Filename)
+		r.removeEncrypted(f.Filename)
 		if cmutil.FileExists(fileFullPath) {
 			if success {
 				logger.Info("remove file: %s", fileFullPath)
@@ -506,3 +515,30 @@ func (r *BinlogRotate) Remove(sizeBytesToFree, sizeBytesBurstToFree int64, succe
 		success, sizeDeleted, fileDeleted, stopFile)
 	return nil
 }
+
+// uploadFile 返回需要提交上传的文件
+// 启用加密时，binlog 加密到 binlog 目录下的 EncryptedBinlogDir，文件名保持不变，上传成功或者 binlog 删除时清理
+func (r *BinlogRotate) uploadFile(fileName string) (string, error) {
+	binlogFile := filepath.Join(r.binlogDir, fileName)
+	if r.encryptTool == nil {
+		return binlogFile, nil
+	}
+	encryptedDir := filepath.Join(r.binlogDir, cst.EncryptedBinlogDir)
+	if err := os.MkdirAll(encryptedDir, 0755); err != nil {
+		return "", errors.Wrap(err, "create encrypted binlog dir")
+	}
+	encryptedFile := filepath.Join(encryptedDir, fileName)
+	logger.Info("encrypt binlog %s to %s", binlogFile, encryptedFile)
+	if err := r.encryptTool.EncryptFile(binlogFile, encryptedFile); err != nil {
+		return "", err
+	}
+	return encryptedFile, nil
+}
+
+// removeEncrypted 删除 binlog 对应的加密文件
+func (r *BinlogRotate) removeEncrypted(fileName string) {
+	encryptedFile := filepath.Join(r.binlogDir, cst.EncryptedBinlogDir, fileName)
+	if err := os.Remove(encryptedFile); err != nil && !os.IsNotExist(err) {
+		logger.Warn("remove encrypted binlog %s failed: %s", encryptedFile, err.Error())
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/common/go-pubpkg/iocrypt"
 	"dbm-services/common/go-pubpkg/logger"
 	"dbm-services/common/go-pubpkg/timeutil"
 	"dbm-services/mysql/db-tools/dbactuator/pkg/native"
@@ -37,6 +38,8 @@ type ServerObj struct {
 	// 已按文件名升序排序，本地存在的binlog文件列表
 	binlogFiles  []*BinlogFile
 	backupClient backup.BackupClient
+	// encryptTool 不为 nil 时，binlog 加密后再上传备份系统
+	encryptTool  *iocrypt.SioCrypt
 	instance     *native.InsObject
 	rotate       *BinlogRotate
 	backupEnable bool
@@ -85,6 +88,7 @@ func (i *ServerObj) Rotate() (lastFileBefore *models.BinlogFileModel, err error)
 		rotateInterval:  timeutil.ViperGetDuration("public.rotate_interval"),
 		maxKeepDuration: maxKeepDuration,
 		binlogDir:       i.binlogDir,
+		encryptTool:     i.encryptTool,
 	}
 	i.rotate = rotate
 	logger.Info("rotate obj: %+v", rotate)
@@ -162,7 +166,7 @@ func (i *ServerObj) getBinlogFilesLocal() (string, []*BinlogFile, error) {
 	days60 := time.Hour * 24 * 60
 	for _, fi := range files {
 		if !reFilename.MatchString(fi.Name()) {
-			if !strings.HasSuffix(fi.Name(), ".index") {
+			if !strings.HasSuffix(fi.Name(), ".index") && fi.Name() != cst.EncryptedBinlogDir {
 				logger.Warn("illegal binlog file name %s", fi.Name())
 			}
 			continue
@@ -76,6 +76,23 @@ func NewRedisClientWithTimeout(addr, passwd string, db int, dbType string, timeo
 	return
 }
 
+// NewRedisClusterClientWithTimeout 建 redis cluster 智能client,可指定超时时间
+// 命令按key路由到集群中不同的实例,只用于按key读写整个集群的场景(如dts数据校验访问目的集群)
+func NewRedisClusterClientWithTimeout(addr, passwd string, timeout time.Duration) (conn *RedisClient, err error) {
+	conn = &RedisClient{
+		Addr:         addr,
+		Password:     passwd,
+		MaxRetryTime: int(timeout.Seconds()),
+		DbType:       consts.TendisTypeRedisCluster,
+		nodesMu:      &sync.Mutex{},
+	}
+	err = conn.newConn(timeout)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
 func (db *RedisClient) newConn(timeout time.Duration) (err error) {
 	// 执行命令失败重连,确保重连后,databases正确
 	var redisConnHook = func(ctx context.Context, cn *redis.Conn) error {
@@ -46,6 +46,7 @@ type BkDbmonInstallParams struct {
 	BackupClientStrorageType string                 `json:"backup_client_storage_type"`
 	RedisFullBackup          map[string]interface{} `json:"redis_fullbackup" validate:"required"`
 	RedisBinlogBackup        map[string]interface{} `json:"redis_binlogbackup" validate:"required"`
+	BackupEncrypt            map[string]interface{} `json:"backup_encrypt"`
 	RedisHeartbeat           map[string]interface{} `json:"redis_heartbeat" validate:"required"`
 	RedisMonitor             map[string]interface{} `json:"redis_monitor" validate:"required"`
 	RedisKeyLifecyckle       map[string]interface{} `json:"redis_keylife" mapstructure:"redis_keylife"`
@@ -424,6 +425,7 @@ type bkDbmonConf struct {
 	BackupClientStrorageType string                 `json:"backup_client_storage_type" yaml:"backup_client_storage_type"`
 	RedisFullBackup          map[string]interface{} `json:"redis_fullbackup" yaml:"redis_fullbackup"`
 	RedisBinlogBackup        map[string]interface{} `json:"redis_binlogbackup" yaml:"redis_binlogbackup"`
+	BackupEncrypt            map[string]interface{} `json:"backup_encrypt,omitempty" yaml:"backup_encrypt,omitempty"`
 	RedisHeartbeat           map[string]interface{} `json:"redis_heartbeat" yaml:"redis_heartbeat"`
 	RedisMonitor             map[string]interface{} `json:"redis_monitor" yaml:"redis_monitor"`
 	RedisKeyLifecyckle       map[string]interface{} `json:"redis_keylife" yaml:"redis_keylife"`
@@ -451,6 +453,7 @@ func (job *BkDbmonInstall) GenerateConfigFile() (err error) {
 		BackupClientStrorageType: job.params.BackupClientStrorageType,
 		RedisFullBackup:          job.params.RedisFullBackup,
 		RedisBinlogBackup:        job.params.RedisBinlogBackup,
+		BackupEncrypt:            job.params.BackupEncrypt,
 		RedisHeartbeat:           job.params.RedisHeartbeat,
 		RedisMonitor:             job.params.RedisMonitor,
 		RedisKeyLifecyckle:       job.params.RedisKeyLifecyckle,
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"sync"
 
+	"dbm-services/common/go-pubpkg/iocrypt"
 	"dbm-services/redis/db-tools/dbactuator/models/myredis"
 	"dbm-services/redis/db-tools/dbactuator/pkg/consts"
 	"dbm-services/redis/db-tools/dbactuator/pkg/datastructure"
@@ -31,6 +32,7 @@ type RedisDataStructureParams struct {
 	DestDir           string                     `json:"dest_dir"`                           // 备份下载/存放目录
 	FullFileList      []datastructure.FileDetail `json:"full_file_list" validate:"required"` // 全备文件列表
 	BinlogFileList    []datastructure.FileDetail `json:"binlog_file_list" `                  // binlog文件列表
+	DecryptKeyFile    string                     `json:"decrypt_key_file"`                   // 备份加密时,解密用的RSA私钥文件
 }
 
 // RedisDataStructure redis 数据构造
@@ -126,6 +128,12 @@ func (task *RedisDataStructure) Run() (err error) {
 		return err
 	}
 
+	// 加密的备份文件先原地解密
+	err = task.DecryptFileList()
+	if err != nil {
+		return err
+	}
+
 	task.runtime.Logger.Info(task.params.RecoveryTimePoint)
 	// 构造任务初始化
 	recoverTasks := make([]*datastructure.TendisInsRecoverTask, 0, len(task.params.SourcePorts))
@@ -256,3 +264,37 @@ func (task *RedisDataStructure) CheckFileList() error {
 	}
 	return nil
 }
+
+// DecryptFileList 全备和增备文件是 bk-dbmon 加密上传的,原地解密
+// 加密文件通过文件头识别,未加密的文件不处理
+func (task *RedisDataStructure) DecryptFileList() error {
+	var decrypter *iocrypt.SioDecrypter
+	fileList := append([]datastructure.FileDetail{}, task.params.FullFileList...)
+	fileList = append(fileList, task.params.BinlogFileList...)
+	for _, file := range fileList {
+		filePath := filepath.Join(task.RecoverDir, file.FileName)
+		encrypted, err := iocrypt.IsSioEncryptedFile(filePath)
+		if err != nil {
+			task.runtime.Logger.Error("check file:%s encrypted fail,err:%v", filePath, err)
+			return err
+		}
+		if !encrypted {
+			continue
+		}
+		if decrypter == nil {
+			decrypter, err = iocrypt.NewSioDecrypter(task.params.DecryptKeyFile, "")
+			if err != nil {
+				err = fmt.Errorf("file:%s is encrypted,err:%v", filePath, err)
+				task.runtime.Logger.Error(err.Error())
+				return err
+			}
+		}
+		task.runtime.Logger.Info("decrypt file:%s", filePath)
+		err = decrypter.DecryptFile(filePath, filePath)
+		if err != nil {
+			task.runtime.Logger.Error("decrypt file:%s fail,err:%v", filePath, err)
+			return err
+		}
+	}
+	return nil
+}
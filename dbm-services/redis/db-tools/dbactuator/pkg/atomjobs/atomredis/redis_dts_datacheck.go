@@ -2,14 +2,13 @@ package atomredis
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,6 +22,7 @@ import (
 	"dbm-services/redis/db-tools/dbactuator/pkg/util"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/go-redis/redis/v8"
 	"github.com/gofrs/flock"
 	"github.com/panjf2000/ants/v2"
 )
@@ -36,6 +36,7 @@ type PortAndSegment struct {
 
 // RedisDtsDataCheckAndRpaireParams 数据校验与修复参数
 type RedisDtsDataCheckAndRpaireParams struct {
+	// 数据校验与修复已不再依赖 dbtools 中的工具,保留该字段兼容已有单据
 	common.DbToolsMediaPkg
 	BkBizID     string `json:"bk_biz_id" validate:"required"`
 	DtsCopyType string `json:"dts_copy_type" validate:"required"`
@@ -54,16 +55,18 @@ type RedisDtsDataCheckAndRpaireParams struct {
 	DstClusterPassword string `json:"dst_cluster_password" validate:"required"`
 	KeyWhiteRegex      string `json:"key_white_regex" validate:"required"`
 	KeyBlackRegex      string `json:"key_black_regex"`
+	// 数据校验时,单个源redis实例每秒执行的命令数上限;目的集群上限为该值*并发度. 默认10000
+	CheckQPS int `json:"check_qps"`
+	// 数据修复时,单个源redis实例每秒执行的命令数上限;目的集群上限为该值*并发度. 默认2000
+	RepairQPS int `json:"repair_qps"`
 }
 
 // RedisDtsDataCheck dts 数据校验
 type RedisDtsDataCheck struct {
-	atomJobName    string
-	saveDir        string
-	dataCheckTool  string
-	dataRepairTool string
-	params         RedisDtsDataCheckAndRpaireParams
-	runtime        *jobruntime.JobGenericRuntime
+	atomJobName string
+	saveDir     string
+	params      RedisDtsDataCheckAndRpaireParams
+	runtime     *jobruntime.JobGenericRuntime
 }
 
 // 无实际作用,仅确保实现了 jobruntime.JobRunner 接口
@@ -103,6 +106,12 @@ func (job *RedisDtsDataCheck) Init(m *jobruntime.JobGenericRuntime) error {
 		job.runtime.Logger.Error(err.Error())
 		return err
 	}
+	if job.params.CheckQPS <= 0 {
+		job.params.CheckQPS = dtsDefaultCheckQPS
+	}
+	if job.params.RepairQPS <= 0 {
+		job.params.RepairQPS = dtsDefaultRepairQPS
+	}
 	return nil
 }
 
@@ -125,53 +134,80 @@ func (job *RedisDtsDataCheck) Run() (err error) {
 	if err != nil {
 		return
 	}
-	// 2. 获取工具
-	err = job.GetTools()
+	// 2. 结果目录
+	job.getSaveDir()
+
+	// 3. 并发校验,并发度5
+	taskList, err := job.newInsTasks()
+	if err != nil {
+		return err
+	}
+	err = job.runInsTasks(taskList, (*RedisInsDtsDataCheckAndRepairTask).RunDataCheck)
 	if err != nil {
+		return err
+	}
+
+	var totalDiffKeysCnt uint64 = 0
+	for _, task := range taskList {
+		totalDiffKeysCnt += task.DiffKeysCnt
+	}
+	if totalDiffKeysCnt > 0 {
+		err = fmt.Errorf("RedisDtsDataCheck totalDiffKeysCnt:%d", totalDiffKeysCnt)
+		job.runtime.Logger.Error(err.Error())
 		return
 	}
+	job.runtime.Logger.Info("RedisDtsDataCheck success totalDiffKeysCnt:%d", totalDiffKeysCnt)
+	return
+}
+
+// newInsTasks 为每个源redis实例创建task
+// 全部创建成功后再启动,避免部分task已在执行时返回
+func (job *RedisDtsDataCheck) newInsTasks() (taskList []*RedisInsDtsDataCheckAndRepairTask, err error) {
+	taskList = make([]*RedisInsDtsDataCheckAndRepairTask, 0, len(job.params.SrcRedisPortSegmentList))
+	for _, portItem := range job.params.SrcRedisPortSegmentList {
+		task, err := NewRedisInsDtsDataCheckAndRepairTask(job.params.SrcRedisIP, portItem, job)
+		if err != nil {
+			return nil, err
+		}
+		taskList = append(taskList, task)
+	}
+	return taskList, nil
+}
 
-	// 3. 并发提取与校验,并发度5
+// runInsTasks 并发度5执行task,等待全部执行完毕后汇总所有task的错误
+func (job *RedisDtsDataCheck) runInsTasks(taskList []*RedisInsDtsDataCheckAndRepairTask,
+	fn func(task *RedisInsDtsDataCheckAndRepairTask)) error {
 	var wg sync.WaitGroup
-	taskList := make([]*RedisInsDtsDataCheckAndRepairTask, 0, len(job.params.SrcRedisPortSegmentList))
 	pool, err := ants.NewPoolWithFunc(5, func(i interface{}) {
 		defer wg.Done()
-		task := i.(*RedisInsDtsDataCheckAndRepairTask)
-		task.KeyPatternAndDataCheck()
+		fn(i.(*RedisInsDtsDataCheckAndRepairTask))
 	})
 	if err != nil {
-		job.runtime.Logger.Error("RedisDtsDataCheck Run NewPoolWithFunc failed,err:%v", err)
+		job.runtime.Logger.Error("%s Run NewPoolWithFunc failed,err:%v", job.Name(), err)
 		return err
 	}
 	defer pool.Release()
-
-	for _, portItem := range job.params.SrcRedisPortSegmentList {
+	for _, task := range taskList {
 		wg.Add(1)
-		task, err := NewRedisInsDtsDataCheckAndRepairTask(job.params.SrcRedisIP, portItem, job)
-		if err != nil {
-			continue
+		if err = pool.Invoke(task); err != nil {
+			wg.Done()
+			task.Err = fmt.Errorf("srcAddr:%s invoke task fail,err:%v", task.getSrcRedisAddr(), err)
 		}
-		taskList = append(taskList, task)
-		_ = pool.Invoke(task)
 	}
 	// 等待所有task执行完毕
 	wg.Wait()
+	return insTasksErr(taskList)
+}
 
-	var totalDiffKeysCnt uint64 = 0
-	for _, tmp := range taskList {
-		task := tmp
+// insTasksErr 汇总所有失败task的错误
+func insTasksErr(taskList []*RedisInsDtsDataCheckAndRepairTask) error {
+	var errs []error
+	for _, task := range taskList {
 		if task.Err != nil {
-			return task.Err
+			errs = append(errs, task.Err)
 		}
-		totalDiffKeysCnt += task.DiffKeysCnt
 	}
-	if totalDiffKeysCnt > 0 {
-		err = fmt.Errorf("RedisDtsDataCheck totalDiffKeysCnt:%d", totalDiffKeysCnt)
-		job.runtime.Logger.Error(err.Error())
-		return
-	}
-	job.runtime.Logger.Info("RedisDtsDataCheck success totalDiffKeysCnt:%d", totalDiffKeysCnt)
-	return
+	return errors.Join(errs...)
 }
 
 func (job *RedisDtsDataCheck) getSaveDir() {
@@ -220,50 +256,6 @@ func (job *RedisDtsDataCheck) CheckDtsType() (err error) {
 	return
 }
 
-// GetTools 获取数据校验相关的工具
-func (job *RedisDtsDataCheck) GetTools() (err error) {
-	job.getSaveDir()
-	err = job.params.Check()
-	if err != nil {
-		job.runtime.Logger.Error("GetTools DbToolsMediaPkg check fail,err:%v", err)
-		return
-	}
-	err = job.params.DbToolsMediaPkg.Install()
-	if err != nil {
-		job.runtime.Logger.Error("GetTools DbToolsMediaPkg install fail,err:%v", err)
-		return
-	}
-	// 这一部分和提取key保持一致
-	// 复制dbtools/ldb_tendisplus,ldb_with_len.3.8, ldb_with_len.5.13
-	// redis-shake redisSafeDeleteTool到get_keys_pattern
-	cpCmd := fmt.Sprintf("cp  %s/ldb* %s/redis-shake %s/redis-shake-v4 %s/redisSafeDeleteTool %s", consts.DbToolsPath,
-		consts.DbToolsPath, consts.DbToolsPath, consts.DbToolsPath, job.saveDir)
-	job.runtime.Logger.Info(cpCmd)
-	_, err = util.RunBashCmd(cpCmd, "", nil, 100*time.Second)
-	if err != nil {
-		return
-	}
-	// 因为tendisplus 底层 rocksdb 引擎会做升级,提取key必须依赖每个版本本身自带的ldb工具
-	ldbTendisPath := filepath.Join(consts.UsrLocal, "redis", "bin", "ldb_tendis")
-	ldbTendplusPath := filepath.Join(job.saveDir, "ldb_tendisplus")
-	if util.FileExists(ldbTendisPath) {
-		cpCmd = fmt.Sprintf("cp %s %s", ldbTendisPath, ldbTendplusPath)
-		job.runtime.Logger.Info(cpCmd)
-		_, err = util.RunBashCmd(cpCmd, "", nil, 1*time.Minute)
-		if err != nil {
-			return
-		}
-	}
-	if !util.FileExists(consts.TendisDataCheckBin) {
-		err = fmt.Errorf("%s not exists", consts.TendisDataCheckBin)
-		job.runtime.Logger.Error(err.Error())
-		return
-	}
-	job.dataCheckTool = consts.TendisDataCheckBin
-	job.dataRepairTool = consts.RedisDiffKeysRepairerBin
-	return nil
-}
-
 // Retry times
 func (job *RedisDtsDataCheck) Retry() uint {
 	return 2
@@ -276,30 +268,47 @@ func (job *RedisDtsDataCheck) Rollback() error {
 
 // RedisInsDtsDataCheckAndRepairTask redis实例数据校验与数据修复task
 type RedisInsDtsDataCheckAndRepairTask struct {
-	keyPatternTask *RedisInsKeyPatternTask
-	datacheckJob   *RedisDtsDataCheck
-	DiffKeysCnt    uint64 `json:"diffKeysCnt"`
-	HotKeysCnt     uint64 `json:"hotKeysCnt"`
-	Err            error  `json:"err"`
+	IP            string `json:"ip"`
+	Port          int    `json:"port"`
+	SegStart      int    `json:"segStart"`
+	SegEnd        int    `json:"segEnd"`
+	DiffKeysCnt   uint64 `json:"diffKeysCnt"`
+	HotKeysCnt    uint64 `json:"hotKeysCnt"`
+	Err           error  `json:"err"`
+	datacheckJob  *RedisDtsDataCheck
+	whiteReg      *regexp.Regexp
+	blackReg      *regexp.Regexp
+	srcCli        *myredis.RedisClient
+	dstCli        *myredis.RedisClient
+	comparer      *dtsKeysComparer
+	segFilterable bool // 是否需要按segment过滤key(源为twemproxy架构)
 }
 
 // NewRedisInsDtsDataCheckAndRepairTask new
 func NewRedisInsDtsDataCheckAndRepairTask(ip string, portAndSeg PortAndSegment, job *RedisDtsDataCheck) (
 	task *RedisInsDtsDataCheckAndRepairTask, err error) {
-	task = &RedisInsDtsDataCheckAndRepairTask{}
-	task.datacheckJob = job
-	task.keyPatternTask, err = NewRedisInsKeyPatternTask(
-		job.params.BkBizID,
-		job.params.SrcClusterAddr,
-		ip,
-		portAndSeg.Port,
-		job.params.SrcRedisPassword,
-		job.saveDir,
-		job.runtime,
-		"", "", "", "", "", "", // FileServer相关参数不需要
-		job.params.KeyWhiteRegex, job.params.KeyBlackRegex,
-		false, 0, 0, 0, // key删除相关参数不需要
-		portAndSeg.SegmentStart, portAndSeg.SegmentEnd, nil)
+	task = &RedisInsDtsDataCheckAndRepairTask{
+		IP:           ip,
+		Port:         portAndSeg.Port,
+		SegStart:     portAndSeg.SegmentStart,
+		SegEnd:       portAndSeg.SegmentEnd,
+		datacheckJob: job,
+	}
+	task.whiteReg, err = keyPatternsToRegexp(job.params.KeyWhiteRegex)
+	if err != nil {
+		job.runtime.Logger.Error(err.Error())
+		return nil, err
+	}
+	task.blackReg, err = keyPatternsToRegexp(job.params.KeyBlackRegex)
+	if err != nil {
+		job.runtime.Logger.Error(err.Error())
+		return nil, err
+	}
+	// 单个实例只负责 [segStart,segEnd] 范围的key,为完整范围时无需过滤
+	if task.SegStart >= 0 && task.SegEnd <= consts.TwemproxyMaxSegment && task.SegStart < task.SegEnd &&
+		!(task.SegStart == 0 && task.SegEnd == consts.TwemproxyMaxSegment) {
+		task.segFilterable = true
+	}
 	return
 }
 
@@ -308,7 +317,7 @@ func (task *RedisInsDtsDataCheckAndRepairTask) getSaveDir() string {
 }
 
 func (task *RedisInsDtsDataCheckAndRepairTask) getSrcRedisAddr() string {
-	return task.keyPatternTask.IP + ":" + strconv.Itoa(task.keyPatternTask.Port)
+	return task.IP + ":" + strconv.Itoa(task.Port)
 }
 
 func (task *RedisInsDtsDataCheckAndRepairTask) getSrcRedisPassword() string {
@@ -328,18 +337,18 @@ func (task *RedisInsDtsDataCheckAndRepairTask) getLogger() *logger.Logger {
 }
 
 func (task *RedisInsDtsDataCheckAndRepairTask) getDataCheckDiffKeysFile() string {
-	basename := fmt.Sprintf("dts_datacheck_diff_keys_%s_%d", task.keyPatternTask.IP, task.keyPatternTask.Port)
+	basename := fmt.Sprintf("dts_datacheck_diff_keys_%s_%d", task.IP, task.Port)
 	return filepath.Join(task.getSaveDir(), basename)
 }
 
 func (task *RedisInsDtsDataCheckAndRepairTask) getRepairHotKeysFile() string {
-	basename := fmt.Sprintf("dts_repair_hot_keys_%s_%d", task.keyPatternTask.IP, task.keyPatternTask.Port)
+	basename := fmt.Sprintf("dts_repair_hot_keys_%s_%d", task.IP, task.Port)
 	return filepath.Join(task.getSaveDir(), basename)
 }
 
 func (task *RedisInsDtsDataCheckAndRepairTask) isClusterEnabled() (enabled bool) {
 	var cli01 *myredis.RedisClient
-	cli01, task.Err = myredis.NewRedisClientWithTimeout(task.getSrcRedisAddr(), task.keyPatternTask.Password, 0,
+	cli01, task.Err = myredis.NewRedisClientWithTimeout(task.getSrcRedisAddr(), task.getSrcRedisPassword(), 0,
 		consts.TendisTypeRedisInstance, 10*time.Second)
 	if task.Err != nil {
 		return false
@@ -371,6 +380,221 @@ func (task *RedisInsDtsDataCheckAndRepairTask) tryFileLock(lockFile string, time
 	return locked, flockP
 }
 
+// newDstClient 连接目的集群
+// 目的集群为 proxy 时使用普通client; 为原生 redis cluster(cluster_enabled:1)时使用 cluster client,按key路由到各节点
+func (task *RedisInsDtsDataCheckAndRepairTask) newDstClient() (cli *myredis.RedisClient, err error) {
+	cli, err = myredis.NewRedisClientWithTimeout(task.getDstRedisAddr(), task.getDstRedisPassword(), 0,
+		consts.TendisTypeRedisInstance, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	// twemproxy 不支持 info 命令,报错时按 proxy 处理
+	if clusterEnabled, _ := cli.IsClusterEnabled(); !clusterEnabled {
+		return cli, nil
+	}
+	cli.Close()
+	task.getLogger().Info("dstAddr:%s is redis cluster,use cluster client", task.getDstRedisAddr())
+	return myredis.NewRedisClusterClientWithTimeout(task.getDstRedisAddr(), task.getDstRedisPassword(), 30*time.Second)
+}
+
+// newComparer 连接源redis与目的集群,qps为单实例每秒命令数上限
+func (task *RedisInsDtsDataCheckAndRepairTask) newComparer(qps int) {
+	task.srcCli, task.Err = myredis.NewRedisClientWithTimeout(task.getSrcRedisAddr(), task.getSrcRedisPassword(), 0,
+		consts.TendisTypeRedisInstance, 30*time.Second)
+	if task.Err != nil {
+		return
+	}
+	task.dstCli, task.Err = task.newDstClient()
+	if task.Err != nil {
+		task.srcCli.Close()
+		return
+	}
+	var dstCli redis.UniversalClient = task.dstCli.InstanceClient
+	if task.dstCli.ClusterClient != nil {
+		dstCli = task.dstCli.ClusterClient
+	}
+	task.comparer = &dtsKeysComparer{
+		srcCli:     task.srcCli.InstanceClient,
+		dstCli:     dstCli,
+		srcLimiter: newQPSLimiter(qps),
+		dstLimiter: newQPSLimiter(qps),
+	}
+}
+
+func (task *RedisInsDtsDataCheckAndRepairTask) closeComparer() {
+	if task.srcCli != nil {
+		task.srcCli.Close()
+	}
+	if task.dstCli != nil {
+		task.dstCli.Close()
+	}
+}
+
+// isKeyNeedCheck key是否需要校验: 匹配白名单、不匹配黑名单、属于本实例的segment
+func (task *RedisInsDtsDataCheckAndRepairTask) isKeyNeedCheck(key string, clusterEnabled bool) bool {
+	if task.whiteReg != nil && !task.whiteReg.MatchString(key) {
+		return false
+	}
+	if task.blackReg != nil && task.blackReg.MatchString(key) {
+		return false
+	}
+	if task.segFilterable && !clusterEnabled {
+		seg := twemproxySegment(key, task.datacheckJob.params.SrcHashTag)
+		if seg < task.SegStart || seg > task.SegEnd {
+			return false
+		}
+	}
+	return true
+}
+
+// writeDiffKeys 写入不一致的key
+func writeDiffKeys(writer *bufio.Writer, diffs []*DtsDiffKey) (err error) {
+	for _, diff := range diffs {
+		if _, err = writer.WriteString(diff.ToString() + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDiffKeysFile 按批读取diff文件
+func readDiffKeysFile(diffFile string, batchSize int, fn func(items []*DtsDiffKey) error) (err error) {
+	fp, err := os.Open(diffFile)
+	if err != nil {
+		return fmt.Errorf("open file:%s fail,err:%v", diffFile, err)
+	}
+	defer fp.Close()
+	scanner := bufio.NewScanner(fp)
+	scanner.Buffer(make([]byte, 64*1024), 512*1024*1024)
+	batch := make([]*DtsDiffKey, 0, batchSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		item, err := ParseDtsDiffKey(line)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, item)
+		if len(batch) >= batchSize {
+			if err = fn(batch); err != nil {
+				return err
+			}
+			batch = make([]*DtsDiffKey, 0, batchSize)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("read file:%s fail,err:%v", diffFile, err)
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+func diffRawKeys(items []*DtsDiffKey) []string {
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item.RawKey())
+	}
+	return keys
+}
+
+// scanAndCompare scan源redis并与目的集群比较,不一致的key写入 candidateFile
+func (task *RedisInsDtsDataCheckAndRepairTask) scanAndCompare(candidateFile string, clusterEnabled bool) {
+	fp, err := os.OpenFile(candidateFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		task.Err = fmt.Errorf("open file:%s fail,err:%v", candidateFile, err)
+		task.getLogger().Error(task.Err.Error())
+		return
+	}
+	defer fp.Close()
+	writer := bufio.NewWriter(fp)
+
+	var cursor uint64
+	var keys []string
+	var scannedCnt, checkedCnt, candidateCnt uint64
+	batch := make([]string, 0, dtsCheckBatchSize)
+	lastPrint := time.Now()
+	checkBatch := func() error {
+		diffs, err := task.comparer.Compare(batch)
+		if err != nil {
+			return err
+		}
+		checkedCnt += uint64(len(batch))
+		candidateCnt += uint64(len(diffs))
+		batch = batch[:0]
+		return writeDiffKeys(writer, diffs)
+	}
+	for {
+		task.comparer.srcLimiter.Wait(1)
+		keys, cursor, task.Err = task.srcCli.Scan("*", cursor, dtsCheckScanCount)
+		if task.Err != nil {
+			return
+		}
+		scannedCnt += uint64(len(keys))
+		for _, key := range keys {
+			if !task.isKeyNeedCheck(key, clusterEnabled) {
+				continue
+			}
+			batch = append(batch, key)
+			if len(batch) >= dtsCheckBatchSize {
+				if task.Err = checkBatch(); task.Err != nil {
+					task.getLogger().Error("srcAddr:%s data check fail,err:%v", task.getSrcRedisAddr(), task.Err)
+					return
+				}
+			}
+		}
+		if time.Since(lastPrint) > dtsProgressPrintInterval {
+			lastPrint = time.Now()
+			task.getLogger().Info("srcAddr:%s data check progress,scanned:%d checked:%d diff:%d",
+				task.getSrcRedisAddr(), scannedCnt, checkedCnt, candidateCnt)
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+	if task.Err = checkBatch(); task.Err != nil {
+		task.getLogger().Error("srcAddr:%s data check fail,err:%v", task.getSrcRedisAddr(), task.Err)
+		return
+	}
+	if task.Err = writer.Flush(); task.Err != nil {
+		task.Err = fmt.Errorf("flush file:%s fail,err:%v", candidateFile, task.Err)
+		task.getLogger().Error(task.Err.Error())
+		return
+	}
+	task.getLogger().Info("srcAddr:%s first round data check done,scanned:%d checked:%d diff:%d",
+		task.getSrcRedisAddr(), scannedCnt, checkedCnt, candidateCnt)
+}
+
+// recheckDiffKeys 对首轮不一致的key再次校验,排除同步延迟导致的不一致,结果写入diff文件
+func (task *RedisInsDtsDataCheckAndRepairTask) recheckDiffKeys(candidateFile string) {
+	fp, err := os.OpenFile(task.getDataCheckDiffKeysFile(), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		task.Err = fmt.Errorf("open file:%s fail,err:%v", task.getDataCheckDiffKeysFile(), err)
+		task.getLogger().Error(task.Err.Error())
+		return
+	}
+	defer fp.Close()
+	writer := bufio.NewWriter(fp)
+	task.Err = readDiffKeysFile(candidateFile, dtsCheckBatchSize, func(items []*DtsDiffKey) error {
+		diffs, err := task.comparer.Compare(diffRawKeys(items))
+		if err != nil {
+			return err
+		}
+		return writeDiffKeys(writer, diffs)
+	})
+	if task.Err != nil {
+		task.getLogger().Error("srcAddr:%s recheck diff keys fail,err:%v", task.getSrcRedisAddr(), task.Err)
+		return
+	}
+	if task.Err = writer.Flush(); task.Err != nil {
+		task.Err = fmt.Errorf("flush file:%s fail,err:%v", task.getDataCheckDiffKeysFile(), task.Err)
+		task.getLogger().Error(task.Err.Error())
+	}
+}
+
 func (task *RedisInsDtsDataCheckAndRepairTask) getDataCheckRet() {
 	// 获取不一致key信息
 	var msg string
@@ -379,6 +603,7 @@ func (task *RedisInsDtsDataCheckAndRepairTask) getDataCheckRet() {
 	diffFileStat, task.Err = os.Stat(task.getDataCheckDiffKeysFile())
 	if task.Err != nil && os.IsNotExist(task.Err) == true {
 		// 没有不一致的key
+		task.Err = nil
 		msg = fmt.Sprintf("srcAddr:%s dstAddr:%s dts data check success no diff keys",
 			task.getSrcRedisAddr(), task.getDstRedisAddr())
 		task.getLogger().Info(msg)
@@ -408,7 +633,6 @@ func (task *RedisInsDtsDataCheckAndRepairTask) getDataCheckRet() {
 	var predix string = fmt.Sprintf("srcRedisAddr:%s,dstRedisAddr:%s,dts data check fail,diff keys for example:",
 		task.getSrcRedisAddr(), task.getDstRedisAddr())
 	if task.DiffKeysCnt <= 20 {
-		predix = "all diffKeys:"
 		predix = fmt.Sprintf("srcRedisAddr:%s,dstRedisAddr:%s,dts data check fail,all diffKeys:",
 			task.getSrcRedisAddr(), task.getDstRedisAddr())
 	}
@@ -456,72 +680,19 @@ func (task *RedisInsDtsDataCheckAndRepairTask) getDataRepairRet() {
 	return
 }
 
-// RunCmdAndWatchLog 执行命令并不断打印命令日志
-func (task *RedisInsDtsDataCheckAndRepairTask) RunCmdAndWatchLog(myCmd, logCmd string) {
-	var errBuffer bytes.Buffer
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, "bash", "-c", myCmd)
-	stdout, _ := cmd.StdoutPipe()
-	cmd.Stderr = &errBuffer
-
-	if task.Err = cmd.Start(); task.Err != nil {
-		task.Err = fmt.Errorf("RedisInsDtsDataCheckAndRepairTask cmd.Start fail,err:%v,cmd:%s", task.Err, logCmd)
-		task.getLogger().Error(task.Err.Error())
-		return
-	}
-
-	scanner := bufio.NewScanner(stdout)
-	scanner.Split(bufio.ScanLines)
-	for scanner.Scan() {
-		// 不断打印进度
-		line := scanner.Text()
-		if strings.Contains(line, `"level":"error"`) == true {
-			task.Err = errors.New(line)
-			task.getLogger().Error(task.Err.Error())
-			continue
-		}
-		line = line + ";" + task.getSrcRedisAddr()
-		task.getLogger().Info(line)
-	}
-	task.Err = scanner.Err()
-	if task.Err != nil {
-		task.getLogger().Error(task.Err.Error())
-		return
-	}
-
-	if task.Err = cmd.Wait(); task.Err != nil {
-		task.Err = fmt.Errorf("RedisInsDtsDataCheckAndRepairTask cmd.Wait fail,err:%v", task.Err)
-		task.getLogger().Error(task.Err.Error())
-		return
-	}
-	errStr := errBuffer.String()
-	errStr = strings.TrimSpace(errStr)
-	if len(errStr) > 0 {
-		task.Err = fmt.Errorf("RedisInsDtsDataCheckAndRepairTask fail,err:%s", errStr)
-		task.getLogger().Error(task.Err.Error())
-		return
-	}
-}
-
-// KeyPatternAndDataCheck key提取和数据校验
-func (task *RedisInsDtsDataCheckAndRepairTask) KeyPatternAndDataCheck() {
-	var extraOptsBuilder strings.Builder
-	var checkMode string
+// RunDataCheck 数据校验
+// scan 源redis(按segment、key白名单/黑名单过滤),pipeline比较目的集群中key的 类型、ttl、长度、值摘要;
+// 首轮不一致的key等待一段时间后复查,仍不一致的写入diff文件(每行一个json)
+func (task *RedisInsDtsDataCheckAndRepairTask) RunDataCheck() {
 	var locked bool
 	var flockP *flock.Flock
 	clusterEnabled := task.isClusterEnabled()
 	if task.Err != nil {
 		return
 	}
-	if clusterEnabled {
-		extraOptsBuilder.WriteString(" --is-src-cluster-replicate ")
-	}
 
 	// 尝试获取文件锁,确保单个redis同一时间只有一个进程在进行数据校验
-	lockFile := filepath.Join(task.getSaveDir(), fmt.Sprintf("lock_dtsdatacheck.%s.%d",
-		task.keyPatternTask.IP, task.keyPatternTask.Port))
+	lockFile := filepath.Join(task.getSaveDir(), fmt.Sprintf("lock_dtsdatacheck.%s.%d", task.IP, task.Port))
 	locked, flockP = task.tryFileLock(lockFile, 24*time.Hour)
 	if task.Err != nil {
 		return
@@ -531,59 +702,32 @@ func (task *RedisInsDtsDataCheckAndRepairTask) KeyPatternAndDataCheck() {
 	}
 	defer flockP.Unlock()
 
-	// 源redis 属于dbm管理,则执行key提取,而后执行数据校验
-	if consts.IsDtsTypeSrcClusterBelongDbm(task.datacheckJob.params.DtsCopyType) {
-		checkMode = consts.DtsDataCheckByKeysFileMode
-		// 获取key
-		task.keyPatternTask.newConnect()
-		if task.keyPatternTask.Err != nil {
-			task.Err = task.keyPatternTask.Err
-			return
-		}
-		task.keyPatternTask.GetTendisKeys()
-		if task.keyPatternTask.Err != nil {
-			task.Err = task.keyPatternTask.Err
-			return
-		}
-		if task.keyPatternTask.TendisType == consts.TendisTypeTendisplusInsance {
-			// 合并 kvstore keys临时文件
-			task.keyPatternTask.mergeTendisplusDbFile()
-			if task.keyPatternTask.Err != nil {
-				task.Err = task.keyPatternTask.Err
-				return
-			}
-		}
-		extraOptsBuilder.WriteString(" --keys-file=" + task.keyPatternTask.ResultFile + " --thread-cnt=200 ")
-	} else {
-		// 源redis 属于用户自建,则通过scan方式获取key,而后执行数据校验
-		checkMode = consts.DtsDataCheckByScanMode
-		extraOptsBuilder.WriteString(fmt.Sprintf(" --match-pattern=%q --scan-count=20000  --thread-cnt=200 ",
-			task.datacheckJob.params.KeyWhiteRegex))
-	}
-	// 数据校验
-	dataCheckCmd := fmt.Sprintf(
-		`cd %s && %s %s --src-addr=%s --src-password=%s --dst-addr=%s --dst-password=%s --result-file=%s --ticker=120 %s`,
-		task.datacheckJob.saveDir, task.datacheckJob.dataCheckTool, checkMode,
-		task.getSrcRedisAddr(), task.getSrcRedisPassword(),
-		task.getDstRedisAddr(), task.getDstRedisPassword(),
-		task.getDataCheckDiffKeysFile(), extraOptsBuilder.String())
-	logCmd := fmt.Sprintf(
-		`cd %s && %s %s --src-addr=%s --src-password=xxxx --dst-addr=%s --dst-password=xxxx --result-file=%s --ticker=120 %s`,
-		task.datacheckJob.saveDir, task.datacheckJob.dataCheckTool, checkMode,
-		task.getSrcRedisAddr(), task.getDstRedisAddr(),
-		task.getDataCheckDiffKeysFile(), extraOptsBuilder.String())
-	task.getLogger().Info(logCmd)
-
-	task.RunCmdAndWatchLog(dataCheckCmd, logCmd)
+	task.newComparer(task.datacheckJob.params.CheckQPS)
+	if task.Err != nil {
+		return
+	}
+	defer task.closeComparer()
+
+	task.getLogger().Info("srcAddr:%s dstAddr:%s start data check,segment:%d-%d,clusterEnabled:%v,qps:%d",
+		task.getSrcRedisAddr(), task.getDstRedisAddr(), task.SegStart, task.SegEnd, clusterEnabled,
+		task.datacheckJob.params.CheckQPS)
+	candidateFile := task.getDataCheckDiffKeysFile() + ".tmp"
+	defer os.Remove(candidateFile)
+	task.scanAndCompare(candidateFile, clusterEnabled)
+	if task.Err != nil {
+		return
+	}
+	time.Sleep(dtsRecheckWait)
+	task.recheckDiffKeys(candidateFile)
 	if task.Err != nil {
 		return
 	}
 	// 数据校验结果
 	task.getDataCheckRet()
-	return
 }
 
 // RunDataRepair 执行数据修复
+// 读取diff文件,复查后以源redis为准重写目的集群中的key,修复后仍不一致的key记为热key
 func (task *RedisInsDtsDataCheckAndRepairTask) RunDataRepair() {
 	var diffKeysCnt uint64
 	var locked bool
@@ -603,8 +747,7 @@ func (task *RedisInsDtsDataCheckAndRepairTask) RunDataRepair() {
 	}
 
 	// 尝试获取文件锁,确保单个redis同一时间只有一个进程在进行数据修复
-	lockFile := filepath.Join(task.getSaveDir(), fmt.Sprintf("lock_dtsdatarepair.%s.%d",
-		task.keyPatternTask.IP, task.keyPatternTask.Port))
+	lockFile := filepath.Join(task.getSaveDir(), fmt.Sprintf("lock_dtsdatarepair.%s.%d", task.IP, task.Port))
 	locked, flockP = task.tryFileLock(lockFile, 5*time.Hour)
 	if task.Err != nil {
 		return
@@ -614,36 +757,64 @@ func (task *RedisInsDtsDataCheckAndRepairTask) RunDataRepair() {
 	}
 	defer flockP.Unlock()
 
-	var extraOptsBuilder strings.Builder
-	clusterEnabled := task.isClusterEnabled()
+	task.newComparer(task.datacheckJob.params.RepairQPS)
 	if task.Err != nil {
 		return
 	}
-	if clusterEnabled {
-		extraOptsBuilder.WriteString(" --is-src-cluster-replicate ")
-	}
-
-	repairCmd := fmt.Sprintf(
-		`cd %s && %s --src-addr=%s --src-password=%s \
-		--dest-addr=%s --dest-password=%s  --diff-keys-file=%s --hot-keys-file=%s %s`,
-		task.getSaveDir(), task.datacheckJob.dataRepairTool,
-		task.getSrcRedisAddr(), task.getSrcRedisPassword(),
-		task.getDstRedisAddr(), task.getDstRedisPassword(),
-		task.getDataCheckDiffKeysFile(), task.getRepairHotKeysFile(),
-		extraOptsBuilder.String())
-	logCmd := fmt.Sprintf(
-		`cd %s && %s --src-addr=%s --src-password=xxxx \
-		--dest-addr=%s --dest-password=xxxx  --diff-keys-file=%s --hot-keys-file=%s %s`,
-		task.getSaveDir(), task.datacheckJob.dataRepairTool,
-		task.getSrcRedisAddr(), task.getDstRedisAddr(),
-		task.getDataCheckDiffKeysFile(), task.getRepairHotKeysFile(),
-		extraOptsBuilder.String())
-
-	task.getLogger().Info(logCmd)
-	task.RunCmdAndWatchLog(repairCmd, logCmd)
+	defer task.closeComparer()
+
+	hotFp, err := os.OpenFile(task.getRepairHotKeysFile(), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		task.Err = fmt.Errorf("open file:%s fail,err:%v", task.getRepairHotKeysFile(), err)
+		task.getLogger().Error(task.Err.Error())
+		return
+	}
+	defer hotFp.Close()
+	hotWriter := bufio.NewWriter(hotFp)
+
+	var repairedCnt uint64
+	task.getLogger().Info("srcAddr:%s dstAddr:%s start data repair,diff keys:%d,qps:%d",
+		task.getSrcRedisAddr(), task.getDstRedisAddr(), diffKeysCnt, task.datacheckJob.params.RepairQPS)
+	task.Err = readDiffKeysFile(task.getDataCheckDiffKeysFile(), dtsCheckBatchSize, func(items []*DtsDiffKey) error {
+		// 修复前复查,已一致的key无需修复
+		diffs, err := task.comparer.Compare(diffRawKeys(items))
+		if err != nil {
+			return err
+		}
+		var hotDiffs []*DtsDiffKey
+		for _, diff := range diffs {
+			if err = task.comparer.RepairKey(diff.RawKey()); err != nil {
+				task.getLogger().Warn("srcAddr:%s repair key:%s fail,err:%v", task.getSrcRedisAddr(), diff.Key, err)
+				hotDiffs = append(hotDiffs, diff)
+				continue
+			}
+			repairedCnt++
+		}
+		// 修复后再次比较,仍不一致(写入频繁)的key记为热key
+		diffs, err = task.comparer.Compare(diffRawKeys(diffs))
+		if err != nil {
+			return err
+		}
+		hotKeys := make(map[string]bool, len(hotDiffs))
+		for _, diff := range hotDiffs {
+			hotKeys[diff.RawKey()] = true
+		}
+		for _, diff := range diffs {
+			if !hotKeys[diff.RawKey()] {
+				hotDiffs = append(hotDiffs, diff)
+			}
+		}
+		return writeDiffKeys(hotWriter, hotDiffs)
+	})
 	if task.Err != nil {
+		task.getLogger().Error("srcAddr:%s data repair fail,err:%v", task.getSrcRedisAddr(), task.Err)
+		return
+	}
+	if task.Err = hotWriter.Flush(); task.Err != nil {
+		task.Err = fmt.Errorf("flush file:%s fail,err:%v", task.getRepairHotKeysFile(), task.Err)
+		task.getLogger().Error(task.Err.Error())
 		return
 	}
+	task.getLogger().Info("srcAddr:%s data repair done,repaired keys:%d", task.getSrcRedisAddr(), repairedCnt)
 	task.getDataRepairRet()
-	return
 }
@@ -0,0 +1,693 @@
+package atomredis
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"dbm-services/redis/db-tools/dbactuator/pkg/consts"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 数据校验不一致原因
+const (
+	DtsDiffReasonDstMissing = "dst_missing" // 目的集群key不存在
+	DtsDiffReasonTypeDiff   = "type_diff"   // 数据类型不一致
+	DtsDiffReasonTTLDiff    = "ttl_diff"    // 过期时间不一致
+	DtsDiffReasonLenDiff    = "len_diff"    // 元素个数/string长度不一致
+	DtsDiffReasonValueDiff  = "value_diff"  // 值摘要不一致
+)
+
+const (
+	dtsCheckScanCount        = 1000             // 源redis scan count
+	dtsCheckBatchSize        = 200              // 单次pipeline校验的key个数
+	dtsDigestBatchElements   = 20000            // 单次pipeline获取值的元素个数上限
+	dtsDigestMaxElements     = 5000             // 元素个数超过该值的key只比较长度,不比较值
+	dtsTTLToleranceMs        = 30 * 1000        // 源与目的ttl允许的误差
+	dtsRecheckWait           = 10 * time.Second // 首轮校验后等待一段时间再复查,排除同步延迟导致的不一致
+	dtsRepairScanCount       = 1000             // 修复时 hscan/sscan/zscan/lrange 单次元素个数
+	dtsDefaultCheckQPS       = 10000
+	dtsDefaultRepairQPS      = 2000
+	dtsProgressPrintInterval = 120 * time.Second
+	dtsPipelineRetryTimes    = 3           // pipeline中返回错误的命令重试次数
+	dtsPipelineRetryWait     = time.Second // 重试间隔
+)
+
+// DtsDiffKey 不一致的key,diff文件中每行一个json
+type DtsDiffKey struct {
+	Key       string `json:"key"`
+	KeyBase64 bool   `json:"key_base64,omitempty"` // key不是合法utf8时,key字段为base64编码
+	Reason    string `json:"reason"`
+	SrcType   string `json:"src_type"`
+	DstType   string `json:"dst_type"`
+	SrcTTL    int64  `json:"src_ttl_ms"`
+	DstTTL    int64  `json:"dst_ttl_ms"`
+	SrcLen    int64  `json:"src_len"`
+	DstLen    int64  `json:"dst_len"`
+	rawKey    string
+}
+
+// NewDtsDiffKey new
+func NewDtsDiffKey(key, reason string, src, dst dtsKeyMeta) *DtsDiffKey {
+	item := &DtsDiffKey{
+		Reason:  reason,
+		SrcType: src.Type,
+		DstType: dst.Type,
+		SrcTTL:  src.PTTL,
+		DstTTL:  dst.PTTL,
+		SrcLen:  src.Len,
+		DstLen:  dst.Len,
+		rawKey:  key,
+	}
+	if utf8.ValidString(key) {
+		item.Key = key
+	} else {
+		item.Key = base64.StdEncoding.EncodeToString([]byte(key))
+		item.KeyBase64 = true
+	}
+	return item
+}
+
+// RawKey 原始key名
+func (d *DtsDiffKey) RawKey() string {
+	return d.rawKey
+}
+
+// ToString json格式
+func (d *DtsDiffKey) ToString() string {
+	ret, _ := json.Marshal(d)
+	return string(ret)
+}
+
+// ParseDtsDiffKey 解析diff文件中的一行
+func ParseDtsDiffKey(line string) (item *DtsDiffKey, err error) {
+	item = &DtsDiffKey{}
+	err = json.Unmarshal([]byte(line), item)
+	if err != nil {
+		return nil, fmt.Errorf("diff line:%s json.Unmarshal fail,err:%v", line, err)
+	}
+	item.rawKey = item.Key
+	if item.KeyBase64 {
+		raw, err := base64.StdEncoding.DecodeString(item.Key)
+		if err != nil {
+			return nil, fmt.Errorf("diff line:%s decode base64 key fail,err:%v", line, err)
+		}
+		item.rawKey = string(raw)
+	}
+	return item, nil
+}
+
+// dtsKeyMeta key的类型、ttl、长度
+type dtsKeyMeta struct {
+	Type string
+	PTTL int64
+	Len  int64
+}
+
+// exists key是否存在
+func (m dtsKeyMeta) exists() bool {
+	return m.Type != "" && m.Type != "none" && m.PTTL != -2
+}
+
+// dtsLenCmds 各类型获取长度的命令
+var dtsLenCmds = map[string]string{
+	"string": "strlen",
+	"hash":   "hlen",
+	"list":   "llen",
+	"set":    "scard",
+	"zset":   "zcard",
+	"stream": "xlen",
+}
+
+// qpsLimiter 按秒限制命令数
+type qpsLimiter struct {
+	qps         int
+	used        int
+	windowStart time.Time
+	mu          sync.Mutex
+}
+
+func newQPSLimiter(qps int) *qpsLimiter {
+	return &qpsLimiter{qps: qps}
+}
+
+// Wait 获取n个命令的配额,配额不足时sleep到下一秒
+func (l *qpsLimiter) Wait(n int) {
+	if l == nil || l.qps <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for {
+		now := time.Now()
+		if now.Sub(l.windowStart) >= time.Second {
+			l.windowStart = now
+			l.used = 0
+		}
+		// 单批命令数大于qps时,独占一个窗口
+		if l.used == 0 || l.used+n <= l.qps {
+			l.used += n
+			return
+		}
+		time.Sleep(l.windowStart.Add(time.Second).Sub(now))
+	}
+}
+
+// twemproxySegment 计算key在twemproxy中的segment,与 twemproxy fnv1a_64 + modhash 算法一致
+// twemproxy 中 hash 值为 uint32,且按 signed char 逐字节计算
+func twemproxySegment(key string, hashTag bool) int {
+	if hashTag {
+		if start := strings.IndexByte(key, '{'); start >= 0 {
+			if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+				key = key[start+1 : start+1+end]
+			}
+		}
+	}
+	var hash uint32 = 0x84222325
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(int32(int8(key[i])))
+		hash *= 0x000001b3
+	}
+	return int(hash % uint32(consts.TwemproxyMaxSegment+1))
+}
+
+// keyPatternsToRegexp 将多行key模式转换为正则,与key提取(getSafeRegexPattern)规则一致:
+// 每行 '.' 与 '|' 按字面匹配, '*' 匹配任意字符, 其余正则语法(如 $、[0-9]、+)保留; 每行均为前缀匹配
+func keyPatternsToRegexp(patterns string) (reg *regexp.Regexp, err error) {
+	if patterns == "*" || patterns == ".*" || patterns == "^.*$" {
+		return nil, nil
+	}
+	var parts []string
+	scanner := bufio.NewScanner(strings.NewReader(patterns))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		line = strings.ReplaceAll(line, "|", "\\|")
+		line = strings.ReplaceAll(line, ".", "\\.")
+		line = strings.ReplaceAll(line, "*", ".*")
+		if !strings.HasPrefix(line, "^") {
+			line = "^" + line
+		}
+		parts = append(parts, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("keyPatternsToRegexp scanner.Err:%v,patterns:%s", err, patterns)
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	reg, err = regexp.Compile(strings.Join(parts, "|"))
+	if err != nil {
+		return nil, fmt.Errorf("keyPatternsToRegexp compile fail,err:%v,patterns:%s", err, patterns)
+	}
+	return reg, nil
+}
+
+// clientAddr 客户端地址,用于错误信息
+func clientAddr(cli redis.UniversalClient) string {
+	switch c := cli.(type) {
+	case *redis.Client:
+		return c.Options().Addr
+	case *redis.ClusterClient:
+		return strings.Join(c.Options().Addrs, ",")
+	}
+	return ""
+}
+
+// runPipeline 执行一次pipeline; 连接类错误直接返回,命令本身返回的错误(redis.Error)由调用方检查
+func runPipeline(cli redis.UniversalClient, args [][]interface{}) ([]*redis.Cmd, error) {
+	ctx := context.TODO()
+	pipe := cli.Pipeline()
+	cmds := make([]*redis.Cmd, 0, len(args))
+	for _, arg := range args {
+		cmds = append(cmds, pipe.Do(ctx, arg...))
+	}
+	_, _ = pipe.Exec(ctx)
+	for _, cmd := range cmds {
+		err := cmd.Err()
+		if err == nil || err == redis.Nil {
+			continue
+		}
+		if _, ok := err.(redis.Error); ok {
+			continue
+		}
+		return nil, fmt.Errorf("redis:%s pipeline exec fail,err:%v", clientAddr(cli), err)
+	}
+	return cmds, nil
+}
+
+// execPipeline 执行pipeline并检查每个命令的结果
+// 返回 redis.Error 的命令(如 LOADING、BUSY、key类型在两次命令间发生变化)单独重试,重试后仍失败则返回错误;
+// 命令失败时未执行,重试不会重复写入; redis.Nil 不算错误
+func execPipeline(cli redis.UniversalClient, limiter *qpsLimiter, args [][]interface{}) ([]*redis.Cmd, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	limiter.Wait(len(args))
+	cmds, err := runPipeline(cli, args)
+	if err != nil {
+		return nil, err
+	}
+	for retry := 0; ; retry++ {
+		var failedIdx []int
+		for i, cmd := range cmds {
+			if _, ok := cmd.Err().(redis.Error); ok {
+				failedIdx = append(failedIdx, i)
+			}
+		}
+		if len(failedIdx) == 0 {
+			return cmds, nil
+		}
+		first := cmds[failedIdx[0]]
+		if retry >= dtsPipelineRetryTimes {
+			return nil, fmt.Errorf("redis:%s %d commands fail after %d retries,first:%s,err:%v",
+				clientAddr(cli), len(failedIdx), retry, dtsCmdName(first), first.Err())
+		}
+		time.Sleep(dtsPipelineRetryWait)
+		retryArgs := make([][]interface{}, 0, len(failedIdx))
+		for _, i := range failedIdx {
+			retryArgs = append(retryArgs, args[i])
+		}
+		limiter.Wait(len(retryArgs))
+		retryCmds, err := runPipeline(cli, retryArgs)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range failedIdx {
+			cmds[i] = retryCmds[j]
+		}
+	}
+}
+
+// dtsCmdName 命令名与第一个参数(通常为key),用于错误信息
+func dtsCmdName(cmd *redis.Cmd) string {
+	args := cmd.Args()
+	if len(args) > 2 {
+		args = args[:2]
+	}
+	return fmt.Sprint(args...)
+}
+
+// fetchKeysMeta 批量获取key的类型、ttl、长度
+func fetchKeysMeta(cli redis.UniversalClient, limiter *qpsLimiter, keys []string) ([]dtsKeyMeta, error) {
+	metas := make([]dtsKeyMeta, len(keys))
+	args := make([][]interface{}, 0, 2*len(keys))
+	for _, key := range keys {
+		args = append(args, []interface{}{"type", key}, []interface{}{"pttl", key})
+	}
+	cmds, err := execPipeline(cli, limiter, args)
+	if err != nil {
+		return nil, err
+	}
+	args = args[:0]
+	lenIdx := make([]int, 0, len(keys))
+	for i, key := range keys {
+		metas[i].Type, _ = cmds[2*i].Text()
+		metas[i].PTTL, _ = cmds[2*i+1].Int64()
+		if !metas[i].exists() {
+			continue
+		}
+		if lenCmd, ok := dtsLenCmds[metas[i].Type]; ok {
+			args = append(args, []interface{}{lenCmd, key})
+			lenIdx = append(lenIdx, i)
+		}
+	}
+	cmds, err = execPipeline(cli, limiter, args)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range lenIdx {
+		metas[i].Len, _ = cmds[j].Int64()
+	}
+	return metas, nil
+}
+
+// digestValueArgs 获取完整值的命令
+func digestValueArgs(key, keyType string) []interface{} {
+	switch keyType {
+	case "string":
+		return []interface{}{"get", key}
+	case "hash":
+		return []interface{}{"hgetall", key}
+	case "list":
+		return []interface{}{"lrange", key, 0, -1}
+	case "set":
+		return []interface{}{"smembers", key}
+	case "zset":
+		return []interface{}{"zrange", key, 0, -1, "withscores"}
+	}
+	return nil
+}
+
+// valueDigest 计算值摘要,hash/set/zset 元素排序后计算,与编码方式无关
+func valueDigest(keyType string, cmd *redis.Cmd) string {
+	var elems []string
+	if keyType == "string" {
+		val, err := cmd.Text()
+		if err != nil {
+			return "err:" + err.Error()
+		}
+		elems = []string{val}
+	} else {
+		vals, err := cmd.StringSlice()
+		if err != nil {
+			return "err:" + err.Error()
+		}
+		switch keyType {
+		case "hash", "zset":
+			pairs := make([]string, 0, len(vals)/2)
+			for i := 0; i+1 < len(vals); i += 2 {
+				second := vals[i+1]
+				if keyType == "zset" {
+					// score 统一格式,避免不同版本输出格式不同
+					if score, err := strconv.ParseFloat(second, 64); err == nil {
+						second = strconv.FormatFloat(score, 'g', -1, 64)
+					}
+				}
+				pairs = append(pairs, vals[i]+"\x00"+second)
+			}
+			sort.Strings(pairs)
+			elems = pairs
+		case "set":
+			sort.Strings(vals)
+			elems = vals
+		default:
+			elems = vals
+		}
+	}
+	h := sha1.New()
+	lenBuf := make([]byte, 8)
+	for _, e := range elems {
+		binary.LittleEndian.PutUint64(lenBuf, uint64(len(e)))
+		h.Write(lenBuf)
+		h.Write([]byte(e))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fetchKeysDigest 批量获取值摘要,keys 中的key 类型一致由调用方保证
+func fetchKeysDigest(cli redis.UniversalClient, limiter *qpsLimiter, keys []string,
+	metas []dtsKeyMeta) ([]string, error) {
+	digests := make([]string, len(keys))
+	start := 0
+	for start < len(keys) {
+		var elems int64
+		end := start
+		for end < len(keys) && (end == start || elems+metas[end].Len <= dtsDigestBatchElements) {
+			elems += metas[end].Len
+			end++
+		}
+		args := make([][]interface{}, 0, end-start)
+		for i := start; i < end; i++ {
+			args = append(args, digestValueArgs(keys[i], metas[i].Type))
+		}
+		cmds, err := execPipeline(cli, limiter, args)
+		if err != nil {
+			return nil, err
+		}
+		for i := start; i < end; i++ {
+			digests[i] = valueDigest(metas[i].Type, cmds[i-start])
+		}
+		start = end
+	}
+	return digests, nil
+}
+
+// compareKeyMeta 比较类型、ttl、长度,一致返回空
+func compareKeyMeta(src, dst dtsKeyMeta) string {
+	if !dst.exists() {
+		return DtsDiffReasonDstMissing
+	}
+	if src.Type != dst.Type {
+		return DtsDiffReasonTypeDiff
+	}
+	if (src.PTTL < 0) != (dst.PTTL < 0) {
+		return DtsDiffReasonTTLDiff
+	}
+	if src.PTTL >= 0 && (src.PTTL-dst.PTTL > dtsTTLToleranceMs || dst.PTTL-src.PTTL > dtsTTLToleranceMs) {
+		return DtsDiffReasonTTLDiff
+	}
+	if src.Len != dst.Len {
+		return DtsDiffReasonLenDiff
+	}
+	return ""
+}
+
+// dtsKeysComparer 源redis 与 目的集群数据比较
+// 目的集群为原生 redis cluster 时 dstCli 为 cluster client
+type dtsKeysComparer struct {
+	srcCli     redis.UniversalClient
+	dstCli     redis.UniversalClient
+	srcLimiter *qpsLimiter
+	dstLimiter *qpsLimiter
+}
+
+// Compare 比较一批key,返回不一致的key; 源端已不存在的key(已过期/已删除)忽略
+func (c *dtsKeysComparer) Compare(keys []string) (diffs []*DtsDiffKey, err error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	srcMetas, err := fetchKeysMeta(c.srcCli, c.srcLimiter, keys)
+	if err != nil {
+		return nil, err
+	}
+	dstMetas, err := fetchKeysMeta(c.dstCli, c.dstLimiter, keys)
+	if err != nil {
+		return nil, err
+	}
+	var digestKeys []string
+	var digestSrcMetas, digestDstMetas []dtsKeyMeta
+	for i, key := range keys {
+		if !srcMetas[i].exists() {
+			continue
+		}
+		reason := compareKeyMeta(srcMetas[i], dstMetas[i])
+		if reason != "" {
+			diffs = append(diffs, NewDtsDiffKey(key, reason, srcMetas[i], dstMetas[i]))
+			continue
+		}
+		if digestValueArgs(key, srcMetas[i].Type) == nil || srcMetas[i].Len > dtsDigestMaxElements {
+			continue
+		}
+		digestKeys = append(digestKeys, key)
+		digestSrcMetas = append(digestSrcMetas, srcMetas[i])
+		digestDstMetas = append(digestDstMetas, dstMetas[i])
+	}
+	srcDigests, err := fetchKeysDigest(c.srcCli, c.srcLimiter, digestKeys, digestSrcMetas)
+	if err != nil {
+		return nil, err
+	}
+	dstDigests, err := fetchKeysDigest(c.dstCli, c.dstLimiter, digestKeys, digestDstMetas)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range digestKeys {
+		if srcDigests[i] != dstDigests[i] {
+			diffs = append(diffs, NewDtsDiffKey(key, DtsDiffReasonValueDiff, digestSrcMetas[i], digestDstMetas[i]))
+		}
+	}
+	return diffs, nil
+}
+
+// RepairKey 以源redis为准修复目的集群中的key
+// 不先删除目的key再重写(修复过程中key会短暂不存在或不完整,中途失败则数据丢失),而是原地收敛:
+// string 用一条 set 覆盖; hash/set/zset 先写入源端全部元素再删除目的端多余的元素;
+// list 按下标 lset 覆盖、rpush 追加后 ltrim 多余部分; 最后对齐ttl.
+// 仅当目的key类型与源不同时需要先删除.
+func (c *dtsKeysComparer) RepairKey(key string) (err error) {
+	srcMetas, err := fetchKeysMeta(c.srcCli, c.srcLimiter, []string{key})
+	if err != nil {
+		return err
+	}
+	dstMetas, err := fetchKeysMeta(c.dstCli, c.dstLimiter, []string{key})
+	if err != nil {
+		return err
+	}
+	src, dst := srcMetas[0], dstMetas[0]
+	if !src.exists() {
+		if dst.exists() {
+			_, err = execPipeline(c.dstCli, c.dstLimiter, [][]interface{}{{"del", key}})
+		}
+		return err
+	}
+	if dst.exists() && dst.Type != src.Type {
+		if _, err = execPipeline(c.dstCli, c.dstLimiter, [][]interface{}{{"del", key}}); err != nil {
+			return err
+		}
+		dst = dtsKeyMeta{}
+	}
+	switch src.Type {
+	case "string":
+		// set 会同时清除旧的ttl,带 px 时原子设置ttl
+		return c.repairString(key, src.PTTL)
+	case "hash":
+		err = c.repairByScan(key, "hscan", "hmset", "hdel", false)
+	case "set":
+		err = c.repairByScan(key, "sscan", "sadd", "srem", false)
+	case "zset":
+		err = c.repairByScan(key, "zscan", "zadd", "zrem", true)
+	case "list":
+		err = c.repairList(key, dst.Len)
+	default:
+		err = fmt.Errorf("key:%s type:%s not support repair", key, src.Type)
+	}
+	if err != nil {
+		return err
+	}
+	if src.PTTL > 0 {
+		_, err = execPipeline(c.dstCli, c.dstLimiter, [][]interface{}{{"pexpire", key, src.PTTL}})
+	} else if dst.PTTL > 0 {
+		_, err = execPipeline(c.dstCli, c.dstLimiter, [][]interface{}{{"persist", key}})
+	}
+	return err
+}
+
+func (c *dtsKeysComparer) repairString(key string, pttl int64) error {
+	c.srcLimiter.Wait(1)
+	val, err := c.srcCli.Get(context.TODO(), key).Result()
+	if err == redis.Nil {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("redis:%s get key:%s fail,err:%v", clientAddr(c.srcCli), key, err)
+	}
+	args := []interface{}{"set", key, val}
+	if pttl > 0 {
+		args = append(args, "px", pttl)
+	}
+	_, err = execPipeline(c.dstCli, c.dstLimiter, [][]interface{}{args})
+	return err
+}
+
+// scanElements hscan/sscan/zscan 遍历key的元素, fn 参数为本批返回的元素(hash/zset 为 field value/member score 交替)
+func scanElements(cli redis.UniversalClient, limiter *qpsLimiter, key, scanCmd string,
+	fn func(elems []interface{}) error) error {
+	var cursor uint64
+	ctx := context.TODO()
+	for {
+		limiter.Wait(1)
+		cmd := cli.Do(ctx, scanCmd, key, cursor, "count", dtsRepairScanCount)
+		ret, err := cmd.Slice()
+		if err != nil || len(ret) != 2 {
+			return fmt.Errorf("redis:%s %s key:%s fail,err:%v", clientAddr(cli), scanCmd, key, err)
+		}
+		cursor, err = strconv.ParseUint(fmt.Sprint(ret[0]), 10, 64)
+		if err != nil {
+			return fmt.Errorf("redis:%s %s key:%s cursor invalid,err:%v", clientAddr(cli), scanCmd, key, err)
+		}
+		if elems, _ := ret[1].([]interface{}); len(elems) > 0 {
+			if err = fn(elems); err != nil {
+				return err
+			}
+		}
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// repairByScan hash/set/zset 通过 hscan/sscan/zscan 分批读取源端元素并写入,再删除目的端源端不存在的元素
+func (c *dtsKeysComparer) repairByScan(key, scanCmd, writeCmd, delCmd string, isZset bool) error {
+	step := 1
+	if scanCmd != "sscan" {
+		step = 2
+	}
+	members := make(map[string]struct{})
+	err := scanElements(c.srcCli, c.srcLimiter, key, scanCmd, func(elems []interface{}) error {
+		args := []interface{}{writeCmd, key}
+		for i := 0; i+step-1 < len(elems); i += step {
+			members[fmt.Sprint(elems[i])] = struct{}{}
+		}
+		if isZset {
+			// zscan 返回 member score,zadd 参数为 score member
+			for i := 0; i+1 < len(elems); i += 2 {
+				args = append(args, elems[i+1], elems[i])
+			}
+		} else {
+			args = append(args, elems...)
+		}
+		_, err := execPipeline(c.dstCli, c.dstLimiter, [][]interface{}{args})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return scanElements(c.dstCli, c.dstLimiter, key, scanCmd, func(elems []interface{}) error {
+		args := []interface{}{delCmd, key}
+		for i := 0; i < len(elems); i += step {
+			if _, ok := members[fmt.Sprint(elems[i])]; !ok {
+				args = append(args, elems[i])
+			}
+		}
+		if len(args) == 2 {
+			return nil
+		}
+		_, err := execPipeline(c.dstCli, c.dstLimiter, [][]interface{}{args})
+		return err
+	})
+}
+
+// repairList 按下标覆盖目的list的前 dstLen 个元素,其余追加,最后截断目的端多余的元素
+func (c *dtsKeysComparer) repairList(key string, dstLen int64) error {
+	ctx := context.TODO()
+	var start int64
+	for ; ; start += dtsRepairScanCount {
+		c.srcLimiter.Wait(1)
+		vals, err := c.srcCli.LRange(ctx, key, start, start+dtsRepairScanCount-1).Result()
+		if err != nil && err != redis.Nil {
+			return fmt.Errorf("redis:%s lrange key:%s fail,err:%v", clientAddr(c.srcCli), key, err)
+		}
+		if len(vals) == 0 {
+			break
+		}
+		args := listRepairArgs(key, start, dstLen, vals)
+		if _, err = execPipeline(c.dstCli, c.dstLimiter, args); err != nil {
+			return err
+		}
+		if len(vals) < dtsRepairScanCount {
+			start += int64(len(vals))
+			break
+		}
+	}
+	if start == 0 {
+		return nil
+	}
+	_, err := execPipeline(c.dstCli, c.dstLimiter, [][]interface{}{{"ltrim", key, 0, start - 1}})
+	return err
+}
+
+// listRepairArgs 源list从下标 start 开始的一批元素对应的目的端命令:
+// 目的端已有的下标用 lset 覆盖,超出 dstLen 的元素 rpush 追加
+func listRepairArgs(key string, start, dstLen int64, vals []string) [][]interface{} {
+	args := make([][]interface{}, 0, len(vals)+1)
+	var push []interface{}
+	for i, v := range vals {
+		idx := start + int64(i)
+		if idx < dstLen {
+			args = append(args, []interface{}{"lset", key, idx, v})
+			continue
+		}
+		if push == nil {
+			push = []interface{}{"rpush", key}
+		}
+		push = append(push, v)
+	}
+	if push != nil {
+		args = append(args, push)
+	}
+	return args
+}
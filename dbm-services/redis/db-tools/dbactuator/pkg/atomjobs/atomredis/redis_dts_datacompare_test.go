@@ -0,0 +1,149 @@
+package atomredis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestTwemproxySegment(t *testing.T) {
+	convey.Convey("twemproxy fnv1a_64 modhash segment", t, func() {
+		tests := []struct {
+			key     string
+			hashTag bool
+			segment int
+		}{
+			{"", false, 69733},
+			{"a", false, 13036},
+			{"abc", false, 388267},
+			{"\xff\xfe", false, 176848}, // 按 signed char 计算
+			{"{tag}x", false, 182767},
+			{"{tag}x", true, 271507}, // 只计算 hash tag
+			{"tag", true, 271507},
+			{"{}x", true, 145447}, // hash tag 为空或不完整时使用整个key
+			{"{tag", true, 311746},
+		}
+		for _, tt := range tests {
+			convey.So(twemproxySegment(tt.key, tt.hashTag), convey.ShouldEqual, tt.segment)
+		}
+	})
+}
+
+func TestKeyPatternsToRegexp(t *testing.T) {
+	convey.Convey("key patterns to regexp", t, func() {
+		tests := []struct {
+			patterns string
+			isNil    bool
+			match    []string
+			notMatch []string
+		}{
+			{patterns: "", isNil: true},
+			{patterns: "\n  \n", isNil: true},
+			{patterns: "*", isNil: true},
+			{patterns: "^.*$", isNil: true},
+			{patterns: "a*\nb", match: []string{"a", "a1", "b", "bx"}, notMatch: []string{"xa", "c"}},
+			{patterns: "^a.b", match: []string{"a.b", "a.bc"}, notMatch: []string{"axb"}},
+			{patterns: "user:*:name", match: []string{"user:1:name", "user::name1"}, notMatch: []string{"user:1"}},
+			// 与key提取一致, 其余正则语法保留
+			{patterns: "^hello$\n^world$", match: []string{"hello", "world"}, notMatch: []string{"hello1", "xworld"}},
+			{patterns: "user:[0-9]+$", match: []string{"user:1", "user:123"}, notMatch: []string{"user:a", "user:1a"}},
+			{patterns: "a|b", match: []string{"a|b"}, notMatch: []string{"a", "b"}},
+		}
+		for _, tt := range tests {
+			reg, err := keyPatternsToRegexp(tt.patterns)
+			convey.So(err, convey.ShouldBeNil)
+			if tt.isNil {
+				convey.So(reg, convey.ShouldBeNil)
+				continue
+			}
+			convey.So(reg, convey.ShouldNotBeNil)
+			for _, key := range tt.match {
+				convey.So(reg.MatchString(key), convey.ShouldBeTrue)
+			}
+			for _, key := range tt.notMatch {
+				convey.So(reg.MatchString(key), convey.ShouldBeFalse)
+			}
+		}
+		_, err := keyPatternsToRegexp("a(b")
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
+
+func TestListRepairArgs(t *testing.T) {
+	convey.Convey("list repair args", t, func() {
+		// 目的端已有2个元素,覆盖后追加
+		args := listRepairArgs("l", 0, 2, []string{"a", "b", "c", "d"})
+		convey.So(args, convey.ShouldResemble, [][]interface{}{
+			{"lset", "l", int64(0), "a"},
+			{"lset", "l", int64(1), "b"},
+			{"rpush", "l", "c", "d"},
+		})
+		// 目的端元素更多,只覆盖
+		args = listRepairArgs("l", 1000, 5000, []string{"a"})
+		convey.So(args, convey.ShouldResemble, [][]interface{}{{"lset", "l", int64(1000), "a"}})
+		// 目的端不存在
+		args = listRepairArgs("l", 0, 0, []string{"a"})
+		convey.So(args, convey.ShouldResemble, [][]interface{}{{"rpush", "l", "a"}})
+	})
+}
+
+func TestInsTasksErr(t *testing.T) {
+	convey.Convey("collect all task errors", t, func() {
+		convey.So(insTasksErr([]*RedisInsDtsDataCheckAndRepairTask{{}, {}}), convey.ShouldBeNil)
+		err := insTasksErr([]*RedisInsDtsDataCheckAndRepairTask{
+			{Err: fmt.Errorf("task1 fail")}, {}, {Err: fmt.Errorf("task3 fail")},
+		})
+		convey.So(err, convey.ShouldNotBeNil)
+		convey.So(err.Error(), convey.ShouldContainSubstring, "task1 fail")
+		convey.So(err.Error(), convey.ShouldContainSubstring, "task3 fail")
+	})
+}
+
+func newTestCmd(val interface{}) *redis.Cmd {
+	cmd := redis.NewCmd(context.TODO())
+	cmd.SetVal(val)
+	return cmd
+}
+
+func TestValueDigest(t *testing.T) {
+	convey.Convey("value digest", t, func() {
+		tests := []struct {
+			name    string
+			keyType string
+			a, b    interface{}
+			equal   bool
+		}{
+			{"string equal", "string", "v1", "v1", true},
+			{"string diff", "string", "v1", "v2", false},
+			{"hash order", "hash", []interface{}{"f1", "v1", "f2", "v2"}, []interface{}{"f2", "v2", "f1", "v1"}, true},
+			{"hash field value swap", "hash", []interface{}{"f1", "v1"}, []interface{}{"v1", "f1"}, false},
+			{"hash boundary", "hash", []interface{}{"a", "bc"}, []interface{}{"ab", "c"}, false},
+			{"set order", "set", []interface{}{"a", "b", "c"}, []interface{}{"c", "a", "b"}, true},
+			{"set diff", "set", []interface{}{"a", "b"}, []interface{}{"a", "bb"}, false},
+			{"set concat", "set", []interface{}{"ab", "c"}, []interface{}{"a", "bc"}, false},
+			{"list order", "list", []interface{}{"a", "b"}, []interface{}{"b", "a"}, false},
+			{"list equal", "list", []interface{}{"a", "b"}, []interface{}{"a", "b"}, true},
+			{"zset score format", "zset", []interface{}{"m1", "1", "m2", "2.5"},
+				[]interface{}{"m2", "2.50", "m1", "1.0"}, true},
+			{"zset score diff", "zset", []interface{}{"m1", "1"}, []interface{}{"m1", "2"}, false},
+		}
+		for _, tt := range tests {
+			a := valueDigest(tt.keyType, newTestCmd(tt.a))
+			b := valueDigest(tt.keyType, newTestCmd(tt.b))
+			convey.So(a, convey.ShouldHaveLength, 40)
+			if tt.equal {
+				convey.So(a, convey.ShouldEqual, b)
+			} else {
+				convey.So(a, convey.ShouldNotEqual, b)
+			}
+		}
+
+		errCmd := redis.NewCmd(context.TODO())
+		errCmd.SetErr(redis.Nil)
+		convey.So(valueDigest("string", errCmd), convey.ShouldStartWith, "err:")
+		convey.So(valueDigest("hash", newTestCmd("not slice")), convey.ShouldStartWith, "err:")
+	})
+}
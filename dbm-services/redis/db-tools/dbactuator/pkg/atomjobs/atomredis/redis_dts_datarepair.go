@@ -3,9 +3,6 @@ package atomredis
 import (
 	"dbm-services/redis/db-tools/dbactuator/pkg/jobruntime"
 	"fmt"
-	"sync"
-
-	"github.com/panjf2000/ants/v2"
 )
 
 // RedisDtsDataRepair dts数据修复
@@ -43,43 +40,20 @@ func (job *RedisDtsDataRepair) Run() (err error) {
 	if err != nil {
 		return
 	}
-	// 2. 获取工具
-	err = job.GetTools()
+	// 2. diff文件目录
+	job.getSaveDir()
+	// 3. 并发修复,并发度5
+	taskList, err := job.newInsTasks()
 	if err != nil {
-		return
+		return err
 	}
-	// 3. 并发提取与校验,并发度5
-	var wg sync.WaitGroup
-	taskList := make([]*RedisInsDtsDataCheckAndRepairTask, 0, len(job.params.SrcRedisPortSegmentList))
-	pool, err := ants.NewPoolWithFunc(5, func(i interface{}) {
-		defer wg.Done()
-		task := i.(*RedisInsDtsDataCheckAndRepairTask)
-		task.RunDataRepair()
-	})
+	err = job.runInsTasks(taskList, (*RedisInsDtsDataCheckAndRepairTask).RunDataRepair)
 	if err != nil {
-		job.runtime.Logger.Error("RedisDtsDataRepair Run NewPoolWithFunc failed,err:%v", err)
 		return err
 	}
-	defer pool.Release()
-
-	for _, portItem := range job.params.SrcRedisPortSegmentList {
-		wg.Add(1)
-		task, err := NewRedisInsDtsDataCheckAndRepairTask(job.params.SrcRedisIP, portItem, job.RedisDtsDataCheck)
-		if err != nil {
-			continue
-		}
-		taskList = append(taskList, task)
-		_ = pool.Invoke(task)
-	}
-	// 等待所有task执行完毕
-	wg.Wait()
 
 	var totalHotKeysCnt uint64 = 0
-	for _, tmp := range taskList {
-		task := tmp
-		if task.Err != nil {
-			return task.Err
-		}
+	for _, task := range taskList {
 		totalHotKeysCnt += task.HotKeysCnt
 	}
 	if totalHotKeysCnt > 0 {
@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -37,6 +38,14 @@ type AnalysisHotkeyParams struct {
 	ApiServer    string `json:"api_server" validate:"required"`
 	BkCloudId    int    `json:"bk_cloud_id"`
 	DbCloudToken string `json:"db_cloud_token" validate:"required"`
+	// Method 分析方式: capture(默认,抓包)/sample(scan采样)/proxy(proxy端口抓包采样)/proxy_log(proxy命令日志采样)
+	Method string `json:"method"`
+	TopN   int    `json:"top_n"`
+	// SampleRate proxy/proxy_log 方式每 N 条命令取 1 条
+	SampleRate int `json:"sample_rate"`
+	// ProxyAccessLog proxy_log 方式的命令日志路径, {PORT} 会被替换为端口
+	// 日志须为 myRedisCapture 输出格式(如对 proxy 端口常驻抓包工具),不支持 twemproxy/predixy 自身的日志
+	ProxyAccessLog string `json:"proxy_access_log"`
 }
 
 // HotkeyAnalysis  结构体
@@ -91,14 +100,42 @@ func (job *HotkeyAnalysis) Init(m *jobruntime.JobGenericRuntime) error {
 
 	}
 	job.errChan = make(chan error, len(ins))
-	job.monitorTool = consts.MyRedisCaptureBin
-	_, err = os.Stat(job.monitorTool)
-	if err != nil && os.IsNotExist(err) {
-		return fmt.Errorf("获取myRedisCapture失败,请检查是否下发成功:err:%v", err)
+	if job.params.Method == "" {
+		job.params.Method = HotkeyMethodCapture
 	}
-
-	job.device, err = util.GetIpv4InterfaceName(job.params.IP)
-	if err != nil {
+	if job.params.TopN <= 0 {
+		job.params.TopN = 20
+	}
+	if job.params.SampleRate <= 0 {
+		job.params.SampleRate = 1
+	}
+	switch job.params.Method {
+	case HotkeyMethodCapture, HotkeyMethodProxy:
+		job.monitorTool = consts.MyRedisCaptureBin
+		_, err = os.Stat(job.monitorTool)
+		if err != nil && os.IsNotExist(err) {
+			return fmt.Errorf("获取myRedisCapture失败,请检查是否下发成功:err:%v", err)
+		}
+		job.device, err = util.GetIpv4InterfaceName(job.params.IP)
+		if err != nil {
+			return err
+		}
+	case HotkeyMethodSample:
+	case HotkeyMethodProxyLog:
+		if !strings.Contains(job.params.ProxyAccessLog, "{PORT}") && len(ins) > 1 {
+			err = fmt.Errorf("proxy_access_log:%s must contains {PORT} when ins_list has more than one port",
+				job.params.ProxyAccessLog)
+			job.runtime.Logger.Error(err.Error())
+			return err
+		}
+		if job.params.ProxyAccessLog == "" {
+			err = fmt.Errorf("method:%s proxy_access_log is required", job.params.Method)
+			job.runtime.Logger.Error(err.Error())
+			return err
+		}
+	default:
+		err = fmt.Errorf("hotkey analysis method:%s not supported", job.params.Method)
+		job.runtime.Logger.Error(err.Error())
 		return err
 	}
 
@@ -178,9 +215,9 @@ type HotkeyParams struct {
 	HotKeyInfos []HotkeyInsert `json:"hot_key_infos"`
 }
 
-// Analysis 监听请求
+// Analysis 分析热key并上报
 func (job *HotkeyAnalysis) Analysis(port, recordId int) {
-	job.runtime.Logger.Info("Analysis port[%d] begin..", port)
+	job.runtime.Logger.Info("Analysis port[%d] method[%s] begin..", port, job.params.Method)
 	defer job.runtime.Logger.Info("Analysis port[%d] end..", port)
 	var err error
 	running, err := job.IsRedisRunning(port)
@@ -190,6 +227,27 @@ func (job *HotkeyAnalysis) Analysis(port, recordId int) {
 		return
 	}
 
+	var h *MinHeap
+	var allTotalCount int64
+	switch job.params.Method {
+	case HotkeyMethodSample:
+		h, allTotalCount, err = job.sampleHotkeys(port)
+	case HotkeyMethodProxyLog:
+		h, allTotalCount, err = job.proxyLogHotkeys(port)
+	case HotkeyMethodProxy:
+		h, allTotalCount, err = job.captureHotkeys(port, int64(job.params.SampleRate))
+	default:
+		h, allTotalCount, err = job.captureHotkeys(port, 1)
+	}
+	if err != nil {
+		job.errChan <- err
+		return
+	}
+	job.reportHotkeys(port, recordId, h, allTotalCount)
+}
+
+// captureHotkeys 抓包分析,统计每个key的命令执行次数, 每 weight 条命令取 1 条统计
+func (job *HotkeyAnalysis) captureHotkeys(port int, weight int64) (h *MinHeap, allTotalCount int64, err error) {
 	nowstr := time.Now().Local().Format("150405")
 	capturelog := fmt.Sprintf("%s/capture_%s_%d_%s.log", job.saveDir, job.params.IP, port, nowstr)
 	timeout := MaxTimeout
@@ -211,83 +269,96 @@ func (job *HotkeyAnalysis) Analysis(port, recordId int) {
 				continue
 			}
 			err = fmt.Errorf("monitor cmd[%s] exec error:%s", monitorCmd, err.Error())
-			job.errChan <- err
-			return
+			return nil, 0, err
 		}
 	}
 
 	// 开始统计key命令执行情况
 	hotkeyMap := make(map[string]*HotKey)
-	allTotalCount := int64(0)
 	for seq := 1; seq <= fileCount; seq++ {
 		outputlog := fmt.Sprintf("%s/capture_result_%s_%d_%s_%d.txt",
 			job.saveDir, job.params.IP, port, nowstr, seq)
-		file, err := os.Open(outputlog)
+		err = job.countLogFile(outputlog, weight, hotkeyMap, &allTotalCount)
 		if err != nil {
-			job.errChan <- err
-			return
+			return nil, 0, err
 		}
-		defer file.Close()
+	}
+	return job.topHotkeys(hotkeyMap), allTotalCount, nil
+}
 
-		// 创建一个 Scanner 对象
-		scanner := bufio.NewScanner(file)
+// countLogFile 读取抓包结果文件,按 key 统计命令次数, weight 大于1时每 weight 行取 1 行
+func (job *HotkeyAnalysis) countLogFile(file string, weight int64, hotkeyMap map[string]*HotKey,
+	allTotalCount *int64) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-		// 按行读取文件内容
-		for scanner.Scan() {
-			line := scanner.Text() // 获取当前行的内容
-			entry, err := parseLogLine(line)
-			if err != nil {
-				job.runtime.Logger.Warn("%s parse log error [%+s]", line, err.Error())
-				continue
-			}
+	// 创建一个 Scanner 对象
+	scanner := bufio.NewScanner(f)
 
-			if len(entry.Keys) == 0 {
-				job.runtime.Logger.Warn("%s parse log keys is empty", line)
-				continue
-			}
-			// key 大小写区分。 cmd 大小写不区分
-			key := entry.Keys[0]
-			cmd := entry.Cmd
-
-			//统计
-			allTotalCount++
-			_, _ok := hotkeyMap[key]
-			if !_ok {
-				hotkeyMap[key] = &HotKey{
-					Key:        key,
-					TotalCount: 1,
-					Ratio:      0,
-				}
-				hotkeyMap[key].CmdCount = make(map[string]int64)
-				hotkeyMap[key].CmdCount[cmd]++
-			} else {
-				hotkeyMap[key].TotalCount++
-				hotkeyMap[key].CmdCount[cmd]++
-			}
+	// 按行读取文件内容
+	var lineNo int64
+	for scanner.Scan() {
+		lineNo++
+		if lineNo%weight != 0 {
+			continue
 		}
+		job.countLogLine(scanner.Text(), weight, hotkeyMap, allTotalCount)
+	}
+
+	// 检查是否在读取过程中发生错误
+	return scanner.Err()
+}
+
+// countLogLine 解析一行日志并计数, weight 为采样倍率; 行格式不匹配时返回 false
+func (job *HotkeyAnalysis) countLogLine(line string, weight int64, hotkeyMap map[string]*HotKey,
+	allTotalCount *int64) (parsed bool) {
+	entry, err := parseLogLine(line)
+	if err != nil {
+		job.runtime.Logger.Warn("%s parse log error [%+s]", line, err.Error())
+		return false
+	}
 
-		// 检查是否在读取过程中发生错误
-		if err := scanner.Err(); err != nil {
-			job.errChan <- err
-			return
+	if len(entry.Keys) == 0 {
+		job.runtime.Logger.Warn("%s parse log keys is empty", line)
+		return true
+	}
+	// key 大小写区分。 cmd 大小写不区分
+	key := entry.Keys[0]
+	cmd := entry.Cmd
+
+	//统计
+	*allTotalCount += weight
+	_, _ok := hotkeyMap[key]
+	if !_ok {
+		hotkeyMap[key] = &HotKey{
+			Key:        key,
+			TotalCount: weight,
+			Ratio:      0,
 		}
+		hotkeyMap[key].CmdCount = make(map[string]int64)
+		hotkeyMap[key].CmdCount[cmd] += weight
+	} else {
+		hotkeyMap[key].TotalCount += weight
+		hotkeyMap[key].CmdCount[cmd] += weight
 	}
+	return true
+}
 
-	// 处理结果，只取前20条记录插入表中
-	// 初始化一个大小为 20 的最小堆
+// topHotkeys 取 TotalCount 最大的 TopN 个key
+func (job *HotkeyAnalysis) topHotkeys(hotkeyMap map[string]*HotKey) *MinHeap {
 	h := &MinHeap{}
 	heap.Init(h)
-
-	// 遍历 map
 	for _, hotKey := range hotkeyMap {
-		if h.Len() < 20 {
-			heap.Push(h, hotKey) // 如果堆大小小于 20，直接加入
-		} else if hotKey.TotalCount > (*h)[0].TotalCount {
-			heap.Pop(h)          // 如果当前元素的 TotalCount 大于堆顶，弹出堆顶
-			heap.Push(h, hotKey) // 将当前元素加入堆
-		}
+		h.PushTopN(hotKey, job.params.TopN)
 	}
+	return h
+}
 
+// reportHotkeys 将堆中的热key上报
+func (job *HotkeyAnalysis) reportHotkeys(port, recordId int, h *MinHeap, allTotalCount int64) {
 	cli, err := util.NewClient(job.params.ApiServer, job.params.DbCloudToken, job.params.BkCloudId)
 	if err != nil {
 		return
@@ -297,13 +368,10 @@ func (job *HotkeyAnalysis) Analysis(port, recordId int) {
 	var hotkeyList []HotkeyInsert
 	for h.Len() != 0 {
 		hotKeyT := heap.Pop(h).(*HotKey)
-		hotKeyT.Ratio = float32(hotKeyT.TotalCount) / float32(allTotalCount)
-		var sb strings.Builder
-		for key, value := range hotKeyT.CmdCount {
-			sb.WriteString(fmt.Sprintf("%s:%d ", key, value))
+		if allTotalCount > 0 {
+			hotKeyT.Ratio = float32(hotKeyT.TotalCount) / float32(allTotalCount)
 		}
-		// 去掉最后一个逗号
-		cmdStr := sb.String()
+		cmdStr := hotKeyT.CmdInfo()
 
 		// 调用api插入分析记录
 		hotkey := HotkeyInsert{
@@ -370,6 +438,30 @@ type HotKey struct {
 	// 存储cmd执行次数
 	CmdCount map[string]int64
 	Ratio    float32
+	// ObjectFreq sample 方式 LFU 策略下的 OBJECT FREQ
+	ObjectFreq int64
+	// IdleHitRounds sample 方式非 LFU 策略下 OBJECT IDLETIME==0 的轮数
+	IdleHitRounds int64
+}
+
+// CmdInfo 上报的命令信息, 抓包方式为各命令执行次数, sample 方式为采样指标
+func (k *HotKey) CmdInfo() string {
+	var sb strings.Builder
+	cmds := make([]string, 0, len(k.CmdCount))
+	for cmd := range k.CmdCount {
+		cmds = append(cmds, cmd)
+	}
+	sort.Strings(cmds)
+	for _, cmd := range cmds {
+		sb.WriteString(fmt.Sprintf("%s:%d ", cmd, k.CmdCount[cmd]))
+	}
+	if k.ObjectFreq > 0 {
+		sb.WriteString(fmt.Sprintf("object_freq:%d ", k.ObjectFreq))
+	}
+	if k.IdleHitRounds > 0 {
+		sb.WriteString(fmt.Sprintf("idle_hit_rounds:%d ", k.IdleHitRounds))
+	}
+	return sb.String()
 }
 
 // MinHeap 定义一个最小堆结构排序
@@ -391,6 +483,16 @@ func (h *MinHeap) Pop() interface{} {
 	return x
 }
 
+// PushTopN 堆大小小于 n 时直接加入, 否则大于堆顶时替换堆顶
+func (h *MinHeap) PushTopN(hotKey *HotKey, n int) {
+	if h.Len() < n {
+		heap.Push(h, hotKey)
+	} else if hotKey.TotalCount > (*h)[0].TotalCount {
+		heap.Pop(h)
+		heap.Push(h, hotKey)
+	}
+}
+
 // LogEntry 代表一行日志的所有字段
 type LogEntry struct {
 	Time     time.Time // 日志时间
@@ -0,0 +1,96 @@
+package atomsys
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/redis/db-tools/dbactuator/pkg/jobruntime"
+)
+
+func newTestHotkeyAnalysis() *HotkeyAnalysis {
+	return &HotkeyAnalysis{
+		runtime: &jobruntime.JobGenericRuntime{Logger: logger.New(io.Discard, false, logger.InfoLevel)},
+		params:  &AnalysisHotkeyParams{TopN: 20},
+	}
+}
+
+func TestParseLogLine(t *testing.T) {
+	convey.Convey("parse myRedisCapture log line", t, func() {
+		entry, err := parseLogLine(`[2024-01-02 03:04:05] client: 1.1.1.1:1234 => 2.2.2.2:6379 "set" "k\"1" "v"`)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(entry.Cmd, convey.ShouldEqual, "SET")
+		convey.So(entry.Keys, convey.ShouldResemble, []string{`k"1`})
+		convey.So(entry.ClientIP, convey.ShouldEqual, "1.1.1.1:1234")
+
+		_, err = parseLogLine("2024-01-02 03:04:05 set k1 v")
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+	convey.Convey("extract keys", t, func() {
+		convey.So(extractKeys("AUTH", []string{"AUTH", "pwd"}), convey.ShouldResemble, []string{"********"})
+		convey.So(extractKeys("EVAL", []string{"EVAL", "script", "2", "k1", "k2", "a1"}),
+			convey.ShouldResemble, []string{"k1", "k2"})
+		convey.So(extractKeys("EVAL", []string{"EVAL", "script", "3", "k1"}), convey.ShouldBeNil)
+	})
+}
+
+func TestCountLogFile(t *testing.T) {
+	convey.Convey("count capture log with sample weight", t, func() {
+		file := filepath.Join(t.TempDir(), "capture.log")
+		var content string
+		for i := 0; i < 10; i++ {
+			content += fmt.Sprintf("[2024-01-02 03:04:05] client: 1.1.1.1:1234 => 2.2.2.2:6379 \"get\" \"k%d\"\n", i%2)
+		}
+		convey.So(os.WriteFile(file, []byte(content), 0644), convey.ShouldBeNil)
+		job := newTestHotkeyAnalysis()
+
+		hotkeyMap := map[string]*HotKey{}
+		var total int64
+		convey.So(job.countLogFile(file, 1, hotkeyMap, &total), convey.ShouldBeNil)
+		convey.So(total, convey.ShouldEqual, 10)
+		convey.So(hotkeyMap["k0"].CmdCount["GET"], convey.ShouldEqual, 5)
+
+		// 每 2 行取 1 行,只会取到 k1, 按权重计数
+		hotkeyMap = map[string]*HotKey{}
+		total = 0
+		convey.So(job.countLogFile(file, 2, hotkeyMap, &total), convey.ShouldBeNil)
+		convey.So(total, convey.ShouldEqual, 10)
+		convey.So(hotkeyMap, convey.ShouldNotContainKey, "k0")
+		convey.So(hotkeyMap["k1"].TotalCount, convey.ShouldEqual, 10)
+	})
+}
+
+func TestHotKeyCmdInfo(t *testing.T) {
+	convey.Convey("hot key cmd info", t, func() {
+		k := &HotKey{CmdCount: map[string]int64{"SET": 2, "GET": 3}}
+		convey.So(k.CmdInfo(), convey.ShouldEqual, "GET:3 SET:2 ")
+		convey.So((&HotKey{ObjectFreq: 100}).CmdInfo(), convey.ShouldEqual, "object_freq:100 ")
+
+		hotkeyMap := map[string]*HotKey{}
+		countIdleHit(hotkeyMap, "k1")
+		countIdleHit(hotkeyMap, "k1")
+		convey.So(hotkeyMap["k1"].TotalCount, convey.ShouldEqual, 2)
+		convey.So(hotkeyMap["k1"].CmdCount, convey.ShouldBeEmpty)
+		convey.So(hotkeyMap["k1"].CmdInfo(), convey.ShouldEqual, "idle_hit_rounds:2 ")
+	})
+}
+
+func TestMinHeapPushTopN(t *testing.T) {
+	convey.Convey("keep top n hot keys", t, func() {
+		job := newTestHotkeyAnalysis()
+		job.params.TopN = 2
+		hotkeyMap := map[string]*HotKey{}
+		for i, count := range []int64{5, 1, 9, 3} {
+			key := fmt.Sprintf("k%d", i)
+			hotkeyMap[key] = &HotKey{Key: key, TotalCount: count}
+		}
+		h := job.topHotkeys(hotkeyMap)
+		convey.So(h.Len(), convey.ShouldEqual, 2)
+		convey.So((*h)[0].TotalCount, convey.ShouldEqual, 5)
+	})
+}
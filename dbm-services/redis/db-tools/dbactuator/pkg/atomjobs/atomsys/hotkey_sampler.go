@@ -0,0 +1,250 @@
+package atomsys
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbm-services/redis/db-tools/dbactuator/models/myredis"
+	"dbm-services/redis/db-tools/dbactuator/pkg/consts"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 热key分析方式
+const (
+	// HotkeyMethodCapture 抓包分析,最准确,开销最大
+	HotkeyMethodCapture = "capture"
+	// HotkeyMethodSample scan 采样: LFU 策略读取 OBJECT FREQ, 否则多轮统计 OBJECT IDLETIME==0 的次数
+	HotkeyMethodSample = "sample"
+	// HotkeyMethodProxy 在 proxy 机器上对 twemproxy/predixy 端口抓包,每 SampleRate 条命令取 1 条统计
+	// proxy 自身的日志不记录命令与key,通过抓包获取命令
+	HotkeyMethodProxy = "proxy"
+	// HotkeyMethodProxyLog 采样已有的 proxy 命令日志, 只支持 myRedisCapture 输出格式的日志
+	HotkeyMethodProxyLog = "proxy_log"
+)
+
+const (
+	hotkeySampleScanCount  = 1000
+	hotkeySampleBatchSleep = 10 * time.Millisecond
+	// hotkeySampleKeysPerRound idle 采样每轮最多检查的key数,下一轮从上一轮的 scan cursor 继续
+	hotkeySampleKeysPerRound = 10000
+	// hotkeySampleRoundWait 两轮idle采样的最小间隔, redis LRU 时钟精度为1秒
+	hotkeySampleRoundWait = time.Second
+	// hotkeyProxyLogCheckLines proxy_log 方式前N个采样行都无法解析时,认为日志格式不支持
+	hotkeyProxyLogCheckLines = 100
+)
+
+// sampleHotkeys 通过 scan + OBJECT 采样热key,不执行 MONITOR 也不抓包
+// 只分析 db0
+func (job *HotkeyAnalysis) sampleHotkeys(port int) (h *MinHeap, allTotalCount int64, err error) {
+	addr := fmt.Sprintf("%s:%d", job.params.IP, port)
+	password, err := myredis.GetRedisPasswdFromConfFile(port)
+	if err != nil {
+		return nil, 0, err
+	}
+	cli, err := myredis.NewRedisClientWithTimeout(addr, password, 0, consts.TendisTypeRedisInstance, 10*time.Second)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cli.Close()
+	dbType, err := cli.GetTendisType()
+	if err != nil {
+		return nil, 0, err
+	}
+	if dbType != consts.TendisTypeRedisInstance {
+		err = fmt.Errorf("redis:%s dbtype:%s not support method:%s", addr, dbType, HotkeyMethodSample)
+		job.runtime.Logger.Error(err.Error())
+		return nil, 0, err
+	}
+	confRet, err := cli.ConfigGet("maxmemory-policy")
+	if err != nil {
+		return nil, 0, err
+	}
+	if strings.Contains(confRet["maxmemory-policy"], "lfu") {
+		return job.sampleByFreq(cli)
+	}
+	return job.sampleByIdle(cli)
+}
+
+// scanKeysObject 从 cursor 开始 scan,对每批key执行 OBJECT <subcmd>
+// limit 大于0时检查 limit 个key后返回,返回下一次 scan 的 cursor,为0表示已经 scan 完一遍
+func (job *HotkeyAnalysis) scanKeysObject(cli *myredis.RedisClient, subcmd string, cursor uint64, limit int,
+	fn func(key string, val int64)) (uint64, error) {
+	ctx := context.TODO()
+	scanned := 0
+	for {
+		keys, nextCursor, err := cli.Scan("*", cursor, hotkeySampleScanCount)
+		if err != nil {
+			return 0, err
+		}
+		if len(keys) > 0 {
+			pipe := cli.InstanceClient.Pipeline()
+			cmds := make([]*redis.Cmd, 0, len(keys))
+			for _, key := range keys {
+				cmds = append(cmds, pipe.Do(ctx, "object", subcmd, key))
+			}
+			_, err = pipe.Exec(ctx)
+			if err != nil && err != redis.Nil {
+				if _, ok := err.(redis.Error); !ok {
+					err = fmt.Errorf("redis:%s pipeline 'object %s' fail,err:%v", cli.Addr, subcmd, err)
+					job.runtime.Logger.Error(err.Error())
+					return 0, err
+				}
+			}
+			for idx, cmd := range cmds {
+				// key 可能已过期或被删除
+				val, err := cmd.Int64()
+				if err != nil {
+					continue
+				}
+				fn(keys[idx], val)
+			}
+			scanned += len(keys)
+			time.Sleep(hotkeySampleBatchSleep)
+		}
+		cursor = nextCursor
+		if cursor == 0 || (limit > 0 && scanned >= limit) {
+			return cursor, nil
+		}
+	}
+}
+
+// sampleByFreq LFU 策略下 OBJECT FREQ 即访问频率(对数计数),一轮 scan 即可
+func (job *HotkeyAnalysis) sampleByFreq(cli *myredis.RedisClient) (h *MinHeap, allTotalCount int64, err error) {
+	h = &MinHeap{}
+	heap.Init(h)
+	_, err = job.scanKeysObject(cli, "freq", 0, 0, func(key string, freq int64) {
+		if freq <= 0 {
+			return
+		}
+		allTotalCount += freq
+		h.PushTopN(&HotKey{
+			Key:        key,
+			TotalCount: freq,
+			ObjectFreq: freq,
+			CmdCount:   map[string]int64{},
+		}, job.params.TopN)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return h, allTotalCount, nil
+}
+
+// sampleByIdle 非 LFU 策略时,在 AnalysisTime 内多轮采样,
+// 统计每个key在多少轮中 OBJECT IDLETIME==0(最近1秒内被访问)
+// 每轮最多检查 hotkeySampleKeysPerRound 个key,大实例上多轮依次覆盖不同的key,单轮开销有上限
+func (job *HotkeyAnalysis) sampleByIdle(cli *myredis.RedisClient) (h *MinHeap, allTotalCount int64, err error) {
+	hotkeyMap := make(map[string]*HotKey)
+	deadline := time.Now().Add(time.Duration(job.params.AnalysisTime) * time.Second)
+	rounds := 0
+	var cursor uint64
+	for {
+		roundStart := time.Now()
+		cursor, err = job.scanKeysObject(cli, "idletime", cursor, hotkeySampleKeysPerRound,
+			func(key string, idle int64) {
+				if idle == 0 {
+					allTotalCount++
+					countIdleHit(hotkeyMap, key)
+				}
+			})
+		if err != nil {
+			return nil, 0, err
+		}
+		rounds++
+		if time.Now().After(deadline) {
+			break
+		}
+		if cost := time.Since(roundStart); cost < hotkeySampleRoundWait {
+			time.Sleep(hotkeySampleRoundWait - cost)
+		}
+	}
+	job.runtime.Logger.Info("redis:%s idle sample rounds:%d,hit keys:%d", cli.Addr, rounds, len(hotkeyMap))
+	return job.topHotkeys(hotkeyMap), allTotalCount, nil
+}
+
+// countIdleHit 记录一次 OBJECT IDLETIME==0
+func countIdleHit(hotkeyMap map[string]*HotKey, key string) {
+	if _, ok := hotkeyMap[key]; !ok {
+		hotkeyMap[key] = &HotKey{Key: key, CmdCount: map[string]int64{}}
+	}
+	hotkeyMap[key].TotalCount++
+	hotkeyMap[key].IdleHitRounds++
+}
+
+// proxyLogHotkeys 在 AnalysisTime 内跟踪 proxy 命令日志新增内容,每 SampleRate 行取 1 行统计
+// 日志行格式须与 myRedisCapture 抓包结果一致: [time] client: ip:port => ip:port "CMD" "key" ...
+// 格式不匹配时直接报错,避免输出空的热key报告
+func (job *HotkeyAnalysis) proxyLogHotkeys(port int) (h *MinHeap, allTotalCount int64, err error) {
+	logFile := strings.ReplaceAll(job.params.ProxyAccessLog, "{PORT}", strconv.Itoa(port))
+	f, err := os.Open(logFile)
+	if err != nil {
+		err = fmt.Errorf("open proxy access log:%s fail,err:%v", logFile, err)
+		job.runtime.Logger.Error(err.Error())
+		return nil, 0, err
+	}
+	defer func() {
+		f.Close()
+	}()
+	// 只分析任务开始后的新日志
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+	hotkeyMap := make(map[string]*HotKey)
+	weight := int64(job.params.SampleRate)
+	reader := bufio.NewReader(f)
+	var lineNo, sampled, parsed int64
+	var partial string
+	deadline := time.Now().Add(time.Duration(job.params.AnalysisTime) * time.Second)
+	for time.Now().Before(deadline) {
+		line, err := reader.ReadString('\n')
+		offset += int64(len(line))
+		if err == io.EOF {
+			// 未读完整的行留到下一次
+			partial += line
+			time.Sleep(time.Second)
+			if st, statErr := os.Stat(logFile); statErr == nil && st.Size() < offset {
+				// 日志被轮转或清空,从头读新文件
+				job.runtime.Logger.Info("proxy access log:%s rotated,reopen", logFile)
+				f.Close()
+				if f, err = os.Open(logFile); err != nil {
+					return nil, 0, err
+				}
+				reader.Reset(f)
+				offset, partial = 0, ""
+			}
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		line, partial = partial+line, ""
+		lineNo++
+		if lineNo%weight != 0 {
+			continue
+		}
+		sampled++
+		if job.countLogLine(strings.TrimRight(line, "\r\n"), weight, hotkeyMap, &allTotalCount) {
+			parsed++
+		}
+		if parsed == 0 && sampled >= hotkeyProxyLogCheckLines {
+			break
+		}
+	}
+	if parsed == 0 && sampled > 0 {
+		err = fmt.Errorf("proxy access log:%s %d lines not match myRedisCapture format,"+
+			"twemproxy/predixy logs are not supported", logFile, sampled)
+		job.runtime.Logger.Error(err.Error())
+		return nil, 0, err
+	}
+	job.runtime.Logger.Info("proxy access log:%s read lines:%d,keys:%d", logFile, lineNo, len(hotkeyMap))
+	return job.topHotkeys(hotkeyMap), allTotalCount, nil
+}
@@ -8,6 +8,8 @@ import (
 	"os"
 
 	"github.com/spf13/viper"
+
+	"dbm-services/common/go-pubpkg/iocrypt"
 )
 
 // ConfServerItem servers配置项
@@ -47,6 +49,29 @@ type ConfRedisBinlogBackup struct {
 	OldFileLeftDay int    `json:"old_file_left_day" mapstructure:"old_file_left_day"`
 }
 
+// ConfBackupEncrypt 全备/binlog备份加密配置
+type ConfBackupEncrypt struct {
+	Enable bool `json:"enable" mapstructure:"enable"`
+	// PublicKey RSA 公钥文件,备份文件使用 sio 原地加密,每个文件的 data key 由公钥加密后写在文件头
+	// 恢复时使用平台对应的私钥解密,不需要额外上报密钥
+	PublicKey string `json:"public_key" mapstructure:"public_key"`
+}
+
+// GetEncryptTool enable=false 时返回 nil
+func (c ConfBackupEncrypt) GetEncryptTool() (*iocrypt.SioCrypt, error) {
+	if !c.Enable {
+		return nil, nil
+	}
+	if c.PublicKey == "" {
+		return nil, fmt.Errorf("backup_encrypt.public_key is required when backup_encrypt is enabled")
+	}
+	pubKey, err := iocrypt.ReadPublicKeyFile(c.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &iocrypt.SioCrypt{PublicKey: pubKey}, nil
+}
+
 // ConfRedisHeartbeat 心跳配置
 type ConfRedisHeartbeat struct {
 	Cron string `json:"cron" mapstructure:"cron"`
@@ -87,6 +112,7 @@ type Configuration struct {
 	BackupClientStrorageType string                `json:"backup_client_storage_type" mapstructure:"backup_client_storage_type"`
 	RedisFullBackup          ConfRedisFullBackup   `json:"redis_fullbackup" mapstructure:"redis_fullbackup"`
 	RedisBinlogBackup        ConfRedisBinlogBackup `json:"redis_binlogbackup" mapstructure:"redis_binlogbackup"`
+	BackupEncrypt            ConfBackupEncrypt     `json:"backup_encrypt" mapstructure:"backup_encrypt"`
 	RedisHeartbeat           ConfRedisHeartbeat    `json:"redis_heartbeat" mapstructure:"redis_heartbeat"`
 	KeyLifeCycle             ConfRedisKeyLifeCycle `json:"redis_keylife" mapstructure:"redis_keylife"`
 	RedisMonitor             ConfRedisMonitor      `json:"redis_monitor" mapstructure:"redis_monitor"`
@@ -179,6 +179,10 @@ const (
 	RedisKeyModeReporter = "redis_keymod_%s.log"
 	// RedisKeyLifeReporter TODO
 	RedisKeyLifeReporter = "redis_keylife_%s.log"
+	// RedisRdbBigKeyReporter 内置rdb解析器统计的大key,每行一个json
+	RedisRdbBigKeyReporter = "redis_rdb_bigkey_%s.log"
+	// RedisRdbKeyModeReporter 内置rdb解析器统计的key模式,每行一个json
+	RedisRdbKeyModeReporter = "redis_rdb_keymod_%s.log"
 )
 
 // meta role
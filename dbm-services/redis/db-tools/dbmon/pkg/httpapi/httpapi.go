@@ -9,6 +9,9 @@ import (
 	"dbm-services/redis/db-tools/dbmon/config"
 	"dbm-services/redis/db-tools/dbmon/mylog"
 	"dbm-services/redis/db-tools/dbmon/pkg/consts"
+	"dbm-services/redis/db-tools/dbmon/pkg/prommetrics"
+	// 注册全备时间指标
+	_ "dbm-services/redis/db-tools/dbmon/pkg/redisfullbackup"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,6 +39,7 @@ func StartListen(conf *config.Configuration) {
 	r.Use(mylog.GinLogger(), mylog.GinRecovery(true))
 	r.GET("/health", health)
 	r.GET("/version", version)
+	r.GET("/metrics", gin.WrapH(prommetrics.Handler()))
 	mylog.Logger.Info(fmt.Sprintf("start listen %s", conf.HttpAddress))
 	r.Run(conf.HttpAddress)
 }
@@ -29,6 +29,8 @@ type Job struct { // NOCC:golint/naming(其他:设计如此)
 	BigKeyRp      report.Reporter       `json:"-"`
 	KeyModeRp     report.Reporter       `json:"-"`
 	KeyLifeRp     report.Reporter       `json:"-"`
+	RdbBigKeyRp   report.Reporter       `json:"-"`
+	RdbKeyModeRp  report.Reporter       `json:"-"`
 	Err           error                 `json:"-"`
 }
 
@@ -64,6 +66,8 @@ func (job *Job) Run() {
 	defer job.BigKeyRp.Close()
 	defer job.KeyModeRp.Close()
 	defer job.KeyLifeRp.Close()
+	defer job.RdbBigKeyRp.Close()
+	defer job.RdbKeyModeRp.Close()
 
 	if job.createTasks(); job.Err != nil {
 		return
@@ -81,8 +85,10 @@ func (job *Job) precheck() {
 		}
 	}
 
+	// 没有工具时只输出内置rdb解析器的大key/key模式统计, keystat 格式的结果和热key统计需要工具
 	if !util.FileExists(consts.TendisKeyLifecycleBin) {
-		job.Err = fmt.Errorf("file :%s does not exist|%+v", consts.TendisKeyLifecycleBin, job.Err)
+		mylog.Logger.Warn(fmt.Sprintf("file :%s does not exist,only rdb analyse available",
+			consts.TendisKeyLifecycleBin))
 	}
 }
 
@@ -99,6 +105,10 @@ func (job *Job) GetReporter() {
 		fmt.Sprintf(consts.RedisKeyModeReporter, time.Now().Local().Format(consts.FilenameDayLayout))))
 	job.KeyLifeRp, job.Err = report.NewFileReport(filepath.Join(reportDir,
 		fmt.Sprintf(consts.RedisKeyLifeReporter, time.Now().Local().Format(consts.FilenameDayLayout))))
+	job.RdbBigKeyRp, job.Err = report.NewFileReport(filepath.Join(reportDir,
+		fmt.Sprintf(consts.RedisRdbBigKeyReporter, time.Now().Local().Format(consts.FilenameDayLayout))))
+	job.RdbKeyModeRp, job.Err = report.NewFileReport(filepath.Join(reportDir,
+		fmt.Sprintf(consts.RedisRdbKeyModeReporter, time.Now().Local().Format(consts.FilenameDayLayout))))
 }
 
 func (job *Job) createTasks() {
@@ -141,5 +151,5 @@ func (job *Job) createTasks() {
 		}
 	}
 	job.StatTask = NewKeyStatTask(localInstances, &job.Conf.KeyLifeCycle,
-		job.HotKeyRp, job.BigKeyRp, job.KeyModeRp, job.KeyLifeRp)
+		job.HotKeyRp, job.BigKeyRp, job.KeyModeRp, job.KeyLifeRp, job.RdbBigKeyRp, job.RdbKeyModeRp)
 }
@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"dbm-services/redis/db-tools/dbmon/config"
 	"dbm-services/redis/db-tools/dbmon/mylog"
 	"dbm-services/redis/db-tools/dbmon/pkg/consts"
+	"dbm-services/redis/db-tools/dbmon/pkg/rdbanalyzer"
 	"dbm-services/redis/db-tools/dbmon/pkg/report"
 	"dbm-services/redis/db-tools/dbmon/util"
 
@@ -23,6 +25,9 @@ import (
 // 给个默认值
 var MemUsedPercent = 70
 
+// keyModeTopCnt key模式上报个数
+const keyModeTopCnt = 30
+
 // Task 任务内容
 type Task struct {
 	statServers []Instance
@@ -38,24 +43,30 @@ type Task struct {
 	BigKeyRp  report.Reporter
 	KeyModeRp report.Reporter
 	KeyLifeRp report.Reporter
+	// RdbBigKeyRp/RdbKeyModeRp 内置rdb解析器的统计结果, 与 keystat 的结果分开上报
+	RdbBigKeyRp  report.Reporter
+	RdbKeyModeRp report.Reporter
 }
 
 // NewKeyStatTask new a task
 func NewKeyStatTask(servers []Instance, conf *config.ConfRedisKeyLifeCycle,
-	hkRp report.Reporter, bkRp report.Reporter, kmRp report.Reporter, klRp report.Reporter) *Task {
+	hkRp report.Reporter, bkRp report.Reporter, kmRp report.Reporter, klRp report.Reporter,
+	rdbBkRp report.Reporter, rdbKmRp report.Reporter) *Task {
 
 	return &Task{
-		statServers: servers,
-		conf:        conf,
-		HotKeyRp:    hkRp,
-		BigKeyRp:    bkRp,
-		KeyModeRp:   kmRp,
-		KeyLifeRp:   klRp,
-		logFile:     "tendis.keystat.log",
-		errFile:     "tendis.keystat.err",
-		lockFile:    "tendis.keystat.lock",
-		magicFile:   "tendis.lifecycle.magic.done",
-		basicDir:    fmt.Sprintf("%s/redis", consts.GetRedisDataDir()),
+		statServers:  servers,
+		conf:         conf,
+		HotKeyRp:     hkRp,
+		BigKeyRp:     bkRp,
+		KeyModeRp:    kmRp,
+		KeyLifeRp:    klRp,
+		RdbBigKeyRp:  rdbBkRp,
+		RdbKeyModeRp: rdbKmRp,
+		logFile:      "tendis.keystat.log",
+		errFile:      "tendis.keystat.err",
+		lockFile:     "tendis.keystat.lock",
+		magicFile:    "tendis.lifecycle.magic.done",
+		basicDir:     fmt.Sprintf("%s/redis", consts.GetRedisDataDir()),
 	}
 }
 
@@ -75,9 +86,12 @@ func (t *Task) RunStat() error {
 	defer func() { doneChan <- struct{}{} }()
 
 	rstHash := map[string]interface{}{}
-	cmdVer := fmt.Sprintf("%s version| grep build_date | awk '{print $3}'", consts.TendisKeyLifecycleBin)
-	r1, _ := util.RunBashCmd(cmdVer, "", nil, time.Second)
-	rstHash["tool_version"] = strings.TrimSuffix(r1, "\n")
+	rstHash["tool_version"] = "rdbanalyzer-" + consts.BkDbmonVersion
+	if util.FileExists(consts.TendisKeyLifecycleBin) {
+		cmdVer := fmt.Sprintf("%s version| grep build_date | awk '{print $3}'", consts.TendisKeyLifecycleBin)
+		r1, _ := util.RunBashCmd(cmdVer, "", nil, time.Second)
+		rstHash["tool_version"] = strings.TrimSuffix(r1, "\n")
+	}
 
 	gStartTime := time.Now().Unix()
 	for _, server := range t.statServers {
@@ -112,12 +126,17 @@ func (t *Task) RunStat() error {
 			rstHash["keys_total"] = dbsize
 
 			rstHash["data_type"] = "tendis_bigkeys"
-			err = t.sendAndReport(t.BigKeyRp, fbig)
-			mylog.Logger.Warn(fmt.Sprintf("role slave , do big key analyse done.. :%s:%+v", server.Addr, err))
+			// 没有 keystat 工具时只有内置rdb解析器的结果
+			if util.FileExists(fbig) {
+				err = t.sendAndReport(t.BigKeyRp, fbig)
+				mylog.Logger.Warn(fmt.Sprintf("role slave , do big key analyse done.. :%s:%+v", server.Addr, err))
+			}
 
 			rstHash["data_type"] = "tendis_keymod"
-			err = t.sendAndReport(t.KeyModeRp, fmod)
-			mylog.Logger.Warn(fmt.Sprintf("role slave , do big key analyse done.. :%s:%+v", server.Addr, err))
+			if util.FileExists(fmod) {
+				err = t.sendAndReport(t.KeyModeRp, fmod)
+				mylog.Logger.Warn(fmt.Sprintf("role slave , do big key analyse done.. :%s:%+v", server.Addr, err))
+			}
 		} else {
 			mylog.Logger.Error(fmt.Sprintf("unkown server role %s:%s", server.Addr, server.Role))
 		}
@@ -162,6 +181,9 @@ func (t *Task) rotateFile(f string) {
 
 // hotKeyWithMonitor 热key 分析
 func (t *Task) hotKeyWithMonitor(server Instance) (string, error) {
+	if !util.FileExists(consts.TendisKeyLifecycleBin) {
+		return "", fmt.Errorf("file :%s does not exist", consts.TendisKeyLifecycleBin)
+	}
 	hkfile := fmt.Sprintf("tendis.keystat.hotkeys.%d.info", server.Port)
 	t.rotateFile(hkfile)
 
@@ -215,14 +237,22 @@ func (t *Task) bigKeySmartStat(server Instance) (string, string, int64, int64, e
 }
 
 // bigKeyWithRdb4Cache  -- 大key & key 模式分析
+// 内置rdb解析器的结果写入 RdbBigKeyRp/RdbKeyModeRp, keystat 工具存在时仍按原格式输出 bkfile/kmfile
 func (t *Task) bigKeyWithRdb4Cache(server Instance, bkfile, kmfile string) (int64, int64, error) {
 	if err := server.Cli.BgSaveAndWaitForFinish(); err != nil {
 		return 0, 0, err
 	}
+	rdbFile := fmt.Sprintf("%s/%d/data/dump.rdb", t.basicDir, server.Port)
+	rdbKeys, rdbErr := t.statRdbFileDetail(rdbFile, server)
+	if !util.FileExists(consts.TendisKeyLifecycleBin) {
+		return rdbKeys, 1, rdbErr
+	}
+	if rdbErr != nil {
+		mylog.Logger.Warn(fmt.Sprintf("rdb analyse failed %s:%+v", server.Addr, rdbErr))
+	}
 
 	allkeys := fmt.Sprintf("v.%d.keys", server.Port)
-	cmdKeys := fmt.Sprintf("%s rdbstat -f %s/%d/data/dump.rdb > %s 2>&1",
-		consts.TendisKeyLifecycleBin, t.basicDir, server.Port, allkeys)
+	cmdKeys := fmt.Sprintf("%s rdbstat -f %s > %s 2>&1", consts.TendisKeyLifecycleBin, rdbFile, allkeys)
 	mylog.Logger.Info(fmt.Sprintf("do parse keys %s:%s", server.Addr, cmdKeys))
 	if _, err := util.RunBashCmd(cmdKeys, "", nil, time.Hour); err != nil {
 		return 0, 0, err
@@ -230,11 +260,70 @@ func (t *Task) bigKeyWithRdb4Cache(server Instance, bkfile, kmfile string) (int6
 	return t.statRawKeysFileDetail(allkeys, bkfile, kmfile, server)
 }
 
+// statRdbFileDetail 内置rdb解析器统计大key和key模式,每行一个json写入 RdbBigKeyRp/RdbKeyModeRp
+func (t *Task) statRdbFileDetail(rdbFile string, server Instance) (int64, error) {
+	mylog.Logger.Info(fmt.Sprintf("do parse rdb %s:%s", server.Addr, rdbFile))
+	analyzer := rdbanalyzer.NewAnalyzer(t.conf.BigKeyConf.TopCnt, keyModeTopCnt, t.conf.BigKeyConf.KeyModSpec)
+	if err := analyzer.AnalyzeFile(rdbFile); err != nil {
+		return 0, err
+	}
+	summary := analyzer.Summary()
+	mylog.Logger.Info(fmt.Sprintf("parse rdb %s done,keys:%d,size:%d,cost:%ds",
+		rdbFile, summary.TotalKeys, summary.TotalSize, summary.CostSeconds))
+
+	meta := map[string]interface{}{
+		"ip":     server.IP,
+		"port":   server.Port,
+		"domain": server.Domain,
+		"app":    server.App,
+	}
+	if err := analyzer.ReportBigKeys(t.RdbBigKeyRp, meta); err != nil {
+		return 0, err
+	}
+	if err := analyzer.ReportKeyModes(t.RdbKeyModeRp, meta); err != nil {
+		return 0, err
+	}
+	return summary.TotalKeys, nil
+}
+
+// aofRdbPreamble aof-use-rdb-preamble 开启时,aof rewrite 后的base文件就是rdb格式
+func (t *Task) aofRdbPreamble(server Instance) string {
+	dataDir := fmt.Sprintf("%s/%d/data", t.basicDir, server.Port)
+	// redis 7.0+ multi part aof
+	bases, _ := filepath.Glob(filepath.Join(dataDir, "appendonlydir", "*.base.rdb"))
+	var latest string
+	var latestMod time.Time
+	for _, base := range bases {
+		if fi, err := os.Stat(base); err == nil && fi.ModTime().After(latestMod) {
+			latest, latestMod = base, fi.ModTime()
+		}
+	}
+	if latest != "" && rdbanalyzer.IsRdbFile(latest) {
+		return latest
+	}
+	aofFile := filepath.Join(dataDir, "appendonly.aof")
+	if rdbanalyzer.IsRdbFile(aofFile) {
+		return aofFile
+	}
+	return ""
+}
+
 func (t *Task) bigKeyWithAof4Cache(server Instance, bkfile, kmfile string) (int64, int64, error) {
 	if err := server.Cli.BgRewriteAOFAndWaitForDone(); err != nil {
 		mylog.Logger.Warn(fmt.Sprintf("aof rewrite failed :%+v", err))
 		return 0, 0, err
 	}
+	if rdbFile := t.aofRdbPreamble(server); rdbFile != "" {
+		rdbKeys, rdbErr := t.statRdbFileDetail(rdbFile, server)
+		if !util.FileExists(consts.TendisKeyLifecycleBin) {
+			return rdbKeys, 1, rdbErr
+		}
+		if rdbErr != nil {
+			mylog.Logger.Warn(fmt.Sprintf("rdb analyse failed %s:%+v", server.Addr, rdbErr))
+		}
+	} else if !util.FileExists(consts.TendisKeyLifecycleBin) {
+		return 0, 0, fmt.Errorf("aof without rdb preamble and file :%s does not exist", consts.TendisKeyLifecycleBin)
+	}
 
 	allkeys := fmt.Sprintf("v.%d.keys", server.Port)
 	cmdKeys := fmt.Sprintf("%s parseaof -f %s/%d/data/appendonly.aof > %s 2>&1",
@@ -309,6 +398,9 @@ func (t *Task) bigAndMode4TendisPlus(server Instance, bkfile, kmfile string) (in
 func (t *Task) statRawKeysFileDetail(keysFile string, bkFile string, kmFile string, server Instance) (int64, int64,
 	error) {
 	var err error
+	if !util.FileExists(consts.TendisKeyLifecycleBin) {
+		return 0, 0, fmt.Errorf("file :%s does not exist", consts.TendisKeyLifecycleBin)
+	}
 	keyLines, _ := util.GetFileLines(keysFile)
 	step, slptime, sample, confidence, adjfactor := getStatToolParams(keyLines)
 
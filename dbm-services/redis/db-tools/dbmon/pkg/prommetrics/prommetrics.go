@@ -0,0 +1,180 @@
+// Package prommetrics dbmon prometheus 指标
+package prommetrics
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dbmon"
+
+// LabelAddr 实例地址label(ip:port)
+// 不使用 instance,避免与 prometheus 抓取时自动添加的 target label 冲突
+const LabelAddr = "ip_port"
+
+// instanceLabels 实例类指标公共label
+var instanceLabels = []string{"bk_biz_id", "cluster_domain", "cluster_type", "role", LabelAddr}
+
+// Registry dbmon 指标注册表(不包含go runtime指标)
+var Registry = prometheus.NewRegistry()
+
+var (
+	// RedisUp redis 是否可连接
+	RedisUp = newInstanceGauge("redis_up", "redis instance connectable(1) or not(0)")
+	// RedisUsedMemory redis used_memory
+	RedisUsedMemory = newInstanceGauge("redis_used_memory_bytes", "redis info memory used_memory")
+	// RedisMaxmemory redis maxmemory
+	RedisMaxmemory = newInstanceGauge("redis_maxmemory_bytes", "redis config maxmemory, 0 means unlimited")
+	// RedisConnectedClients redis connected_clients
+	RedisConnectedClients = newInstanceGauge("redis_connected_clients", "redis info clients connected_clients")
+	// RedisKeyspaceHits redis keyspace_hits
+	RedisKeyspaceHits = NewInfoCounterVec("redis_keyspace_hits_total", "redis info stats keyspace_hits")
+	// RedisKeyspaceMisses redis keyspace_misses
+	RedisKeyspaceMisses = NewInfoCounterVec("redis_keyspace_misses_total", "redis info stats keyspace_misses")
+	// RedisSlaveReplLag master上看到的各slave复制offset落后字节数
+	RedisSlaveReplLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "redis_slave_repl_offset_lag_bytes",
+		Help:      "master_repl_offset minus slave offset, reported by master",
+	}, append(instanceLabels, "slave"))
+	// ProxyUp proxy 是否可连接
+	ProxyUp = newInstanceGauge("proxy_up", "twemproxy/predixy connectable(1) or not(0)")
+	// ProxyBackendUp proxy 后端是否正常
+	ProxyBackendUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "proxy_backend_up",
+		Help:      "twemproxy/predixy backend healthy(1) or not(0)",
+	}, append(instanceLabels, "backend"))
+)
+
+var instanceGauges = []*prometheus.GaugeVec{
+	RedisUp, RedisUsedMemory, RedisMaxmemory, RedisConnectedClients,
+	RedisSlaveReplLag, ProxyUp, ProxyBackendUp,
+}
+
+var instanceCounters = []*InfoCounterVec{RedisKeyspaceHits, RedisKeyspaceMisses}
+
+func init() {
+	for _, gauge := range instanceGauges {
+		Registry.MustRegister(gauge)
+	}
+	for _, counter := range instanceCounters {
+		Registry.MustRegister(counter)
+	}
+}
+
+func newInstanceGauge(name, help string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, instanceLabels)
+}
+
+// InstanceLabels 生成实例类指标label
+func InstanceLabels(bkBizID, clusterDomain, clusterType, role, addr string) prometheus.Labels {
+	return prometheus.Labels{
+		"bk_biz_id":      bkBizID,
+		"cluster_domain": clusterDomain,
+		"cluster_type":   clusterType,
+		"role":           role,
+		LabelAddr:        addr,
+	}
+}
+
+// AddrLabels 按实例地址匹配的label,用于 DeletePartialMatch
+func AddrLabels(addr string) prometheus.Labels {
+	return prometheus.Labels{LabelAddr: addr}
+}
+
+// DeleteInstance 删除某个实例的所有指标
+// 实例下架、角色切换后避免残留旧指标
+func DeleteInstance(addr string) {
+	for _, gauge := range instanceGauges {
+		gauge.DeletePartialMatch(AddrLabels(addr))
+	}
+	for _, counter := range instanceCounters {
+		counter.DeletePartialMatch(AddrLabels(addr))
+	}
+}
+
+// InfoCounterVec redis info 中的累计值(如 keyspace_hits),以 counter 类型导出
+// 值由 info 结果直接设置,redis 重启后归零由 prometheus rate/increase 按计数器重置处理
+type InfoCounterVec struct {
+	desc   *prometheus.Desc
+	mu     sync.Mutex
+	values map[string]infoCounterValue // key: label values
+}
+
+type infoCounterValue struct {
+	labels prometheus.Labels
+	value  float64
+}
+
+// NewInfoCounterVec new
+func NewInfoCounterVec(name, help string) *InfoCounterVec {
+	return &InfoCounterVec{
+		desc:   prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, instanceLabels, nil),
+		values: map[string]infoCounterValue{},
+	}
+}
+
+func (c *InfoCounterVec) labelValues(labels prometheus.Labels) []string {
+	vals := make([]string, 0, len(instanceLabels))
+	for _, name := range instanceLabels {
+		vals = append(vals, labels[name])
+	}
+	return vals
+}
+
+// Set 设置累计值
+func (c *InfoCounterVec) Set(labels prometheus.Labels, value float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := strings.Join(c.labelValues(labels), "\xff")
+	c.values[key] = infoCounterValue{labels: labels, value: value}
+}
+
+// DeletePartialMatch 删除label包含 labels 的所有值,返回删除个数
+func (c *InfoCounterVec) DeletePartialMatch(labels prometheus.Labels) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	deleted := 0
+	for key, item := range c.values {
+		match := true
+		for name, val := range labels {
+			if item.labels[name] != val {
+				match = false
+				break
+			}
+		}
+		if match {
+			delete(c.values, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Describe 实现 prometheus.Collector
+func (c *InfoCounterVec) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect 实现 prometheus.Collector
+func (c *InfoCounterVec) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, item := range c.values {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, item.value, c.labelValues(item.labels)...)
+	}
+}
+
+// Handler /metrics handler
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,277 @@
+package rdbanalyzer
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// KeyModeOthers key模式数量超过上限后,新模式都归入此模式
+	KeyModeOthers = "__others__"
+	// DefaultTopN 默认大key个数
+	DefaultTopN = 100
+	// DefaultKeyModeTopN 默认key模式个数
+	DefaultKeyModeTopN = 30
+	// maxKeyModes 内存中最多保留的key模式个数
+	maxKeyModes = 100000
+	// keyModeDelimiters key 分段分隔符
+	keyModeDelimiters = ":_|.-#/"
+)
+
+// KeyModeStat key模式统计
+type KeyModeStat struct {
+	KeyMode    string `json:"keymode"`
+	Keys       int64  `json:"keys"`
+	Size       int64  `json:"size"`
+	Elements   int64  `json:"elements"`
+	ExpireKeys int64  `json:"expire_keys"`
+	MaxSize    int64  `json:"max_size"`
+	// SampleKey 该模式下size最大的key
+	SampleKey string `json:"sample_key"`
+}
+
+// TypeStat 按数据类型统计
+type TypeStat struct {
+	Type     string `json:"type"`
+	Keys     int64  `json:"keys"`
+	Size     int64  `json:"size"`
+	Elements int64  `json:"elements"`
+}
+
+// Summary 统计汇总
+type Summary struct {
+	RdbVersion  int                  `json:"rdb_version"`
+	RedisVer    string               `json:"redis_version"`
+	TotalKeys   int64                `json:"total_keys"`
+	TotalSize   int64                `json:"total_size"`
+	ExpireKeys  int64                `json:"expire_keys"`
+	ExpiredKeys int64                `json:"expired_keys"`
+	Types       map[string]*TypeStat `json:"types"`
+	CostSeconds int64                `json:"cost_seconds"`
+}
+
+// BigKey 大key
+type BigKey struct {
+	Entry
+	// TTL 剩余过期秒数, -1 不过期
+	TTL int64 `json:"ttl"`
+}
+
+// Analyzer 汇总rdb中每个key的统计信息
+type Analyzer struct {
+	topN        int
+	keyModeTopN int
+	keyModSpec  []string
+	now         time.Time
+
+	summary  Summary
+	bigKeys  bigKeyHeap
+	keyModes map[string]*KeyModeStat
+}
+
+// NewAnalyzer new
+// keyModSpec 业务指定的key前缀,多个以逗号分隔,如 "user:,order:*",优先于自动识别的模式
+func NewAnalyzer(topN, keyModeTopN int, keyModSpec string) *Analyzer {
+	if topN <= 0 {
+		topN = DefaultTopN
+	}
+	if keyModeTopN <= 0 {
+		keyModeTopN = DefaultKeyModeTopN
+	}
+	a := &Analyzer{
+		topN:        topN,
+		keyModeTopN: keyModeTopN,
+		now:         time.Now(),
+		keyModes:    map[string]*KeyModeStat{},
+		summary:     Summary{Types: map[string]*TypeStat{}},
+	}
+	for _, spec := range strings.Split(keyModSpec, ",") {
+		spec = strings.TrimSuffix(strings.TrimSpace(spec), "*")
+		if spec != "" {
+			a.keyModSpec = append(a.keyModSpec, spec)
+		}
+	}
+	return a
+}
+
+// AnalyzeFile 解析并统计rdb文件
+func (a *Analyzer) AnalyzeFile(file string) error {
+	start := time.Now()
+	p, err := ParseFile(file, a.Add)
+	if p != nil {
+		a.summary.RdbVersion = p.Version
+		a.summary.RedisVer = p.Aux["redis-ver"]
+	}
+	a.summary.CostSeconds = int64(time.Since(start).Seconds())
+	return err
+}
+
+// Add 统计一个key
+func (a *Analyzer) Add(e *Entry) error {
+	a.summary.TotalKeys++
+	a.summary.TotalSize += e.Size
+	if e.ExpireAt > 0 {
+		a.summary.ExpireKeys++
+		if e.ExpireAt <= a.now.UnixMilli() {
+			a.summary.ExpiredKeys++
+		}
+	}
+	ts, ok := a.summary.Types[e.Type]
+	if !ok {
+		ts = &TypeStat{Type: e.Type}
+		a.summary.Types[e.Type] = ts
+	}
+	ts.Keys++
+	ts.Size += e.Size
+	ts.Elements += e.Elements
+
+	a.addKeyMode(e)
+
+	if a.bigKeys.Len() < a.topN {
+		heap.Push(&a.bigKeys, a.toBigKey(e))
+	} else if a.bigKeys[0].Size < e.Size {
+		a.bigKeys[0] = a.toBigKey(e)
+		heap.Fix(&a.bigKeys, 0)
+	}
+	return nil
+}
+
+func (a *Analyzer) toBigKey(e *Entry) *BigKey {
+	bk := &BigKey{Entry: *e, TTL: -1}
+	if e.ExpireAt > 0 {
+		bk.TTL = (e.ExpireAt - a.now.UnixMilli()) / 1000
+		if bk.TTL < 0 {
+			bk.TTL = 0
+		}
+	}
+	return bk
+}
+
+func (a *Analyzer) addKeyMode(e *Entry) {
+	mode := a.KeyMode(e.Key)
+	km, ok := a.keyModes[mode]
+	if !ok {
+		if len(a.keyModes) >= maxKeyModes {
+			mode = KeyModeOthers
+			km = a.keyModes[mode]
+		}
+		if km == nil {
+			km = &KeyModeStat{KeyMode: mode}
+			a.keyModes[mode] = km
+		}
+	}
+	km.Keys++
+	km.Size += e.Size
+	km.Elements += e.Elements
+	if e.ExpireAt > 0 {
+		km.ExpireKeys++
+	}
+	if e.Size > km.MaxSize {
+		km.MaxSize = e.Size
+		km.SampleKey = e.Key
+	}
+}
+
+// KeyMode 计算key的模式:
+// 1. 命中业务指定前缀,返回 前缀+*
+// 2. 否则按分隔符切分,含数字或是长十六进制串的分段替换为 *
+func (a *Analyzer) KeyMode(key string) string {
+	for _, spec := range a.keyModSpec {
+		if strings.HasPrefix(key, spec) {
+			return spec + "*"
+		}
+	}
+	var sb strings.Builder
+	segStart := 0
+	flush := func(end int) {
+		seg := key[segStart:end]
+		if isVariableSegment(seg) {
+			sb.WriteByte('*')
+		} else {
+			sb.WriteString(seg)
+		}
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(keyModeDelimiters, key[i]) >= 0 {
+			flush(i)
+			sb.WriteByte(key[i])
+			segStart = i + 1
+		}
+	}
+	flush(len(key))
+	return sb.String()
+}
+
+// isVariableSegment 分段是否是变量(id、时间、uuid等)
+func isVariableSegment(seg string) bool {
+	if seg == "" {
+		return false
+	}
+	hex := len(seg) >= 16
+	for _, c := range seg {
+		if unicode.IsDigit(c) {
+			return true
+		}
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			hex = false
+		}
+	}
+	return hex
+}
+
+// Summary 汇总信息
+func (a *Analyzer) Summary() Summary {
+	return a.summary
+}
+
+// BigKeys 按size降序的大key
+func (a *Analyzer) BigKeys() []*BigKey {
+	ret := make([]*BigKey, len(a.bigKeys))
+	copy(ret, a.bigKeys)
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Size > ret[j].Size
+	})
+	return ret
+}
+
+// KeyModes 按size降序的前 keyModeTopN 个key模式
+func (a *Analyzer) KeyModes() []*KeyModeStat {
+	ret := make([]*KeyModeStat, 0, len(a.keyModes))
+	for _, km := range a.keyModes {
+		ret = append(ret, km)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Size == ret[j].Size {
+			return ret[i].KeyMode < ret[j].KeyMode
+		}
+		return ret[i].Size > ret[j].Size
+	})
+	if len(ret) > a.keyModeTopN {
+		ret = ret[:a.keyModeTopN]
+	}
+	return ret
+}
+
+// bigKeyHeap 按size的小顶堆
+type bigKeyHeap []*BigKey
+
+func (h bigKeyHeap) Len() int           { return len(h) }
+func (h bigKeyHeap) Less(i, j int) bool { return h[i].Size < h[j].Size }
+func (h bigKeyHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+// Push heap.Interface
+func (h *bigKeyHeap) Push(x interface{}) {
+	*h = append(*h, x.(*BigKey))
+}
+
+// Pop heap.Interface
+func (h *bigKeyHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
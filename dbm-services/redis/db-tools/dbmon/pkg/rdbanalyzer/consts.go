@@ -0,0 +1,104 @@
+// Package rdbanalyzer 纯Go实现的RDB解析与key统计(大key、key模式、过期时间)
+// 支持 RDB version <= 12 (Redis 7.x), 包括 listpack/quicklist2/stream,
+// module 类型的value会被跳过,不做解析; Redis 7.4 hash field 过期相关的编码暂不支持.
+package rdbanalyzer
+
+// rdb 文件魔数及支持的最大版本
+const (
+	rdbMagic      = "REDIS"
+	rdbMaxVersion = 12
+)
+
+// rdb opcode
+const (
+	opFunction2      = 245
+	opFunctionPreGA  = 246
+	opModuleAux      = 247
+	opIdle           = 248
+	opFreq           = 249
+	opAux            = 250
+	opResizeDB       = 251
+	opExpireTimeMs   = 252
+	opExpireTime     = 253
+	opSelectDB       = 254
+	opEOF            = 255
+	encValInt8       = 0
+	encValInt16      = 1
+	encValInt32      = 2
+	encValLZF        = 3
+	len6Bit          = 0
+	len14Bit         = 1
+	len32Or64Bit     = 2
+	lenEncVal        = 3
+	len32Bit         = 0x80
+	len64Bit         = 0x81
+	moduleOpcodeEOF  = 0
+	moduleOpcodeSInt = 1
+	moduleOpcodeUInt = 2
+	moduleOpcodeFlt  = 3
+	moduleOpcodeDbl  = 4
+	moduleOpcodeStr  = 5
+	quicklistPlain   = 1
+	quicklistPacked  = 2
+)
+
+// rdb value type
+const (
+	typeString          = 0
+	typeList            = 1
+	typeSet             = 2
+	typeZset            = 3
+	typeHash            = 4
+	typeZset2           = 5
+	typeModulePreGA     = 6
+	typeModule2         = 7
+	typeHashZipmap      = 9
+	typeListZiplist     = 10
+	typeSetIntset       = 11
+	typeZsetZiplist     = 12
+	typeHashZiplist     = 13
+	typeListQuicklist   = 14
+	typeStreamListpacks = 15
+	typeHashListpack    = 16
+	typeZsetListpack    = 17
+	typeListQuicklist2  = 18
+	typeStreamListpack2 = 19
+	typeSetListpack     = 20
+	typeStreamListpack3 = 21
+)
+
+// key 数据类型名称
+const (
+	KeyTypeString = "string"
+	KeyTypeList   = "list"
+	KeyTypeSet    = "set"
+	KeyTypeZset   = "zset"
+	KeyTypeHash   = "hash"
+	KeyTypeStream = "stream"
+	KeyTypeModule = "module"
+)
+
+// valueTypeDesc rdb value type -> (key类型,编码)
+var valueTypeDesc = map[byte][2]string{
+	typeString:          {KeyTypeString, "string"},
+	typeList:            {KeyTypeList, "linkedlist"},
+	typeSet:             {KeyTypeSet, "hashtable"},
+	typeZset:            {KeyTypeZset, "skiplist"},
+	typeHash:            {KeyTypeHash, "hashtable"},
+	typeZset2:           {KeyTypeZset, "skiplist"},
+	typeModulePreGA:     {KeyTypeModule, "module"},
+	typeModule2:         {KeyTypeModule, "module"},
+	typeHashZipmap:      {KeyTypeHash, "zipmap"},
+	typeListZiplist:     {KeyTypeList, "ziplist"},
+	typeSetIntset:       {KeyTypeSet, "intset"},
+	typeZsetZiplist:     {KeyTypeZset, "ziplist"},
+	typeHashZiplist:     {KeyTypeHash, "ziplist"},
+	typeListQuicklist:   {KeyTypeList, "quicklist"},
+	typeStreamListpacks: {KeyTypeStream, "listpacks"},
+	typeHashListpack:    {KeyTypeHash, "listpack"},
+	typeZsetListpack:    {KeyTypeZset, "listpack"},
+	typeListQuicklist2:  {KeyTypeList, "quicklist"},
+	typeStreamListpack2: {KeyTypeStream, "listpacks"},
+	typeSetListpack:     {KeyTypeSet, "listpack"},
+	typeStreamListpack3: {KeyTypeStream, "listpacks"},
+}
@@ -0,0 +1,175 @@
+package rdbanalyzer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ziplistLen ziplist 元素个数,zllen 溢出(65535)时遍历计数
+func ziplistLen(zl []byte) (int64, error) {
+	if len(zl) < 11 {
+		return 0, fmt.Errorf("ziplist too short:%d", len(zl))
+	}
+	if zllen := binary.LittleEndian.Uint16(zl[8:10]); zllen < 0xffff {
+		return int64(zllen), nil
+	}
+	var count int64
+	pos := 10
+	for pos < len(zl) && zl[pos] != 0xff {
+		// prevlen
+		if zl[pos] < 254 {
+			pos++
+		} else {
+			pos += 5
+		}
+		if pos >= len(zl) {
+			return 0, fmt.Errorf("ziplist entry out of range")
+		}
+		enc := zl[pos]
+		switch enc >> 6 {
+		case 0:
+			pos += 1 + int(enc&0x3f)
+		case 1:
+			if pos+1 >= len(zl) {
+				return 0, fmt.Errorf("ziplist entry out of range")
+			}
+			pos += 2 + (int(enc&0x3f)<<8 | int(zl[pos+1]))
+		case 2:
+			if pos+4 >= len(zl) {
+				return 0, fmt.Errorf("ziplist entry out of range")
+			}
+			pos += 5 + int(binary.BigEndian.Uint32(zl[pos+1:pos+5]))
+		default:
+			switch {
+			case enc == 0xc0:
+				pos += 3
+			case enc == 0xd0:
+				pos += 5
+			case enc == 0xe0:
+				pos += 9
+			case enc == 0xf0:
+				pos += 4
+			case enc == 0xfe:
+				pos += 2
+			case enc >= 0xf1 && enc <= 0xfd:
+				pos++
+			default:
+				return 0, fmt.Errorf("unknown ziplist encoding 0x%x", enc)
+			}
+		}
+		count++
+	}
+	return count, nil
+}
+
+// listpackLen listpack 元素个数,num-elements 溢出(65535)时遍历计数
+func listpackLen(lp []byte) (int64, error) {
+	if len(lp) < 7 {
+		return 0, fmt.Errorf("listpack too short:%d", len(lp))
+	}
+	if num := binary.LittleEndian.Uint16(lp[4:6]); num < 0xffff {
+		return int64(num), nil
+	}
+	var count int64
+	pos := 6
+	for pos < len(lp) && lp[pos] != 0xff {
+		enc := lp[pos]
+		var entryLen int
+		switch {
+		case enc&0x80 == 0: // 7bit uint
+			entryLen = 1
+		case enc&0xc0 == 0x80: // 6bit str
+			entryLen = 1 + int(enc&0x3f)
+		case enc&0xe0 == 0xc0: // 13bit int
+			entryLen = 2
+		case enc&0xf0 == 0xe0: // 12bit str
+			if pos+1 >= len(lp) {
+				return 0, fmt.Errorf("listpack entry out of range")
+			}
+			entryLen = 2 + (int(enc&0x0f)<<8 | int(lp[pos+1]))
+		case enc == 0xf0: // 32bit str
+			if pos+4 >= len(lp) {
+				return 0, fmt.Errorf("listpack entry out of range")
+			}
+			entryLen = 5 + int(binary.LittleEndian.Uint32(lp[pos+1:pos+5]))
+		case enc == 0xf1:
+			entryLen = 3
+		case enc == 0xf2:
+			entryLen = 4
+		case enc == 0xf3:
+			entryLen = 5
+		case enc == 0xf4:
+			entryLen = 9
+		default:
+			return 0, fmt.Errorf("unknown listpack encoding 0x%x", enc)
+		}
+		pos += entryLen + listpackBacklenSize(entryLen)
+		count++
+	}
+	return count, nil
+}
+
+func listpackBacklenSize(l int) int {
+	switch {
+	case l < 128:
+		return 1
+	case l < 16384:
+		return 2
+	case l < 2097152:
+		return 3
+	case l < 268435456:
+		return 4
+	}
+	return 5
+}
+
+// intsetLen intset 元素个数
+func intsetLen(is []byte) (int64, error) {
+	if len(is) < 8 {
+		return 0, fmt.Errorf("intset too short:%d", len(is))
+	}
+	return int64(binary.LittleEndian.Uint32(is[4:8])), nil
+}
+
+// zipmapLen zipmap 中 field 个数,zmlen>=254时遍历计数
+func zipmapLen(zm []byte) (int64, error) {
+	if len(zm) < 2 {
+		return 0, fmt.Errorf("zipmap too short:%d", len(zm))
+	}
+	if zm[0] < 254 {
+		return int64(zm[0]), nil
+	}
+	readLen := func(pos int) (int, int, error) {
+		if pos >= len(zm) {
+			return 0, 0, fmt.Errorf("zipmap entry out of range")
+		}
+		if zm[pos] < 254 {
+			return int(zm[pos]), 1, nil
+		}
+		if pos+4 >= len(zm) {
+			return 0, 0, fmt.Errorf("zipmap entry out of range")
+		}
+		return int(binary.LittleEndian.Uint32(zm[pos+1 : pos+5])), 5, nil
+	}
+	var count int64
+	pos := 1
+	for pos < len(zm) && zm[pos] != 0xff {
+		klen, n, err := readLen(pos)
+		if err != nil {
+			return 0, err
+		}
+		pos += n + klen
+		vlen, n, err := readLen(pos)
+		if err != nil {
+			return 0, err
+		}
+		pos += n
+		if pos >= len(zm) {
+			return 0, fmt.Errorf("zipmap entry out of range")
+		}
+		free := int(zm[pos])
+		pos += 1 + vlen + free
+		count++
+	}
+	return count, nil
+}
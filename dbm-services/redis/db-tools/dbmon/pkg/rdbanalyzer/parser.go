@@ -0,0 +1,472 @@
+package rdbanalyzer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// ErrStopParse 回调返回该错误时停止解析,Parse 返回 nil
+var ErrStopParse = errors.New("stop parse")
+
+// Entry rdb 中一个key的统计信息
+type Entry struct {
+	DB       int    `json:"db"`
+	Key      string `json:"key"`
+	Type     string `json:"type"`
+	Encoding string `json:"encoding"`
+	// Size key+value 序列化后的字节数
+	Size int64 `json:"size"`
+	// Elements 元素个数, string 为1
+	Elements int64 `json:"elements"`
+	// ExpireAt 过期时间(毫秒时间戳), 0 表示不过期
+	ExpireAt int64 `json:"expire_at"`
+	// LFUFreq maxmemory-policy 为 lfu 时才有, -1 表示无
+	LFUFreq int `json:"lfu_freq"`
+	// Idle maxmemory-policy 为 lru 时才有(秒), -1 表示无
+	Idle int64 `json:"idle"`
+}
+
+// Parser rdb 解析器
+type Parser struct {
+	rd      *rdbReader
+	Version int               `json:"version"`
+	Aux     map[string]string `json:"aux"`
+}
+
+// NewParser new
+func NewParser(r io.Reader) *Parser {
+	return &Parser{
+		rd:  newRdbReader(r),
+		Aux: map[string]string{},
+	}
+}
+
+// ParseFile 解析rdb文件,每个key回调一次fn
+func ParseFile(file string, fn func(e *Entry) error) (p *Parser, err error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("open rdb file %s fail,err:%v", file, err)
+	}
+	defer fh.Close()
+	p = NewParser(fh)
+	if err = p.Parse(fn); err != nil {
+		return p, fmt.Errorf("parse rdb file %s fail,err:%v", file, err)
+	}
+	return p, nil
+}
+
+// IsRdbFile 文件是否以rdb魔数开头(aof-use-rdb-preamble 的aof也是rdb开头)
+func IsRdbFile(file string) bool {
+	fh, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer fh.Close()
+	buf := make([]byte, len(rdbMagic))
+	if _, err = io.ReadFull(fh, buf); err != nil {
+		return false
+	}
+	return string(buf) == rdbMagic
+}
+
+// Parse 解析rdb,遇到 EOF opcode 结束(之后的aof内容会被忽略)
+func (p *Parser) Parse(fn func(e *Entry) error) (err error) {
+	if err = p.readHeader(); err != nil {
+		return err
+	}
+	db := 0
+	entry := newEntry(db)
+	// entryStart 当前key第一个opcode(过期时间等或value type)的偏移, -1 表示还没有开始
+	entryStart := int64(-1)
+	for {
+		start := p.rd.pos
+		op, err := p.rd.readByte()
+		if err != nil {
+			return fmt.Errorf("read opcode fail,err:%v", err)
+		}
+		switch op {
+		case opEOF:
+			return nil
+		case opSelectDB:
+			dbIdx, err := p.rd.readLen()
+			if err != nil {
+				return err
+			}
+			db = int(dbIdx)
+			entry, entryStart = newEntry(db), -1
+			continue
+		case opResizeDB:
+			if _, err = p.rd.readLen(); err != nil {
+				return err
+			}
+			if _, err = p.rd.readLen(); err != nil {
+				return err
+			}
+			continue
+		case opAux:
+			key, err := p.rd.readString()
+			if err != nil {
+				return err
+			}
+			val, err := p.rd.readString()
+			if err != nil {
+				return err
+			}
+			p.Aux[string(key)] = string(val)
+			continue
+		case opModuleAux:
+			if err = p.skipModuleAux(); err != nil {
+				return err
+			}
+			continue
+		case opFunction2:
+			if err = p.rd.skipString(); err != nil {
+				return err
+			}
+			continue
+		case opFunctionPreGA:
+			return fmt.Errorf("rdb function pre-GA opcode is not supported")
+		case opExpireTime:
+			sec, err := p.rd.readUint32LE()
+			if err != nil {
+				return err
+			}
+			entry.ExpireAt = int64(sec) * 1000
+			entryStart = entryPos(entryStart, start)
+			continue
+		case opExpireTimeMs:
+			ms, err := p.rd.readUint64LE()
+			if err != nil {
+				return err
+			}
+			entry.ExpireAt = int64(ms)
+			entryStart = entryPos(entryStart, start)
+			continue
+		case opFreq:
+			freq, err := p.rd.readByte()
+			if err != nil {
+				return err
+			}
+			entry.LFUFreq = int(freq)
+			entryStart = entryPos(entryStart, start)
+			continue
+		case opIdle:
+			idle, err := p.rd.readLen()
+			if err != nil {
+				return err
+			}
+			entry.Idle = int64(idle)
+			entryStart = entryPos(entryStart, start)
+			continue
+		}
+
+		// 剩下的都是 value type, 过期时间等opcode的字节也计入key的大小
+		entryStart = entryPos(entryStart, start)
+		desc, ok := valueTypeDesc[op]
+		if !ok {
+			return fmt.Errorf("unknown value type %d at offset %d", op, p.rd.pos-1)
+		}
+		key, err := p.rd.readString()
+		if err != nil {
+			return fmt.Errorf("read key fail,err:%v", err)
+		}
+		entry.Key = string(key)
+		entry.Type, entry.Encoding = desc[0], desc[1]
+		if entry.Elements, err = p.skipValue(op); err != nil {
+			return fmt.Errorf("read value of key %q fail,err:%v", entry.Key, err)
+		}
+		entry.Size = p.rd.pos - entryStart
+		if err = fn(entry); err != nil {
+			if errors.Is(err, ErrStopParse) {
+				return nil
+			}
+			return err
+		}
+		entry, entryStart = newEntry(db), -1
+	}
+}
+
+// entryPos key 还没有开始时返回当前opcode的偏移
+func entryPos(entryStart, pos int64) int64 {
+	if entryStart < 0 {
+		return pos
+	}
+	return entryStart
+}
+
+func newEntry(db int) *Entry {
+	return &Entry{DB: db, LFUFreq: -1, Idle: -1}
+}
+
+func (p *Parser) readHeader() error {
+	buf, err := p.rd.readFull(9)
+	if err != nil {
+		return fmt.Errorf("read rdb header fail,err:%v", err)
+	}
+	if string(buf[:5]) != rdbMagic {
+		return fmt.Errorf("bad rdb magic %q", string(buf[:5]))
+	}
+	if p.Version, err = strconv.Atoi(string(buf[5:])); err != nil {
+		return fmt.Errorf("bad rdb version %q", string(buf[5:]))
+	}
+	if p.Version < 1 || p.Version > rdbMaxVersion {
+		return fmt.Errorf("rdb version %d is not supported", p.Version)
+	}
+	return nil
+}
+
+// skipValue 跳过value,返回元素个数
+func (p *Parser) skipValue(valueType byte) (int64, error) {
+	rd := p.rd
+	switch valueType {
+	case typeString:
+		return 1, rd.skipString()
+	case typeList, typeSet:
+		n, err := rd.readLen()
+		if err != nil {
+			return 0, err
+		}
+		for i := uint64(0); i < n; i++ {
+			if err = rd.skipString(); err != nil {
+				return 0, err
+			}
+		}
+		return int64(n), nil
+	case typeZset, typeZset2:
+		n, err := rd.readLen()
+		if err != nil {
+			return 0, err
+		}
+		for i := uint64(0); i < n; i++ {
+			if err = rd.skipString(); err != nil {
+				return 0, err
+			}
+			if valueType == typeZset2 {
+				err = rd.skip(8)
+			} else {
+				err = rd.skipDouble()
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		return int64(n), nil
+	case typeHash:
+		n, err := rd.readLen()
+		if err != nil {
+			return 0, err
+		}
+		for i := uint64(0); i < n*2; i++ {
+			if err = rd.skipString(); err != nil {
+				return 0, err
+			}
+		}
+		return int64(n), nil
+	case typeHashZipmap:
+		return p.readBlobLen(zipmapLen, 1)
+	case typeListZiplist:
+		return p.readBlobLen(ziplistLen, 1)
+	case typeSetIntset:
+		return p.readBlobLen(intsetLen, 1)
+	case typeZsetZiplist, typeHashZiplist:
+		return p.readBlobLen(ziplistLen, 2)
+	case typeHashListpack, typeZsetListpack:
+		return p.readBlobLen(listpackLen, 2)
+	case typeSetListpack:
+		return p.readBlobLen(listpackLen, 1)
+	case typeListQuicklist, typeListQuicklist2:
+		return p.skipQuicklist(valueType)
+	case typeStreamListpacks, typeStreamListpack2, typeStreamListpack3:
+		return p.skipStream(valueType)
+	case typeModule2:
+		if _, err := rd.readLen(); err != nil { // module id
+			return 0, err
+		}
+		return 0, p.skipModuleValue()
+	case typeModulePreGA:
+		return 0, fmt.Errorf("module pre-GA value type is not supported")
+	}
+	return 0, fmt.Errorf("unknown value type %d", valueType)
+}
+
+// readBlobLen 读取ziplist/listpack等编码的字符串并计算元素个数
+func (p *Parser) readBlobLen(lenFunc func([]byte) (int64, error), div int64) (int64, error) {
+	blob, err := p.rd.readString()
+	if err != nil {
+		return 0, err
+	}
+	n, err := lenFunc(blob)
+	if err != nil {
+		return 0, err
+	}
+	return n / div, nil
+}
+
+func (p *Parser) skipQuicklist(valueType byte) (int64, error) {
+	nodes, err := p.rd.readLen()
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for i := uint64(0); i < nodes; i++ {
+		if valueType == typeListQuicklist {
+			n, err := p.readBlobLen(ziplistLen, 1)
+			if err != nil {
+				return 0, err
+			}
+			total += n
+			continue
+		}
+		container, err := p.rd.readLen()
+		if err != nil {
+			return 0, err
+		}
+		if container == quicklistPlain {
+			if err = p.rd.skipString(); err != nil {
+				return 0, err
+			}
+			total++
+			continue
+		}
+		if container != quicklistPacked {
+			return 0, fmt.Errorf("unknown quicklist container %d", container)
+		}
+		n, err := p.readBlobLen(listpackLen, 1)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// skipStream 跳过stream,返回消息条数
+func (p *Parser) skipStream(valueType byte) (int64, error) {
+	rd := p.rd
+	skipLens := func(n int) error {
+		for i := 0; i < n; i++ {
+			if _, err := rd.readLen(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	listpacks, err := rd.readLen()
+	if err != nil {
+		return 0, err
+	}
+	for i := uint64(0); i < listpacks*2; i++ { // master id + listpack
+		if err = rd.skipString(); err != nil {
+			return 0, err
+		}
+	}
+	length, err := rd.readLen()
+	if err != nil {
+		return 0, err
+	}
+	// last_id
+	if err = skipLens(2); err != nil {
+		return 0, err
+	}
+	if valueType >= typeStreamListpack2 {
+		// first_id, max_deleted_entry_id, entries_added
+		if err = skipLens(5); err != nil {
+			return 0, err
+		}
+	}
+	groups, err := rd.readLen()
+	if err != nil {
+		return 0, err
+	}
+	for i := uint64(0); i < groups; i++ {
+		if err = rd.skipString(); err != nil {
+			return 0, err
+		}
+		if err = skipLens(2); err != nil {
+			return 0, err
+		}
+		if valueType >= typeStreamListpack2 {
+			if err = skipLens(1); err != nil { // entries_read
+				return 0, err
+			}
+		}
+		pel, err := rd.readLen()
+		if err != nil {
+			return 0, err
+		}
+		for j := uint64(0); j < pel; j++ {
+			// stream id(16) + delivery time(8)
+			if err = rd.skip(24); err != nil {
+				return 0, err
+			}
+			if err = skipLens(1); err != nil { // delivery count
+				return 0, err
+			}
+		}
+		consumers, err := rd.readLen()
+		if err != nil {
+			return 0, err
+		}
+		for j := uint64(0); j < consumers; j++ {
+			if err = rd.skipString(); err != nil {
+				return 0, err
+			}
+			timeLen := uint64(8) // seen time
+			if valueType >= typeStreamListpack3 {
+				timeLen += 8 // active time
+			}
+			if err = rd.skip(timeLen); err != nil {
+				return 0, err
+			}
+			cpel, err := rd.readLen()
+			if err != nil {
+				return 0, err
+			}
+			if err = rd.skip(cpel * 16); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return int64(length), nil
+}
+
+// skipModuleAux 跳过 module aux 数据
+func (p *Parser) skipModuleAux() error {
+	// module id, when opcode, when
+	for i := 0; i < 3; i++ {
+		if _, err := p.rd.readLen(); err != nil {
+			return err
+		}
+	}
+	return p.skipModuleValue()
+}
+
+// skipModuleValue 跳过 module value, 依赖 module v2 的 opcode 序列化格式
+func (p *Parser) skipModuleValue() error {
+	for {
+		opcode, err := p.rd.readLen()
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case moduleOpcodeEOF:
+			return nil
+		case moduleOpcodeSInt, moduleOpcodeUInt:
+			_, err = p.rd.readLen()
+		case moduleOpcodeFlt:
+			err = p.rd.skip(4)
+		case moduleOpcodeDbl:
+			err = p.rd.skip(8)
+		case moduleOpcodeStr:
+			err = p.rd.skipString()
+		default:
+			return fmt.Errorf("unknown module opcode %d", opcode)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
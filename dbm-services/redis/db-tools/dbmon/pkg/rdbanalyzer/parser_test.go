@@ -0,0 +1,159 @@
+package rdbanalyzer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+type rdbBuilder struct {
+	bytes.Buffer
+}
+
+func (b *rdbBuilder) length(n int) {
+	switch {
+	case n < 64:
+		b.WriteByte(byte(n))
+	case n < 16384:
+		b.WriteByte(byte(0x40 | n>>8))
+		b.WriteByte(byte(n))
+	default:
+		b.WriteByte(len32Bit)
+		buf := make([]byte, 4)
+		binary.BigEndian.PutUint32(buf, uint32(n))
+		b.Write(buf)
+	}
+}
+
+func (b *rdbBuilder) str(s string) {
+	b.length(len(s))
+	b.WriteString(s)
+}
+
+// listpack 构造只包含小字符串元素的listpack
+func listpack(elems ...string) string {
+	var body bytes.Buffer
+	for _, e := range elems {
+		body.WriteByte(0x80 | byte(len(e)))
+		body.WriteString(e)
+		body.WriteByte(byte(1 + len(e)))
+	}
+	buf := make([]byte, 6, 6+body.Len()+1)
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(6+body.Len()+1))
+	binary.LittleEndian.PutUint16(buf[4:6], uint16(len(elems)))
+	buf = append(buf, body.Bytes()...)
+	return string(append(buf, 0xff))
+}
+
+func buildTestRdb() []byte {
+	b := &rdbBuilder{}
+	b.WriteString("REDIS0011")
+	b.WriteByte(opAux)
+	b.str("redis-ver")
+	b.str("7.2.4")
+	b.WriteByte(opSelectDB)
+	b.length(0)
+	b.WriteByte(opResizeDB)
+	b.length(5)
+	b.length(1)
+
+	// string with expire
+	b.WriteByte(opExpireTimeMs)
+	expire := make([]byte, 8)
+	binary.LittleEndian.PutUint64(expire, uint64(time.Now().Add(time.Hour).UnixMilli()))
+	b.Write(expire)
+	b.WriteByte(typeString)
+	b.str("user:1001:name")
+	b.str("tom")
+
+	// lzf compressed string "aaaaaaaa"
+	b.WriteByte(typeString)
+	b.str("user:1002:name")
+	b.WriteByte(0xc0 | encValLZF)
+	b.length(4)
+	b.length(8)
+	b.Write([]byte{0x00, 'a', 0xa0, 0x00})
+
+	// hash listpack
+	b.WriteByte(typeHashListpack)
+	b.str("order:20240101:detail")
+	b.str(listpack("f1", "v1", "f2", "v2", "f3", "v3"))
+
+	// list quicklist2, one packed node and one plain node
+	b.WriteByte(opFreq)
+	b.WriteByte(9)
+	b.WriteByte(typeListQuicklist2)
+	b.str("queue:tasks")
+	b.length(2)
+	b.length(quicklistPacked)
+	b.str(listpack("a", "b", "c"))
+	b.length(quicklistPlain)
+	b.str("plain-node-value")
+
+	// empty stream
+	b.WriteByte(typeStreamListpack3)
+	b.str("stream:events")
+	b.length(0)
+	b.length(0)
+	b.length(0)
+	b.length(0)
+	for i := 0; i < 5; i++ {
+		b.length(0)
+	}
+	b.length(0)
+
+	b.WriteByte(opEOF)
+	b.Write(make([]byte, 8))
+	return b.Bytes()
+}
+
+// test unit
+func TestParseRdb(t *testing.T) {
+	convey.Convey("parse rdb", t, func() {
+		entries := map[string]Entry{}
+		p := NewParser(bytes.NewReader(buildTestRdb()))
+		err := p.Parse(func(e *Entry) error {
+			entries[e.Key] = *e
+			return nil
+		})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(p.Version, convey.ShouldEqual, 11)
+		convey.So(p.Aux["redis-ver"], convey.ShouldEqual, "7.2.4")
+		convey.So(len(entries), convey.ShouldEqual, 5)
+		convey.So(entries["user:1001:name"].ExpireAt, convey.ShouldBeGreaterThan, 0)
+		// expire opcode(1+8) + type(1) + key(1+14) + value(1+3)
+		convey.So(entries["user:1001:name"].Size, convey.ShouldEqual, 29)
+		convey.So(entries["user:1002:name"].Type, convey.ShouldEqual, KeyTypeString)
+		convey.So(entries["order:20240101:detail"].Elements, convey.ShouldEqual, 3)
+		convey.So(entries["order:20240101:detail"].Encoding, convey.ShouldEqual, "listpack")
+		convey.So(entries["queue:tasks"].Elements, convey.ShouldEqual, 4)
+		convey.So(entries["queue:tasks"].LFUFreq, convey.ShouldEqual, 9)
+		convey.So(entries["stream:events"].Type, convey.ShouldEqual, KeyTypeStream)
+	})
+
+	convey.Convey("lzf decompress", t, func() {
+		out, err := lzfDecompress([]byte{0x00, 'a', 0xa0, 0x00}, 8)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(string(out), convey.ShouldEqual, "aaaaaaaa")
+	})
+
+	convey.Convey("analyze rdb", t, func() {
+		a := NewAnalyzer(2, 10, "queue:")
+		err := NewParser(bytes.NewReader(buildTestRdb())).Parse(a.Add)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(a.Summary().TotalKeys, convey.ShouldEqual, 5)
+		convey.So(a.Summary().ExpireKeys, convey.ShouldEqual, 1)
+		convey.So(len(a.BigKeys()), convey.ShouldEqual, 2)
+		convey.So(a.BigKeys()[0].Size, convey.ShouldBeGreaterThanOrEqualTo, a.BigKeys()[1].Size)
+		convey.So(a.KeyMode("user:1001:name"), convey.ShouldEqual, "user:*:name")
+		convey.So(a.KeyMode("queue:tasks"), convey.ShouldEqual, "queue:*")
+		modes := map[string]int64{}
+		for _, km := range a.KeyModes() {
+			modes[km.KeyMode] = km.Keys
+		}
+		convey.So(modes["user:*:name"], convey.ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,257 @@
+package rdbanalyzer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// rdbReader 带偏移量统计的rdb读取器,偏移量用于计算key序列化后的大小
+type rdbReader struct {
+	r   *bufio.Reader
+	pos int64
+	buf [8]byte
+}
+
+func newRdbReader(r io.Reader) *rdbReader {
+	return &rdbReader{r: bufio.NewReaderSize(r, 1024*1024)}
+}
+
+func (rd *rdbReader) readByte() (byte, error) {
+	b, err := rd.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	rd.pos++
+	return b, nil
+}
+
+func (rd *rdbReader) readFull(n uint64) ([]byte, error) {
+	if n > math.MaxInt32 {
+		return nil, fmt.Errorf("read %d bytes at offset %d exceeds limit", n, rd.pos)
+	}
+	buf := make([]byte, n)
+	read, err := io.ReadFull(rd.r, buf)
+	rd.pos += int64(read)
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (rd *rdbReader) skip(n uint64) error {
+	for n > 0 {
+		step := n
+		if step > math.MaxInt32 {
+			step = math.MaxInt32
+		}
+		discarded, err := rd.r.Discard(int(step))
+		rd.pos += int64(discarded)
+		if err != nil {
+			return err
+		}
+		n -= step
+	}
+	return nil
+}
+
+func (rd *rdbReader) readUint32LE() (uint32, error) {
+	read, err := io.ReadFull(rd.r, rd.buf[:4])
+	rd.pos += int64(read)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(rd.buf[:4]), nil
+}
+
+func (rd *rdbReader) readUint64LE() (uint64, error) {
+	read, err := io.ReadFull(rd.r, rd.buf[:8])
+	rd.pos += int64(read)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(rd.buf[:8]), nil
+}
+
+// readLength 读取长度编码,encoded为true时 length 表示特殊编码类型(int/lzf)
+func (rd *rdbReader) readLength() (length uint64, encoded bool, err error) {
+	b, err := rd.readByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch (b & 0xc0) >> 6 {
+	case len6Bit:
+		return uint64(b & 0x3f), false, nil
+	case len14Bit:
+		next, err := rd.readByte()
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(b&0x3f)<<8 | uint64(next), false, nil
+	case lenEncVal:
+		return uint64(b & 0x3f), true, nil
+	}
+	switch b {
+	case len32Bit:
+		buf, err := rd.readFull(4)
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(binary.BigEndian.Uint32(buf)), false, nil
+	case len64Bit:
+		buf, err := rd.readFull(8)
+		if err != nil {
+			return 0, false, err
+		}
+		return binary.BigEndian.Uint64(buf), false, nil
+	}
+	return 0, false, fmt.Errorf("unknown length encoding 0x%x at offset %d", b, rd.pos-1)
+}
+
+// readLen 读取普通长度,不允许特殊编码
+func (rd *rdbReader) readLen() (uint64, error) {
+	length, encoded, err := rd.readLength()
+	if err != nil {
+		return 0, err
+	}
+	if encoded {
+		return 0, fmt.Errorf("unexpected encoded length at offset %d", rd.pos)
+	}
+	return length, nil
+}
+
+// readString 读取字符串,整数编码会转为十进制字符串,lzf压缩会解压
+func (rd *rdbReader) readString() ([]byte, error) {
+	length, encoded, err := rd.readLength()
+	if err != nil {
+		return nil, err
+	}
+	if !encoded {
+		return rd.readFull(length)
+	}
+	switch length {
+	case encValInt8:
+		b, err := rd.readByte()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.Itoa(int(int8(b)))), nil
+	case encValInt16:
+		buf, err := rd.readFull(2)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.Itoa(int(int16(binary.LittleEndian.Uint16(buf))))), nil
+	case encValInt32:
+		buf, err := rd.readFull(4)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.Itoa(int(int32(binary.LittleEndian.Uint32(buf))))), nil
+	case encValLZF:
+		clen, err := rd.readLen()
+		if err != nil {
+			return nil, err
+		}
+		ulen, err := rd.readLen()
+		if err != nil {
+			return nil, err
+		}
+		compressed, err := rd.readFull(clen)
+		if err != nil {
+			return nil, err
+		}
+		return lzfDecompress(compressed, ulen)
+	}
+	return nil, fmt.Errorf("unknown string encoding %d at offset %d", length, rd.pos)
+}
+
+// skipString 跳过字符串,不解压,用于不关心内容的value
+func (rd *rdbReader) skipString() error {
+	length, encoded, err := rd.readLength()
+	if err != nil {
+		return err
+	}
+	if !encoded {
+		return rd.skip(length)
+	}
+	switch length {
+	case encValInt8:
+		return rd.skip(1)
+	case encValInt16:
+		return rd.skip(2)
+	case encValInt32:
+		return rd.skip(4)
+	case encValLZF:
+		clen, err := rd.readLen()
+		if err != nil {
+			return err
+		}
+		if _, err = rd.readLen(); err != nil {
+			return err
+		}
+		return rd.skip(clen)
+	}
+	return fmt.Errorf("unknown string encoding %d at offset %d", length, rd.pos)
+}
+
+// skipDouble 跳过 RDB_TYPE_ZSET 中的字符串格式double
+func (rd *rdbReader) skipDouble() error {
+	b, err := rd.readByte()
+	if err != nil {
+		return err
+	}
+	switch b {
+	case 253, 254, 255: // nan, +inf, -inf
+		return nil
+	}
+	return rd.skip(uint64(b))
+}
+
+// lzfDecompress lzf解压
+func lzfDecompress(in []byte, ulen uint64) ([]byte, error) {
+	if ulen > math.MaxInt32 {
+		return nil, fmt.Errorf("lzf uncompressed length %d exceeds limit", ulen)
+	}
+	out := make([]byte, 0, ulen)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+		if ctrl < 32 { // literal run
+			ctrl++
+			if i+ctrl > len(in) {
+				return nil, fmt.Errorf("lzf literal run out of range")
+			}
+			out = append(out, in[i:i+ctrl]...)
+			i += ctrl
+			continue
+		}
+		length := ctrl >> 5
+		if length == 7 {
+			if i >= len(in) {
+				return nil, fmt.Errorf("lzf back reference out of range")
+			}
+			length += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, fmt.Errorf("lzf back reference out of range")
+		}
+		ref := len(out) - ((ctrl & 0x1f) << 8) - int(in[i]) - 1
+		i++
+		if ref < 0 {
+			return nil, fmt.Errorf("lzf back reference before start")
+		}
+		// 引用区间可能与写入区间重叠,需逐字节复制
+		for j := 0; j < length+2; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+	if uint64(len(out)) != ulen {
+		return nil, fmt.Errorf("lzf decompressed length %d not equal to %d", len(out), ulen)
+	}
+	return out, nil
+}
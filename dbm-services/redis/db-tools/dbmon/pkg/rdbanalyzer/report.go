@@ -0,0 +1,66 @@
+package rdbanalyzer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbm-services/redis/db-tools/dbmon/pkg/report"
+)
+
+// ReportBigKeys 大key写入reporter,每行一个json,meta(ip/port/domain等)合并到每行中
+func (a *Analyzer) ReportBigKeys(rp report.Reporter, meta map[string]interface{}) error {
+	for _, bk := range a.BigKeys() {
+		line := copyMeta(meta)
+		line["db"] = bk.DB
+		line["key"] = bk.Key
+		line["key_type"] = bk.Type
+		line["encoding"] = bk.Encoding
+		line["size"] = bk.Size
+		line["elements"] = bk.Elements
+		line["ttl"] = bk.TTL
+		if err := addJSONRecord(rp, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReportKeyModes key模式写入reporter,每行一个json
+func (a *Analyzer) ReportKeyModes(rp report.Reporter, meta map[string]interface{}) error {
+	for _, km := range a.KeyModes() {
+		line := copyMeta(meta)
+		line["keymode"] = km.KeyMode
+		line["keys"] = km.Keys
+		line["size"] = km.Size
+		line["elements"] = km.Elements
+		line["expire_keys"] = km.ExpireKeys
+		line["max_size"] = km.MaxSize
+		line["sample_key"] = km.SampleKey
+		if a.summary.TotalSize > 0 {
+			line["size_ratio"] = float64(km.Size) * 100 / float64(a.summary.TotalSize)
+		}
+		if err := addJSONRecord(rp, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyMeta(meta map[string]interface{}) map[string]interface{} {
+	line := make(map[string]interface{}, len(meta)+8)
+	for k, v := range meta {
+		line[k] = v
+	}
+	return line
+}
+
+func addJSONRecord(rp report.Reporter, line map[string]interface{}) error {
+	if rp == nil {
+		return fmt.Errorf("reporter is nil")
+	}
+	buf, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	return rp.AddRecord(string(buf)+"\n", true)
+}
@@ -158,6 +158,12 @@ func (job *Job) createTasks() {
 	var instStr string
 
 	job.Tasks = []*Task{}
+	encryptTool, err := job.Conf.BackupEncrypt.GetEncryptTool()
+	if err != nil {
+		job.Err = err
+		mylog.Logger.Error(fmt.Sprintf("init backup encrypt fail,err:%v", err))
+		return
+	}
 	for _, svrItem := range job.Conf.Servers {
 		if !consts.IsRedisMetaRole(svrItem.MetaRole) {
 			continue
@@ -178,7 +184,7 @@ func (job *Job) createTasks() {
 				job.Conf.RedisBinlogBackup.OldFileLeftDay,
 				job.Reporter,
 				job.Conf.BackupClientStrorageType, job.Conf.RedisBinlogBackup.BackupFileTag,
-				job.sqdb)
+				job.sqdb, encryptTool)
 			if job.Err != nil {
 				return
 			}
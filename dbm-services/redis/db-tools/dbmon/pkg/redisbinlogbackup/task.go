@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"dbm-services/common/go-pubpkg/iocrypt"
 	"dbm-services/redis/db-tools/dbmon/models/myredis"
 	"dbm-services/redis/db-tools/dbmon/mylog"
 	"dbm-services/redis/db-tools/dbmon/pkg/backupsys"
@@ -108,6 +109,7 @@ type Task struct {
 	Cli            *myredis.RedisClient `json:"-"`
 	reporter       report.Reporter
 	backupClient   backupsys.BackupClient
+	encryptTool    *iocrypt.SioCrypt
 	sqdb           *gorm.DB
 	lockFile       string `json:"-"`
 	Err            error  `json:"-"`
@@ -117,7 +119,7 @@ type Task struct {
 func NewBinlogBackupTask(bkBizID string, bkCloudID int64, domain, ip string, port int,
 	password, toBackupSys, backupDir, shardValue string, oldFileLeftDay int,
 	reporter report.Reporter, storageType string, backupFileTag string,
-	sqdb *gorm.DB) (ret *Task, err error) {
+	sqdb *gorm.DB, encryptTool *iocrypt.SioCrypt) (ret *Task, err error) {
 
 	timeZone, _ := time.Now().Local().Zone()
 	ret = &Task{
@@ -125,6 +127,7 @@ func NewBinlogBackupTask(bkBizID string, bkCloudID int64, domain, ip string, por
 		ToBackupSystem: toBackupSys,
 		OldFileLeftDay: oldFileLeftDay,
 		reporter:       reporter,
+		encryptTool:    encryptTool,
 		sqdb:           sqdb,
 	}
 	ret.RedisBinlogHistorySchema = RedisBinlogHistorySchema{
@@ -427,6 +430,17 @@ func (task *Task) compressAndUpload() {
 			task.Message = task.Err.Error()
 			return
 		}
+		// 启用备份加密时,压缩后的binlog原地加密,文件名不变
+		if task.encryptTool != nil {
+			task.Err = task.encryptTool.EncryptFile(task.BackupFile, task.BackupFile)
+			if task.Err != nil {
+				task.Err = fmt.Errorf("encrypt %s fail,err:%v", task.BackupFile, task.Err)
+				mylog.Logger.Error(task.Err.Error())
+				task.Status = consts.BackupStatusFailed
+				task.Message = task.Err.Error()
+				return
+			}
+		}
 		util.LocalFileChmodAllRead(task.BackupFile)
 		fileInfo, _ := os.Stat(task.BackupFile)
 		task.BackupFileSize = fileInfo.Size()
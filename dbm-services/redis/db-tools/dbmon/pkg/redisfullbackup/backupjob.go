@@ -158,6 +158,12 @@ func (job *Job) createTasks() {
 
 	mylog.Logger.Info(fmt.Sprintf("start create fullback tasks,Servers:%s", util.ToString(job.Conf.Servers)))
 	job.Tasks = []*BackupTask{}
+	encryptTool, err := job.Conf.BackupEncrypt.GetEncryptTool()
+	if err != nil {
+		job.Err = err
+		mylog.Logger.Error(fmt.Sprintf("init backup encrypt fail,err:%v", err))
+		return
+	}
 
 	for _, svrItem := range job.Conf.Servers {
 		if !consts.IsRedisMetaRole(svrItem.MetaRole) {
@@ -176,7 +182,7 @@ func (job *Job) createTasks() {
 				job.Conf.RedisFullBackup.TarSplit, job.Conf.RedisFullBackup.TarSplitPartSize,
 				svrItem.ServerShards[instStr], job.Reporter,
 				job.Conf.BackupClientStrorageType, job.Conf.RedisFullBackup.BackupFileTag,
-				job.sqdb)
+				job.sqdb, encryptTool)
 			if job.Err != nil {
 				return
 			}
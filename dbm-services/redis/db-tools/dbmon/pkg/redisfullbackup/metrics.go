@@ -0,0 +1,98 @@
+package redisfullbackup
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"dbm-services/redis/db-tools/dbmon/config"
+	"dbm-services/redis/db-tools/dbmon/models/mysqlite"
+	"dbm-services/redis/db-tools/dbmon/mylog"
+	"dbm-services/redis/db-tools/dbmon/pkg/consts"
+	"dbm-services/redis/db-tools/dbmon/pkg/prommetrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var backupAgeDesc = prometheus.NewDesc(
+	"dbmon_redis_fullbackup_age_seconds",
+	"seconds since the end of the latest successful redis fullbackup, read from local sqlite history",
+	[]string{"bk_biz_id", "cluster_domain", "cluster_type", "role", prommetrics.LabelAddr}, nil,
+)
+
+func init() {
+	// 包初始化时注册一次, httpapi.StartListen 重复调用不会重复注册
+	prommetrics.Registry.MustRegister(NewBackupAgeCollector())
+}
+
+// BackupAgeCollector 每次抓取时从本地sqlite备份记录计算各实例最近一次成功全备距今秒数
+type BackupAgeCollector struct{}
+
+// NewBackupAgeCollector new
+func NewBackupAgeCollector() *BackupAgeCollector {
+	return &BackupAgeCollector{}
+}
+
+// Describe 实现 prometheus.Collector
+func (c *BackupAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- backupAgeDesc
+}
+
+// Collect 实现 prometheus.Collector
+func (c *BackupAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	sqdb, err := mysqlite.GetLocalSqDB()
+	if err != nil {
+		return
+	}
+	defer mysqlite.CloseDB(sqdb)
+	if !sqdb.Migrator().HasTable(&RedisFullbackupHistorySchema{}) {
+		return
+	}
+	rows := []RedisFullbackupHistorySchema{}
+	// 本地备份成功即认为备份完成,上传备份系统的结果另有告警
+	// 备份记录按完成顺序写入,每个实例只取id最大的一条
+	latestIDs := sqdb.Model(&RedisFullbackupHistorySchema{}).Select("max(id)").
+		Where("status in (?,?,?,?)",
+			consts.BackupStatusLocalSuccess,
+			consts.BackupStatusToBakSystemStart,
+			consts.BackupStatusToBakSystemFailed,
+			consts.BackupStatusToBakSysSuccess,
+		).Group("server_ip, server_port")
+	err = sqdb.Select("bk_biz_id, domain, role, server_ip, server_port, end_time").
+		Where("id in (?)", latestIDs).Find(&rows).Error
+	if err != nil {
+		mylog.Logger.Warn(fmt.Sprintf("BackupAgeCollector gorm find fail,err:%v", err))
+		return
+	}
+	var servers []config.ConfServerItem
+	if config.GlobalConf != nil {
+		servers = config.GlobalConf.Servers
+	}
+	for _, metric := range backupAgeMetrics(rows, serverClusterTypes(servers), time.Now()) {
+		ch <- metric
+	}
+}
+
+// serverClusterTypes 配置中各实例的集群类型, key: ip:port
+// 备份记录中没有集群类型,按实例地址从配置中获取
+func serverClusterTypes(servers []config.ConfServerItem) map[string]string {
+	ret := make(map[string]string)
+	for _, server := range servers {
+		for _, port := range server.ServerPorts {
+			ret[server.ServerIP+":"+strconv.Itoa(port)] = server.ClusterType
+		}
+	}
+	return ret
+}
+
+// backupAgeMetrics 各实例最近一次全备距 now 的秒数
+func backupAgeMetrics(rows []RedisFullbackupHistorySchema, clusterTypes map[string]string,
+	now time.Time) []prometheus.Metric {
+	ret := make([]prometheus.Metric, 0, len(rows))
+	for _, row := range rows {
+		ret = append(ret, prometheus.MustNewConstMetric(backupAgeDesc, prometheus.GaugeValue,
+			now.Sub(row.EndTime).Seconds(),
+			row.BkBizID, row.Domain, clusterTypes[row.Addr()], row.RealRole, row.Addr()))
+	}
+	return ret
+}
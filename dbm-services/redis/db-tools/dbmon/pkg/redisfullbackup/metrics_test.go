@@ -0,0 +1,59 @@
+package redisfullbackup
+
+import (
+	"testing"
+	"time"
+
+	"dbm-services/redis/db-tools/dbmon/config"
+	"dbm-services/redis/db-tools/dbmon/pkg/prommetrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestBackupAgeCollectorRegistered(t *testing.T) {
+	convey.Convey("backup age collector registered once", t, func() {
+		err := prommetrics.Registry.Register(NewBackupAgeCollector())
+		_, ok := err.(prometheus.AlreadyRegisteredError)
+		convey.So(ok, convey.ShouldBeTrue)
+	})
+}
+
+func TestBackupAgeMetrics(t *testing.T) {
+	convey.Convey("backup age metrics with cluster_type", t, func() {
+		clusterTypes := serverClusterTypes([]config.ConfServerItem{
+			{ServerIP: "1.1.1.1", ServerPorts: []int{30000, 30001}, ClusterType: "PredixyTendisplusCluster"},
+			{ServerIP: "2.2.2.2", ServerPorts: []int{30000}, ClusterType: "TwemproxyRedisInstance"},
+		})
+		convey.So(clusterTypes, convey.ShouldHaveLength, 3)
+
+		now := time.Now()
+		rows := []RedisFullbackupHistorySchema{
+			{BkBizID: "3", Domain: "cache.test.db", RealRole: "slave", ServerIP: "1.1.1.1", ServerPort: 30001,
+				EndTime: now.Add(-time.Hour)},
+			{BkBizID: "3", Domain: "cache2.test.db", RealRole: "slave", ServerIP: "3.3.3.3", ServerPort: 30000,
+				EndTime: now.Add(-time.Minute)},
+		}
+		metrics := backupAgeMetrics(rows, clusterTypes, now)
+		convey.So(metrics, convey.ShouldHaveLength, 2)
+
+		m := &dto.Metric{}
+		convey.So(metrics[0].Write(m), convey.ShouldBeNil)
+		convey.So(m.GetGauge().GetValue(), convey.ShouldEqual, 3600)
+		labels := map[string]string{}
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		convey.So(labels["cluster_type"], convey.ShouldEqual, "PredixyTendisplusCluster")
+		convey.So(labels[prommetrics.LabelAddr], convey.ShouldEqual, "1.1.1.1:30001")
+
+		// 配置中没有的实例, cluster_type 为空
+		convey.So(metrics[1].Write(m), convey.ShouldBeNil)
+		for _, l := range m.GetLabel() {
+			if l.GetName() == "cluster_type" {
+				convey.So(l.GetValue(), convey.ShouldBeEmpty)
+			}
+		}
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"dbm-services/common/go-pubpkg/iocrypt"
 	"dbm-services/redis/db-tools/dbmon/models/myredis"
 	"dbm-services/redis/db-tools/dbmon/mylog"
 	"dbm-services/redis/db-tools/dbmon/pkg/backupsys"
@@ -110,6 +111,7 @@ type BackupTask struct {
 	SSDLogCount      TendisSSDSetLogCount `json:"-"`
 	reporter         report.Reporter
 	backupClient     backupsys.BackupClient
+	encryptTool      *iocrypt.SioCrypt
 	sqdb             *gorm.DB
 	Err              error `json:"-"`
 }
@@ -119,7 +121,7 @@ func NewFullBackupTask(bkBizID string, bkCloudID int64, domain, ip string, port
 	toBackupSys, backupType, cacheBackupMode, backupDir string, tarSplit bool,
 	tarSplitSize, shardValue string,
 	reporter report.Reporter, storageType string, backupFileTag string,
-	sqdb *gorm.DB) (ret *BackupTask, err error) {
+	sqdb *gorm.DB, encryptTool *iocrypt.SioCrypt) (ret *BackupTask, err error) {
 	ret = &BackupTask{
 		Password:         password,
 		ToBackupSystem:   toBackupSys,
@@ -128,6 +130,7 @@ func NewFullBackupTask(bkBizID string, bkCloudID int64, domain, ip string, port
 		TarSplit:         tarSplit,
 		TarSplitPartSize: tarSplitSize,
 		reporter:         reporter,
+		encryptTool:      encryptTool,
 		sqdb:             sqdb,
 	}
 	timeZone, _ := time.Now().Local().Zone()
@@ -252,6 +255,10 @@ func (task *BackupTask) BakcupToLocal() {
 	}
 	mylog.Logger.Info(fmt.Sprintf("redis(%s) dbType:%s start backup...", task.Addr(), task.DbType))
 
+	if task.Err != nil {
+		return
+	}
+	task.EncryptBackupFile()
 	if task.Err != nil {
 		return
 	}
@@ -554,6 +561,23 @@ func (task *BackupTask) GetBakFilesSize() {
 	task.BackupFileSize = fileSize
 }
 
+// EncryptBackupFile 启用备份加密时,备份文件原地加密,文件名不变
+func (task *BackupTask) EncryptBackupFile() {
+	if task.encryptTool == nil {
+		return
+	}
+	mylog.Logger.Info(fmt.Sprintf("redis(%s) encrypt backupFile:%s", task.Addr(), task.BackupFile))
+	task.Err = task.encryptTool.EncryptFile(task.BackupFile, task.BackupFile)
+	if task.Err != nil {
+		task.Err = fmt.Errorf("encrypt %s fail,err:%v", task.BackupFile, task.Err)
+		mylog.Logger.Error(task.Err.Error())
+		return
+	}
+	util.LocalFileChmodAllRead(task.BackupFile)
+	util.LocalDirChownMysql(task.BackupDir)
+	task.GetBakFilesSize()
+}
+
 // TendisSSDSetLougCount tendisSSD设置log-count参数
 func (task *BackupTask) TendisSSDSetLougCount() {
 	if task.SSDLogCount.LogCount > 0 {
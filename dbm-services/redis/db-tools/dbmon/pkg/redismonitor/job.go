@@ -40,6 +40,7 @@ func (job *Job) Run() {
 		}
 	}()
 	job.Err = nil
+	cleanStaleMetrics(job.Conf)
 	var password string
 	var predixyItem *PredixyMonitorTask
 	var twemItem *TwemproxyMonitorTask
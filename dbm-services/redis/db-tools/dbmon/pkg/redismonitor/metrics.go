@@ -0,0 +1,279 @@
+package redismonitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dbm-services/redis/db-tools/dbmon/config"
+	"dbm-services/redis/db-tools/dbmon/models/myredis"
+	"dbm-services/redis/db-tools/dbmon/mylog"
+	"dbm-services/redis/db-tools/dbmon/pkg/consts"
+	"dbm-services/redis/db-tools/dbmon/pkg/prommetrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// reportedInstances 上一轮上报过指标的实例,用于清理已下架实例的指标
+var reportedInstances = map[string]bool{}
+var reportedMu sync.Mutex
+
+// cleanStaleMetrics 删除已不在配置中的实例指标
+func cleanStaleMetrics(conf *config.Configuration) {
+	current := map[string]bool{}
+	for _, svrItem := range conf.Servers {
+		for _, port := range svrItem.ServerPorts {
+			current[fmt.Sprintf("%s:%d", svrItem.ServerIP, port)] = true
+		}
+	}
+	reportedMu.Lock()
+	defer reportedMu.Unlock()
+	for addr := range reportedInstances {
+		if !current[addr] {
+			prommetrics.DeleteInstance(addr)
+		}
+	}
+	reportedInstances = current
+}
+
+func (task *baseTask) metricLabels(addr string) prometheus.Labels {
+	return prommetrics.InstanceLabels(task.ServerConf.BkBizID, task.ServerConf.ClusterDomain,
+		task.ServerConf.ClusterType, task.ServerConf.MetaRole, addr)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// infoFloat 从info结果中取数值,不存在或非数值时 ok=false
+func infoFloat(infoRet map[string]string, key string) (val float64, ok bool) {
+	str, ok := infoRet[key]
+	if !ok {
+		return 0, false
+	}
+	val, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, false
+	}
+	return val, true
+}
+
+// ReportMetrics 采集redis指标,连接失败的实例 redis_up=0
+// 指标采集失败不影响后续告警检查,故不设置 task.Err
+func (task *RedisMonitorTask) ReportMetrics() {
+	cliMap := make(map[string]*myredis.RedisClient, len(task.redisClis))
+	for _, cliItem := range task.redisClis {
+		cliMap[cliItem.Addr] = cliItem
+	}
+	for _, port := range task.ServerConf.ServerPorts {
+		addr := task.getRedisAddr(task.ServerConf.ServerIP, port)
+		labels := task.metricLabels(addr)
+		cliItem, ok := cliMap[addr]
+		if !ok {
+			prommetrics.RedisUp.With(labels).Set(0)
+			continue
+		}
+		prommetrics.RedisUp.With(labels).Set(1)
+		task.reportRedisInfoMetrics(cliItem, labels)
+	}
+}
+
+func (task *RedisMonitorTask) reportRedisInfoMetrics(cliItem *myredis.RedisClient, labels prometheus.Labels) {
+	var infoRet map[string]string
+	var err error
+	infoRet, err = cliItem.Info("memory")
+	if err == nil {
+		if val, ok := infoFloat(infoRet, "used_memory"); ok {
+			prommetrics.RedisUsedMemory.With(labels).Set(val)
+		}
+	}
+	if maxmemory, err := cliItem.MaxMemory(); err == nil {
+		prommetrics.RedisMaxmemory.With(labels).Set(float64(maxmemory))
+	}
+	infoRet, err = cliItem.Info("clients")
+	if err == nil {
+		if val, ok := infoFloat(infoRet, "connected_clients"); ok {
+			prommetrics.RedisConnectedClients.With(labels).Set(val)
+		}
+	}
+	infoRet, err = cliItem.Info("stats")
+	if err == nil {
+		if val, ok := infoFloat(infoRet, "keyspace_hits"); ok {
+			prommetrics.RedisKeyspaceHits.Set(labels, val)
+		}
+		if val, ok := infoFloat(infoRet, "keyspace_misses"); ok {
+			prommetrics.RedisKeyspaceMisses.Set(labels, val)
+		}
+	}
+	infoRet, err = cliItem.Info("replication")
+	if err != nil {
+		return
+	}
+	// slave 列表会变化,先删除该实例下旧的slave指标
+	prommetrics.RedisSlaveReplLag.DeletePartialMatch(prommetrics.AddrLabels(cliItem.Addr))
+	if infoRet["role"] != consts.RedisMasterRole {
+		return
+	}
+	masterOffset, ok := infoFloat(infoRet, "master_repl_offset")
+	if !ok {
+		// tendisplus 等无 master_repl_offset
+		return
+	}
+	for key, value := range infoRet {
+		// slave0:ip=x.x.x.x,port=30000,state=online,offset=1234,lag=0
+		if !strings.HasPrefix(key, "slave") {
+			continue
+		}
+		if _, err = strconv.Atoi(strings.TrimPrefix(key, "slave")); err != nil {
+			continue
+		}
+		fields := map[string]string{}
+		for _, kv := range strings.Split(value, ",") {
+			list01 := strings.SplitN(kv, "=", 2)
+			if len(list01) == 2 {
+				fields[list01[0]] = list01[1]
+			}
+		}
+		slaveOffset, err := strconv.ParseFloat(fields["offset"], 64)
+		if err != nil || fields["ip"] == "" {
+			continue
+		}
+		slaveLabels := prometheus.Labels{"slave": fields["ip"] + ":" + fields["port"]}
+		for k, v := range labels {
+			slaveLabels[k] = v
+		}
+		prommetrics.RedisSlaveReplLag.With(slaveLabels).Set(masterOffset - slaveOffset)
+	}
+}
+
+// reportProxyUp 检查proxy能否连接,返回可用的client(调用方负责关闭)
+func (task *baseTask) reportProxyUp(proxyAddr string, proxyPort int) (cli *myredis.RedisClient) {
+	labels := task.metricLabels(proxyAddr)
+	// 不使用 task.getPassword,避免覆盖 task.Err
+	password, err := myredis.GetProxyPasswdFromConfFlie(proxyPort, task.ServerConf.MetaRole)
+	if err != nil {
+		prommetrics.ProxyUp.With(labels).Set(0)
+		return nil
+	}
+	cli, err = myredis.NewRedisClientWithTimeout(proxyAddr, password, 0,
+		consts.TendisTypeRedisInstance, 5*time.Second)
+	if err != nil {
+		prommetrics.ProxyUp.With(labels).Set(0)
+		return nil
+	}
+	prommetrics.ProxyUp.With(labels).Set(1)
+	return cli
+}
+
+func (task *baseTask) setProxyBackendUp(proxyAddr, backend string, up bool) {
+	labels := prometheus.Labels{"backend": backend}
+	for k, v := range task.metricLabels(proxyAddr) {
+		labels[k] = v
+	}
+	prommetrics.ProxyBackendUp.With(labels).Set(boolToFloat(up))
+}
+
+// ReportMetrics 采集twemproxy指标
+// 后端健康: 从 port+1000 管理端口获取 nosqlproxy servers, 并检测后端端口能否连接
+func (task *TwemproxyMonitorTask) ReportMetrics() {
+	for _, proxyPort := range task.ServerConf.ServerPorts {
+		proxyAddr := fmt.Sprintf("%s:%d", task.ServerConf.ServerIP, proxyPort)
+		cli := task.reportProxyUp(proxyAddr, proxyPort)
+		if cli == nil {
+			continue
+		}
+		cli.Close()
+		backends, err := getTwemproxyBackends(task.ServerConf.ServerIP, proxyPort)
+		if err != nil {
+			continue
+		}
+		prommetrics.ProxyBackendUp.DeletePartialMatch(prommetrics.AddrLabels(proxyAddr))
+		for _, backend := range backends {
+			conn, err := net.DialTimeout("tcp", backend, 2*time.Second)
+			if err == nil {
+				conn.Close()
+			}
+			task.setProxyBackendUp(proxyAddr, backend, err == nil)
+		}
+	}
+}
+
+// getTwemproxyBackends 获取twemproxy后端地址列表
+// 返回格式: ip:port app segStart-segEnd weight
+func getTwemproxyBackends(ip string, port int) (backends []string, err error) {
+	addr := net.JoinHostPort(ip, strconv.Itoa(port+1000))
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		err = fmt.Errorf("twemproxy admin(%s) connect fail,err:%v", addr, err)
+		mylog.Logger.Warn(err.Error())
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err = conn.Write([]byte("get nosqlproxy servers")); err != nil {
+		err = fmt.Errorf("twemproxy admin(%s) 'get nosqlproxy servers' fail,err:%v", addr, err)
+		mylog.Logger.Warn(err.Error())
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		fields := strings.Fields(line)
+		if len(fields) == 4 {
+			backends = append(backends, fields[0])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("twemproxy admin(%s) read servers fail,err:%v", addr, err)
+			mylog.Logger.Warn(err.Error())
+			return nil, err
+		}
+	}
+	return backends, nil
+}
+
+// ReportMetrics 采集predixy指标
+// 后端健康: info servers 中 CurrentIsFail==0 为正常
+func (task *PredixyMonitorTask) ReportMetrics() {
+	for _, proxyPort := range task.ServerConf.ServerPorts {
+		proxyAddr := fmt.Sprintf("%s:%d", task.ServerConf.ServerIP, proxyPort)
+		cli := task.reportProxyUp(proxyAddr, proxyPort)
+		if cli == nil {
+			continue
+		}
+		svrsInfo, err := cli.InstanceClient.Info(context.TODO(), "servers").Result()
+		cli.Close()
+		if err != nil {
+			mylog.Logger.Warn(fmt.Sprintf("predixy(%s) 'info servers' fail,err:%v", proxyAddr, err))
+			continue
+		}
+		prommetrics.ProxyBackendUp.DeletePartialMatch(prommetrics.AddrLabels(proxyAddr))
+		var server string
+		for _, line := range strings.Split(svrsInfo, "\n") {
+			list01 := strings.SplitN(strings.TrimSpace(line), ":", 2)
+			if len(list01) != 2 {
+				continue
+			}
+			switch list01[0] {
+			case "Server":
+				server = list01[1]
+			case "CurrentIsFail":
+				if server != "" {
+					task.setProxyBackendUp(proxyAddr, server, list01[1] == "0")
+				}
+				server = ""
+			}
+		}
+	}
+}
@@ -41,6 +41,7 @@ func (task *PredixyMonitorTask) RunMonitor() {
 	}()
 
 	task.RestartWhenConnFail()
+	task.ReportMetrics()
 	if task.Err != nil {
 		return
 	}
@@ -52,6 +52,8 @@ func (task *RedisMonitorTask) RunMonitor() {
 		return
 	}
 	task.CheckRedisConn()
+	// 连接失败的实例也需上报 redis_up=0
+	task.ReportMetrics()
 	if task.Err != nil {
 		return
 	}
@@ -42,6 +42,7 @@ func (task *TwemproxyMonitorTask) RunMonitor() {
 	// twemproxy update 失败,也需要尝试重启twemproxy
 	task.UpdateConfFileHashTag()
 	task.RestartWhenConnFail()
+	task.ReportMetrics()
 	if task.Err != nil {
 		return
 	}
@@ -39,6 +39,7 @@ type TbTendisDTSJob struct {
 	DstClusterType                    string                `json:"dst_cluster_type" gorm:"column:dst_cluster_type"`
 	KeyWhiteRegex                     string                `json:"key_white_regex" gorm:"column:key_white_regex"`
 	KeyBlackRegex                     string                `json:"key_black_regex" gorm:"column:key_black_regex"`
+	TransformRules                    string                `json:"transform_rules" gorm:"column:transform_rules"`
 	Status                            int                   `json:"status" gorm:"column:status"`
 	Reason                            string                `json:"reason" gorm:"column:reason"`
 	CreateTime                        customtime.CustomTime `json:"create_time" gorm:"column:create_time"`
@@ -50,6 +50,7 @@ type TbTendisDTSTask struct {
 	SrcTwemproxyHashTagEnabled int                   `json:"src_twemproxy_hash_tag_enabled" gorm:"column:src_twemproxy_hash_tag_enabled"` // 源实例twemproxy是否开启hash_tag
 	KeyWhiteRegex              string                `json:"key_white_regex" gorm:"column:key_white_regex"`                               // key正则(白名单)
 	KeyBlackRegex              string                `json:"key_black_regex" gorm:"column:key_black_regex"`                               // key正则(黑名单)
+	TransformRules             string                `json:"transform_rules" gorm:"column:transform_rules"`                               // key转换规则(json),见 keyTransform.RuleSet
 	SrcKvStoreID               int                   `json:"src_kvstore_id" gorm:"column:src_kvstore_id"`                                 // tendisplus kvstore id
 	DstCluster                 string                `json:"dst_cluster" gorm:"column:dst_cluster"`                                       // 目的集群
 	DstPassword                string                `json:"dst_password" gorm:"column:dst_password"`                                     // 目的密码base64值
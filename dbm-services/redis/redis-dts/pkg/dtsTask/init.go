@@ -14,6 +14,7 @@ import (
 	"dbm-services/redis/redis-dts/models/myredis"
 	"dbm-services/redis/redis-dts/models/mysql/tendisdb"
 	"dbm-services/redis/redis-dts/pkg/constvar"
+	"dbm-services/redis/redis-dts/pkg/keyTransform"
 	"dbm-services/redis/redis-dts/pkg/scrdbclient"
 	"dbm-services/redis/redis-dts/tclog"
 	"dbm-services/redis/redis-dts/util"
@@ -654,6 +655,29 @@ func (task *TendisDtsFatherTask) GetDstRedisPasswd() string {
 	return string(dstPasswd)
 }
 
+// GetTransformRules 解析task的key转换规则,并检查各同步工具是否支持
+// 规则不被支持时设置 task.Err,避免全量与增量数据不一致
+func (task *TendisDtsFatherTask) GetTransformRules(engines ...string) (rules *keyTransform.RuleSet) {
+	rules, err := keyTransform.ParseRuleSet(task.RowData.TransformRules)
+	if err != nil {
+		task.Err = err
+		task.UpdateDbAndLogLocal("解析key转换规则失败,err:%v", err)
+		return nil
+	}
+	for _, engine := range engines {
+		err = rules.CheckEngineSupport(engine)
+		if err != nil {
+			task.Err = err
+			task.UpdateDbAndLogLocal("key转换规则检查失败,err:%v", err)
+			return nil
+		}
+	}
+	if !rules.IsEmpty() {
+		task.Logger.Info("key transform rules", zap.String("rules", rules.ToString()))
+	}
+	return rules
+}
+
 // DisableDstClusterSlowlog  dst cluster 'config set slowlog-log-slower-than -1'
 func (task *TendisDtsFatherTask) DisableDstClusterSlowlog() {
 	dstProxyAddrs, err := util.LookupDbDNSIPs(task.RowData.DstCluster)
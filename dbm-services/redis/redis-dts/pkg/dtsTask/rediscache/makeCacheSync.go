@@ -12,11 +12,13 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"dbm-services/redis/redis-dts/models/myredis"
 	"dbm-services/redis/redis-dts/models/mysql/tendisdb"
 	"dbm-services/redis/redis-dts/pkg/constvar"
 	"dbm-services/redis/redis-dts/pkg/dtsTask"
+	"dbm-services/redis/redis-dts/pkg/keyTransform"
 	"dbm-services/redis/redis-dts/util"
 
 	"github.com/jinzhu/gorm"
@@ -483,6 +485,10 @@ func (task *MakeCacheSyncTask) createShakeV4ConfigFile() {
 		return
 	}
 
+	rules := task.GetTransformRules(keyTransform.EngineRedisShakeV4)
+	if task.Err != nil {
+		return
+	}
 	currentPath, _ := util.CurrentExecutePath()
 	tempFile := filepath.Join(currentPath, "redis-shake-template.toml")
 	tempContent, err := ioutil.ReadFile(tempFile)
@@ -497,12 +503,18 @@ func (task *MakeCacheSyncTask) createShakeV4ConfigFile() {
 	if debug == true {
 		loglevel = "debug"
 	}
-	keyWhiteRegex, keyBlackRegex := "*", ""
+	// allow_key_regex/block_key_regex 为toml字符串数组,模板中已包含方括号
+	keyWhiteRegex, keyBlackRegex := "", ""
 	if task.RowData.KeyWhiteRegex != "" && !task.IsMatchAny(task.RowData.KeyWhiteRegex) {
-		keyWhiteRegex = task.RowData.KeyWhiteRegex
+		keyWhiteRegex, task.Err = tomlQuote(task.RowData.KeyWhiteRegex)
 	}
-	if task.RowData.KeyBlackRegex != "" && !task.IsMatchAny(task.RowData.KeyBlackRegex) {
-		keyBlackRegex = task.RowData.KeyBlackRegex // V4配置文件变动
+	if task.Err == nil && task.RowData.KeyBlackRegex != "" && !task.IsMatchAny(task.RowData.KeyBlackRegex) {
+		keyBlackRegex, task.Err = tomlQuote(task.RowData.KeyBlackRegex)
+		keyBlackRegex = ", " + keyBlackRegex // 注意最前面有个逗号
+	}
+	if task.Err != nil {
+		task.Logger.Error(task.Err.Error())
+		return
 	}
 
 	// WriteMode=delete_and_write_to_redis/flushall_and_write_to_redis
@@ -530,6 +542,13 @@ func (task *MakeCacheSyncTask) createShakeV4ConfigFile() {
 	tempData = strings.ReplaceAll(tempData, "{{KEY_WHITE_REGEX}}", keyWhiteRegex)
 	tempData = strings.ReplaceAll(tempData, "{{KEY_BLACK_REGEX}}", keyBlackRegex)
 	tempData = strings.ReplaceAll(tempData, "{{KEY_EXISTS}}", keyExists)
+	// key转换规则通过 [filter] function 实现
+	if !rules.IsEmpty() && !strings.Contains(tempData, "{{FILTER_FUNCTION}}") {
+		task.Err = fmt.Errorf("template:%s not contains {{FILTER_FUNCTION}},cannot apply transform rules", tempFile)
+		task.Logger.Error(task.Err.Error())
+		return
+	}
+	tempData = strings.ReplaceAll(tempData, "{{FILTER_FUNCTION}}", rules.RedisShakeV4Function())
 	err = ioutil.WriteFile(task.ShakeConfFile, []byte(tempData), 0755)
 
 	if err != nil {
@@ -576,6 +595,15 @@ func (task *MakeCacheSyncTask) createShakeConfigFile() {
 		return
 	}
 
+	// redis-shake v2 只支持将所有db写入同一个目标db(target.db)
+	rules := task.GetTransformRules(keyTransform.EngineRedisShake)
+	if task.Err != nil {
+		return
+	}
+	targetDb, _ := rules.SingleTargetDb()
+	if targetDb < 0 {
+		targetDb = 0
+	}
 	currentPath, _ := util.CurrentExecutePath()
 	tempFile := filepath.Join(currentPath, "redis-shake-template.conf")
 	tempContent, err := ioutil.ReadFile(tempFile)
@@ -652,6 +680,7 @@ func (task *MakeCacheSyncTask) createShakeConfigFile() {
 	tempData = strings.ReplaceAll(tempData, "{{KEY_BLACK_REGEX}}", keyBlackRegex)
 	tempData = strings.ReplaceAll(tempData, "{{KEY_EXISTS}}", keyExists)
 	tempData = strings.ReplaceAll(tempData, "{{BIG_KEY_THRESHOLD}}", strconv.Itoa(bigKeyThreshold))
+	tempData = strings.ReplaceAll(tempData, "{{TARGET_DB}}", strconv.Itoa(targetDb))
 
 	err = ioutil.WriteFile(task.ShakeConfFile, []byte(tempData), 0755)
 	if err != nil {
@@ -1030,3 +1059,26 @@ func getRedisShakeV4BeginVersion() int {
 	}
 	return beginVersion
 }
+
+// tomlQuote 生成toml基本字符串
+// strconv.Quote 生成的 \x.. 转义在toml中不合法, 控制字符统一用 \uXXXX 转义
+func tomlQuote(s string) (string, error) {
+	if !utf8.ValidString(s) {
+		return "", fmt.Errorf("%q is not valid utf-8,cannot be written to toml", s)
+	}
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, c := range s {
+		switch {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&sb, "\\u%04X", c)
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String(), nil
+}
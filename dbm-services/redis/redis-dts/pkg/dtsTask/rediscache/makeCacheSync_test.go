@@ -0,0 +1,28 @@
+package rediscache
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+func TestTomlQuote(t *testing.T) {
+	for _, s := range []string{`^a\d+$`, `say "hi"`, "tab\tnew\nline\x01\x7f", "中文:.*"} {
+		quoted, err := tomlQuote(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var conf struct {
+			Regex []string `toml:"regex"`
+		}
+		if err = toml.Unmarshal([]byte("regex = ["+quoted+"]"), &conf); err != nil {
+			t.Fatalf("tomlQuote(%q)=%s decode fail,err:%v", s, quoted, err)
+		}
+		if len(conf.Regex) != 1 || conf.Regex[0] != s {
+			t.Fatalf("tomlQuote(%q) decoded %q", s, conf.Regex)
+		}
+	}
+	if _, err := tomlQuote("\xff"); err == nil {
+		t.Fatal("invalid utf-8 should fail")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"dbm-services/redis/redis-dts/models/mysql/tendisdb"
 	"dbm-services/redis/redis-dts/pkg/constvar"
 	"dbm-services/redis/redis-dts/pkg/dtsTask"
+	"dbm-services/redis/redis-dts/pkg/keyTransform"
 	"dbm-services/redis/redis-dts/util"
 
 	"github.com/jinzhu/gorm"
@@ -369,6 +370,11 @@ func (task *MakeSyncTask) createSyncConfigFile() {
 		task.Logger.Info(fmt.Sprintf("redis-sync config file:%s already exists", task.SyncConfigFile))
 		return
 	}
+	// redis-sync 只支持按数据类型丢弃(filter-commands)
+	rules := task.GetTransformRules(keyTransform.EngineRedisSync)
+	if task.Err != nil {
+		return
+	}
 	sampleFile, err := util.IsFileExistsInCurrDir("tendisplus-sync-template.conf")
 	if err != nil {
 		task.Err = err
@@ -405,6 +411,7 @@ func (task *MakeSyncTask) createSyncConfigFile() {
 	sampleData = strings.ReplaceAll(sampleData, "{{KEY_WHITE_REGEX}}", keyWhiteRegex)
 	sampleData = strings.ReplaceAll(sampleData, "{{KEY_BLACK_REGEX}}", keyBlackRegex)
 	sampleData = strings.ReplaceAll(sampleData, "{{FULLSYNC_DEL_KEYS_FIRST}}", fullsyncDelKeysFirst)
+	sampleData = strings.ReplaceAll(sampleData, "{{FILTER_COMMANDS}}", rules.RedisSyncFilterCommands())
 	// 如果目标集群是域名,则redis-sync需要先解析域名中的 proxy ips,而后连接;该行为通过 proxy-enable 参数控制
 	proxyEnable := "no"
 	if util.IsDbDNS(task.GetDstRedisAddr()) {
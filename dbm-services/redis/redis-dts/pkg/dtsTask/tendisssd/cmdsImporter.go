@@ -19,6 +19,7 @@ import (
 	"dbm-services/redis/redis-dts/models/mysql/tendisdb"
 	"dbm-services/redis/redis-dts/pkg/constvar"
 	"dbm-services/redis/redis-dts/pkg/dtsTask"
+	"dbm-services/redis/redis-dts/pkg/keyTransform"
 	"dbm-services/redis/redis-dts/util"
 
 	"github.com/spf13/viper"
@@ -28,15 +29,16 @@ import (
 // CmdsImporterTask 命令导入task
 type CmdsImporterTask struct {
 	dtsTask.TendisDtsFatherTask
-	DelFiles               []string   `json:"delFiles"`
-	OutputFiles            []string   `json:"outputFiles"`
-	ListFiles              []string   `json:"listFiles"`
-	ExpireFiles            []string   `json:"expireFiles"`
-	ImportLogDir           string     `json:"importLogDir"`
-	DstProxyAddrs          []string   `json:"dstProxyAddrs"`
-	DstProxyIterIdx        int32      `json:"dstProxyIterIdx"`
-	DtsProxyMut            sync.Mutex `json:"-"` // 更新DstProxyAddrs时上锁
-	DtsProxyLastUpdatetime time.Time  `json:"-"` // 最后更新DstProxyAddrs的时间
+	DelFiles               []string              `json:"delFiles"`
+	OutputFiles            []string              `json:"outputFiles"`
+	ListFiles              []string              `json:"listFiles"`
+	ExpireFiles            []string              `json:"expireFiles"`
+	ImportLogDir           string                `json:"importLogDir"`
+	DstProxyAddrs          []string              `json:"dstProxyAddrs"`
+	DstProxyIterIdx        int32                 `json:"dstProxyIterIdx"`
+	DtsProxyMut            sync.Mutex            `json:"-"` // 更新DstProxyAddrs时上锁
+	DtsProxyLastUpdatetime time.Time             `json:"-"` // 最后更新DstProxyAddrs的时间
+	TransformRules         *keyTransform.RuleSet `json:"-"` // key转换规则
 }
 
 // TaskType task类型
@@ -59,7 +61,8 @@ func NewCmdsImporterTask(row *tendisdb.TbTendisDTSTask) *CmdsImporterTask {
 // ImporterItem 命令导入项(为并发执行导入)
 type ImporterItem struct {
 	RedisClient   string            `json:"redisClient"`
-	SQLFile       string            `json:"sqlFile"`
+	SrcFile       string            `json:"srcFile"` // tredisdump 结果文件
+	SQLFile       string            `json:"sqlFile"` // 实际导入的文件,有转换规则时为转换后的文件
 	DstPassword   string            `json:"dstPassword"`
 	LogFile       string            `json:"logFile"`
 	IgnoreErrlist []string          `json:"ignoreErrLsit"`
@@ -88,7 +91,7 @@ func (item *ImporterItem) MaxRetryTimes() int {
 
 // RetryAble 能否重复导入
 func (item *ImporterItem) RetryAble() bool {
-	if constvar.ListKeyFileReg.MatchString(item.SQLFile) {
+	if constvar.ListKeyFileReg.MatchString(item.SrcFile) {
 		return false
 	}
 	return true
@@ -112,8 +115,29 @@ func (item *ImporterItem) ErrorAbleToBeIgnored(errData string) bool {
 	return item.IsWrongTypeErr(errData)
 }
 
+// transformFile 按 drop_types 过滤生成导入文件,源文件保持不变,保证重试时可重复过滤
+func (item *ImporterItem) transformFile(rules *keyTransform.RuleSet) {
+	stat, err := rules.FilterRespFile(item.SrcFile, item.SQLFile)
+	if err != nil {
+		item.Err = err
+		item.Logger.Error("transform file fail", zap.Error(err))
+		return
+	}
+	if stat.Dropped > 0 {
+		item.Logger.Info(fmt.Sprintf("transform file:%s commands:%d dropped:%d",
+			item.SrcFile, stat.Commands, stat.Dropped))
+	}
+}
+
 // RunTask 执行导入task
 func (item *ImporterItem) RunTask(task *CmdsImporterTask) {
+	if item.SQLFile != item.SrcFile {
+		item.transformFile(task.TransformRules)
+		if item.Err != nil {
+			return
+		}
+		defer os.Remove(item.SQLFile)
+	}
 	supPipeImport := task.IsSupportPipeImport()
 	importTimeout := task.ImportTimeout()
 	cmdTimeout := importTimeout + 60
@@ -246,9 +270,14 @@ func (task *CmdsImporterTask) NextDstProxyAddr(refreshDns bool) string {
 func (task *CmdsImporterTask) NewImporterItem(redisCli, keysFile, dstPasswd string) (item ImporterItem, err error) {
 	baseName := filepath.Base(keysFile)
 	importLogDir := task.getimportLogDir()
+	sqlFile := keysFile
+	if task.TransformRules != nil && !task.TransformRules.IsEmpty() {
+		sqlFile = filepath.Join(importLogDir, baseName+".transformed")
+	}
 	item = ImporterItem{
 		RedisClient: redisCli,
-		SQLFile:     keysFile,
+		SrcFile:     keysFile,
+		SQLFile:     sqlFile,
 		task:        task,
 		DstPassword: dstPasswd,
 		LogFile:     filepath.Join(importLogDir, baseName+".log"),
@@ -634,6 +663,11 @@ func (task *CmdsImporterTask) Execute() {
 	if task.Err != nil {
 		return
 	}
+	// 增量同步(redis-sync)同样需要支持转换规则,全量导入也只支持 drop_types
+	task.TransformRules = task.GetTransformRules(keyTransform.EngineCmdsImporter, keyTransform.EngineRedisSync)
+	if task.Err != nil {
+		return
+	}
 	parallelLimit := task.ImportParallelLimit()
 	dstPasswd, _ := base64.StdEncoding.DecodeString(task.RowData.DstPassword)
 
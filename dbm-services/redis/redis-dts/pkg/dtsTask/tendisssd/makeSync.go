@@ -15,6 +15,7 @@ import (
 	"dbm-services/redis/redis-dts/models/mysql/tendisdb"
 	"dbm-services/redis/redis-dts/pkg/constvar"
 	"dbm-services/redis/redis-dts/pkg/dtsTask"
+	"dbm-services/redis/redis-dts/pkg/keyTransform"
 	"dbm-services/redis/redis-dts/util"
 
 	"github.com/jinzhu/gorm"
@@ -680,6 +681,11 @@ func (task *MakeSyncTask) createSyncConfigFile() {
 		return
 	}
 
+	// redis-sync 只支持按数据类型丢弃(filter-commands)
+	rules := task.GetTransformRules(keyTransform.EngineRedisSync)
+	if task.Err != nil {
+		return
+	}
 	currentPath, _ := util.CurrentExecutePath()
 	tempFile := filepath.Join(currentPath, "tendisssd-sync-template.conf")
 	tempContent, err := ioutil.ReadFile(tempFile)
@@ -713,6 +719,7 @@ func (task *MakeSyncTask) createSyncConfigFile() {
 	tempData = strings.ReplaceAll(tempData, "{{DST_ADDR}}", task.DstADDR)
 	tempData = strings.ReplaceAll(tempData, "{{DST_PASSWORD}}", task.DstPassword)
 	tempData = strings.ReplaceAll(tempData, "{{LOG_LEVEL}}", loglevel)
+	tempData = strings.ReplaceAll(tempData, "{{FILTER_COMMANDS}}", rules.RedisSyncFilterCommands())
 
 	// 如果目标集群是域名,则redis-sync需要先解析域名中的 proxy ips,而后连接;该行为通过 proxy-enable 参数控制
 	proxyEnable := "no"
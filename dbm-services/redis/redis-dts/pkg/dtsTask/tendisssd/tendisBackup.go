@@ -12,6 +12,7 @@ import (
 	"dbm-services/redis/redis-dts/models/mysql/tendisdb"
 	"dbm-services/redis/redis-dts/pkg/constvar"
 	"dbm-services/redis/redis-dts/pkg/dtsTask"
+	"dbm-services/redis/redis-dts/pkg/keyTransform"
 	"dbm-services/redis/redis-dts/pkg/scrdbclient"
 	"dbm-services/redis/redis-dts/util"
 
@@ -125,6 +126,11 @@ func (task *TendisBackupTask) Execute() {
 			task.UpdateRow()
 		}
 	}()
+	// 全量导入与增量同步都只支持 drop_types, 备份前检查,避免备份、导出完成后才发现规则不被支持
+	task.GetTransformRules(keyTransform.EngineCmdsImporter, keyTransform.EngineRedisSync)
+	if task.Err != nil {
+		return
+	}
 	// 如果当前tendisSSD的redis-sync状态正常,则直接watch redis-sync 即可
 	isSyncOK := task.IsSyncStateOK()
 	if isSyncOK {
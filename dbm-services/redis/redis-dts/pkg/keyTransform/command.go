@@ -0,0 +1,57 @@
+package keyTransform
+
+import "strings"
+
+// typeWriteCommands 各数据类型的写命令
+var typeWriteCommands = map[string][]string{
+	TypeString: {"set", "setex", "psetex", "setnx", "mset", "msetnx", "append", "setrange", "setbit",
+		"incr", "incrby", "incrbyfloat", "decr", "decrby", "getset"},
+	TypeHash: {"hset", "hmset", "hsetnx", "hdel", "hincrby", "hincrbyfloat"},
+	TypeList: {"rpush", "lpush", "rpushx", "lpushx", "linsert", "lset", "lrem", "ltrim", "lpop", "rpop",
+		"rpoplpush", "lmove"},
+	TypeSet: {"sadd", "srem", "spop", "smove", "sinterstore", "sunionstore", "sdiffstore"},
+	TypeZset: {"zadd", "zincrby", "zrem", "zremrangebyscore", "zremrangebyrank", "zremrangebylex",
+		"zpopmin", "zpopmax", "zunionstore", "zinterstore"},
+}
+
+// cmdDataTypes 写命令 => 数据类型
+var cmdDataTypes = map[string]string{}
+
+func init() {
+	for dataType, cmds := range typeWriteCommands {
+		for _, cmd := range cmds {
+			cmdDataTypes[cmd] = dataType
+		}
+	}
+}
+
+// rdbTypeToDataType restore payload 首字节(rdb value type) => 数据类型
+var rdbTypeToDataType = map[byte]string{
+	0: TypeString,
+	1: TypeList, 10: TypeList, 14: TypeList, 18: TypeList,
+	2: TypeSet, 11: TypeSet, 20: TypeSet,
+	3: TypeZset, 5: TypeZset, 12: TypeZset, 17: TypeZset,
+	4: TypeHash, 9: TypeHash, 13: TypeHash, 16: TypeHash,
+}
+
+// restorePayloadType restore 命令 payload 对应的数据类型
+func restorePayloadType(payload string) string {
+	if payload == "" {
+		return ""
+	}
+	return rdbTypeToDataType[payload[0]]
+}
+
+// IsCommandDropped 命令写入的数据类型是否被 drop_types 丢弃
+// 只按数据类型过滤,命令内容不做改写; 无法判断数据类型的命令(select/del/expire等)保留
+func (r *RuleSet) IsCommandDropped(args []string) bool {
+	if len(args) == 0 || len(r.DropTypes) == 0 {
+		return false
+	}
+	cmd := strings.ToLower(args[0])
+	dataType := cmdDataTypes[cmd]
+	if cmd == "restore" && len(args) > 3 {
+		dataType = restorePayloadType(args[3])
+	}
+	return dataType != "" && r.IsTypeDropped(dataType)
+}
@@ -0,0 +1,30 @@
+package keyTransform
+
+import "testing"
+
+func TestIsCommandDropped(t *testing.T) {
+	r := &RuleSet{DropTypes: []string{TypeList, TypeZset}}
+	tests := []struct {
+		args []string
+		drop bool
+	}{
+		{[]string{"rpush", "l", "1"}, true},
+		{[]string{"LMOVE", "l1", "l2", "left", "right"}, true},
+		{[]string{"zadd", "z", "1", "m"}, true},
+		{[]string{"restore", "l", "0", "\x0epayload"}, true},
+		{[]string{"restore", "s", "0", "\x00payload"}, false},
+		{[]string{"set", "s", "v"}, false},
+		{[]string{"del", "l"}, false},
+		{[]string{"expire", "l", "10"}, false},
+		{[]string{"select", "1"}, false},
+		{[]string{}, false},
+	}
+	for _, tt := range tests {
+		if drop := r.IsCommandDropped(tt.args); drop != tt.drop {
+			t.Errorf("IsCommandDropped(%q)=%v, want %v", tt.args, drop, tt.drop)
+		}
+	}
+	if (&RuleSet{}).IsCommandDropped([]string{"rpush", "l", "1"}) {
+		t.Error("empty rules should not drop commands")
+	}
+}
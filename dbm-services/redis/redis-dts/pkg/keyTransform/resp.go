@@ -0,0 +1,117 @@
+package keyTransform
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// FileStat resp文件转换统计
+type FileStat struct {
+	Commands int64 `json:"commands"`
+	Dropped  int64 `json:"dropped"`
+}
+
+// FilterRespFile 按 drop_types 过滤 resp 格式的命令文件(redis-cli --pipe 导入文件),结果写入 dstFile
+func (r *RuleSet) FilterRespFile(srcFile, dstFile string) (stat FileStat, err error) {
+	src, err := os.Open(srcFile)
+	if err != nil {
+		return stat, fmt.Errorf("open file:%s fail,err:%v", srcFile, err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(dstFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return stat, fmt.Errorf("open file:%s fail,err:%v", dstFile, err)
+	}
+	defer dst.Close()
+
+	reader := bufio.NewReaderSize(src, 1024*1024)
+	writer := bufio.NewWriterSize(dst, 1024*1024)
+	for {
+		args, err := readRespCommand(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stat, fmt.Errorf("read command from file:%s fail,commands:%d,err:%v", srcFile, stat.Commands, err)
+		}
+		stat.Commands++
+		if r.IsCommandDropped(args) {
+			stat.Dropped++
+			continue
+		}
+		if err = writeRespCommand(writer, args); err != nil {
+			return stat, fmt.Errorf("write file:%s fail,err:%v", dstFile, err)
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		return stat, fmt.Errorf("flush file:%s fail,err:%v", dstFile, err)
+	}
+	return stat, nil
+}
+
+func readRespLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			return "", io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("resp line %q not end with CRLF", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// readRespCommand 读取一条 resp array 格式的命令
+func readRespCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readRespLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return nil, fmt.Errorf("resp command %q not start with '*'", line)
+	}
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("resp array length %q invalid", line)
+	}
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		line, err = readRespLine(reader)
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("resp bulk string %q not start with '$'", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("resp bulk string length %q invalid", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, buf); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func writeRespCommand(writer *bufio.Writer, args []string) (err error) {
+	if _, err = writer.WriteString("*" + strconv.Itoa(len(args)) + "\r\n"); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if _, err = writer.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
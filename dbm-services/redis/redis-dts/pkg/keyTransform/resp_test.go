@@ -0,0 +1,103 @@
+package keyTransform
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRespRoundTrip(t *testing.T) {
+	cmds := [][]string{
+		{"select", "0"},
+		{"set", "k", "v\r\nwith crlf"},
+		{"restore", "k2", "0", "\x00\x01binary\x00"},
+		{"del"},
+		{"set", "k3", ""},
+	}
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	for _, args := range cmds {
+		if err := writeRespCommand(w, args); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(&buf)
+	for _, want := range cmds {
+		got, err := readRespCommand(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(want) == 1 && len(got) == 1 && got[0] == want[0] {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+	if _, err := readRespCommand(r); err != io.EOF {
+		t.Fatalf("want EOF, got %v", err)
+	}
+}
+
+func TestReadRespCommandInvalid(t *testing.T) {
+	for _, data := range []string{
+		"set k v\r\n",
+		"*x\r\n",
+		"*2\r\n$3\r\nset\r\n",
+		"*1\r\n$5\r\nset\r\n",
+		"*1\r\n+set\r\n",
+		"*1\n$3\r\nset\r\n",
+		"*1\r\n$3\r\nset",
+	} {
+		if _, err := readRespCommand(bufio.NewReader(strings.NewReader(data))); err == nil || err == io.EOF {
+			t.Errorf("readRespCommand(%q) err:%v, want error", data, err)
+		}
+	}
+}
+
+func TestFilterRespFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.resp")
+	dst := filepath.Join(dir, "dst.resp")
+	data := "*2\r\n$6\r\nselect\r\n$1\r\n1\r\n" +
+		"*3\r\n$3\r\nset\r\n$5\r\nold:a\r\n$1\r\nv\r\n" +
+		"*3\r\n$5\r\nrpush\r\n$5\r\nold:l\r\n$1\r\n1\r\n" +
+		"*3\r\n$6\r\nexpire\r\n$5\r\nold:a\r\n$4\r\n9999\r\n"
+	if err := os.WriteFile(src, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &RuleSet{DropTypes: []string{TypeList}}
+	stat, err := r.FilterRespFile(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Commands != 4 || stat.Dropped != 1 {
+		t.Fatalf("stat:%+v", stat)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "*2\r\n$6\r\nselect\r\n$1\r\n1\r\n" +
+		"*3\r\n$3\r\nset\r\n$5\r\nold:a\r\n$1\r\nv\r\n" +
+		"*3\r\n$6\r\nexpire\r\n$5\r\nold:a\r\n$4\r\n9999\r\n"
+	if string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// 文件截断
+	if err = os.WriteFile(src, []byte(data[:len(data)-3]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.FilterRespFile(src, dst); err == nil {
+		t.Fatal("truncated file should fail")
+	}
+}
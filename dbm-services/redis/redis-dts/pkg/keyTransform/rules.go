@@ -0,0 +1,211 @@
+// Package keyTransform 迁移过程中key的转换规则:前缀改写、db映射、TTL上限、按数据类型丢弃
+package keyTransform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 支持丢弃的数据类型
+const (
+	TypeString = "string"
+	TypeHash   = "hash"
+	TypeList   = "list"
+	TypeSet    = "set"
+	TypeZset   = "zset"
+)
+
+// 执行同步的工具,不同工具能支持的规则不同
+const (
+	// EngineCmdsImporter tendisssd 全量数据导入(redis-cli --pipe)
+	EngineCmdsImporter = "cmds_importer"
+	// EngineRedisSync tendisssd/tendisplus 增量同步工具 redis-sync
+	EngineRedisSync = "redis_sync"
+	// EngineRedisShake redis-shake v2,redis cache 全量+增量
+	EngineRedisShake = "redis_shake"
+	// EngineRedisShakeV4 redis-shake v4,redis7 及以上 全量+增量
+	EngineRedisShakeV4 = "redis_shake_v4"
+)
+
+// 规则名称
+const (
+	RulePrefixRewrite  = "prefix_rewrites"
+	RuleDbIndexMapping = "db_index_mapping"
+	RuleMaxTTLSeconds  = "max_ttl_seconds"
+	RuleDropTypes      = "drop_types"
+)
+
+// engineSupportedRules 各工具支持的规则, 前缀改写与TTL上限只有 redis-shake v4 支持
+var engineSupportedRules = map[string][]string{
+	// 全量导入只按数据类型过滤导入文件,与增量 redis-sync 保持一致
+	EngineCmdsImporter: {RuleDropTypes},
+	EngineRedisShakeV4: {RulePrefixRewrite, RuleDbIndexMapping, RuleMaxTTLSeconds, RuleDropTypes},
+	// redis-shake v2 只能通过 target.db 将所有db写入同一个db
+	EngineRedisShake: {RuleDbIndexMapping},
+	// redis-sync 只能通过 filter-commands 过滤写命令
+	EngineRedisSync: {RuleDropTypes},
+}
+
+// PrefixRewrite key前缀改写, 如 old: => new:
+type PrefixRewrite struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RuleSet key转换规则集合,保存在 dts task 的 transform_rules 字段(json)
+type RuleSet struct {
+	PrefixRewrites []PrefixRewrite `json:"prefix_rewrites"`
+	// DbIndexMapping 源db => 目标db, 未配置的db保持不变
+	DbIndexMapping map[int]int `json:"db_index_mapping"`
+	// MaxTTLSeconds TTL 上限,大于该值的过期时间被截断为该值;不影响无过期时间的key; 0表示不限制
+	MaxTTLSeconds int64 `json:"max_ttl_seconds"`
+	// DropTypes 不迁移的数据类型,如 ["list","zset"]
+	DropTypes []string `json:"drop_types"`
+}
+
+// ParseRuleSet 解析json格式的规则,空字符串返回空规则
+func ParseRuleSet(rulesJSON string) (rules *RuleSet, err error) {
+	rules = &RuleSet{}
+	rulesJSON = strings.TrimSpace(rulesJSON)
+	if rulesJSON == "" {
+		return rules, nil
+	}
+	err = json.Unmarshal([]byte(rulesJSON), rules)
+	if err != nil {
+		err = fmt.Errorf("transform rules:%s json.Unmarshal fail,err:%v", rulesJSON, err)
+		return nil, err
+	}
+	// 按 from 长度倒序,保证最长前缀优先匹配
+	sort.SliceStable(rules.PrefixRewrites, func(i, j int) bool {
+		return len(rules.PrefixRewrites[i].From) > len(rules.PrefixRewrites[j].From)
+	})
+	return rules, rules.Validate()
+}
+
+// IsEmpty 是否没有任何规则
+func (r *RuleSet) IsEmpty() bool {
+	return len(r.EnabledRules()) == 0
+}
+
+// EnabledRules 已配置的规则名
+func (r *RuleSet) EnabledRules() (ret []string) {
+	if len(r.PrefixRewrites) > 0 {
+		ret = append(ret, RulePrefixRewrite)
+	}
+	if len(r.DbIndexMapping) > 0 {
+		ret = append(ret, RuleDbIndexMapping)
+	}
+	if r.MaxTTLSeconds > 0 {
+		ret = append(ret, RuleMaxTTLSeconds)
+	}
+	if len(r.DropTypes) > 0 {
+		ret = append(ret, RuleDropTypes)
+	}
+	return
+}
+
+// Validate 校验规则
+func (r *RuleSet) Validate() error {
+	froms := make(map[string]bool, len(r.PrefixRewrites))
+	for _, item := range r.PrefixRewrites {
+		if item.From == "" {
+			return fmt.Errorf("prefix rewrite from cannot be empty,to:%s", item.To)
+		}
+		if froms[item.From] {
+			return fmt.Errorf("prefix rewrite from:%s duplicate", item.From)
+		}
+		froms[item.From] = true
+	}
+	for src, dst := range r.DbIndexMapping {
+		if src < 0 || dst < 0 {
+			return fmt.Errorf("db index mapping %d=>%d invalid", src, dst)
+		}
+	}
+	if r.MaxTTLSeconds < 0 {
+		return fmt.Errorf("max_ttl_seconds:%d cannot be negative", r.MaxTTLSeconds)
+	}
+	for _, t := range r.DropTypes {
+		if _, ok := typeWriteCommands[t]; !ok {
+			return fmt.Errorf("drop type:%s not supported,valid types:%s,%s,%s,%s,%s",
+				t, TypeString, TypeHash, TypeList, TypeSet, TypeZset)
+		}
+	}
+	return nil
+}
+
+// CheckEngineSupport 检查工具是否支持已配置的全部规则
+// 不支持时直接报错,而不是忽略规则,避免全量与增量数据不一致
+func (r *RuleSet) CheckEngineSupport(engine string) error {
+	supported := engineSupportedRules[engine]
+	var unsupported []string
+	for _, rule := range r.EnabledRules() {
+		found := false
+		for _, s := range supported {
+			if s == rule {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unsupported = append(unsupported, rule)
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("transform rules [%s] not supported by %s,supported rules:[%s]",
+			strings.Join(unsupported, ","), engine, strings.Join(supported, ","))
+	}
+	if engine == EngineRedisShake {
+		if _, err := r.SingleTargetDb(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SingleTargetDb 所有db映射到同一个目标db时返回该db(redis-shake v2 target.db)
+func (r *RuleSet) SingleTargetDb() (int, error) {
+	target := -1
+	for _, dst := range r.DbIndexMapping {
+		if target >= 0 && dst != target {
+			return -1, fmt.Errorf("db index mapping must map all dbs to one target db,mapping:%v", r.DbIndexMapping)
+		}
+		target = dst
+	}
+	return target, nil
+}
+
+// IsTypeDropped 数据类型是否被丢弃
+func (r *RuleSet) IsTypeDropped(dataType string) bool {
+	for _, t := range r.DropTypes {
+		if t == dataType {
+			return true
+		}
+	}
+	return false
+}
+
+// DroppedWriteCommands 被丢弃数据类型对应的全部写命令(redis-sync filter-commands)
+func (r *RuleSet) DroppedWriteCommands() []string {
+	ret := []string{}
+	for _, t := range r.DropTypes {
+		ret = append(ret, typeWriteCommands[t]...)
+	}
+	return ret
+}
+
+// RedisSyncFilterCommands redis-sync 配置 filter-commands 需追加的命令,以逗号开头;无需过滤时返回空
+func (r *RuleSet) RedisSyncFilterCommands() string {
+	cmds := r.DroppedWriteCommands()
+	if len(cmds) == 0 {
+		return ""
+	}
+	return "," + strings.Join(cmds, ",")
+}
+
+// ToString json格式
+func (r *RuleSet) ToString() string {
+	ret, _ := json.Marshal(r)
+	return string(ret)
+}
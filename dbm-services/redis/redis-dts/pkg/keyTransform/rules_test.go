@@ -0,0 +1,99 @@
+package keyTransform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRuleSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+		rules   []string
+	}{
+		{name: "empty", json: "  "},
+		{name: "all rules",
+			json: `{"prefix_rewrites":[{"from":"a:","to":"b:"}],"db_index_mapping":{"1":0},` +
+				`"max_ttl_seconds":60,"drop_types":["list"]}`,
+			rules: []string{RulePrefixRewrite, RuleDbIndexMapping, RuleMaxTTLSeconds, RuleDropTypes}},
+		{name: "bad json", json: `{"prefix_rewrites":`, wantErr: "json.Unmarshal fail"},
+		{name: "empty from", json: `{"prefix_rewrites":[{"from":"","to":"b:"}]}`, wantErr: "cannot be empty"},
+		{name: "duplicate from", json: `{"prefix_rewrites":[{"from":"a","to":"b"},{"from":"a","to":"c"}]}`,
+			wantErr: "duplicate"},
+		{name: "negative db", json: `{"db_index_mapping":{"1":-1}}`, wantErr: "invalid"},
+		{name: "negative ttl", json: `{"max_ttl_seconds":-1}`, wantErr: "cannot be negative"},
+		{name: "unknown type", json: `{"drop_types":["stream"]}`, wantErr: "not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRuleSet(tt.json)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err:%v, want contains %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.EnabledRules(); !reflect.DeepEqual(got, tt.rules) {
+				t.Fatalf("EnabledRules:%v, want %v", got, tt.rules)
+			}
+		})
+	}
+}
+
+func TestParseRuleSetLongestPrefixFirst(t *testing.T) {
+	r, err := ParseRuleSet(`{"prefix_rewrites":[{"from":"a","to":"x"},{"from":"ab:","to":"y:"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PrefixRewrite{{From: "ab:", To: "y:"}, {From: "a", To: "x"}}
+	if !reflect.DeepEqual(r.PrefixRewrites, want) {
+		t.Fatalf("PrefixRewrites:%v, want %v", r.PrefixRewrites, want)
+	}
+}
+
+func TestCheckEngineSupport(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  RuleSet
+		engine string
+		ok     bool
+	}{
+		{"empty on redis-sync", RuleSet{}, EngineRedisSync, true},
+		{"prefix on importer", RuleSet{PrefixRewrites: []PrefixRewrite{{"a", "b"}}}, EngineCmdsImporter, false},
+		{"ttl on importer", RuleSet{MaxTTLSeconds: 10}, EngineCmdsImporter, false},
+		{"drop types on importer", RuleSet{DropTypes: []string{TypeList}}, EngineCmdsImporter, true},
+		{"prefix on shake v4", RuleSet{PrefixRewrites: []PrefixRewrite{{"a", "b"}}}, EngineRedisShakeV4, true},
+		{"prefix on redis-sync", RuleSet{PrefixRewrites: []PrefixRewrite{{"a", "b"}}}, EngineRedisSync, false},
+		{"prefix on shake v2", RuleSet{PrefixRewrites: []PrefixRewrite{{"a", "b"}}}, EngineRedisShake, false},
+		{"drop types on redis-sync", RuleSet{DropTypes: []string{TypeList}}, EngineRedisSync, true},
+		{"ttl on redis-sync", RuleSet{MaxTTLSeconds: 10}, EngineRedisSync, false},
+		{"single target db on shake v2", RuleSet{DbIndexMapping: map[int]int{1: 0, 2: 0}}, EngineRedisShake, true},
+		{"multi target db on shake v2", RuleSet{DbIndexMapping: map[int]int{1: 0, 2: 3}}, EngineRedisShake, false},
+		{"unknown engine", RuleSet{MaxTTLSeconds: 10}, "unknown", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.CheckEngineSupport(tt.engine)
+			if (err == nil) != tt.ok {
+				t.Fatalf("CheckEngineSupport(%s) err:%v, want ok:%v", tt.engine, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRedisSyncFilterCommands(t *testing.T) {
+	r := RuleSet{}
+	if got := r.RedisSyncFilterCommands(); got != "" {
+		t.Fatalf("empty rules got %q", got)
+	}
+	r.DropTypes = []string{TypeHash}
+	want := ",hset,hmset,hsetnx,hdel,hincrby,hincrbyfloat"
+	if got := r.RedisSyncFilterCommands(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
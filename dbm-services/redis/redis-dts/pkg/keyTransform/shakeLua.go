@@ -0,0 +1,139 @@
+package keyTransform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// shakeGroups 数据类型 => redis-shake v4 function 中的 GROUP
+var shakeGroups = map[string]string{
+	TypeString: "STRING",
+	TypeHash:   "HASH",
+	TypeList:   "LIST",
+	TypeSet:    "SET",
+	TypeZset:   "SORTED_SET",
+}
+
+// luaQuote 生成lua字符串字面量,非打印字符及引号统一用 \ddd 转义
+// (生成结果放在 toml 多行字面量字符串中,不能出现单引号)
+func luaQuote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c >= 0x7f || c == '"' || c == '\'' || c == '\\' {
+			fmt.Fprintf(&sb, "\\%03d", c)
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// RedisShakeV4Function 生成 redis-shake v4 [filter] function 的lua代码
+// 规则为空时返回默认透传函数
+func (r *RuleSet) RedisShakeV4Function() string {
+	var sb strings.Builder
+	sb.WriteString("local prefix_rewrites = {")
+	for _, item := range r.PrefixRewrites {
+		fmt.Fprintf(&sb, "{%s, %s},", luaQuote(item.From), luaQuote(item.To))
+	}
+	sb.WriteString("}\n")
+
+	sb.WriteString("local db_mapping = {")
+	srcDbs := make([]int, 0, len(r.DbIndexMapping))
+	for src := range r.DbIndexMapping {
+		srcDbs = append(srcDbs, src)
+	}
+	sort.Ints(srcDbs)
+	for _, src := range srcDbs {
+		fmt.Fprintf(&sb, "[%d] = %d,", src, r.DbIndexMapping[src])
+	}
+	sb.WriteString("}\n")
+
+	sb.WriteString("local drop_groups = {")
+	for _, t := range r.DropTypes {
+		fmt.Fprintf(&sb, "[%s] = true,", luaQuote(shakeGroups[t]))
+	}
+	sb.WriteString("}\n")
+	// restore 命令通过 payload 首字节(rdb type)判断数据类型
+	sb.WriteString("local rdb_type_groups = {")
+	rdbTypes := make([]int, 0, len(rdbTypeToDataType))
+	for b := range rdbTypeToDataType {
+		rdbTypes = append(rdbTypes, int(b))
+	}
+	sort.Ints(rdbTypes)
+	for _, b := range rdbTypes {
+		fmt.Fprintf(&sb, "[%d] = %s,", b, luaQuote(shakeGroups[rdbTypeToDataType[byte(b)]]))
+	}
+	sb.WriteString("}\n")
+	fmt.Fprintf(&sb, "local max_ttl = %d\n", r.MaxTTLSeconds)
+	sb.WriteString(shakeLuaBody)
+	return sb.String()
+}
+
+// shakeLuaBody 规则执行逻辑, 变量 DB/CMD/GROUP/KEY_INDEXES/ARGV 由 redis-shake 提供
+const shakeLuaBody = `
+local cmd = string.upper(CMD)
+local group = string.upper(GROUP)
+if cmd == "RESTORE" and ARGV[4] ~= nil then
+  group = rdb_type_groups[string.byte(ARGV[4], 1)] or group
+end
+if drop_groups[group] then
+  return
+end
+for _, index in ipairs(KEY_INDEXES) do
+  local key = ARGV[index]
+  for _, item in ipairs(prefix_rewrites) do
+    if string.sub(key, 1, #item[1]) == item[1] then
+      ARGV[index] = item[2] .. string.sub(key, #item[1] + 1)
+      break
+    end
+  end
+end
+local function cap(idx, unit, absolute)
+  local val = tonumber(ARGV[idx])
+  if val == nil then
+    return
+  end
+  local limit = max_ttl * unit
+  if absolute then
+    limit = limit + os.time() * unit
+  end
+  if val > limit then
+    ARGV[idx] = tostring(limit)
+  end
+end
+if max_ttl > 0 then
+  if cmd == "EXPIRE" or cmd == "SETEX" then
+    cap(3, 1, false)
+  elseif cmd == "PEXPIRE" or cmd == "PSETEX" then
+    cap(3, 1000, false)
+  elseif cmd == "EXPIREAT" then
+    cap(3, 1, true)
+  elseif cmd == "PEXPIREAT" then
+    cap(3, 1000, true)
+  elseif cmd == "SET" then
+    for i = 4, #ARGV - 1 do
+      local opt = string.upper(ARGV[i])
+      if opt == "EX" then cap(i + 1, 1, false) break end
+      if opt == "PX" then cap(i + 1, 1000, false) break end
+      if opt == "EXAT" then cap(i + 1, 1, true) break end
+      if opt == "PXAT" then cap(i + 1, 1000, true) break end
+    end
+  elseif cmd == "RESTORE" and ARGV[3] ~= "0" then
+    local absolute = false
+    for i = 5, #ARGV do
+      if string.upper(ARGV[i]) == "ABSTTL" then absolute = true end
+    end
+    cap(3, 1000, absolute)
+  end
+end
+local db = DB
+if db_mapping[db] ~= nil then
+  db = db_mapping[db]
+end
+shake.call(db, ARGV)
+`
@@ -0,0 +1,23 @@
+# Generated by Django 3.2.25 on 2026-10-19 02:30
+
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ("redis_dts", "0017_auto_20240908_1038_squashed_0018_auto_20240914_1754"),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name="tbtendisdtsjob",
+            name="transform_rules",
+            field=models.TextField(default="", verbose_name="key转换规则"),
+        ),
+        migrations.AddField(
+            model_name="tbtendisdtstask",
+            name="transform_rules",
+            field=models.TextField(default="", verbose_name="key转换规则"),
+        ),
+    ]
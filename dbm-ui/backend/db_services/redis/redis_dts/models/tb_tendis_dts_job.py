@@ -85,6 +85,8 @@ class TbTendisDTSJob(models.Model):
     dst_cluster_type = models.CharField(max_length=64, default="", verbose_name=_("目标集群类型"))
     key_white_regex = models.TextField(default="", verbose_name=_("key正则(包含key)"))
     key_black_regex = models.TextField(default="", verbose_name=_("key正则(排除key)"))
+    # key转换规则(json),如前缀改写、db映射、TTL上限、按类型丢弃,见 redis-dts keyTransform.RuleSet
+    transform_rules = models.TextField(default="", verbose_name=_("key转换规则"))
     # 任务状态,该字段没用了
     status = models.IntegerField(default=0, db_index=True, verbose_name=_("任务状态"))
     reason = models.TextField(default="", verbose_name=_("备注"))
@@ -50,6 +50,8 @@ class TbTendisDtsTask(models.Model):
     src_twemproxy_hash_tag_enabled = models.IntegerField(default=0, verbose_name=_("源twemproxy集群是否开启hash_tag"))
     key_white_regex = models.TextField(default="", verbose_name=_("包含key(正则)"))
     key_black_regex = models.TextField(default="", verbose_name=_("排除key(正则)"))
+    # key转换规则(json),从 job 复制
+    transform_rules = models.TextField(default="", verbose_name=_("key转换规则"))
     src_kvstore_id = models.IntegerField(default=0, verbose_name="tendisplus kvstore id")
     dst_cluster = models.CharField(max_length=128, default="", verbose_name=_("目的集群"))
     dst_password = models.CharField(max_length=128, default="", verbose_name=_("目的密码base64值"))
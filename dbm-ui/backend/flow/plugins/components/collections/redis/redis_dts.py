@@ -475,6 +475,8 @@ class RedisDtsExecuteService(BaseService):
                 job.dst_cluster_type = kwargs["cluster"]["dst"]["cluster_type"]
                 job.key_white_regex = kwargs["cluster"]["info"]["key_white_regex"]
                 job.key_black_regex = kwargs["cluster"]["info"]["key_black_regex"]
+                # key转换规则(json字符串),由 redis-dts 解析与校验
+                job.transform_rules = kwargs["cluster"]["info"].get("transform_rules", "")
                 job.create_time = datetime.datetime.now(timezone.utc)
                 job.save()
                 job_id = job.id
@@ -510,6 +512,7 @@ class RedisDtsExecuteService(BaseService):
                         task.src_twemproxy_hash_tag_enabled = src_twemproxy_hash_tag_enabled
                         task.key_white_regex = task_white_regex
                         task.key_black_regex = task_black_regex
+                        task.transform_rules = job.transform_rules
                         task.dst_cluster = kwargs["cluster"]["dst"]["cluster_addr"]
                         task.dst_password = dst_passsword_base64
                         task.create_time = datetime.datetime.now(timezone.utc)